@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// aliasRequest is the body of the /v1/me/emails requests
+type aliasRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// handleEmailAliases serves the token user's aliases: GET lists them, POST
+// adds one and sends it a verification code, and DELETE ?email= removes one
+func (s *server) handleEmailAliases(w http.ResponseWriter, r *http.Request) {
+	user := tokenUser(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		aliases, err := s.coreService.ListEmailAliases(r.Context(), user.ID)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, aliases)
+	case http.MethodPost:
+		var req aliasRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		alias, err := s.coreService.AddEmailAlias(r.Context(), user, req.Email)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, alias)
+	case http.MethodDelete:
+		emailAddr := r.URL.Query().Get("email")
+		if emailAddr == "" {
+			writeError(w, http.StatusBadRequest, "email query parameter is required")
+			return
+		}
+		if err := s.coreService.RemoveEmailAlias(r.Context(), user.ID, emailAddr); err != nil {
+			writeAppError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleVerifyEmailAlias serves POST /v1/me/emails/verify with the code the
+// alias was sent
+func (s *server) handleVerifyEmailAlias(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req aliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	alias, err := s.coreService.VerifyEmailAlias(r.Context(), tokenUser(r.Context()).ID, req.Code)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, alias)
+}
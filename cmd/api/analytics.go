@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// analyticsDefaultDays is the range /v1/analytics covers without ?from
+const analyticsDefaultDays = 90
+
+// handleAnalytics returns the dashboard views between from and to
+// (YYYY-MM-DD, default the last 90 days) as of the last nightly refresh
+func (s *server) handleAnalytics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -analyticsDefaultDays)
+	var err error
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = time.Parse(dateLayout, value); err != nil {
+			writeError(w, http.StatusBadRequest, "from must be YYYY-MM-DD")
+			return
+		}
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = time.Parse(dateLayout, value); err != nil {
+			writeError(w, http.StatusBadRequest, "to must be YYYY-MM-DD")
+			return
+		}
+	}
+	if to.Before(from) {
+		writeError(w, http.StatusBadRequest, "to is before from")
+		return
+	}
+
+	dashboard, err := s.analytics.Dashboard(r.Context(), from, to)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dashboard)
+}
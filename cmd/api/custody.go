@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
+)
+
+// keyRequest is the body of the /v1/me/key requests
+type keyRequest struct {
+	Passphrase    string `json:"passphrase"`
+	NewPassphrase string `json:"new_passphrase"`
+	Confirm       string `json:"confirm"`
+}
+
+// handleKey serves the token user's data key: GET exports it, POST creates
+// it under a passphrase and PUT changes the passphrase
+func (s *server) handleKey(w http.ResponseWriter, r *http.Request) {
+	user := tokenUser(r.Context())
+
+	var req keyRequest
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+	}
+
+	var key *custody.Key
+	var err error
+	status := http.StatusOK
+	switch r.Method {
+	case http.MethodGet:
+		key, err = s.custody.Get(r.Context(), user.ID)
+	case http.MethodPost:
+		key, err = s.custody.Create(r.Context(), user.ID, req.Passphrase)
+		status = http.StatusCreated
+	case http.MethodPut:
+		key, err = s.custody.ChangePassphrase(r.Context(), user.ID, req.Passphrase, req.NewPassphrase)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, status, key)
+}
+
+// selfCustodyStatus is the body of GET /v1/me/key/self-custody
+type selfCustodyStatus struct {
+	Enabled bool   `json:"enabled"`
+	Warning string `json:"warning"`
+	Confirm string `json:"confirm"`
+}
+
+// handleSelfCustody serves GET /v1/me/key/self-custody, the warning to show
+// first, and POST, which seals the token user's entries for good
+func (s *server) handleSelfCustody(w http.ResponseWriter, r *http.Request) {
+	user := tokenUser(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		enabled := false
+		if key, err := s.custody.Get(r.Context(), user.ID); err == nil {
+			enabled = key.SelfCustodyAt != nil
+		}
+		writeJSON(w, http.StatusOK, selfCustodyStatus{Enabled: enabled, Warning: custody.Warning, Confirm: custody.ConfirmPhrase})
+	case http.MethodPost:
+		var req keyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		sealed, err := s.custody.EnableSelfCustody(r.Context(), user.ID, req.Passphrase, req.Confirm)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"sealed_entries": sealed})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/auth"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/entryformat"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/language"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+//go:embed web/*.html
+var webFS embed.FS
+
+// dashboardPages are the page templates, each parsed with web/layout.html
+var dashboardPages = []string{"login", "error", "calendar", "summaries", "preferences"}
+
+// pauseOptions are the pause lengths offered on the preferences page, in days
+var pauseOptions = []int{7, 14, 30}
+
+// dashboardPage is the data for every dashboard template; each page uses the
+// fields in its section
+type dashboardPage struct {
+	Title     string
+	User      *models.User
+	Notice    string
+	Error     string
+	CSRFToken string
+
+	// Login
+	Sent bool
+	// Token is the magic link token the sign-in confirmation posts
+	Token string
+
+	// Calendar
+	Month     string
+	PrevMonth string
+	NextMonth string
+	Weekdays  []string
+	Weeks     [][]calendarDay
+	Day       string
+	Entry     *models.Entry
+
+	// Summaries
+	Summaries []*models.WeeklySummary
+	Summary   *models.WeeklySummary
+
+	// Preferences
+	Prefs        *models.Preferences
+	EntryFormats []string
+	Languages    []language.Language
+	PauseOptions []int
+}
+
+// calendarDay is one cell of the month calendar
+type calendarDay struct {
+	Date     string
+	Day      int
+	InMonth  bool
+	Today    bool
+	HasEntry bool
+	Selected bool
+}
+
+type sessionContextKey struct{}
+
+// parseDashboardTemplates parses every page with the shared layout
+func parseDashboardTemplates() (map[string]*template.Template, error) {
+	pages := make(map[string]*template.Template, len(dashboardPages))
+	for _, name := range dashboardPages {
+		tmpl, err := template.ParseFS(webFS, "web/layout.html", "web/"+name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dashboard template %s: %w", name, err)
+		}
+		pages[name] = tmpl
+	}
+	return pages, nil
+}
+
+// registerDashboard serves the web dashboard under /app/
+func (s *server) registerDashboard(mux *http.ServeMux) {
+	mux.HandleFunc("/app/login", s.handleDashboardLogin)
+	mux.HandleFunc("/app/auth", s.handleDashboardAuth)
+	mux.HandleFunc("/app/logout", s.requireSession(s.handleDashboardLogout))
+	mux.HandleFunc("/app/", s.requireSession(s.handleDashboardCalendar))
+	mux.HandleFunc("/app/summaries", s.requireSession(s.handleDashboardSummaries))
+	mux.HandleFunc("/app/preferences", s.requireSession(s.handleDashboardPreferences))
+	mux.HandleFunc("/app/pause", s.requireSession(s.handleDashboardPause))
+	mux.HandleFunc("/app/resume", s.requireSession(s.handleDashboardResume))
+}
+
+// sessionUser returns the user signed in by requireSession
+func sessionUser(r *http.Request) *models.User {
+	user, _ := r.Context().Value(sessionContextKey{}).(*models.User)
+	return user
+}
+
+// requireSession redirects to the sign-in page unless the request carries a
+// valid session cookie. Form posts must also carry the session's CSRF token.
+func (s *server) requireSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session := auth.SessionFromRequest(r)
+		user, err := s.auth.Authenticate(r.Context(), session)
+		if apperrors.Is(err, apperrors.CodeUnauthorized) {
+			http.Redirect(w, r, "/app/login", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			s.renderDashboardError(w, r, nil, err)
+			return
+		}
+
+		if r.Method != http.MethodGet && !s.auth.ValidCSRF(session, r.FormValue(auth.CSRFField)) {
+			s.renderDashboardError(w, r, user, apperrors.New(apperrors.CodeUnauthorized, "this form has expired; reload the page and try again"))
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, user)))
+	}
+}
+
+func (s *server) handleDashboardLogin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.renderDashboard(w, r, http.StatusOK, "login", &dashboardPage{Title: "Sign in"})
+	case http.MethodPost:
+		emailAddr := strings.ToLower(strings.TrimSpace(r.FormValue("email")))
+		if emailAddr == "" {
+			s.renderDashboard(w, r, http.StatusBadRequest, "login", &dashboardPage{Title: "Sign in", Error: "Enter your email address."})
+			return
+		}
+		if err := s.auth.SendMagicLink(r.Context(), emailAddr, s.cfg.DashboardURL+"/app/auth"); err != nil {
+			s.renderDashboardError(w, r, nil, err)
+			return
+		}
+		s.renderDashboard(w, r, http.StatusOK, "login", &dashboardPage{Title: "Sign in", Sent: true})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleDashboardAuth serves the magic link. GET only shows a button that
+// posts ?token back, since mail scanners prefetch links and would otherwise
+// use up the token and get the session; POST redeems it and sets the
+// session cookie.
+func (s *server) handleDashboardAuth(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			http.Redirect(w, r, "/app/login", http.StatusSeeOther)
+			return
+		}
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		s.renderDashboard(w, r, http.StatusOK, "login", &dashboardPage{Title: "Sign in", Token: token})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, err := s.auth.Redeem(r.Context(), r.FormValue("token"))
+	if apperrors.Is(err, apperrors.CodeUnauthorized) {
+		s.renderDashboard(w, r, http.StatusUnauthorized, "login", &dashboardPage{
+			Title: "Sign in",
+			Error: "That sign-in link has expired or was already used. Request a new one below.",
+		})
+		return
+	}
+	if err != nil {
+		s.renderDashboardError(w, r, nil, err)
+		return
+	}
+
+	auth.SetCookie(w, session, strings.HasPrefix(s.cfg.DashboardURL, "https://"))
+	http.Redirect(w, r, "/app/", http.StatusSeeOther)
+}
+
+func (s *server) handleDashboardLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.auth.SignOut(r.Context(), auth.SessionFromRequest(r)); err != nil {
+		s.renderDashboardError(w, r, sessionUser(r), err)
+		return
+	}
+
+	auth.ClearCookie(w)
+	http.Redirect(w, r, "/app/login", http.StatusSeeOther)
+}
+
+// handleDashboardCalendar shows ?month (YYYY-MM, default this month) with the
+// days that have entries, and the entry for ?day when given
+func (s *server) handleDashboardCalendar(w http.ResponseWriter, r *http.Request) {
+	user := sessionUser(r)
+	if r.URL.Path != "/app/" {
+		s.renderDashboardError(w, r, user, apperrors.New(apperrors.CodeNotFound, "page not found"))
+		return
+	}
+
+	today := userToday(user)
+	month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if value := r.URL.Query().Get("month"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err != nil {
+			s.renderDashboardError(w, r, user, apperrors.New(apperrors.CodeInvalidInput, "month must be YYYY-MM"))
+			return
+		}
+		month = parsed
+	}
+
+	page := &dashboardPage{
+		Title:     "Entries",
+		User:      user,
+		Month:     month.Format("January 2006"),
+		PrevMonth: month.AddDate(0, -1, 0).Format("2006-01"),
+		NextMonth: month.AddDate(0, 1, 0).Format("2006-01"),
+	}
+
+	if value := r.URL.Query().Get("day"); value != "" {
+		day, err := time.Parse(dateLayout, value)
+		if err != nil {
+			s.renderDashboardError(w, r, user, apperrors.New(apperrors.CodeInvalidInput, "day must be YYYY-MM-DD"))
+			return
+		}
+		page.Day = day.Format("Monday, January 2, 2006")
+		page.Entry, err = s.coreService.GetEntry(r.Context(), user.ID, day)
+		if err != nil && !apperrors.Is(err, apperrors.CodeNotFound) {
+			s.renderDashboardError(w, r, user, err)
+			return
+		}
+	}
+
+	entries, err := s.coreService.GetEntriesBetween(r.Context(), user.ID, month, month.AddDate(0, 1, 0))
+	if err != nil {
+		s.renderDashboardError(w, r, user, err)
+		return
+	}
+	hasEntry := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		hasEntry[entry.EntryDate.Format(dateLayout)] = true
+	}
+
+	firstDay := period.FirstWeekday(user.WeekStart)
+	for i := 0; i < 7; i++ {
+		page.Weekdays = append(page.Weekdays, time.Weekday((int(firstDay) + i) % 7).String()[:3])
+	}
+
+	selected := r.URL.Query().Get("day")
+	end := month.AddDate(0, 1, 0)
+	for day := period.StartOfWeek(month, firstDay); day.Before(end); {
+		week := make([]calendarDay, 0, 7)
+		for i := 0; i < 7; i++ {
+			date := day.Format(dateLayout)
+			week = append(week, calendarDay{
+				Date:     date,
+				Day:      day.Day(),
+				InMonth:  day.Month() == month.Month(),
+				Today:    day.Equal(today),
+				HasEntry: hasEntry[date],
+				Selected: date == selected,
+			})
+			day = day.AddDate(0, 0, 1)
+		}
+		page.Weeks = append(page.Weeks, week)
+	}
+
+	s.renderDashboard(w, r, http.StatusOK, "calendar", page)
+}
+
+// handleDashboardSummaries lists past weekly summaries and shows the one for
+// the week containing ?week (YYYY-MM-DD), or the latest
+func (s *server) handleDashboardSummaries(w http.ResponseWriter, r *http.Request) {
+	user := sessionUser(r)
+
+	summaries, err := s.coreService.ListWeeklySummaries(r.Context(), user.ID, maxSummariesLimit)
+	if err != nil {
+		s.renderDashboardError(w, r, user, err)
+		return
+	}
+
+	page := &dashboardPage{Title: "Summaries", User: user, Summaries: summaries}
+	if value := r.URL.Query().Get("week"); value != "" {
+		day, err := time.Parse(dateLayout, value)
+		if err != nil {
+			s.renderDashboardError(w, r, user, apperrors.New(apperrors.CodeInvalidInput, "week must be YYYY-MM-DD"))
+			return
+		}
+		weekStart := period.StartOfWeek(day, period.FirstWeekday(user.WeekStart))
+		page.Summary, err = s.coreService.GetWeeklySummary(r.Context(), user.ID, weekStart)
+		if err != nil {
+			s.renderDashboardError(w, r, user, err)
+			return
+		}
+		if page.Summary == nil {
+			page.Notice = "No summary for the week of " + weekStart.Format("January 2, 2006") + "."
+		}
+	} else if len(summaries) > 0 {
+		page.Summary = summaries[0]
+	}
+
+	s.renderDashboard(w, r, http.StatusOK, "summaries", page)
+}
+
+// handleDashboardPreferences shows and saves the preferences form. Only
+// fields that changed are saved, so the schedule confirmation email goes out
+// only when the timezone or prompt time actually changes.
+func (s *server) handleDashboardPreferences(w http.ResponseWriter, r *http.Request) {
+	user := sessionUser(r)
+	page := &dashboardPage{
+		Title:        "Preferences",
+		User:         user,
+		Prefs:        models.PreferencesFor(user),
+		EntryFormats: entryformat.Names(),
+		Languages:    language.All(),
+		PauseOptions: pauseOptions,
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		switch r.URL.Query().Get("done") {
+		case "saved":
+			page.Notice = "Preferences saved."
+		case "paused":
+			page.Notice = "Prompts paused."
+		case "resumed":
+			page.Notice = "Prompts resumed."
+		}
+		s.renderDashboard(w, r, http.StatusOK, "preferences", page)
+	case http.MethodPost:
+		_, err := s.coreService.UpdatePreferences(r.Context(), user.ID, preferencesFromForm(r, page.Prefs))
+		if apperrors.Is(err, apperrors.CodeInvalidInput) {
+			page.Error = err.Error()
+			s.renderDashboard(w, r, http.StatusBadRequest, "preferences", page)
+			return
+		}
+		if err != nil {
+			s.renderDashboardError(w, r, user, err)
+			return
+		}
+		http.Redirect(w, r, "/app/preferences?done=saved", http.StatusSeeOther)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// preferencesFromForm returns the form fields that differ from current. A
+// blank field is left unchanged, except the project focus, which it clears.
+func preferencesFromForm(r *http.Request, current *models.Preferences) models.PreferencesUpdate {
+	changed := func(field, current string, clearable bool) *string {
+		value := strings.TrimSpace(r.FormValue(field))
+		if value == current || (value == "" && !clearable) {
+			return nil
+		}
+		return &value
+	}
+
+	focus := ""
+	if current.ProjectFocus != nil {
+		focus = *current.ProjectFocus
+	}
+
+	update := models.PreferencesUpdate{
+		Name:            changed("name", current.Name, false),
+		Timezone:        changed("timezone", current.Timezone, false),
+		PromptTime:      changed("prompt_time", current.PromptTime, false),
+		ProjectFocus:    changed("project_focus", focus, true),
+		WeekStart:       changed("week_start", current.WeekStart, false),
+		EntryFormat:     changed("entry_format", current.EntryFormat, false),
+		SummaryVoice:    changed("summary_voice", current.SummaryVoice, false),
+		Language:        changed("language", current.Language, false),
+		SummaryLanguage: changed("summary_language", current.SummaryLanguage, false),
+		PromptThreading: changed("prompt_threading", current.PromptThreading, false),
+	}
+
+	// Unchecked boxes aren't submitted at all
+	if quotes := r.FormValue("quotes_enabled") != ""; quotes != current.QuotesEnabled {
+		update.QuotesEnabled = &quotes
+	}
+	if compare := r.FormValue("compare_weeks") != ""; compare != current.CompareWeeks {
+		update.CompareWeeks = &compare
+	}
+	if goals := r.FormValue("weekly_goals") != ""; goals != current.WeeklyGoals {
+		update.WeeklyGoals = &goals
+	}
+	if tracking := r.FormValue("email_tracking") != ""; tracking != current.EmailTracking {
+		update.EmailTracking = &tracking
+	}
+	return update
+}
+
+// handleDashboardPause pauses prompts for one of pauseOptions days
+func (s *server) handleDashboardPause(w http.ResponseWriter, r *http.Request) {
+	user := sessionUser(r)
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days, err := strconv.Atoi(r.FormValue("days"))
+	if err != nil || !validPauseOption(days) {
+		s.renderDashboardError(w, r, user, apperrors.New(apperrors.CodeInvalidInput, "invalid pause length"))
+		return
+	}
+
+	if err := s.coreService.PauseUser(r.Context(), user.ID, time.Now().AddDate(0, 0, days)); err != nil {
+		s.renderDashboardError(w, r, user, err)
+		return
+	}
+	http.Redirect(w, r, "/app/preferences?done=paused", http.StatusSeeOther)
+}
+
+func validPauseOption(days int) bool {
+	for _, option := range pauseOptions {
+		if days == option {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *server) handleDashboardResume(w http.ResponseWriter, r *http.Request) {
+	user := sessionUser(r)
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.coreService.ResumeUser(r.Context(), user.ID); err != nil {
+		s.renderDashboardError(w, r, user, err)
+		return
+	}
+	http.Redirect(w, r, "/app/preferences?done=resumed", http.StatusSeeOther)
+}
+
+// userToday returns today's date in the user's timezone, at midnight UTC like entry dates
+func userToday(user *models.User) time.Time {
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// renderDashboard writes page, adding the CSRF token its forms need when
+// the user is signed in
+func (s *server) renderDashboard(w http.ResponseWriter, r *http.Request, status int, name string, page *dashboardPage) {
+	if page.User != nil {
+		page.CSRFToken = s.auth.CSRFToken(auth.SessionFromRequest(r))
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := s.dashboardTemplates[name].ExecuteTemplate(w, "layout", page); err != nil {
+		logrus.WithError(err).WithField("page", name).Error("Failed to render dashboard page")
+	}
+}
+
+// renderDashboardError shows err on the error page with the status mapped
+// from its code. As in writeAppError, internal errors are logged and not shown.
+func (s *server) renderDashboardError(w http.ResponseWriter, r *http.Request, user *models.User, err error) {
+	code := apperrors.CodeOf(err)
+	message := err.Error()
+	if code == apperrors.CodeInternal {
+		logrus.WithError(err).WithField("error_code", code).Error("Dashboard request failed")
+		message = "Something went wrong. Please try again."
+	}
+	s.renderDashboard(w, r, apperrors.HTTPStatus(code), "error", &dashboardPage{Title: "Error", User: user, Error: message})
+}
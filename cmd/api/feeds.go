@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/feeds"
+)
+
+// feedResponse is the body of the /v1/me/feed requests. The URLs are only
+// returned when the feed is enabled or rotated.
+type feedResponse struct {
+	Enabled       bool       `json:"enabled"`
+	AtomURL       string     `json:"atom_url,omitempty"`
+	RSSURL        string     `json:"rss_url,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+}
+
+// baseURL is where feed URLs point: DASHBOARD_URL, or the host the request
+// was made to
+func (s *server) baseURL(r *http.Request) string {
+	if s.cfg.DashboardURL != "" {
+		return s.cfg.DashboardURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// handleSummaryFeed serves the token user's summary feed: GET tells whether
+// it is on, POST turns it on or rotates its URL, and DELETE turns it off
+func (s *server) handleSummaryFeed(w http.ResponseWriter, r *http.Request) {
+	user := tokenUser(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		feed, err := s.coreService.GetSummaryFeed(r.Context(), user.ID)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		if feed == nil {
+			writeJSON(w, http.StatusOK, feedResponse{})
+			return
+		}
+		writeJSON(w, http.StatusOK, feedResponse{Enabled: true, CreatedAt: &feed.CreatedAt, LastFetchedAt: feed.LastFetchedAt})
+	case http.MethodPost:
+		token, err := s.coreService.EnableSummaryFeed(r.Context(), user)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		base := s.baseURL(r) + feeds.Path + token
+		writeJSON(w, http.StatusCreated, feedResponse{
+			Enabled: true,
+			AtomURL: base + "." + feeds.FormatAtom,
+			RSSURL:  base + "." + feeds.FormatRSS,
+		})
+	case http.MethodDelete:
+		if _, err := s.coreService.DisableSummaryFeed(r.Context(), user.ID); err != nil {
+			writeAppError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleFeed serves a summary feed. It needs no session or API token: the
+// token in the URL names the feed, so the URL itself is the secret.
+func (s *server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, feeds.Path)
+	format := strings.TrimPrefix(path.Ext(name), ".")
+	contentType, ok := feeds.ContentTypes[format]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	user, summaries, err := s.coreService.SummaryFeedFor(r.Context(), strings.TrimSuffix(name, "."+format))
+	if apperrors.Is(err, apperrors.CodeNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to load summary feed")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	feed := &feeds.Feed{
+		User:         user,
+		Summaries:    summaries,
+		Self:         s.baseURL(r) + r.URL.Path,
+		DashboardURL: s.cfg.DashboardURL,
+	}
+	var body bytes.Buffer
+	if err := feed.Write(&body, format); err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to render summary feed")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/core"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/graphql"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// maxSummariesLimit caps summaries(limit:)
+const maxSummariesLimit = 52
+
+// maxGraphQLBody bounds a POSTed request; the parser also caps nesting
+const maxGraphQLBody = 64 << 10
+
+type userContextKey struct{}
+
+// tokenUser returns the user authenticated by requireUserToken
+func tokenUser(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userContextKey{}).(*models.User)
+	return user
+}
+
+// requireUserToken authenticates a per-user API token sent as a bearer token.
+// Every resolver is scoped to that user.
+func (s *server) requireUserToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		user, err := s.coreService.AuthenticateAPIToken(r.Context(), token)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), userContextKey{}, user)))
+	}
+}
+
+// handleGraphQL serves POST requests with a JSON body and GET requests with
+// the query in ?query=
+func (s *server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid variables JSON")
+				return
+			}
+		}
+		if strings.HasPrefix(strings.TrimSpace(req.Query), "mutation") {
+			writeError(w, http.StatusMethodNotAllowed, "mutations require POST")
+			return
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLBody)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	resp := graphql.Execute(r.Context(), s.graphqlSchema, req)
+
+	// Resolver failures without a code are not echoed back, as in writeAppError
+	for _, gqlErr := range resp.Errors {
+		if gqlErr.Unwrap() != nil && apperrors.CodeOf(gqlErr) == apperrors.CodeInternal {
+			logrus.WithError(gqlErr.Unwrap()).WithField("path", gqlErr.Path).Error("GraphQL resolver failed")
+			gqlErr.Message = "internal error"
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// graphqlStats is the Stats type: the energy trend for a week plus lifetime counts
+type graphqlStats struct {
+	WeekStart          string              `json:"week_start"`
+	WeekSparkline      string              `json:"week_sparkline"`
+	MonthSparkline     string              `json:"month_sparkline"`
+	Weeks              []graphqlWeekEnergy `json:"weeks"`
+	EntryCount         int                 `json:"entry_count"`
+	FirstEntryDate     *time.Time          `json:"first_entry_date"`
+	LastEntryDate      *time.Time          `json:"last_entry_date"`
+	WeeklySummaryCount int                 `json:"weekly_summary_count"`
+}
+
+type graphqlWeekEnergy struct {
+	WeekStart string  `json:"week_start"`
+	Entries   int     `json:"entries"`
+	Energy    float64 `json:"energy"`
+}
+
+// newGraphQLSchema builds the dashboard schema:
+//
+//	type Query {
+//	  preferences: Preferences
+//	  entries(from: String, to: String): [Entry]
+//	  summary(week: String): Summary
+//	  summaries(limit: Int): [Summary]
+//	  stats(week: String): Stats
+//	}
+//	type Mutation {
+//	  updatePreferences(name: String, timezone: String, prompt_time: String,
+//	    project_focus: String, week_start: String, entry_format: String, summary_voice: String,
+//	    language: String, summary_language: String, quotes_enabled: Boolean, compare_weeks: Boolean,
+//	    prompt_threading: String, weekly_goals: Boolean, email_tracking: Boolean): Preferences
+//	}
+//
+// Dates are YYYY-MM-DD. Object fields use the same names as the REST API.
+func newGraphQLSchema(coreService *core.Service) *graphql.Schema {
+	preferences := graphql.NewObject("Preferences", models.Preferences{})
+	entry := graphql.NewObject("Entry", models.Entry{})
+	summary := graphql.NewObject("Summary", models.WeeklySummary{})
+	weekEnergy := graphql.NewObject("WeekEnergy", graphqlWeekEnergy{})
+	statsType := graphql.NewObject("Stats", graphqlStats{})
+	statsType.Fields["weeks"] = &graphql.FieldDef{Type: weekEnergy}
+
+	query := graphql.NewObject("Query", nil)
+	query.Fields["preferences"] = &graphql.FieldDef{
+		Type: preferences,
+		Resolve: func(ctx context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+			return models.PreferencesFor(tokenUser(ctx)), nil
+		},
+	}
+	query.Fields["entries"] = &graphql.FieldDef{
+		Type: entry,
+		Args: []string{"from", "to"},
+		Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+			to := time.Now().UTC().Truncate(24 * time.Hour)
+			from := to.AddDate(0, 0, -6)
+			if err := dateArg(args, "from", &from); err != nil {
+				return nil, err
+			}
+			if err := dateArg(args, "to", &to); err != nil {
+				return nil, err
+			}
+			return coreService.GetEntriesBetween(ctx, tokenUser(ctx).ID, from, to.AddDate(0, 0, 1))
+		},
+	}
+	query.Fields["summary"] = &graphql.FieldDef{
+		Type: summary,
+		Args: []string{"week"},
+		Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+			user := tokenUser(ctx)
+			weekStart, err := weekArg(args, user)
+			if err != nil {
+				return nil, err
+			}
+			return coreService.GetWeeklySummary(ctx, user.ID, weekStart)
+		},
+	}
+	query.Fields["summaries"] = &graphql.FieldDef{
+		Type: summary,
+		Args: []string{"limit"},
+		Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+			limit, ok, err := graphql.IntArg(args, "limit")
+			if err != nil {
+				return nil, err
+			}
+			if !ok || limit <= 0 || limit > maxSummariesLimit {
+				limit = maxSummariesLimit
+			}
+			return coreService.ListWeeklySummaries(ctx, tokenUser(ctx).ID, limit)
+		},
+	}
+	query.Fields["stats"] = &graphql.FieldDef{
+		Type: statsType,
+		Args: []string{"week"},
+		Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+			user := tokenUser(ctx)
+			weekStart, err := weekArg(args, user)
+			if err != nil {
+				return nil, err
+			}
+			return buildGraphQLStats(ctx, coreService, user, weekStart)
+		},
+	}
+
+	mutation := graphql.NewObject("Mutation", nil)
+	mutation.Fields["updatePreferences"] = &graphql.FieldDef{
+		Type: preferences,
+		Args: []string{"name", "timezone", "prompt_time", "project_focus", "week_start", "entry_format", "summary_voice", "language", "summary_language", "prompt_threading", "quotes_enabled", "compare_weeks", "weekly_goals", "email_tracking"},
+		Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+			var update models.PreferencesUpdate
+			for name, field := range map[string]**string{
+				"name":             &update.Name,
+				"timezone":         &update.Timezone,
+				"prompt_time":      &update.PromptTime,
+				"project_focus":    &update.ProjectFocus,
+				"week_start":       &update.WeekStart,
+				"entry_format":     &update.EntryFormat,
+				"summary_voice":    &update.SummaryVoice,
+				"language":         &update.Language,
+				"summary_language": &update.SummaryLanguage,
+				"prompt_threading": &update.PromptThreading,
+			} {
+				value, ok, err := graphql.StringArg(args, name)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					*field = &value
+				}
+			}
+			for name, field := range map[string]**bool{
+				"quotes_enabled": &update.QuotesEnabled,
+				"compare_weeks":  &update.CompareWeeks,
+				"weekly_goals":   &update.WeeklyGoals,
+				"email_tracking": &update.EmailTracking,
+			} {
+				value, ok, err := graphql.BoolArg(args, name)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					*field = &value
+				}
+			}
+			return coreService.UpdatePreferences(ctx, tokenUser(ctx).ID, update)
+		},
+	}
+
+	return &graphql.Schema{Query: query, Mutation: mutation}
+}
+
+func buildGraphQLStats(ctx context.Context, coreService *core.Service, user *models.User, weekStart time.Time) (*graphqlStats, error) {
+	trend, err := coreService.BuildEnergyTrend(ctx, user.ID, weekStart)
+	if err != nil {
+		return nil, err
+	}
+	report, err := coreService.BuildDataReport(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &graphqlStats{
+		WeekStart:          weekStart.Format(dateLayout),
+		WeekSparkline:      trend.Week,
+		MonthSparkline:     trend.MonthSparkline(),
+		EntryCount:         report.EntryCount,
+		FirstEntryDate:     report.FirstEntryDate,
+		LastEntryDate:      report.LastEntryDate,
+		WeeklySummaryCount: report.WeeklySummaryCount,
+	}
+	for _, week := range trend.Month {
+		result.Weeks = append(result.Weeks, graphqlWeekEnergy{
+			WeekStart: week.WeekStart.Format(dateLayout),
+			Entries:   week.Entries,
+			Energy:    week.Energy,
+		})
+	}
+	return result, nil
+}
+
+// dateArg parses a YYYY-MM-DD argument into dst when it is given
+func dateArg(args map[string]interface{}, name string, dst *time.Time) error {
+	value, ok, err := graphql.StringArg(args, name)
+	if err != nil || !ok {
+		return err
+	}
+	parsed, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return apperrors.New(apperrors.CodeInvalidInput, "argument %q must be YYYY-MM-DD", name)
+	}
+	*dst = parsed
+	return nil
+}
+
+// weekArg returns the start of the user's week containing the week argument,
+// defaulting to the current week
+func weekArg(args map[string]interface{}, user *models.User) (time.Time, error) {
+	day := time.Now().UTC()
+	if err := dateArg(args, "week", &day); err != nil {
+		return time.Time{}, err
+	}
+	return period.StartOfWeek(day, period.FirstWeekday(user.WeekStart)), nil
+}
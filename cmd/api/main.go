@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/analytics"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/auth"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/billing"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/core"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/embeddings"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/events"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/feeds"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/graphql"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/integrations/msteams"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/integrations/slack"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/jobqueue"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/llm"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/orgs"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/quotes"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/webhooks"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+type server struct {
+	cfg           *config.Config
+	emailService  *email.Service
+	coreService   *core.Service
+	analytics     *analytics.Service
+	orgs          *orgs.Service
+	billing       *billing.Service
+	custody       *custody.Service
+	graphqlSchema *graphql.Schema
+
+	auth               *auth.Service
+	dashboardTemplates map[string]*template.Template
+}
+
+func main() {
+	logrus.SetLevel(logrus.InfoLevel)
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+
+	cfg, err := config.Load()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load config")
+	}
+
+	db, err := database.New(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to connect to database")
+	}
+	defer db.Close()
+
+	emailService, err := email.NewService(db, cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create email service")
+	}
+
+	bus, err := events.NewBus(context.Background(), cfg.EventBus, cfg.AWSRegion, cfg.EventBusTarget)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create event bus")
+	}
+
+	webhookService := webhooks.NewService(db)
+	bus.Subscribe("webhooks", webhookService.HandleEvent)
+	emailService.SetEvents(bus)
+
+	queue, err := jobqueue.New(cfg, db)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create job queue")
+	}
+	defer queue.Close()
+	emailService.SetQueue(queue)
+
+	coreService := core.NewService(db, emailService)
+	coreService.SetEvents(bus)
+	coreService.SetEntryMergeWindow(cfg.EntryMergeWindow)
+	coreService.SetEmailChangeAliasWindow(cfg.EmailChangeAliasWindow)
+	coreService.SetRetentionPolicies(cfg.RetentionPolicies)
+	coreService.SetClarificationPolicy(core.ClarificationPolicy{
+		MaxAttempts: cfg.ClarificationMaxAttempts,
+		ResetAfter:  cfg.ClarificationResetAfter,
+		AdminEmail:  cfg.ClarificationAdminEmail,
+	})
+
+	llmService, err := llm.NewService(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create LLM service")
+	}
+	llmService.SetCallLog(db)
+	coreService.SetLLM(llmService)
+
+	embeddingsService, err := embeddings.NewService(db, cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create embeddings service")
+	}
+	coreService.SetEmbeddings(embeddingsService)
+
+	billingService, err := billing.NewService(db, emailService, cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create billing service")
+	}
+
+	srv := &server{
+		cfg:           cfg,
+		emailService:  emailService,
+		coreService:   coreService,
+		analytics:     analytics.NewService(db),
+		orgs:          orgs.NewService(db),
+		billing:       billingService,
+		custody:       custody.NewService(db),
+		graphqlSchema: newGraphQLSchema(coreService),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/signup", srv.requireAdmin(srv.handleSignup))
+	mux.HandleFunc("/v1/entries", srv.requireAdmin(srv.handleEntries))
+	mux.HandleFunc("/v1/summaries", srv.requireAdmin(srv.handleSummary))
+	mux.HandleFunc("/v1/preferences", srv.requireAdmin(srv.handlePreferences))
+	mux.HandleFunc("/v1/data-report", srv.requireAdmin(srv.handleDataReport))
+	mux.HandleFunc("/v1/quotes", srv.requireAdmin(srv.handleQuotes))
+	mux.HandleFunc("/v1/quotes/", srv.requireAdmin(srv.handleQuote))
+	mux.HandleFunc("/v1/analytics", srv.requireAdmin(srv.handleAnalytics))
+	mux.HandleFunc("/v1/outbox", srv.requireAdmin(srv.handleOutbox))
+	mux.HandleFunc("/v1/orgs/", srv.requireAdmin(srv.handleOrg))
+	mux.HandleFunc("/v1/graphql", srv.requireUserToken(srv.handleGraphQL))
+	mux.HandleFunc("/v1/me/key", srv.requireUserToken(srv.handleKey))
+	mux.HandleFunc("/v1/me/key/self-custody", srv.requireUserToken(srv.handleSelfCustody))
+	mux.HandleFunc("/v1/me/emails", srv.requireUserToken(srv.handleEmailAliases))
+	mux.HandleFunc("/v1/me/emails/verify", srv.requireUserToken(srv.handleVerifyEmailAlias))
+	mux.HandleFunc("/v1/me/feed", srv.requireUserToken(srv.handleSummaryFeed))
+	mux.HandleFunc(feeds.Path, srv.handleFeed)
+	mux.HandleFunc("/v1/quick-entry", srv.handleQuickEntry)
+
+	if cfg.DashboardURL != "" {
+		srv.auth, err = auth.NewService(db, emailService, cfg.AuthSecret)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to create auth service")
+		}
+		srv.dashboardTemplates, err = parseDashboardTemplates()
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to load dashboard templates")
+		}
+		srv.registerDashboard(mux)
+	}
+
+	if cfg.EmailTracking {
+		srv.registerTracking(mux)
+	}
+
+	if billingService != nil {
+		mux.HandleFunc("/v1/billing/stripe/webhook", srv.handleStripeWebhook)
+	}
+
+	if cfg.MSTeamsSecurityToken != "" {
+		teamsHandler, err := msteams.NewHandler(cfg.MSTeamsSecurityToken, func(ctx context.Context, externalUserID, text string) error {
+			return srv.coreService.HandleChannelReply(ctx, models.DeliveryChannelMSTeams, externalUserID, text)
+		})
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to create Teams handler")
+		}
+		mux.Handle("/v1/integrations/msteams/messages", teamsHandler)
+	}
+
+	if cfg.SlackSigningSecret != "" {
+		mux.Handle("/v1/integrations/slack", slack.NewHandler(cfg.SlackSigningSecret,
+			func(ctx context.Context, externalUserID string, msg slack.Message) error {
+				return srv.coreService.ImportChannelMessage(ctx, models.ChannelSlack, externalUserID, msg.ID(), msg.Text, msg.SentAt)
+			},
+			func(ctx context.Context, externalUserID, text string) error {
+				return srv.coreService.HandleChannelReply(ctx, models.ChannelSlack, externalUserID, text)
+			}))
+	}
+
+	httpServer := &http.Server{
+		Addr:              cfg.APIAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		logrus.WithField("addr", cfg.APIAddr).Info("API server started")
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.WithError(err).Fatal("API server failed")
+		}
+	}()
+
+	// Wait for interrupt signal
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	<-c
+
+	logrus.Info("Shutting down API server...")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		logrus.WithError(err).Error("Failed to shut down API server cleanly")
+	}
+}
+
+// requireAdmin rejects requests that don't carry the admin API key as a bearer token
+func (s *server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.cfg.AdminAPIKey == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.AdminAPIKey)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleDataReport returns counts of everything stored about a user
+func (s *server) handleDataReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, ok := s.lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := s.coreService.BuildDataReport(r.Context(), user)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// quoteRequest is the body for creating a quote
+type quoteRequest struct {
+	Text   string  `json:"text"`
+	Author *string `json:"author"`
+}
+
+// handleQuotes lists quotes (?all=true includes inactive ones) or adds one
+func (s *server) handleQuotes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.coreService.Quotes().List(r.Context(), r.URL.Query().Get("all") == "true")
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	case http.MethodPost:
+		var req quoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		quote, err := s.coreService.Quotes().Add(r.Context(), req.Text, req.Author, nil)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, quote)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleQuote updates (PATCH) or deletes (DELETE) /v1/quotes/{id}
+func (s *server) handleQuote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/v1/quotes/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid quote id")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		var update quotes.Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		quote, err := s.coreService.Quotes().Update(r.Context(), id, update)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, quote)
+	case http.MethodDelete:
+		if err := s.coreService.Quotes().Remove(r.Context(), id); err != nil {
+			writeAppError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logrus.WithError(err).Error("Failed to encode response")
+	}
+}
+
+// errorResponse is the JSON body returned for failed requests
+type errorResponse struct {
+	Error string         `json:"error"`
+	Code  apperrors.Code `json:"code"`
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message, Code: codeForStatus(status)})
+}
+
+// writeAppError logs err and responds with the status mapped from its error code.
+// Internal errors are not echoed back to the client.
+func writeAppError(w http.ResponseWriter, err error) {
+	code := apperrors.CodeOf(err)
+	status := apperrors.HTTPStatus(code)
+
+	message := err.Error()
+	if code == apperrors.CodeInternal {
+		logrus.WithError(err).WithField("error_code", code).Error("Request failed")
+		message = "internal error"
+	}
+
+	writeJSON(w, status, errorResponse{Error: message, Code: code})
+}
+
+func codeForStatus(status int) apperrors.Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusMethodNotAllowed:
+		return apperrors.CodeInvalidInput
+	case http.StatusUnauthorized:
+		return apperrors.CodeUnauthorized
+	default:
+		return apperrors.CodeInternal
+	}
+}
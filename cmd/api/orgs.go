@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/billing"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+)
+
+// maxStripeWebhookBody bounds a Stripe webhook request
+const maxStripeWebhookBody = 1 << 20
+
+// handleOrg routes /v1/orgs/{id}/usage, /v1/orgs/{id}/billing and
+// /v1/orgs/{id}/checkout
+func (s *server) handleOrg(w http.ResponseWriter, r *http.Request) {
+	idPart, resource, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/orgs/"), "/")
+	id, err := strconv.Atoi(idPart)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid organization id")
+		return
+	}
+
+	switch {
+	case resource == "usage":
+		s.handleOrgUsage(w, r, id)
+	case resource == "billing" && s.billing != nil:
+		s.handleOrgBilling(w, r, id)
+	case resource == "checkout" && s.billing != nil:
+		s.handleOrgCheckout(w, r, id)
+	default:
+		writeAppError(w, apperrors.New(apperrors.CodeNotFound, "not found: %s", r.URL.Path))
+	}
+}
+
+// handleOrgUsage returns GET /v1/orgs/{id}/usage: the organization's metered
+// usage for ?month=YYYY-MM (default this month) with its daily records
+func (s *server) handleOrgUsage(w http.ResponseWriter, r *http.Request, id int) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	month := time.Now().UTC()
+	if value := r.URL.Query().Get("month"); value != "" {
+		var err error
+		if month, err = time.Parse("2006-01", value); err != nil {
+			writeError(w, http.StatusBadRequest, "month must be YYYY-MM")
+			return
+		}
+	}
+
+	usage, err := s.orgs.Usage(r.Context(), id, month)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// handleOrgBilling returns GET /v1/orgs/{id}/billing: the organization's
+// subscription and whether it is suspended
+func (s *server) handleOrgBilling(w http.ResponseWriter, r *http.Request, id int) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	status, err := s.billing.Status(r.Context(), id)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// checkoutRequest is the body of POST /v1/orgs/{id}/checkout. The URLs
+// default to the dashboard.
+type checkoutRequest struct {
+	Email      string `json:"email"`
+	SuccessURL string `json:"success_url"`
+	CancelURL  string `json:"cancel_url"`
+}
+
+// handleOrgCheckout starts a Stripe Checkout session subscribing the
+// organization and returns its URL to send the billing contact to
+func (s *server) handleOrgCheckout(w http.ResponseWriter, r *http.Request, id int) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req checkoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	session, err := s.billing.Checkout(r.Context(), id, req.Email, req.SuccessURL, req.CancelURL)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, session)
+}
+
+// handleStripeWebhook applies a Stripe webhook event. It needs no API key:
+// the event is signed with STRIPE_WEBHOOK_SECRET.
+func (s *server) handleStripeWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxStripeWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	if err := s.billing.HandleWebhook(r.Context(), r.Header.Get(billing.SignatureHeader), payload); err != nil {
+		logrus.WithError(err).Warn("Failed to handle Stripe webhook")
+		writeAppError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+)
+
+// outboxStatusResponse adds how long the oldest due email has waited, and
+// whether that is past OUTBOX_STUCK_AFTER, to the outbox counts
+type outboxStatusResponse struct {
+	*email.OutboxStatus
+	OldestDueAgeSeconds int64 `json:"oldest_due_age_seconds"`
+	Stuck               bool  `json:"stuck"`
+}
+
+// handleOutbox reports pending, due and claimed emails by type
+func (s *server) handleOutbox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	status, err := s.emailService.OutboxStatus(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	age := status.OldestDueAge(time.Now())
+	writeJSON(w, http.StatusOK, outboxStatusResponse{
+		OutboxStatus:        status,
+		OldestDueAgeSeconds: int64(age / time.Second),
+		Stuck:               s.cfg.OutboxStuckAfter > 0 && age >= s.cfg.OutboxStuckAfter,
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// maxQuickEntryBody bounds the request body; entries themselves are capped
+// by the core service
+const maxQuickEntryBody = 64 << 10
+
+// quickEntryRequest is the body of a quick entry. The token may instead be
+// sent as a bearer token; automation apps make either easy.
+type quickEntryRequest struct {
+	Token string `json:"token"`
+	Text  string `json:"text"`
+}
+
+// handleQuickEntry saves a dictated entry from a phone automation (iOS
+// Shortcuts, Tasker) authenticated by a per-user API token, and returns the
+// streak with a message ready to show in a notification
+func (s *server) handleQuickEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req quickEntryRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxQuickEntryBody)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	token := req.Token
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	user, err := s.coreService.AuthenticateAPIToken(r.Context(), token)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	result, err := s.coreService.QuickEntry(r.Context(), user, req.Text)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, result)
+}
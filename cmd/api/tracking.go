@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+)
+
+// registerTracking serves the open pixel and click redirects of tracked
+// summaries. Neither needs a session: the email's token names it.
+func (s *server) registerTracking(mux *http.ServeMux) {
+	mux.HandleFunc(email.TrackOpenPath, s.handleTrackOpen)
+	mux.HandleFunc(email.TrackClickPath, s.handleTrackClick)
+}
+
+// handleTrackOpen records an open and returns the pixel. The pixel is
+// returned whatever happens, so a mail client never shows a broken image.
+func (s *server) handleTrackOpen(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, email.TrackOpenPath), ".gif")
+	if err := s.emailService.RecordOpen(r.Context(), token); err != nil {
+		logrus.WithError(err).Warn("Failed to record email open")
+	}
+
+	w.Header().Set("Content-Type", "image/gif")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(email.TrackingPixel)
+}
+
+// handleTrackClick records a click and redirects to the link
+func (s *server) handleTrackClick(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, email.TrackClickPath)
+	query := r.URL.Query()
+
+	target, err := s.emailService.RecordClick(r.Context(), token, query.Get("u"), query.Get("s"))
+	if err != nil {
+		http.Error(w, "invalid link", http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
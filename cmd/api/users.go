@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/entries"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// signupRequest is the body for starting a signup
+type signupRequest struct {
+	Email string `json:"email"`
+}
+
+// handleSignup sends the welcome email with a verification code, exactly as
+// an email to the signup address would
+func (s *server) handleSignup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req signupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	emailAddr := strings.ToLower(strings.TrimSpace(req.Email))
+	if emailAddr == "" {
+		writeError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	if err := s.coreService.HandleSignupRequest(r.Context(), emailAddr); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleEntries lists a page of a user's entries, oldest first, filtered by
+// from and to (YYYY-MM-DD, inclusive), project and q (full-text, every word).
+// page is the next_page cursor of the previous response and limit the page
+// size, up to entries.MaxPageSize.
+func (s *server) handleEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, ok := s.lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	params := r.URL.Query()
+	filter, err := entries.ParseFilter(params.Get("from"), params.Get("to"), params.Get("project"), params.Get("q"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	limit := 0
+	if value := params.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+	}
+
+	page, err := s.coreService.ListEntries(r.Context(), user.ID, filter, params.Get("page"), limit)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handleSummary returns the archived summary for the week containing ?week
+// (YYYY-MM-DD, default the current week) using the user's week start
+func (s *server) handleSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, ok := s.lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	day := time.Now().UTC()
+	if value := r.URL.Query().Get("week"); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "week must be YYYY-MM-DD")
+			return
+		}
+		day = parsed
+	}
+	weekStart := period.StartOfWeek(day, period.FirstWeekday(user.WeekStart))
+
+	summary, err := s.coreService.GetWeeklySummary(r.Context(), user.ID, weekStart)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	if summary == nil {
+		writeAppError(w, apperrors.New(apperrors.CodeNotFound, "no summary for week of %s", weekStart.Format(dateLayout)))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// handlePreferences returns (GET) or updates (PATCH) a user's preferences
+func (s *server) handlePreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPatch {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, ok := s.lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, models.PreferencesFor(user))
+		return
+	}
+
+	var update models.PreferencesUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	prefs, err := s.coreService.UpdatePreferences(r.Context(), user.ID, update)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+// lookupUser resolves the ?email query parameter, writing the error response
+// and returning false when it is missing or unknown
+func (s *server) lookupUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	emailAddr := r.URL.Query().Get("email")
+	if emailAddr == "" {
+		writeError(w, http.StatusBadRequest, "email query parameter is required")
+		return nil, false
+	}
+
+	user, err := s.emailService.GetUserByEmail(r.Context(), emailAddr)
+	if err != nil {
+		writeAppError(w, err)
+		return nil, false
+	}
+	if user == nil {
+		writeAppError(w, apperrors.New(apperrors.CodeUserNotFound, "user not found"))
+		return nil, false
+	}
+
+	return user, true
+}
@@ -4,60 +4,85 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"html"
+	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/sirupsen/logrus"
 
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/analytics"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/anomalies"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/billing"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/auth"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/backup"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/core"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/digest"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/embeddings"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/entries"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/events"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/feeds"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/importers"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/inbound"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/infra"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/integrations/credentials"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/integrations/msteams"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/integrations/slack"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/jobqueue"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/jobs"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/llm"
-	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/models"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/llm/evals"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/orgs"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/quotes"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/retention"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/seed"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/sharecard"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/stats"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/webhooks"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
 )
 
 var (
-	cfg          *config.Config
-	db           *database.DB
-	emailService *email.Service
-	coreService  *core.Service
-	llmService   *llm.Service
+	cfg               *config.Config
+	db                *database.DB
+	emailService      *email.Service
+	coreService       *core.Service
+	llmService        *llm.Service
+	webhookService    *webhooks.Service
+	embeddingsService *embeddings.Service
+	credentialStore   *credentials.Store
 )
 
 func main() {
-	var err error
-	
-	cfg, err = config.Load()
-	if err != nil {
-		logrus.WithError(err).Fatal("Failed to load config")
-	}
-
-	db, err = database.New(cfg)
-	if err != nil {
-		logrus.WithError(err).Fatal("Failed to connect to database")
-	}
-	defer db.Close()
-
-	emailService, err = email.NewService(db, cfg)
-	if err != nil {
-		logrus.WithError(err).Fatal("Failed to create email service")
-	}
-
-	coreService = core.NewService(db, emailService)
-
-	llmService, err = llm.NewService(cfg)
-	if err != nil {
-		logrus.WithError(err).Fatal("Failed to create LLM service")
-	}
+	defer func() {
+		if db != nil {
+			db.Close()
+		}
+	}()
 
 	rootCmd := &cobra.Command{
 		Use:   "whatdidyougetdone",
 		Short: "CLI for What Did You Get Done This Week journaling service",
 		Long:  "Command line interface for managing the What Did You Get Done This Week email journaling service",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if !skipsServices(cmd) {
+				initServices()
+			}
+		},
 	}
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
 	// Verify subcommands
 	verifyCmd := &cobra.Command{
@@ -121,6 +146,83 @@ func main() {
 		},
 	})
 
+	outboxCmd := &cobra.Command{
+		Use:   "outbox",
+		Short: "Inspect the email outbox",
+	}
+	outboxCmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show pending and due emails by type, and how long the oldest has waited",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showOutboxStatus()
+		},
+	})
+	emailCmd.AddCommand(outboxCmd)
+
+	var trackingDays int
+	trackingCmd := &cobra.Command{
+		Use:   "tracking",
+		Short: "Show how many tracked weekly summaries were opened and clicked (EMAIL_TRACKING)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showTrackingStats(trackingDays)
+		},
+	}
+	trackingCmd.Flags().IntVar(&trackingDays, "days", 28, "Count summaries sent in the last this many days")
+	emailCmd.AddCommand(trackingCmd)
+
+	emailCmd.AddCommand(&cobra.Command{
+		Use:   "check-templates",
+		Short: "Render every email template against sample data and report any that fail",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return checkTemplates()
+		},
+	})
+
+	var previewDataPath string
+	var previewHTML bool
+	previewCmd := &cobra.Command{
+		Use:       "preview [daily|weekly|goals|welcome|clarification|clarification-plain|confirmation|schedule|mentor-digest]",
+		Short:     "Render an email template with sample data without sending it",
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"daily", "weekly", "goals", "welcome", "clarification", "clarification-plain", "confirmation", "schedule", "mentor-digest"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return previewEmail(args[0], previewDataPath, previewHTML)
+		},
+	}
+	previewCmd.Flags().StringVar(&previewDataPath, "data", "", "JSON file with template data overriding the built-in sample")
+	previewCmd.Flags().BoolVar(&previewHTML, "html", false, "Write the rendered email to a temporary HTML file and open it")
+	emailCmd.AddCommand(previewCmd)
+
+	broadcastOpts := core.BroadcastOptions{}
+	var broadcastTemplate string
+	broadcastCmd := &cobra.Command{
+		Use:   "broadcast",
+		Short: "Queue an announcement to all verified, active, non-suppressed users",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return broadcast(broadcastTemplate, broadcastOpts)
+		},
+	}
+	broadcastCmd.Flags().StringVar(&broadcastTemplate, "template", "", "Announcement template file, or the name of a built-in one such as announce.txt; {{.Name}} and {{.Email}} are available")
+	broadcastCmd.Flags().StringVar(&broadcastOpts.Subject, "subject", "", "Email subject")
+	broadcastCmd.Flags().BoolVar(&broadcastOpts.DryRun, "dry-run", false, "Count recipients and render a sample without queueing anything")
+	broadcastCmd.Flags().IntVar(&broadcastOpts.BatchSize, "batch-size", 10, "Emails scheduled per batch")
+	broadcastCmd.Flags().DurationVar(&broadcastOpts.BatchInterval, "batch-interval", 5*time.Minute, "Delay between batches")
+	broadcastCmd.MarkFlagRequired("template")
+	broadcastCmd.MarkFlagRequired("subject")
+	emailCmd.AddCommand(broadcastCmd)
+
+	var suppressReason string
+	suppressCmd := &cobra.Command{
+		Use:   "suppress [email]",
+		Short: "Exclude an address from broadcasts",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return suppressEmail(args[0], suppressReason)
+		},
+	}
+	suppressCmd.Flags().StringVar(&suppressReason, "reason", "manual", "Why the address is suppressed")
+	emailCmd.AddCommand(suppressCmd)
+
 	// User management subcommands
 	userCmd := &cobra.Command{
 		Use:   "user",
@@ -144,231 +246,2918 @@ func main() {
 		},
 	})
 
-	// Database subcommands
-	dbCmd := &cobra.Command{
-		Use:   "db",
-		Short: "Database related commands",
+	var manualVerification core.ManualVerification
+	verifyUserCmd := &cobra.Command{
+		Use:   "verify [email]",
+		Short: "Verify a user who can't complete the signup emails, recorded in the audit log",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return verifyUserManually(args[0], manualVerification)
+		},
 	}
+	verifyUserCmd.Flags().StringVar(&manualVerification.Name, "name", "", "The user's name")
+	verifyUserCmd.Flags().StringVar(&manualVerification.Timezone, "timezone", "", "IANA timezone, abbreviation, UTC offset or city, as in a signup reply")
+	verifyUserCmd.Flags().StringVar(&manualVerification.PromptTime, "time", "", "Daily prompt time, e.g. 9am or 16:00 (default 16:00)")
+	verifyUserCmd.Flags().StringVar(&manualVerification.Project, "project", "", "Project focus")
+	verifyUserCmd.MarkFlagRequired("name")
+	verifyUserCmd.MarkFlagRequired("timezone")
+	userCmd.AddCommand(verifyUserCmd)
 
-	dbCmd.AddCommand(&cobra.Command{
-		Use:   "migrate",
-		Short: "Run database migrations",
+	var teamsWebhookURL, teamsUserID string
+	linkTeamsCmd := &cobra.Command{
+		Use:   "link-msteams [email]",
+		Short: "Deliver a user's daily prompts to Microsoft Teams and accept replies from their Teams account",
+		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runMigrations()
+			return linkMSTeams(args[0], teamsWebhookURL, teamsUserID)
 		},
-	})
+	}
+	linkTeamsCmd.Flags().StringVar(&teamsWebhookURL, "webhook-url", "", "Incoming webhook URL of the user's chat or channel")
+	linkTeamsCmd.Flags().StringVar(&teamsUserID, "teams-user-id", "", "The user's Azure AD object id, used to match bot replies")
+	linkTeamsCmd.MarkFlagRequired("webhook-url")
+	userCmd.AddCommand(linkTeamsCmd)
 
-	rootCmd.AddCommand(verifyCmd, configCmd, emailCmd, userCmd, dbCmd)
+	var slackUserID, standupChannel string
+	linkSlackCmd := &cobra.Command{
+		Use:   "link-slack [email]",
+		Short: "Log a user's Slack messages: the Log to journal action, /standup and, with --standup-channel, a daily import",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return linkSlack(args[0], slackUserID, standupChannel)
+		},
+	}
+	linkSlackCmd.Flags().StringVar(&slackUserID, "slack-user-id", "", "The user's Slack member id (U...)")
+	linkSlackCmd.Flags().StringVar(&standupChannel, "standup-channel", "", "Id of the channel (C...) to import the user's messages from daily; empty to stop importing")
+	linkSlackCmd.MarkFlagRequired("slack-user-id")
+	userCmd.AddCommand(linkSlackCmd)
 
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	var userCalendar calendarFlags
+	userCalendarCmd := &cobra.Command{
+		Use:   "calendar [email]",
+		Short: "Set the fiscal week or sprint a user's summaries cover, overriding their organization's",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setUserCalendar(args[0], userCalendar)
+		},
 	}
-}
+	userCalendar.register(userCalendarCmd)
+	userCmd.AddCommand(userCalendarCmd)
 
-func resendVerification(email string) error {
-	ctx := context.Background()
-	
-	user, err := emailService.GetUserByEmail(ctx, email)
-	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage a user's API tokens for the GraphQL endpoint",
 	}
 
-	if user == nil {
-		return fmt.Errorf("user not found: %s", email)
+	var tokenName string
+	tokenCreateCmd := &cobra.Command{
+		Use:   "create [email]",
+		Short: "Create an API token (shown once)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return createAPIToken(args[0], tokenName)
+		},
 	}
+	tokenCreateCmd.Flags().StringVar(&tokenName, "name", "", "Label to identify the token, e.g. dashboard")
+	tokenCmd.AddCommand(tokenCreateCmd)
 
-	if user.IsVerified {
-		fmt.Printf("User %s is already verified\n", email)
-		return nil
+	tokenCmd.AddCommand(&cobra.Command{
+		Use:   "revoke [email]",
+		Short: "Revoke all of a user's API tokens",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return revokeAPITokens(args[0])
+		},
+	})
+	userCmd.AddCommand(tokenCmd)
+
+	var disableFeed bool
+	feedCmd := &cobra.Command{
+		Use:   "feed [email]",
+		Short: "Turn on or rotate a user's private Atom/RSS feed of weekly summaries (URL shown once)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setSummaryFeed(args[0], disableFeed)
+		},
 	}
+	feedCmd.Flags().BoolVar(&disableFeed, "disable", false, "Turn the feed off instead")
+	userCmd.AddCommand(feedCmd)
 
-	// Generate new verification code
-	verificationCode := email.GenerateVerificationCode()
-	
-	// Update user with new code
-	query := `UPDATE users SET verification_code = $2, updated_at = NOW() WHERE id = $1`
-	_, err = db.ExecContext(ctx, query, user.ID, verificationCode)
-	if err != nil {
-		return fmt.Errorf("failed to update verification code: %w", err)
+	sessionsCmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "Manage a user's web dashboard sessions",
 	}
+	sessionsCmd.AddCommand(&cobra.Command{
+		Use:   "revoke [email]",
+		Short: "Sign a user out of the dashboard everywhere and invalidate their sign-in links",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return revokeSessions(args[0])
+		},
+	})
+	userCmd.AddCommand(sessionsCmd)
 
-	// Send welcome email
-	err = emailService.SendWelcomeEmail(ctx, email, verificationCode)
-	if err != nil {
-		return fmt.Errorf("failed to send welcome email: %w", err)
+	aliasCmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Manage other addresses a user replies from",
 	}
+	aliasCmd.AddCommand(&cobra.Command{
+		Use:   "list [email]",
+		Short: "List a user's aliases",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listEmailAliases(args[0])
+		},
+	})
+	aliasCmd.AddCommand(&cobra.Command{
+		Use:   "add [email] [alias]",
+		Short: "Add an alias and send it a verification code",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return addEmailAlias(args[0], args[1])
+		},
+	})
+	aliasCmd.AddCommand(&cobra.Command{
+		Use:   "verify [email] [code]",
+		Short: "Verify an alias with the code it was sent",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return verifyEmailAlias(args[0], args[1])
+		},
+	})
+	aliasCmd.AddCommand(&cobra.Command{
+		Use:   "remove [email] [alias]",
+		Short: "Stop an alias reaching the user",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return removeEmailAlias(args[0], args[1])
+		},
+	})
+	userCmd.AddCommand(aliasCmd)
 
-	fmt.Printf("Verification email sent to %s\n", email)
-	return nil
-}
+	userCmd.AddCommand(&cobra.Command{
+		Use:   "projects [email]",
+		Short: "Show a user's project history",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listProjects(args[0])
+		},
+	})
 
-func showUserConfig(email string) error {
-	ctx := context.Background()
-	
-	user, err := emailService.GetUserByEmail(ctx, email)
-	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+	// Summary subcommands
+	summaryCmd := &cobra.Command{
+		Use:   "summary",
+		Short: "Summary related commands",
 	}
 
-	if user == nil {
-		return fmt.Errorf("user not found: %s", email)
+	var summaryFrom, summaryTo string
+	summaryGenerateCmd := &cobra.Command{
+		Use:   "generate [email]",
+		Short: "Summarize a user's entries for a custom date range, e.g. a sprint or review window",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generateRangeSummary(args[0], summaryFrom, summaryTo)
+		},
 	}
+	summaryGenerateCmd.Flags().StringVar(&summaryFrom, "from", "", "First day of the range (YYYY-MM-DD)")
+	summaryGenerateCmd.Flags().StringVar(&summaryTo, "to", "", "Last day of the range (YYYY-MM-DD, default today)")
+	summaryGenerateCmd.MarkFlagRequired("from")
+	summaryCmd.AddCommand(summaryGenerateCmd)
 
-	userJSON, err := json.MarshalIndent(user, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal user: %w", err)
+	var rollupQuarter string
+	summaryProjectsCmd := &cobra.Command{
+		Use:   "projects [email]",
+		Short: "Preview a user's per-project rollups for a quarter (default last quarter) without sending them",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return previewProjectRollups(args[0], rollupQuarter)
+		},
 	}
+	summaryProjectsCmd.Flags().StringVar(&rollupQuarter, "quarter", "", "Quarter to roll up, e.g. 2024Q2")
+	summaryCmd.AddCommand(summaryProjectsCmd)
 
-	fmt.Println(string(userJSON))
-	return nil
-}
+	// Entry subcommands
+	entryCmd := &cobra.Command{
+		Use:     "entry",
+		Aliases: []string{"entries"},
+		Short:   "Entry related commands",
+	}
 
-func triggerDailyPrompt(email string) error {
-	ctx := context.Background()
-	
-	user, err := emailService.GetUserByEmail(ctx, email)
-	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+	var listOpts entryListOptions
+	entryListCmd := &cobra.Command{
+		Use:   "list [email]",
+		Short: "List a user's entries, filtered like GET /v1/entries (default this week)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listEntries(args[0], listOpts)
+		},
 	}
+	entryListCmd.Flags().StringVar(&listOpts.Week, "week", "", "Any day of the week to list (YYYY-MM-DD)")
+	entryListCmd.Flags().StringVar(&listOpts.Month, "month", "", "Month to list (YYYY-MM)")
+	entryListCmd.Flags().StringVar(&listOpts.From, "from", "", "First day to list (YYYY-MM-DD)")
+	entryListCmd.Flags().StringVar(&listOpts.To, "to", "", "Last day to list (YYYY-MM-DD)")
+	entryListCmd.Flags().StringVar(&listOpts.Project, "project", "", "Only entries tagged with this project")
+	entryListCmd.Flags().StringVar(&listOpts.Query, "q", "", "Only entries containing every word")
+	entryListCmd.Flags().StringVar(&listOpts.Page, "page", "", "Cursor printed at the end of the previous page")
+	entryListCmd.Flags().IntVar(&listOpts.Limit, "limit", entries.DefaultPageSize, "Entries per page")
+	entryListCmd.MarkFlagsMutuallyExclusive("week", "month", "from")
+	entryListCmd.MarkFlagsMutuallyExclusive("week", "month", "to")
+	entryCmd.AddCommand(entryListCmd)
 
-	if user == nil {
-		return fmt.Errorf("user not found: %s", email)
+	entryCmd.AddCommand(&cobra.Command{
+		Use:   "show [email] [YYYY-MM-DD]",
+		Short: "Show a user's entry",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showEntry(args[0], args[1])
+		},
+	})
+
+	var setContent string
+	entrySetCmd := &cobra.Command{
+		Use:   "set [email] [YYYY-MM-DD]",
+		Short: "Replace a user's entry, creating it if there is none",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setEntry(args[0], args[1], setContent)
+		},
 	}
+	entrySetCmd.Flags().StringVar(&setContent, "content", "", "New entry text (- reads it from stdin)")
+	entrySetCmd.MarkFlagRequired("content")
+	entryCmd.AddCommand(entrySetCmd)
 
-	if !user.IsVerified {
-		return fmt.Errorf("user is not verified: %s", email)
+	var importFormat string
+	var importReplace, importDryRun bool
+	entryImportCmd := &cobra.Command{
+		Use:   "import [email] [path]",
+		Short: "Import a user's entries from a Day One export, Obsidian vault or markdown folder",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return importEntries(args[0], args[1], importFormat, importReplace, importDryRun)
+		},
 	}
+	entryImportCmd.Flags().StringVar(&importFormat, "format", "", "Source format ("+strings.Join(importers.Formats(), ", ")+")")
+	entryImportCmd.Flags().BoolVar(&importReplace, "replace", false, "Overwrite days that already have an entry")
+	entryImportCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Report what would be imported without saving")
+	entryImportCmd.MarkFlagRequired("format")
+	entryCmd.AddCommand(entryImportCmd)
 
-	err = emailService.SendDailyPrompt(ctx, user.ID, user.Email, user.ProjectFocus)
-	if err != nil {
-		return fmt.Errorf("failed to send daily prompt: %w", err)
+	entryCmd.AddCommand(&cobra.Command{
+		Use:   "delete [email] [YYYY-MM-DD]",
+		Short: "Soft-delete a user's entry (restorable for 30 days)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setEntryDeleted(args[0], args[1], true)
+		},
+	})
+
+	entryCmd.AddCommand(&cobra.Command{
+		Use:   "restore [email] [YYYY-MM-DD]",
+		Short: "Restore an entry deleted in the last 30 days",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setEntryDeleted(args[0], args[1], false)
+		},
+	})
+
+	// Database subcommands
+	dbCmd := &cobra.Command{
+		Use:   "db",
+		Short: "Database related commands",
 	}
 
-	fmt.Printf("Daily prompt sent to %s\n", email)
-	return nil
-}
+	dbCmd.AddCommand(&cobra.Command{
+		Use:   "migrate",
+		Short: "Run database migrations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrations()
+		},
+	})
 
-func triggerWeeklySummary(email string) error {
-	ctx := context.Background()
-	
-	user, err := emailService.GetUserByEmail(ctx, email)
-	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+	var dumpTo string
+	dumpCmd := &cobra.Command{
+		Use:   "dump",
+		Short: "Write a consistent JSONL snapshot of the database",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return dumpDatabase(dumpTo)
+		},
 	}
+	dumpCmd.Flags().StringVar(&dumpTo, "to", "", "Destination: file path, s3://bucket/key, or - for stdout")
+	dumpCmd.MarkFlagRequired("to")
+	dbCmd.AddCommand(dumpCmd)
 
-	if user == nil {
-		return fmt.Errorf("user not found: %s", email)
+	var restoreFrom string
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Load a JSONL snapshot into an empty database",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return restoreDatabase(restoreFrom)
+		},
 	}
+	restoreCmd.Flags().StringVar(&restoreFrom, "from", "", "Source: file path, s3://bucket/key, or - for stdin")
+	restoreCmd.MarkFlagRequired("from")
+	dbCmd.AddCommand(restoreCmd)
 
-	if !user.IsVerified {
-		return fmt.Errorf("user is not verified: %s", email)
+	var retentionDryRun bool
+	retentionCmd := &cobra.Command{
+		Use:   "retention",
+		Short: "Apply the RETENTION_POLICIES periods now and report what was removed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return enforceRetention(retentionDryRun)
+		},
 	}
+	retentionCmd.Flags().BoolVar(&retentionDryRun, "dry-run", false, "Report what would be removed without changing anything")
+	dbCmd.AddCommand(retentionCmd)
 
-	// Get user's entries for this week
-	entries, err := getUserWeekEntries(ctx, user.ID)
-	if err != nil {
-		return fmt.Errorf("failed to get user entries: %w", err)
+	dbCmd.AddCommand(&cobra.Command{
+		Use:   "rotate-credentials",
+		Short: "Encrypt plaintext Teams webhook URLs and re-encrypt credentials under the primary INTEGRATION_ENCRYPTION_KEYS key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return rotateCredentials()
+		},
+	})
+
+	// Infrastructure subcommands
+	infraCmd := &cobra.Command{
+		Use:   "infra",
+		Short: "Provision AWS resources",
 	}
 
-	if len(entries) == 0 {
-		fmt.Printf("No entries found for user %s this week\n", email)
-		return nil
+	var sesOpts infra.SESSetupOptions
+	setupSESCmd := &cobra.Command{
+		Use:   "setup-ses",
+		Short: "Create or update the SES receipt rule set and verify the domain",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setupSES(sesOpts)
+		},
 	}
+	setupSESCmd.Flags().StringVar(&sesOpts.Domain, "domain", "", "Domain to receive mail for (default DOMAIN)")
+	setupSESCmd.Flags().StringVar(&sesOpts.RuleSetName, "rule-set", "", "Receipt rule set name (default <domain>-ruleset)")
+	setupSESCmd.Flags().StringVar(&sesOpts.RuleName, "rule", "inbound-email-rule", "Receipt rule name")
+	setupSESCmd.Flags().StringSliceVar(&sesOpts.Recipients, "recipient", nil, "Recipient address or domain, repeatable (default the domain)")
+	setupSESCmd.Flags().StringVar(&sesOpts.Bucket, "bucket", "", "S3 bucket for raw messages (default AWS_S3_BUCKET)")
+	setupSESCmd.Flags().StringVar(&sesOpts.ObjectPrefix, "prefix", "emails/", "S3 object key prefix")
+	setupSESCmd.Flags().StringVar(&sesOpts.LambdaARN, "lambda-arn", "", "Parser Lambda function ARN (default AWS_LAMBDA_FUNCTION)")
+	setupSESCmd.Flags().BoolVar(&sesOpts.Activate, "activate", true, "Make the rule set the active one")
+	setupSESCmd.Flags().BoolVar(&sesOpts.DryRun, "dry-run", false, "Show what would change without changing anything")
+	infraCmd.AddCommand(setupSESCmd)
 
-	// Generate summary
-	summary, err := llmService.GenerateWeeklySummary(ctx, entries)
-	if err != nil {
-		return fmt.Errorf("failed to generate summary: %w", err)
+	var dkimDomain string
+	verifyDKIMCmd := &cobra.Command{
+		Use:   "verify-dkim",
+		Short: "Check DKIM, SPF and DMARC alignment for the sending domain",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return verifyDKIM(dkimDomain)
+		},
 	}
+	verifyDKIMCmd.Flags().StringVar(&dkimDomain, "domain", "", "Domain to check (default the EMAIL_FROM domain)")
+	infraCmd.AddCommand(verifyDKIMCmd)
 
-	// Send summary email
-	weekStart := getWeekStart()
-	err = emailService.SendWeeklySummary(ctx, user.ID, user.Email, weekStart, 
-		summary.Paragraph, summary.BulletPoints)
-	if err != nil {
-		return fmt.Errorf("failed to send weekly summary: %w", err)
+	var syncTemplatesPrune, syncTemplatesDryRun bool
+	syncTemplatesCmd := &cobra.Command{
+		Use:   "sync-ses-templates",
+		Short: "Create or update the SES templates SES_BULK_TEMPLATES sends batch mail through",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return syncSESTemplates(syncTemplatesPrune, syncTemplatesDryRun)
+		},
 	}
+	syncTemplatesCmd.Flags().BoolVar(&syncTemplatesPrune, "prune", false, "Delete other templates under SES_TEMPLATE_PREFIX, such as ones a changed footer replaced")
+	syncTemplatesCmd.Flags().BoolVar(&syncTemplatesDryRun, "dry-run", false, "Show what would change without changing anything")
+	infraCmd.AddCommand(syncTemplatesCmd)
 
-	fmt.Printf("Weekly summary sent to %s\n", email)
-	return nil
-}
+	// Development subcommands
+	devCmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Development and demo helpers",
+	}
 
-func processOutbox() error {
-	ctx := context.Background()
-	
-	err := emailService.ProcessOutbox(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to process outbox: %w", err)
+	var seedOpts seed.Options
+	seedCmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Generate deterministic demo users, entries and summaries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return seedDemoData(seedOpts)
+		},
 	}
+	seedCmd.Flags().IntVar(&seedOpts.Users, "users", 20, "Number of demo users to create")
+	seedCmd.Flags().IntVar(&seedOpts.Weeks, "weeks", 4, "Number of past weeks of entries and summaries per user")
+	seedCmd.Flags().Int64Var(&seedOpts.Seed, "seed", 42, "Random seed; the same seed always produces the same data")
+	devCmd.AddCommand(seedCmd)
 
-	fmt.Println("Email outbox processed")
-	return nil
-}
+	// Webhook subcommands
+	webhookCmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Manage outbound webhook endpoints",
+	}
 
-func listUsers() error {
-	ctx := context.Background()
-	
-	query := `SELECT email, name, timezone, is_verified, is_paused, created_at FROM users ORDER BY created_at DESC`
-	rows, err := db.QueryContext(ctx, query)
-	if err != nil {
-		return fmt.Errorf("failed to query users: %w", err)
+	var webhookEvents []string
+	webhookAddCmd := &cobra.Command{
+		Use:   "add [url]",
+		Short: "Register an endpoint and print its signing secret",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return addWebhook(args[0], webhookEvents)
+		},
 	}
-	defer rows.Close()
+	webhookAddCmd.Flags().StringSliceVar(&webhookEvents, "events", webhooks.EventTypes, "Event types to subscribe to")
+	webhookCmd.AddCommand(webhookAddCmd)
 
-	fmt.Printf("%-30s %-20s %-20s %-10s %-8s %s\n", "EMAIL", "NAME", "TIMEZONE", "VERIFIED", "PAUSED", "CREATED")
+	webhookCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List registered endpoints",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listWebhooks()
+		},
+	})
+
+	webhookCmd.AddCommand(&cobra.Command{
+		Use:   "remove [id]",
+		Short: "Remove an endpoint and its delivery log",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return removeWebhook(args[0])
+		},
+	})
+
+	var deliveriesEndpoint, deliveriesLimit int
+	webhookDeliveriesCmd := &cobra.Command{
+		Use:   "deliveries",
+		Short: "Show recent webhook deliveries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listWebhookDeliveries(deliveriesEndpoint, deliveriesLimit)
+		},
+	}
+	webhookDeliveriesCmd.Flags().IntVar(&deliveriesEndpoint, "endpoint", 0, "Only show deliveries for this endpoint id")
+	webhookDeliveriesCmd.Flags().IntVar(&deliveriesLimit, "limit", 20, "Number of deliveries to show")
+	webhookCmd.AddCommand(webhookDeliveriesCmd)
+
+	webhookCmd.AddCommand(&cobra.Command{
+		Use:   "process",
+		Short: "Send due webhook deliveries now",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return processWebhooks()
+		},
+	})
+
+	// Organization subcommands
+	orgCmd := &cobra.Command{
+		Use:   "org",
+		Short: "Manage organizations and their metered usage",
+	}
+
+	orgCmd.AddCommand(&cobra.Command{
+		Use:   "create [name]",
+		Short: "Create an organization",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return createOrg(args[0])
+		},
+	})
+
+	orgCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List organizations and their member counts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listOrgs()
+		},
+	})
+
+	orgCmd.AddCommand(&cobra.Command{
+		Use:   "add-user [org-id] [email]",
+		Short: "Move a user into an organization",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setUserOrg(args[1], args[0])
+		},
+	})
+
+	orgCmd.AddCommand(&cobra.Command{
+		Use:   "remove-user [email]",
+		Short: "Take a user out of their organization",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setUserOrg(args[0], "")
+		},
+	})
+
+	var usageMonth string
+	orgUsageCmd := &cobra.Command{
+		Use:   "usage [org-id]",
+		Short: "Show an organization's metered usage for a month",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showOrgUsage(args[0], usageMonth)
+		},
+	}
+	orgUsageCmd.Flags().StringVar(&usageMonth, "month", "", "Month as YYYY-MM (default this month)")
+	orgCmd.AddCommand(orgUsageCmd)
+
+	orgCmd.AddCommand(&cobra.Command{
+		Use:   "billing [org-id]",
+		Short: "Show an organization's subscription and dunning state",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showOrgBilling(args[0])
+		},
+	})
+
+	var orgCalendar calendarFlags
+	orgCalendarCmd := &cobra.Command{
+		Use:   "calendar [org-id]",
+		Short: "Set the fiscal week or sprint an organization's summaries cover",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setOrgCalendar(args[0], orgCalendar)
+		},
+	}
+	orgCalendar.register(orgCalendarCmd)
+	orgCmd.AddCommand(orgCalendarCmd)
+
+	// Inbound message subcommands
+	inboundCmd := &cobra.Command{
+		Use:   "inbound",
+		Short: "Inspect and replay received replies",
+	}
+
+	var inboundFailed bool
+	var inboundLimit int
+	inboundListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Show recently received replies and whether they were processed",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listInboundMessages(inboundFailed, inboundLimit)
+		},
+	}
+	inboundListCmd.Flags().BoolVar(&inboundFailed, "failed", false, "Only show replies that failed to process")
+	inboundListCmd.Flags().IntVar(&inboundLimit, "limit", 20, "Number of replies to show")
+	inboundCmd.AddCommand(inboundListCmd)
+
+	inboundCmd.AddCommand(&cobra.Command{
+		Use:   "replay [id]",
+		Short: "Process a reply that failed again, e.g. after fixing a parsing bug",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return replayInboundMessage(args[0])
+		},
+	})
+
+	// Quote subcommands
+	quoteCmd := &cobra.Command{
+		Use:   "quote",
+		Short: "Manage daily prompt quotes",
+	}
+
+	var quoteListAll bool
+	quoteListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List quotes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listQuotes(quoteListAll)
+		},
+	}
+	quoteListCmd.Flags().BoolVar(&quoteListAll, "all", false, "Include inactive quotes and submissions awaiting approval")
+	quoteCmd.AddCommand(quoteListCmd)
+
+	var quoteAuthor string
+	quoteAddCmd := &cobra.Command{
+		Use:   "add [text]",
+		Short: "Add an active quote",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return addQuote(args[0], quoteAuthor)
+		},
+	}
+	quoteAddCmd.Flags().StringVar(&quoteAuthor, "author", "", "Who said it")
+	quoteCmd.AddCommand(quoteAddCmd)
+
+	quoteCmd.AddCommand(&cobra.Command{
+		Use:   "approve [id]",
+		Short: "Activate a quote, such as a user submission",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setQuoteActive(args[0], true)
+		},
+	})
+
+	quoteCmd.AddCommand(&cobra.Command{
+		Use:   "disable [id]",
+		Short: "Stop showing a quote without deleting it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setQuoteActive(args[0], false)
+		},
+	})
+
+	quoteCmd.AddCommand(&cobra.Command{
+		Use:   "remove [id]",
+		Short: "Delete a quote",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return removeQuote(args[0])
+		},
+	})
+
+	analyticsCmd := &cobra.Command{
+		Use:   "analytics",
+		Short: "Dashboard analytics commands",
+	}
+
+	analyticsCmd.AddCommand(&cobra.Command{
+		Use:   "refresh",
+		Short: "Recompute the analytics views now instead of waiting for the nightly refresh",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return refreshAnalytics()
+		},
+	})
+
+	jobsCmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "Scheduler job commands",
+	}
+
+	jobsCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List scheduler jobs with their schedule, whether they're enabled and their last run",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listJobs()
+		},
+	})
+
+	jobsCmd.AddCommand(&cobra.Command{
+		Use:   "run [name]",
+		Short: "Run a job now, even if it is disabled",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runJob(args[0])
+		},
+	})
+
+	jobsCmd.AddCommand(&cobra.Command{
+		Use:   "disable [name]",
+		Short: "Stop the scheduler running a job until it is enabled again",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setJobEnabled(args[0], false)
+		},
+	})
+
+	jobsCmd.AddCommand(&cobra.Command{
+		Use:   "enable [name]",
+		Short: "Let the scheduler run a disabled job again",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setJobEnabled(args[0], true)
+		},
+	})
+
+	var jobHistoryLimit int
+	jobHistoryCmd := &cobra.Command{
+		Use:   "history [name]",
+		Short: "Show a job's recent runs",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showJobHistory(args[0], jobHistoryLimit)
+		},
+	}
+	jobHistoryCmd.Flags().IntVar(&jobHistoryLimit, "limit", 20, "Runs to show")
+	jobsCmd.AddCommand(jobHistoryCmd)
+
+	// Self-custody subcommands
+	custodyCmd := &cobra.Command{
+		Use:   "custody",
+		Short: "Read entries sealed under self-custody",
+	}
+
+	var custodyKeyFile string
+	decryptCmd := &cobra.Command{
+		Use:   "decrypt [file]",
+		Short: "Decrypt the sealed entries in an export (or stdin) with a key file and CUSTODY_PASSPHRASE",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := ""
+			if len(args) == 1 {
+				path = args[0]
+			}
+			return decryptSealed(custodyKeyFile, path)
+		},
+	}
+	decryptCmd.Flags().StringVar(&custodyKeyFile, "key", "", "Key file saved from GET /v1/me/key")
+	decryptCmd.MarkFlagRequired("key")
+	custodyCmd.AddCommand(decryptCmd)
+
+	// LLM subcommands
+	llmCmd := &cobra.Command{
+		Use:   "llm",
+		Short: "Check summary prompts and models",
+	}
+
+	var evalOpts evalOptions
+	evalCmd := &cobra.Command{
+		Use:   "eval",
+		Short: "Score weekly summaries of the eval fixtures with LLM_PROVIDER and LLM_MODEL",
+		Long: "Summarize each eval fixture through the weekly summary model chain and score the result for grounding, " +
+			"bullet count, voice and the terms it must or mustn't mention. Run it before rolling out a prompt or model change.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEvals(evalOpts)
+		},
+	}
+	evalCmd.Flags().StringVar(&evalOpts.dir, "fixtures", "", "Directory of fixture files (default: the built-in fixtures)")
+	evalCmd.Flags().StringSliceVar(&evalOpts.only, "only", nil, "Run only the named fixtures")
+	evalCmd.Flags().BoolVar(&evalOpts.replay, "replay", false, "Replay the fixtures' recorded responses instead of calling the provider")
+	evalCmd.Flags().BoolVar(&evalOpts.record, "record", false, "Save each live response as the fixture's recording (needs --fixtures)")
+	evalCmd.Flags().Float64Var(&evalOpts.minScore, "min-score", 1, "Fail when the share of passed checks is below this")
+	llmCmd.AddCommand(evalCmd)
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:       "completion [bash|zsh|fish]",
+		Short:     "Generate a shell completion script",
+		Long:      "Generate a shell completion script, e.g. `source <(whatdidyougetdone completion bash)`",
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generateCompletion(cmd.Root(), args[0])
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "tui",
+		Short: "Interactively browse users, entries, the email outbox and recent failures",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTUI(os.Stdin, os.Stdout)
+		},
+	})
+
+	rootCmd.AddCommand(verifyCmd, configCmd, emailCmd, userCmd, entryCmd, summaryCmd, dbCmd, devCmd, webhookCmd, inboundCmd, orgCmd, infraCmd, quoteCmd, analyticsCmd, jobsCmd, custodyCmd, llmCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// initServices connects to the database and builds the services commands use
+func initServices() {
+	var err error
+
+	cfg, err = config.Load()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load config")
+	}
+
+	db, err = database.New(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to connect to database")
+	}
+
+	emailService, err = email.NewService(db, cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create email service")
+	}
+
+	bus, err := events.NewBus(context.Background(), cfg.EventBus, cfg.AWSRegion, cfg.EventBusTarget)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create event bus")
+	}
+
+	webhookService = webhooks.NewService(db)
+	bus.Subscribe("webhooks", webhookService.HandleEvent)
+	emailService.SetEvents(bus)
+
+	queue, err := jobqueue.New(cfg, db)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create job queue")
+	}
+	emailService.SetQueue(queue)
+
+	coreService = core.NewService(db, emailService)
+	coreService.SetEvents(bus)
+	coreService.SetRetentionPolicies(cfg.RetentionPolicies)
+	coreService.RegisterChannel(models.DeliveryChannelMSTeams, msteams.NewClient())
+	coreService.SetPromptRecap(core.PromptRecap{Enabled: cfg.PromptRecap, LLM: cfg.PromptRecapLLM})
+
+	llmService, err = llm.NewService(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create LLM service")
+	}
+	llmService.SetCallLog(db)
+	coreService.SetLLM(llmService)
+
+	embeddingsService, err = embeddings.NewService(db, cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create embeddings service")
+	}
+	coreService.SetEmbeddings(embeddingsService)
+
+	shareCards, err := sharecard.NewStore(context.Background(), cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create share card store")
+	}
+	coreService.SetShareCards(shareCards)
+
+	credentialStore, err = credentials.NewStore(db, cfg.IntegrationEncryptionKeys)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create credential store")
+	}
+	coreService.SetCredentials(credentialStore)
+}
+
+// skipsServices reports whether cmd runs without a database, so completion
+// works on machines with no config
+func skipsServices(cmd *cobra.Command) bool {
+	switch cmd.Name() {
+	case "completion", "help", "check-templates", "decrypt", "eval", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	return false
+}
+
+func generateCompletion(root *cobra.Command, shell string) error {
+	switch shell {
+	case "bash":
+		return root.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		return root.GenZshCompletion(os.Stdout)
+	case "fish":
+		return root.GenFishCompletion(os.Stdout, true)
+	}
+	return fmt.Errorf("unsupported shell: %s", shell)
+}
+
+func resendVerification(emailAddr string) error {
+	ctx := context.Background()
+	
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	if user.IsVerified {
+		fmt.Printf("User %s is already verified\n", emailAddr)
+		return nil
+	}
+
+	// Generate new verification code
+	verificationCode := email.GenerateVerificationCode()
+	
+	// Update user with new code
+	query := `UPDATE users SET verification_code = $2, updated_at = NOW() WHERE id = $1`
+	_, err = db.ExecContext(ctx, query, user.ID, verificationCode)
+	if err != nil {
+		return fmt.Errorf("failed to update verification code: %w", err)
+	}
+	emailService.Users().Invalidate(user.ID)
+
+	// Send welcome email
+	err = emailService.SendWelcomeEmail(ctx, emailAddr, verificationCode)
+	if err != nil {
+		return fmt.Errorf("failed to send welcome email: %w", err)
+	}
+
+	fmt.Printf("Verification email sent to %s\n", emailAddr)
+	return nil
+}
+
+func showUserConfig(email string) error {
+	ctx := context.Background()
+	
+	user, err := emailService.GetUserByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", email)
+	}
+
+	userJSON, err := json.MarshalIndent(user, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal user: %w", err)
+	}
+
+	fmt.Println(string(userJSON))
+	return nil
+}
+
+func triggerDailyPrompt(email string) error {
+	ctx := context.Background()
+	
+	user, err := emailService.GetUserByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", email)
+	}
+
+	if !user.IsVerified {
+		return apperrors.New(apperrors.CodeNotVerified, "user is not verified: %s", email)
+	}
+
+	err = coreService.SendDailyPrompt(ctx, user)
+	if err != nil {
+		return fmt.Errorf("failed to send daily prompt: %w", err)
+	}
+
+	fmt.Printf("Daily prompt sent to %s\n", email)
+	return nil
+}
+
+func triggerWeeklySummary(email string) error {
+	ctx := context.Background()
+	
+	user, err := emailService.GetUserByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", email)
+	}
+
+	if !user.IsVerified {
+		return apperrors.New(apperrors.CodeNotVerified, "user is not verified: %s", email)
+	}
+
+	// Get user's entries for this week, or this period of their calendar
+	weekStart, weekEnd, err := coreService.SummaryBounds(ctx, user, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to get summary period: %w", err)
+	}
+	to := weekStart.AddDate(0, 0, 7)
+	if weekEnd.After(to) {
+		to = weekEnd.AddDate(0, 0, 1)
+	}
+	entries, err := coreService.GetEntriesBetween(ctx, user.ID, weekStart, to)
+	if err != nil {
+		return fmt.Errorf("failed to get user entries: %w", err)
+	}
+	entries = custody.Unsealed(entries)
+
+	if len(entries) == 0 {
+		fmt.Printf("No entries found for user %s this week\n", email)
+		return nil
+	}
+
+	previous, err := coreService.PriorSummaries(ctx, user, weekStart)
+	if err != nil {
+		return fmt.Errorf("failed to load prior summaries: %w", err)
+	}
+
+	missed, err := coreService.MissedDays(ctx, user.ID, weekStart, weekEnd, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to find missed days: %w", err)
+	}
+
+	// Generate summary
+	summary, err := llmService.GenerateSummary(ctx, llm.SummaryJob{User: user, Entries: entries, Previous: previous, Start: weekStart, End: weekEnd, MissedDays: missed})
+	if err != nil {
+		return fmt.Errorf("failed to generate summary: %w", err)
+	}
+
+	// Send summary email
+	trend, err := coreService.BuildEnergyTrend(ctx, user.ID, weekStart)
+	if err != nil {
+		return fmt.Errorf("failed to build energy trend: %w", err)
+	}
+
+	lookback, err := coreService.LastQuarterLookback(ctx, user.ID, weekStart, entries)
+	if err != nil {
+		return fmt.Errorf("failed to find last quarter lookback: %w", err)
+	}
+
+	ccEmails, err := coreService.GetSummaryCC(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load summary CC list: %w", err)
+	}
+
+	goals, err := coreService.GetWeeklyGoals(ctx, user.ID, weekStart)
+	if err != nil {
+		return fmt.Errorf("failed to load weekly goals: %w", err)
+	}
+
+	cardURL, err := coreService.CreateShareCard(ctx, user, weekStart, summary.BulletPoints)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to create share card, sending summary without it")
+		cardURL = ""
+	}
+
+	err = emailService.SendWeeklySummary(ctx, user.ID, user.Email, ccEmails, weekStart, weekEnd,
+		summary.Paragraph, summary.BulletPoints, goals, missed, trend, lookback, cardURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send weekly summary: %w", err)
+	}
+
+	err = coreService.SaveWeeklySummary(ctx, user.ID, weekStart, summary.Paragraph,
+		summary.BulletPoints, summary.Model, summary.CostCents)
+	if err != nil {
+		return fmt.Errorf("failed to save weekly summary: %w", err)
+	}
+
+	if cardURL != "" {
+		if err := coreService.SetSummaryCard(ctx, user.ID, weekStart, cardURL); err != nil {
+			logrus.WithError(err).Warn("Failed to save share card link")
+		}
+	}
+
+	fmt.Printf("Weekly summary sent to %s (model: %s)\n", email, summary.Model)
+	return nil
+}
+
+func generateRangeSummary(emailAddr, fromArg, toArg string) error {
+	ctx := context.Background()
+
+	from, err := time.Parse("2006-01-02", fromArg)
+	if err != nil {
+		return apperrors.New(apperrors.CodeInvalidInput, "--from must be YYYY-MM-DD: %s", fromArg)
+	}
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if toArg != "" {
+		if to, err = time.Parse("2006-01-02", toArg); err != nil {
+			return apperrors.New(apperrors.CodeInvalidInput, "--to must be YYYY-MM-DD: %s", toArg)
+		}
+	}
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	summary, err := coreService.GenerateSummaryForRange(ctx, user.ID, from, to)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Summary for %s, %s to %s (model: %s)\n\n", emailAddr, from.Format("2006-01-02"), to.Format("2006-01-02"), summary.Model)
+	fmt.Println(summary.Paragraph)
+	fmt.Println()
+	for _, bullet := range summary.BulletPoints {
+		fmt.Printf("• %s\n", bullet)
+	}
+	return nil
+}
+
+func previewProjectRollups(emailAddr, quarterArg string) error {
+	ctx := context.Background()
+
+	quarter := period.PreviousQuarter(time.Now())
+	if quarterArg != "" {
+		var err error
+		if quarter, err = period.ParseQuarter(quarterArg); err != nil {
+			return apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid --quarter")
+		}
+	}
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	projects, err := coreService.QuarterProjects(ctx, user.ID, quarter)
+	if err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		fmt.Printf("No projects with enough tagged entries in %s\n", period.QuarterLabel(quarter))
+		return nil
+	}
+
+	for i, project := range projects {
+		rollup, err := coreService.GenerateProjectRollup(ctx, user, project, quarter)
+		if err != nil {
+			return err
+		}
+		subject, _, err := email.RenderProjectRollupEmail(rollup)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s (%s, model: %s)\n\n", subject, period.QuarterLabel(quarter), rollup.LLMModel)
+		fmt.Println(rollup.SummaryParagraph)
+		fmt.Println()
+		for _, bullet := range rollup.BulletPoints {
+			fmt.Printf("• %s\n", bullet)
+		}
+	}
+	return nil
+}
+
+func processOutbox() error {
+	ctx := context.Background()
+	
+	err := emailService.ProcessOutbox(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to process outbox: %w", err)
+	}
+
+	fmt.Println("Email outbox processed")
+	return nil
+}
+
+func showOutboxStatus() error {
+	ctx := context.Background()
+
+	status, err := emailService.OutboxStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	since := func(at *time.Time) string {
+		if at == nil {
+			return "-"
+		}
+		return now.Sub(*at).Round(time.Second).String()
+	}
+
+	fmt.Printf("%-18s %-9s %-6s %-8s %-12s %s\n", "TYPE", "PENDING", "DUE", "SENDING", "OLDEST DUE", "OLDEST CLAIM")
+	fmt.Println(strings.Repeat("-", 70))
+	for _, t := range status.Types {
+		fmt.Printf("%-18s %-9d %-6d %-8d %-12s %s\n", t.EmailType, t.Pending, t.Due, t.Sending, since(t.OldestDueAt), since(t.OldestClaimedAt))
+	}
+	fmt.Println(strings.Repeat("-", 70))
+
+	age := status.OldestDueAge(now)
+	fmt.Printf("%-18s %-9d %-6d %-8d %-12s %s\n", "total", status.Pending, status.Due, status.Sending, since(status.OldestDueAt), since(status.OldestClaimedAt))
+	if cfg.OutboxStuckAfter > 0 && age >= cfg.OutboxStuckAfter {
+		fmt.Printf("\nThe outbox is stuck: the oldest due email has waited longer than OUTBOX_STUCK_AFTER (%s)\n", cfg.OutboxStuckAfter)
+	}
+	return nil
+}
+
+func showTrackingStats(days int) error {
+	ctx := context.Background()
+
+	stats, err := emailService.SummaryTrackingStats(ctx, days)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Tracked weekly summaries sent in the last %d days: %d\n", days, stats.Tracked)
+	if stats.Tracked == 0 {
+		if !cfg.EmailTracking {
+			fmt.Println("EMAIL_TRACKING is off")
+		}
+		return nil
+	}
+	fmt.Printf("Opened:  %d (%.0f%%)\n", stats.Opened, 100*float64(stats.Opened)/float64(stats.Tracked))
+	fmt.Printf("Clicked: %d (%.0f%%)\n", stats.Clicked, 100*float64(stats.Clicked)/float64(stats.Tracked))
+	fmt.Println("\nOpens are a lower bound: many mail clients block images or show the text part.")
+	return nil
+}
+
+func refreshAnalytics() error {
+	ctx := context.Background()
+
+	if err := analytics.NewService(db).Refresh(ctx); err != nil {
+		return err
+	}
+
+	fmt.Println("Analytics views refreshed")
+	return nil
+}
+
+func enforceRetention(dryRun bool) error {
+	ctx := context.Background()
+
+	service, err := retention.NewService(db, cfg.RetentionPolicies)
+	if err != nil {
+		return err
+	}
+
+	results, err := service.Enforce(ctx, dryRun)
+
+	verb := "Affected"
+	if dryRun {
+		verb = "Would affect"
+	}
+	fmt.Printf("%-14s %-10s %-12s %s\n", "POLICY", "KEEP", "CUTOFF", verb)
+	for _, result := range results {
+		keep, cutoff := "forever", "-"
+		if result.Cutoff != nil {
+			keep = fmt.Sprintf("%dd", int(result.Keep.Hours()/24))
+			cutoff = result.Cutoff.Format("2006-01-02")
+		}
+		fmt.Printf("%-14s %-10s %-12s %d\n", result.Name, keep, cutoff, result.Affected)
+	}
+	return err
+}
+
+func rotateCredentials() error {
+	if credentialStore == nil {
+		return apperrors.New(apperrors.CodeInvalidInput, "INTEGRATION_ENCRYPTION_KEYS is not set")
+	}
+	ctx := context.Background()
+
+	moved, err := coreService.EncryptChannelWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt channel webhooks: %w", err)
+	}
+	rotated, err := credentialStore.RotateKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to rotate credentials: %w", err)
+	}
+
+	fmt.Printf("Encrypted %d plaintext webhook URLs, re-encrypted %d credentials\n", moved, rotated)
+	return nil
+}
+
+// jobRegistry returns the scheduler's jobs, run against the CLI's services
+func jobRegistry() *jobs.Registry {
+	retentionService, err := retention.NewService(db, cfg.RetentionPolicies)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create retention service")
+	}
+
+	billingService, err := billing.NewService(db, emailService, cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create billing service")
+	}
+
+	registry := jobs.NewRegistry(db, cfg.JobsDisabled)
+	jobs.RegisterBuiltin(registry, jobs.Services{
+		Core:       coreService,
+		Email:      emailService,
+		LLM:        llmService,
+		Webhooks:   webhookService,
+		Analytics:  analytics.NewService(db),
+		Embeddings: embeddingsService,
+		Retention:  retentionService,
+		Anomalies:  anomalies.NewService(db),
+		Orgs:       orgs.NewService(db),
+		Billing:    billingService,
+		Slack:      slack.NewClient(cfg.SlackBotToken),
+	})
+	if err := registry.SetSchedules(cfg.JobSchedules); err != nil {
+		logrus.WithError(err).Fatal("Invalid JOB_SCHEDULES")
+	}
+	return registry
+}
+
+func listJobs() error {
+	ctx := context.Background()
+
+	statuses, err := jobRegistry().List(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-24s %-14s %-9s %-10s %-22s %s\n", "NAME", "SCHEDULE", "ENABLED", "LAST", "STARTED", "DURATION")
+	fmt.Println(strings.Repeat("-", 100))
+
+	for _, status := range statuses {
+		enabled := "yes"
+		if !status.Enabled {
+			enabled = "no"
+		}
+
+		last, started, duration := "-", "-", "-"
+		if run := status.LastRun; run != nil {
+			last = run.Status
+			started = run.StartedAt.Format(time.RFC3339)
+			if run.DurationMs != nil {
+				duration = (time.Duration(*run.DurationMs) * time.Millisecond).String()
+			}
+		}
+
+		fmt.Printf("%-24s %-14s %-9s %-10s %-22s %s\n", status.Name, status.Schedule, enabled, last, started, duration)
+		if status.Reason != "" {
+			fmt.Printf("  disabled: %s\n", status.Reason)
+		}
+	}
+
+	return nil
+}
+
+func runJob(name string) error {
+	ctx := context.Background()
+
+	start := time.Now()
+	if err := jobRegistry().Run(ctx, name, jobs.TriggerManual); err != nil {
+		return err
+	}
+
+	fmt.Printf("Job %s finished in %s\n", name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func setJobEnabled(name string, enabled bool) error {
+	ctx := context.Background()
+
+	if err := jobRegistry().SetEnabled(ctx, name, enabled); err != nil {
+		return err
+	}
+
+	if enabled {
+		fmt.Printf("Job %s enabled\n", name)
+	} else {
+		fmt.Printf("Job %s disabled; the scheduler skips it from its next run\n", name)
+	}
+	return nil
+}
+
+func showJobHistory(name string, limit int) error {
+	ctx := context.Background()
+
+	registry := jobRegistry()
+	if _, err := registry.Get(name); err != nil {
+		return err
+	}
+
+	runs, err := registry.History(ctx, name, limit)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Printf("Job %s has no recorded runs\n", name)
+		return nil
+	}
+
+	fmt.Printf("%-8s %-22s %-10s %-10s %-12s %s\n", "ID", "STARTED", "TRIGGER", "STATUS", "DURATION", "ERROR")
+	fmt.Println(strings.Repeat("-", 100))
+
+	for _, run := range runs {
+		duration, errorMessage := "-", ""
+		if run.DurationMs != nil {
+			duration = (time.Duration(*run.DurationMs) * time.Millisecond).String()
+		}
+		if run.ErrorMessage != nil {
+			errorMessage = *run.ErrorMessage
+		}
+		fmt.Printf("%-8d %-22s %-10s %-10s %-12s %s\n", run.ID, run.StartedAt.Format(time.RFC3339), run.TriggeredBy, run.Status, duration, errorMessage)
+	}
+
+	return nil
+}
+
+func checkTemplates() error {
+	if err := email.ValidateTemplates(); err != nil {
+		return err
+	}
+	fmt.Println("All email templates render")
+	return nil
+}
+
+func broadcast(templatePath string, opts core.BroadcastOptions) error {
+	ctx := context.Background()
+
+	body, err := os.ReadFile(templatePath)
+	if err == nil {
+		opts.Template = string(body)
+	} else if os.IsNotExist(err) && !strings.ContainsRune(templatePath, os.PathSeparator) {
+		// A bare name is one of the embedded announcement templates
+		opts.Template, err = email.Announcement(templatePath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read broadcast template: %w", err)
+	}
+
+	opts.TemplateName = filepath.Base(templatePath)
+	opts.SentBy = os.Getenv("USER")
+	if opts.SentBy == "" {
+		opts.SentBy = "cli"
+	}
+
+	result, err := coreService.Broadcast(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to broadcast: %w", err)
+	}
+
+	if opts.DryRun {
+		fmt.Printf("Dry run (broadcast #%d): would queue %d emails in %d batches\n\n", result.BroadcastID, result.Recipients, result.Batches)
+		fmt.Printf("Subject: %s\n\n%s\n", opts.Subject, result.Sample)
+		return nil
+	}
+
+	fmt.Printf("Broadcast #%d queued: %d emails in %d batches\n", result.BroadcastID, result.Recipients, result.Batches)
+	return nil
+}
+
+func suppressEmail(emailAddr, reason string) error {
+	ctx := context.Background()
+
+	if err := coreService.SuppressEmail(ctx, emailAddr, reason); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s will be excluded from broadcasts\n", emailAddr)
+	return nil
+}
+
+// previewFixture holds the sample values used to render email previews
+type previewFixture struct {
+	VerificationCode string   `json:"verification_code"`
+	ProjectFocus     string   `json:"project_focus"`
+	WeekStart        string   `json:"week_start"`
+	SummaryParagraph string   `json:"summary_paragraph"`
+	BulletPoints     []string `json:"bullet_points"`
+	Goals            []string `json:"goals"`
+	MissedDays       []string `json:"missed_days"`
+	OriginalMessage  string   `json:"original_message"`
+	Name             string   `json:"name"`
+	Timezone         string   `json:"timezone"`
+	PromptTime       string   `json:"prompt_time"`
+	WeekStartDay     string   `json:"week_start_day"`
+	EntryFormat      string   `json:"entry_format"`
+	PromptThreading  string   `json:"prompt_threading"`
+	Quote            string   `json:"quote"`
+	Recap            string   `json:"recap"`
+}
+
+func defaultPreviewFixture() previewFixture {
+	return previewFixture{
+		VerificationCode: "123456",
+		ProjectFocus:     "Project Atlas",
+		WeekStart:        period.StartOfWeek(time.Now(), time.Monday).Format("2006-01-02"),
+		SummaryParagraph: "Shipped the billing migration, unblocked the mobile release, and cut API latency in half. Execution was tight and the team is moving faster.",
+		BulletPoints: []string{
+			"Migrated all customers to the new billing system",
+			"Unblocked the iOS release by fixing the login crash",
+			"Reduced p95 API latency from 400ms to 180ms",
+		},
+		Goals: []string{
+			"Finish the billing migration",
+			"Ship the iOS release",
+		},
+		OriginalMessage: "did stuff <pause>forever</pause>",
+		Name:            "Ada Lovelace",
+		Timezone:        "Europe/London",
+		PromptTime:      "16:00",
+		WeekStartDay:    period.WeekStartMonday,
+		Quote:           "The way to get started is to quit talking and begin doing. - Walt Disney",
+		Recap:           "Yesterday you said you'd finish the API migration - how did that go?",
+	}
+}
+
+func previewEmail(kind, dataPath string, asHTML bool) error {
+	fixture := defaultPreviewFixture()
+	if dataPath != "" {
+		data, err := os.ReadFile(dataPath)
+		if err != nil {
+			return fmt.Errorf("failed to read preview data: %w", err)
+		}
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return fmt.Errorf("failed to parse preview data: %w", err)
+		}
+	}
+
+	var subject, body string
+	var err error
+	switch kind {
+	case "welcome":
+		subject, body, err = email.RenderWelcomeEmail(fixture.VerificationCode)
+	case "daily":
+		var projectFocus *string
+		if fixture.ProjectFocus != "" {
+			projectFocus = &fixture.ProjectFocus
+		}
+		subject, body, err = email.RenderDailyPromptEmail(projectFocus, fixture.EntryFormat, fixture.Quote, fixture.Recap,
+			email.PromptVariation{Threading: fixture.PromptThreading, WeekStart: fixture.WeekStartDay})
+	case "weekly":
+		weekStart, parseErr := time.Parse("2006-01-02", fixture.WeekStart)
+		if parseErr != nil {
+			return fmt.Errorf("invalid week_start (expected YYYY-MM-DD): %w", parseErr)
+		}
+		// Treat each bullet as a day's entry so the trend section has data
+		var entries []*models.Entry
+		for i, bullet := range fixture.BulletPoints {
+			entries = append(entries, &models.Entry{EntryDate: weekStart.AddDate(0, 0, i), RawContent: bullet})
+		}
+		trend := stats.BuildTrend(entries, weekStart)
+		// Three or more missed days preview the light week variant
+		var missed []time.Time
+		for _, day := range fixture.MissedDays {
+			date, parseErr := time.Parse("2006-01-02", day)
+			if parseErr != nil {
+				return fmt.Errorf("invalid missed_days (expected YYYY-MM-DD): %w", parseErr)
+			}
+			missed = append(missed, date)
+		}
+		// Quote the first bullet as if it were written a quarter ago
+		var lookback *email.Lookback
+		if len(fixture.BulletPoints) > 0 {
+			lookback = &email.Lookback{Date: weekStart.AddDate(0, 0, -91), Excerpt: fixture.BulletPoints[0]}
+		}
+		subject, body, err = email.RenderWeeklySummaryEmail(weekStart, period.SummaryEnd(weekStart), fixture.SummaryParagraph, fixture.BulletPoints, fixture.Goals, missed, trend, lookback, "")
+	case "goals":
+		weekStart, parseErr := time.Parse("2006-01-02", fixture.WeekStart)
+		if parseErr != nil {
+			return fmt.Errorf("invalid week_start (expected YYYY-MM-DD): %w", parseErr)
+		}
+		var projectFocus *string
+		if fixture.ProjectFocus != "" {
+			projectFocus = &fixture.ProjectFocus
+		}
+		subject, body, err = email.RenderWeeklyGoalsEmail(weekStart, projectFocus)
+	case "mentor-digest":
+		weekStart, parseErr := time.Parse("2006-01-02", fixture.WeekStart)
+		if parseErr != nil {
+			return fmt.Errorf("invalid week_start (expected YYYY-MM-DD): %w", parseErr)
+		}
+		// The sample summary repeated for four weeks of the month
+		month := digest.MonthStart(weekStart)
+		var summaries []*models.WeeklySummary
+		for i := 0; i < 4; i++ {
+			summaries = append(summaries, &models.WeeklySummary{
+				WeekStartDate:    month.AddDate(0, 0, 7*i),
+				SummaryParagraph: fixture.SummaryParagraph,
+				BulletPoints:     fixture.BulletPoints,
+			})
+		}
+		subject, body, err = email.RenderMentorDigestEmail(fixture.Name, month, digest.Build(summaries))
+	case "clarification":
+		subject, body, err = email.RenderClarificationEmail(fixture.OriginalMessage)
+	case "clarification-plain":
+		subject, body, err = email.RenderPlainTextClarificationEmail()
+	case "confirmation":
+		promptTime, parseErr := time.Parse("15:04", fixture.PromptTime)
+		if parseErr != nil {
+			return fmt.Errorf("invalid prompt_time (expected HH:MM): %w", parseErr)
+		}
+		var projectFocus *string
+		if fixture.ProjectFocus != "" {
+			projectFocus = &fixture.ProjectFocus
+		}
+		subject, body, err = email.RenderConfirmationEmail(fixture.Name, fixture.Timezone, promptTime, projectFocus, fixture.WeekStartDay)
+	case "schedule":
+		promptTime, parseErr := time.Parse("15:04", fixture.PromptTime)
+		if parseErr != nil {
+			return fmt.Errorf("invalid prompt_time (expected HH:MM): %w", parseErr)
+		}
+		subject, body, err = email.RenderScheduleUpdatedEmail(fixture.Timezone, promptTime)
+	default:
+		return fmt.Errorf("unknown email type: %s", kind)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to render %s email: %w", kind, err)
+	}
+
+	if !asHTML {
+		fmt.Printf("Subject: %s\n\n%s\n", subject, body)
+		return nil
+	}
+
+	file, err := os.CreateTemp("", "email-preview-*.html")
+	if err != nil {
+		return fmt.Errorf("failed to create preview file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>%s</title></head>\n<body><h3>%s</h3><pre>%s</pre></body></html>\n",
+		html.EscapeString(subject), html.EscapeString(subject), html.EscapeString(body))
+	if err != nil {
+		return fmt.Errorf("failed to write preview file: %w", err)
+	}
+
+	fmt.Printf("Preview written to %s\n", file.Name())
+	return openInBrowser(file.Name())
+}
+
+func openInBrowser(path string) error {
+	opener := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		opener = "open"
+	}
+
+	if err := exec.Command(opener, path).Start(); err != nil {
+		logrus.WithError(err).Warn("Failed to open preview in browser")
+	}
+	return nil
+}
+
+func listUsers() error {
+	ctx := context.Background()
+	
+	query := `SELECT email, name, timezone, is_verified, is_paused, created_at FROM users ORDER BY created_at DESC`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	fmt.Printf("%-30s %-20s %-20s %-10s %-8s %s\n", "EMAIL", "NAME", "TIMEZONE", "VERIFIED", "PAUSED", "CREATED")
+	fmt.Println(strings.Repeat("-", 100))
+
+	for rows.Next() {
+		var email, name, timezone, createdAt string
+		var isVerified, isPaused bool
+		
+		err := rows.Scan(&email, &name, &timezone, &isVerified, &isPaused, &createdAt)
+		if err != nil {
+			return fmt.Errorf("failed to scan user: %w", err)
+		}
+
+		fmt.Printf("%-30s %-20s %-20s %-10t %-8t %s\n", 
+			email, name, timezone, isVerified, isPaused, createdAt[:10])
+	}
+
+	return nil
+}
+
+func initiateSignup(email string) error {
+	ctx := context.Background()
+	
+	err := coreService.HandleSignupRequest(ctx, email)
+	if err != nil {
+		return fmt.Errorf("failed to initiate signup: %w", err)
+	}
+
+	fmt.Printf("Signup initiated for %s\n", email)
+	return nil
+}
+
+func verifyUserManually(emailAddr string, mv core.ManualVerification) error {
+	ctx := context.Background()
+
+	mv.Actor = os.Getenv("USER")
+	if mv.Actor == "" {
+		mv.Actor = "cli"
+	}
+
+	user, err := coreService.VerifyUserManually(ctx, emailAddr, mv)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Verified %s (%s, %s, prompts at %s)\n", user.Email, user.Name, user.Timezone, user.PromptTime.Format("15:04"))
+	return nil
+}
+
+func linkMSTeams(emailAddr, webhookURL, teamsUserID string) error {
+	ctx := context.Background()
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	var externalUserID *string
+	if teamsUserID != "" {
+		externalUserID = &teamsUserID
+	}
+
+	if err := coreService.LinkChannel(ctx, user.ID, models.DeliveryChannelMSTeams, &webhookURL, externalUserID); err != nil {
+		return fmt.Errorf("failed to link Teams: %w", err)
+	}
+
+	fmt.Printf("Daily prompts for %s will be delivered to Microsoft Teams\n", emailAddr)
+	return nil
+}
+
+func linkSlack(emailAddr, slackUserID, standupChannel string) error {
+	ctx := context.Background()
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	var importChannel *string
+	if standupChannel != "" {
+		importChannel = &standupChannel
+	}
+
+	if err := coreService.LinkChannelIdentity(ctx, user.ID, models.ChannelSlack, slackUserID, importChannel); err != nil {
+		return fmt.Errorf("failed to link Slack: %w", err)
+	}
+
+	fmt.Printf("Slack account %s is linked to %s\n", slackUserID, emailAddr)
+	if importChannel != nil {
+		fmt.Printf("Their messages in %s will be imported daily\n", standupChannel)
+	}
+	return nil
+}
+
+func createAPIToken(emailAddr, name string) error {
+	ctx := context.Background()
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	token, err := coreService.CreateAPIToken(ctx, user, name)
+	if err != nil {
+		return fmt.Errorf("failed to create API token: %w", err)
+	}
+
+	fmt.Printf("API token for %s (shown once): %s\n", emailAddr, token)
+	return nil
+}
+
+func revokeAPITokens(emailAddr string) error {
+	ctx := context.Background()
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	revoked, err := coreService.RevokeAPITokens(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Revoked %d API token(s) for %s\n", revoked, emailAddr)
+	return nil
+}
+
+func setSummaryFeed(emailAddr string, disable bool) error {
+	ctx := context.Background()
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	if disable {
+		disabled, err := coreService.DisableSummaryFeed(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		if !disabled {
+			fmt.Printf("%s has no summary feed\n", emailAddr)
+			return nil
+		}
+		fmt.Printf("Summary feed of %s is off; its URL no longer works\n", emailAddr)
+		return nil
+	}
+
+	token, err := coreService.EnableSummaryFeed(ctx, user)
+	if err != nil {
+		return fmt.Errorf("failed to enable summary feed: %w", err)
+	}
+
+	base := cfg.DashboardURL + feeds.Path + token
+	fmt.Printf("Summary feed of %s (shown once; any earlier URL no longer works):\n", emailAddr)
+	fmt.Printf("  Atom: %s.%s\n", base, feeds.FormatAtom)
+	fmt.Printf("  RSS:  %s.%s\n", base, feeds.FormatRSS)
+	if cfg.DashboardURL == "" {
+		fmt.Println("Set DASHBOARD_URL to print full URLs; prefix these with the API server's address")
+	}
+	return nil
+}
+
+func revokeSessions(emailAddr string) error {
+	ctx := context.Background()
+
+	authService, err := auth.NewService(db, emailService, cfg.AuthSecret)
+	if err != nil {
+		return err
+	}
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	revoked, err := authService.RevokeUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Ended %d dashboard session(s) for %s\n", revoked, emailAddr)
+	return nil
+}
+
+func setEntryDeleted(emailAddr, dateArg string, deleted bool) error {
+	ctx := context.Background()
+
+	user, date, err := entryUser(ctx, emailAddr, dateArg)
+	if err != nil {
+		return err
+	}
+
+	if deleted {
+		if err := coreService.DeleteEntry(ctx, user.ID, date); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %s's entry for %s (restorable for 30 days)\n", emailAddr, dateArg)
+		return nil
+	}
+
+	if err := coreService.RestoreEntry(ctx, user.ID, date); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %s's entry for %s\n", emailAddr, dateArg)
+	return nil
+}
+
+// entryUser parses an entry command's date argument and looks up the user
+func entryUser(ctx context.Context, emailAddr, dateArg string) (*models.User, time.Time, error) {
+	date, err := time.Parse("2006-01-02", dateArg)
+	if err != nil {
+		return nil, time.Time{}, apperrors.New(apperrors.CodeInvalidInput, "date must be YYYY-MM-DD: %s", dateArg)
+	}
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, time.Time{}, apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+	return user, date, nil
+}
+
+// entryListOptions are the flags of entry list. Week and Month are
+// shorthands for From and To; with no filter at all the current week is
+// listed.
+type entryListOptions struct {
+	Week    string
+	Month   string
+	From    string
+	To      string
+	Project string
+	Query   string
+	Page    string
+	Limit   int
+}
+
+func listEntries(emailAddr string, opts entryListOptions) error {
+	ctx := context.Background()
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	switch {
+	case opts.Month != "":
+		month, err := time.Parse("2006-01", opts.Month)
+		if err != nil {
+			return apperrors.New(apperrors.CodeInvalidInput, "month must be YYYY-MM: %s", opts.Month)
+		}
+		opts.From = month.Format("2006-01-02")
+		opts.To = month.AddDate(0, 1, -1).Format("2006-01-02")
+	case opts.Week != "" || (opts.From == "" && opts.To == "" && opts.Project == "" && opts.Query == ""):
+		day := time.Now()
+		if opts.Week != "" {
+			day, err = time.Parse("2006-01-02", opts.Week)
+			if err != nil {
+				return apperrors.New(apperrors.CodeInvalidInput, "week must be YYYY-MM-DD: %s", opts.Week)
+			}
+		}
+		from := period.StartOfWeek(day, period.FirstWeekday(user.WeekStart))
+		opts.From = from.Format("2006-01-02")
+		opts.To = from.AddDate(0, 0, 6).Format("2006-01-02")
+	}
+
+	filter, err := entries.ParseFilter(opts.From, opts.To, opts.Project, opts.Query)
+	if err != nil {
+		return err
+	}
+
+	page, err := coreService.ListEntries(ctx, user.ID, filter, opts.Page, opts.Limit)
+	if err != nil {
+		return err
+	}
+
+	if len(page.Entries) == 0 {
+		fmt.Printf("No entries for %s\n", emailAddr)
+		return nil
+	}
+
+	fmt.Printf("%-12s %-16s %-7s %s\n", "DATE", "PROJECT", "CHARS", "FIRST LINE")
+	for _, entry := range page.Entries {
+		project := "-"
+		if entry.ProjectTag != nil {
+			project = *entry.ProjectTag
+		}
+		firstLine, _, _ := strings.Cut(strings.TrimSpace(entry.RawContent), "\n")
+		if len(firstLine) > 60 {
+			firstLine = firstLine[:57] + "..."
+		}
+		fmt.Printf("%-12s %-16s %-7d %s\n", entry.EntryDate.Format("2006-01-02"), project, len(entry.RawContent), firstLine)
+	}
+	if page.NextPage != "" {
+		fmt.Printf("\nMore entries: --page %s\n", page.NextPage)
+	}
+	return nil
+}
+
+func showEntry(emailAddr, dateArg string) error {
+	ctx := context.Background()
+
+	user, date, err := entryUser(ctx, emailAddr, dateArg)
+	if err != nil {
+		return err
+	}
+
+	entry, err := coreService.GetEntry(ctx, user.ID, date)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("User:    %s\n", emailAddr)
+	fmt.Printf("Date:    %s\n", entry.EntryDate.Format("Monday, January 2, 2006"))
+	if entry.ProjectTag != nil {
+		fmt.Printf("Project: %s\n", *entry.ProjectTag)
+	}
+	fmt.Printf("Created: %s\n", entry.CreatedAt.Format(time.RFC3339))
+	fmt.Printf("Updated: %s\n", entry.UpdatedAt.Format(time.RFC3339))
+	fmt.Printf("\n%s\n", entry.RawContent)
+	if entry.ParsedContent != nil && *entry.ParsedContent != entry.RawContent {
+		fmt.Printf("\nParsed (%s):\n%s\n", user.EntryFormat, *entry.ParsedContent)
+	}
+	return nil
+}
+
+func setEntry(emailAddr, dateArg, content string) error {
+	ctx := context.Background()
+
+	user, date, err := entryUser(ctx, emailAddr, dateArg)
+	if err != nil {
+		return err
+	}
+
+	if content == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read content from stdin: %w", err)
+		}
+		content = string(data)
+	}
+
+	if err := coreService.SetEntryContent(ctx, user, date, strings.TrimSpace(content)); err != nil {
+		return err
+	}
+	fmt.Printf("Set %s's entry for %s\n", emailAddr, dateArg)
+	return nil
+}
+
+func importEntries(emailAddr, path, format string, replace, dryRun bool) error {
+	ctx := context.Background()
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	read, err := importers.Import(format, path)
+	if err != nil {
+		return apperrors.Wrap(apperrors.CodeInvalidInput, err, "failed to read %s", path)
+	}
+
+	result, err := coreService.ImportEntries(ctx, user, read.Entries, replace, dryRun)
+	if err != nil {
+		return err
+	}
+
+	verb := "Imported"
+	if dryRun {
+		verb = "Would import"
+	}
+	fmt.Printf("%s %d entries for %s: %d new, %d replaced, %d skipped (already had an entry)\n",
+		verb, result.Created+result.Replaced, emailAddr, result.Created, result.Replaced, result.Skipped)
+	if len(read.Entries) > 0 {
+		fmt.Printf("Dates: %s to %s\n", read.Entries[0].Date.Format("2006-01-02"), read.Entries[len(read.Entries)-1].Date.Format("2006-01-02"))
+	}
+	if len(read.Skipped) > 0 {
+		fmt.Printf("\nSkipped %d file(s) with no date:\n", len(read.Skipped))
+		for _, file := range read.Skipped {
+			fmt.Printf("  %s\n", file)
+		}
+	}
+	return nil
+}
+
+func addWebhook(url string, eventTypes []string) error {
+	ctx := context.Background()
+
+	endpoint, err := webhookService.Register(ctx, url, eventTypes)
+	if err != nil {
+		return fmt.Errorf("failed to register webhook: %w", err)
+	}
+
+	fmt.Printf("Webhook #%d registered for %s\n", endpoint.ID, strings.Join(endpoint.EventTypes, ", "))
+	fmt.Printf("Signing secret (shown once): %s\n", endpoint.Secret)
+	return nil
+}
+
+func listProjects(emailAddr string) error {
+	ctx := context.Background()
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	projects, err := coreService.GetProjectHistory(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		fmt.Printf("No project history for %s\n", emailAddr)
+		return nil
+	}
+
+	fmt.Printf("%-40s %-12s %-12s\n", "PROJECT", "STARTED", "ENDED")
+	fmt.Println(strings.Repeat("-", 66))
+	for _, project := range projects {
+		ended := "current"
+		if project.EndedOn != nil {
+			// ended_on is exclusive; show the last day on the project
+			ended = project.EndedOn.AddDate(0, 0, -1).Format("2006-01-02")
+		}
+		fmt.Printf("%-40s %-12s %-12s\n", project.Name, project.StartedOn.Format("2006-01-02"), ended)
+	}
+	return nil
+}
+
+// aliasOwner returns the user with emailAddr, which may itself be an alias
+func aliasOwner(ctx context.Context, emailAddr string) (*models.User, error) {
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+	return user, nil
+}
+
+func listEmailAliases(emailAddr string) error {
+	ctx := context.Background()
+
+	user, err := aliasOwner(ctx, emailAddr)
+	if err != nil {
+		return err
+	}
+
+	aliases, err := coreService.ListEmailAliases(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if len(aliases) == 0 {
+		fmt.Printf("No aliases for %s\n", user.Email)
+		return nil
+	}
+
+	fmt.Printf("%-40s %-20s\n", "ALIAS", "VERIFIED")
+	fmt.Println(strings.Repeat("-", 61))
+	for _, alias := range aliases {
+		verified := "pending"
+		if alias.VerifiedAt != nil {
+			verified = alias.VerifiedAt.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-40s %-20s\n", alias.Email, verified)
+	}
+	return nil
+}
+
+func addEmailAlias(emailAddr, aliasAddr string) error {
+	ctx := context.Background()
+
+	user, err := aliasOwner(ctx, emailAddr)
+	if err != nil {
+		return err
+	}
+
+	alias, err := coreService.AddEmailAlias(ctx, user, aliasAddr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Sent a verification code to %s; verify it with `user alias verify %s <code>` or by replying from it\n", alias.Email, user.Email)
+	return nil
+}
+
+func verifyEmailAlias(emailAddr, code string) error {
+	ctx := context.Background()
+
+	user, err := aliasOwner(ctx, emailAddr)
+	if err != nil {
+		return err
+	}
+
+	alias, err := coreService.VerifyEmailAlias(ctx, user.ID, code)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Replies from %s now reach %s\n", alias.Email, user.Email)
+	return nil
+}
+
+func removeEmailAlias(emailAddr, aliasAddr string) error {
+	ctx := context.Background()
+
+	user, err := aliasOwner(ctx, emailAddr)
+	if err != nil {
+		return err
+	}
+
+	if err := coreService.RemoveEmailAlias(ctx, user.ID, aliasAddr); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed alias %s from %s\n", aliasAddr, user.Email)
+	return nil
+}
+
+func listWebhooks() error {
+	ctx := context.Background()
+
+	endpoints, err := webhookService.ListEndpoints(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-5s %-50s %-8s %s\n", "ID", "URL", "ACTIVE", "EVENTS")
+	fmt.Println(strings.Repeat("-", 100))
+
+	for _, endpoint := range endpoints {
+		fmt.Printf("%-5d %-50s %-8t %s\n", endpoint.ID, endpoint.URL, endpoint.IsActive, strings.Join(endpoint.EventTypes, ","))
+	}
+
+	return nil
+}
+
+func removeWebhook(idArg string) error {
+	ctx := context.Background()
+
+	id, err := strconv.Atoi(idArg)
+	if err != nil {
+		return apperrors.New(apperrors.CodeInvalidInput, "invalid webhook id: %s", idArg)
+	}
+
+	if err := webhookService.Remove(ctx, id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Webhook #%d removed\n", id)
+	return nil
+}
+
+func listQuotes(includeInactive bool) error {
+	ctx := context.Background()
+
+	list, err := coreService.Quotes().List(ctx, includeInactive)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-5s %-8s %-10s %s\n", "ID", "ACTIVE", "SUBMITTED", "QUOTE")
+	fmt.Println(strings.Repeat("-", 100))
+
+	for _, quote := range list {
+		submitted := "admin"
+		if quote.SubmittedBy != nil {
+			submitted = fmt.Sprintf("user %d", *quote.SubmittedBy)
+		}
+		fmt.Printf("%-5d %-8t %-10s %s\n", quote.ID, quote.IsActive, submitted, quote.String())
+	}
+
+	return nil
+}
+
+func addQuote(text, author string) error {
+	ctx := context.Background()
+
+	var authorPtr *string
+	if author != "" {
+		authorPtr = &author
+	}
+
+	quote, err := coreService.Quotes().Add(ctx, text, authorPtr, nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Quote #%d added\n", quote.ID)
+	return nil
+}
+
+func setQuoteActive(idArg string, active bool) error {
+	ctx := context.Background()
+
+	id, err := strconv.Atoi(idArg)
+	if err != nil {
+		return apperrors.New(apperrors.CodeInvalidInput, "invalid quote id: %s", idArg)
+	}
+
+	if _, err := coreService.Quotes().Update(ctx, id, quotes.Update{IsActive: &active}); err != nil {
+		return err
+	}
+
+	state := "disabled"
+	if active {
+		state = "active"
+	}
+	fmt.Printf("Quote #%d is now %s\n", id, state)
+	return nil
+}
+
+func removeQuote(idArg string) error {
+	ctx := context.Background()
+
+	id, err := strconv.Atoi(idArg)
+	if err != nil {
+		return apperrors.New(apperrors.CodeInvalidInput, "invalid quote id: %s", idArg)
+	}
+
+	if err := coreService.Quotes().Remove(ctx, id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Quote #%d removed\n", id)
+	return nil
+}
+
+func listWebhookDeliveries(endpointID, limit int) error {
+	ctx := context.Background()
+
+	deliveries, err := webhookService.ListDeliveries(ctx, endpointID, limit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-8s %-9s %-20s %-10s %-9s %-7s %s\n", "ID", "ENDPOINT", "EVENT", "STATUS", "ATTEMPTS", "HTTP", "CREATED")
+	fmt.Println(strings.Repeat("-", 100))
+
+	for _, d := range deliveries {
+		httpStatus := "-"
+		if d.ResponseStatus != nil {
+			httpStatus = strconv.Itoa(*d.ResponseStatus)
+		}
+		fmt.Printf("%-8d %-9d %-20s %-10s %-9d %-7s %s\n",
+			d.ID, d.EndpointID, d.EventType, d.Status, d.Attempts, httpStatus, d.CreatedAt.Format(time.RFC3339))
+		if d.ErrorMessage != nil && d.Status != models.WebhookStatusDelivered {
+			fmt.Printf("         error: %s\n", *d.ErrorMessage)
+		}
+	}
+
+	return nil
+}
+
+func processWebhooks() error {
+	ctx := context.Background()
+
+	if err := webhookService.ProcessDeliveries(ctx); err != nil {
+		return fmt.Errorf("failed to process webhook deliveries: %w", err)
+	}
+
+	fmt.Println("Webhook deliveries processed")
+	return nil
+}
+
+func createOrg(name string) error {
+	ctx := context.Background()
+
+	org, err := orgs.NewService(db).Create(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Organization #%d %s created\n", org.ID, org.Name)
+	return nil
+}
+
+func listOrgs() error {
+	ctx := context.Background()
+
+	list, err := orgs.NewService(db).List(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-5s %-40s %-8s %s\n", "ID", "NAME", "MEMBERS", "CREATED")
+	fmt.Println(strings.Repeat("-", 80))
+
+	for _, org := range list {
+		fmt.Printf("%-5d %-40s %-8d %s\n", org.ID, org.Name, org.Members, org.CreatedAt.Format("2006-01-02"))
+	}
+
+	return nil
+}
+
+// setUserOrg moves the user into organization orgArg, or out of theirs when
+// it is empty
+func setUserOrg(emailAddr, orgArg string) error {
+	ctx := context.Background()
+
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	var orgID *int
+	if orgArg != "" {
+		id, err := strconv.Atoi(orgArg)
+		if err != nil {
+			return apperrors.New(apperrors.CodeInvalidInput, "invalid organization id: %s", orgArg)
+		}
+		orgID = &id
+	}
+
+	if err := orgs.NewService(db).SetUserOrganization(ctx, user.ID, orgID); err != nil {
+		return err
+	}
+
+	if orgID == nil {
+		fmt.Printf("%s is no longer in an organization\n", emailAddr)
+	} else {
+		fmt.Printf("%s is now in organization #%d\n", emailAddr, *orgID)
+	}
+	return nil
+}
+
+func showOrgUsage(idArg, monthArg string) error {
+	ctx := context.Background()
+
+	id, err := strconv.Atoi(idArg)
+	if err != nil {
+		return apperrors.New(apperrors.CodeInvalidInput, "invalid organization id: %s", idArg)
+	}
+	month := time.Now().UTC()
+	if monthArg != "" {
+		if month, err = time.Parse("2006-01", monthArg); err != nil {
+			return apperrors.New(apperrors.CodeInvalidInput, "--month must be YYYY-MM")
+		}
+	}
+
+	usage, err := orgs.NewService(db).Usage(ctx, id, month)
+	if err != nil {
+		return err
+	}
+
+	through := "not metered yet"
+	if usage.MeteredThrough != nil {
+		through = "metered through " + usage.MeteredThrough.Format("2006-01-02")
+	}
+	fmt.Printf("Organization #%d, %s (%s)\n\n", usage.OrganizationID, usage.Month, through)
+	fmt.Printf("  Users (peak): %d\n", usage.Users)
+	fmt.Printf("  Prompts:      %d\n", usage.Prompts)
+	fmt.Printf("  Summaries:    %d\n", usage.Summaries)
+	fmt.Printf("  LLM spend:    $%.2f\n", float64(usage.LLMCostCents)/100)
+	return nil
+}
+
+func showOrgBilling(idArg string) error {
+	ctx := context.Background()
+
+	id, err := strconv.Atoi(idArg)
+	if err != nil {
+		return apperrors.New(apperrors.CodeInvalidInput, "invalid organization id: %s", idArg)
+	}
+	billingService, err := billing.NewService(db, emailService, cfg)
+	if err != nil {
+		return err
+	}
+	if billingService == nil {
+		return fmt.Errorf("billing is off: set STRIPE_SECRET_KEY")
+	}
+
+	status, err := billingService.Status(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Organization #%d, %s\n\n", status.OrganizationID, status.Name)
+	fmt.Printf("  Status:        %s\n", status.SubscriptionStatus)
+	if status.BillingEmail != nil {
+		fmt.Printf("  Billing email: %s\n", *status.BillingEmail)
+	}
+	if status.StripeCustomerID != nil {
+		fmt.Printf("  Customer:      %s\n", *status.StripeCustomerID)
+	}
+	if status.GraceUntil != nil {
+		fmt.Printf("  Grace until:   %s\n", status.GraceUntil.Format(time.RFC3339))
+	}
+	if status.InvoiceURL != nil {
+		fmt.Printf("  Invoice:       %s\n", *status.InvoiceURL)
+	}
+	fmt.Printf("  Dunning stage: %d\n", status.DunningStage)
+	fmt.Printf("  Suspended:     %t\n", status.Suspended)
+	return nil
+}
+
+// calendarFlags are the flags of the user and org calendar commands
+type calendarFlags struct {
+	weeks  int
+	ending string
+	clear  bool
+}
+
+func (f *calendarFlags) register(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&f.weeks, "weeks", 2, "Length of each period in weeks")
+	cmd.Flags().StringVar(&f.ending, "ending", "", "Last day of any one period, as YYYY-MM-DD")
+	cmd.Flags().BoolVar(&f.clear, "clear", false, "Go back to calendar weeks")
+}
+
+// calendar returns the calendar the flags describe, the zero Calendar for --clear
+func (f calendarFlags) calendar() (period.Calendar, error) {
+	if f.clear {
+		return period.Calendar{}, nil
+	}
+	if f.ending == "" {
+		return period.Calendar{}, apperrors.New(apperrors.CodeInvalidInput, "--ending or --clear is required")
+	}
+	end, err := time.Parse("2006-01-02", f.ending)
+	if err != nil {
+		return period.Calendar{}, apperrors.New(apperrors.CodeInvalidInput, "--ending must be YYYY-MM-DD")
+	}
+	calendar, err := period.NewCalendar(f.weeks, end)
+	if err != nil {
+		return period.Calendar{}, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid calendar")
+	}
+	return calendar, nil
+}
+
+func setUserCalendar(emailAddr string, flags calendarFlags) error {
+	ctx := context.Background()
+
+	calendar, err := flags.calendar()
+	if err != nil {
+		return err
+	}
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	if err := coreService.SetSummaryCalendar(ctx, user.ID, calendar); err != nil {
+		return err
+	}
+
+	// Show what applies now, which may be their organization's
+	effective, err := coreService.SummaryCalendar(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Summaries for %s now cover %s\n", emailAddr, effective)
+	return nil
+}
+
+func setOrgCalendar(idArg string, flags calendarFlags) error {
+	ctx := context.Background()
+
+	id, err := strconv.Atoi(idArg)
+	if err != nil {
+		return apperrors.New(apperrors.CodeInvalidInput, "invalid organization id: %s", idArg)
+	}
+	calendar, err := flags.calendar()
+	if err != nil {
+		return err
+	}
+
+	if err := orgs.NewService(db).SetCalendar(ctx, id, calendar); err != nil {
+		return err
+	}
+
+	fmt.Printf("Summaries for organization #%d now cover %s\n", id, calendar)
+	return nil
+}
+
+func listInboundMessages(failedOnly bool, limit int) error {
+	ctx := context.Background()
+
+	status := ""
+	if failedOnly {
+		status = models.InboundStatusFailed
+	}
+	messages, err := inbound.NewStore(db).List(ctx, status, limit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-8s %-8s %-30s %-10s %-9s %s\n", "ID", "SOURCE", "SENDER", "STATUS", "ATTEMPTS", "RECEIVED")
 	fmt.Println(strings.Repeat("-", 100))
 
-	for rows.Next() {
-		var email, name, timezone, createdAt string
-		var isVerified, isPaused bool
-		
-		err := rows.Scan(&email, &name, &timezone, &isVerified, &isPaused, &createdAt)
+	for _, msg := range messages {
+		sender := "-"
+		if msg.Sender != nil {
+			sender = *msg.Sender
+		}
+		fmt.Printf("%-8d %-8s %-30s %-10s %-9d %s\n",
+			msg.ID, msg.Source, sender, msg.Status, msg.Attempts, msg.ReceivedAt.Format(time.RFC3339))
+		if msg.S3Bucket != nil && msg.S3Key != nil {
+			fmt.Printf("         s3: %s/%s\n", *msg.S3Bucket, *msg.S3Key)
+		}
+		if msg.ErrorMessage != nil && msg.Status != models.InboundStatusProcessed {
+			fmt.Printf("         error: %s\n", *msg.ErrorMessage)
+		}
+	}
+
+	return nil
+}
+
+func replayInboundMessage(idArg string) error {
+	ctx := context.Background()
+
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil {
+		return apperrors.New(apperrors.CodeInvalidInput, "invalid inbound message id: %s", idArg)
+	}
+
+	limits := inbound.Limits{
+		MaxBytes:       cfg.InboundMaxBytes,
+		MaxBodyBytes:   cfg.InboundMaxBodyBytes,
+		OversizePolicy: cfg.InboundOversizePolicy,
+	}
+	err = inbound.NewStore(db).Replay(ctx, id, coreService, limits)
+	if inbound.IsIgnored(err) {
+		fmt.Printf("Inbound message #%d %s\n", id, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("replay of inbound message %d failed: %w", id, err)
+	}
+
+	fmt.Printf("Inbound message #%d processed\n", id)
+	return nil
+}
+
+// evalOptions are the flags of llm eval
+type evalOptions struct {
+	dir      string
+	only     []string
+	replay   bool
+	record   bool
+	minScore float64
+}
+
+// runEvals scores the eval fixtures. It needs no database, only the LLM
+// settings.
+func runEvals(opts evalOptions) error {
+	ctx := context.Background()
+
+	if opts.record && (opts.replay || opts.dir == "") {
+		return apperrors.New(apperrors.CodeInvalidInput, "--record needs --fixtures and a live provider")
+	}
+
+	evalCfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var fixtures []*evals.Fixture
+	if opts.dir != "" {
+		fixtures, err = evals.LoadDir(opts.dir)
+	} else {
+		fixtures, err = evals.Builtin()
+	}
+	if err != nil {
+		return err
+	}
+	if len(opts.only) > 0 {
+		wanted := map[string]bool{}
+		for _, name := range opts.only {
+			wanted[name] = true
+		}
+		var selected []*evals.Fixture
+		for _, fixture := range fixtures {
+			if wanted[fixture.Name] {
+				selected = append(selected, fixture)
+			}
+		}
+		if len(selected) == 0 {
+			return apperrors.New(apperrors.CodeInvalidInput, "no fixtures named %s", strings.Join(opts.only, ", "))
+		}
+		fixtures = selected
+	}
+
+	providerFor := evals.Replay
+	if !opts.replay {
+		provider, err := llm.NewProvider(ctx, evalCfg)
 		if err != nil {
-			return fmt.Errorf("failed to scan user: %w", err)
+			return err
 		}
+		providerFor = func(*evals.Fixture) llm.Provider { return provider }
+		fmt.Printf("Scoring %d fixtures with %s on %s\n\n", len(fixtures), evalCfg.LLMModel, evalCfg.LLMProvider)
+	}
 
-		fmt.Printf("%-30s %-20s %-20s %-10t %-8t %s\n", 
-			email, name, timezone, isVerified, isPaused, createdAt[:10])
+	report := evals.Run(ctx, evalCfg, fixtures, providerFor)
+
+	fmt.Printf("%-28s %-7s %-6s %s\n", "FIXTURE", "SCORE", "CALLS", "MODEL")
+	fmt.Println(strings.Repeat("-", 80))
+	for _, result := range report.Results {
+		model := "-"
+		if result.Summary != nil {
+			model = result.Summary.Model
+		}
+		fmt.Printf("%-28s %-7s %-6d %s\n", result.Fixture.Name, fmt.Sprintf("%.0f%%", result.Score()*100), result.Calls, model)
+		for _, check := range result.Failed() {
+			fmt.Printf("    ✗ %s: %s\n", check.Name, check.Detail)
+		}
+
+		if opts.record && result.Response != "" {
+			result.Fixture.Recorded = result.Response
+			if err := evals.Save(result.Fixture); err != nil {
+				return fmt.Errorf("failed to record %s: %w", result.Fixture.Name, err)
+			}
+		}
 	}
 
+	score := report.Score()
+	fmt.Printf("\nOverall: %.0f%% of checks passed\n", score*100)
+	if opts.record {
+		fmt.Printf("Recorded responses in %s\n", opts.dir)
+	}
+	if score < opts.minScore {
+		return fmt.Errorf("eval score %.0f%% is below --min-score %.0f%%", score*100, opts.minScore*100)
+	}
 	return nil
 }
 
-func initiateSignup(email string) error {
+// decryptSealed writes the file at path, or stdin, to stdout with its
+// sealed entries decrypted. It runs offline: the key is unwrapped here with
+// the passphrase, which never leaves the machine.
+func decryptSealed(keyFile, path string) error {
+	passphrase := os.Getenv("CUSTODY_PASSPHRASE")
+	if passphrase == "" {
+		return apperrors.New(apperrors.CodeInvalidInput, "set CUSTODY_PASSPHRASE to the key's passphrase")
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+	var key custody.Key
+	if err := json.Unmarshal(data, &key); err != nil {
+		return apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid key file")
+	}
+	private, err := key.Unwrap(passphrase)
+	if err != nil {
+		return err
+	}
+
+	var input []byte
+	if path == "" {
+		input, err = io.ReadAll(os.Stdin)
+	} else {
+		input, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	output, err := custody.DecryptText(private, string(input), json.Valid(input))
+	if err != nil {
+		return err
+	}
+	fmt.Print(output)
+	return nil
+}
+
+func seedDemoData(opts seed.Options) error {
 	ctx := context.Background()
-	
-	err := coreService.HandleSignupRequest(ctx, email)
+
+	if opts.Users < 1 || opts.Weeks < 1 {
+		return fmt.Errorf("--users and --weeks must be at least 1")
+	}
+
+	result, err := seed.NewService(db).Run(ctx, opts)
 	if err != nil {
-		return fmt.Errorf("failed to initiate signup: %w", err)
+		return fmt.Errorf("failed to seed demo data: %w", err)
 	}
 
-	fmt.Printf("Signup initiated for %s\n", email)
+	fmt.Printf("Seeded %d users, %d entries, %d weekly summaries\n", result.Users, result.Entries, result.Summaries)
 	return nil
 }
 
-func runMigrations() error {
-	err := db.RunMigrations()
+func dumpDatabase(location string) error {
+	ctx := context.Background()
+
+	w, err := backup.Create(ctx, location, cfg.AWSRegion)
 	if err != nil {
+		return err
+	}
+
+	result, err := backup.NewService(db).Dump(ctx, w)
+	if err != nil {
+		w.Close()
+		return fmt.Errorf("failed to dump database: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	// Keep stdout clean when the snapshot itself is written there
+	out := os.Stdout
+	if location == "-" {
+		out = os.Stderr
+	}
+	fmt.Fprintf(out, "Dumped %d rows to %s\n", result.Rows, location)
+	for _, table := range backup.Tables {
+		fmt.Fprintf(out, "  %-22s %d\n", table, result.Counts[table])
+	}
+	return nil
+}
+
+func restoreDatabase(location string) error {
+	ctx := context.Background()
+
+	// The snapshot must be restored into the current schema
+	if err := db.RunMigrations(); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	fmt.Println("Database migrations completed")
+	r, err := backup.Open(ctx, location, cfg.AWSRegion)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	result, err := backup.NewService(db).Restore(ctx, r)
+	if err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	fmt.Printf("Restored %d rows from %s\n", result.Rows, location)
+	for _, table := range backup.Tables {
+		fmt.Printf("  %-22s %d\n", table, result.Counts[table])
+	}
+	return nil
+}
+
+func setupSES(opts infra.SESSetupOptions) error {
+	ctx := context.Background()
+
+	if opts.Domain == "" {
+		opts.Domain = cfg.Domain
+	}
+	if opts.Bucket == "" {
+		opts.Bucket = cfg.AWSS3Bucket
+	}
+	if opts.LambdaARN == "" {
+		opts.LambdaARN = cfg.AWSLambdaFunc
+	}
+
+	// Receipt rules live in the SES region, which may differ from AWS_REGION
+	setup, err := infra.NewSESSetup(ctx, cfg.AWSSESRegion)
+	if err != nil {
+		return err
+	}
+
+	result, err := setup.Run(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to set up SES: %w", err)
+	}
+
+	if len(result.Actions) == 0 {
+		fmt.Println("SES is already up to date")
+	}
+	for _, action := range result.Actions {
+		fmt.Printf("- %s\n", action)
+	}
+
+	fmt.Printf("Domain verification: %s\n", result.VerificationStatus)
+	if result.VerificationToken != "" && result.VerificationStatus != "Success" {
+		fmt.Printf("  TXT   _amazonses.%s  %s\n", opts.Domain, result.VerificationToken)
+	}
+	for _, token := range result.DKIMTokens {
+		fmt.Printf("  CNAME %s._domainkey.%s  %s.dkim.amazonses.com\n", token, opts.Domain, token)
+	}
+	if !opts.DryRun && len(result.Actions) > 0 {
+		fmt.Println("The parser Lambda must allow ses.amazonaws.com to invoke it (see aws_lambda_permission in terraform/main.tf)")
+	}
+	return nil
+}
+
+func syncSESTemplates(prune, dryRun bool) error {
+	ctx := context.Background()
+
+	templates, err := email.SESTemplates(cfg.SESTemplatePrefix)
+	if err != nil {
+		return err
+	}
+
+	setup, err := infra.NewSESSetup(ctx, cfg.AWSSESRegion)
+	if err != nil {
+		return err
+	}
+
+	actions, err := setup.SyncTemplates(ctx, cfg.SESTemplatePrefix, templates, prune, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if len(actions) == 0 {
+		fmt.Println("SES templates are already up to date")
+	}
+	for _, action := range actions {
+		fmt.Printf("- %s\n", action)
+	}
+	if !cfg.SESBulkTemplates {
+		fmt.Println("Set SES_BULK_TEMPLATES=true for the outbox to send through them")
+	}
 	return nil
 }
 
-// Helper functions (would need proper implementation)
-func getUserWeekEntries(ctx context.Context, userID int) ([]*models.Entry, error) {
-	// Implementation would query entries for the current week
-	return nil, nil
+func verifyDKIM(domain string) error {
+	ctx := context.Background()
+
+	if domain == "" {
+		var err error
+		if domain, err = infra.SendingDomain(cfg.EmailFrom); err != nil {
+			return err
+		}
+	}
+
+	setup, err := infra.NewSESSetup(ctx, cfg.AWSSESRegion)
+	if err != nil {
+		return err
+	}
+
+	report, err := setup.CheckDeliverability(ctx, domain)
+	if err != nil {
+		return fmt.Errorf("failed to check deliverability: %w", err)
+	}
+
+	fmt.Printf("Domain:    %s\n", report.Domain)
+	fmt.Printf("DKIM:      enabled=%t status=%s\n", report.DKIMEnabled, report.DKIMStatus)
+	for _, record := range report.DKIMRecords {
+		status := "missing"
+		if record.Published {
+			status = "ok"
+		}
+		fmt.Printf("  %-7s %s  %s  %s\n", status, record.Type, record.Name, record.Value)
+	}
+	if report.MailFromDomain != "" {
+		fmt.Printf("MAIL FROM: %s\n", report.MailFromDomain)
+	}
+	if report.SPFRecord != "" {
+		fmt.Printf("SPF:       %s (aligned=%t)\n", report.SPFRecord, report.SPFAligned)
+	}
+	if report.DMARCRecord != "" {
+		fmt.Printf("DMARC:     %s\n", report.DMARCRecord)
+	}
+
+	if report.Aligned() {
+		fmt.Println("Sending domain is fully aligned")
+		return nil
+	}
+
+	fmt.Println("\nProblems:")
+	for _, problem := range report.Problems {
+		fmt.Printf("- %s\n", problem)
+	}
+	return fmt.Errorf("%d deliverability problems found for %s", len(report.Problems), domain)
 }
 
-func getWeekStart() time.Time {
-	now := time.Now().UTC()
-	weekday := int(now.Weekday())
-	if weekday == 0 { // Sunday
-		weekday = 7
+func runMigrations() error {
+	err := db.RunMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
 	}
-	daysToMonday := weekday - 1
-	monday := now.AddDate(0, 0, -daysToMonday)
-	return time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, time.UTC)
-}
\ No newline at end of file
+
+	fmt.Println("Database migrations completed")
+	return nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// tuiFailureLimit is how many failed emails and webhook deliveries the
+// failures screen shows
+const tuiFailureLimit = 20
+
+// tuiPreviewWidth truncates entry previews in the entries list
+const tuiPreviewWidth = 70
+
+// runTUI is an interactive menu over the same queries as the list commands,
+// for browsing without remembering subcommands. It reads choices from in
+// until the user quits or in is closed.
+func runTUI(in io.Reader, out io.Writer) error {
+	t := &tui{in: bufio.NewScanner(in), out: out}
+
+	for {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "What Did You Get Done This Week")
+		fmt.Fprintln(out, "  1) Users")
+		fmt.Fprintln(out, "  2) Entries for a user")
+		fmt.Fprintln(out, "  3) Email outbox")
+		fmt.Fprintln(out, "  4) Recent failures")
+		fmt.Fprintln(out, "  q) Quit")
+
+		choice, ok := t.prompt("> ")
+		if !ok {
+			return nil
+		}
+
+		var err error
+		switch strings.ToLower(choice) {
+		case "1", "u", "users":
+			err = listUsers()
+		case "2", "e", "entries":
+			err = t.browseEntries()
+		case "3", "o", "outbox":
+			err = t.showOutbox()
+		case "4", "f", "failures":
+			err = t.showFailures()
+		case "q", "quit", "exit":
+			return nil
+		case "":
+			continue
+		default:
+			fmt.Fprintf(out, "Unknown choice %q\n", choice)
+			continue
+		}
+
+		// Errors are shown rather than ending the session, so a typo in an
+		// email address doesn't drop the user back to the shell
+		if err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+		}
+	}
+}
+
+type tui struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// prompt reads one trimmed line, returning false at end of input
+func (t *tui) prompt(label string) (string, bool) {
+	fmt.Fprint(t.out, label)
+	if !t.in.Scan() {
+		fmt.Fprintln(t.out)
+		return "", false
+	}
+	return strings.TrimSpace(t.in.Text()), true
+}
+
+// browseEntries lists a user's recent entries and shows any one in full
+func (t *tui) browseEntries() error {
+	ctx := context.Background()
+
+	emailAddr, ok := t.prompt("Email: ")
+	if !ok || emailAddr == "" {
+		return nil
+	}
+	user, err := emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %s", emailAddr)
+	}
+
+	days := 7
+	if value, ok := t.prompt("Days to show [7]: "); ok && value != "" {
+		if days, err = strconv.Atoi(value); err != nil || days <= 0 {
+			return apperrors.New(apperrors.CodeInvalidInput, "days must be a positive number")
+		}
+	}
+
+	to := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	entries, err := coreService.GetEntriesBetween(ctx, user.ID, to.AddDate(0, 0, -days), to)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Fprintf(t.out, "No entries in the last %d days\n", days)
+		return nil
+	}
+
+	for {
+		fmt.Fprintf(t.out, "%-4s %-12s %s\n", "#", "DATE", "ENTRY")
+		fmt.Fprintln(t.out, strings.Repeat("-", 90))
+		for i, entry := range entries {
+			fmt.Fprintf(t.out, "%-4d %-12s %s\n", i+1, entry.EntryDate.Format("2006-01-02"), entryPreview(entry))
+		}
+
+		choice, ok := t.prompt("Entry # to read (enter to go back): ")
+		if !ok || choice == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(choice)
+		if err != nil || n < 1 || n > len(entries) {
+			fmt.Fprintf(t.out, "Choose 1-%d\n", len(entries))
+			continue
+		}
+
+		entry := entries[n-1]
+		fmt.Fprintf(t.out, "\n%s\n%s\n\n", entry.EntryDate.Format("Monday, January 2, 2006"), entry.RawContent)
+	}
+}
+
+// entryPreview is the first line of an entry, cut to fit one table row
+func entryPreview(entry *models.Entry) string {
+	preview := strings.TrimSpace(entry.RawContent)
+	if i := strings.IndexAny(preview, "\r\n"); i >= 0 {
+		preview = preview[:i] + " ..."
+	}
+	if runes := []rune(preview); len(runes) > tuiPreviewWidth {
+		preview = string(runes[:tuiPreviewWidth-3]) + "..."
+	}
+	return preview
+}
+
+// showOutbox prints email counts by status and offers to send due emails now
+func (t *tui) showOutbox() error {
+	ctx := context.Background()
+
+	query := `
+		SELECT status, COUNT(*), MIN(created_at)
+		FROM email_logs
+		GROUP BY status
+		ORDER BY status`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to query email outbox: %w", err)
+	}
+	defer rows.Close()
+
+	fmt.Fprintf(t.out, "%-10s %-8s %s\n", "STATUS", "COUNT", "OLDEST")
+	fmt.Fprintln(t.out, strings.Repeat("-", 50))
+
+	pending := 0
+	for rows.Next() {
+		var status string
+		var count int
+		var oldest time.Time
+		if err := rows.Scan(&status, &count, &oldest); err != nil {
+			return fmt.Errorf("failed to scan outbox status: %w", err)
+		}
+		if status == models.EmailStatusPending {
+			pending = count
+		}
+		fmt.Fprintf(t.out, "%-10s %-8d %s\n", status, count, oldest.Format(time.RFC3339))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read outbox status: %w", err)
+	}
+
+	if pending == 0 {
+		return nil
+	}
+	answer, ok := t.prompt("Send due emails now? [y/N]: ")
+	if !ok || !strings.EqualFold(answer, "y") {
+		return nil
+	}
+	return processOutbox()
+}
+
+// showFailures prints the most recent failed emails and webhook deliveries
+func (t *tui) showFailures() error {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, recipient_email, email_type, retry_count, COALESCE(error_message, ''), updated_at
+		FROM email_logs
+		WHERE status = $1
+		ORDER BY updated_at DESC
+		LIMIT $2`
+
+	rows, err := db.QueryContext(ctx, query, models.EmailStatusFailed, tuiFailureLimit)
+	if err != nil {
+		return fmt.Errorf("failed to query failed emails: %w", err)
+	}
+	defer rows.Close()
+
+	fmt.Fprintln(t.out, "Failed emails")
+	fmt.Fprintf(t.out, "%-8s %-30s %-15s %-8s %s\n", "ID", "RECIPIENT", "TYPE", "RETRIES", "UPDATED")
+	fmt.Fprintln(t.out, strings.Repeat("-", 90))
+	for rows.Next() {
+		var id, retries int
+		var recipient, emailType, errorMessage string
+		var updatedAt time.Time
+		if err := rows.Scan(&id, &recipient, &emailType, &retries, &errorMessage, &updatedAt); err != nil {
+			return fmt.Errorf("failed to scan failed email: %w", err)
+		}
+		fmt.Fprintf(t.out, "%-8d %-30s %-15s %-8d %s\n", id, recipient, emailType, retries, updatedAt.Format(time.RFC3339))
+		if errorMessage != "" {
+			fmt.Fprintf(t.out, "         error: %s\n", errorMessage)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read failed emails: %w", err)
+	}
+
+	deliveries, err := webhookService.ListDeliveries(ctx, 0, tuiFailureLimit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(t.out)
+	fmt.Fprintln(t.out, "Failed webhook deliveries")
+	fmt.Fprintf(t.out, "%-8s %-9s %-20s %-9s %s\n", "ID", "ENDPOINT", "EVENT", "ATTEMPTS", "CREATED")
+	fmt.Fprintln(t.out, strings.Repeat("-", 90))
+	for _, d := range deliveries {
+		if d.Status != models.WebhookStatusFailed {
+			continue
+		}
+		fmt.Fprintf(t.out, "%-8d %-9d %-20s %-9d %s\n", d.ID, d.EndpointID, d.EventType, d.Attempts, d.CreatedAt.Format(time.RFC3339))
+		if d.ErrorMessage != nil {
+			fmt.Fprintf(t.out, "         error: %s\n", *d.ErrorMessage)
+		}
+	}
+
+	return nil
+}
@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -12,46 +16,187 @@ import (
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/core"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/embeddings"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	domainevents "github.com/jamesonstone/what-did-you-get-done-this-week/internal/events"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/inbound"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/jobqueue"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/llm"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/webhooks"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
 )
 
-type EmailData struct {
-	From    string `json:"from"`
-	Subject string `json:"subject"`
-	Body    string `json:"body"`
-}
+// lambdaMaxOpenConns caps the pool in each Lambda container, which handles
+// one event at a time; concurrency comes from more containers, and each
+// holds its connections while warm
+const lambdaMaxOpenConns = 2
 
-func main() {
-	lambda.Start(handleSESEvent)
+// app is what the handlers share. It is built once per Lambda container and
+// reused by every warm invocation, so config, the DB pool and the services
+// aren't rebuilt per event.
+type app struct {
+	cfg   *config.Config
+	db    *database.DB
+	core  *core.Service
+	guard *inbound.Guard
+	// messages keeps each reply so a failed one can be replayed
+	messages *inbound.Store
+	// guardErr is why the inbound webhook is unavailable, when guard is nil
+	guardErr error
+	// limits bound the size and type of mail that is processed
+	limits inbound.Limits
 }
 
-func handleSESEvent(ctx context.Context, sesEvent events.SESEvent) error {
+var (
+	sharedMu  sync.Mutex
+	sharedApp *app
+)
+
+func main() {
 	logrus.SetLevel(logrus.InfoLevel)
 	logrus.SetFormatter(&logrus.JSONFormatter{})
 
+	// Build during the init phase so the first event doesn't pay for it. A
+	// failure is retried by the first invocation.
+	if _, err := getApp(); err != nil {
+		logrus.WithError(err).Error("Failed to initialize, retrying on first event")
+	}
+
+	handler, err := selectHandler(os.Getenv("PARSER_HANDLER"))
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid PARSER_HANDLER")
+	}
+	lambda.Start(handler)
+}
+
+// selectHandler picks the Lambda entry point: "ses" (the default) for SES
+// receipt rule events, "webhook" for the API Gateway inbound webhook. One
+// function serves one trigger, so each is deployed with its own setting.
+func selectHandler(name string) (interface{}, error) {
+	switch name {
+	case "", "ses":
+		return handleSESEvent, nil
+	case "webhook":
+		return handleWebhook, nil
+	default:
+		return nil, fmt.Errorf("unknown handler %q, expected ses or webhook", name)
+	}
+}
+
+// getApp returns the container's app, building it on first use. Nothing is
+// cached after a failure, so a database that was briefly unreachable doesn't
+// leave the container broken.
+func getApp() (*app, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if sharedApp != nil {
+		return sharedApp, nil
+	}
+
+	a, err := newApp(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	sharedApp = a
+	return a, nil
+}
+
+func newApp(ctx context.Context) (*app, error) {
+	start := time.Now()
+
 	cfg, err := config.Load()
 	if err != nil {
-		logrus.WithError(err).Error("Failed to load config")
-		return err
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.DBMaxOpenConns <= 0 || cfg.DBMaxOpenConns > lambdaMaxOpenConns {
+		cfg.DBMaxOpenConns = lambdaMaxOpenConns
+	}
+	if cfg.DBMaxIdleConns > cfg.DBMaxOpenConns {
+		cfg.DBMaxIdleConns = cfg.DBMaxOpenConns
 	}
 
 	db, err := database.New(cfg)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to connect to database")
-		return err
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	coreService, err := newCoreService(ctx, cfg, db)
+	if err != nil {
+		db.Close()
+		return nil, err
 	}
-	defer db.Close()
 
+	a := &app{cfg: cfg, db: db, core: coreService, messages: inbound.NewStore(db), limits: inbound.Limits{
+		MaxBytes:       cfg.InboundMaxBytes,
+		MaxBodyBytes:   cfg.InboundMaxBodyBytes,
+		OversizePolicy: cfg.InboundOversizePolicy,
+	}}
+	a.guard, a.guardErr = inbound.NewGuard(db, cfg.InboundWebhookSecret, cfg.InboundAllowedIPs, cfg.InboundRateLimit)
+
+	logrus.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Parser initialized")
+	return a, nil
+}
+
+// newCoreService wires the core service and its dependencies
+func newCoreService(ctx context.Context, cfg *config.Config, db *database.DB) (*core.Service, error) {
 	emailService, err := email.NewService(db, cfg)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to create email service")
-		return err
+		return nil, fmt.Errorf("failed to create email service: %w", err)
+	}
+
+	bus, err := domainevents.NewBus(ctx, cfg.EventBus, cfg.AWSRegion, cfg.EventBusTarget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event bus: %w", err)
+	}
+
+	webhookService := webhooks.NewService(db)
+	bus.Subscribe("webhooks", webhookService.HandleEvent)
+	emailService.SetEvents(bus)
+
+	queue, err := jobqueue.New(cfg, db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job queue: %w", err)
 	}
+	emailService.SetQueue(queue)
 
 	coreService := core.NewService(db, emailService)
+	coreService.SetEvents(bus)
+	coreService.SetEntryMergeWindow(cfg.EntryMergeWindow)
+	coreService.SetEmailChangeAliasWindow(cfg.EmailChangeAliasWindow)
+	coreService.SetRetentionPolicies(cfg.RetentionPolicies)
+	coreService.SetClarificationPolicy(core.ClarificationPolicy{
+		MaxAttempts: cfg.ClarificationMaxAttempts,
+		ResetAfter:  cfg.ClarificationResetAfter,
+		AdminEmail:  cfg.ClarificationAdminEmail,
+	})
+
+	llmService, err := llm.NewService(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create LLM service: %w", err)
+	}
+	llmService.SetCallLog(db)
+	coreService.SetLLM(llmService)
+
+	embeddingsService, err := embeddings.NewService(db, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings service: %w", err)
+	}
+	coreService.SetEmbeddings(embeddingsService)
+
+	return coreService, nil
+}
+
+func handleSESEvent(ctx context.Context, sesEvent events.SimpleEmailEvent) error {
+	a, err := getApp()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initialize")
+		return err
+	}
 
 	for _, record := range sesEvent.Records {
-		if err := processEmailRecord(ctx, coreService, record); err != nil {
+		if err := processEmailRecord(ctx, a, record); err != nil {
 			logrus.WithError(err).Error("Failed to process email record")
 			continue
 		}
@@ -60,7 +205,7 @@ func handleSESEvent(ctx context.Context, sesEvent events.SESEvent) error {
 	return nil
 }
 
-func processEmailRecord(ctx context.Context, coreService *core.Service, record events.SESEventRecord) error {
+func processEmailRecord(ctx context.Context, a *app, record events.SimpleEmailRecord) error {
 	ses := record.SES
 	mail := ses.Mail
 
@@ -70,25 +215,42 @@ func processEmailRecord(ctx context.Context, coreService *core.Service, record e
 		"source":     mail.Source,
 	}).Info("Processing inbound email")
 
-	// Extract sender email
-	senderEmail := mail.Source
-	if senderEmail == "" {
-		return fmt.Errorf("no sender email found")
-	}
-
 	// Get email content from S3 (if stored there) or from the SES event
-	emailData, err := extractEmailContent(record)
+	reply, err := extractEmailContent(record)
 	if err != nil {
 		return fmt.Errorf("failed to extract email content: %w", err)
 	}
 
-	// Process the email reply
-	err = coreService.HandleEmailReply(ctx, senderEmail, emailData.Subject, emailData.Body)
+	payload, err := json.Marshal(reply)
+	if err != nil {
+		return fmt.Errorf("failed to encode email content: %w", err)
+	}
+	if err := a.limits.CheckSize(len(payload)); err != nil {
+		logrus.WithError(err).WithField("message_id", mail.MessageID).Warn("Refused inbound email")
+		return err
+	}
+	msg := &models.InboundMessage{
+		Source:    models.InboundSourceSES,
+		MessageID: optional(mail.MessageID),
+		S3Bucket:  optional(ses.Receipt.Action.BucketName),
+		S3Key:     optional(ses.Receipt.Action.ObjectKey),
+		Payload:   string(payload),
+	}
+	recordMessage(ctx, a, msg)
+
+	// Match by the reply+<token> recipient when present, else by sender
+	senderEmail, err := inbound.Handle(ctx, a.core, nil, a.limits, reply)
+	finishMessage(ctx, a, msg, senderEmail, err)
+	if inbound.IsIgnored(err) {
+		logrus.WithError(err).WithField("message_id", mail.MessageID).Info("Inbound email is not a reply")
+		return nil
+	}
 	if err != nil {
 		logrus.WithError(err).WithFields(logrus.Fields{
 			"sender":     senderEmail,
-			"subject":    emailData.Subject,
+			"subject":    reply.Subject,
 			"message_id": mail.MessageID,
+			"error_code": apperrors.CodeOf(err),
 		}).Error("Failed to handle email reply")
 		return err
 	}
@@ -101,14 +263,15 @@ func processEmailRecord(ctx context.Context, coreService *core.Service, record e
 	return nil
 }
 
-func extractEmailContent(record events.SESEventRecord) (*EmailData, error) {
+func extractEmailContent(record events.SimpleEmailRecord) (*inbound.Reply, error) {
 	ses := record.SES
 	mail := ses.Mail
 
 	// For now, we'll extract basic info from the SES event
 	// In a full implementation, you'd retrieve the raw email from S3
-	emailData := &EmailData{
+	emailData := &inbound.Reply{
 		From:    mail.Source,
+		To:      ses.Receipt.Recipients,
 		Subject: "Daily Journal Reply", // Would be extracted from the actual email
 		Body:    "",                    // Would be extracted from the actual email
 	}
@@ -123,7 +286,7 @@ func extractEmailContent(record events.SESEventRecord) (*EmailData, error) {
 	
 	// This is a simplified version - in production you'd implement
 	// proper email parsing from S3
-	if len(record.SES.Receipt.Action.S3Action.BucketName) > 0 {
+	if len(record.SES.Receipt.Action.BucketName) > 0 {
 		// Email was stored in S3, would retrieve and parse it here
 		logrus.Info("Email stored in S3, would retrieve and parse")
 	}
@@ -131,48 +294,120 @@ func extractEmailContent(record events.SESEventRecord) (*EmailData, error) {
 	return emailData, nil
 }
 
-// Alternative HTTP handler for webhook-based email processing
+// handleWebhook processes replies posted to the inbound webhook through API
+// Gateway; it runs when PARSER_HANDLER=webhook
 func handleWebhook(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	logrus.SetLevel(logrus.InfoLevel)
-	logrus.SetFormatter(&logrus.JSONFormatter{})
-
-	cfg, err := config.Load()
+	a, err := getApp()
 	if err != nil {
-		logrus.WithError(err).Error("Failed to load config")
+		logrus.WithError(err).Error("Failed to initialize")
 		return events.APIGatewayProxyResponse{StatusCode: 500}, err
 	}
 
-	db, err := database.New(cfg)
-	if err != nil {
-		logrus.WithError(err).Error("Failed to connect to database")
-		return events.APIGatewayProxyResponse{StatusCode: 500}, err
+	// Authenticate the request before doing any work for it
+	if a.guard == nil {
+		logrus.WithError(a.guardErr).Error("Inbound webhook is not configured")
+		return events.APIGatewayProxyResponse{StatusCode: 500}, a.guardErr
 	}
-	defer db.Close()
-
-	emailService, err := email.NewService(db, cfg)
-	if err != nil {
-		logrus.WithError(err).Error("Failed to create email service")
-		return events.APIGatewayProxyResponse{StatusCode: 500}, err
+	sourceIP := request.RequestContext.Identity.SourceIP
+	if err := a.guard.CheckSource(sourceIP); err != nil {
+		logrus.WithError(err).WithField("source_ip", sourceIP).Warn("Rejected inbound webhook")
+		return errorResponse(err), nil
+	}
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		if body, err = base64.StdEncoding.DecodeString(request.Body); err != nil {
+			return errorResponse(apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid base64 body")), nil
+		}
+	}
+	if err := a.limits.CheckSize(len(body)); err != nil {
+		logrus.WithError(err).WithField("source_ip", sourceIP).Warn("Rejected inbound webhook")
+		return errorResponse(err), nil
+	}
+	if err := a.guard.Verify(request.Headers, body); err != nil {
+		logrus.WithError(err).WithField("source_ip", sourceIP).Warn("Rejected inbound webhook")
+		return errorResponse(err), nil
 	}
 
-	coreService := core.NewService(db, emailService)
+	// Keep the body as received, so it can be replayed if parsing it fails
+	msg := &models.InboundMessage{Source: models.InboundSourceWebhook, Payload: string(body)}
+	recordMessage(ctx, a, msg)
 
 	// Parse webhook payload
-	var emailData EmailData
-	if err := json.Unmarshal([]byte(request.Body), &emailData); err != nil {
+	var reply inbound.Reply
+	if err := json.Unmarshal(body, &reply); err != nil {
 		logrus.WithError(err).Error("Failed to parse webhook payload")
+		finishMessage(ctx, a, msg, "", err)
 		return events.APIGatewayProxyResponse{StatusCode: 400}, err
 	}
 
-	// Process the email
-	err = coreService.HandleEmailReply(ctx, emailData.From, emailData.Subject, emailData.Body)
+	// Process the email, once the sender is within their rate limit
+	senderEmail, err := inbound.Handle(ctx, a.core, a.guard, a.limits, &reply)
+	finishMessage(ctx, a, msg, senderEmail, err)
+	if inbound.IsIgnored(err) {
+		logrus.WithError(err).Info("Inbound webhook message is not a reply")
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers:    jsonHeaders,
+			Body:       `{"status": "ignored"}`,
+		}, nil
+	}
 	if err != nil {
-		logrus.WithError(err).Error("Failed to handle email reply")
-		return events.APIGatewayProxyResponse{StatusCode: 500}, err
+		logrus.WithError(err).WithField("error_code", apperrors.CodeOf(err)).Error("Failed to handle email reply")
+		return errorResponse(err), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: 200,
+		Headers:    jsonHeaders,
 		Body:       `{"status": "success"}`,
 	}, nil
-}
\ No newline at end of file
+}
+
+// recordMessage keeps msg for replay. A failure is only logged, so the
+// reply is still processed.
+func recordMessage(ctx context.Context, a *app, msg *models.InboundMessage) {
+	if err := a.messages.Record(ctx, msg); err != nil {
+		logrus.WithError(err).Warn("Failed to record inbound message")
+	}
+}
+
+// finishMessage records how processing msg went, if msg was recorded
+func finishMessage(ctx context.Context, a *app, msg *models.InboundMessage, sender string, procErr error) {
+	if msg.ID == 0 {
+		return
+	}
+	if err := a.messages.Finish(ctx, msg.ID, sender, procErr); err != nil {
+		logrus.WithError(err).WithField("inbound_message_id", msg.ID).Warn("Failed to update inbound message")
+	}
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// jsonHeaders are the headers of the webhook's responses
+var jsonHeaders = map[string]string{"Content-Type": "application/json"}
+
+// errorResponse reports err with the status for its code. Internal errors,
+// which can carry database or driver messages, aren't echoed back, as in
+// the API's writeAppError.
+func errorResponse(err error) events.APIGatewayProxyResponse {
+	code := apperrors.CodeOf(err)
+	message := err.Error()
+	if code == apperrors.CodeInternal {
+		message = "internal error"
+	}
+
+	body, _ := json.Marshal(struct {
+		Error string         `json:"error"`
+		Code  apperrors.Code `json:"code"`
+	}{message, code})
+	return events.APIGatewayProxyResponse{
+		StatusCode: apperrors.HTTPStatus(code),
+		Headers:    jsonHeaders,
+		Body:       string(body),
+	}
+}
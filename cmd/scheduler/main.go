@@ -2,30 +2,77 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/go-co-op/gocron"
 	"github.com/sirupsen/logrus"
 
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/analytics"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/anomalies"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/billing"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/core"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/embeddings"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/events"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/infra"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/integrations/credentials"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/integrations/msteams"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/integrations/slack"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/jobqueue"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/jobs"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/llm"
-	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/models"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/orgs"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/retention"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/sharecard"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/webhooks"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
 )
 
+// lambdaMaxOpenConns caps the pool in lambda mode, where each invocation
+// runs the due jobs one after another
+const lambdaMaxOpenConns = 2
+
 func main() {
+	simulate := flag.Bool("simulate", false, "report who would be sent prompts and summaries at --at, then exit without sending anything")
+	atFlag := flag.String("at", "", "instant to simulate, e.g. 2024-05-03T16:00Z (default now)")
+	flag.Parse()
+
 	logrus.SetLevel(logrus.InfoLevel)
 	logrus.SetFormatter(&logrus.JSONFormatter{})
 
+	at := time.Now()
+	if *atFlag != "" {
+		parsed, err := parseInstant(*atFlag)
+		if err != nil {
+			logrus.WithError(err).Fatal("Invalid --at")
+		}
+		at = parsed
+	}
+	if *simulate {
+		// Keep the report readable; failures are still logged
+		logrus.SetLevel(logrus.WarnLevel)
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to load config")
 	}
+	if cfg.SchedulerMode == "lambda" {
+		if cfg.DBMaxOpenConns <= 0 || cfg.DBMaxOpenConns > lambdaMaxOpenConns {
+			cfg.DBMaxOpenConns = lambdaMaxOpenConns
+		}
+		if cfg.DBMaxIdleConns > cfg.DBMaxOpenConns {
+			cfg.DBMaxIdleConns = cfg.DBMaxOpenConns
+		}
+	}
 
 	db, err := database.New(cfg)
 	if err != nil {
@@ -33,8 +80,10 @@ func main() {
 	}
 	defer db.Close()
 
-	if err := db.RunMigrations(); err != nil {
-		logrus.WithError(err).Fatal("Failed to run database migrations")
+	if !*simulate {
+		if err := db.RunMigrations(); err != nil {
+			logrus.WithError(err).Fatal("Failed to run database migrations")
+		}
 	}
 
 	emailService, err := email.NewService(db, cfg)
@@ -42,38 +91,124 @@ func main() {
 		logrus.WithError(err).Fatal("Failed to create email service")
 	}
 
+	bus, err := events.NewBus(context.Background(), cfg.EventBus, cfg.AWSRegion, cfg.EventBusTarget)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create event bus")
+	}
+
+	webhookService := webhooks.NewService(db)
+	bus.Subscribe("webhooks", webhookService.HandleEvent)
+	emailService.SetEvents(bus)
+
+	queue, err := jobqueue.New(cfg, db)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create job queue")
+	}
+	defer queue.Close()
+	emailService.SetQueue(queue)
+
 	coreService := core.NewService(db, emailService)
+	coreService.SetEvents(bus)
+	coreService.SetRetentionPolicies(cfg.RetentionPolicies)
+	coreService.RegisterChannel(models.DeliveryChannelMSTeams, msteams.NewClient())
+	coreService.SetPromptRecap(core.PromptRecap{Enabled: cfg.PromptRecap, LLM: cfg.PromptRecapLLM})
 
 	llmService, err := llm.NewService(cfg)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to create LLM service")
 	}
+	llmService.SetCallLog(db)
+	coreService.SetLLM(llmService)
 
-	scheduler := gocron.NewScheduler(time.UTC)
+	embeddingsService, err := embeddings.NewService(db, cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create embeddings service")
+	}
+	coreService.SetEmbeddings(embeddingsService)
 
-	// Schedule daily prompts (run every hour to check for users)
-	scheduler.Every(1).Hour().Do(func() {
-		if err := sendDailyPrompts(context.Background(), coreService, emailService); err != nil {
-			logrus.WithError(err).Error("Failed to send daily prompts")
-		}
-	})
+	shareCards, err := sharecard.NewStore(context.Background(), cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create share card store")
+	}
+	coreService.SetShareCards(shareCards)
+
+	credentialStore, err := credentials.NewStore(db, cfg.IntegrationEncryptionKeys)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create credential store")
+	}
+	coreService.SetCredentials(credentialStore)
+
+	retentionService, err := retention.NewService(db, cfg.RetentionPolicies)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create retention service")
+	}
 
-	// Schedule weekly summaries (run every Friday at 4:30 PM UTC)
-	scheduler.Every(1).Week().Friday().At("16:30").Do(func() {
-		if err := sendWeeklySummaries(context.Background(), coreService, emailService, llmService); err != nil {
-			logrus.WithError(err).Error("Failed to send weekly summaries")
+	billingService, err := billing.NewService(db, emailService, cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create billing service")
+	}
+
+	registry := jobs.NewRegistry(db, cfg.JobsDisabled)
+	services := jobs.Services{
+		Core:       coreService,
+		Email:      emailService,
+		LLM:        llmService,
+		Webhooks:   webhookService,
+		Analytics:  analytics.NewService(db),
+		Embeddings: embeddingsService,
+		Retention:  retentionService,
+		Anomalies:  anomalies.NewService(db),
+		Orgs:       orgs.NewService(db),
+		Billing:    billingService,
+		Slack:      slack.NewClient(cfg.SlackBotToken),
+	}
+	jobs.RegisterBuiltin(registry, services)
+	if err := registry.SetSchedules(cfg.JobSchedules); err != nil {
+		logrus.WithError(err).Fatal("Invalid JOB_SCHEDULES")
+	}
+
+	if *simulate {
+		sim, err := jobs.Simulate(context.Background(), registry, services, at)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to simulate scheduler")
 		}
-	})
+		printSimulation(sim)
+		return
+	}
 
-	// Schedule email outbox processing (every 5 minutes)
-	scheduler.Every(5).Minutes().Do(func() {
-		if err := emailService.ProcessOutbox(context.Background()); err != nil {
-			logrus.WithError(err).Error("Failed to process email outbox")
+	switch cfg.SchedulerMode {
+	case "tick":
+		// One pass, from cron, a Kubernetes CronJob or an ECS scheduled task
+		if err := registry.Tick(context.Background(), time.Now(), cfg.SchedulerMaxLateness); err != nil {
+			logrus.WithError(err).Fatal("Scheduler tick failed")
 		}
-	})
+		return
+	case "lambda":
+		// One pass per invocation, from an EventBridge schedule rule
+		lambda.Start(func(ctx context.Context) error {
+			return registry.Tick(ctx, time.Now(), cfg.SchedulerMaxLateness)
+		})
+		return
+	}
+
+	scheduler := gocron.NewScheduler(time.UTC)
+	if err := registry.Schedule(scheduler); err != nil {
+		logrus.WithError(err).Fatal("Failed to schedule jobs")
+	}
 
 	scheduler.StartAsync()
-	logrus.Info("Scheduler started")
+	logrus.WithField("app_env", cfg.AppEnv).Info("Scheduler started")
+
+	// Send mail as soon as any process queues it, not only on schedule
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	defer stopListening()
+	go func() {
+		if err := registry.Listen(listenCtx, queue, email.OutboxTopic, "email-outbox"); err != nil {
+			logrus.WithError(err).Error("Failed to listen for queued email, sending on schedule only")
+		}
+	}()
+
+	go warnDeliverability(cfg)
 
 	// Wait for interrupt signal
 	c := make(chan os.Signal, 1)
@@ -84,116 +219,94 @@ func main() {
 	scheduler.Stop()
 }
 
-func sendDailyPrompts(ctx context.Context, coreService *core.Service, emailService *email.Service) error {
-	currentHour := time.Now().UTC().Hour()
-	
-	users, err := coreService.GetUsersForDailyPrompt(ctx, currentHour)
-	if err != nil {
-		return err
-	}
-
-	for _, user := range users {
-		// Check if user's local time matches their preferred prompt time
-		if shouldSendPrompt(user, currentHour) {
-			err := emailService.SendDailyPrompt(ctx, user.ID, user.Email, user.ProjectFocus)
-			if err != nil {
-				logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to send daily prompt")
-				continue
-			}
-			
-			logrus.WithField("user_id", user.ID).Info("Daily prompt queued")
+// parseInstant accepts RFC 3339 with or without seconds
+func parseInstant(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00"} {
+		if at, err := time.Parse(layout, value); err == nil {
+			return at, nil
 		}
 	}
-
-	return nil
+	return time.Time{}, fmt.Errorf("%q is not a time like 2024-05-03T16:00Z", value)
 }
 
-func shouldSendPrompt(user *models.User, currentHour int) bool {
-	// Load user's timezone
-	loc, err := time.LoadLocation(user.Timezone)
-	if err != nil {
-		logrus.WithError(err).WithField("timezone", user.Timezone).Error("Invalid timezone")
-		return false
-	}
-
-	// Get current time in user's timezone
-	userTime := time.Now().In(loc)
-	promptHour := user.PromptTime.Hour()
+func printSimulation(sim *jobs.Simulation) {
+	fmt.Printf("Simulating %s (%s)\n\n", sim.At.Format(time.RFC3339), sim.At.Format("Monday"))
 
-	return userTime.Hour() == promptHour
-}
-
-func sendWeeklySummaries(ctx context.Context, coreService *core.Service, emailService *email.Service, llmService *llm.Service) error {
-	// Get all verified users
-	users, err := getAllVerifiedUsers(ctx, coreService)
-	if err != nil {
-		return err
+	if len(sim.Jobs) == 0 {
+		fmt.Println("No jobs are scheduled for this minute.")
+		return
 	}
-
-	for _, user := range users {
-		// Get entries for this week
-		entries, err := getWeekEntries(ctx, coreService, user.ID)
-		if err != nil {
-			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to get week entries")
-			continue
+	fmt.Println("Jobs:")
+	for _, job := range sim.Jobs {
+		state := "runs"
+		if !job.Enabled {
+			state = "skipped: " + job.Reason
 		}
+		fmt.Printf("  %-22s %-16s %s\n", job.Name, job.Schedule, state)
+	}
 
-		if len(entries) == 0 {
-			logrus.WithField("user_id", user.ID).Info("No entries for this week, skipping summary")
-			continue
+	for _, job := range sim.Jobs {
+		switch job.Name {
+		case "daily-prompts":
+			printDecisions("Daily prompts", sim.Prompts, sim.At)
+		case "weekly-summaries":
+			printDecisions("Weekly summaries", sim.Summaries, sim.At)
 		}
+	}
+}
 
-		// Generate summary using LLM
-		summary, err := llmService.GenerateWeeklySummary(ctx, entries)
-		if err != nil {
-			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to generate weekly summary")
-			continue
-		}
+func printDecisions(title string, decisions []jobs.Decision, at time.Time) {
+	fmt.Printf("\n%s:\n", title)
+	if len(decisions) == 0 {
+		fmt.Println("  No users.")
+		return
+	}
 
-		// Send summary email
-		weekStart := getWeekStart()
-		err = emailService.SendWeeklySummary(ctx, user.ID, user.Email, weekStart, 
-			summary.Paragraph, summary.BulletPoints)
-		if err != nil {
-			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to send weekly summary")
-			continue
+	fmt.Printf("  %-30s %-22s %-17s %-6s %s\n", "EMAIL", "TIMEZONE", "LOCAL TIME", "SEND", "REASON")
+	sending := 0
+	for _, d := range decisions {
+		local := "-"
+		if loc, err := time.LoadLocation(d.User.Timezone); err == nil {
+			local = at.In(loc).Format("2006-01-02 15:04")
 		}
-
-		// Save summary to database
-		err = saveWeeklySummary(ctx, coreService, user.ID, weekStart, summary)
-		if err != nil {
-			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to save weekly summary")
+		send := "no"
+		if d.Send {
+			send = "yes"
+			sending++
 		}
-
-		logrus.WithField("user_id", user.ID).Info("Weekly summary sent")
+		fmt.Printf("  %-30s %-22s %-17s %-6s %s\n", d.User.Email, d.User.Timezone, local, send, d.Reason)
 	}
-
-	return nil
+	fmt.Printf("  %d of %d would be sent\n", sending, len(decisions))
 }
 
-func getWeekStart() time.Time {
-	now := time.Now().UTC()
-	weekday := int(now.Weekday())
-	if weekday == 0 { // Sunday
-		weekday = 7
+// warnDeliverability logs each DKIM, SPF or DMARC problem with the sending
+// domain at startup. Mail still goes out either way, so it never fails.
+func warnDeliverability(cfg *config.Config) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	domain, err := infra.SendingDomain(cfg.EmailFrom)
+	if err != nil {
+		logrus.WithError(err).Warn("Skipping deliverability check")
+		return
 	}
-	daysToMonday := weekday - 1
-	monday := now.AddDate(0, 0, -daysToMonday)
-	return time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, time.UTC)
-}
 
-// Placeholder functions that would need implementation
-func getAllVerifiedUsers(ctx context.Context, coreService *core.Service) ([]*models.User, error) {
-	// Implementation needed
-	return nil, nil
-}
+	setup, err := infra.NewSESSetup(ctx, cfg.AWSSESRegion)
+	if err != nil {
+		logrus.WithError(err).Warn("Skipping deliverability check")
+		return
+	}
 
-func getWeekEntries(ctx context.Context, coreService *core.Service, userID int) ([]*models.Entry, error) {
-	// Implementation needed
-	return nil, nil
-}
+	report, err := setup.CheckDeliverability(ctx, domain)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to check deliverability")
+		return
+	}
 
-func saveWeeklySummary(ctx context.Context, coreService *core.Service, userID int, weekStart time.Time, summary *llm.WeeklySummary) error {
-	// Implementation needed
-	return nil
-}
\ No newline at end of file
+	for _, problem := range report.Problems {
+		logrus.WithField("domain", domain).Warn("Deliverability problem: " + problem)
+	}
+	if !report.Aligned() {
+		logrus.WithField("domain", domain).Warn("Run `cli infra verify-dkim` for the records to publish")
+	}
+}
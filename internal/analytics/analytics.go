@@ -0,0 +1,193 @@
+// Package analytics maintains the dashboard views of reply activity: daily
+// active repliers, reply latency and cohort retention. The views are
+// materialized and refreshed nightly, so reading them never scans entries.
+// They are read from the read replica, if there is one.
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+)
+
+// Views lists the materialized views Refresh maintains
+var Views = []string{
+	"analytics_daily_active_repliers",
+	"analytics_reply_latency",
+	"analytics_cohort_retention",
+}
+
+type Service struct {
+	db *database.DB
+}
+
+func NewService(db *database.DB) *Service {
+	return &Service{db: db}
+}
+
+// DailyActive is the number of users who replied on one day
+type DailyActive struct {
+	Day      time.Time `json:"day"`
+	Repliers int       `json:"repliers"`
+}
+
+// ReplyLatency is how long users took to reply to the prompts sent in one
+// week, as bucket counts and percentiles in minutes
+type ReplyLatency struct {
+	WeekStart    time.Time `json:"week_start"`
+	Replies      int       `json:"replies"`
+	Under1h      int       `json:"under_1h"`
+	From1hTo4h   int       `json:"from_1h_to_4h"`
+	From4hTo12h  int       `json:"from_4h_to_12h"`
+	From12hTo24h int       `json:"from_12h_to_24h"`
+	Over24h      int       `json:"over_24h"`
+	P50Minutes   float64   `json:"p50_minutes"`
+	P90Minutes   float64   `json:"p90_minutes"`
+}
+
+// CohortWeek is how many of the users who signed up in CohortWeek replied
+// WeekNumber weeks later
+type CohortWeek struct {
+	CohortWeek  time.Time `json:"cohort_week"`
+	WeekNumber  int       `json:"week_number"`
+	CohortSize  int       `json:"cohort_size"`
+	ActiveUsers int       `json:"active_users"`
+	Retention   float64   `json:"retention"`
+}
+
+// Dashboard is every view for one date range
+type Dashboard struct {
+	From         time.Time      `json:"from"`
+	To           time.Time      `json:"to"`
+	DailyActive  []DailyActive  `json:"daily_active"`
+	ReplyLatency []ReplyLatency `json:"reply_latency"`
+	Cohorts      []CohortWeek   `json:"cohorts"`
+}
+
+// refreshTimeout bounds refreshing one view in place of DB_QUERY_TIMEOUT,
+// since a refresh scans every entry and prompt
+const refreshTimeout = 15 * time.Minute
+
+// Refresh recomputes every view. Views are refreshed concurrently with
+// readers, so dashboards keep serving the previous data meanwhile.
+func (s *Service) Refresh(ctx context.Context) error {
+	for _, view := range Views {
+		start := time.Now()
+		refreshCtx, cancel := context.WithTimeout(ctx, refreshTimeout)
+		_, err := s.db.ExecContext(refreshCtx, "REFRESH MATERIALIZED VIEW CONCURRENTLY "+view)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to refresh %s: %w", view, err)
+		}
+		logrus.WithFields(logrus.Fields{
+			"view":     view,
+			"duration": time.Since(start).String(),
+		}).Info("Refreshed analytics view")
+	}
+	return nil
+}
+
+// Dashboard reads every view for days, prompt weeks and signup cohorts from
+// from through to
+func (s *Service) Dashboard(ctx context.Context, from, to time.Time) (*Dashboard, error) {
+	dashboard := &Dashboard{From: from, To: to}
+	var err error
+
+	if dashboard.DailyActive, err = s.DailyActiveRepliers(ctx, from, to); err != nil {
+		return nil, err
+	}
+	if dashboard.ReplyLatency, err = s.ReplyLatency(ctx, from, to); err != nil {
+		return nil, err
+	}
+	if dashboard.Cohorts, err = s.CohortRetention(ctx, from, to); err != nil {
+		return nil, err
+	}
+	return dashboard, nil
+}
+
+// DailyActiveRepliers returns the repliers for each day from through to that
+// had any
+func (s *Service) DailyActiveRepliers(ctx context.Context, from, to time.Time) ([]DailyActive, error) {
+	query := `
+		SELECT day, repliers
+		FROM analytics_daily_active_repliers
+		WHERE day BETWEEN $1 AND $2
+		ORDER BY day`
+
+	rows, err := s.db.Reader().QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily active repliers: %w", err)
+	}
+	defer rows.Close()
+
+	days := []DailyActive{}
+	for rows.Next() {
+		var day DailyActive
+		if err := rows.Scan(&day.Day, &day.Repliers); err != nil {
+			return nil, fmt.Errorf("failed to scan daily active repliers: %w", err)
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// ReplyLatency returns reply latency for each week starting from through to
+func (s *Service) ReplyLatency(ctx context.Context, from, to time.Time) ([]ReplyLatency, error) {
+	query := `
+		SELECT week_start, replies, under_1h, from_1h_to_4h, from_4h_to_12h,
+			from_12h_to_24h, over_24h, p50_minutes, p90_minutes
+		FROM analytics_reply_latency
+		WHERE week_start BETWEEN $1 AND $2
+		ORDER BY week_start`
+
+	rows, err := s.db.Reader().QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reply latency: %w", err)
+	}
+	defer rows.Close()
+
+	weeks := []ReplyLatency{}
+	for rows.Next() {
+		var w ReplyLatency
+		err := rows.Scan(&w.WeekStart, &w.Replies, &w.Under1h, &w.From1hTo4h, &w.From4hTo12h,
+			&w.From12hTo24h, &w.Over24h, &w.P50Minutes, &w.P90Minutes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reply latency: %w", err)
+		}
+		weeks = append(weeks, w)
+	}
+	return weeks, rows.Err()
+}
+
+// CohortRetention returns retention by week for the cohorts that signed up
+// in weeks starting from through to
+func (s *Service) CohortRetention(ctx context.Context, from, to time.Time) ([]CohortWeek, error) {
+	query := `
+		SELECT cohort_week, week_number, cohort_size, active_users
+		FROM analytics_cohort_retention
+		WHERE cohort_week BETWEEN $1 AND $2
+		ORDER BY cohort_week, week_number`
+
+	rows, err := s.db.Reader().QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cohort retention: %w", err)
+	}
+	defer rows.Close()
+
+	cohorts := []CohortWeek{}
+	for rows.Next() {
+		var c CohortWeek
+		if err := rows.Scan(&c.CohortWeek, &c.WeekNumber, &c.CohortSize, &c.ActiveUsers); err != nil {
+			return nil, fmt.Errorf("failed to scan cohort retention: %w", err)
+		}
+		if c.CohortSize > 0 {
+			c.Retention = float64(c.ActiveUsers) / float64(c.CohortSize)
+		}
+		cohorts = append(cohorts, c)
+	}
+	return cohorts, rows.Err()
+}
@@ -0,0 +1,241 @@
+// Package anomalies looks for signs that something is quietly broken:
+// prompts that keep bouncing, weeks with entries but no summary, summaries
+// that were never delivered, and email types failing far more than usual.
+// The detect-anomalies job runs it nightly and reports what it finds to the
+// admin.
+package anomalies
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+const (
+	// bounceWindow and bounceThreshold flag a user whose daily prompts
+	// failed bounceThreshold times within bounceWindow
+	bounceWindow    = 7 * 24 * time.Hour
+	bounceThreshold = 3
+
+	// unsentWindow is how far back summaries are checked for delivery, and
+	// unsentGrace how long the outbox has to send one before it is flagged
+	unsentWindow = 14 * 24 * time.Hour
+	unsentGrace  = 6 * time.Hour
+
+	// An email type is spiking when, over the last spikeWindow, at least
+	// spikeMinFailed sends failed, at least spikeMinRate of them, and the
+	// rate is spikeFactor times its rate over the baselineWindow before
+	spikeWindow    = 24 * time.Hour
+	baselineWindow = 7 * 24 * time.Hour
+	spikeMinFailed = 5
+	spikeMinRate   = 0.2
+	spikeFactor    = 2.0
+)
+
+type Service struct {
+	db *database.DB
+}
+
+func NewService(db *database.DB) *Service {
+	return &Service{db: db}
+}
+
+// Detect runs every check as of now. summaryRun is when the weekly summary
+// job last ran; users with entries in that week but no summary are flagged.
+func (s *Service) Detect(ctx context.Context, now, summaryRun time.Time) (*models.AnomalyReport, error) {
+	report := &models.AnomalyReport{CheckedAt: now}
+	var err error
+
+	if report.BouncingUsers, err = s.bouncingUsers(ctx, now); err != nil {
+		return nil, err
+	}
+	if report.MissingSummaries, err = s.missingSummaries(ctx, summaryRun); err != nil {
+		return nil, err
+	}
+	if report.UnsentSummaries, err = s.unsentSummaries(ctx, now); err != nil {
+		return nil, err
+	}
+	if report.FailureSpikes, err = s.failureSpikes(ctx, now); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *Service) bouncingUsers(ctx context.Context, now time.Time) ([]models.UserAnomaly, error) {
+	query := `
+		SELECT u.id, u.email, COUNT(*), MAX(el.error_message)
+		FROM email_logs el
+		JOIN users u ON u.id = el.user_id
+		WHERE el.email_type = $1 AND el.status = $2 AND el.created_at >= $3
+		GROUP BY u.id, u.email
+		HAVING COUNT(*) >= $4
+		ORDER BY COUNT(*) DESC, u.id`
+
+	rows, err := s.db.QueryContext(ctx, query, models.EmailTypeDailyPrompt, models.EmailStatusFailed,
+		now.Add(-bounceWindow), bounceThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bouncing prompts: %w", err)
+	}
+	defer rows.Close()
+
+	anomalies := []models.UserAnomaly{}
+	for rows.Next() {
+		var a models.UserAnomaly
+		var failed int
+		var lastError *string
+		if err := rows.Scan(&a.UserID, &a.Email, &failed, &lastError); err != nil {
+			return nil, fmt.Errorf("failed to scan bouncing prompts: %w", err)
+		}
+		a.Detail = fmt.Sprintf("%d prompts failed in the last %d days", failed, int(bounceWindow.Hours()/24))
+		if lastError != nil && *lastError != "" {
+			a.Detail += ": " + *lastError
+		}
+		anomalies = append(anomalies, a)
+	}
+	return anomalies, rows.Err()
+}
+
+// missingSummaries finds verified users who wrote entries in the week of
+// summaryRun, before it ran, but have no summary for that week
+func (s *Service) missingSummaries(ctx context.Context, summaryRun time.Time) ([]models.UserAnomaly, error) {
+	mondayWeek := period.StartOfWeek(summaryRun, time.Monday)
+	sundayWeek := period.StartOfWeek(summaryRun, time.Sunday)
+
+	query := `
+		WITH weeks AS (
+			SELECT id, email, CASE WHEN week_start = $2 THEN $4::date ELSE $3::date END AS week_start
+			FROM users
+			WHERE is_verified = TRUE
+		)
+		SELECT w.id, w.email, w.week_start, COUNT(e.id)
+		FROM weeks w
+		JOIN entries e ON e.user_id = w.id
+			AND e.entry_date >= w.week_start AND e.created_at < $1 AND e.deleted_at IS NULL
+		WHERE NOT EXISTS (
+			SELECT 1 FROM weekly_summaries ws
+			WHERE ws.user_id = w.id AND ws.week_start_date = w.week_start
+		)
+		GROUP BY w.id, w.email, w.week_start
+		ORDER BY w.id`
+
+	rows, err := s.db.QueryContext(ctx, query, summaryRun, period.WeekStartSunday, mondayWeek, sundayWeek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query missing summaries: %w", err)
+	}
+	defer rows.Close()
+
+	anomalies := []models.UserAnomaly{}
+	for rows.Next() {
+		var a models.UserAnomaly
+		var weekStart time.Time
+		var entries int
+		if err := rows.Scan(&a.UserID, &a.Email, &weekStart, &entries); err != nil {
+			return nil, fmt.Errorf("failed to scan missing summaries: %w", err)
+		}
+		a.Detail = fmt.Sprintf("%d entries but no summary for the week of %s", entries, weekStart.Format("Jan 2"))
+		anomalies = append(anomalies, a)
+	}
+	return anomalies, rows.Err()
+}
+
+// unsentSummaries finds recent summaries whose email was never sent: still
+// pending unsentGrace after it was due, failed, or never queued
+func (s *Service) unsentSummaries(ctx context.Context, now time.Time) ([]models.UserAnomaly, error) {
+	// The summary email is queued just before the summary is saved, and may
+	// be scheduled for later to pace the outbox
+	query := `
+		SELECT u.id, u.email, ws.week_start_date, COALESCE((
+			SELECT el.status FROM email_logs el
+			WHERE el.user_id = ws.user_id AND el.email_type = $3
+			  AND el.created_at >= ws.created_at - INTERVAL '1 hour'
+			ORDER BY el.created_at DESC LIMIT 1
+		), '')
+		FROM weekly_summaries ws
+		JOIN users u ON u.id = ws.user_id
+		WHERE ws.created_at >= $1 AND ws.created_at < $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM email_logs el
+			WHERE el.user_id = ws.user_id AND el.email_type = $3
+			  AND (el.status = $4 OR (el.status = $5 AND el.scheduled_at >= $2))
+			  AND el.created_at >= ws.created_at - INTERVAL '1 hour'
+		)
+		ORDER BY ws.created_at, u.id`
+
+	rows, err := s.db.QueryContext(ctx, query, now.Add(-unsentWindow), now.Add(-unsentGrace),
+		models.EmailTypeWeeklySummary, models.EmailStatusSent, models.EmailStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unsent summaries: %w", err)
+	}
+	defer rows.Close()
+
+	anomalies := []models.UserAnomaly{}
+	for rows.Next() {
+		var a models.UserAnomaly
+		var weekStart time.Time
+		var status string
+		if err := rows.Scan(&a.UserID, &a.Email, &weekStart, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan unsent summaries: %w", err)
+		}
+		if status == "" {
+			status = "never queued"
+		}
+		a.Detail = fmt.Sprintf("summary for the week of %s not sent (%s)", weekStart.Format("Jan 2"), status)
+		anomalies = append(anomalies, a)
+	}
+	return anomalies, rows.Err()
+}
+
+func (s *Service) failureSpikes(ctx context.Context, now time.Time) ([]models.FailureSpike, error) {
+	query := `
+		SELECT email_type,
+			COUNT(*) FILTER (WHERE status = $3 AND created_at >= $2),
+			COUNT(*) FILTER (WHERE status = $4 AND created_at >= $2),
+			COUNT(*) FILTER (WHERE status = $3 AND created_at < $2),
+			COUNT(*) FILTER (WHERE status = $4 AND created_at < $2)
+		FROM email_logs
+		WHERE created_at >= $1 AND status IN ($3, $4)
+		GROUP BY email_type
+		ORDER BY email_type`
+
+	recent := now.Add(-spikeWindow)
+	rows, err := s.db.QueryContext(ctx, query, recent.Add(-baselineWindow), recent,
+		models.EmailStatusSent, models.EmailStatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query email failure rates: %w", err)
+	}
+	defer rows.Close()
+
+	spikes := []models.FailureSpike{}
+	for rows.Next() {
+		var spike models.FailureSpike
+		var baselineSent, baselineFailed int
+		if err := rows.Scan(&spike.EmailType, &spike.Sent, &spike.Failed, &baselineSent, &baselineFailed); err != nil {
+			return nil, fmt.Errorf("failed to scan email failure rates: %w", err)
+		}
+		spike.Rate = failureRate(spike.Sent, spike.Failed)
+		spike.BaselineRate = failureRate(baselineSent, baselineFailed)
+		if isSpike(spike) {
+			spikes = append(spikes, spike)
+		}
+	}
+	return spikes, rows.Err()
+}
+
+func failureRate(sent, failed int) float64 {
+	if sent+failed == 0 {
+		return 0
+	}
+	return float64(failed) / float64(sent+failed)
+}
+
+// isSpike reports whether an email type's recent failures are both
+// substantial and well above its baseline
+func isSpike(spike models.FailureSpike) bool {
+	return spike.Failed >= spikeMinFailed &&
+		spike.Rate >= spikeMinRate &&
+		spike.Rate >= spikeFactor*spike.BaselineRate
+}
@@ -0,0 +1,39 @@
+package anomalies
+
+import (
+	"testing"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+func TestIsSpike(t *testing.T) {
+	tests := []struct {
+		name           string
+		sent, failed   int
+		baselineSent   int
+		baselineFailed int
+		want           bool
+	}{
+		{"quiet", 100, 0, 700, 0, false},
+		{"too few failures", 4, 4, 700, 0, false},
+		{"low rate", 100, 10, 700, 0, false},
+		{"spike from nothing", 20, 10, 700, 0, true},
+		{"usual failure rate", 20, 10, 350, 350, false},
+		{"double the usual", 20, 10, 600, 100, true},
+		{"no baseline traffic", 0, 6, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spike := models.FailureSpike{
+				Sent:         tt.sent,
+				Failed:       tt.failed,
+				Rate:         failureRate(tt.sent, tt.failed),
+				BaselineRate: failureRate(tt.baselineSent, tt.baselineFailed),
+			}
+			if got := isSpike(spike); got != tt.want {
+				t.Errorf("isSpike(%+v) = %v, want %v", spike, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+// Package auth is passwordless sign-in for the web dashboard. A magic link
+// carries a short-lived token signed with AUTH_SECRET; redeeming it (once)
+// starts a session kept in an HttpOnly cookie. Forms posted with a session
+// must carry a CSRF token bound to that session. Only SHA-256 hashes of link
+// and session tokens are stored, so a database dump can't be used to sign in.
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+const (
+	// LinkTTL is how long an emailed sign-in link works
+	LinkTTL = 15 * time.Minute
+	// SessionTTL is how long a session lasts after sign-in
+	SessionTTL = 30 * 24 * time.Hour
+
+	// CookieName is the session cookie
+	CookieName = "wdyg_session"
+	// CSRFField is the form field that carries the CSRF token
+	CSRFField = "csrf_token"
+
+	// minSecretLength is the shortest AUTH_SECRET accepted, in bytes
+	minSecretLength = 32
+)
+
+type Service struct {
+	db           *database.DB
+	emailService *email.Service
+	secret       []byte
+}
+
+// NewService fails unless secret is at least 32 bytes, since it is what
+// magic links and CSRF tokens are signed with
+func NewService(db *database.DB, emailService *email.Service, secret string) (*Service, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("AUTH_SECRET must be at least %d bytes", minSecretLength)
+	}
+	return &Service{db: db, emailService: emailService, secret: []byte(secret)}, nil
+}
+
+// SendMagicLink emails a sign-in link to emailAddr. loginURL is the sign-in
+// endpoint; the token is appended as ?token=. Unknown and unverified
+// addresses get nothing but the same nil error, so the sign-in form doesn't
+// reveal who has an account.
+func (s *Service) SendMagicLink(ctx context.Context, emailAddr, loginURL string) error {
+	user, err := s.emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.IsVerified {
+		logrus.WithField("email", emailAddr).Info("Magic link requested for unknown or unverified address")
+		return nil
+	}
+
+	expiresAt := time.Now().UTC().Add(LinkTTL)
+	token, err := s.signLink(user.ID, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO web_logins (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := s.db.ExecContext(ctx, query, user.ID, hashToken(token), expiresAt); err != nil {
+		return fmt.Errorf("failed to store magic link: %w", err)
+	}
+
+	return s.emailService.SendMagicLink(ctx, user.ID, user.Email, loginURL+"?token="+token, LinkTTL)
+}
+
+// Redeem uses up a magic link and starts a session, returning the session
+// token. Forged, expired, used and revoked links are CodeUnauthorized.
+func (s *Service) Redeem(ctx context.Context, token string) (string, error) {
+	userID, err := s.verifyLink(token, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+
+	// The signature proves we issued the link; the row makes it single-use
+	// and revocable
+	query := `
+		UPDATE web_logins
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND user_id = $2 AND used_at IS NULL
+		RETURNING id`
+
+	var id int
+	err = s.db.QueryRowContext(ctx, query, hashToken(token), userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", apperrors.New(apperrors.CodeUnauthorized, "sign-in link was already used")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to redeem magic link: %w", err)
+	}
+
+	session, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	query = `INSERT INTO web_sessions (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := s.db.ExecContext(ctx, query, userID, hashToken(session), time.Now().UTC().Add(SessionTTL)); err != nil {
+		return "", fmt.Errorf("failed to start session: %w", err)
+	}
+
+	logrus.WithField("user_id", userID).Info("Dashboard session started")
+	return session, nil
+}
+
+// Authenticate returns the verified user a session belongs to, or a
+// CodeUnauthorized error for unknown, expired and revoked sessions
+func (s *Service) Authenticate(ctx context.Context, session string) (*models.User, error) {
+	if session == "" {
+		return nil, apperrors.New(apperrors.CodeUnauthorized, "not signed in")
+	}
+
+	var userID int
+	query := `
+		UPDATE web_sessions
+		SET last_seen_at = NOW()
+		WHERE token_hash = $1 AND expires_at > $2
+		RETURNING user_id`
+
+	err := s.db.QueryRowContext(ctx, query, hashToken(session), time.Now().UTC()).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeUnauthorized, "not signed in")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate session: %w", err)
+	}
+
+	user, err := s.emailService.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsVerified {
+		return nil, apperrors.New(apperrors.CodeUnauthorized, "not signed in")
+	}
+	return user, nil
+}
+
+// SignOut ends one session
+func (s *Service) SignOut(ctx context.Context, session string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM web_sessions WHERE token_hash = $1`, hashToken(session)); err != nil {
+		return fmt.Errorf("failed to end session: %w", err)
+	}
+	return nil
+}
+
+// RevokeUser ends all of the user's sessions and invalidates their unused
+// magic links, returning how many sessions were ended
+func (s *Service) RevokeUser(ctx context.Context, userID int) (int, error) {
+	if _, err := s.db.ExecContext(ctx, `UPDATE web_logins SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`, userID); err != nil {
+		return 0, fmt.Errorf("failed to revoke magic links: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM web_sessions WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	n, _ := result.RowsAffected()
+	logrus.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"sessions": n,
+	}).Info("Dashboard sessions revoked")
+	return int(n), nil
+}
+
+// CSRFToken is the token forms posted with session must carry. It is derived
+// from the session, so it needs no storage and dies with the session.
+func (s *Service) CSRFToken(session string) string {
+	return s.sign("csrf", session)
+}
+
+// ValidCSRF reports whether token is session's CSRF token
+func (s *Service) ValidCSRF(session, token string) bool {
+	return session != "" && hmac.Equal([]byte(token), []byte(s.CSRFToken(session)))
+}
+
+// SetCookie stores session in the session cookie. It is HttpOnly and
+// SameSite=Lax, so scripts can't read it and other sites can't post with it.
+func SetCookie(w http.ResponseWriter, session string, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    session,
+		Path:     "/",
+		MaxAge:   int(SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearCookie removes the session cookie
+func ClearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: CookieName, Path: "/", MaxAge: -1})
+}
+
+// SessionFromRequest returns the session token in r's cookie, or ""
+func SessionFromRequest(r *http.Request) string {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// signLink returns a link token: the user, expiry and a nonce, then their
+// signature. The nonce makes every link distinct.
+func (s *Service) signLink(userID int, expiresAt time.Time) (string, error) {
+	nonce, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	payload := fmt.Sprintf("%d.%d.%s", userID, expiresAt.Unix(), nonce[:16])
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + s.sign("link", encoded), nil
+}
+
+// verifyLink checks a link token's signature and expiry, returning its user
+func (s *Service) verifyLink(token string, now time.Time) (int, error) {
+	invalid := apperrors.New(apperrors.CodeUnauthorized, "sign-in link is invalid")
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign("link", encoded))) {
+		return 0, invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, invalid
+	}
+
+	fields := strings.Split(string(payload), ".")
+	if len(fields) != 3 {
+		return 0, invalid
+	}
+	userID, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, invalid
+	}
+	expires, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, invalid
+	}
+	if now.Unix() >= expires {
+		return 0, apperrors.New(apperrors.CodeUnauthorized, "sign-in link has expired")
+	}
+	return userID, nil
+}
+
+// sign returns the hex HMAC-SHA256 of value under purpose, so a signature
+// made for one purpose is never valid for another
+func (s *Service) sign(purpose, value string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,274 @@
+// Package backup writes and restores logical database snapshots as JSONL so
+// data can be moved between Postgres instances or loaded into other stores.
+package backup
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+)
+
+// Snapshot format identifiers written to the header line
+const (
+	Format  = "wdygd-snapshot"
+	Version = 1
+)
+
+// Tables lists every table in the snapshot, parents before children so a
+// restore satisfies foreign keys as it goes
+var Tables = []string{
+	"organizations",
+	"users",
+	"user_channels",
+	"entries",
+	"weekly_summaries",
+	"email_logs",
+	"email_events",
+	"email_suppressions",
+	"broadcasts",
+	"admin_actions",
+	"summary_cc_recipients",
+	"webhook_endpoints",
+	"webhook_deliveries",
+	"quotes",
+	"quote_deliveries",
+	"prompt_sends",
+	"api_tokens",
+	"clarification_state",
+	"job_runs",
+	"job_settings",
+	"mentors",
+	"email_changes",
+	"user_emails",
+	"user_blackouts",
+	"web_logins",
+	"web_sessions",
+	"user_projects",
+	"project_rollups",
+	"usage_records",
+}
+
+// seededTables are populated by migrations, so a fresh database is not empty.
+// Restore replaces their contents with the snapshot's.
+var seededTables = map[string]bool{
+	"quotes": true,
+}
+
+// Record is one line of a snapshot. The first line is a header, then one row
+// per line, then a footer with per-table counts that marks the file complete.
+type Record struct {
+	Type      string          `json:"type"`
+	Format    string          `json:"format,omitempty"`
+	Version   int             `json:"version,omitempty"`
+	CreatedAt *time.Time      `json:"created_at,omitempty"`
+	Table     string          `json:"table,omitempty"`
+	Row       json.RawMessage `json:"row,omitempty"`
+	Counts    map[string]int  `json:"counts,omitempty"`
+}
+
+// Record types
+const (
+	RecordHeader = "header"
+	RecordRow    = "row"
+	RecordFooter = "footer"
+)
+
+// maxLineBytes bounds a single row when reading a snapshot
+const maxLineBytes = 16 * 1024 * 1024
+
+// Result reports how many rows were dumped or restored per table
+type Result struct {
+	Counts map[string]int
+	Rows   int
+}
+
+type Service struct {
+	db *database.DB
+}
+
+func NewService(db *database.DB) *Service {
+	return &Service{db: db}
+}
+
+// Dump writes every table to w from a single repeatable-read transaction so
+// the snapshot is consistent even while the scheduler is running. It reads
+// from the read replica, if there is one.
+func (s *Service) Dump(ctx context.Context, w io.Writer) (*Result, error) {
+	tx, err := s.db.Reader().BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin snapshot transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+
+	now := time.Now().UTC()
+	if err := enc.Encode(Record{Type: RecordHeader, Format: Format, Version: Version, CreatedAt: &now}); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot header: %w", err)
+	}
+
+	result := &Result{Counts: make(map[string]int)}
+	for _, table := range Tables {
+		count, err := dumpTable(ctx, tx, enc, table)
+		if err != nil {
+			return nil, err
+		}
+		result.Counts[table] = count
+		result.Rows += count
+	}
+
+	if err := enc.Encode(Record{Type: RecordFooter, Counts: result.Counts}); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot footer: %w", err)
+	}
+	if err := buf.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to flush snapshot: %w", err)
+	}
+
+	logrus.WithField("rows", result.Rows).Info("Database snapshot written")
+	return result, nil
+}
+
+func dumpTable(ctx context.Context, tx *sql.Tx, enc *json.Encoder, table string) (int, error) {
+	// Table names come from Tables, never from input
+	query := fmt.Sprintf(`SELECT row_to_json(t)::text FROM %s t ORDER BY id`, table)
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var row string
+		if err := rows.Scan(&row); err != nil {
+			return 0, fmt.Errorf("failed to scan %s row: %w", table, err)
+		}
+		if err := enc.Encode(Record{Type: RecordRow, Table: table, Row: json.RawMessage(row)}); err != nil {
+			return 0, fmt.Errorf("failed to write %s row: %w", table, err)
+		}
+		count++
+	}
+
+	return count, rows.Err()
+}
+
+// Restore loads a snapshot into an empty database in one transaction. Nothing
+// is committed unless the footer is present and its counts match.
+func (s *Service) Restore(ctx context.Context, r io.Reader) (*Result, error) {
+	known := make(map[string]bool, len(Tables))
+	for _, table := range Tables {
+		known[table] = true
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin restore transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range Tables {
+		if seededTables[table] {
+			continue
+		}
+
+		var populated bool
+		query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s)`, table)
+		if err := tx.QueryRowContext(ctx, query).Scan(&populated); err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", table, err)
+		}
+		if populated {
+			return nil, fmt.Errorf("refusing to restore: table %s is not empty", table)
+		}
+	}
+
+	for table := range seededTables {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s`, table)); err != nil {
+			return nil, fmt.Errorf("failed to clear seeded %s: %w", table, err)
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+
+	result := &Result{Counts: make(map[string]int)}
+	var footer *Record
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: invalid snapshot record: %w", line, err)
+		}
+
+		if line == 1 {
+			if record.Type != RecordHeader || record.Format != Format {
+				return nil, fmt.Errorf("not a %s file", Format)
+			}
+			if record.Version > Version {
+				return nil, fmt.Errorf("snapshot version %d is newer than supported version %d", record.Version, Version)
+			}
+			continue
+		}
+
+		if footer != nil {
+			return nil, fmt.Errorf("line %d: data after snapshot footer", line)
+		}
+
+		switch record.Type {
+		case RecordRow:
+			if !known[record.Table] {
+				return nil, fmt.Errorf("line %d: unknown table %q", line, record.Table)
+			}
+			query := fmt.Sprintf(`INSERT INTO %[1]s SELECT * FROM json_populate_record(NULL::%[1]s, $1::json)`, record.Table)
+			if _, err := tx.ExecContext(ctx, query, string(record.Row)); err != nil {
+				return nil, fmt.Errorf("line %d: failed to restore %s row: %w", line, record.Table, err)
+			}
+			result.Counts[record.Table]++
+			result.Rows++
+		case RecordFooter:
+			footer = &record
+		default:
+			return nil, fmt.Errorf("line %d: unexpected record type %q", line, record.Type)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	if footer == nil {
+		return nil, fmt.Errorf("snapshot is truncated: no footer after %d lines", line)
+	}
+	for table, expected := range footer.Counts {
+		if result.Counts[table] != expected {
+			return nil, fmt.Errorf("snapshot is inconsistent: %s has %d rows, footer expects %d", table, result.Counts[table], expected)
+		}
+	}
+
+	// Move id sequences past the restored rows so new inserts don't collide
+	for _, table := range Tables {
+		query := fmt.Sprintf(`
+			SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 1), MAX(id) IS NOT NULL)
+			FROM %[1]s`, table)
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return nil, fmt.Errorf("failed to reset %s sequence: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+
+	logrus.WithField("rows", result.Rows).Info("Database snapshot restored")
+	return result, nil
+}
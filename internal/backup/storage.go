@@ -0,0 +1,115 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/s3"
+)
+
+// Locations are a local file path, "-" for stdin/stdout, or s3://bucket/key
+
+// Create opens location for writing a snapshot. S3 objects are staged in a
+// temporary file and uploaded when the writer is closed.
+func Create(ctx context.Context, location, region string) (io.WriteCloser, error) {
+	if location == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+
+	bucket, key, isS3 := parseS3URL(location)
+	if !isS3 {
+		file, err := os.Create(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", location, err)
+		}
+		return file, nil
+	}
+
+	client, err := s3.NewClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	staging, err := os.CreateTemp("", "wdygd-snapshot-*.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging file: %w", err)
+	}
+
+	return &s3Writer{ctx: ctx, client: client, bucket: bucket, key: key, staging: staging}, nil
+}
+
+// Open opens location for reading a snapshot
+func Open(ctx context.Context, location, region string) (io.ReadCloser, error) {
+	if location == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+
+	bucket, key, isS3 := parseS3URL(location)
+	if !isS3 {
+		file, err := os.Open(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", location, err)
+		}
+		return file, nil
+	}
+
+	client, err := s3.NewClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetObject(ctx, bucket, key)
+}
+
+func parseS3URL(location string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(location, "s3://")
+	if !ok {
+		return "", "", false
+	}
+	bucket, key, _ := strings.Cut(rest, "/")
+	return bucket, key, true
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+type s3Writer struct {
+	ctx     context.Context
+	client  *s3.Client
+	bucket  string
+	key     string
+	staging *os.File
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.staging.Write(p)
+}
+
+// Close uploads the staged snapshot and removes the staging file
+func (w *s3Writer) Close() error {
+	defer os.Remove(w.staging.Name())
+	defer w.staging.Close()
+
+	if _, err := w.staging.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind staging file: %w", err)
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, w.staging)
+	if err != nil {
+		return fmt.Errorf("failed to hash snapshot: %w", err)
+	}
+
+	if _, err := w.staging.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind staging file: %w", err)
+	}
+
+	return w.client.PutObject(w.ctx, w.bucket, w.key, w.staging, size, hex.EncodeToString(hash.Sum(nil)), "application/x-ndjson")
+}
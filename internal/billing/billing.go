@@ -0,0 +1,187 @@
+// Package billing subscribes organizations through Stripe. Checkout creates
+// the subscription, Stripe's webhooks keep each organization's status in
+// step, and a failed payment starts a grace period with dunning emails to
+// the billing contact. Once a lapsed subscription's grace period ends, its
+// members get no prompts or summaries until it is paid.
+package billing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Subscription statuses: StatusNone before checkout, else Stripe's
+const (
+	StatusNone     = "none"
+	StatusActive   = "active"
+	StatusTrialing = "trialing"
+	StatusPastDue  = "past_due"
+	StatusUnpaid   = "unpaid"
+	StatusCanceled = "canceled"
+)
+
+// Dunning stages, the last dunning email an organization was sent
+const (
+	StageNone = iota
+	StagePaymentFailed
+	StageReminder
+	StageSuspended
+)
+
+// suspendedOrg is true for organizations o whose subscription lapsed and
+// whose grace period, if any, has ended. Organizations that never checked
+// out aren't suspended.
+const suspendedOrg = `o.subscription_status NOT IN ('none', 'active', 'trialing')
+	AND (o.grace_until IS NULL OR o.grace_until <= NOW())`
+
+type Service struct {
+	db            *database.DB
+	email         *email.Service
+	stripe        *stripeClient
+	webhookSecret string
+	priceID       string
+	gracePeriod   time.Duration
+	dashboardURL  string
+}
+
+// NewService returns the billing service, or nil when STRIPE_SECRET_KEY is
+// unset. A nil service suspends nobody.
+func NewService(db *database.DB, emailService *email.Service, cfg *config.Config) (*Service, error) {
+	if cfg.StripeSecretKey == "" {
+		return nil, nil
+	}
+	if cfg.StripeWebhookSecret == "" || cfg.StripePriceID == "" {
+		return nil, fmt.Errorf("STRIPE_WEBHOOK_SECRET and STRIPE_PRICE_ID are required with STRIPE_SECRET_KEY")
+	}
+
+	return &Service{
+		db:            db,
+		email:         emailService,
+		stripe:        newStripeClient(cfg.StripeSecretKey),
+		webhookSecret: cfg.StripeWebhookSecret,
+		priceID:       cfg.StripePriceID,
+		gracePeriod:   cfg.BillingGracePeriod,
+		dashboardURL:  cfg.DashboardURL,
+	}, nil
+}
+
+// Status returns organization orgID's subscription
+func (s *Service) Status(ctx context.Context, orgID int) (*models.OrgBilling, error) {
+	query := `
+		SELECT o.id, o.name, o.billing_email, o.stripe_customer_id, o.stripe_subscription_id,
+			o.subscription_status, o.grace_until, o.dunning_stage, o.invoice_url, o.billing_updated_at,
+			(` + suspendedOrg + `)
+		FROM organizations o
+		WHERE o.id = $1`
+
+	var b models.OrgBilling
+	err := s.db.QueryRowContext(ctx, query, orgID).Scan(&b.OrganizationID, &b.Name, &b.BillingEmail,
+		&b.StripeCustomerID, &b.StripeSubscriptionID, &b.SubscriptionStatus, &b.GraceUntil,
+		&b.DunningStage, &b.InvoiceURL, &b.UpdatedAt, &b.Suspended)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeNotFound, "organization not found: %d", orgID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization billing: %w", err)
+	}
+	return &b, nil
+}
+
+// Checkout starts a Stripe Checkout session subscribing organization orgID,
+// one seat per member. The Stripe customer is created on the first
+// checkout with billingEmail, which dunning emails go to. The URLs default
+// to the dashboard.
+func (s *Service) Checkout(ctx context.Context, orgID int, billingEmail, successURL, cancelURL string) (*CheckoutSession, error) {
+	b, err := s.Status(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if b.SubscriptionStatus == StatusActive || b.SubscriptionStatus == StatusTrialing {
+		return nil, apperrors.New(apperrors.CodeConflict, "organization %d already has a subscription", orgID)
+	}
+
+	billingEmail = strings.TrimSpace(billingEmail)
+	if billingEmail == "" && b.BillingEmail != nil {
+		billingEmail = *b.BillingEmail
+	}
+	if billingEmail == "" {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "a billing email is required")
+	}
+	if successURL == "" {
+		successURL = s.dashboardURL + "/app"
+	}
+	if cancelURL == "" {
+		cancelURL = s.dashboardURL + "/app"
+	}
+	if !strings.HasPrefix(successURL, "http") || !strings.HasPrefix(cancelURL, "http") {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "success_url and cancel_url are required without DASHBOARD_URL")
+	}
+
+	customerID := ""
+	if b.StripeCustomerID != nil {
+		customerID = *b.StripeCustomerID
+	} else {
+		customer, err := s.stripe.createCustomer(ctx, billingEmail, b.Name, orgID)
+		if err != nil {
+			return nil, err
+		}
+		customerID = customer.ID
+	}
+
+	query := `
+		UPDATE organizations
+		SET stripe_customer_id = $2, billing_email = $3, billing_updated_at = NOW()
+		WHERE id = $1`
+	if _, err := s.db.ExecContext(ctx, query, orgID, customerID, billingEmail); err != nil {
+		return nil, fmt.Errorf("failed to save Stripe customer: %w", err)
+	}
+
+	var members int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE organization_id = $1`, orgID).Scan(&members); err != nil {
+		return nil, fmt.Errorf("failed to count organization members: %w", err)
+	}
+	if members < 1 {
+		members = 1
+	}
+
+	return s.stripe.createCheckoutSession(ctx, customerID, s.priceID, members, orgID, successURL, cancelURL)
+}
+
+// SuspendedUsers returns the users whose organization is suspended, for
+// the prompt and summary jobs to skip
+func (s *Service) SuspendedUsers(ctx context.Context) (map[int]bool, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	query := `
+		SELECT u.id
+		FROM users u
+		JOIN organizations o ON o.id = u.organization_id
+		WHERE ` + suspendedOrg
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suspended users: %w", err)
+	}
+	defer rows.Close()
+
+	suspended := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan suspended user: %w", err)
+		}
+		suspended[id] = true
+	}
+	return suspended, rows.Err()
+}
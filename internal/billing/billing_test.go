@@ -0,0 +1,60 @@
+package billing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/webhooks"
+)
+
+func TestVerifySignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	payload := []byte(`{"id":"evt_1","type":"invoice.paid"}`)
+	valid := webhooks.Sign("whsec_test", "1700000000", payload)
+
+	tests := []struct {
+		name   string
+		header string
+		ok     bool
+	}{
+		{"valid", "t=1700000000,v1=" + valid, true},
+		{"one of several", "t=1700000000,v1=deadbeef,v1=" + valid + ",v0=ignored", true},
+		{"wrong signature", "t=1700000000,v1=deadbeef", false},
+		{"too old", "t=1699999000,v1=" + webhooks.Sign("whsec_test", "1699999000", payload), false},
+		{"no timestamp", "v1=" + valid, false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		err := verifySignature("whsec_test", tt.header, payload, now)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: verifySignature() error = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestDunningNotice(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	in := func(d time.Duration) *time.Time { at := now.Add(d); return &at }
+
+	tests := []struct {
+		name       string
+		stage      int
+		graceUntil *time.Time
+		notice     string
+		next       int
+	}{
+		{"early in grace", StagePaymentFailed, in(5 * 24 * time.Hour), "", StagePaymentFailed},
+		{"reminder due", StagePaymentFailed, in(48 * time.Hour), email.BillingReminder, StageReminder},
+		{"reminder sent", StageReminder, in(48 * time.Hour), "", StageReminder},
+		{"grace over", StageReminder, in(-time.Hour), email.BillingSuspended, StageSuspended},
+		{"canceled without grace", StageNone, nil, email.BillingSuspended, StageSuspended},
+		{"already suspended", StageSuspended, in(-time.Hour), "", StageSuspended},
+	}
+	for _, tt := range tests {
+		notice, next := dunningNotice(tt.stage, tt.graceUntil, now)
+		if notice != tt.notice || next != tt.next {
+			t.Errorf("%s: dunningNotice() = %q, %d, want %q, %d", tt.name, notice, next, tt.notice, tt.next)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+)
+
+// ReminderBefore is how long before the grace period ends the reminder is
+// sent
+const ReminderBefore = 72 * time.Hour
+
+// dunningNotice returns the dunning email due to an organization at stage
+// whose grace period ends at graceUntil, if any, and the stage sending it
+// reaches. It returns "" when nothing is due.
+func dunningNotice(stage int, graceUntil *time.Time, now time.Time) (string, int) {
+	switch {
+	case stage >= StageSuspended:
+		return "", stage
+	case graceUntil == nil || !now.Before(*graceUntil):
+		return email.BillingSuspended, StageSuspended
+	case stage < StageReminder && !now.Before(graceUntil.Add(-ReminderBefore)):
+		return email.BillingReminder, StageReminder
+	}
+	return "", stage
+}
+
+// RunDunning sends the reminders and suspension notices due at now to the
+// billing contacts of lapsed subscriptions, and returns how many it sent
+func (s *Service) RunDunning(ctx context.Context, now time.Time) (int, error) {
+	query := `
+		SELECT o.id, o.name, o.billing_email, o.grace_until, o.invoice_url, o.dunning_stage
+		FROM organizations o
+		WHERE o.subscription_status NOT IN ('none', 'active', 'trialing')
+		  AND o.billing_email IS NOT NULL AND o.dunning_stage < $1
+		ORDER BY o.id`
+
+	rows, err := s.db.QueryContext(ctx, query, StageSuspended)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query lapsed subscriptions: %w", err)
+	}
+	var due []orgUpdate
+	for rows.Next() {
+		var org orgUpdate
+		err := rows.Scan(&org.id, &org.name, &org.billingEmail, &org.graceUntil, &org.invoiceURL, &org.prevStage)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan lapsed subscription: %w", err)
+		}
+		due = append(due, org)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query lapsed subscriptions: %w", err)
+	}
+
+	sent := 0
+	for i := range due {
+		org := &due[i]
+		notice, stage := dunningNotice(org.prevStage, org.graceUntil, now)
+		if notice == "" {
+			continue
+		}
+
+		// Claim the stage first, so two runs can't both send it
+		result, err := s.db.ExecContext(ctx, `UPDATE organizations SET dunning_stage = $3 WHERE id = $1 AND dunning_stage = $2`,
+			org.id, org.prevStage, stage)
+		if err != nil {
+			return sent, fmt.Errorf("failed to update dunning stage: %w", err)
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			continue
+		}
+
+		err = s.email.SendBillingEmail(ctx, org.billingEmail.String, notice, org.name, org.graceUntil, org.invoiceURL.String)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"organization_id": org.id, "notice": notice}).Error("Failed to send billing notice")
+			continue
+		}
+		logrus.WithFields(logrus.Fields{"organization_id": org.id, "notice": notice}).Info("Billing notice sent")
+		sent++
+	}
+	return sent, nil
+}
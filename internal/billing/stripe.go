@@ -0,0 +1,97 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const stripeAPI = "https://api.stripe.com/v1"
+
+// stripeClient makes the few Stripe API calls billing needs with
+// form-encoded requests, without the Stripe SDK
+type stripeClient struct {
+	httpClient *http.Client
+	secretKey  string
+	baseURL    string
+}
+
+func newStripeClient(secretKey string) *stripeClient {
+	return &stripeClient{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		secretKey:  secretKey,
+		baseURL:    stripeAPI,
+	}
+}
+
+// stripeCustomer is the part of a Stripe customer billing reads
+type stripeCustomer struct {
+	ID string `json:"id"`
+}
+
+// CheckoutSession is a Stripe Checkout page to start a subscription on
+type CheckoutSession struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+func (c *stripeClient) createCustomer(ctx context.Context, email, name string, orgID int) (*stripeCustomer, error) {
+	form := url.Values{
+		"email":                     {email},
+		"name":                      {name},
+		"metadata[organization_id]": {fmt.Sprint(orgID)},
+	}
+
+	var customer stripeCustomer
+	if err := c.post(ctx, "/customers", form, &customer); err != nil {
+		return nil, fmt.Errorf("failed to create Stripe customer: %w", err)
+	}
+	return &customer, nil
+}
+
+func (c *stripeClient) createCheckoutSession(ctx context.Context, customerID, priceID string, quantity, orgID int, successURL, cancelURL string) (*CheckoutSession, error) {
+	form := url.Values{
+		"mode":                    {"subscription"},
+		"customer":                {customerID},
+		"client_reference_id":     {fmt.Sprint(orgID)},
+		"line_items[0][price]":    {priceID},
+		"line_items[0][quantity]": {fmt.Sprint(quantity)},
+		"success_url":             {successURL},
+		"cancel_url":              {cancelURL},
+	}
+
+	var session CheckoutSession
+	if err := c.post(ctx, "/checkout/sessions", form, &session); err != nil {
+		return nil, fmt.Errorf("failed to create Stripe checkout session: %w", err)
+	}
+	return &session, nil
+}
+
+func (c *stripeClient) post(ctx context.Context, path string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build Stripe request: %w", err)
+	}
+	req.SetBasicAuth(c.secretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Stripe request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Stripe POST %s returned %d: %s", path, resp.StatusCode, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Stripe response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package billing
+
+import (
+	"context"
+	"crypto/hmac"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/webhooks"
+)
+
+// SignatureHeader is the header Stripe signs webhook requests in
+const SignatureHeader = "Stripe-Signature"
+
+// signatureTolerance is how old a signed webhook may be, as in Stripe's
+// libraries
+const signatureTolerance = 5 * time.Minute
+
+// event is a Stripe webhook event; Object is the checkout session,
+// subscription or invoice it is about
+type event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+type checkoutSession struct {
+	Customer          string `json:"customer"`
+	Subscription      string `json:"subscription"`
+	ClientReferenceID string `json:"client_reference_id"`
+	CustomerDetails   struct {
+		Email string `json:"email"`
+	} `json:"customer_details"`
+}
+
+type subscription struct {
+	ID       string `json:"id"`
+	Customer string `json:"customer"`
+	Status   string `json:"status"`
+}
+
+type invoice struct {
+	Customer         string `json:"customer"`
+	HostedInvoiceURL string `json:"hosted_invoice_url"`
+}
+
+// verifySignature checks header, Stripe's "t=<unix>,v1=<hex>" signature,
+// against payload. Any of several v1 signatures may match, as during a
+// secret rotation.
+func verifySignature(secret, header string, payload []byte, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return apperrors.New(apperrors.CodeUnauthorized, "invalid %s header", SignatureHeader)
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return apperrors.New(apperrors.CodeUnauthorized, "invalid %s timestamp", SignatureHeader)
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return apperrors.New(apperrors.CodeUnauthorized, "webhook timestamp is outside the allowed window")
+	}
+
+	expected := webhooks.Sign(secret, timestamp, payload)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return apperrors.New(apperrors.CodeUnauthorized, "invalid webhook signature")
+}
+
+// HandleWebhook verifies and applies a Stripe webhook event. Each event is
+// applied once; one that fails is forgotten, so Stripe's retry applies it.
+// Events about customers we don't know are ignored.
+func (s *Service) HandleWebhook(ctx context.Context, signature string, payload []byte) error {
+	if err := verifySignature(s.webhookSecret, signature, payload, time.Now()); err != nil {
+		return err
+	}
+
+	var evt event
+	if err := json.Unmarshal(payload, &evt); err != nil || evt.ID == "" {
+		return apperrors.New(apperrors.CodeInvalidInput, "invalid Stripe event")
+	}
+
+	var id string
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO stripe_events (id, type) VALUES ($1, $2)
+		ON CONFLICT (id) DO NOTHING
+		RETURNING id`, evt.ID, evt.Type).Scan(&id)
+	if err == sql.ErrNoRows {
+		logrus.WithField("event_id", evt.ID).Info("Stripe event already handled, skipping")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record Stripe event: %w", err)
+	}
+
+	if err := s.applyEvent(ctx, &evt); err != nil {
+		if _, delErr := s.db.ExecContext(ctx, `DELETE FROM stripe_events WHERE id = $1`, evt.ID); delErr != nil {
+			logrus.WithError(delErr).WithField("event_id", evt.ID).Error("Failed to forget failed Stripe event")
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) applyEvent(ctx context.Context, evt *event) error {
+	switch evt.Type {
+	case "checkout.session.completed":
+		var session checkoutSession
+		if err := json.Unmarshal(evt.Data.Object, &session); err != nil {
+			return apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid checkout session")
+		}
+		return s.checkoutCompleted(ctx, &session)
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		var sub subscription
+		if err := json.Unmarshal(evt.Data.Object, &sub); err != nil {
+			return apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid subscription")
+		}
+		return s.subscriptionChanged(ctx, &sub)
+	case "invoice.payment_failed":
+		var inv invoice
+		if err := json.Unmarshal(evt.Data.Object, &inv); err != nil {
+			return apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid invoice")
+		}
+		return s.paymentFailed(ctx, &inv)
+	case "invoice.paid":
+		var inv invoice
+		if err := json.Unmarshal(evt.Data.Object, &inv); err != nil {
+			return apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid invoice")
+		}
+		return s.invoicePaid(ctx, &inv)
+	}
+	return nil
+}
+
+// checkoutCompleted links the new subscription to the organization that
+// checked out. Its status arrives with the subscription events.
+func (s *Service) checkoutCompleted(ctx context.Context, session *checkoutSession) error {
+	orgID, err := strconv.Atoi(session.ClientReferenceID)
+	if err != nil {
+		logrus.WithField("client_reference_id", session.ClientReferenceID).Warn("Checkout session without an organization, ignoring")
+		return nil
+	}
+
+	query := `
+		UPDATE organizations
+		SET stripe_customer_id = $2, stripe_subscription_id = NULLIF($3, ''),
+		    billing_email = COALESCE(billing_email, NULLIF($4, '')), billing_updated_at = NOW()
+		WHERE id = $1`
+	_, err = s.db.ExecContext(ctx, query, orgID, session.Customer, session.Subscription, session.CustomerDetails.Email)
+	if err != nil {
+		return fmt.Errorf("failed to save checkout: %w", err)
+	}
+	return nil
+}
+
+// orgUpdate is an organization as a webhook update left it, with the
+// dunning stage it had before
+type orgUpdate struct {
+	id           int
+	name         string
+	billingEmail sql.NullString
+	graceUntil   *time.Time
+	invoiceURL   sql.NullString
+	prevStage    int
+}
+
+// updateCustomer applies set, an UPDATE's SET list with the customer ID as
+// $1, to the customer's organization. It returns nil for an unknown
+// customer.
+func (s *Service) updateCustomer(ctx context.Context, customerID, set string, args ...interface{}) (*orgUpdate, error) {
+	query := `
+		WITH prev AS (
+			SELECT id, dunning_stage AS prev_stage FROM organizations WHERE stripe_customer_id = $1 FOR UPDATE
+		)
+		UPDATE organizations o
+		SET ` + set + `, billing_updated_at = NOW()
+		FROM prev
+		WHERE o.id = prev.id
+		RETURNING o.id, o.name, o.billing_email, o.grace_until, o.invoice_url, prev.prev_stage`
+
+	var org orgUpdate
+	err := s.db.QueryRowContext(ctx, query, append([]interface{}{customerID}, args...)...).
+		Scan(&org.id, &org.name, &org.billingEmail, &org.graceUntil, &org.invoiceURL, &org.prevStage)
+	if err == sql.ErrNoRows {
+		logrus.WithField("customer", customerID).Warn("Stripe event for an unknown customer, ignoring")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update organization billing: %w", err)
+	}
+	return &org, nil
+}
+
+// subscriptionChanged mirrors the subscription's status. Falling past due
+// starts the grace period; becoming active ends dunning.
+func (s *Service) subscriptionChanged(ctx context.Context, sub *subscription) error {
+	set := `stripe_subscription_id = $2, subscription_status = $3,
+		grace_until = CASE WHEN $3 IN ('active', 'trialing') THEN NULL
+			WHEN $3 IN ('past_due', 'unpaid') THEN COALESCE(grace_until, $4) ELSE grace_until END,
+		invoice_url = CASE WHEN $3 IN ('active', 'trialing') THEN NULL ELSE invoice_url END,
+		dunning_stage = CASE WHEN $3 IN ('active', 'trialing') THEN 0 ELSE dunning_stage END`
+
+	org, err := s.updateCustomer(ctx, sub.Customer, set, sub.ID, sub.Status, time.Now().Add(s.gracePeriod))
+	if err != nil || org == nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{"organization_id": org.id, "status": sub.Status}).Info("Subscription updated")
+	if (sub.Status == StatusActive || sub.Status == StatusTrialing) && org.prevStage > StageNone {
+		s.notify(ctx, org, email.BillingRestored)
+	}
+	return nil
+}
+
+// paymentFailed starts the grace period, unless one is running, and sends
+// the payment failed email on the first failure
+func (s *Service) paymentFailed(ctx context.Context, inv *invoice) error {
+	set := `subscription_status = CASE WHEN subscription_status IN ('active', 'trialing') THEN 'past_due' ELSE subscription_status END,
+		grace_until = COALESCE(grace_until, $2),
+		invoice_url = COALESCE(NULLIF($3, ''), invoice_url),
+		dunning_stage = GREATEST(dunning_stage, 1)`
+
+	org, err := s.updateCustomer(ctx, inv.Customer, set, time.Now().Add(s.gracePeriod), inv.HostedInvoiceURL)
+	if err != nil || org == nil {
+		return err
+	}
+
+	logrus.WithField("organization_id", org.id).Warn("Subscription payment failed")
+	if org.prevStage == StageNone {
+		s.notify(ctx, org, email.BillingPaymentFailed)
+	}
+	return nil
+}
+
+// invoicePaid ends the grace period and dunning of a past due subscription
+func (s *Service) invoicePaid(ctx context.Context, inv *invoice) error {
+	set := `subscription_status = CASE WHEN subscription_status IN ('past_due', 'unpaid') THEN 'active' ELSE subscription_status END,
+		grace_until = NULL, invoice_url = NULL, dunning_stage = 0`
+
+	org, err := s.updateCustomer(ctx, inv.Customer, set)
+	if err != nil || org == nil {
+		return err
+	}
+
+	if org.prevStage > StageNone {
+		logrus.WithField("organization_id", org.id).Info("Subscription payment recovered")
+		s.notify(ctx, org, email.BillingRestored)
+	}
+	return nil
+}
+
+// notify sends org's billing contact a billing notice. A failure is only
+// logged: the organization's billing is already updated.
+func (s *Service) notify(ctx context.Context, org *orgUpdate, notice string) {
+	if !org.billingEmail.Valid {
+		logrus.WithField("organization_id", org.id).Warn("No billing email, skipping billing notice")
+		return
+	}
+	err := s.email.SendBillingEmail(ctx, org.billingEmail.String, notice, org.name, org.graceUntil, org.invoiceURL.String)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"organization_id": org.id, "notice": notice}).Error("Failed to send billing notice")
+	}
+}
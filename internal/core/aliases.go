@@ -0,0 +1,201 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// MaxEmailAliases caps how many addresses a user can reply from besides
+// their own
+const MaxEmailAliases = 5
+
+// ListEmailAliases returns the user's aliases, verified or not, oldest first
+func (s *Service) ListEmailAliases(ctx context.Context, userID int) ([]models.UserEmail, error) {
+	query := `
+		SELECT id, user_id, email, verification_code, verified_at, created_at
+		FROM user_emails WHERE user_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query email aliases: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := []models.UserEmail{}
+	for rows.Next() {
+		var alias models.UserEmail
+		var verifiedAt sql.NullTime
+		if err := rows.Scan(&alias.ID, &alias.UserID, &alias.Email, &alias.VerificationCode, &verifiedAt, &alias.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan email alias: %w", err)
+		}
+		if verifiedAt.Valid {
+			alias.VerifiedAt = &verifiedAt.Time
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases, rows.Err()
+}
+
+// AddEmailAlias sends address a code that, once entered or replied from
+// there, lets the user reply from it. Adding a pending alias again sends a
+// new code.
+func (s *Service) AddEmailAlias(ctx context.Context, user *models.User, address string) (*models.UserEmail, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(address))
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid email address")
+	}
+	address = strings.ToLower(parsed.Address)
+
+	if strings.EqualFold(address, user.Email) {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "%s is already your address", address)
+	}
+	existing, err := s.emailService.GetUserByEmail(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if existing != nil {
+		if existing.ID == user.ID {
+			return nil, apperrors.New(apperrors.CodeConflict, "%s already reaches your account", address)
+		}
+		return nil, apperrors.New(apperrors.CodeConflict, "%s belongs to another account", address)
+	}
+
+	var count int
+	query := `SELECT COUNT(*) FROM user_emails WHERE user_id = $1 AND LOWER(email) <> $2`
+	if err := s.db.QueryRowContext(ctx, query, user.ID, address).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count email aliases: %w", err)
+	}
+	if count >= MaxEmailAliases {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "you can have at most %d aliases", MaxEmailAliases)
+	}
+
+	alias := models.UserEmail{UserID: user.ID, Email: address, VerificationCode: email.GenerateVerificationCode()}
+	query = `
+		INSERT INTO user_emails (user_id, email, verification_code)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, LOWER(email)) DO UPDATE
+		SET verification_code = $3, created_at = NOW()
+		WHERE user_emails.verified_at IS NULL
+		RETURNING id, created_at`
+
+	err = s.db.QueryRowContext(ctx, query, user.ID, address, alias.VerificationCode).Scan(&alias.ID, &alias.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeConflict, "%s already reaches your account", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save email alias: %w", err)
+	}
+
+	if err := s.emailService.SendEmailAliasRequest(ctx, user.ID, user.Email, address, alias.VerificationCode); err != nil {
+		return nil, err
+	}
+
+	logrus.WithField("user_id", user.ID).Info("Email alias requested")
+	return &alias, nil
+}
+
+// VerifyEmailAlias verifies the user's pending alias that was sent code
+func (s *Service) VerifyEmailAlias(ctx context.Context, userID int, code string) (*models.UserEmail, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "a verification code is required")
+	}
+
+	alias, err := s.verifyEmailAlias(ctx, `user_id = $1 AND verification_code = $2`, userID, code)
+	if err != nil {
+		return nil, err
+	}
+	if alias == nil {
+		return nil, apperrors.New(apperrors.CodeNotFound, "no pending alias has that code")
+	}
+	return alias, nil
+}
+
+// confirmEmailAlias verifies the pending alias sender whose code appears in
+// body. It reports whether the reply verified one.
+func (s *Service) confirmEmailAlias(ctx context.Context, sender, body string) (bool, error) {
+	query := `
+		SELECT id, verification_code FROM user_emails
+		WHERE LOWER(email) = LOWER($1) AND verified_at IS NULL`
+
+	rows, err := s.db.QueryContext(ctx, query, sender)
+	if err != nil {
+		return false, fmt.Errorf("failed to query pending email aliases: %w", err)
+	}
+
+	aliasID := 0
+	for rows.Next() {
+		var id int
+		var code string
+		if err := rows.Scan(&id, &code); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan email alias: %w", err)
+		}
+		if contains(body, code) {
+			aliasID = id
+			break
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to read email aliases: %w", err)
+	}
+
+	if aliasID == 0 {
+		return false, nil
+	}
+	if _, err := s.verifyEmailAlias(ctx, `id = $1`, aliasID); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// verifyEmailAlias verifies the pending alias where matches, unless the
+// address has since become another account's, and returns it or nil if
+// none matched
+func (s *Service) verifyEmailAlias(ctx context.Context, where string, args ...interface{}) (*models.UserEmail, error) {
+	query := `
+		UPDATE user_emails SET verified_at = NOW()
+		WHERE ` + where + ` AND verified_at IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM users WHERE LOWER(users.email) = LOWER(user_emails.email))
+		RETURNING id, user_id, email, verification_code, verified_at, created_at`
+
+	var alias models.UserEmail
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(
+		&alias.ID, &alias.UserID, &alias.Email, &alias.VerificationCode, &alias.VerifiedAt, &alias.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify email alias: %w", err)
+	}
+
+	logrus.WithField("user_id", alias.UserID).Info("Email alias verified")
+	return &alias, nil
+}
+
+// RemoveEmailAlias stops address reaching the user
+func (s *Service) RemoveEmailAlias(ctx context.Context, userID int, address string) error {
+	query := `DELETE FROM user_emails WHERE user_id = $1 AND LOWER(email) = LOWER($2)`
+
+	result, err := s.db.ExecContext(ctx, query, userID, strings.TrimSpace(address))
+	if err != nil {
+		return fmt.Errorf("failed to remove email alias: %w", err)
+	}
+	if removed, _ := result.RowsAffected(); removed == 0 {
+		return apperrors.New(apperrors.CodeNotFound, "%s is not one of your aliases", address)
+	}
+
+	logrus.WithField("user_id", userID).Info("Email alias removed")
+	return nil
+}
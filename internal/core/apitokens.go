@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// apiTokenPrefix makes tokens recognizable in logs and secret scanners
+const apiTokenPrefix = "wdyg_"
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIToken issues a token that authenticates as user on the GraphQL
+// endpoint. Only its hash is stored, so the token can't be shown again.
+func (s *Service) CreateAPIToken(ctx context.Context, user *models.User, name string) (string, error) {
+	if !user.IsVerified {
+		return "", apperrors.New(apperrors.CodeNotVerified, "user %s is not verified", user.Email)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+	token := apiTokenPrefix + hex.EncodeToString(b)
+
+	query := `INSERT INTO api_tokens (user_id, name, token_hash) VALUES ($1, $2, $3)`
+	if _, err := s.db.ExecContext(ctx, query, user.ID, name, hashAPIToken(token)); err != nil {
+		return "", fmt.Errorf("failed to store API token: %w", err)
+	}
+
+	return token, nil
+}
+
+// RevokeAPITokens revokes every active token of the user and returns how many
+// were revoked
+func (s *Service) RevokeAPITokens(ctx context.Context, userID int) (int, error) {
+	query := `UPDATE api_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	result, err := s.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke API tokens: %w", err)
+	}
+
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// AuthenticateAPIToken returns the verified user a token belongs to, or a
+// CodeUnauthorized error for unknown or revoked tokens
+func (s *Service) AuthenticateAPIToken(ctx context.Context, token string) (*models.User, error) {
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return nil, apperrors.New(apperrors.CodeUnauthorized, "invalid API token")
+	}
+
+	var userEmail string
+	query := `
+		UPDATE api_tokens t
+		SET last_used_at = NOW()
+		FROM users u
+		WHERE t.user_id = u.id AND t.token_hash = $1 AND t.revoked_at IS NULL
+		RETURNING u.email`
+
+	err := s.db.QueryRowContext(ctx, query, hashAPIToken(token)).Scan(&userEmail)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeUnauthorized, "invalid API token")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate API token: %w", err)
+	}
+
+	user, err := s.emailService.GetUserByEmail(ctx, userEmail)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsVerified {
+		return nil, apperrors.New(apperrors.CodeUnauthorized, "invalid API token")
+	}
+	return user, nil
+}
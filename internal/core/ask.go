@@ -0,0 +1,156 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/embeddings"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/llm"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// maxAskEntries is how many matching entries a question is answered from
+const maxAskEntries = 20
+
+// maxSearchTerms bounds the query built from a question
+const maxSearchTerms = 20
+
+var searchTermRegex = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// answerQuestion answers a question about the user's journal from the
+// entries that best match it and emails the answer back
+func (s *Service) answerQuestion(ctx context.Context, user *models.User, question string) error {
+	if s.llm == nil {
+		return fmt.Errorf("journal questions are not configured")
+	}
+
+	entries, err := s.relevantEntries(ctx, user.ID, question)
+	if err != nil {
+		return err
+	}
+
+	answer := &llm.Answer{Text: "None of your entries match that question. Try the words you'd have used when you wrote about it."}
+	if len(entries) > 0 {
+		answer, err = s.llm.AnswerQuestion(ctx, question, entries)
+		if err != nil {
+			return err
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":    user.ID,
+		"matches":    len(entries),
+		"citations":  len(answer.Citations),
+		"model":      answer.Model,
+		"cost_cents": answer.CostCents,
+	}).Info("Journal question answered")
+
+	return s.emailService.SendAskAnswer(ctx, user.ID, user.Email, question, answer.Text, answer.Citations)
+}
+
+// relevantEntries returns up to maxAskEntries entries for question: the
+// closest in meaning when embeddings are enabled, then full-text matches for
+// the exact words. Nearest neighbours always fill their limit, so they get
+// half the slots. A failed semantic search falls back to full text alone.
+func (s *Service) relevantEntries(ctx context.Context, userID int, question string) ([]*models.Entry, error) {
+	var entries []*models.Entry
+	seen := map[int]bool{}
+
+	if s.embeddings != nil {
+		matches, err := s.embeddings.SearchSimilarEntries(ctx, userID, question, embeddings.SearchOptions{Limit: maxAskEntries / 2})
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", userID).Warn("Semantic entry search failed, using full text only")
+		}
+		for _, match := range matches {
+			seen[match.Entry.ID] = true
+			entries = append(entries, match.Entry)
+		}
+	}
+
+	textMatches, err := s.SearchEntries(ctx, userID, question, maxAskEntries)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range textMatches {
+		if len(entries) == maxAskEntries {
+			break
+		}
+		if !seen[entry.ID] {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// SearchEntries returns up to limit of the user's entries matching any word
+// of query by full-text search, best match first, then newest first. It
+// reads from the read replica, if there is one.
+func (s *Service) SearchEntries(ctx context.Context, userID int, query string, limit int) ([]*models.Entry, error) {
+	tsquery := searchTerms(query)
+	if tsquery == "" {
+		return nil, nil
+	}
+
+	sqlQuery := `
+		SELECT id, user_id, entry_date, raw_content, parsed_content, project_tag, created_at, updated_at
+		FROM entries
+		WHERE user_id = $1 AND deleted_at IS NULL AND raw_content NOT LIKE '` + custody.SealedPrefix + `%'
+		  AND to_tsvector('english', raw_content) @@ to_tsquery('english', $2)
+		ORDER BY ts_rank(to_tsvector('english', raw_content), to_tsquery('english', $2)) DESC, entry_date DESC
+		LIMIT $3`
+
+	rows, err := s.db.Reader().QueryContext(ctx, sqlQuery, userID, tsquery, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.Entry
+	for rows.Next() {
+		var entry models.Entry
+		var parsedContent, projectTag sql.NullString
+
+		err := rows.Scan(&entry.ID, &entry.UserID, &entry.EntryDate, &entry.RawContent,
+			&parsedContent, &projectTag, &entry.CreatedAt, &entry.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+
+		if parsedContent.Valid {
+			entry.ParsedContent = &parsedContent.String
+		}
+		if projectTag.Valid {
+			entry.ProjectTag = &projectTag.String
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// searchTerms turns free text into a tsquery matching any of its words.
+// Words are reduced to letters and digits, so nothing in the text can change
+// the query's syntax; Postgres drops stop words like "when" and "the".
+func searchTerms(text string) string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, word := range searchTermRegex.FindAllString(strings.ToLower(text), -1) {
+		if len(word) < 2 || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return strings.Join(terms, " | ")
+}
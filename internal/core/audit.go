@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Admin actions recorded in admin_actions
+const (
+	AdminActionVerifyUser  = "verify_user"
+	AdminActionChangeEmail = "change_email"
+)
+
+// AdminAction is an operator's change to a user's account, kept in the
+// audit log alongside broadcasts
+type AdminAction struct {
+	Action  string
+	Actor   string
+	UserID  int
+	Details map[string]interface{}
+}
+
+func recordAdminAction(ctx context.Context, tx *sql.Tx, action *AdminAction) error {
+	details, err := json.Marshal(action.Details)
+	if err != nil {
+		return fmt.Errorf("failed to encode admin action details: %w", err)
+	}
+
+	query := `
+		INSERT INTO admin_actions (action, actor, user_id, details)
+		VALUES ($1, $2, $3, $4)`
+
+	if _, err := tx.ExecContext(ctx, query, action.Action, action.Actor, action.UserID, details); err != nil {
+		return fmt.Errorf("failed to record admin action: %w", err)
+	}
+	return nil
+}
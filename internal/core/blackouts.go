@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/holidays"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// DaysOff is a user's blackouts, loaded once to check many days
+type DaysOff struct {
+	ranges  [][2]time.Time
+	country string
+}
+
+// Off reports whether day's calendar date is in one of the user's off
+// ranges or is a public holiday in their calendar
+func (d *DaysOff) Off(day time.Time) bool {
+	if d == nil {
+		return false
+	}
+
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	for _, r := range d.ranges {
+		if !day.Before(r[0]) && !day.After(r[1]) {
+			return true
+		}
+	}
+	if d.country != "" {
+		_, ok := holidays.On(d.country, day)
+		return ok
+	}
+	return false
+}
+
+// GetDaysOff loads the user's off ranges and holiday calendar
+func (s *Service) GetDaysOff(ctx context.Context, userID int) (*DaysOff, error) {
+	query := `SELECT start_date, end_date, country FROM user_blackouts WHERE user_id = $1`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blackouts: %w", err)
+	}
+	defer rows.Close()
+
+	days := &DaysOff{}
+	for rows.Next() {
+		var start, end sql.NullTime
+		var country sql.NullString
+		if err := rows.Scan(&start, &end, &country); err != nil {
+			return nil, fmt.Errorf("failed to scan blackout: %w", err)
+		}
+		if country.Valid {
+			days.country = country.String
+			continue
+		}
+		days.ranges = append(days.ranges, [2]time.Time{start.Time.UTC(), end.Time.UTC()})
+	}
+
+	return days, rows.Err()
+}
+
+// HolidayCalendar returns the country of the user's holiday calendar, or ""
+// if they have none
+func (s *Service) HolidayCalendar(ctx context.Context, userID int) (string, error) {
+	var country string
+	err := s.db.QueryRowContext(ctx, `SELECT country FROM user_blackouts WHERE user_id = $1 AND country IS NOT NULL`, userID).Scan(&country)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query holiday calendar: %w", err)
+	}
+	return country, nil
+}
+
+// IsDayOff reports whether at falls on a day off in the user's timezone
+func (s *Service) IsDayOff(ctx context.Context, user *models.User, at time.Time) (bool, error) {
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	days, err := s.GetDaysOff(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	return days.Off(at.In(loc)), nil
+}
+
+// addTimeOff records the days from start to end, inclusive, as off
+func (s *Service) addTimeOff(ctx context.Context, userID int, start, end time.Time) error {
+	query := `INSERT INTO user_blackouts (user_id, start_date, end_date) VALUES ($1, $2, $3)`
+	if _, err := s.db.ExecContext(ctx, query, userID, start, end); err != nil {
+		return fmt.Errorf("failed to save time off: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id": userID,
+		"start":   start.Format("2006-01-02"),
+		"end":     end.Format("2006-01-02"),
+	}).Info("Time off added")
+	return nil
+}
+
+// clearTimeOff removes the user's current and upcoming off ranges. Past ones
+// are kept so the streaks they bridged stay intact.
+func (s *Service) clearTimeOff(ctx context.Context, userID int) error {
+	query := `DELETE FROM user_blackouts WHERE user_id = $1 AND country IS NULL AND end_date >= CURRENT_DATE`
+	if _, err := s.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to clear time off: %w", err)
+	}
+
+	logrus.WithField("user_id", userID).Info("Time off cleared")
+	return nil
+}
+
+// setHolidayCalendar sets the country whose public holidays are days off, or
+// removes it when country is ""
+func (s *Service) setHolidayCalendar(ctx context.Context, userID int, country string) error {
+	if country == "" {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM user_blackouts WHERE user_id = $1 AND country IS NOT NULL`, userID); err != nil {
+			return fmt.Errorf("failed to remove holiday calendar: %w", err)
+		}
+		logrus.WithField("user_id", userID).Info("Holiday calendar removed")
+		return nil
+	}
+
+	query := `
+		INSERT INTO user_blackouts (user_id, country)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) WHERE country IS NOT NULL
+		DO UPDATE SET country = $2`
+
+	if _, err := s.db.ExecContext(ctx, query, userID, country); err != nil {
+		return fmt.Errorf("failed to save holiday calendar: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id": userID,
+		"country": country,
+	}).Info("Holiday calendar set")
+	return nil
+}
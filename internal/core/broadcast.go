@@ -0,0 +1,166 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// BroadcastOptions configures an admin announcement
+type BroadcastOptions struct {
+	Subject      string
+	TemplateName string
+	Template     string
+	SentBy       string
+	DryRun       bool
+
+	// Recipients are queued in batches, each scheduled BatchInterval after the
+	// previous one so the outbox drains them gradually
+	BatchSize     int
+	BatchInterval time.Duration
+}
+
+// BroadcastResult describes a queued (or dry-run) announcement
+type BroadcastResult struct {
+	BroadcastID int
+	Recipients  int
+	Batches     int
+	// Sample is the body rendered for the first recipient
+	Sample string
+}
+
+// Broadcast queues an announcement to every verified, non-paused,
+// non-suppressed user and records an audit entry. Outside production only
+// dry runs are allowed, since a staging database may hold real addresses.
+func (s *Service) Broadcast(ctx context.Context, opts BroadcastOptions) (*BroadcastResult, error) {
+	if strings.TrimSpace(opts.Subject) == "" {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "broadcast subject is required")
+	}
+	if opts.BatchSize < 1 {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "batch size must be at least 1")
+	}
+	if !opts.DryRun && !s.emailService.IsProd() {
+		return nil, apperrors.New(apperrors.CodeForbidden, "broadcasts are only sent from APP_ENV=prod; use --dry-run to preview")
+	}
+
+	tmpl, err := email.ParseAnnouncement(opts.TemplateName, opts.Template)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid broadcast template")
+	}
+
+	recipients, err := s.getBroadcastRecipients(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Render every body up front so a template error aborts before anything is queued
+	bodies := make([]string, len(recipients))
+	for i, user := range recipients {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, email.AnnouncementData{Name: user.Name, Email: user.Email}); err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "failed to render broadcast for %s", user.Email)
+		}
+		bodies[i] = buf.String()
+	}
+
+	result := &BroadcastResult{
+		Recipients: len(recipients),
+		Batches:    (len(recipients) + opts.BatchSize - 1) / opts.BatchSize,
+	}
+	if len(bodies) > 0 {
+		result.Sample = bodies[0]
+	}
+
+	result.BroadcastID, err = s.recordBroadcast(ctx, opts, len(recipients))
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	start := time.Now().UTC()
+	for i, user := range recipients {
+		scheduledAt := start.Add(time.Duration(i/opts.BatchSize) * opts.BatchInterval)
+		userID := user.ID
+		err := s.emailService.QueueEmail(ctx, &userID, user.Email, models.EmailTypeAnnouncement, opts.Subject, bodies[i], &scheduledAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to queue broadcast after %d of %d recipients: %w", i, len(recipients), err)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"broadcast_id": result.BroadcastID,
+		"recipients":   result.Recipients,
+		"batches":      result.Batches,
+		"sent_by":      opts.SentBy,
+	}).Info("Broadcast queued")
+
+	return result, nil
+}
+
+func (s *Service) getBroadcastRecipients(ctx context.Context) ([]*models.User, error) {
+	query := `
+		SELECT u.id, u.email, u.name
+		FROM users u
+		WHERE u.is_verified = TRUE
+		  AND (u.is_paused = FALSE OR u.pause_until < NOW())
+		  AND NOT EXISTS (SELECT 1 FROM email_suppressions es WHERE LOWER(es.email) = LOWER(u.email))
+		ORDER BY u.id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query broadcast recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Email, &user.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan broadcast recipient: %w", err)
+		}
+		users = append(users, &user)
+	}
+
+	return users, rows.Err()
+}
+
+func (s *Service) recordBroadcast(ctx context.Context, opts BroadcastOptions, recipients int) (int, error) {
+	query := `
+		INSERT INTO broadcasts (subject, template_name, body_template, recipient_count, dry_run, sent_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`
+
+	var id int
+	err := s.db.QueryRowContext(ctx, query, opts.Subject, opts.TemplateName, opts.Template,
+		recipients, opts.DryRun, opts.SentBy).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record broadcast: %w", err)
+	}
+
+	return id, nil
+}
+
+// SuppressEmail stops bulk email such as broadcasts going to an address
+func (s *Service) SuppressEmail(ctx context.Context, emailAddr, reason string) error {
+	query := `
+		INSERT INTO email_suppressions (email, reason)
+		VALUES (LOWER($1), $2)
+		ON CONFLICT (email) DO UPDATE SET reason = EXCLUDED.reason`
+
+	if _, err := s.db.ExecContext(ctx, query, emailAddr, reason); err != nil {
+		return fmt.Errorf("failed to suppress email: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// SummaryCalendar returns the calendar userID's summaries follow: their
+// own, else their organization's, else the zero Calendar for calendar weeks
+func (s *Service) SummaryCalendar(ctx context.Context, userID int) (period.Calendar, error) {
+	query := `
+		SELECT COALESCE(u.summary_period_weeks, o.summary_period_weeks),
+			CASE WHEN u.summary_period_weeks IS NOT NULL THEN u.summary_period_end ELSE o.summary_period_end END
+		FROM users u
+		LEFT JOIN organizations o ON o.id = u.organization_id
+		WHERE u.id = $1`
+
+	var weeks sql.NullInt64
+	var end sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&weeks, &end)
+	if err == sql.ErrNoRows {
+		return period.Calendar{}, apperrors.New(apperrors.CodeUserNotFound, "user not found: %d", userID)
+	}
+	if err != nil {
+		return period.Calendar{}, fmt.Errorf("failed to get summary calendar: %w", err)
+	}
+	if !weeks.Valid || !end.Valid {
+		return period.Calendar{}, nil
+	}
+	return period.NewCalendar(int(weeks.Int64), end.Time)
+}
+
+// SetSummaryCalendar sets userID's own summary calendar; the zero Calendar
+// clears it, so their organization's applies
+func (s *Service) SetSummaryCalendar(ctx context.Context, userID int, calendar period.Calendar) error {
+	var weeks *int
+	var end *time.Time
+	if !calendar.IsZero() {
+		weeks, end = &calendar.Weeks, &calendar.End
+	}
+
+	query := `UPDATE users SET summary_period_weeks = $2, summary_period_end = $3, updated_at = NOW() WHERE id = $1`
+	result, err := s.db.ExecContext(ctx, query, userID, weeks, end)
+	if err != nil {
+		return fmt.Errorf("failed to set summary calendar: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %d", userID)
+	}
+	return nil
+}
+
+// SummaryBounds returns the first and last days of the summary period
+// containing date for user: a period of their calendar, or the week
+// starting on their week start day through its Friday
+func (s *Service) SummaryBounds(ctx context.Context, user *models.User, date time.Time) (time.Time, time.Time, error) {
+	calendar, err := s.SummaryCalendar(ctx, user.ID)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !calendar.IsZero() {
+		start, end := calendar.Bounds(date)
+		return start, end, nil
+	}
+	start := period.StartOfWeek(date, period.FirstWeekday(user.WeekStart))
+	return start, period.SummaryEnd(start), nil
+}
@@ -0,0 +1,398 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// PromptChannel delivers daily prompts over a chat integration
+type PromptChannel interface {
+	SendDailyPrompt(ctx context.Context, user *models.User, target *models.UserChannel, quote, recap string) error
+}
+
+// RegisterChannel makes a chat integration available for users whose
+// delivery_channel matches name
+func (s *Service) RegisterChannel(name string, channel PromptChannel) {
+	s.channels[name] = channel
+}
+
+// SendDailyPrompt delivers the prompt over the user's delivery channel, falling
+// back to email when the channel is unavailable or not linked. At most one
+// prompt is sent per user per local date; a second attempt returns a
+// CodeConflict error.
+func (s *Service) SendDailyPrompt(ctx context.Context, user *models.User) error {
+	promptDate, err := s.claimPromptSend(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	if err := s.deliverDailyPrompt(ctx, user); err != nil {
+		s.releasePromptSend(ctx, user.ID, promptDate)
+		return err
+	}
+	return nil
+}
+
+func (s *Service) deliverDailyPrompt(ctx context.Context, user *models.User) error {
+	quote := s.pickQuote(ctx, user)
+	recap := s.promptRecap(ctx, user)
+
+	if user.DeliveryChannel != "" && user.DeliveryChannel != models.DeliveryChannelEmail {
+		sent, err := s.sendChannelPrompt(ctx, user, quote, recap)
+		if err != nil || sent {
+			return err
+		}
+	}
+
+	return s.emailService.SendDailyPrompt(ctx, user.ID, user.Email, user.ReplyToken, user.ProjectFocus, user.EntryFormat, quote, recap, email.PromptVariationFor(user))
+}
+
+// claimPromptSend records today's prompt in the user's timezone, failing if
+// one was already recorded. The unique key makes concurrent claims safe.
+func (s *Service) claimPromptSend(ctx context.Context, user *models.User) (string, error) {
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	promptDate := time.Now().In(loc).Format("2006-01-02")
+
+	query := `
+		INSERT INTO prompt_sends (user_id, prompt_date)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, prompt_date) DO NOTHING`
+
+	result, err := s.db.ExecContext(ctx, query, user.ID, promptDate)
+	if err != nil {
+		return "", fmt.Errorf("failed to record prompt send: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return "", apperrors.New(apperrors.CodeConflict, "daily prompt already sent to user %d for %s", user.ID, promptDate)
+	}
+
+	return promptDate, nil
+}
+
+// PromptSent reports whether the user has been sent a daily prompt for at's
+// date in their timezone
+func (s *Service) PromptSent(ctx context.Context, user *models.User, at time.Time) (bool, error) {
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	var sent bool
+	query := `SELECT EXISTS (SELECT 1 FROM prompt_sends WHERE user_id = $1 AND prompt_date = $2)`
+	if err := s.db.QueryRowContext(ctx, query, user.ID, at.In(loc).Format("2006-01-02")).Scan(&sent); err != nil {
+		return false, fmt.Errorf("failed to check prompt send: %w", err)
+	}
+	return sent, nil
+}
+
+// releasePromptSend removes a claim whose delivery failed so a retry can send
+func (s *Service) releasePromptSend(ctx context.Context, userID int, promptDate string) {
+	query := `DELETE FROM prompt_sends WHERE user_id = $1 AND prompt_date = $2`
+	if _, err := s.db.ExecContext(ctx, query, userID, promptDate); err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Error("Failed to release prompt send after delivery failure")
+	}
+}
+
+func (s *Service) sendChannelPrompt(ctx context.Context, user *models.User, quote, recap string) (bool, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"user_id": user.ID,
+		"channel": user.DeliveryChannel,
+	})
+
+	channel, ok := s.channels[user.DeliveryChannel]
+	if !ok {
+		logger.Warn("No integration registered for delivery channel, falling back to email")
+		return false, nil
+	}
+
+	target, err := s.GetUserChannel(ctx, user.ID, user.DeliveryChannel)
+	if err != nil {
+		return false, err
+	}
+	if target == nil || target.WebhookURL == nil {
+		logger.Warn("Delivery channel not linked, falling back to email")
+		return false, nil
+	}
+
+	if err := channel.SendDailyPrompt(ctx, user, target, quote, recap); err != nil {
+		return false, fmt.Errorf("failed to send %s prompt: %w", user.DeliveryChannel, err)
+	}
+
+	logger.Info("Daily prompt delivered")
+	return true, nil
+}
+
+// HandleChannelReply processes a reply sent from a chat integration by the
+// linked external user
+func (s *Service) HandleChannelReply(ctx context.Context, channel, externalUserID, body string) error {
+	user, err := s.getUserByChannelIdentity(ctx, channel, externalUserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "no user linked to %s account %s", channel, externalUserID)
+	}
+	if !user.IsVerified {
+		return apperrors.New(apperrors.CodeNotVerified, "finish email signup before replying from %s", channel)
+	}
+
+	// Chat replies have no subject, so each channel is one thread
+	return s.processReply(ctx, user, channel, body, false)
+}
+
+// ImportChannelMessage processes a chat message the linked external user
+// posted, as a reply sent when the message was. messageID identifies the
+// message on the channel; a message already imported returns a CodeConflict
+// error, so running an import again or logging a message twice is safe.
+func (s *Service) ImportChannelMessage(ctx context.Context, channel, externalUserID, messageID, body string, sentAt time.Time) error {
+	user, err := s.getUserByChannelIdentity(ctx, channel, externalUserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return apperrors.New(apperrors.CodeUserNotFound, "no user linked to %s account %s", channel, externalUserID)
+	}
+	if !user.IsVerified {
+		return apperrors.New(apperrors.CodeNotVerified, "finish email signup before logging from %s", channel)
+	}
+
+	query := `
+		INSERT INTO channel_messages (channel, message_id, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (channel, message_id) DO NOTHING`
+
+	result, err := s.db.ExecContext(ctx, query, channel, messageID, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record %s message: %w", channel, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.New(apperrors.CodeConflict, "%s message %s was already imported", channel, messageID)
+	}
+
+	var entryDate *time.Time
+	day := sentAt.UTC().Truncate(24 * time.Hour)
+	if day.Before(time.Now().UTC().Truncate(24 * time.Hour)) {
+		entryDate = &day
+	}
+
+	if err := s.processReplyFor(ctx, user, channel, body, false, entryDate); err != nil {
+		// Let a retry import it
+		query := `DELETE FROM channel_messages WHERE channel = $1 AND message_id = $2`
+		if _, delErr := s.db.ExecContext(ctx, query, channel, messageID); delErr != nil {
+			logrus.WithError(delErr).WithField("message_id", messageID).Error("Failed to release channel message after processing failure")
+		}
+		return err
+	}
+	return nil
+}
+
+// LinkChannel stores a user's chat identity and makes it their delivery channel
+func (s *Service) LinkChannel(ctx context.Context, userID int, channel string, webhookURL, externalUserID *string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// With a credential store the webhook URL, which anyone can post to, is
+	// kept encrypted and user_channels only records that it exists
+	storedURL := webhookURL
+	if s.credentials != nil {
+		storedURL = nil
+	}
+
+	query := `
+		INSERT INTO user_channels (user_id, channel, webhook_url, external_user_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, channel) DO UPDATE
+		SET webhook_url = EXCLUDED.webhook_url, external_user_id = EXCLUDED.external_user_id, updated_at = NOW()`
+
+	if _, err := tx.ExecContext(ctx, query, userID, channel, storedURL, externalUserID); err != nil {
+		return fmt.Errorf("failed to link channel: %w", err)
+	}
+
+	if s.credentials != nil {
+		if webhookURL != nil {
+			err = s.credentials.Put(ctx, userID, channel, *webhookURL)
+		} else {
+			err = s.credentials.Delete(ctx, userID, channel)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	query = `UPDATE users SET delivery_channel = $1, updated_at = NOW() WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, query, channel, userID); err != nil {
+		return fmt.Errorf("failed to update delivery channel: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.emailService.Users().Invalidate(userID)
+	return nil
+}
+
+// LinkChannelIdentity stores a user's identity on a chat integration that
+// doesn't deliver prompts, leaving their delivery channel alone. Messages
+// they post in importChannel, if set, are imported daily.
+func (s *Service) LinkChannelIdentity(ctx context.Context, userID int, channel, externalUserID string, importChannel *string) error {
+	var taken bool
+	query := `SELECT EXISTS (SELECT 1 FROM user_channels WHERE channel = $1 AND external_user_id = $2 AND user_id <> $3)`
+	if err := s.db.QueryRowContext(ctx, query, channel, externalUserID, userID).Scan(&taken); err != nil {
+		return fmt.Errorf("failed to check channel identity: %w", err)
+	}
+	if taken {
+		return apperrors.New(apperrors.CodeConflict, "%s account %s is linked to another user", channel, externalUserID)
+	}
+
+	query = `
+		INSERT INTO user_channels (user_id, channel, external_user_id, import_channel)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, channel) DO UPDATE
+		SET external_user_id = EXCLUDED.external_user_id, import_channel = EXCLUDED.import_channel, updated_at = NOW()`
+
+	if _, err := s.db.ExecContext(ctx, query, userID, channel, externalUserID, importChannel); err != nil {
+		return fmt.Errorf("failed to link channel: %w", err)
+	}
+	return nil
+}
+
+// ChannelImports returns the links on channel that have a channel to import
+// messages from
+func (s *Service) ChannelImports(ctx context.Context, channel string) ([]*models.UserChannel, error) {
+	query := `
+		SELECT id, user_id, channel, external_user_id, import_channel, created_at, updated_at
+		FROM user_channels
+		WHERE channel = $1 AND import_channel IS NOT NULL AND external_user_id IS NOT NULL
+		ORDER BY user_id`
+
+	rows, err := s.db.QueryContext(ctx, query, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query channel imports: %w", err)
+	}
+	defer rows.Close()
+
+	var links []*models.UserChannel
+	for rows.Next() {
+		var uc models.UserChannel
+		if err := rows.Scan(&uc.ID, &uc.UserID, &uc.Channel, &uc.ExternalUserID, &uc.ImportChannel, &uc.CreatedAt, &uc.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan channel import: %w", err)
+		}
+		links = append(links, &uc)
+	}
+	return links, rows.Err()
+}
+
+// GetUserChannel returns the user's identity on a chat integration, or nil if
+// not linked. An encrypted webhook URL is decrypted into WebhookURL.
+func (s *Service) GetUserChannel(ctx context.Context, userID int, channel string) (*models.UserChannel, error) {
+	query := `
+		SELECT id, user_id, channel, webhook_url, external_user_id, import_channel, created_at, updated_at
+		FROM user_channels WHERE user_id = $1 AND channel = $2`
+
+	var uc models.UserChannel
+	var webhookURL, externalUserID sql.NullString
+
+	err := s.db.QueryRowContext(ctx, query, userID, channel).Scan(
+		&uc.ID, &uc.UserID, &uc.Channel, &webhookURL, &externalUserID, &uc.ImportChannel, &uc.CreatedAt, &uc.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user channel: %w", err)
+	}
+
+	if webhookURL.Valid {
+		uc.WebhookURL = &webhookURL.String
+	} else if s.credentials != nil {
+		secret, err := s.credentials.Get(ctx, userID, channel)
+		if err != nil {
+			return nil, err
+		}
+		if secret != "" {
+			uc.WebhookURL = &secret
+		}
+	}
+	if externalUserID.Valid {
+		uc.ExternalUserID = &externalUserID.String
+	}
+
+	return &uc, nil
+}
+
+func (s *Service) getUserByChannelIdentity(ctx context.Context, channel, externalUserID string) (*models.User, error) {
+	query := `
+		SELECT u.email FROM user_channels uc
+		JOIN users u ON u.id = uc.user_id
+		WHERE uc.channel = $1 AND uc.external_user_id = $2`
+
+	var emailAddr string
+	err := s.db.QueryRowContext(ctx, query, channel, externalUserID).Scan(&emailAddr)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up channel identity: %w", err)
+	}
+
+	return s.emailService.GetUserByEmail(ctx, emailAddr)
+}
+
+// EncryptChannelWebhooks moves webhook URLs still stored in plaintext in
+// user_channels into the credential store and returns how many it moved
+func (s *Service) EncryptChannelWebhooks(ctx context.Context) (int, error) {
+	if s.credentials == nil {
+		return 0, apperrors.New(apperrors.CodeInvalidInput, "INTEGRATION_ENCRYPTION_KEYS is not set")
+	}
+
+	query := `SELECT user_id, channel, webhook_url FROM user_channels WHERE webhook_url IS NOT NULL`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query channel webhooks: %w", err)
+	}
+
+	type webhook struct {
+		userID  int
+		channel string
+		url     string
+	}
+	var plaintext []webhook
+	for rows.Next() {
+		var w webhook
+		if err := rows.Scan(&w.userID, &w.channel, &w.url); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan channel webhook: %w", err)
+		}
+		plaintext = append(plaintext, w)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query channel webhooks: %w", err)
+	}
+
+	moved := 0
+	for _, w := range plaintext {
+		if err := s.credentials.Put(ctx, w.userID, w.channel, w.url); err != nil {
+			return moved, err
+		}
+		query := `UPDATE user_channels SET webhook_url = NULL, updated_at = NOW() WHERE user_id = $1 AND channel = $2`
+		if _, err := s.db.ExecContext(ctx, query, w.userID, w.channel); err != nil {
+			return moved, fmt.Errorf("failed to clear channel webhook: %w", err)
+		}
+		moved++
+	}
+	return moved, nil
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// ClarificationPolicy limits how many clarification emails one reply thread
+// can trigger. Once a thread reaches MaxAttempts unparseable replies the user
+// is asked for plain text instead, AdminEmail (if set) is notified, and later
+// failures on the thread are only logged. A thread with no failures for
+// ResetAfter starts over. MaxAttempts of zero never escalates.
+type ClarificationPolicy struct {
+	MaxAttempts int
+	ResetAfter  time.Duration
+	AdminEmail  string
+}
+
+// DefaultClarificationPolicy escalates on the second failure in a day
+var DefaultClarificationPolicy = ClarificationPolicy{
+	MaxAttempts: 2,
+	ResetAfter:  24 * time.Hour,
+}
+
+// SetClarificationPolicy replaces DefaultClarificationPolicy
+func (s *Service) SetClarificationPolicy(policy ClarificationPolicy) {
+	s.clarification = policy
+}
+
+// replyPrefixRegex matches the reply and forward prefixes mail clients add
+// to a subject, in a few languages
+var replyPrefixRegex = regexp.MustCompile(`(?i)^\s*(re|fwd?|aw|wg|sv|tr|rv)\s*(\[\d+\])?\s*:\s*`)
+
+// threadKey identifies the conversation a reply belongs to, so "Re: Re: X"
+// and "X" count against the same clarification attempts
+func threadKey(subject string) string {
+	key := strings.TrimSpace(subject)
+	for replyPrefixRegex.MatchString(key) {
+		key = replyPrefixRegex.ReplaceAllString(key, "")
+	}
+	key = strings.ToLower(strings.TrimSpace(key))
+	if runes := []rune(key); len(runes) > 255 {
+		key = string(runes[:255])
+	}
+	return key
+}
+
+// requestClarification records a failed reply on thread and sends whichever
+// email the attempt count calls for
+func (s *Service) requestClarification(ctx context.Context, user *models.User, thread, originalMessage string) error {
+	if s.clarification.MaxAttempts <= 0 {
+		return s.emailService.SendClarificationRequest(ctx, user.ID, user.Email, originalMessage)
+	}
+
+	query := `
+		INSERT INTO clarification_state (user_id, thread_key, attempts, last_attempt_at)
+		VALUES ($1, $2, 1, NOW())
+		ON CONFLICT (user_id, thread_key) DO UPDATE
+		SET attempts = CASE
+				WHEN clarification_state.last_attempt_at < $3 THEN 1
+				ELSE clarification_state.attempts + 1
+			END,
+			last_attempt_at = NOW()
+		RETURNING attempts`
+
+	cutoff := time.Now().UTC().Add(-s.clarification.ResetAfter)
+	var attempts int
+	if err := s.db.QueryRowContext(ctx, query, user.ID, threadKey(thread), cutoff).Scan(&attempts); err != nil {
+		return fmt.Errorf("failed to record clarification attempt: %w", err)
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"user_id":  user.ID,
+		"thread":   thread,
+		"attempts": attempts,
+	})
+
+	switch {
+	case attempts < s.clarification.MaxAttempts:
+		return s.emailService.SendClarificationRequest(ctx, user.ID, user.Email, originalMessage)
+	case attempts == s.clarification.MaxAttempts:
+		logger.Warn("Escalating repeated clarification requests")
+		if s.clarification.AdminEmail != "" {
+			if err := s.emailService.SendClarificationAlert(ctx, s.clarification.AdminEmail, user.Email, thread, attempts, originalMessage); err != nil {
+				logger.WithError(err).Error("Failed to notify admin of clarification loop")
+			}
+		}
+		return s.emailService.SendPlainTextClarification(ctx, user.ID, user.Email)
+	}
+
+	logger.Info("Clarification limit reached, not sending another")
+	return nil
+}
+
+// resetClarifications clears the failed attempts on thread after a reply
+// parses
+func (s *Service) resetClarifications(ctx context.Context, userID int, thread string) error {
+	query := `DELETE FROM clarification_state WHERE user_id = $1 AND thread_key = $2`
+
+	if _, err := s.db.ExecContext(ctx, query, userID, threadKey(thread)); err != nil {
+		return fmt.Errorf("failed to reset clarification attempts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// commandService lets reply commands act on the service without exporting
+// the helpers they call
+type commandService struct {
+	*Service
+}
+
+func (c commandService) SaveEntry(ctx context.Context, user *models.User, content string, projectTag *string, date *time.Time) error {
+	return c.saveEntry(ctx, user.ID, user.EntryFormat, content, projectTag, date)
+}
+
+func (c commandService) SendDataReport(ctx context.Context, user *models.User) error {
+	return c.sendDataReport(ctx, user)
+}
+
+func (c commandService) ResendWeeklySummary(ctx context.Context, user *models.User, date time.Time) error {
+	return c.resendWeeklySummary(ctx, user, date)
+}
+
+func (c commandService) AnswerQuestion(ctx context.Context, user *models.User, question string) error {
+	return c.answerQuestion(ctx, user, question)
+}
+
+func (c commandService) SubmitQuote(ctx context.Context, userID int, submission string) error {
+	return c.submitQuote(ctx, userID, submission)
+}
+
+func (c commandService) UpdateSummaryCC(ctx context.Context, user *models.User, addresses []string) error {
+	return c.updateSummaryCC(ctx, user, addresses)
+}
+
+func (c commandService) UpdateMentor(ctx context.Context, user *models.User, address string) error {
+	return c.updateMentor(ctx, user, address)
+}
+
+func (c commandService) RequestEmailChange(ctx context.Context, user *models.User, address string) error {
+	return c.requestEmailChange(ctx, user, address)
+}
+
+func (c commandService) SetHolidayCalendar(ctx context.Context, userID int, country string) error {
+	return c.setHolidayCalendar(ctx, userID, country)
+}
+
+func (c commandService) AddTimeOff(ctx context.Context, userID int, start, end time.Time) error {
+	return c.addTimeOff(ctx, userID, start, end)
+}
+
+func (c commandService) ClearTimeOff(ctx context.Context, userID int) error {
+	return c.clearTimeOff(ctx, userID)
+}
@@ -0,0 +1,117 @@
+package commands
+
+import "regexp"
+
+// Built-in command names
+const (
+	Pause           = "pause"
+	Project         = "project"
+	Entry           = "entry"
+	EntryDay        = "entry_day"
+	MyData          = "my_data"
+	ResendSummary   = "resend_summary"
+	Time            = "time"
+	Timezone        = "timezone"
+	SummaryCC       = "summary_cc"
+	Mentor          = "mentor"
+	Ask             = "ask"
+	Holiday         = "holiday"
+	Off             = "off"
+	EntryFormat     = "entry_format"
+	SummaryVoice    = "summary_voice"
+	Quote           = "quote"
+	Quotes          = "quotes"
+	Compare         = "compare"
+	Goals           = "goals"
+	Language        = "language"
+	SummaryLanguage = "summary_language"
+	Tracking        = "tracking"
+	Thread          = "thread"
+	DeleteEntry     = "delete_entry"
+	RestoreEntry    = "restore_entry"
+	ChangeEmail     = "change_email"
+)
+
+// RegisterBuiltin adds the reply commands to r. <project> and <date> come
+// before <entry> so entries in the same reply are tagged with the new
+// project and saved for that day.
+func RegisterBuiltin(r *Registry) {
+	r.Register(&pauseCommand{tag{Pause,
+		"<pause>3 days</pause> - Pause prompts",
+		regexp.MustCompile(`<pause>([^<]+)</pause>`)}})
+	r.Register(&projectCommand{tag{Project,
+		"<project>New Project</project> - Update project focus",
+		regexp.MustCompile(`<project>([^<]+)</project>`)}})
+	r.Register(&entryDayCommand{tag{EntryDay,
+		"<date>yesterday</date> - Save this reply's entry for another day (yesterday, a weekday or YYYY-MM-DD)",
+		regexp.MustCompile(`(?i)<date>([^<]+)</date>`)}})
+	r.Register(&entryCommand{tag{Entry,
+		"<entry>Shipped the release</entry> - Save an entry alongside other commands",
+		regexp.MustCompile(`<entry>([^<]+)</entry>`)}})
+	r.Register(&myDataCommand{tag{MyData,
+		"<my data> - Email a report of everything stored about you",
+		regexp.MustCompile(`(?i)<my\s*data\s*/?>`)}})
+	r.Register(&resendSummaryCommand{tag{ResendSummary,
+		"<resend summary last week> - Resend a weekly summary (last week, this week or YYYY-MM-DD)",
+		regexp.MustCompile(`(?i)<resend\s+summary\s*([^>]*)>`)}})
+	r.Register(&timeCommand{tag{Time,
+		"<time>8am</time> - Change your daily prompt time",
+		regexp.MustCompile(`(?i)<time>([^<]+)</time>`)}})
+	r.Register(&timezoneCommand{tag{Timezone,
+		"<timezone>Europe/Berlin</timezone> - Change your timezone",
+		regexp.MustCompile(`(?i)<timezone>([^<]+)</timezone>`)}})
+	r.Register(&summaryCCCommand{tag{SummaryCC,
+		"<cc>manager@example.com</cc> - CC your weekly summary (none to stop)",
+		regexp.MustCompile(`(?i)<cc>([^<]*)</cc>`)}})
+	r.Register(&mentorCommand{tag{Mentor,
+		"<mentor>mentor@example.com</mentor> - Send a mentor a monthly digest (none to stop)",
+		regexp.MustCompile(`(?i)<mentor>([^<]*)</mentor>`)}})
+	r.Register(&askCommand{tag{Ask,
+		"<ask>When did I last work on billing?</ask> - Ask a question about your journal",
+		regexp.MustCompile(`(?i)<ask>([^<]+)</ask>`)}})
+	r.Register(&holidayCommand{tag{Holiday,
+		"<holiday>US</holiday> - Treat your country's public holidays as days off (none to stop)",
+		regexp.MustCompile(`(?i)<holiday>([^<]*)</holiday>`)}})
+	r.Register(&offCommand{tag{Off,
+		"<off>Dec 23 - Jan 2</off> - Take days off (none to cancel)",
+		regexp.MustCompile(`(?i)<off>([^<]*)</off>`)}})
+	r.Register(&entryFormatCommand{tag{EntryFormat,
+		"<format>standup</format> - Switch entry format (standup, reflection or freeform)",
+		regexp.MustCompile(`(?i)<format>([^<]+)</format>`)}})
+	r.Register(&summaryVoiceCommand{tag{SummaryVoice,
+		"<voice>first person</voice> - Write your weekly summary as you or to you (coach)",
+		regexp.MustCompile(`(?i)<voice>([^<]+)</voice>`)}})
+	r.Register(&quoteCommand{tag{Quote,
+		"<quote>Stay hungry. - Stewart Brand</quote> - Suggest a quote for daily prompts",
+		regexp.MustCompile(`(?i)<quote>([^<]+)</quote>`)}})
+	r.Register(&quotesCommand{tag{Quotes,
+		"<quotes>off</quotes> - Hide or show the daily quote",
+		regexp.MustCompile(`(?i)<quotes>\s*(on|off)\s*</quotes>`)}})
+	r.Register(&compareCommand{tag{Compare,
+		"<compare>off</compare> - Stop or resume comparing summaries with previous weeks",
+		regexp.MustCompile(`(?i)<compare>\s*(on|off)\s*</compare>`)}})
+	r.Register(&goalsCommand{tag{Goals,
+		"<goals>on</goals> - Get a Monday prompt for the week's goals, echoed in your summary",
+		regexp.MustCompile(`(?i)<goals>\s*(on|off)\s*</goals>`)}})
+	r.Register(&languageCommand{tag{Language,
+		"<language>es</language> - Reply with keywords in your language, e.g. \"pausa 2 semanas\" (en, es, fr, de, pt)",
+		regexp.MustCompile(`(?i)<language>([^<]+)</language>`)}})
+	r.Register(&summaryLanguageCommand{tag{SummaryLanguage,
+		"<summary language>fr</summary language> - Write your weekly summary in a language (auto matches your entries)",
+		regexp.MustCompile(`(?i)<summary[ _]language>([^<]+)</summary[ _]language>`)}})
+	r.Register(&trackingCommand{tag{Tracking,
+		"<tracking>off</tracking> - Stop or allow counting when you open or click your weekly summary",
+		regexp.MustCompile(`(?i)<tracking>\s*(on|off)\s*</tracking>`)}})
+	r.Register(&threadCommand{tag{Thread,
+		"<thread>weekly</thread> - Keep a week's prompts in one email thread, or start a new one daily",
+		regexp.MustCompile(`(?i)<thread>([^<]+)</thread>`)}})
+	r.Register(&entryDateCommand{tag{DeleteEntry,
+		"<delete entry today> - Delete an entry (today, yesterday or YYYY-MM-DD)",
+		regexp.MustCompile(`(?i)<delete\s+entry\s*([^>]*)>`)}, false})
+	r.Register(&entryDateCommand{tag{RestoreEntry,
+		"<restore entry yesterday> - Restore a deleted entry",
+		regexp.MustCompile(`(?i)<restore\s+entry\s*([^>]*)>`)}, true})
+	r.Register(&changeEmailCommand{tag{ChangeEmail,
+		"<change email to new@example.com> - Move your account to a new address, confirmed by a code sent there",
+		regexp.MustCompile(`(?i)<change\s+email\s+(?:to\s+)?([^>]*)>`)}})
+}
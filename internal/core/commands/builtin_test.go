@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandParse(t *testing.T) {
+	tests := []struct {
+		command   string
+		arg       string
+		wantValue string
+		wantErr   string
+	}{
+		{Pause, "2 weeks", "2 weeks", ""},
+		{Pause, "next month", "next month", ""},
+		{Pause, "until May 3rd", "until May 3rd", ""},
+		{Pause, "forever", "", "invalid pause"},
+		{Pause, "until 2024-12-01", "", "has already ended"},
+		{Project, " Apollo ", "Apollo", ""},
+		{Entry, " fixed the build ", "fixed the build", ""},
+		{EntryDay, " yesterday ", "yesterday", ""},
+		{EntryDay, "Thursday", "Thursday", ""},
+		{EntryDay, "2024-12-28", "", "can't be dated in the future"},
+		{EntryDay, "last week", "", `expected "today", "yesterday", a weekday or YYYY-MM-DD`},
+		{MyData, "", "", ""},
+		{ResendSummary, "", "", ""},
+		{ResendSummary, " this week /", "this week", ""},
+		{ResendSummary, "2024-12-02", "2024-12-02", ""},
+		{ResendSummary, "last month", "", `expected "last week", "this week" or YYYY-MM-DD`},
+		{Time, "4 pm", "16:00", ""},
+		{Time, "09:30", "09:30", ""},
+		{Time, "noonish", "", "unable to parse time"},
+		{Timezone, "america/new_york", "America/New_York", ""},
+		{Timezone, "utc", "UTC", ""},
+		{Timezone, "Mars/Olympus", "", "invalid timezone"},
+		{Timezone, "EST", "America/New_York", ""},
+		{Timezone, "GMT+2", "Etc/GMT-2", ""},
+		{Timezone, "CST", "", `"CST" could mean America/Chicago or Asia/Shanghai`},
+		{SummaryCC, "A@Example.com; b@example.com a@example.com", "a@example.com,b@example.com", ""},
+		{SummaryCC, "none", "", ""},
+		{SummaryCC, "not-an-address", "", "invalid address"},
+		{SummaryCC, "a@x.com b@x.com c@x.com d@x.com", "", "at most 3 CC addresses"},
+		{Mentor, " Lead@Example.com ", "lead@example.com", ""},
+		{Mentor, "none", "", ""},
+		{Mentor, "lead at example", "", "invalid address"},
+		{Ask, "what did I ship?", "what did I ship?", ""},
+		{Holiday, "uk", "GB", ""},
+		{Holiday, "none", "", ""},
+		{Holiday, "atlantis", "", "no holiday calendar"},
+		{Off, "none", "none", ""},
+		{Off, "Dec 30 - Jan 2", "Dec 30 - Jan 2", ""},
+		{Off, "2025-01-05 to 2025-01-02", "", "range ends before it starts"},
+		{Off, "someday", "", `expected a date like "Dec 23"`},
+		{EntryFormat, "standup", "standup", ""},
+		{EntryFormat, "haiku", "", "haiku"},
+		{SummaryVoice, "first-person", "first_person", ""},
+		{SummaryVoice, "you", "coach", ""},
+		{SummaryVoice, "robot", "", "invalid summary voice"},
+		{Quote, `"Ship it" - Ada`, `"Ship it" - Ada`, ""},
+		{Quote, `""`, "", "quote text is empty"},
+		{Quotes, "OFF", "off", ""},
+		{Compare, "On", "on", ""},
+		{Tracking, "OFF", "off", ""},
+		{Thread, " Weekly ", "weekly", ""},
+		{Thread, "day", "daily", ""},
+		{Thread, "monthly", "", "invalid prompt threading"},
+		{Goals, "ON", "on", ""},
+		{Language, "Español", "es", ""},
+		{Language, "german", "de", ""},
+		{Language, "klingon", "", "unsupported language"},
+		{SummaryLanguage, "Français", "fr", ""},
+		{SummaryLanguage, " Auto ", "auto", ""},
+		{SummaryLanguage, "klingon", "", "unsupported language"},
+		{DeleteEntry, "yesterday", "yesterday", ""},
+		{DeleteEntry, "2024-12-20/", "2024-12-20", ""},
+		{DeleteEntry, "last tuesday", "", `expected "today", "yesterday" or YYYY-MM-DD`},
+		{RestoreEntry, "today", "today", ""},
+		{ChangeEmail, " New@Example.com ", "new@example.com", ""},
+		{ChangeEmail, "Alex <alex@newco.example.com>", "alex@newco.example.com", ""},
+		{ChangeEmail, "", "", "invalid address"},
+		{ChangeEmail, "not an address", "", "invalid address"},
+	}
+
+	r := builtinRegistry()
+	for _, tt := range tests {
+		t.Run(tt.command+"/"+tt.arg, func(t *testing.T) {
+			c, ok := r.Get(tt.command)
+			if !ok {
+				t.Fatalf("command %s is not registered", tt.command)
+			}
+
+			inv, err := c.Parse(tt.arg, testNow)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Parse(%q) succeeded, want error containing %q", tt.arg, tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Parse(%q) error = %q, want it to contain %q", tt.arg, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.arg, err)
+			}
+			if inv.Value != tt.wantValue {
+				t.Errorf("Parse(%q) value = %q, want %q", tt.arg, inv.Value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestCommandParseFields(t *testing.T) {
+	r := builtinRegistry()
+	parse := func(name, arg string) *Invocation {
+		t.Helper()
+		c, _ := r.Get(name)
+		inv, err := c.Parse(arg, testNow)
+		if err != nil {
+			t.Fatalf("%s Parse(%q) error = %v", name, arg, err)
+		}
+		return inv
+	}
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	if inv := parse(Pause, "3 days"); !inv.Date.Equal(testNow.Add(72 * time.Hour)) {
+		t.Errorf("pause 3 days ends %s", inv.Date)
+	}
+
+	if inv := parse(Time, "4:15 PM"); inv.Time.Hour() != 16 || inv.Time.Minute() != 15 {
+		t.Errorf("time 4:15 PM = %s", inv.Time)
+	}
+
+	if inv := parse(ResendSummary, ""); !inv.Date.Equal(testNow.AddDate(0, 0, -7)) {
+		t.Errorf("resend summary defaults to %s, want a week ago", inv.Date)
+	}
+
+	if inv := parse(DeleteEntry, "yesterday"); !inv.Date.Equal(day(2024, 12, 26)) {
+		t.Errorf("delete entry yesterday = %s", inv.Date)
+	}
+
+	// testNow is a Friday
+	if inv := parse(EntryDay, "thu"); !inv.Date.Equal(day(2024, 12, 26)) {
+		t.Errorf("date thu = %s", inv.Date)
+	}
+	if inv := parse(EntryDay, "friday"); !inv.Date.Equal(day(2024, 12, 27)) {
+		t.Errorf("date friday = %s, want today", inv.Date)
+	}
+
+	if inv := parse(SummaryCC, "a@x.com, b@x.com"); len(inv.Addresses) != 2 || inv.Addresses[1] != "b@x.com" {
+		t.Errorf("cc addresses = %v", inv.Addresses)
+	}
+
+	if inv := parse(Off, ""); inv.Date != nil || inv.EndDate != nil {
+		t.Errorf("empty off = %s to %s, want it to clear time off", inv.Date, inv.EndDate)
+	}
+}
+
+func TestParseOffRange(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec      string
+		now       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{"Dec 30 - Jan 2", day(2024, 12, 27), day(2024, 12, 30), day(2025, 1, 2)},
+		{"Dec 23 - Jan 2", day(2025, 1, 1), day(2024, 12, 23), day(2025, 1, 2)},
+		{"Jan 6", day(2024, 12, 27), day(2025, 1, 6), day(2025, 1, 6)},
+		{"2025-03-03 to 2025-03-07", day(2024, 12, 27), day(2025, 3, 3), day(2025, 3, 7)},
+		{"Dec 30 2024 until Jan 3", day(2024, 6, 1), day(2024, 12, 30), day(2025, 1, 3)},
+		{"23 December through 2 January 2025", day(2024, 6, 1), day(2024, 12, 23), day(2025, 1, 2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			start, end, err := parseOffRange(tt.spec, tt.now)
+			if err != nil {
+				t.Fatalf("parseOffRange(%q) error = %v", tt.spec, err)
+			}
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("parseOffRange(%q) = %s to %s, want %s to %s",
+					tt.spec, start.Format("2006-01-02"), end.Format("2006-01-02"),
+					tt.wantStart.Format("2006-01-02"), tt.wantEnd.Format("2006-01-02"))
+			}
+		})
+	}
+
+	if _, _, err := parseOffRange("2024-01-01 to 2025-06-01", day(2024, 1, 1)); err == nil {
+		t.Error("parseOffRange accepted a range longer than a year")
+	}
+}
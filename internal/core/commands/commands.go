@@ -0,0 +1,175 @@
+// Package commands defines the tags users can put in a reply, such as
+// <pause>3 days</pause>, and a registry that finds them. Each command parses
+// and validates its own tag and knows how to carry it out, so adding one
+// doesn't touch the reply parser or the code that applies replies.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Command is one kind of reply tag
+type Command interface {
+	// Name identifies the command in logs
+	Name() string
+	// Help is a one-line example of the tag and what it does
+	Help() string
+	// Pattern matches the tag. Its first group, if any, is the argument
+	// passed to Parse. Matches are removed from the reply's entry text.
+	Pattern() *regexp.Regexp
+	// Parse validates arg and returns what to execute, or nil to ignore
+	// the tag. now is the time the reply is parsed.
+	Parse(arg string, now time.Time) (*Invocation, error)
+	// Execute carries out a parsed invocation for env.User
+	Execute(ctx context.Context, env *Env, inv *Invocation) error
+	// OwnerOnly reports whether the command changes the account or deletes
+	// data, so only runs when the reply comes from the user's own address
+	OwnerOnly() bool
+}
+
+// Invocation is a parsed tag. Commands set whichever fields they need.
+type Invocation struct {
+	Command Command
+	Value   string
+	Date    *time.Time
+	Time    *time.Time
+	// Addresses holds the parsed list for the cc command
+	Addresses []string
+	// EndDate is the last day of an off range starting at Date
+	EndDate *time.Time
+	// ParsedAt is the now the tag was parsed at, which Execute reads
+	// relative dates from rather than the time it runs
+	ParsedAt time.Time
+}
+
+// Service is what commands act on
+type Service interface {
+	PauseUser(ctx context.Context, userID int, pauseUntil time.Time) error
+	SaveEntry(ctx context.Context, user *models.User, content string, projectTag *string, date *time.Time) error
+	DeleteEntry(ctx context.Context, userID int, date time.Time) error
+	RestoreEntry(ctx context.Context, userID int, date time.Time) error
+	SendDataReport(ctx context.Context, user *models.User) error
+	ResendWeeklySummary(ctx context.Context, user *models.User, date time.Time) error
+	AnswerQuestion(ctx context.Context, user *models.User, question string) error
+	SubmitQuote(ctx context.Context, userID int, submission string) error
+	UpdateSummaryCC(ctx context.Context, user *models.User, addresses []string) error
+	UpdateMentor(ctx context.Context, user *models.User, address string) error
+	SetHolidayCalendar(ctx context.Context, userID int, country string) error
+	HolidayCalendar(ctx context.Context, userID int) (string, error)
+	AddTimeOff(ctx context.Context, userID int, start, end time.Time) error
+	ClearTimeOff(ctx context.Context, userID int) error
+	RequestEmailChange(ctx context.Context, user *models.User, address string) error
+}
+
+// Env is the state shared by the commands in one reply
+type Env struct {
+	Service Service
+	User    *models.User
+	// ProjectTag tags the entries the reply saves; <project> sets it
+	ProjectTag *string
+	// EntryDate is the day the reply's entries are saved for, nil for today.
+	// It starts as the date of the prompt being replied to; <date> sets it.
+	EntryDate *time.Time
+	// Patch collects preference changes. The caller applies them together
+	// once every command has run, if Patched is set.
+	Patch   models.PreferencesUpdate
+	Patched bool
+}
+
+// Registry holds the commands a reply can contain
+type Registry struct {
+	commands []Command
+	byName   map[string]Command
+}
+
+// NewRegistry returns an empty registry; see RegisterBuiltin
+func NewRegistry() *Registry {
+	return &Registry{byName: map[string]Command{}}
+}
+
+// Register adds c. Names must be unique. Commands are parsed, and so run, in
+// registration order.
+func (r *Registry) Register(c Command) {
+	if _, exists := r.byName[c.Name()]; exists {
+		panic(fmt.Sprintf("commands: %s registered twice", c.Name()))
+	}
+	r.commands = append(r.commands, c)
+	r.byName[c.Name()] = c
+}
+
+// Get returns the command called name
+func (r *Registry) Get(name string) (Command, bool) {
+	c, ok := r.byName[name]
+	return c, ok
+}
+
+// Commands returns every command in registration order
+func (r *Registry) Commands() []Command {
+	return r.commands
+}
+
+// Help lists every command's help line
+func (r *Registry) Help() string {
+	lines := make([]string, 0, len(r.commands))
+	for _, c := range r.commands {
+		lines = append(lines, c.Help())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Parse finds every tag in content and returns the invocations, in
+// registration order, and content without the tags. The first invalid tag
+// fails the whole reply with CodeParseFailure.
+func (r *Registry) Parse(content string, now time.Time) ([]*Invocation, string, error) {
+	var invocations []*Invocation
+	for _, c := range r.commands {
+		for _, match := range c.Pattern().FindAllStringSubmatch(content, -1) {
+			arg := ""
+			if len(match) > 1 {
+				arg = match[1]
+			}
+
+			inv, err := c.Parse(arg, now)
+			if err != nil {
+				return nil, "", apperrors.Wrap(apperrors.CodeParseFailure, err, "invalid %s: %s", c.Name(), arg)
+			}
+			if inv == nil {
+				continue
+			}
+			inv.Command = c
+			inv.ParsedAt = now
+			invocations = append(invocations, inv)
+		}
+	}
+
+	for _, c := range r.commands {
+		content = c.Pattern().ReplaceAllString(content, "")
+	}
+	return invocations, strings.TrimSpace(content), nil
+}
+
+// tag implements the parts of Command every command shares
+type tag struct {
+	name    string
+	help    string
+	pattern *regexp.Regexp
+}
+
+func (t tag) Name() string            { return t.name }
+func (t tag) Help() string            { return t.help }
+func (t tag) Pattern() *regexp.Regexp { return t.pattern }
+
+// OwnerOnly is true unless a command opts out, so new commands are safe from
+// forwarded prompts by default
+func (t tag) OwnerOnly() bool { return true }
+
+func stringPtr(s string) *string { return &s }
+
+func boolPtr(b bool) *bool { return &b }
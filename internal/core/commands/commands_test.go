@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+)
+
+// testNow is a Friday, so "last week" and "yesterday" stay inside December
+var testNow = time.Date(2024, 12, 27, 10, 30, 0, 0, time.UTC)
+
+func builtinRegistry() *Registry {
+	r := NewRegistry()
+	RegisterBuiltin(r)
+	return r
+}
+
+func TestRegistryParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantNames   []string
+		wantValues  []string
+		wantContent string
+	}{
+		{
+			name:        "no tags",
+			content:     "  Shipped the release  ",
+			wantContent: "Shipped the release",
+		},
+		{
+			name:        "invocations follow registration order, not reply order",
+			content:     "<entry>fixed the build</entry> Shipped it <project>Apollo</project> <pause>2 days</pause>",
+			wantNames:   []string{Pause, Project, Entry},
+			wantValues:  []string{"2 days", "Apollo", "fixed the build"},
+			wantContent: "Shipped it",
+		},
+		{
+			name:        "repeated tags run in reply order",
+			content:     "<entry>first</entry><entry>second</entry>",
+			wantNames:   []string{Entry, Entry},
+			wantValues:  []string{"first", "second"},
+			wantContent: "",
+		},
+		{
+			name:        "case-insensitive tags",
+			content:     "Done <TIMEZONE>europe/berlin</TIMEZONE><My Data/>",
+			wantNames:   []string{MyData, Timezone},
+			wantValues:  []string{"", "Europe/Berlin"},
+			wantContent: "Done",
+		},
+		{
+			name:        "a nil invocation is skipped but its tag is still stripped",
+			content:     "Quiet day <ask>  </ask>",
+			wantContent: "Quiet day",
+		},
+		{
+			name:        "unclosed tags are left as text",
+			content:     "Wrote <pause>2 days",
+			wantContent: "Wrote <pause>2 days",
+		},
+	}
+
+	r := builtinRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invocations, content, err := r.Parse(tt.content, testNow)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if content != tt.wantContent {
+				t.Errorf("content = %q, want %q", content, tt.wantContent)
+			}
+			if len(invocations) != len(tt.wantNames) {
+				t.Fatalf("got %d invocations, want %d", len(invocations), len(tt.wantNames))
+			}
+			for i, inv := range invocations {
+				if inv.Command.Name() != tt.wantNames[i] {
+					t.Errorf("invocation %d is %s, want %s", i, inv.Command.Name(), tt.wantNames[i])
+				}
+				if inv.Value != tt.wantValues[i] {
+					t.Errorf("invocation %d value = %q, want %q", i, inv.Value, tt.wantValues[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRegistryParseInvalidTag(t *testing.T) {
+	r := builtinRegistry()
+
+	invocations, content, err := r.Parse("Did things <project>Apollo</project> <time>noonish</time>", testNow)
+	if err == nil {
+		t.Fatal("Parse() succeeded with an invalid <time>")
+	}
+	if !apperrors.Is(err, apperrors.CodeParseFailure) {
+		t.Errorf("error code = %s, want %s", apperrors.CodeOf(err), apperrors.CodeParseFailure)
+	}
+	if !strings.Contains(err.Error(), "invalid time: noonish") {
+		t.Errorf("error = %q, want it to name the command and argument", err)
+	}
+	if invocations != nil || content != "" {
+		t.Errorf("Parse() = %v, %q, want nothing alongside the error", invocations, content)
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := builtinRegistry()
+
+	names := make([]string, 0, len(r.Commands()))
+	for _, c := range r.Commands() {
+		names = append(names, c.Name())
+	}
+	if names[0] != Pause || names[1] != Project || names[2] != EntryDay || names[3] != Entry {
+		t.Errorf("registration order = %v, want pause, project, entry_day, entry first", names)
+	}
+
+	if c, ok := r.Get(Mentor); !ok || c.Name() != Mentor {
+		t.Errorf("Get(%q) = %v, %v", Mentor, c, ok)
+	}
+	if _, ok := r.Get("missing"); ok {
+		t.Error("Get(missing) found a command")
+	}
+
+	if lines := strings.Split(r.Help(), "\n"); len(lines) != len(names) {
+		t.Errorf("Help() has %d lines, want one per command (%d)", len(lines), len(names))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate name did not panic")
+		}
+	}()
+	c, _ := r.Get(Pause)
+	r.Register(c)
+}
+
+func TestOwnerOnly(t *testing.T) {
+	allowed := map[string]bool{Entry: true, EntryDay: true, MyData: true, ResendSummary: true, Ask: true}
+
+	for _, c := range builtinRegistry().Commands() {
+		if got, want := c.OwnerOnly(), !allowed[c.Name()]; got != want {
+			t.Errorf("%s OwnerOnly() = %v, want %v", c.Name(), got, want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type entryCommand struct{ tag }
+
+func (c *entryCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	return &Invocation{Value: strings.TrimSpace(arg)}, nil
+}
+
+func (c *entryCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	return env.Service.SaveEntry(ctx, env.User, inv.Value, env.ProjectTag, env.EntryDate)
+}
+
+// OwnerOnly is false so replies from an alias still journal
+func (c *entryCommand) OwnerOnly() bool { return false }
+
+// entryDayCommand sets the day the reply's entries are saved for
+type entryDayCommand struct{ tag }
+
+func (c *entryDayCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	spec := strings.TrimSpace(arg)
+	date, err := parseEntryDay(spec, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: spec, Date: &date}, nil
+}
+
+func (c *entryDayCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.EntryDate = inv.Date
+	return nil
+}
+
+// OwnerOnly is false as it only dates the entries a forwarded reply may save
+func (c *entryDayCommand) OwnerOnly() bool { return false }
+
+type myDataCommand struct{ tag }
+
+func (c *myDataCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	return &Invocation{}, nil
+}
+
+func (c *myDataCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	return env.Service.SendDataReport(ctx, env.User)
+}
+
+// OwnerOnly is false as the report goes to the user's address, not the sender
+func (c *myDataCommand) OwnerOnly() bool { return false }
+
+type resendSummaryCommand struct{ tag }
+
+func (c *resendSummaryCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	spec := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(arg), "/"))
+	date, err := parseSummaryDate(spec, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: spec, Date: &date}, nil
+}
+
+func (c *resendSummaryCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	return env.Service.ResendWeeklySummary(ctx, env.User, *inv.Date)
+}
+
+// OwnerOnly is false as the summary goes to the user's address
+func (c *resendSummaryCommand) OwnerOnly() bool { return false }
+
+type askCommand struct{ tag }
+
+func (c *askCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	question := strings.TrimSpace(arg)
+	if question == "" {
+		return nil, nil
+	}
+	return &Invocation{Value: question}, nil
+}
+
+func (c *askCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	return env.Service.AnswerQuestion(ctx, env.User, inv.Value)
+}
+
+// OwnerOnly is false as the answer goes to the user's address
+func (c *askCommand) OwnerOnly() bool { return false }
+
+// entryDateCommand deletes, or with restore set restores, the entry for a day
+type entryDateCommand struct {
+	tag
+	restore bool
+}
+
+func (c *entryDateCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	spec := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(arg), "/"))
+	date, err := parseEntryDate(spec, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: spec, Date: &date}, nil
+}
+
+func (c *entryDateCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	if c.restore {
+		return env.Service.RestoreEntry(ctx, env.User.ID, *inv.Date)
+	}
+	return env.Service.DeleteEntry(ctx, env.User.ID, *inv.Date)
+}
+
+// parseEntryDay resolves the day in a <date> command: "today",
+// "yesterday", a weekday within the last week or YYYY-MM-DD. Entries can't
+// be dated in the future.
+func parseEntryDay(spec string, now time.Time) (time.Time, error) {
+	today := now.Truncate(24 * time.Hour)
+	for offset := 0; offset < 7; offset++ {
+		day := today.AddDate(0, 0, -offset)
+		if strings.EqualFold(spec, day.Weekday().String()) || strings.EqualFold(spec, day.Format("Mon")) {
+			return day, nil
+		}
+	}
+
+	date, err := parseEntryDate(spec, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected \"today\", \"yesterday\", a weekday or YYYY-MM-DD: %s", spec)
+	}
+	if date.After(today) {
+		return time.Time{}, fmt.Errorf("entries can't be dated in the future: %s", spec)
+	}
+	return date, nil
+}
+
+// parseSummaryDate resolves a resend spec ("last week", "this week" or a
+// YYYY-MM-DD date) to a date inside the requested week
+func parseSummaryDate(spec string, now time.Time) (time.Time, error) {
+	switch strings.ToLower(spec) {
+	case "", "last week", "previous week":
+		return now.AddDate(0, 0, -7), nil
+	case "this week":
+		return now, nil
+	}
+
+	date, err := time.Parse("2006-01-02", spec)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected \"last week\", \"this week\" or YYYY-MM-DD: %s", spec)
+	}
+	return date, nil
+}
+
+// parseEntryDate resolves the date in a delete or restore entry command:
+// "today", "yesterday" or YYYY-MM-DD
+func parseEntryDate(spec string, now time.Time) (time.Time, error) {
+	today := now.Truncate(24 * time.Hour)
+	switch strings.ToLower(spec) {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	date, err := time.Parse("2006-01-02", spec)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected \"today\", \"yesterday\" or YYYY-MM-DD: %s", spec)
+	}
+	return date, nil
+}
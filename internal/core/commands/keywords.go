@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/language"
+)
+
+var keywordLine = regexp.MustCompile(`^\s*(\pL+)(?:\s*(:)\s*|\s+)(.+?)\s*[.!]?\s*$`)
+
+// Localize rewrites the lines of content that are keyword commands in lang
+// into the tags Parse finds, so "pausa 2 semanas" becomes
+// <pause>2 weeks</pause>. A pause line must be a duration such as
+// "deux semaines"; a project line needs a colon ("Projekt: Apollo") so an
+// entry that starts with the word isn't taken for one. Other lines, and
+// replies in English, are left alone.
+func Localize(content, lang string) string {
+	l, ok := language.Lookup(lang)
+	if !ok || (len(l.Pause) == 0 && len(l.Project) == 0) {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		m := keywordLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		keyword, colon, arg := strings.ToLower(m[1]), m[2] != "", m[3]
+
+		switch {
+		case hasWord(l.Pause, keyword):
+			if duration, ok := localDuration(l, arg); ok {
+				lines[i] = "<pause>" + duration + "</pause>"
+			}
+		case hasWord(l.Project, keyword) && colon:
+			lines[i] = "<project>" + arg + "</project>"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// localDuration translates a duration written in l, such as "2 semanas" or
+// "eine Woche", to the English form parsePauseDuration reads
+func localDuration(l language.Language, spec string) (string, bool) {
+	words := strings.Fields(strings.ToLower(spec))
+	if len(words) == 1 {
+		// "pausa semana": one of the unit
+		words = append([]string{"1"}, words...)
+	}
+	if len(words) != 2 {
+		return "", false
+	}
+
+	number, err := strconv.Atoi(words[0])
+	if err != nil {
+		var ok bool
+		if number, ok = l.Numbers[words[0]]; !ok {
+			return "", false
+		}
+	}
+	unit, ok := l.Units[words[1]]
+	if !ok || number <= 0 {
+		return "", false
+	}
+	if number > 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", number, unit), true
+}
+
+func hasWord(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package commands
+
+import "testing"
+
+func TestLocalize(t *testing.T) {
+	tests := []struct {
+		lang    string
+		content string
+		want    string
+	}{
+		{"es", "pausa 2 semanas", "<pause>2 weeks</pause>"},
+		{"es", "Pausa una semana.", "<pause>1 week</pause>"},
+		{"fr", "pause deux semaines", "<pause>2 weeks</pause>"},
+		{"fr", "Projet : Apollo", "<project>Apollo</project>"},
+		{"de", "Pause: drei Tage", "<pause>3 days</pause>"},
+		{"pt", "pausar 1 mês", "<pause>1 month</pause>"},
+		{"pt", "Projeto: Billing\nShipped the invoices", "<project>Billing</project>\nShipped the invoices"},
+		{"fr", "pause café avec l'équipe", "pause café avec l'équipe"},
+		{"es", "proyecto terminado hoy", "proyecto terminado hoy"},
+		{"es", "Hoy terminé la migración", "Hoy terminé la migración"},
+		{"en", "pause 2 weeks", "pause 2 weeks"},
+		{"", "pausa 2 semanas", "pausa 2 semanas"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang+"/"+tt.content, func(t *testing.T) {
+			if got := Localize(tt.content, tt.lang); got != tt.want {
+				t.Errorf("Localize(%q, %q) = %q, want %q", tt.content, tt.lang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalizedPauseParses(t *testing.T) {
+	r := builtinRegistry()
+	invocations, _, err := r.Parse(Localize("pause deux semaines", "fr"), testNow)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(invocations) != 1 || invocations[0].Command.Name() != Pause || !invocations[0].Date.Equal(testNow.AddDate(0, 0, 14)) {
+		t.Errorf("Parse() = %+v, want a two week pause", invocations)
+	}
+}
@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/holidays"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+)
+
+// maxPauseDays is how far ahead a pause to a date can end
+const maxPauseDays = 366
+
+// PauseOptions are the user settings a pause's dates are read with
+type PauseOptions struct {
+	// Location is the timezone of the day prompts resume, UTC when nil
+	Location *time.Location
+	// Country is the holiday calendar holiday names are looked up in, or ""
+	// for whichever calendar has the holiday soonest
+	Country string
+	// WeekStart is the week start preference "the rest of the week" runs to
+	WeekStart string
+}
+
+// pausePrefixes introduce the day in a pause to a date, longest first.
+// after marks those naming the last day off rather than the day back.
+var pausePrefixes = []struct {
+	prefix string
+	after  bool
+}{
+	{"until after ", true},
+	{"till after ", true},
+	{"til after ", true},
+	{"back after ", true},
+	{"after ", true},
+	{"through ", true},
+	{"thru ", true},
+	{"until ", false},
+	{"till ", false},
+	{"til ", false},
+	{"back on ", false},
+	{"back ", false},
+	{"on ", false},
+}
+
+var (
+	// pauseRest matches "for the rest of the month" and "until the end of the week"
+	pauseRest = regexp.MustCompile(`^(?:for |until |till |through )?(?:the )?(?:rest|end) of (?:the |this )?(week|month|year)$`)
+	// pauseOrdinal matches "3rd" and "3rd of", as in "3rd of May"
+	pauseOrdinal    = regexp.MustCompile(`\b(\d{1,2})(?:st|nd|rd|th)\b(?: of\b)?`)
+	pauseDayOfMonth = regexp.MustCompile(`^(\d{1,2})$`)
+)
+
+// ParsePauseUntil returns when a pause written as spec ends. A duration such
+// as "3 days" runs from now; otherwise the pause ends at the start of the
+// day prompts resume in opts.Location: "until May 3rd", "back on Monday" and
+// "until the 15th" resume that day, "until after Labor Day" and "through
+// Friday" the day after, and "for the rest of the month" on the 1st.
+func ParsePauseUntil(spec string, now time.Time, opts PauseOptions) (time.Time, error) {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	spec = strings.Join(strings.Fields(strings.ToLower(strings.Trim(spec, " .!"))), " ")
+
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	var resume time.Time
+	if m := pauseRest.FindStringSubmatch(spec); m != nil {
+		switch m[1] {
+		case "week":
+			resume = period.StartOfWeek(today, period.FirstWeekday(opts.WeekStart)).AddDate(0, 0, 7)
+		case "month":
+			resume = time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case "year":
+			resume = time.Date(today.Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+		}
+	} else if duration, err := parsePauseDuration(spec); err == nil {
+		return now.Add(duration), nil
+	} else {
+		value, after := spec, false
+		for _, p := range pausePrefixes {
+			if strings.HasPrefix(spec, p.prefix) {
+				value, after = strings.TrimPrefix(spec, p.prefix), p.after
+				break
+			}
+		}
+
+		// Back on a day means tomorrow at the earliest; off through one can
+		// mean today
+		earliest := today.AddDate(0, 0, 1)
+		if after {
+			earliest = today
+		}
+		start, end, ok := pauseDay(pauseOrdinal.ReplaceAllString(value, "$1"), today, earliest, opts)
+		if !ok {
+			return time.Time{}, fmt.Errorf(`invalid pause %q: expected a duration like "2 weeks" or a date like "May 3", "Monday" or "after Labor Day"`, spec)
+		}
+		resume = start
+		if after {
+			resume = end.AddDate(0, 0, 1)
+		}
+	}
+
+	if !resume.After(today) {
+		return time.Time{}, fmt.Errorf("pause %q has already ended", spec)
+	}
+	if resume.After(today.AddDate(0, 0, maxPauseDays)) {
+		return time.Time{}, fmt.Errorf("pause %q ends more than a year from now", spec)
+	}
+	return time.Date(resume.Year(), resume.Month(), resume.Day(), 0, 0, 0, 0, loc), nil
+}
+
+// pauseDay reads the day named in a pause, at or after earliest unless it
+// has a year, and the last day of it that is off: a holiday's observed day
+func pauseDay(value string, today, earliest time.Time, opts PauseOptions) (start, end time.Time, ok bool) {
+	value = strings.TrimPrefix(value, "the ")
+	switch value {
+	case "today":
+		return today, today, true
+	case "tomorrow":
+		day := today.AddDate(0, 0, 1)
+		return day, day, true
+	case "next week":
+		day := period.StartOfWeek(today, period.FirstWeekday(opts.WeekStart)).AddDate(0, 0, 7)
+		return day, day, true
+	case "next month":
+		day := time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		return day, day, true
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(value, "next "), "this ")
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		full := strings.ToLower(weekday.String())
+		if name != full && name != full[:3] {
+			continue
+		}
+		day := earliest
+		for day.Weekday() != weekday {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day, day, true
+	}
+
+	if m := pauseDayOfMonth.FindStringSubmatch(value); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		for i := 0; i < 12; i++ {
+			day := time.Date(earliest.Year(), earliest.Month()+time.Month(i), n, 0, 0, 0, 0, time.UTC)
+			if day.Day() == n && !day.Before(earliest) {
+				return day, day, true
+			}
+		}
+		return time.Time{}, time.Time{}, false
+	}
+
+	if day, hasYear, err := parseOffDay(value); err == nil {
+		if !hasYear {
+			day = withYear(day, earliest.Year())
+			if day.Before(earliest) {
+				day = day.AddDate(1, 0, 0)
+			}
+		}
+		return day, day, true
+	}
+
+	return holidays.Next(opts.Country, value, earliest)
+}
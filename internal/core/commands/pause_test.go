@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+func TestParsePauseUntil(t *testing.T) {
+	losAngeles, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	// Christmas and Boxing Day 2021 fell on a weekend, so in GB they were
+	// also taken on the Monday and Tuesday after
+	december2021 := time.Date(2021, 12, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		spec    string
+		now     time.Time
+		opts    PauseOptions
+		want    time.Time
+		wantErr string
+	}{
+		{spec: "3 days", want: testNow.Add(72 * time.Hour)},
+		{spec: "for 2 weeks", want: testNow.AddDate(0, 0, 14)},
+		{spec: "until May 3rd", want: day(2025, 5, 3)},
+		{spec: "until the 3rd of May.", want: day(2025, 5, 3)},
+		{spec: "until 2nd January", want: day(2025, 1, 2)},
+		{spec: "back on Monday", want: day(2024, 12, 30)},
+		{spec: "monday", want: day(2024, 12, 30)},
+		{spec: "until next fri", want: day(2025, 1, 3)},
+		{spec: "through Friday", want: day(2024, 12, 28)},
+		{spec: "back next week", want: day(2024, 12, 30)},
+		{spec: "until the 15th", want: day(2025, 1, 15)},
+		{spec: "for the rest of the week", want: day(2024, 12, 30)},
+		{spec: "for the rest of the week", opts: PauseOptions{WeekStart: "sunday"}, want: day(2024, 12, 29)},
+		{spec: "for the rest of the month", want: day(2025, 1, 1)},
+		{spec: "until the end of the year", want: day(2025, 1, 1)},
+		{spec: "until after Labor Day", opts: PauseOptions{Country: "US"}, want: day(2025, 9, 2)},
+		{spec: "until after labour day", opts: PauseOptions{Country: "CA"}, want: day(2025, 9, 2)},
+		{spec: "until after New Year's", want: day(2025, 1, 2)},
+		{spec: "until after Thanksgiving", opts: PauseOptions{Country: "US"}, want: day(2025, 11, 28)},
+		{spec: "until after Thanksgiving", want: day(2025, 10, 14)},
+		{spec: "until after Christmas", now: december2021, opts: PauseOptions{Country: "GB"}, want: day(2021, 12, 28)},
+		{spec: "until Christmas", now: december2021, opts: PauseOptions{Country: "GB"}, want: day(2021, 12, 25)},
+		{spec: "back on Monday", opts: PauseOptions{Location: losAngeles}, want: time.Date(2024, 12, 30, 0, 0, 0, 0, losAngeles)},
+		{spec: "forever", wantErr: "invalid pause"},
+		{spec: "until Groundhog Day", wantErr: "invalid pause"},
+		{spec: "until the 32nd", wantErr: "invalid pause"},
+		{spec: "until today", wantErr: "has already ended"},
+		{spec: "until May 3 2024", wantErr: "has already ended"},
+		{spec: "until Dec 2 2026", wantErr: "more than a year"},
+	}
+
+	for _, tt := range tests {
+		now := tt.now
+		if now.IsZero() {
+			now = testNow
+		}
+		got, err := ParsePauseUntil(tt.spec, now, tt.opts)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParsePauseUntil(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePauseUntil(%q) error = %v", tt.spec, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParsePauseUntil(%q) = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+// pauseService records the pause a command sets
+type pauseService struct {
+	Service
+	country string
+	until   time.Time
+}
+
+func (s *pauseService) HolidayCalendar(ctx context.Context, userID int) (string, error) {
+	return s.country, nil
+}
+
+func (s *pauseService) PauseUser(ctx context.Context, userID int, pauseUntil time.Time) error {
+	s.until = pauseUntil
+	return nil
+}
+
+func TestPauseExecutesAsOfParse(t *testing.T) {
+	r := builtinRegistry()
+	invocations, _, err := r.Parse("<pause>until after Thanksgiving</pause>", testNow)
+	if err != nil || len(invocations) != 1 {
+		t.Fatalf("Parse() = %v, %v", invocations, err)
+	}
+
+	// Executed long after the reply arrived, as a replay is
+	service := &pauseService{country: "US"}
+	env := &Env{Service: service, User: &models.User{ID: 1, Timezone: "America/New_York"}}
+	if err := invocations[0].Command.Execute(context.Background(), env, invocations[0]); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	newYork, _ := time.LoadLocation("America/New_York")
+	if want := time.Date(2025, 11, 28, 0, 0, 0, 0, newYork); !service.until.Equal(want) {
+		t.Errorf("paused until %s, want %s", service.until, want)
+	}
+}
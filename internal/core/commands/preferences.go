@@ -0,0 +1,284 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/entryformat"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/language"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/quotes"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// MaxSummaryCC caps how many addresses a user can CC on their weekly summary
+const MaxSummaryCC = 3
+
+type projectCommand struct{ tag }
+
+func (c *projectCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	return &Invocation{Value: strings.TrimSpace(arg)}, nil
+}
+
+func (c *projectCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.Patch.ProjectFocus, env.Patched = stringPtr(inv.Value), true
+	env.ProjectTag = stringPtr(inv.Value)
+	return nil
+}
+
+type entryFormatCommand struct{ tag }
+
+func (c *entryFormatCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	format, err := entryformat.Parse(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: format}, nil
+}
+
+func (c *entryFormatCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.Patch.EntryFormat, env.Patched = stringPtr(inv.Value), true
+	return nil
+}
+
+type summaryVoiceCommand struct{ tag }
+
+func (c *summaryVoiceCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	voice, err := ParseSummaryVoice(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: voice}, nil
+}
+
+func (c *summaryVoiceCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.Patch.SummaryVoice, env.Patched = stringPtr(inv.Value), true
+	return nil
+}
+
+type quoteCommand struct{ tag }
+
+func (c *quoteCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	if _, _, err := quotes.ParseSubmission(arg); err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: strings.TrimSpace(arg)}, nil
+}
+
+func (c *quoteCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	return env.Service.SubmitQuote(ctx, env.User.ID, inv.Value)
+}
+
+type quotesCommand struct{ tag }
+
+func (c *quotesCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	return &Invocation{Value: strings.ToLower(arg)}, nil
+}
+
+func (c *quotesCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.Patch.QuotesEnabled, env.Patched = boolPtr(inv.Value == "on"), true
+	return nil
+}
+
+type compareCommand struct{ tag }
+
+func (c *compareCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	return &Invocation{Value: strings.ToLower(arg)}, nil
+}
+
+func (c *compareCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.Patch.CompareWeeks, env.Patched = boolPtr(inv.Value == "on"), true
+	return nil
+}
+
+type trackingCommand struct{ tag }
+
+func (c *trackingCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	return &Invocation{Value: strings.ToLower(arg)}, nil
+}
+
+func (c *trackingCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.Patch.EmailTracking, env.Patched = boolPtr(inv.Value == "on"), true
+	return nil
+}
+
+type threadCommand struct{ tag }
+
+func (c *threadCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	threading, err := ParsePromptThreading(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: threading}, nil
+}
+
+func (c *threadCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.Patch.PromptThreading, env.Patched = stringPtr(inv.Value), true
+	return nil
+}
+
+type goalsCommand struct{ tag }
+
+func (c *goalsCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	return &Invocation{Value: strings.ToLower(arg)}, nil
+}
+
+func (c *goalsCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.Patch.WeeklyGoals, env.Patched = boolPtr(inv.Value == "on"), true
+	return nil
+}
+
+type languageCommand struct{ tag }
+
+func (c *languageCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	code, err := language.Parse(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: code}, nil
+}
+
+func (c *languageCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.Patch.Language, env.Patched = stringPtr(inv.Value), true
+	return nil
+}
+
+type summaryLanguageCommand struct{ tag }
+
+func (c *summaryLanguageCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	code, err := ParseSummaryLanguage(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: code}, nil
+}
+
+func (c *summaryLanguageCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.Patch.SummaryLanguage, env.Patched = stringPtr(inv.Value), true
+	return nil
+}
+
+type summaryCCCommand struct{ tag }
+
+func (c *summaryCCCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	addresses, err := parseCCList(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: strings.Join(addresses, ","), Addresses: addresses}, nil
+}
+
+func (c *summaryCCCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	return env.Service.UpdateSummaryCC(ctx, env.User, inv.Addresses)
+}
+
+type mentorCommand struct{ tag }
+
+func (c *mentorCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	address, err := parseMentorAddress(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: address}, nil
+}
+
+func (c *mentorCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	return env.Service.UpdateMentor(ctx, env.User, inv.Value)
+}
+
+type changeEmailCommand struct{ tag }
+
+func (c *changeEmailCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(arg))
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %s", strings.TrimSpace(arg))
+	}
+	return &Invocation{Value: strings.ToLower(parsed.Address)}, nil
+}
+
+func (c *changeEmailCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	return env.Service.RequestEmailChange(ctx, env.User, inv.Value)
+}
+
+// ParseSummaryVoice normalizes a summary voice preference
+func ParseSummaryVoice(value string) (string, error) {
+	normalized := strings.NewReplacer("-", " ", "_", " ").Replace(strings.ToLower(strings.TrimSpace(value)))
+	switch normalized {
+	case "first person", "first", "i", "me":
+		return models.SummaryVoiceFirstPerson, nil
+	case "coach", "third person", "third", "you":
+		return models.SummaryVoiceCoach, nil
+	}
+	return "", fmt.Errorf("invalid summary voice: %s (expected first person or coach)", value)
+}
+
+// ParsePromptThreading normalizes a prompt threading preference
+func ParsePromptThreading(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case models.PromptThreadingDaily, "day", "new", "new daily", "separate":
+		return models.PromptThreadingDaily, nil
+	case models.PromptThreadingWeekly, "week", "one", "together":
+		return models.PromptThreadingWeekly, nil
+	}
+	return "", fmt.Errorf("invalid prompt threading: %s (expected daily or weekly)", value)
+}
+
+// ParseSummaryLanguage normalizes a summary language preference: a language
+// code or name, or "auto" to match the entries
+func ParseSummaryLanguage(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case models.SummaryLanguageAuto, "automatic", "detect", "match", "entries":
+		return models.SummaryLanguageAuto, nil
+	}
+	return language.Parse(value)
+}
+
+// parseCCList parses a comma, semicolon or space separated address list.
+// "none" or an empty list clears all CC recipients.
+func parseCCList(list string) ([]string, error) {
+	fields := strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\n' || r == '\t'
+	})
+
+	if len(fields) == 1 && strings.EqualFold(fields[0], "none") {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var addresses []string
+	for _, field := range fields {
+		parsed, err := mail.ParseAddress(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address: %s", field)
+		}
+
+		address := strings.ToLower(parsed.Address)
+		if !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	if len(addresses) > MaxSummaryCC {
+		return nil, fmt.Errorf("at most %d CC addresses are allowed, got %d", MaxSummaryCC, len(addresses))
+	}
+
+	return addresses, nil
+}
+
+// parseMentorAddress parses a single mentor address. "none" or an empty tag
+// removes the mentor, returned as "".
+func parseMentorAddress(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, "none") {
+		return "", nil
+	}
+
+	parsed, err := mail.ParseAddress(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid address: %s", value)
+	}
+	return strings.ToLower(parsed.Address), nil
+}
@@ -0,0 +1,280 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/holidays"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/timezones"
+)
+
+type pauseCommand struct{ tag }
+
+func (c *pauseCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	until, err := ParsePauseUntil(arg, now, PauseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: arg, Date: &until}, nil
+}
+
+// Execute reads the pause again, as of when it was parsed, with the user's
+// timezone, week start and holiday calendar, which Parse doesn't know
+func (c *pauseCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	country, err := env.Service.HolidayCalendar(ctx, env.User.ID)
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(env.User.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	until, err := ParsePauseUntil(inv.Value, inv.ParsedAt, PauseOptions{Location: loc, Country: country, WeekStart: env.User.WeekStart})
+	if err != nil {
+		return err
+	}
+	return env.Service.PauseUser(ctx, env.User.ID, until)
+}
+
+type timeCommand struct{ tag }
+
+func (c *timeCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	promptTime, err := ParseTime(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: promptTime.Format("15:04"), Time: &promptTime}, nil
+}
+
+func (c *timeCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.Patch.PromptTime, env.Patched = stringPtr(inv.Value), true
+	return nil
+}
+
+type timezoneCommand struct{ tag }
+
+func (c *timezoneCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	timezone, err := timezones.Resolve(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: timezone}, nil
+}
+
+func (c *timezoneCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	env.Patch.Timezone, env.Patched = stringPtr(inv.Value), true
+	return nil
+}
+
+type holidayCommand struct{ tag }
+
+func (c *holidayCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	country, err := parseHolidayCountry(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{Value: country}, nil
+}
+
+func (c *holidayCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	return env.Service.SetHolidayCalendar(ctx, env.User.ID, inv.Value)
+}
+
+// offCommand adds a range of days off; an empty range or "none" clears
+// upcoming time off
+type offCommand struct{ tag }
+
+func (c *offCommand) Parse(arg string, now time.Time) (*Invocation, error) {
+	spec := strings.TrimSpace(arg)
+	inv := &Invocation{Value: spec}
+	if spec == "" || strings.EqualFold(spec, "none") {
+		return inv, nil
+	}
+
+	start, end, err := parseOffRange(spec, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	inv.Date, inv.EndDate = &start, &end
+	return inv, nil
+}
+
+func (c *offCommand) Execute(ctx context.Context, env *Env, inv *Invocation) error {
+	if inv.Date == nil {
+		return env.Service.ClearTimeOff(ctx, env.User.ID)
+	}
+	return env.Service.AddTimeOff(ctx, env.User.ID, *inv.Date, *inv.EndDate)
+}
+
+var pauseDurationRegex = regexp.MustCompile(`(\d+)\s*(day|days|week|weeks|month|months)`)
+
+func parsePauseDuration(durationStr string) (time.Duration, error) {
+	durationStr = strings.ToLower(strings.TrimSpace(durationStr))
+
+	// Handle common phrases
+	switch durationStr {
+	case "today":
+		return 24 * time.Hour, nil
+	case "tomorrow":
+		return 24 * time.Hour, nil
+	case "this week", "1 week":
+		return 7 * 24 * time.Hour, nil
+	case "next week":
+		return 7 * 24 * time.Hour, nil
+	case "this month", "1 month":
+		return 30 * 24 * time.Hour, nil
+	case "next month":
+		return 30 * 24 * time.Hour, nil
+	}
+
+	// Try to parse number + unit
+	matches := pauseDurationRegex.FindStringSubmatch(durationStr)
+	if len(matches) != 3 {
+		return 0, fmt.Errorf("invalid duration format: %s", durationStr)
+	}
+
+	number, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid number in duration: %s", matches[1])
+	}
+
+	unit := matches[2]
+	switch unit {
+	case "day", "days":
+		return time.Duration(number) * 24 * time.Hour, nil
+	case "week", "weeks":
+		return time.Duration(number) * 7 * 24 * time.Hour, nil
+	case "month", "months":
+		return time.Duration(number) * 30 * 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("unknown time unit: %s", unit)
+	}
+}
+
+// ParseTime parses a prompt time such as "16:00", "4 PM" or "4pm"
+func ParseTime(timeStr string) (time.Time, error) {
+	// Common time formats
+	formats := []string{
+		"15:04",   // 16:00
+		"3:04 PM", // 4:00 PM
+		"3:04PM",  // 4:00PM
+		"3 PM",    // 4 PM
+		"3PM",     // 4PM
+		"15",      // 16
+	}
+
+	timeStr = strings.ToUpper(strings.TrimSpace(timeStr))
+
+	for _, format := range formats {
+		if t, err := time.Parse(format, timeStr); err == nil {
+			return time.Date(0, 1, 1, t.Hour(), t.Minute(), 0, 0, time.UTC), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse time: %s", timeStr)
+}
+
+// parseHolidayCountry returns the calendar code for value, or "" for "none"
+// or an empty value, which removes the calendar
+func parseHolidayCountry(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, "none") {
+		return "", nil
+	}
+
+	country := holidays.Normalize(value)
+	if country == "" {
+		return "", fmt.Errorf("no holiday calendar for %q (supported: %s)", value, strings.Join(holidays.Supported(), ", "))
+	}
+	return country, nil
+}
+
+// maxOffDays bounds a single <off> range
+const maxOffDays = 366
+
+var (
+	// offRangeSeparator splits "Dec 23 - Jan 2"; the spaces keep it from
+	// splitting YYYY-MM-DD dates
+	offRangeSeparator = regexp.MustCompile(`(?i)\s+(?:-|–|—|to|until|through)\s+`)
+
+	// offDayLayouts are the accepted <off> dates, with a year and without
+	offDayLayouts       = []string{"2006-01-02", "Jan 2 2006", "Jan 2, 2006", "January 2 2006", "January 2, 2006", "2 Jan 2006", "2 January 2006"}
+	offDayLayoutsNoYear = []string{"Jan 2", "January 2", "2 Jan", "2 January"}
+)
+
+// parseOffRange parses "Dec 23 - Jan 2", "2024-12-23 to 2025-01-02" or a
+// single day. Dates without a year are placed in the nearest range that
+// hasn't ended yet, so in late December "Dec 23 - Jan 2" is this holiday
+// season and in early January it is the one in progress.
+func parseOffRange(spec string, now time.Time) (time.Time, time.Time, error) {
+	parts := offRangeSeparator.Split(strings.TrimSpace(spec), 2)
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+
+	start, startHasYear, err := parseOffDay(parts[0])
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, endHasYear, err := parseOffDay(parts[1])
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case startHasYear && !endHasYear:
+		end = withYear(end, start.Year())
+		if end.Before(start) {
+			end = end.AddDate(1, 0, 0)
+		}
+	case !startHasYear && endHasYear:
+		start = withYear(start, end.Year())
+		if start.After(end) {
+			start = start.AddDate(-1, 0, 0)
+		}
+	case !startHasYear && !endHasYear:
+		for year := today.Year() - 1; year <= today.Year()+1; year++ {
+			start, end = withYear(start, year), withYear(end, year)
+			if end.Before(start) {
+				end = end.AddDate(1, 0, 0)
+			}
+			if !end.Before(today) {
+				break
+			}
+		}
+	}
+
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("range ends before it starts: %s", spec)
+	}
+	if end.Sub(start) >= maxOffDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("time off is limited to %d days at a time: %s", maxOffDays, spec)
+	}
+	return start, end, nil
+}
+
+// parseOffDay parses one <off> date and reports whether it included a year
+func parseOffDay(value string) (time.Time, bool, error) {
+	value = strings.Join(strings.Fields(value), " ")
+	for _, layout := range offDayLayouts {
+		if day, err := time.Parse(layout, value); err == nil {
+			return day, true, nil
+		}
+	}
+	for _, layout := range offDayLayoutsNoYear {
+		if day, err := time.Parse(layout, value); err == nil {
+			return day, false, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("expected a date like \"Dec 23\" or YYYY-MM-DD: %s", value)
+}
+
+func withYear(day time.Time, year int) time.Time {
+	return time.Date(year, day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,23 @@
+package core
+
+import (
+	"crypto/ecdh"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
+)
+
+// storedContent returns the raw and parsed content the entries table keeps
+// for content: sealed to sealTo with no parsed content when the user is in
+// self-custody, else the text and its structured form
+func storedContent(sealTo *ecdh.PublicKey, entryFormat, content string) (string, *string, error) {
+	if sealTo != nil {
+		sealed, err := custody.Seal(sealTo, content)
+		return sealed, nil, err
+	}
+
+	parsed, err := structuredContent(entryFormat, content)
+	if err != nil {
+		return "", nil, err
+	}
+	return content, &parsed, nil
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/retention"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// retentionLabels name the retention policies' data types in the data report
+var retentionLabels = map[string]string{
+	"entries":          "journal entries",
+	"email_bodies":     "email contents",
+	"email_events":     "email open and click events",
+	"inbound_messages": "stored copies of your replies",
+	"llm_calls":        "records of AI requests",
+	"audit_logs":       "admin audit records",
+}
+
+// SetRetentionPolicies sets the RETENTION_POLICIES periods the data report
+// describes
+func (s *Service) SetRetentionPolicies(policies map[string]time.Duration) {
+	s.retention = policies
+}
+
+// retentionPolicy describes how long each kind of data is kept under the
+// configured policies
+func (s *Service) retentionPolicy() string {
+	var kept []string
+	if s.retention != nil {
+		for _, name := range retention.Names() {
+			label, ok := retentionLabels[name]
+			if !ok {
+				continue
+			}
+			period := "indefinitely"
+			if keep := s.retention[name]; keep > 0 {
+				period = "for " + retentionPeriod(keep)
+			}
+			kept = append(kept, label+" are kept "+period)
+		}
+	}
+
+	policy := "Entries you delete can be restored for 30 days and are then removed permanently. " +
+		"Attachments are discarded on receipt and never stored."
+	if len(kept) > 0 {
+		joined := strings.Join(kept, "; ")
+		policy = strings.ToUpper(joined[:1]) + joined[1:] + ". " + policy
+	}
+	return policy
+}
+
+// retentionPeriod writes keep in the units RETENTION_POLICIES takes
+func retentionPeriod(keep time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case keep%(365*day) == 0:
+		return plural(int(keep/(365*day)), "year")
+	case keep%day == 0:
+		return plural(int(keep/day), "day")
+	default:
+		return keep.String()
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// BuildDataReport counts everything stored about a user
+func (s *Service) BuildDataReport(ctx context.Context, user *models.User) (*models.DataReport, error) {
+	report := &models.DataReport{
+		Email:           user.Email,
+		MemberSince:     user.CreatedAt,
+		RetentionPolicy: s.retentionPolicy(),
+	}
+
+	var firstEntry, lastEntry sql.NullTime
+	query := `
+		SELECT COUNT(*), MIN(entry_date), MAX(entry_date)
+		FROM entries WHERE user_id = $1 AND deleted_at IS NULL`
+
+	err := s.db.QueryRowContext(ctx, query, user.ID).Scan(&report.EntryCount, &firstEntry, &lastEntry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count entries: %w", err)
+	}
+
+	if firstEntry.Valid {
+		report.FirstEntryDate = &firstEntry.Time
+	}
+	if lastEntry.Valid {
+		report.LastEntryDate = &lastEntry.Time
+	}
+
+	query = `SELECT COUNT(*) FROM weekly_summaries WHERE user_id = $1`
+	if err := s.db.QueryRowContext(ctx, query, user.ID).Scan(&report.WeeklySummaryCount); err != nil {
+		return nil, fmt.Errorf("failed to count weekly summaries: %w", err)
+	}
+
+	query = `SELECT COUNT(*) FROM email_logs WHERE user_id = $1`
+	if err := s.db.QueryRowContext(ctx, query, user.ID).Scan(&report.EmailLogCount); err != nil {
+		return nil, fmt.Errorf("failed to count email logs: %w", err)
+	}
+
+	// Linked chat channels and stored credentials for other services
+	query = `
+		SELECT (SELECT COUNT(*) FROM user_channels WHERE user_id = $1) +
+		       (SELECT COUNT(*) FROM integration_credentials WHERE user_id = $1)`
+	if err := s.db.QueryRowContext(ctx, query, user.ID).Scan(&report.IntegrationCount); err != nil {
+		return nil, fmt.Errorf("failed to count integrations: %w", err)
+	}
+
+	return report, nil
+}
+
+func (s *Service) sendDataReport(ctx context.Context, user *models.User) error {
+	report, err := s.BuildDataReport(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	return s.emailService.SendDataReport(ctx, user.ID, user.Email, report)
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicy(t *testing.T) {
+	day := 24 * time.Hour
+	s := &Service{}
+	if got := s.retentionPolicy(); strings.Contains(got, "kept") {
+		t.Errorf("retentionPolicy() without policies = %q, want only the fixed rules", got)
+	}
+
+	s.SetRetentionPolicies(map[string]time.Duration{
+		"entries":          0,
+		"email_bodies":     14 * day,
+		"email_events":     180 * day,
+		"inbound_messages": 30 * day,
+		"llm_calls":        36 * time.Hour,
+		"audit_logs":       365 * day,
+	})
+	got := s.retentionPolicy()
+	for _, want := range []string{
+		"Admin audit records are kept for 1 year",
+		"email contents are kept for 14 days",
+		"journal entries are kept indefinitely",
+		"records of AI requests are kept for 36h0m0s",
+		"Entries you delete can be restored for 30 days",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("retentionPolicy() = %q, missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "delete your account") {
+		t.Errorf("retentionPolicy() = %q promises account deletion", got)
+	}
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// emailChangeCodeTTL is how long the code sent to a new address confirms
+// the change
+const emailChangeCodeTTL = 24 * time.Hour
+
+// SetEmailChangeAliasWindow sets how long a user's previous address still
+// finds them for inbound mail after they change it. Zero ends it at once.
+func (s *Service) SetEmailChangeAliasWindow(window time.Duration) {
+	s.emailAliasWindow = window
+}
+
+// requestEmailChange sends address a code that moves the user's account to
+// it when replied from there. A new request replaces a pending one.
+func (s *Service) requestEmailChange(ctx context.Context, user *models.User, address string) error {
+	if strings.EqualFold(address, user.Email) {
+		return apperrors.New(apperrors.CodeInvalidInput, "%s is already your address", address)
+	}
+
+	existing, err := s.emailService.GetUserByEmail(ctx, address)
+	if err != nil {
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+	if existing != nil && existing.ID != user.ID {
+		return apperrors.New(apperrors.CodeConflict, "%s belongs to another account", address)
+	}
+
+	code := email.GenerateVerificationCode()
+	query := `
+		INSERT INTO email_changes (user_id, old_email, new_email, confirmation_code)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) WHERE confirmed_at IS NULL DO UPDATE
+		SET old_email = $2, new_email = $3, confirmation_code = $4, requested_at = NOW()`
+
+	if _, err := s.db.ExecContext(ctx, query, user.ID, user.Email, address, code); err != nil {
+		return fmt.Errorf("failed to save email change: %w", err)
+	}
+
+	if err := s.emailService.SendEmailChangeRequest(ctx, user.ID, user.Email, address, code); err != nil {
+		return err
+	}
+
+	logrus.WithField("user_id", user.ID).Info("Email change requested")
+	return nil
+}
+
+// confirmEmailChange completes a pending change to sender whose code appears
+// in body. The user's address, the change and its audit record are written
+// together, and the old address is told about the change. It reports
+// whether the reply confirmed a change.
+func (s *Service) confirmEmailChange(ctx context.Context, sender, body string) (bool, error) {
+	query := `
+		SELECT id, user_id, old_email, new_email, confirmation_code FROM email_changes
+		WHERE LOWER(new_email) = LOWER($1) AND confirmed_at IS NULL AND requested_at > $2`
+
+	rows, err := s.db.QueryContext(ctx, query, sender, time.Now().Add(-emailChangeCodeTTL))
+	if err != nil {
+		return false, fmt.Errorf("failed to query pending email changes: %w", err)
+	}
+
+	var changeID, userID int
+	var oldEmail, newEmail string
+	for rows.Next() {
+		var id, uid int
+		var from, to, code string
+		if err := rows.Scan(&id, &uid, &from, &to, &code); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan email change: %w", err)
+		}
+		if contains(body, code) {
+			changeID, userID, oldEmail, newEmail = id, uid, from, to
+			break
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to read email changes: %w", err)
+	}
+
+	if changeID == 0 {
+		return false, nil
+	}
+
+	aliasUntil := time.Now().Add(s.emailAliasWindow)
+	if err := s.changeEmail(ctx, changeID, userID, oldEmail, newEmail, aliasUntil); err != nil {
+		return true, err
+	}
+
+	logrus.WithField("user_id", userID).Info("Email address changed")
+
+	if err := s.emailService.SendEmailChanged(ctx, userID, oldEmail, newEmail, aliasUntil); err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Error("Failed to notify previous address of email change")
+	}
+	return true, nil
+}
+
+// changeEmail moves userID from oldEmail to newEmail in one transaction. It
+// fails with CodeConflict if the user's address changed since the request
+// or another account took newEmail meanwhile.
+func (s *Service) changeEmail(ctx context.Context, changeID, userID int, oldEmail, newEmail string, aliasUntil time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE users SET email = $3, updated_at = NOW()
+		WHERE id = $1 AND email = $2
+		  AND NOT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($3) AND id <> $1)
+		RETURNING id`
+
+	var id int
+	err = tx.QueryRowContext(ctx, query, userID, oldEmail, newEmail).Scan(&id)
+	if err == sql.ErrNoRows {
+		return apperrors.New(apperrors.CodeConflict, "email change for user %d is out of date", userID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+
+	// The new address no longer needs to be an alias
+	query = `DELETE FROM user_emails WHERE user_id = $1 AND LOWER(email) = LOWER($2)`
+	if _, err := tx.ExecContext(ctx, query, userID, newEmail); err != nil {
+		return fmt.Errorf("failed to remove email alias: %w", err)
+	}
+
+	query = `UPDATE email_changes SET confirmed_at = NOW(), alias_until = $2 WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, changeID, aliasUntil); err != nil {
+		return fmt.Errorf("failed to confirm email change: %w", err)
+	}
+
+	audit := &AdminAction{
+		Action: AdminActionChangeEmail,
+		Actor:  oldEmail,
+		UserID: userID,
+		Details: map[string]interface{}{
+			"old_email":   oldEmail,
+			"new_email":   newEmail,
+			"alias_until": aliasUntil.UTC().Format(time.RFC3339),
+		},
+	}
+	if err := recordAdminAction(ctx, tx, audit); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit email change: %w", err)
+	}
+	s.emailService.Users().Invalidate(userID)
+	return nil
+}
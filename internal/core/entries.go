@@ -0,0 +1,151 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/entryformat"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// EntryRestoreWindow is how long a deleted entry can be restored before the
+// scheduler purges it
+const EntryRestoreWindow = 30 * 24 * time.Hour
+
+// DeleteEntry soft-deletes the user's entry for date, hiding it from
+// summaries, the API and data reports
+func (s *Service) DeleteEntry(ctx context.Context, userID int, date time.Time) error {
+	query := `
+		UPDATE entries SET deleted_at = NOW(), version = version + 1
+		WHERE user_id = $1 AND entry_date = $2 AND deleted_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, userID, date.Format("2006-01-02"))
+	if err != nil {
+		return fmt.Errorf("failed to delete entry: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.New(apperrors.CodeNotFound, "no entry found for %s", date.Format("2006-01-02"))
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"entry_date": date.Format("2006-01-02"),
+	}).Info("Entry deleted")
+	return nil
+}
+
+// RestoreEntry undoes DeleteEntry for an entry deleted within EntryRestoreWindow
+func (s *Service) RestoreEntry(ctx context.Context, userID int, date time.Time) error {
+	query := `
+		UPDATE entries SET deleted_at = NULL, version = version + 1
+		WHERE user_id = $1 AND entry_date = $2 AND deleted_at > $3`
+
+	cutoff := time.Now().UTC().Add(-EntryRestoreWindow)
+	result, err := s.db.ExecContext(ctx, query, userID, date.Format("2006-01-02"), cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to restore entry: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.New(apperrors.CodeNotFound, "no entry deleted in the last 30 days for %s", date.Format("2006-01-02"))
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"entry_date": date.Format("2006-01-02"),
+	}).Info("Entry restored")
+	return nil
+}
+
+// GetEntry returns the user's entry for date, excluding deleted entries
+func (s *Service) GetEntry(ctx context.Context, userID int, date time.Time) (*models.Entry, error) {
+	query := `
+		SELECT id, user_id, entry_date, raw_content, parsed_content, project_tag, created_at, updated_at
+		FROM entries
+		WHERE user_id = $1 AND entry_date = $2 AND deleted_at IS NULL`
+
+	var entry models.Entry
+	var parsedContent, projectTag sql.NullString
+	err := s.db.QueryRowContext(ctx, query, userID, date.Format("2006-01-02")).Scan(&entry.ID, &entry.UserID,
+		&entry.EntryDate, &entry.RawContent, &parsedContent, &projectTag, &entry.CreatedAt, &entry.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeNotFound, "no entry found for %s", date.Format("2006-01-02"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry: %w", err)
+	}
+
+	if parsedContent.Valid {
+		entry.ParsedContent = &parsedContent.String
+	}
+	if projectTag.Valid {
+		entry.ProjectTag = &projectTag.String
+	}
+	return &entry, nil
+}
+
+// SetEntryContent replaces the content of the user's entry for date, creating
+// it (or bringing back a deleted one) if needed. It is for operator
+// corrections: the project tag is kept, or set to the project the user was
+// on that day, and no entry event is published.
+func (s *Service) SetEntryContent(ctx context.Context, user *models.User, date time.Time, content string) error {
+	if content == "" {
+		return apperrors.New(apperrors.CodeInvalidInput, "entry content is empty")
+	}
+
+	sealTo, err := custody.SealingKey(ctx, s.db, user.ID)
+	if err != nil {
+		return err
+	}
+	rawContent, parsedContent, err := storedContent(sealTo, user.EntryFormat, content)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO entries (user_id, entry_date, raw_content, parsed_content, project_tag)
+		VALUES ($1, $2, $3, $4, ` + projectOnSQL + `)
+		ON CONFLICT (user_id, entry_date)
+		DO UPDATE SET raw_content = $3, parsed_content = $4, project_tag = COALESCE(entries.project_tag, EXCLUDED.project_tag),
+		    deleted_at = NULL, version = entries.version + 1, updated_at = NOW()`
+
+	if _, err := s.db.ExecContext(ctx, query, user.ID, date.Format("2006-01-02"), rawContent, parsedContent); err != nil {
+		return fmt.Errorf("failed to set entry content: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":    user.ID,
+		"entry_date": date.Format("2006-01-02"),
+	}).Info("Entry content set")
+	return nil
+}
+
+// structuredContent is what entries.parsed_content stores for content: the
+// sections of the user's entry format as JSON, or the text itself
+func structuredContent(entryFormat, content string) (string, error) {
+	format, ok := entryformat.Lookup(entryFormat)
+	if !ok {
+		return content, nil
+	}
+	structured, ok := format.Split(content)
+	if !ok {
+		return content, nil
+	}
+	return structured.Encode()
+}
+
+// PurgeDeletedEntries permanently removes entries deleted longer ago than
+// EntryRestoreWindow and returns how many were removed
+func (s *Service) PurgeDeletedEntries(ctx context.Context) (int64, error) {
+	cutoff := time.Now().UTC().Add(-EntryRestoreWindow)
+	result, err := s.db.ExecContext(ctx, `DELETE FROM entries WHERE deleted_at <= $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted entries: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// feedTokenPrefix tells feed tokens apart from API tokens, which don't work
+// in feed URLs and vice versa
+const feedTokenPrefix = "wdygf_"
+
+// maxFeedSummaries is how many of the newest summaries a feed carries
+const maxFeedSummaries = 20
+
+// SummaryFeed is a user's opted-in summary feed
+type SummaryFeed struct {
+	CreatedAt     time.Time  `json:"created_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+}
+
+// EnableSummaryFeed opts user into a private feed of their weekly summaries
+// and returns its token. Calling it again rotates the token, so the old feed
+// URL stops working. Only its hash is stored, so the token can't be shown
+// again.
+func (s *Service) EnableSummaryFeed(ctx context.Context, user *models.User) (string, error) {
+	if !user.IsVerified {
+		return "", apperrors.New(apperrors.CodeNotVerified, "user %s is not verified", user.Email)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate feed token: %w", err)
+	}
+	token := feedTokenPrefix + hex.EncodeToString(b)
+
+	query := `
+		INSERT INTO summary_feeds (user_id, token_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET token_hash = EXCLUDED.token_hash, created_at = NOW(), last_fetched_at = NULL`
+
+	if _, err := s.db.ExecContext(ctx, query, user.ID, hashAPIToken(token)); err != nil {
+		return "", fmt.Errorf("failed to store feed token: %w", err)
+	}
+
+	return token, nil
+}
+
+// DisableSummaryFeed turns the user's feed off and reports whether it was on
+func (s *Service) DisableSummaryFeed(ctx context.Context, userID int) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM summary_feeds WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to disable summary feed: %w", err)
+	}
+
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// GetSummaryFeed returns the user's feed, or nil if they haven't opted in
+func (s *Service) GetSummaryFeed(ctx context.Context, userID int) (*SummaryFeed, error) {
+	feed := &SummaryFeed{}
+	query := `SELECT created_at, last_fetched_at FROM summary_feeds WHERE user_id = $1`
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&feed.CreatedAt, &feed.LastFetchedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get summary feed: %w", err)
+	}
+	return feed, nil
+}
+
+// SummaryFeedFor returns the verified user a feed token belongs to and their
+// newest summaries, or a CodeNotFound error for unknown or rotated tokens
+func (s *Service) SummaryFeedFor(ctx context.Context, token string) (*models.User, []*models.WeeklySummary, error) {
+	if !strings.HasPrefix(token, feedTokenPrefix) {
+		return nil, nil, apperrors.New(apperrors.CodeNotFound, "feed not found")
+	}
+
+	var userEmail string
+	query := `
+		UPDATE summary_feeds f
+		SET last_fetched_at = NOW()
+		FROM users u
+		WHERE f.user_id = u.id AND f.token_hash = $1
+		RETURNING u.email`
+
+	err := s.db.QueryRowContext(ctx, query, hashAPIToken(token)).Scan(&userEmail)
+	if err == sql.ErrNoRows {
+		return nil, nil, apperrors.New(apperrors.CodeNotFound, "feed not found")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up summary feed: %w", err)
+	}
+
+	user, err := s.emailService.GetUserByEmail(ctx, userEmail)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil || !user.IsVerified {
+		return nil, nil, apperrors.New(apperrors.CodeNotFound, "feed not found")
+	}
+
+	summaries, err := s.ListWeeklySummaries(ctx, user.ID, maxFeedSummaries)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, summaries, nil
+}
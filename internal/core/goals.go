@@ -0,0 +1,158 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// WeeklyGoalsHour is the local hour on Monday the goals prompt is sent
+const WeeklyGoalsHour = 9
+
+// maxWeeklyGoals caps how many lines of a reply are kept as goals
+const maxWeeklyGoals = 10
+
+// goalBullet matches list markers such as "-", "*", "•", "1." and "2)"
+var goalBullet = regexp.MustCompile(`^\s*(?:[-*•◦]|\d+[.)])\s*`)
+
+// goalsWeek is the week, as its first day, that at falls in on the user's
+// local calendar
+func goalsWeek(user *models.User, at time.Time) time.Time {
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := at.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	return period.StartOfWeek(day, period.FirstWeekday(user.WeekStart))
+}
+
+// GetUsersForWeeklyGoals returns the verified, unpaused users who opted in
+// to the Monday goals prompt
+func (s *Service) GetUsersForWeeklyGoals(ctx context.Context, at time.Time) ([]*models.User, error) {
+	query := `
+		SELECT id, email, timezone, project_focus, week_start, reply_token
+		FROM users
+		WHERE is_verified = TRUE
+		  AND weekly_goals = TRUE
+		  AND (is_paused = FALSE OR pause_until < $1)`
+
+	rows, err := s.db.QueryContext(ctx, query, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users for weekly goals: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		var user models.User
+		var projectFocus sql.NullString
+		if err := rows.Scan(&user.ID, &user.Email, &user.Timezone, &projectFocus, &user.WeekStart, &user.ReplyToken); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		if projectFocus.Valid {
+			user.ProjectFocus = &projectFocus.String
+		}
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
+
+// SendWeeklyGoalsPrompt asks the user for this week's goals. At most one
+// prompt is sent per user per week; a second attempt returns a CodeConflict
+// error.
+func (s *Service) SendWeeklyGoalsPrompt(ctx context.Context, user *models.User, at time.Time) error {
+	weekStart := goalsWeek(user, at)
+
+	query := `
+		INSERT INTO weekly_goals (user_id, week_start_date, prompted_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id, week_start_date) DO NOTHING`
+
+	result, err := s.db.ExecContext(ctx, query, user.ID, weekStart)
+	if err != nil {
+		return fmt.Errorf("failed to record weekly goals prompt: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.New(apperrors.CodeConflict, "weekly goals already requested from user %d for %s", user.ID, weekStart.Format("2006-01-02"))
+	}
+
+	if err := s.emailService.SendWeeklyGoalsPrompt(ctx, user.ID, user.Email, user.ReplyToken, user.ProjectFocus, weekStart); err != nil {
+		// Release the claim so the next run retries
+		query := `DELETE FROM weekly_goals WHERE user_id = $1 AND week_start_date = $2 AND replied_at IS NULL`
+		if _, releaseErr := s.db.ExecContext(ctx, query, user.ID, weekStart); releaseErr != nil {
+			logrus.WithError(releaseErr).WithField("user_id", user.ID).Error("Failed to release weekly goals prompt after delivery failure")
+		}
+		return err
+	}
+	return nil
+}
+
+// saveWeeklyGoals stores a reply to the goals prompt as this week's goals,
+// replacing any sent earlier in the week
+func (s *Service) saveWeeklyGoals(ctx context.Context, user *models.User, thread, body string) error {
+	goals := parseGoals(cleanEmailContent(body))
+	if len(goals) == 0 {
+		return s.requestClarification(ctx, user, thread, body)
+	}
+
+	weekStart := goalsWeek(user, time.Now())
+	query := `
+		INSERT INTO weekly_goals (user_id, week_start_date, goals, replied_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id, week_start_date) DO UPDATE
+		SET goals = EXCLUDED.goals, replied_at = NOW(), updated_at = NOW()`
+
+	if _, err := s.db.ExecContext(ctx, query, user.ID, weekStart, pq.Array(goals)); err != nil {
+		return fmt.Errorf("failed to save weekly goals: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":    user.ID,
+		"week_start": weekStart.Format("2006-01-02"),
+		"goals":      len(goals),
+	}).Info("Saved weekly goals")
+	return nil
+}
+
+// GetWeeklyGoals returns the goals the user set for the week beginning at
+// weekStart, or nil if they set none
+func (s *Service) GetWeeklyGoals(ctx context.Context, userID int, weekStart time.Time) ([]string, error) {
+	query := `SELECT goals FROM weekly_goals WHERE user_id = $1 AND week_start_date = $2`
+
+	var goals []string
+	err := s.db.QueryRowContext(ctx, query, userID, weekStart).Scan(pq.Array(&goals))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weekly goals: %w", err)
+	}
+	return goals, nil
+}
+
+// parseGoals takes one goal per non-empty line, without list markers
+func parseGoals(content string) []string {
+	var goals []string
+	for _, line := range strings.Split(content, "\n") {
+		goal := strings.TrimSpace(goalBullet.ReplaceAllString(line, ""))
+		if goal == "" {
+			continue
+		}
+		goals = append(goals, goal)
+		if len(goals) == maxWeeklyGoals {
+			break
+		}
+	}
+	return goals
+}
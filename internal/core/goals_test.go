@@ -0,0 +1,56 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+func TestParseGoals(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"plain lines", "Finish billing\nWrite the launch post", []string{"Finish billing", "Write the launch post"}},
+		{"bullets and numbers", "- Finish billing\n* Fix login\n• Hire\n1. Plan Q3\n2) Demo", []string{"Finish billing", "Fix login", "Hire", "Plan Q3", "Demo"}},
+		{"blank lines", "\n  Finish billing  \n\n-\n", []string{"Finish billing"}},
+		{"number in goal", "Cut latency to 200ms", []string{"Cut latency to 200ms"}},
+		{"empty", "   \n", nil},
+		{"capped", strings.Repeat("goal\n", 15), strings.Split(strings.TrimSuffix(strings.Repeat("goal\n", maxWeeklyGoals), "\n"), "\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseGoals(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseGoals() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoalsWeek(t *testing.T) {
+	// 9:00 Monday in Auckland is still Sunday in UTC
+	at := time.Date(2024, 5, 5, 21, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		timezone  string
+		weekStart string
+		want      string
+	}{
+		{"Pacific/Auckland", "monday", "2024-05-06"},
+		{"UTC", "monday", "2024-04-29"},
+		{"UTC", "sunday", "2024-05-05"},
+		{"Not/AZone", "monday", "2024-04-29"},
+	}
+
+	for _, tt := range tests {
+		user := &models.User{Timezone: tt.timezone, WeekStart: tt.weekStart}
+		if got := goalsWeek(user, at).Format("2006-01-02"); got != tt.want {
+			t.Errorf("goalsWeek(%s, %s) = %s, want %s", tt.timezone, tt.weekStart, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/importers"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// ImportResult counts what ImportEntries did, or with a dry run would do
+type ImportResult struct {
+	Created  int
+	Replaced int
+	Skipped  int // days that already had an entry
+}
+
+// ImportEntries saves entries read by an importer as the user's journal, in
+// one transaction. A day that already has an entry, including a deleted one
+// not yet purged, is skipped unless replace is set. Entries are tagged with
+// the project the user was on that day. Like entries set from the CLI,
+// imports publish no entry events.
+func (s *Service) ImportEntries(ctx context.Context, user *models.User, entries []importers.Entry, replace, dryRun bool) (*ImportResult, error) {
+	result := &ImportResult{}
+	if len(entries) == 0 {
+		return result, nil
+	}
+
+	existing, err := s.entryDates(ctx, user.ID, entries[0].Date, entries[len(entries)-1].Date)
+	if err != nil {
+		return nil, err
+	}
+
+	sealTo, err := custody.SealingKey(ctx, s.db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin import transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO entries (user_id, entry_date, raw_content, parsed_content, project_tag)
+		VALUES ($1, $2, $3, $4, ` + projectOnSQL + `)
+		ON CONFLICT (user_id, entry_date)
+		DO UPDATE SET raw_content = $3, parsed_content = $4, project_tag = COALESCE(entries.project_tag, EXCLUDED.project_tag),
+		    deleted_at = NULL, version = entries.version + 1, updated_at = NOW()`
+
+	for _, entry := range entries {
+		date := entry.Date.Format("2006-01-02")
+		if existing[date] {
+			if !replace {
+				result.Skipped++
+				continue
+			}
+			result.Replaced++
+		} else {
+			result.Created++
+		}
+
+		if dryRun {
+			continue
+		}
+
+		rawContent, parsedContent, err := storedContent(sealTo, user.EntryFormat, entry.Content)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, query, user.ID, date, rawContent, parsedContent); err != nil {
+			return nil, fmt.Errorf("failed to import entry for %s from %s: %w", date, entry.Source, err)
+		}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":  user.ID,
+		"created":  result.Created,
+		"replaced": result.Replaced,
+		"skipped":  result.Skipped,
+	}).Info("Entries imported")
+	return result, nil
+}
+
+// entryDates returns the dates from through to (inclusive) on which the user
+// has an entry row, deleted or not
+func (s *Service) entryDates(ctx context.Context, userID int, from, to time.Time) (map[string]bool, error) {
+	query := `SELECT entry_date FROM entries WHERE user_id = $1 AND entry_date >= $2 AND entry_date <= $3`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entry dates: %w", err)
+	}
+	defer rows.Close()
+
+	dates := make(map[string]bool)
+	for rows.Next() {
+		var date time.Time
+		if err := rows.Scan(&date); err != nil {
+			return nil, fmt.Errorf("failed to scan entry date: %w", err)
+		}
+		dates[date.Format("2006-01-02")] = true
+	}
+	return dates, rows.Err()
+}
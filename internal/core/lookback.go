@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/embeddings"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// lookbackDays is how far back "this time last quarter" looks
+const lookbackDays = 91
+
+// maxLookbackExcerpt bounds the quoted entry in the weekly summary
+const maxLookbackExcerpt = 140
+
+// LastQuarterLookback returns the entry from the week a quarter before
+// weekStart that is closest in meaning to this week's entries, for the
+// weekly summary's "this time last quarter" line. It returns nil without
+// embeddings or when that week has no embedded entries.
+func (s *Service) LastQuarterLookback(ctx context.Context, userID int, weekStart time.Time, entries []*models.Entry) (*email.Lookback, error) {
+	if s.embeddings == nil || len(entries) == 0 {
+		return nil, nil
+	}
+
+	texts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		texts = append(texts, entry.RawContent)
+	}
+
+	from := weekStart.AddDate(0, 0, -lookbackDays)
+	to := from.AddDate(0, 0, 7)
+	matches, err := s.embeddings.SearchSimilarEntries(ctx, userID, strings.Join(texts, "\n"), embeddings.SearchOptions{
+		From:  &from,
+		To:    &to,
+		Limit: 1,
+	})
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+
+	entry := matches[0].Entry
+	return &email.Lookback{Date: entry.EntryDate, Excerpt: lookbackExcerpt(entry.RawContent)}, nil
+}
+
+// lookbackExcerpt is the entry's first line, shortened to fit one line of
+// the summary
+func lookbackExcerpt(content string) string {
+	line := strings.TrimSpace(content)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	if len(line) > maxLookbackExcerpt {
+		line = strings.TrimSpace(line[:maxLookbackExcerpt-3]) + "..."
+	}
+	return line
+}
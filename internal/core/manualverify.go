@@ -0,0 +1,104 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/events"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// ManualVerification is what support enters for a user who can't complete
+// the signup emails. Values are read like the welcome form's answers.
+type ManualVerification struct {
+	Name       string
+	Timezone   string
+	PromptTime string
+	Project    string
+	Actor      string
+}
+
+// VerifyUserManually verifies emailAddr with the given preferences, creating
+// the user if they never got as far as signing up. The preferences are
+// validated as a signup reply is, the user is activated through the same
+// path a confirmation reply takes, and the override is recorded in the
+// audit log. A user who is already verified is left alone.
+func (s *Service) VerifyUserManually(ctx context.Context, emailAddr string, mv ManualVerification) (*models.User, error) {
+	if strings.TrimSpace(mv.Actor) == "" {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "an actor is required for the audit log")
+	}
+
+	values := map[string]string{
+		"name":     strings.TrimSpace(mv.Name),
+		"timezone": strings.TrimSpace(mv.Timezone),
+	}
+	if mv.PromptTime != "" {
+		values["time"] = mv.PromptTime
+	}
+	if mv.Project != "" {
+		values["project"] = mv.Project
+	}
+	// An ambiguous timezone such as CST fails here too, naming the candidates
+	prefs, err := preferencesFromValues(values)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid preferences")
+	}
+	if len(prefs.Name) > maxNameLength {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "name is longer than %d characters", maxNameLength)
+	}
+
+	user, err := s.emailService.GetUserByEmail(ctx, emailAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if user != nil && user.IsVerified {
+		return nil, apperrors.New(apperrors.CodeConflict, "user %s is already verified", user.Email)
+	}
+	if user == nil {
+		if _, err := mail.ParseAddress(emailAddr); err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid email address")
+		}
+		if err := s.createPendingUser(ctx, emailAddr, email.GenerateVerificationCode()); err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+		if user, err = s.emailService.GetUserByEmail(ctx, emailAddr); err != nil || user == nil {
+			return nil, fmt.Errorf("failed to read created user: %w", err)
+		}
+	}
+
+	audit := &AdminAction{
+		Action: AdminActionVerifyUser,
+		Actor:  mv.Actor,
+		UserID: user.ID,
+		Details: map[string]interface{}{
+			"previous_status": user.SignupStatus,
+			"name":            prefs.Name,
+			"timezone":        prefs.Timezone,
+			"prompt_time":     prefs.PromptTime.Format("15:04"),
+			"project_focus":   prefs.ProjectFocus,
+		},
+	}
+	if err := s.verifyUser(ctx, user.ID, prefs, audit); err != nil {
+		return nil, fmt.Errorf("failed to verify user: %w", err)
+	}
+
+	s.publishEvent(ctx, events.UserVerified, map[string]interface{}{
+		"user_id":  user.ID,
+		"email":    user.Email,
+		"name":     prefs.Name,
+		"timezone": prefs.Timezone,
+	})
+
+	logrus.WithFields(logrus.Fields{
+		"user_id": user.ID,
+		"actor":   mv.Actor,
+	}).Info("User verified manually")
+
+	return s.emailService.GetUserByID(ctx, user.ID)
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// mentorStopRegex matches a mentor's reply asking for no more digests
+var mentorStopRegex = regexp.MustCompile(`(?i)^\s*(stop|unsubscribe)\b`)
+
+// updateMentor sets the user's mentor, or removes it when address is "". A
+// new address is sent a confirmation request and only receives digests once
+// it replies with the code; setting the current mentor again changes nothing.
+func (s *Service) updateMentor(ctx context.Context, user *models.User, address string) error {
+	if address == "" {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM mentors WHERE user_id = $1`, user.ID); err != nil {
+			return fmt.Errorf("failed to remove mentor: %w", err)
+		}
+		logrus.WithField("user_id", user.ID).Info("Mentor removed")
+		return nil
+	}
+
+	if strings.EqualFold(address, user.Email) {
+		return apperrors.New(apperrors.CodeInvalidInput, "you can't be your own mentor")
+	}
+
+	code := email.GenerateVerificationCode()
+	query := `
+		INSERT INTO mentors (user_id, email, confirmation_code)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET email = $2, confirmation_code = $3, confirmed_at = NULL, last_digest_month = NULL, created_at = NOW()
+		WHERE mentors.email <> $2
+		RETURNING id`
+
+	var id int
+	err := s.db.QueryRowContext(ctx, query, user.ID, address, code).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save mentor: %w", err)
+	}
+
+	if err := s.emailService.SendMentorRequest(ctx, user.ID, address, user.Name, user.Email, code); err != nil {
+		return err
+	}
+
+	logrus.WithField("user_id", user.ID).Info("Mentor requested")
+	return nil
+}
+
+// handleMentorReply confirms pending mentor requests for sender whose code
+// appears in body, or, when a confirmed mentor replies "stop", ends their
+// digests. It reports whether the reply was meant for a mentor request.
+func (s *Service) handleMentorReply(ctx context.Context, sender, body string) (bool, error) {
+	query := `SELECT id, confirmation_code, confirmed_at IS NOT NULL FROM mentors WHERE email = LOWER($1)`
+
+	rows, err := s.db.QueryContext(ctx, query, sender)
+	if err != nil {
+		return false, fmt.Errorf("failed to query mentor requests: %w", err)
+	}
+
+	var confirmIDs, confirmedIDs []int
+	for rows.Next() {
+		var id int
+		var code string
+		var confirmed bool
+		if err := rows.Scan(&id, &code, &confirmed); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan mentor request: %w", err)
+		}
+		if confirmed {
+			confirmedIDs = append(confirmedIDs, id)
+		} else if contains(body, code) {
+			confirmIDs = append(confirmIDs, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to read mentor requests: %w", err)
+	}
+
+	if len(confirmIDs) > 0 {
+		query = `UPDATE mentors SET confirmed_at = NOW() WHERE id = ANY($1)`
+		if _, err := s.db.ExecContext(ctx, query, pq.Array(confirmIDs)); err != nil {
+			return true, fmt.Errorf("failed to confirm mentor: %w", err)
+		}
+		logrus.WithField("confirmed", len(confirmIDs)).Info("Mentor confirmed")
+		return true, nil
+	}
+
+	if len(confirmedIDs) > 0 && mentorStopRegex.MatchString(cleanEmailContent(body)) {
+		query = `DELETE FROM mentors WHERE id = ANY($1)`
+		if _, err := s.db.ExecContext(ctx, query, pq.Array(confirmedIDs)); err != nil {
+			return true, fmt.Errorf("failed to stop mentor digests: %w", err)
+		}
+		logrus.WithField("stopped", len(confirmedIDs)).Info("Mentor stopped digests")
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// MentorsDueDigest returns the confirmed mentors who haven't been sent the
+// digest for month (the first of the month) yet
+func (s *Service) MentorsDueDigest(ctx context.Context, month time.Time) ([]*models.Mentor, error) {
+	query := `
+		SELECT id, user_id, email, confirmed_at, last_digest_month, created_at
+		FROM mentors
+		WHERE confirmed_at IS NOT NULL AND (last_digest_month IS NULL OR last_digest_month < $1)
+		ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query mentors: %w", err)
+	}
+	defer rows.Close()
+
+	var mentors []*models.Mentor
+	for rows.Next() {
+		mentor := &models.Mentor{}
+		err := rows.Scan(&mentor.ID, &mentor.UserID, &mentor.Email, &mentor.ConfirmedAt,
+			&mentor.LastDigestMonth, &mentor.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan mentor: %w", err)
+		}
+		mentors = append(mentors, mentor)
+	}
+
+	return mentors, rows.Err()
+}
+
+// MarkMentorDigestSent records that the digest for month went to the mentor
+func (s *Service) MarkMentorDigestSent(ctx context.Context, mentorID int, month time.Time) error {
+	query := `UPDATE mentors SET last_digest_month = $2 WHERE id = $1`
+	if _, err := s.db.ExecContext(ctx, query, mentorID, month); err != nil {
+		return fmt.Errorf("failed to record mentor digest: %w", err)
+	}
+	return nil
+}
@@ -1,10 +1,19 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/core/commands"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/entryformat"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/language"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/timezones"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
 )
 
 type UserPreferences struct {
@@ -12,106 +21,291 @@ type UserPreferences struct {
 	Timezone     string
 	PromptTime   time.Time
 	ProjectFocus *string
+	WeekStart    string
+}
+
+// userPreferences returns user's current signup preferences
+func userPreferences(user *models.User) *UserPreferences {
+	return &UserPreferences{
+		Name:         user.Name,
+		Timezone:     user.Timezone,
+		PromptTime:   user.PromptTime,
+		ProjectFocus: user.ProjectFocus,
+		WeekStart:    user.WeekStart,
+	}
 }
 
+// preferenceLine matches a "Label: value" line of a preferences reply. The
+// welcome form's numbering and "(e.g., ...)" hints are skipped, so
+// "3. Preferred daily prompt time (e.g., 16:00): 9am" has label "Preferred
+// daily prompt time" and value "9am".
+var preferenceLine = regexp.MustCompile(`^\s*(?:\d+[.)]\s*)?([A-Za-z][A-Za-z ]*?)\s*(?:\([^)]*\))?\s*:\s*(.*)$`)
+
+// preferenceField maps a form label to the preference it sets, tolerating
+// misspellings such as "Timzone"
+func preferenceField(label string) string {
+	label = strings.ReplaceAll(strings.ToLower(label), " ", "")
+	switch {
+	case strings.Contains(label, "zone") || label == "tz":
+		return "timezone"
+	case strings.Contains(label, "week"):
+		return "week_start"
+	case strings.Contains(label, "name"):
+		return "name"
+	case strings.Contains(label, "time") || strings.Contains(label, "prompt"):
+		return "time"
+	case strings.Contains(label, "project") || strings.Contains(label, "focus"):
+		return "project"
+	}
+	return ""
+}
+
+// parseUserPreferences reads the welcome form's "Label: value" lines, with
+// or without the form's "|" box borders. The first line for each preference
+// wins, and blank "____" values are ignored.
 func parseUserPreferences(body string) (*UserPreferences, error) {
-	prefs := &UserPreferences{}
-	
-	// Extract name
-	nameRegex := regexp.MustCompile(`(?i)name:\s*([^\n\r]+)`)
-	if matches := nameRegex.FindStringSubmatch(body); len(matches) > 1 {
-		prefs.Name = strings.TrimSpace(matches[1])
-	}
-	
-	// Extract timezone
-	timezoneRegex := regexp.MustCompile(`(?i)timezone[^:]*:\s*([^\n\r]+)`)
-	if matches := timezoneRegex.FindStringSubmatch(body); len(matches) > 1 {
-		prefs.Timezone = strings.TrimSpace(matches[1])
-	}
-	
-	// Extract prompt time
-	timeRegex := regexp.MustCompile(`(?i)(?:time|prompt)[^:]*:\s*([^\n\r]+)`)
-	if matches := timeRegex.FindStringSubmatch(body); len(matches) > 1 {
-		timeStr := strings.TrimSpace(matches[1])
-		parsedTime, err := parseTimeString(timeStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid time format: %s", timeStr)
+	values := map[string]string{}
+	for _, line := range strings.Split(body, "\n") {
+		matches := preferenceLine.FindStringSubmatch(strings.Trim(strings.TrimSpace(line), "|"))
+		if matches == nil {
+			continue
 		}
-		prefs.PromptTime = parsedTime
-	}
-	
-	// Extract project focus (optional)
-	projectRegex := regexp.MustCompile(`(?i)(?:project|focus)[^:]*:\s*([^\n\r]+)`)
-	if matches := projectRegex.FindStringSubmatch(body); len(matches) > 1 {
-		projectName := strings.TrimSpace(matches[1])
-		if projectName != "" && projectName != "_" && projectName != "___________" {
-			prefs.ProjectFocus = &projectName
+		field := preferenceField(matches[1])
+		value := strings.TrimSpace(strings.Trim(strings.TrimSpace(matches[2]), "_"))
+		if field == "" || value == "" {
+			continue
+		}
+		if _, seen := values[field]; !seen {
+			values[field] = value
 		}
 	}
-	
-	// Validate required fields
-	if prefs.Name == "" || prefs.Name == "_" || prefs.Name == "___________" {
+	return preferencesFromValues(values)
+}
+
+// preferencesFromValues validates signup preferences keyed by field name
+// (name, timezone, time, project and week_start). Only name and timezone
+// are required.
+func preferencesFromValues(values map[string]string) (*UserPreferences, error) {
+	prefs := &UserPreferences{Name: values["name"]}
+	if prefs.Name == "" {
 		return nil, fmt.Errorf("name is required")
 	}
-	
-	if prefs.Timezone == "" || prefs.Timezone == "_" || prefs.Timezone == "___________" {
+
+	if values["timezone"] == "" {
 		return nil, fmt.Errorf("timezone is required")
 	}
-	
-	if prefs.PromptTime.IsZero() {
-		// Default to 4 PM if not specified
-		prefs.PromptTime = time.Date(0, 1, 1, 16, 0, 0, 0, time.UTC)
+	tz, err := timezones.Resolve(values["timezone"])
+	if err != nil {
+		return nil, err
+	}
+	prefs.Timezone = tz
+
+	// Default to 4 PM if not specified
+	prefs.PromptTime = time.Date(0, 1, 1, 16, 0, 0, 0, time.UTC)
+	if value, ok := values["time"]; ok {
+		if prefs.PromptTime, err = parsePromptTime(value); err != nil {
+			return nil, fmt.Errorf("invalid time format: %s", value)
+		}
+	}
+
+	if project, ok := values["project"]; ok && !isSkipAnswer(project) {
+		prefs.ProjectFocus = &project
 	}
-	
-	// Validate timezone
-	if !isValidTimezone(prefs.Timezone) {
-		return nil, fmt.Errorf("invalid timezone: %s", prefs.Timezone)
+
+	// Week start is optional and defaults to Monday
+	if prefs.WeekStart, err = period.ParseWeekStart(values["week_start"]); err != nil {
+		return nil, err
 	}
-	
+
 	return prefs, nil
 }
 
-func parseTimeString(timeStr string) (time.Time, error) {
-	// Common time formats
-	formats := []string{
-		"15:04",     // 16:00
-		"3:04 PM",   // 4:00 PM
-		"3:04PM",    // 4:00PM
-		"3 PM",      // 4 PM
-		"3PM",       // 4PM
-		"15",        // 16
-	}
-	
-	timeStr = strings.TrimSpace(timeStr)
-	
-	for _, format := range formats {
-		if t, err := time.Parse(format, timeStr); err == nil {
-			return time.Date(0, 1, 1, t.Hour(), t.Minute(), 0, 0, time.UTC), nil
+// preferenceForm writes prefs in the form parseUserPreferences reads
+func preferenceForm(prefs *UserPreferences) string {
+	lines := []string{
+		"Name: " + prefs.Name,
+		"Timezone: " + prefs.Timezone,
+		"Prompt time: " + prefs.PromptTime.Format("15:04"),
+		"Week starts on: " + prefs.WeekStart,
+	}
+	if prefs.ProjectFocus != nil {
+		lines = append(lines, "Project: "+*prefs.ProjectFocus)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// maxNameLength matches the users.name column
+const maxNameLength = 255
+
+// UpdatePreferences validates patch and applies its non-nil fields to a
+// verified user in a single UPDATE, returning the resulting preferences.
+// Nothing is changed unless every field is valid. Reply commands and the API
+// both go through here. A schedule confirmation is sent when the timezone or
+// prompt time changes, and a project change is recorded in the history.
+func (s *Service) UpdatePreferences(ctx context.Context, userID int, patch models.PreferencesUpdate) (*models.Preferences, error) {
+	user, err := s.emailService.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, apperrors.New(apperrors.CodeUserNotFound, "user not found")
+	}
+	if !user.IsVerified {
+		return nil, apperrors.New(apperrors.CodeNotVerified, "user %s is not verified", user.Email)
+	}
+
+	columns, err := preferenceColumns(patch)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return models.PreferencesFor(user), nil
+	}
+
+	assignments := make([]string, 0, len(columns)+1)
+	args := []interface{}{userID}
+	for _, c := range columns {
+		args = append(args, c.value)
+		assignments = append(assignments, fmt.Sprintf("%s = %s", c.name, strings.Replace(c.expr, "?", fmt.Sprintf("$%d", len(args)), 1)))
+	}
+	assignments = append(assignments, "updated_at = NOW()")
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET ` + strings.Join(assignments, ", ") + ` WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to update preferences: %w", err)
+	}
+	if patch.ProjectFocus != nil {
+		if err := recordProjectChange(ctx, tx, userID, strings.TrimSpace(*patch.ProjectFocus)); err != nil {
+			return nil, err
 		}
 	}
-	
-	return time.Time{}, fmt.Errorf("unable to parse time: %s", timeStr)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to update preferences: %w", err)
+	}
+	s.emailService.Users().Invalidate(userID)
+
+	updated, err := s.emailService.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Timezone != nil || patch.PromptTime != nil {
+		if err := s.emailService.SendScheduleUpdated(ctx, userID, updated.Email, updated.Timezone, updated.PromptTime); err != nil {
+			return nil, fmt.Errorf("failed to send schedule confirmation: %w", err)
+		}
+	}
+
+	return models.PreferencesFor(updated), nil
+}
+
+// preferenceColumn is one validated users column assignment. expr is the SQL
+// for the new value with ? standing in for the bound parameter.
+type preferenceColumn struct {
+	name  string
+	expr  string
+	value interface{}
 }
 
-func isValidTimezone(tz string) bool {
-	// Common timezone validation
-	validTimezones := []string{
-		"UTC", "GMT",
-		"America/New_York", "America/Chicago", "America/Denver", "America/Los_Angeles",
-		"America/Toronto", "America/Vancouver", "America/Montreal",
-		"Europe/London", "Europe/Paris", "Europe/Berlin", "Europe/Rome", "Europe/Madrid",
-		"Asia/Tokyo", "Asia/Shanghai", "Asia/Kolkata", "Asia/Dubai",
-		"Australia/Sydney", "Australia/Melbourne",
-		"Pacific/Auckland",
-	}
-	
-	for _, valid := range validTimezones {
-		if strings.EqualFold(tz, valid) {
-			return true
+// preferenceColumns validates patch, returning the columns to set
+func preferenceColumns(patch models.PreferencesUpdate) ([]preferenceColumn, error) {
+	var columns []preferenceColumn
+	set := func(name string, value interface{}) {
+		columns = append(columns, preferenceColumn{name: name, expr: "?", value: value})
+	}
+
+	if patch.Name != nil {
+		name := strings.TrimSpace(*patch.Name)
+		if name == "" || len(name) > maxNameLength {
+			return nil, apperrors.New(apperrors.CodeInvalidInput, "name must be 1-%d characters", maxNameLength)
 		}
+		set("name", name)
 	}
-	
-	// Try to load the timezone to validate it
-	_, err := time.LoadLocation(tz)
-	return err == nil
-}
\ No newline at end of file
+	if patch.Timezone != nil {
+		tz, err := timezones.Resolve(*patch.Timezone)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid timezone")
+		}
+		set("timezone", tz)
+	}
+	if patch.PromptTime != nil {
+		t, err := commands.ParseTime(*patch.PromptTime)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid prompt time")
+		}
+		set("prompt_time", t)
+	}
+	if patch.ProjectFocus != nil {
+		// An empty project clears it
+		columns = append(columns, preferenceColumn{name: "project_focus", expr: "NULLIF(?, '')", value: strings.TrimSpace(*patch.ProjectFocus)})
+	}
+	if patch.WeekStart != nil {
+		weekStart, err := period.ParseWeekStart(*patch.WeekStart)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid week start")
+		}
+		set("week_start", weekStart)
+	}
+	if patch.EntryFormat != nil {
+		format, err := entryformat.Parse(*patch.EntryFormat)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid entry format")
+		}
+		set("entry_format", format)
+	}
+	if patch.SummaryVoice != nil {
+		voice, err := commands.ParseSummaryVoice(*patch.SummaryVoice)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid summary voice")
+		}
+		set("summary_voice", voice)
+	}
+	if patch.QuotesEnabled != nil {
+		set("quotes_enabled", *patch.QuotesEnabled)
+	}
+	if patch.CompareWeeks != nil {
+		set("compare_weeks", *patch.CompareWeeks)
+	}
+	if patch.WeeklyGoals != nil {
+		set("weekly_goals", *patch.WeeklyGoals)
+	}
+	if patch.EmailTracking != nil {
+		set("email_tracking", *patch.EmailTracking)
+	}
+	if patch.PromptThreading != nil {
+		threading, err := commands.ParsePromptThreading(*patch.PromptThreading)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid prompt threading")
+		}
+		set("prompt_threading", threading)
+	}
+	if patch.Language != nil {
+		code, err := language.Parse(*patch.Language)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid language")
+		}
+		set("language", code)
+	}
+	if patch.SummaryLanguage != nil {
+		code, err := commands.ParseSummaryLanguage(*patch.SummaryLanguage)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid summary language")
+		}
+		set("summary_language", code)
+	}
+
+	return columns, nil
+}
+
+var confirmationRegex = regexp.MustCompile(`(?i)^\s*(confirm|confirmed|yes)\b`)
+
+// isConfirmationReply reports whether a cleaned reply confirms the pending preferences
+func isConfirmationReply(content string) bool {
+	return confirmationRegex.MatchString(content)
+}
+
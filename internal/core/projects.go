@@ -0,0 +1,220 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// minRollupEntries is how many entries a project needs in a quarter to get
+// a rollup; a project mentioned once or twice has nothing to roll up
+const minRollupEntries = 3
+
+// projectOnSQL selects the project user $1 was on at date $2, to tag entries
+// that don't name a project
+const projectOnSQL = `(SELECT name FROM user_projects
+		WHERE user_id = $1 AND started_on <= $2::date AND (ended_on IS NULL OR ended_on > $2::date))`
+
+// recordProjectChange updates the user's project history after project_focus
+// is set to project, which is empty when it was cleared. The current project
+// ends today and the new one starts today; a project started and left on the
+// same day leaves no history, and switching back to a project left today
+// resumes it.
+func recordProjectChange(ctx context.Context, tx *sql.Tx, userID int, project string) error {
+	today := time.Now().UTC().Format("2006-01-02")
+
+	queries := []string{
+		// End the current project unless it is the one being set
+		`DELETE FROM user_projects
+		 WHERE user_id = $1 AND ended_on IS NULL AND name <> $2 AND started_on >= $3::date`,
+		`UPDATE user_projects SET ended_on = $3::date
+		 WHERE user_id = $1 AND ended_on IS NULL AND name <> $2`,
+		// Resume a project left earlier today
+		`UPDATE user_projects SET ended_on = NULL
+		 WHERE user_id = $1 AND name = $2 AND $2 <> '' AND ended_on = $3::date
+		   AND NOT EXISTS (SELECT 1 FROM user_projects WHERE user_id = $1 AND ended_on IS NULL)`,
+		`INSERT INTO user_projects (user_id, name, started_on)
+		 SELECT $1, $2, $3::date
+		 WHERE $2 <> '' AND NOT EXISTS (SELECT 1 FROM user_projects WHERE user_id = $1 AND ended_on IS NULL)`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, userID, project, today); err != nil {
+			return fmt.Errorf("failed to record project change: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetProjectHistory returns the user's projects, most recent first
+func (s *Service) GetProjectHistory(ctx context.Context, userID int) ([]*models.UserProject, error) {
+	query := `
+		SELECT id, user_id, name, started_on, ended_on, created_at
+		FROM user_projects
+		WHERE user_id = $1
+		ORDER BY started_on DESC, id DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project history: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []*models.UserProject
+	for rows.Next() {
+		project := &models.UserProject{}
+		if err := rows.Scan(&project.ID, &project.UserID, &project.Name, &project.StartedOn, &project.EndedOn, &project.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+// ProjectRollupDue is a user's project that had enough entries in a quarter
+// for a rollup and hasn't had one yet
+type ProjectRollupDue struct {
+	UserID  int
+	Project string
+}
+
+// ProjectRollupsDue returns the verified users' projects due a rollup for the
+// quarter starting at quarterStart
+func (s *Service) ProjectRollupsDue(ctx context.Context, quarterStart time.Time) ([]ProjectRollupDue, error) {
+	query := `
+		SELECT e.user_id, e.project_tag
+		FROM entries e
+		JOIN users u ON u.id = e.user_id
+		WHERE u.is_verified = TRUE
+		  AND e.deleted_at IS NULL AND e.project_tag IS NOT NULL AND e.project_tag <> ''
+		  AND e.entry_date >= $1 AND e.entry_date < $2
+		  AND NOT EXISTS (
+		      SELECT 1 FROM project_rollups r
+		      WHERE r.user_id = e.user_id AND r.project = e.project_tag AND r.quarter_start = $1)
+		GROUP BY e.user_id, e.project_tag
+		HAVING COUNT(*) >= $3
+		ORDER BY e.user_id, e.project_tag`
+
+	rows, err := s.db.QueryContext(ctx, query, quarterStart, quarterStart.AddDate(0, 3, 0), minRollupEntries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query projects due a rollup: %w", err)
+	}
+	defer rows.Close()
+
+	var due []ProjectRollupDue
+	for rows.Next() {
+		var d ProjectRollupDue
+		if err := rows.Scan(&d.UserID, &d.Project); err != nil {
+			return nil, fmt.Errorf("failed to scan project rollup: %w", err)
+		}
+		due = append(due, d)
+	}
+
+	return due, rows.Err()
+}
+
+// QuarterProjects returns the projects the user tagged at least
+// minRollupEntries entries with in the quarter starting at quarterStart
+func (s *Service) QuarterProjects(ctx context.Context, userID int, quarterStart time.Time) ([]string, error) {
+	query := `
+		SELECT project_tag
+		FROM entries
+		WHERE user_id = $1 AND deleted_at IS NULL AND project_tag IS NOT NULL AND project_tag <> ''
+		  AND entry_date >= $2 AND entry_date < $3
+		GROUP BY project_tag
+		HAVING COUNT(*) >= $4
+		ORDER BY MIN(entry_date)`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, quarterStart, quarterStart.AddDate(0, 3, 0), minRollupEntries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quarter projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []string
+	for rows.Next() {
+		var project string
+		if err := rows.Scan(&project); err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+// GenerateProjectRollup summarizes the user's entries tagged with project in
+// the quarter starting at quarterStart. The rollup isn't saved; see
+// SaveProjectRollup.
+func (s *Service) GenerateProjectRollup(ctx context.Context, user *models.User, project string, quarterStart time.Time) (*models.ProjectRollup, error) {
+	if s.llm == nil {
+		return nil, fmt.Errorf("summary generation is not configured")
+	}
+
+	all, err := s.GetEntriesBetween(ctx, user.ID, quarterStart, quarterStart.AddDate(0, 3, 0))
+	if err != nil {
+		return nil, err
+	}
+	var entries []*models.Entry
+	for _, entry := range custody.Unsealed(all) {
+		if entry.ProjectTag != nil && *entry.ProjectTag == project {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, apperrors.New(apperrors.CodeNotFound, "no entries for %s in the quarter starting %s", project, quarterStart.Format("2006-01-02"))
+	}
+
+	from, to := entries[0].EntryDate, entries[len(entries)-1].EntryDate
+	summary, err := s.llm.GenerateProjectRollup(ctx, entries, user.SummaryVoice, user.SummaryLanguage, period.FirstWeekday(user.WeekStart), project, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ProjectRollup{
+		UserID:           user.ID,
+		Project:          project,
+		QuarterStart:     quarterStart,
+		FirstEntryDate:   from,
+		LastEntryDate:    to,
+		SummaryParagraph: summary.Paragraph,
+		BulletPoints:     summary.BulletPoints,
+		LLMModel:         summary.Model,
+	}, nil
+}
+
+// SaveProjectRollup archives a rollup, which also marks it sent so the
+// quarterly job doesn't repeat it
+func (s *Service) SaveProjectRollup(ctx context.Context, rollup *models.ProjectRollup) error {
+	query := `
+		INSERT INTO project_rollups (user_id, project, quarter_start, first_entry_date, last_entry_date,
+		                             summary_paragraph, bullet_points, llm_model)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, project, quarter_start) DO UPDATE
+		SET first_entry_date = EXCLUDED.first_entry_date,
+		    last_entry_date = EXCLUDED.last_entry_date,
+		    summary_paragraph = EXCLUDED.summary_paragraph,
+		    bullet_points = EXCLUDED.bullet_points,
+		    llm_model = EXCLUDED.llm_model`
+
+	_, err := s.db.ExecContext(ctx, query, rollup.UserID, rollup.Project, rollup.QuarterStart,
+		rollup.FirstEntryDate, rollup.LastEntryDate, rollup.SummaryParagraph, rollup.BulletPoints, rollup.LLMModel)
+	if err != nil {
+		return fmt.Errorf("failed to save project rollup: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id": rollup.UserID,
+		"project": rollup.Project,
+		"quarter": rollup.QuarterStart.Format("2006-01-02"),
+	}).Info("Project rollup saved")
+	return nil
+}
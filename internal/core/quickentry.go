@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/stats"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// maxQuickEntryLength bounds a dictated entry
+const maxQuickEntryLength = 10000
+
+// QuickEntryResult is what a quick entry returns for display on the phone
+type QuickEntryResult struct {
+	EntryDate string       `json:"entry_date"`
+	Streak    stats.Streak `json:"streak"`
+	Message   string       `json:"message"`
+}
+
+// QuickEntry saves text as today's entry for user, as a reply would, and
+// returns the updated streak. The text is stored as-is rather than parsed for
+// commands, so dictating "pause for a week" logs it instead of pausing.
+func (s *Service) QuickEntry(ctx context.Context, user *models.User, text string) (*QuickEntryResult, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "text is required")
+	}
+	if len(text) > maxQuickEntryLength {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "text must be at most %d characters", maxQuickEntryLength)
+	}
+
+	if err := s.saveEntry(ctx, user.ID, user.EntryFormat, text, nil, nil); err != nil {
+		return nil, err
+	}
+
+	streak, err := s.GetStreak(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QuickEntryResult{
+		EntryDate: time.Now().UTC().Format("2006-01-02"),
+		Streak:    *streak,
+		Message:   streakMessage(streak),
+	}, nil
+}
+
+// GetStreak returns the user's entry streak as of today (UTC, matching
+// entry dates). Holidays and time off don't break it.
+func (s *Service) GetStreak(ctx context.Context, userID int) (*stats.Streak, error) {
+	query := `SELECT entry_date FROM entries WHERE user_id = $1 AND deleted_at IS NULL`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entry dates: %w", err)
+	}
+	defer rows.Close()
+
+	var dates []time.Time
+	for rows.Next() {
+		var date time.Time
+		if err := rows.Scan(&date); err != nil {
+			return nil, fmt.Errorf("failed to scan entry date: %w", err)
+		}
+		dates = append(dates, date)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	daysOff, err := s.GetDaysOff(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	streak := stats.BuildStreak(dates, time.Now().UTC(), daysOff.Off)
+	return &streak, nil
+}
+
+// streakMessage is a one-line confirmation short enough for a notification
+func streakMessage(streak *stats.Streak) string {
+	switch {
+	case streak.Current <= 1:
+		return "Logged. Day 1 of a new streak."
+	case streak.Current >= streak.Longest:
+		return fmt.Sprintf("Logged. 🔥 %d-day streak, your longest yet.", streak.Current)
+	default:
+		return fmt.Sprintf("Logged. 🔥 %d-day streak (best: %d).", streak.Current, streak.Longest)
+	}
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/quotes"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Quotes exposes quote management for the admin API and CLI
+func (s *Service) Quotes() *quotes.Service {
+	return s.quotes
+}
+
+// pickQuote returns the quote for today's prompt, or "" when the user opted
+// out or no quote is available. Failures fall back to no quote rather than
+// blocking the prompt.
+func (s *Service) pickQuote(ctx context.Context, user *models.User) string {
+	if !user.QuotesEnabled {
+		return ""
+	}
+
+	quote, err := s.quotes.Pick(ctx, user.ID)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to pick quote")
+		return ""
+	}
+	if quote == nil {
+		return ""
+	}
+	return quote.String()
+}
+
+// submitQuote queues a user's quote for admin approval
+func (s *Service) submitQuote(ctx context.Context, userID int, submission string) error {
+	text, author, err := quotes.ParseSubmission(submission)
+	if err != nil {
+		return apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid quote")
+	}
+
+	quote, err := s.quotes.Add(ctx, text, author, &userID)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"quote_id": quote.ID,
+	}).Info("Quote submitted for approval")
+	return nil
+}
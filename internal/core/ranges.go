@@ -0,0 +1,52 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/llm"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+)
+
+// maxSummaryRangeDays bounds custom ranges so a single prompt stays a
+// reasonable size; a year covers any review window
+const maxSummaryRangeDays = 366
+
+// GenerateSummaryForRange summarizes the user's entries dated from through to,
+// inclusive, using the same model chain as weekly summaries. The result is
+// not archived, since it doesn't correspond to a week.
+func (s *Service) GenerateSummaryForRange(ctx context.Context, userID int, from, to time.Time) (*llm.WeeklySummary, error) {
+	if s.llm == nil {
+		return nil, fmt.Errorf("summary generation is not configured")
+	}
+	if to.Before(from) {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "range ends before it starts")
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > maxSummaryRangeDays {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "range is %d days; at most %d are allowed", days, maxSummaryRangeDays)
+	}
+
+	var voice, lang, weekStart string
+	err := s.db.QueryRowContext(ctx, `SELECT summary_voice, summary_language, week_start FROM users WHERE id = $1`, userID).Scan(&voice, &lang, &weekStart)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeUserNotFound, "user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load summary preferences: %w", err)
+	}
+
+	entries, err := s.GetEntriesBetween(ctx, userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	entries = custody.Unsealed(entries)
+	if len(entries) == 0 {
+		return nil, apperrors.New(apperrors.CodeNotFound, "no entries from %s to %s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+
+	return s.llm.GenerateRangeSummary(ctx, entries, voice, lang, period.FirstWeekday(weekStart), from, to)
+}
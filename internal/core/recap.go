@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// recapMaxAge is how many days back the daily prompt looks for an entry to
+// recall
+const recapMaxAge = 7
+
+// PromptRecap is how the daily prompt recalls the user's last entry, off by
+// default
+type PromptRecap struct {
+	Enabled bool
+	// LLM has the model turn the entry into a one-line question, at the cost
+	// of a call per prompt; without it, or if the call fails, the entry's
+	// first line is quoted
+	LLM bool
+}
+
+// SetPromptRecap enables recalling the last entry in the daily prompt
+func (s *Service) SetPromptRecap(recap PromptRecap) {
+	s.recap = recap
+}
+
+// promptRecap returns the line recalling the user's last entry from the
+// past recapMaxAge days, or "" when recaps are off or there is none
+func (s *Service) promptRecap(ctx context.Context, user *models.User) string {
+	if !s.recap.Enabled {
+		return ""
+	}
+
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	entry, err := s.lastEntryBefore(ctx, user.ID, today, today.AddDate(0, 0, -recapMaxAge))
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to load last entry for prompt recap")
+		return ""
+	}
+	if entry == nil || custody.IsSealed(entry.RawContent) {
+		return ""
+	}
+
+	day := recapDay(entry.EntryDate, today)
+	if s.recap.LLM && s.llm != nil {
+		line, err := s.llm.PromptRecap(ctx, day, entry)
+		if err == nil {
+			return line
+		}
+		logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to generate prompt recap, quoting the entry")
+	}
+	excerpt := strings.TrimRight(lookbackExcerpt(entry.RawContent), ".!")
+	return fmt.Sprintf("%s you wrote \"%s\". How did that go?", day, excerpt)
+}
+
+// lastEntryBefore returns the user's latest live entry dated on or after
+// from and before day, or nil
+func (s *Service) lastEntryBefore(ctx context.Context, userID int, day, from time.Time) (*models.Entry, error) {
+	query := `
+		SELECT id, user_id, entry_date, raw_content
+		FROM entries
+		WHERE user_id = $1 AND entry_date < $2 AND entry_date >= $3 AND deleted_at IS NULL
+		ORDER BY entry_date DESC
+		LIMIT 1`
+
+	var entry models.Entry
+	err := s.db.QueryRowContext(ctx, query, userID, day.Format("2006-01-02"), from.Format("2006-01-02")).
+		Scan(&entry.ID, &entry.UserID, &entry.EntryDate, &entry.RawContent)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// recapDay says when date was relative to today: "Yesterday", "On Friday"
+// within the week, or "A week ago"
+func recapDay(date, today time.Time) string {
+	switch days := int(today.Sub(date).Hours() / 24); {
+	case days <= 1:
+		return "Yesterday"
+	case days >= 7:
+		return "A week ago"
+	default:
+		return "On " + date.Weekday().String()
+	}
+}
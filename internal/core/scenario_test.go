@@ -0,0 +1,265 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/events"
+	pkgConfig "github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Scenario tests run an inbound reply through HandleEmailReply against a
+// real database and assert exactly which emails were queued and which
+// domain events were published. They need a disposable Postgres database,
+// whose users and email_logs are emptied before each scenario:
+//
+//	TEST_DATABASE_URL=postgres://localhost/wdygdtw_test?sslmode=disable go test ./internal/core -run TestReplyScenarios
+
+// queuedEmail is the part of an email_logs row a scenario checks
+type queuedEmail struct {
+	Type      string
+	Recipient string
+	Subject   string
+}
+
+type replyScenario struct {
+	name    string
+	seed    func(t *testing.T, db *database.DB)
+	sender  string
+	subject string
+	body    string
+	wantErr apperrors.Code
+
+	wantEmails []queuedEmail
+	wantEvents []string
+}
+
+func testDB(t *testing.T) *database.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	conn, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db := &database.DB{DB: conn}
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+	return db
+}
+
+func resetDB(t *testing.T, db *database.DB) {
+	t.Helper()
+	if _, err := db.Exec(`TRUNCATE users, email_logs RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("failed to reset test database: %v", err)
+	}
+}
+
+func seedUser(email string, verified bool, status string) func(t *testing.T, db *database.DB) {
+	return func(t *testing.T, db *database.DB) {
+		t.Helper()
+		_, err := db.Exec(`
+			INSERT INTO users (email, name, timezone, verification_code, is_verified, signup_status)
+			VALUES ($1, 'Alex', 'UTC', '123456', $2, $3)`, email, verified, status)
+		if err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+}
+
+// seedEmailChange seeds alex@example.com with a change to new@example.com,
+// pending or confirmed with its alias window still open
+func seedEmailChange(confirmed bool) func(t *testing.T, db *database.DB) {
+	return func(t *testing.T, db *database.DB) {
+		t.Helper()
+		current := "alex@example.com"
+		if confirmed {
+			current = "new@example.com"
+		}
+		seedUser(current, true, models.SignupStatusActive)(t, db)
+
+		_, err := db.Exec(`
+			INSERT INTO email_changes (user_id, old_email, new_email, confirmation_code, confirmed_at, alias_until)
+			SELECT id, 'alex@example.com', 'new@example.com', '654321',
+			       CASE WHEN $1 THEN NOW() END, CASE WHEN $1 THEN NOW() + INTERVAL '1 day' END
+			FROM users`, confirmed)
+		if err != nil {
+			t.Fatalf("failed to seed email change: %v", err)
+		}
+	}
+}
+
+// seedAlias seeds alex@example.com with work@example.com as an alias,
+// pending with code 654321 or verified
+func seedAlias(verified bool) func(t *testing.T, db *database.DB) {
+	return func(t *testing.T, db *database.DB) {
+		t.Helper()
+		seedUser("alex@example.com", true, models.SignupStatusActive)(t, db)
+
+		_, err := db.Exec(`
+			INSERT INTO user_emails (user_id, email, verification_code, verified_at)
+			SELECT id, 'work@example.com', '654321', CASE WHEN $1 THEN NOW() END FROM users`, verified)
+		if err != nil {
+			t.Fatalf("failed to seed alias: %v", err)
+		}
+	}
+}
+
+func queuedEmails(t *testing.T, db *database.DB) []queuedEmail {
+	t.Helper()
+	rows, err := db.Query(`SELECT email_type, recipient_email, subject FROM email_logs ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var queued []queuedEmail
+	for rows.Next() {
+		var q queuedEmail
+		if err := rows.Scan(&q.Type, &q.Recipient, &q.Subject); err != nil {
+			t.Fatal(err)
+		}
+		queued = append(queued, q)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return queued
+}
+
+func TestReplyScenarios(t *testing.T) {
+	db := testDB(t)
+
+	emailService, err := email.NewService(db, &pkgConfig.Config{AWSSESRegion: "us-east-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []replyScenario{
+		{
+			name:   "unknown sender signs up",
+			sender: "new@example.com",
+			body:   "Please sign up alex",
+			wantEmails: []queuedEmail{
+				{models.EmailTypeVerification, "new@example.com", "Welcome to What Did You Get Done This Week?"},
+			},
+		},
+		{
+			name:    "unknown sender without signup",
+			sender:  "stranger@example.com",
+			body:    "Shipped the importer",
+			wantErr: apperrors.CodeUserNotFound,
+		},
+		{
+			name:   "pending user confirms preferences",
+			seed:   seedUser("alex@example.com", false, models.SignupStatusPendingConfirmation),
+			sender: "alex@example.com",
+			body:   "confirm",
+
+			wantEvents: []string{events.UserVerified},
+		},
+		{
+			name:    "verified user journals",
+			seed:    seedUser("alex@example.com", true, models.SignupStatusActive),
+			sender:  "alex@example.com",
+			subject: "Re: What did you get done today?",
+			body:    "Shipped the importer",
+
+			wantEvents: []string{events.EntrySaved},
+		},
+		{
+			name:    "verified user sends an empty reply",
+			seed:    seedUser("alex@example.com", true, models.SignupStatusActive),
+			sender:  "alex@example.com",
+			subject: "Re: What did you get done today?",
+			body:    "   ",
+			wantEmails: []queuedEmail{
+				{models.EmailTypeClarification, "alex@example.com", "Clarification needed for your journal entry"},
+			},
+		},
+		{
+			name:    "verified user asks to change email",
+			seed:    seedUser("alex@example.com", true, models.SignupStatusActive),
+			sender:  "alex@example.com",
+			subject: "Re: What did you get done today?",
+			body:    "<change email to new@example.com>",
+			wantEmails: []queuedEmail{
+				{models.EmailTypeEmailChange, "new@example.com", "Confirm your new email address"},
+			},
+		},
+		{
+			name:   "new address confirms the change",
+			seed:   seedEmailChange(false),
+			sender: "new@example.com",
+			body:   "654321",
+			wantEmails: []queuedEmail{
+				{models.EmailTypeEmailChange, "alex@example.com", "Your email address has changed"},
+			},
+		},
+		{
+			name:    "old address journals during the alias window",
+			seed:    seedEmailChange(true),
+			sender:  "alex@example.com",
+			subject: "Re: What did you get done today?",
+			body:    "Shipped the importer",
+
+			wantEvents: []string{events.EntrySaved},
+		},
+		{
+			name:   "alias confirms by reply",
+			seed:   seedAlias(false),
+			sender: "work@example.com",
+			body:   "Code: 654321",
+		},
+		{
+			name:    "verified alias journals",
+			seed:    seedAlias(true),
+			sender:  "Work@Example.com",
+			subject: "Re: What did you get done today?",
+			body:    "Shipped the importer",
+
+			wantEvents: []string{events.EntrySaved},
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			resetDB(t, db)
+			if sc.seed != nil {
+				sc.seed(t, db)
+			}
+
+			recorder := events.NewRecorder()
+			service := NewService(db, emailService)
+			service.SetEvents(recorder)
+
+			err := service.HandleEmailReply(context.Background(), sc.sender, false, sc.subject, sc.body)
+			switch {
+			case sc.wantErr != "" && !apperrors.Is(err, sc.wantErr):
+				t.Fatalf("HandleEmailReply() error = %v, want %s", err, sc.wantErr)
+			case sc.wantErr == "" && err != nil:
+				t.Fatalf("HandleEmailReply() error = %v", err)
+			}
+
+			if got := queuedEmails(t, db); !reflect.DeepEqual(got, sc.wantEmails) {
+				t.Errorf("queued emails = %+v, want %+v", got, sc.wantEmails)
+			}
+			if got := recorder.Types(); len(got)+len(sc.wantEvents) > 0 && !reflect.DeepEqual(got, sc.wantEvents) {
+				t.Errorf("published events = %v, want %v", got, sc.wantEvents)
+			}
+		})
+	}
+}
@@ -2,27 +2,115 @@ package core
 
 import (
 	"context"
+	"crypto/ecdh"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/core/commands"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
-	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/models"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/embeddings"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/entries"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/events"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/integrations/credentials"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/llm"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/quotes"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/sharecard"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/timezones"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
 )
 
 type Service struct {
 	db           *database.DB
 	emailService *email.Service
+	channels     map[string]PromptChannel
+	commands     *commands.Registry
+	events       events.Publisher
+	quotes       *quotes.Service
+	entries      *entries.Repository
+	llm          *llm.Service
+	embeddings   *embeddings.Service
+	shareCards   *sharecard.Store
+	credentials  *credentials.Store
+
+	entryMergeWindow time.Duration
+	emailAliasWindow time.Duration
+	retention        map[string]time.Duration
+	clarification    ClarificationPolicy
+	recap            PromptRecap
 }
 
 func NewService(db *database.DB, emailService *email.Service) *Service {
+	registry := commands.NewRegistry()
+	commands.RegisterBuiltin(registry)
+
 	return &Service{
 		db:           db,
 		emailService: emailService,
+		channels:     map[string]PromptChannel{},
+		commands:     registry,
+		quotes:       quotes.NewService(db),
+		entries:      entries.NewRepository(db),
+
+		clarification: DefaultClarificationPolicy,
+	}
+}
+
+// RegisterCommand adds a reply command after the built-in ones
+func (s *Service) RegisterCommand(c commands.Command) {
+	s.commands.Register(c)
+}
+
+// SetEvents enables publishing domain events, such as EntrySaved, to bus
+func (s *Service) SetEvents(bus events.Publisher) {
+	s.events = bus
+}
+
+// SetLLM enables generating summaries from core, such as GenerateSummaryForRange
+func (s *Service) SetLLM(l *llm.Service) {
+	s.llm = l
+}
+
+// SetEmbeddings enables semantic entry search for <ask> and the weekly
+// summary's look back a quarter. e may be nil.
+func (s *Service) SetEmbeddings(e *embeddings.Service) {
+	s.embeddings = e
+}
+
+// SetShareCards enables a share card image for each weekly summary. store may
+// be nil.
+func (s *Service) SetShareCards(store *sharecard.Store) {
+	s.shareCards = store
+}
+
+// SetCredentials encrypts chat webhook URLs into store instead of keeping
+// them in user_channels. store may be nil.
+func (s *Service) SetCredentials(store *credentials.Store) {
+	s.credentials = store
+}
+
+// SetEntryMergeWindow sets how soon after the last reply a follow-up on the
+// same day is appended to the entry instead of replacing it. Zero disables
+// merging.
+func (s *Service) SetEntryMergeWindow(window time.Duration) {
+	s.entryMergeWindow = window
+}
+
+// publishEvent publishes a domain event. Failures are logged rather than
+// returned so consumers never block the user-facing flow.
+func (s *Service) publishEvent(ctx context.Context, eventType string, data interface{}) {
+	if s.events == nil {
+		return
+	}
+	if err := s.events.Publish(ctx, events.New(eventType, data)); err != nil {
+		logrus.WithError(err).WithField("event_type", eventType).Warn("Failed to publish event")
 	}
 }
 
@@ -34,15 +122,16 @@ func (s *Service) HandleSignupRequest(ctx context.Context, emailAddr string) err
 	}
 
 	if existingUser != nil && existingUser.IsVerified {
-		return fmt.Errorf("user already exists and is verified")
+		// Nothing to verify; remind them how to change their settings
+		return s.emailService.SendAlreadySignedUp(ctx, existingUser)
 	}
 
 	// Generate verification code
 	verificationCode := email.GenerateVerificationCode()
 
 	if existingUser != nil {
-		// Update existing user with new verification code
-		err = s.updateUserVerificationCode(ctx, existingUser.ID, verificationCode)
+		// Signing up again starts over with a new code
+		err = s.restartSignup(ctx, existingUser.ID, verificationCode)
 	} else {
 		// Create new user
 		err = s.createPendingUser(ctx, emailAddr, verificationCode)
@@ -56,18 +145,41 @@ func (s *Service) HandleSignupRequest(ctx context.Context, emailAddr string) err
 	return s.emailService.SendWelcomeEmail(ctx, emailAddr, verificationCode)
 }
 
-func (s *Service) HandleEmailReply(ctx context.Context, senderEmail, subject, body string) error {
+// HandleEmailReply applies a reply from senderEmail. forwarded means the
+// reply reached the account by its reply token from a different address (see
+// ResolveReplySender), so only commands that aren't owner-only run.
+func (s *Service) HandleEmailReply(ctx context.Context, senderEmail string, forwarded bool, subject, body string) error {
 	user, err := s.emailService.GetUserByEmail(ctx, senderEmail)
 	if err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
 	}
 
+	// A user confirming a change to this address
+	if confirmed, err := s.confirmEmailChange(ctx, senderEmail, body); confirmed || err != nil {
+		return err
+	}
+
+	// A user confirming an alias they reply from
+	if confirmed, err := s.confirmEmailAlias(ctx, senderEmail, body); confirmed || err != nil {
+		return err
+	}
+
+	// Someone confirming they want to be CC'd on another user's summary
+	if confirmed, err := s.confirmSummaryCC(ctx, senderEmail, body); confirmed || err != nil {
+		return err
+	}
+
+	// Someone confirming or stopping mentor digests for another user
+	if handled, err := s.handleMentorReply(ctx, senderEmail, body); handled || err != nil {
+		return err
+	}
+
 	if user == nil {
 		// New user signup attempt
 		if NeedsVerification(body) {
 			return s.HandleSignupRequest(ctx, senderEmail)
 		}
-		return fmt.Errorf("unknown sender, please sign up first")
+		return apperrors.New(apperrors.CodeUserNotFound, "unknown sender, please sign up first")
 	}
 
 	if !user.IsVerified {
@@ -75,30 +187,79 @@ func (s *Service) HandleEmailReply(ctx context.Context, senderEmail, subject, bo
 		return s.handleVerificationReply(ctx, user, body)
 	}
 
+	// Replies to the Monday prompt are the week's goals, not an entry
+	if email.IsWeeklyGoalsReply(subject) {
+		return s.saveWeeklyGoals(ctx, user, subject, body)
+	}
+
+	return s.processReply(ctx, user, subject, body, forwarded)
+}
+
+// processReply parses a verified user's reply, whichever channel it arrived on,
+// and applies its commands. thread groups replies for clarification limits.
+// A forwarded reply skips owner-only commands.
+func (s *Service) processReply(ctx context.Context, user *models.User, thread, body string, forwarded bool) error {
+	entryDate := repliedPromptDate(thread, time.Now().UTC())
+	if entryDate != nil {
+		logrus.WithFields(logrus.Fields{
+			"user_id":     user.ID,
+			"prompt_date": entryDate.Format("2006-01-02"),
+		}).Info("Reply to an earlier prompt, dating its entry to the prompt")
+	}
+	return s.processReplyFor(ctx, user, thread, body, forwarded, entryDate)
+}
+
+// processReplyFor is processReply with the day entries are saved for, nil
+// for today
+func (s *Service) processReplyFor(ctx context.Context, user *models.User, thread, body string, forwarded bool, entryDate *time.Time) error {
 	// Parse the reply
-	parsed := ParseEmailReply(body)
+	parsed := ParseEmailReply(s.commands, body, user.Language)
 	if !parsed.IsValidated {
-		logrus.WithError(parsed.Error).WithField("user_id", user.ID).Error("Failed to parse email reply")
-		return s.emailService.SendClarificationRequest(ctx, user.ID, user.Email, body)
+		logrus.WithError(parsed.Error).WithFields(logrus.Fields{
+			"user_id":    user.ID,
+			"error_code": apperrors.CodeOf(parsed.Error),
+		}).Error("Failed to parse email reply")
+		var ambiguous *timezones.AmbiguousError
+		if errors.As(parsed.Error, &ambiguous) {
+			return s.askWhichTimezone(ctx, user, ambiguous, "<timezone>%s</timezone>")
+		}
+		return s.requestClarification(ctx, user, thread, body)
 	}
 
 	// Process commands
-	for _, cmd := range parsed.Commands {
-		switch cmd.Type {
-		case CommandTypePause:
-			err = s.pauseUser(ctx, user.ID, *cmd.Duration)
-		case CommandTypeProject:
-			err = s.updateUserProject(ctx, user.ID, cmd.Value)
-		case CommandTypeEntry:
-			err = s.saveEntry(ctx, user.ID, cmd.Value, parsed.ProjectTag)
+	env := &commands.Env{Service: commandService{s}, User: user, EntryDate: entryDate}
+	for _, inv := range parsed.Commands {
+		if forwarded && inv.Command.OwnerOnly() {
+			logrus.WithFields(logrus.Fields{
+				"user_id":      user.ID,
+				"command_type": inv.Command.Name(),
+			}).Warn("Refused owner-only command from a reply sent by a different address")
+			continue
+		}
+		if err := inv.Command.Execute(ctx, env, inv); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"command_type": inv.Command.Name(),
+				"error_code":   apperrors.CodeOf(err),
+			}).Error("Failed to process command")
+			return s.requestClarification(ctx, user, thread, body)
 		}
+	}
 
-		if err != nil {
-			logrus.WithError(err).WithField("command_type", cmd.Type).Error("Failed to process command")
-			return s.emailService.SendClarificationRequest(ctx, user.ID, user.Email, body)
+	// Preference commands are applied together, after the other commands
+	if env.Patched {
+		if _, err := s.UpdatePreferences(ctx, user.ID, env.Patch); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"user_id":    user.ID,
+				"error_code": apperrors.CodeOf(err),
+			}).Error("Failed to update preferences")
+			return s.requestClarification(ctx, user, thread, body)
 		}
 	}
 
+	if err := s.resetClarifications(ctx, user.ID, thread); err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to reset clarification attempts")
+	}
+
 	logrus.WithFields(logrus.Fields{
 		"user_id":       user.ID,
 		"commands_count": len(parsed.Commands),
@@ -107,27 +268,131 @@ func (s *Service) HandleEmailReply(ctx context.Context, senderEmail, subject, bo
 	return nil
 }
 
+// ResolveReplySender returns the address of the user whose reply token
+// appears in recipients, so replies sent from an alias or a second address
+// reach the right account, and whether sender differs from that address.
+// Anyone holding a forwarded prompt knows the token, so such replies may only
+// journal. Without a known token it returns sender.
+func (s *Service) ResolveReplySender(ctx context.Context, recipients []string, sender string) (string, bool, error) {
+	for _, recipient := range recipients {
+		token, ok := email.ReplyToken(recipient)
+		if !ok {
+			continue
+		}
+
+		var userEmail string
+		err := s.db.QueryRowContext(ctx, `SELECT email FROM users WHERE reply_token = $1`, token).Scan(&userEmail)
+		if err == sql.ErrNoRows {
+			logrus.WithField("recipient", recipient).Warn("Reply token does not match any user")
+			continue
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("failed to look up reply token: %w", err)
+		}
+
+		if !strings.EqualFold(userEmail, sender) {
+			logrus.WithFields(logrus.Fields{
+				"sender":     sender,
+				"user_email": userEmail,
+			}).Warn("Routed reply by token from a different address than the user's; owner-only commands are refused")
+			return userEmail, true, nil
+		}
+		return userEmail, false, nil
+	}
+
+	return sender, false, nil
+}
+
 func (s *Service) handleVerificationReply(ctx context.Context, user *models.User, body string) error {
+	if user.SignupStatus == models.SignupStatusPendingConfirmation {
+		return s.handleConfirmationReply(ctx, user, body)
+	}
+
+	// Once the code is accepted, replies answer the signup questions
+	step, err := s.wizardStep(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if step != "" {
+		return s.handleWizardAnswer(ctx, user, step, body)
+	}
+
 	// Look for verification code in the reply
 	if user.VerificationCode == nil {
-		return fmt.Errorf("no verification code set for user")
+		return apperrors.New(apperrors.CodeNotVerified, "no verification code set for user")
 	}
 
 	// Simple check if the verification code is in the body
 	if !contains(body, *user.VerificationCode) {
+		// Asking to sign up again, say after losing the welcome email, sends
+		// a new code
+		if NeedsVerification(body) {
+			return s.HandleSignupRequest(ctx, user.Email)
+		}
 		return s.emailService.SendClarificationRequest(ctx, user.ID, user.Email, 
 			"Please include your verification code in your reply")
 	}
 
-	// Parse user preferences from the reply
+	// A reply with every preference filled in skips the questions
 	preferences, err := parseUserPreferences(body)
 	if err != nil {
-		return s.emailService.SendClarificationRequest(ctx, user.ID, user.Email, 
-			"Please provide your preferences in the format shown in the welcome email")
+		return s.startWizard(ctx, user)
+	}
+
+	return s.requestPreferenceConfirmation(ctx, user, preferences)
+}
+
+func (s *Service) handleConfirmationReply(ctx context.Context, user *models.User, body string) error {
+	content := cleanEmailContent(body)
+	if isConfirmationReply(content) {
+		preferences := userPreferences(user)
+		if err := s.verifyUser(ctx, user.ID, preferences, nil); err != nil {
+			return err
+		}
+
+		s.publishEvent(ctx, events.UserVerified, map[string]interface{}{
+			"user_id":  user.ID,
+			"email":    user.Email,
+			"name":     user.Name,
+			"timezone": user.Timezone,
+		})
+		return nil
+	}
+
+	// Corrected preferences restart the confirmation step. Only the lines
+	// being corrected need to be sent; the rest are kept.
+	preferences, err := parseUserPreferences(content + "\n" + preferenceForm(userPreferences(user)))
+	var ambiguous *timezones.AmbiguousError
+	if errors.As(err, &ambiguous) {
+		return s.askWhichTimezone(ctx, user, ambiguous, "Timezone: %s")
+	}
+	if err != nil {
+		return s.emailService.SendClarificationRequest(ctx, user.ID, user.Email,
+			`Please reply with "confirm" or send corrected preferences`)
+	}
+
+	return s.requestPreferenceConfirmation(ctx, user, preferences)
+}
+
+// askWhichTimezone lists the timezones an ambiguous answer could mean.
+// replyFormat shows how to name the chosen one, with %s for the timezone.
+func (s *Service) askWhichTimezone(ctx context.Context, user *models.User, ambiguous *timezones.AmbiguousError, replyFormat string) error {
+	options := make([]string, len(ambiguous.Candidates))
+	for i, tz := range ambiguous.Candidates {
+		options[i] = timezones.Label(tz, time.Now())
+	}
+	return s.emailService.SendTimezoneClarification(ctx, user.ID, user.Email, ambiguous.Input, options,
+		fmt.Sprintf(replyFormat, ambiguous.Candidates[0]))
+}
+
+// requestPreferenceConfirmation stores the parsed preferences and asks the user to confirm them before prompts start
+func (s *Service) requestPreferenceConfirmation(ctx context.Context, user *models.User, prefs *UserPreferences) error {
+	if err := s.savePendingPreferences(ctx, user.ID, prefs); err != nil {
+		return fmt.Errorf("failed to save pending preferences: %w", err)
 	}
 
-	// Update user with preferences and mark as verified
-	return s.verifyUser(ctx, user.ID, preferences)
+	return s.emailService.SendConfirmationEmail(ctx, user.ID, user.Email, prefs.Name, prefs.Timezone,
+		prefs.PromptTime, prefs.ProjectFocus, prefs.WeekStart)
 }
 
 func (s *Service) createPendingUser(ctx context.Context, email, verificationCode string) error {
@@ -139,71 +404,303 @@ func (s *Service) createPendingUser(ctx context.Context, email, verificationCode
 	return err
 }
 
-func (s *Service) updateUserVerificationCode(ctx context.Context, userID int, verificationCode string) error {
+// restartSignup gives an unverified user a new verification code and drops
+// any signup answers they had given, so the new code starts over
+func (s *Service) restartSignup(ctx context.Context, userID int, verificationCode string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE users
+		SET verification_code = $2, signup_status = $3, updated_at = NOW()
+		WHERE id = $1`
+
+	if _, err := tx.ExecContext(ctx, query, userID, verificationCode, models.SignupStatusPendingVerification); err != nil {
+		return fmt.Errorf("failed to reset verification code: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM signup_wizards WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to reset signup wizard: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to restart signup: %w", err)
+	}
+
+	s.emailService.Users().Invalidate(userID)
+	return nil
+}
+
+func (s *Service) savePendingPreferences(ctx context.Context, userID int, prefs *UserPreferences) error {
 	query := `
 		UPDATE users 
-		SET verification_code = $2, updated_at = NOW()
+		SET name = $2, timezone = $3, prompt_time = $4, project_focus = $5, 
+		    week_start = $6, signup_status = $7, updated_at = NOW()
 		WHERE id = $1`
 
-	_, err := s.db.ExecContext(ctx, query, userID, verificationCode)
+	_, err := s.db.ExecContext(ctx, query, userID, prefs.Name, prefs.Timezone,
+		prefs.PromptTime, prefs.ProjectFocus, prefs.WeekStart, models.SignupStatusPendingConfirmation)
+	s.emailService.Users().Invalidate(userID)
 	return err
 }
 
-func (s *Service) verifyUser(ctx context.Context, userID int, prefs *UserPreferences) error {
+// verifyUser saves prefs and activates the user. A non-nil audit is
+// recorded in the same transaction.
+func (s *Service) verifyUser(ctx context.Context, userID int, prefs *UserPreferences, audit *AdminAction) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE users 
 		SET name = $2, timezone = $3, prompt_time = $4, project_focus = $5, 
-		    is_verified = TRUE, verification_code = NULL, updated_at = NOW()
+		    week_start = $6, is_verified = TRUE, verification_code = NULL, signup_status = $7, updated_at = NOW()
 		WHERE id = $1`
 
-	_, err := s.db.ExecContext(ctx, query, userID, prefs.Name, prefs.Timezone, 
-		prefs.PromptTime, prefs.ProjectFocus)
-	return err
+	_, err = tx.ExecContext(ctx, query, userID, prefs.Name, prefs.Timezone, 
+		prefs.PromptTime, prefs.ProjectFocus, prefs.WeekStart, models.SignupStatusActive)
+	if err != nil {
+		return err
+	}
+
+	// The signup project starts the user's project history
+	if prefs.ProjectFocus != nil {
+		if err := recordProjectChange(ctx, tx, userID, *prefs.ProjectFocus); err != nil {
+			return err
+		}
+	}
+	if audit != nil {
+		if err := recordAdminAction(ctx, tx, audit); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.emailService.Users().Invalidate(userID)
+	return nil
 }
 
-func (s *Service) pauseUser(ctx context.Context, userID int, duration time.Duration) error {
-	pauseUntil := time.Now().Add(duration)
+// PauseUser stops the user's prompts until pauseUntil
+func (s *Service) PauseUser(ctx context.Context, userID int, pauseUntil time.Time) error {
 	query := `
 		UPDATE users 
 		SET is_paused = TRUE, pause_until = $2, updated_at = NOW()
 		WHERE id = $1`
 
 	_, err := s.db.ExecContext(ctx, query, userID, pauseUntil)
+	s.emailService.Users().Invalidate(userID)
 	return err
 }
 
-func (s *Service) updateUserProject(ctx context.Context, userID int, projectName string) error {
+// ResumeUser ends a pause early
+func (s *Service) ResumeUser(ctx context.Context, userID int) error {
 	query := `
-		UPDATE users 
-		SET project_focus = $2, updated_at = NOW()
+		UPDATE users
+		SET is_paused = FALSE, pause_until = NULL, updated_at = NOW()
 		WHERE id = $1`
 
-	_, err := s.db.ExecContext(ctx, query, userID, projectName)
-	return err
+	if _, err := s.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to resume user: %w", err)
+	}
+	s.emailService.Users().Invalidate(userID)
+	return nil
+}
+
+// saveEntry stores the reply. A follow-up reply arriving within the merge
+// window is appended to the day's entry with a timestamp; later replies
+// replace it. For guided formats the sections are stored as JSON in
+// parsed_content; replies that don't follow the skeleton stay plain. An entry
+// without a project tag is tagged with the user's current project.
+// promptReplyWindow is how old a prompt can be for a reply to it to be
+// dated to the prompt
+const promptReplyWindow = 7 * 24 * time.Hour
+
+// repliedPromptDate is the date of the earlier daily prompt thread replies
+// to, or nil if it is today's prompt or not a prompt. Replies to prompts more
+// than promptReplyWindow old are taken as reusing an old thread and dated
+// today.
+func repliedPromptDate(thread string, now time.Time) *time.Time {
+	date, ok := email.PromptDate(thread, now)
+	today := now.Truncate(24 * time.Hour)
+	if !ok || !date.Before(today) || today.Sub(date) > promptReplyWindow {
+		return nil
+	}
+	return &date
+}
+
+// maxEntryWriteAttempts bounds how often saveEntry starts over after another
+// reply for the same day changed the entry between its read and its write
+const maxEntryWriteAttempts = 3
+
+// errEntryChanged is writeEntry's report that the entry's version moved on
+var errEntryChanged = errors.New("entry changed while saving")
+
+// entryWrite is what writeEntry saved
+type entryWrite struct {
+	rawContent string
+	merged     bool
+	sealed     bool
+	sinceLast  time.Duration
+}
+
+// saveEntry saves content as the user's entry for date, or today if date is
+// nil. A reply within the merge window of today's entry, or any reply for an
+// earlier day that already has an entry, is appended to that entry. Writes
+// are checked against the entry's version, so two replies processed at once
+// can't drop one another; the loser reads the entry again and merges or
+// replaces it as if it had arrived second.
+func (s *Service) saveEntry(ctx context.Context, userID int, entryFormat, content string, projectTag *string, date *time.Time) error {
+	now := time.Now().UTC()
+	day := now.Format("2006-01-02")
+	backdated := date != nil && date.Format("2006-01-02") < day
+	if backdated {
+		day = date.Format("2006-01-02")
+	}
+
+	sealTo, err := custody.SealingKey(ctx, s.db, userID)
+	if err != nil {
+		return err
+	}
+
+	var write *entryWrite
+	for attempt := 1; ; attempt++ {
+		write, err = s.writeEntry(ctx, userID, day, backdated, entryFormat, content, projectTag, sealTo, now)
+		if err != errEntryChanged {
+			break
+		}
+		if attempt == maxEntryWriteAttempts {
+			return apperrors.Wrap(apperrors.CodeConflict, err, "entry for %s kept changing after %d attempts", day, attempt)
+		}
+		logrus.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"entry_date": day,
+			"attempt":    attempt,
+		}).Info("Entry changed while saving, retrying")
+	}
+	if err != nil {
+		return err
+	}
+
+	if write.merged {
+		logrus.WithFields(logrus.Fields{
+			"user_id":       userID,
+			"entry_date":    day,
+			"backdated":     backdated,
+			"since_last_ms": write.sinceLast.Milliseconds(),
+			"merge_window":  s.entryMergeWindow.String(),
+		}).Info("Merged follow-up reply into the day's entry")
+	}
+
+	// Sealed content isn't sent on; subscribers couldn't read it
+	eventContent := write.rawContent
+	if write.sealed {
+		eventContent = ""
+	}
+	s.publishEvent(ctx, events.EntrySaved, map[string]interface{}{
+		"user_id":     userID,
+		"entry_date":  day,
+		"content":     eventContent,
+		"project_tag": projectTag,
+		"merged":      write.merged,
+	})
+	return nil
 }
 
-func (s *Service) saveEntry(ctx context.Context, userID int, content string, projectTag *string) error {
-	today := time.Now().UTC().Format("2006-01-02")
-	
+// writeEntry reads the day's entry and its version, then writes the new
+// content only if the version is unchanged, returning errEntryChanged if it
+// isn't. A day without a live entry is written only if no live entry has
+// appeared since. With sealTo the content is sealed; a follow-up is sealed
+// on its own and added as another segment.
+func (s *Service) writeEntry(ctx context.Context, userID int, day string, backdated bool, entryFormat, content string, projectTag *string, sealTo *ecdh.PublicKey, now time.Time) (*entryWrite, error) {
+	var existing string
+	var updatedAt time.Time
+	var version int
 	query := `
-		INSERT INTO entries (user_id, entry_date, raw_content, parsed_content, project_tag)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (user_id, entry_date) 
-		DO UPDATE SET raw_content = $3, parsed_content = $4, project_tag = $5, updated_at = NOW()`
+		SELECT raw_content, updated_at, version FROM entries
+		WHERE user_id = $1 AND entry_date = $2 AND deleted_at IS NULL`
 
-	_, err := s.db.ExecContext(ctx, query, userID, today, content, content, projectTag)
-	return err
+	err := s.db.QueryRowContext(ctx, query, userID, day).Scan(&existing, &updatedAt, &version)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to load the day's entry: %w", err)
+	}
+	found := err == nil
+
+	write := &entryWrite{rawContent: content, sealed: sealTo != nil, sinceLast: now.Sub(updatedAt)}
+	write.merged = found && (backdated || (s.entryMergeWindow > 0 && write.sinceLast <= s.entryMergeWindow))
+
+	var parsedContent *string
+	switch {
+	case write.sealed && write.merged:
+		segment, err := custody.Seal(sealTo, fmt.Sprintf("[%s UTC] %s", now.Format("15:04"), content))
+		if err != nil {
+			return nil, err
+		}
+		write.rawContent = existing + "\n" + segment
+	case write.merged:
+		write.rawContent = fmt.Sprintf("%s\n\n[%s UTC] %s", existing, now.Format("15:04"), content)
+		fallthrough
+	default:
+		write.rawContent, parsedContent, err = storedContent(sealTo, entryFormat, write.rawContent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	args := []interface{}{userID, day, write.rawContent, parsedContent, projectTag}
+	switch {
+	case write.merged:
+		// A follow-up without a project tag keeps the one already on the entry
+		query = `
+			UPDATE entries
+			SET raw_content = $3, parsed_content = $4, project_tag = COALESCE($5, project_tag),
+			    version = version + 1, updated_at = NOW()
+			WHERE user_id = $1 AND entry_date = $2 AND version = $6 AND deleted_at IS NULL`
+		args = append(args, version)
+	case found:
+		query = `
+			UPDATE entries
+			SET raw_content = $3, parsed_content = $4, project_tag = COALESCE($5, ` + projectOnSQL + `),
+			    version = version + 1, updated_at = NOW()
+			WHERE user_id = $1 AND entry_date = $2 AND version = $6 AND deleted_at IS NULL`
+		args = append(args, version)
+	default:
+		// A deleted entry is brought back with the new content
+		query = `
+			INSERT INTO entries (user_id, entry_date, raw_content, parsed_content, project_tag)
+			VALUES ($1, $2, $3, $4, COALESCE($5, ` + projectOnSQL + `))
+			ON CONFLICT (user_id, entry_date)
+			DO UPDATE SET raw_content = $3, parsed_content = $4, project_tag = EXCLUDED.project_tag, deleted_at = NULL,
+			    version = entries.version + 1, updated_at = NOW()
+			WHERE entries.deleted_at IS NOT NULL`
+	}
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save entry: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to save entry: %w", err)
+	} else if n == 0 {
+		return nil, errEntryChanged
+	}
+	return write, nil
 }
 
-func (s *Service) GetUsersForDailyPrompt(ctx context.Context, currentHour int) ([]*models.User, error) {
+// GetUsersForDailyPrompt returns the verified, unpaused users whose prompt
+// hour is at's UTC hour
+func (s *Service) GetUsersForDailyPrompt(ctx context.Context, at time.Time) ([]*models.User, error) {
 	query := `
-		SELECT id, email, name, timezone, prompt_time, project_focus
+		SELECT id, email, name, timezone, prompt_time, project_focus, week_start, delivery_channel, entry_format, quotes_enabled, reply_token
 		FROM users 
 		WHERE is_verified = TRUE 
-		  AND (is_paused = FALSE OR pause_until < NOW())
+		  AND (is_paused = FALSE OR pause_until < $2)
 		  AND EXTRACT(HOUR FROM prompt_time) = $1`
 
-	rows, err := s.db.QueryContext(ctx, query, currentHour)
+	rows, err := s.db.QueryContext(ctx, query, at.UTC().Hour(), at)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query users for daily prompt: %w", err)
 	}
@@ -215,7 +712,7 @@ func (s *Service) GetUsersForDailyPrompt(ctx context.Context, currentHour int) (
 		var projectFocus sql.NullString
 
 		err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.Timezone, 
-			&user.PromptTime, &projectFocus)
+			&user.PromptTime, &projectFocus, &user.WeekStart, &user.DeliveryChannel, &user.EntryFormat, &user.QuotesEnabled, &user.ReplyToken)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
@@ -230,6 +727,98 @@ func (s *Service) GetUsersForDailyPrompt(ctx context.Context, currentHour int) (
 	return users, nil
 }
 
+// GetUsersForWeeklySummary returns the verified users not paused at at,
+// with the preferences summaries are written with
+func (s *Service) GetUsersForWeeklySummary(ctx context.Context, at time.Time) ([]*models.User, error) {
+	query := `
+		SELECT id, email, name, timezone, prompt_time, is_verified, project_focus, signup_status,
+			week_start, delivery_channel, entry_format, summary_voice, quotes_enabled, compare_weeks,
+			weekly_goals, language, summary_language, email_tracking, prompt_threading, reply_token,
+			created_at, updated_at
+		FROM users
+		WHERE is_verified = TRUE
+		  AND (is_paused = FALSE OR pause_until < $1)
+		ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users for weekly summary: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		var user models.User
+		var projectFocus sql.NullString
+
+		err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.Timezone, &user.PromptTime,
+			&user.IsVerified, &projectFocus, &user.SignupStatus, &user.WeekStart, &user.DeliveryChannel,
+			&user.EntryFormat, &user.SummaryVoice, &user.QuotesEnabled, &user.CompareWeeks,
+			&user.WeeklyGoals, &user.Language, &user.SummaryLanguage, &user.EmailTracking,
+			&user.PromptThreading, &user.ReplyToken, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+
+		if projectFocus.Valid {
+			user.ProjectFocus = &projectFocus.String
+		}
+
+		users = append(users, &user)
+	}
+
+	return users, rows.Err()
+}
+
+// GetEntriesForWeek returns the user's entries for the week beginning at weekStart
+func (s *Service) GetEntriesForWeek(ctx context.Context, userID int, weekStart time.Time) ([]*models.Entry, error) {
+	return s.GetEntriesBetween(ctx, userID, weekStart, weekStart.AddDate(0, 0, 7))
+}
+
+// ListEntries returns a page of the user's entries matching filter, for
+// GET /v1/entries and entry list; see entries.Repository.List
+func (s *Service) ListEntries(ctx context.Context, userID int, filter entries.Filter, page string, limit int) (*entries.Page, error) {
+	return s.entries.List(ctx, userID, filter, page, limit)
+}
+
+// GetEntriesBetween returns the user's entries dated in [from, to)
+func (s *Service) GetEntriesBetween(ctx context.Context, userID int, from, to time.Time) ([]*models.Entry, error) {
+	query := `
+		SELECT id, user_id, entry_date, raw_content, parsed_content, project_tag, created_at, updated_at
+		FROM entries
+		WHERE user_id = $1 AND entry_date >= $2 AND entry_date < $3 AND deleted_at IS NULL
+		ORDER BY entry_date ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.Entry
+	for rows.Next() {
+		var entry models.Entry
+		var parsedContent, projectTag sql.NullString
+
+		err := rows.Scan(&entry.ID, &entry.UserID, &entry.EntryDate, &entry.RawContent,
+			&parsedContent, &projectTag, &entry.CreatedAt, &entry.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+
+		if parsedContent.Valid {
+			entry.ParsedContent = &parsedContent.String
+		}
+		if projectTag.Valid {
+			entry.ProjectTag = &projectTag.String
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
 func contains(text, substr string) bool {
 	return len(text) > 0 && len(substr) > 0 && 
 		   strings.Contains(strings.ToLower(text), strings.ToLower(substr))
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/core/commands"
+)
+
+func TestRepliedPromptDate(t *testing.T) {
+	// A Sunday morning
+	now := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		thread string
+		want   string
+	}{
+		{"Re: What did you get done today? - Mar 7", "2024-03-07"},
+		{"RE: Fwd: What did you get done today? - Mar 9", "2024-03-09"},
+		{"Re: What did you get done today? - Mar 10", ""},
+		{"Re: What did you get done today? - Feb 20", ""},
+		{"Re: What will you get done this week? - Mar 4", ""},
+		{"slack", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.thread, func(t *testing.T) {
+			got := repliedPromptDate(tt.thread, now)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("repliedPromptDate(%q) = %s, want nil", tt.thread, got.Format("2006-01-02"))
+				}
+				return
+			}
+			if got == nil || got.Format("2006-01-02") != tt.want {
+				t.Errorf("repliedPromptDate(%q) = %v, want %s", tt.thread, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEmailReplyDatedEntry(t *testing.T) {
+	registry := commands.NewRegistry()
+	commands.RegisterBuiltin(registry)
+
+	parsed := ParseEmailReply(registry, "<date>yesterday</date>\nShipped the importer", "en")
+	if !parsed.IsValidated {
+		t.Fatalf("ParseEmailReply() error = %v", parsed.Error)
+	}
+	if len(parsed.Commands) != 2 || parsed.Commands[1].Value != "Shipped the importer" {
+		t.Errorf("ParseEmailReply() commands = %+v, want <date> then the entry", parsed.Commands)
+	}
+}
+
+func TestRepliedPromptDateAcrossNewYear(t *testing.T) {
+	now := time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)
+	got := repliedPromptDate("Re: What did you get done today? - Dec 30", now)
+	if got == nil || got.Format("2006-01-02") != "2024-12-30" {
+		t.Errorf("repliedPromptDate() = %v, want 2024-12-30", got)
+	}
+}
+
+func TestRecapDay(t *testing.T) {
+	// A Monday
+	today := time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		daysAgo int
+		want    string
+	}{
+		{1, "Yesterday"},
+		{3, "On Friday"},
+		{6, "On Tuesday"},
+		{7, "A week ago"},
+	}
+	for _, tt := range tests {
+		if got := recapDay(today.AddDate(0, 0, -tt.daysAgo), today); got != tt.want {
+			t.Errorf("recapDay(%d days ago) = %q, want %q", tt.daysAgo, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/events"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/sharecard"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/stats"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// GetWeeklySummary returns the archived summary for the week starting at
+// weekStart, or nil if none was generated
+func (s *Service) GetWeeklySummary(ctx context.Context, userID int, weekStart time.Time) (*models.WeeklySummary, error) {
+	query := `
+		SELECT id, user_id, week_start_date, summary_paragraph, bullet_points,
+		       llm_model, llm_cost_cents, card_url, created_at
+		FROM weekly_summaries
+		WHERE user_id = $1 AND week_start_date = $2`
+
+	summary := &models.WeeklySummary{}
+	err := s.db.QueryRowContext(ctx, query, userID, weekStart).Scan(
+		&summary.ID, &summary.UserID, &summary.WeekStartDate, &summary.SummaryParagraph,
+		&summary.BulletPoints, &summary.LLMModel, &summary.LLMCostCents, &summary.CardURL, &summary.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weekly summary: %w", err)
+	}
+
+	return summary, nil
+}
+
+// ListWeeklySummaries returns the user's most recent archived summaries,
+// newest first
+func (s *Service) ListWeeklySummaries(ctx context.Context, userID, limit int) ([]*models.WeeklySummary, error) {
+	query := `
+		SELECT id, user_id, week_start_date, summary_paragraph, bullet_points,
+		       llm_model, llm_cost_cents, card_url, created_at
+		FROM weekly_summaries
+		WHERE user_id = $1
+		ORDER BY week_start_date DESC
+		LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weekly summaries: %w", err)
+	}
+	defer rows.Close()
+
+	return scanWeeklySummaries(rows)
+}
+
+// GetWeeklySummariesBetween returns the user's archived summaries for weeks
+// starting from through to, oldest first
+func (s *Service) GetWeeklySummariesBetween(ctx context.Context, userID int, from, to time.Time) ([]*models.WeeklySummary, error) {
+	query := `
+		SELECT id, user_id, week_start_date, summary_paragraph, bullet_points,
+		       llm_model, llm_cost_cents, card_url, created_at
+		FROM weekly_summaries
+		WHERE user_id = $1 AND week_start_date BETWEEN $2 AND $3
+		ORDER BY week_start_date`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weekly summaries: %w", err)
+	}
+	defer rows.Close()
+
+	return scanWeeklySummaries(rows)
+}
+
+func scanWeeklySummaries(rows *sql.Rows) ([]*models.WeeklySummary, error) {
+	var summaries []*models.WeeklySummary
+	for rows.Next() {
+		summary := &models.WeeklySummary{}
+		err := rows.Scan(&summary.ID, &summary.UserID, &summary.WeekStartDate, &summary.SummaryParagraph,
+			&summary.BulletPoints, &summary.LLMModel, &summary.LLMCostCents, &summary.CardURL, &summary.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan weekly summary: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, rows.Err()
+}
+
+// maxPriorSummaries is how many earlier summaries a new one is compared with
+const maxPriorSummaries = 3
+
+// PriorSummaries returns the user's archived summaries from before weekStart,
+// newest first, for comparison in the next summary. It returns nil when the
+// user has turned comparison off.
+func (s *Service) PriorSummaries(ctx context.Context, user *models.User, weekStart time.Time) ([]*models.WeeklySummary, error) {
+	if !user.CompareWeeks {
+		return nil, nil
+	}
+
+	query := `
+		SELECT id, user_id, week_start_date, summary_paragraph, bullet_points,
+		       llm_model, llm_cost_cents, card_url, created_at
+		FROM weekly_summaries
+		WHERE user_id = $1 AND week_start_date < $2
+		ORDER BY week_start_date DESC
+		LIMIT $3`
+
+	rows, err := s.db.QueryContext(ctx, query, user.ID, weekStart, maxPriorSummaries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prior summaries: %w", err)
+	}
+	defer rows.Close()
+
+	return scanWeeklySummaries(rows)
+}
+
+// SaveWeeklySummary archives a generated summary along with the model that
+// produced it, replacing any earlier summary, and its share card, for the
+// same week
+func (s *Service) SaveWeeklySummary(ctx context.Context, userID int, weekStart time.Time, paragraph string, bulletPoints []string, llmModel string, costCents int) error {
+	query := `
+		INSERT INTO weekly_summaries (user_id, week_start_date, summary_paragraph, bullet_points, llm_model, llm_cost_cents)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, week_start_date) DO UPDATE
+		SET summary_paragraph = EXCLUDED.summary_paragraph,
+		    bullet_points = EXCLUDED.bullet_points,
+		    llm_model = EXCLUDED.llm_model,
+		    llm_cost_cents = EXCLUDED.llm_cost_cents,
+		    card_url = NULL`
+
+	_, err := s.db.ExecContext(ctx, query, userID, weekStart, paragraph,
+		models.BulletPoints(bulletPoints), llmModel, costCents)
+	if err != nil {
+		return fmt.Errorf("failed to save weekly summary: %w", err)
+	}
+
+	s.publishEvent(ctx, events.SummaryGenerated, map[string]interface{}{
+		"user_id":           userID,
+		"week_start":        weekStart.Format("2006-01-02"),
+		"summary_paragraph": paragraph,
+		"bullet_points":     bulletPoints,
+		"llm_model":         llmModel,
+	})
+	return nil
+}
+
+// CreateShareCard renders and stores a share card for the summary of the
+// week starting at weekStart, returning its link, or "" when share cards are
+// off
+func (s *Service) CreateShareCard(ctx context.Context, user *models.User, weekStart time.Time, bulletPoints []string) (string, error) {
+	if s.shareCards == nil {
+		return "", nil
+	}
+
+	streak, err := s.GetStreak(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	return s.shareCards.Upload(ctx, sharecard.Card{
+		WeekStart: weekStart,
+		WeekEnd:   period.SummaryEnd(weekStart),
+		Bullets:   bulletPoints,
+		Streak:    streak.Current,
+	})
+}
+
+// SetSummaryCard records the share card link of the archived summary for the
+// week starting at weekStart
+func (s *Service) SetSummaryCard(ctx context.Context, userID int, weekStart time.Time, cardURL string) error {
+	query := `UPDATE weekly_summaries SET card_url = $3 WHERE user_id = $1 AND week_start_date = $2`
+	if _, err := s.db.ExecContext(ctx, query, userID, weekStart, cardURL); err != nil {
+		return fmt.Errorf("failed to save share card link: %w", err)
+	}
+	return nil
+}
+
+// BuildEnergyTrend computes the weekly and monthly energy trend for the week
+// starting at weekStart
+func (s *Service) BuildEnergyTrend(ctx context.Context, userID int, weekStart time.Time) (*stats.Trend, error) {
+	from := weekStart.AddDate(0, 0, -7*(stats.TrendWeeks-1))
+	entries, err := s.GetEntriesBetween(ctx, userID, from, weekStart.AddDate(0, 0, 7))
+	if err != nil {
+		return nil, err
+	}
+
+	return stats.BuildTrend(entries, weekStart), nil
+}
+
+// MissedDays returns the days from weekStart through weekEnd without an
+// entry, up to the day before now since today's entry may still come. Days
+// off aren't missed, and sealed entries count like any other.
+func (s *Service) MissedDays(ctx context.Context, userID int, weekStart, weekEnd, now time.Time) ([]time.Time, error) {
+	to := weekEnd
+	if yesterday := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -1); yesterday.Before(to) {
+		to = yesterday
+	}
+
+	query := `
+		SELECT entry_date FROM entries
+		WHERE user_id = $1 AND entry_date >= $2 AND entry_date <= $3 AND deleted_at IS NULL`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, weekStart, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entry dates: %w", err)
+	}
+	defer rows.Close()
+
+	var dates []time.Time
+	for rows.Next() {
+		var date time.Time
+		if err := rows.Scan(&date); err != nil {
+			return nil, fmt.Errorf("failed to scan entry date: %w", err)
+		}
+		dates = append(dates, date)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	daysOff, err := s.GetDaysOff(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return stats.MissedDays(dates, weekStart, to, daysOff.Off), nil
+}
+
+// resendWeeklySummary re-emails an archived summary without calling the LLM
+func (s *Service) resendWeeklySummary(ctx context.Context, user *models.User, date time.Time) error {
+	weekStart, weekEnd, err := s.SummaryBounds(ctx, user, date)
+	if err != nil {
+		return err
+	}
+
+	summary, err := s.GetWeeklySummary(ctx, user.ID, weekStart)
+	if err != nil {
+		return err
+	}
+	if summary == nil {
+		return apperrors.New(apperrors.CodeInvalidInput, "no summary found for week of %s", weekStart.Format("2006-01-02"))
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":    user.ID,
+		"week_start": weekStart.Format("2006-01-02"),
+	}).Info("Resending archived weekly summary")
+
+	trend, err := s.BuildEnergyTrend(ctx, user.ID, weekStart)
+	if err != nil {
+		return err
+	}
+
+	goals, err := s.GetWeeklyGoals(ctx, user.ID, weekStart)
+	if err != nil {
+		return err
+	}
+
+	missed, err := s.MissedDays(ctx, user.ID, weekStart, weekEnd, time.Now())
+	if err != nil {
+		return err
+	}
+
+	cardURL := ""
+	if summary.CardURL != nil {
+		cardURL = *summary.CardURL
+	}
+
+	return s.emailService.SendWeeklySummary(ctx, user.ID, user.Email, nil, summary.WeekStartDate, weekEnd, summary.SummaryParagraph, summary.BulletPoints, goals, missed, trend, nil, cardURL, nil)
+}
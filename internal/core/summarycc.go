@@ -0,0 +1,143 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// updateSummaryCC replaces the user's CC list. Addresses already on the list
+// keep their confirmation state; new ones are sent a confirmation request and
+// only receive summaries once they reply with the code.
+func (s *Service) updateSummaryCC(ctx context.Context, user *models.User, addresses []string) error {
+	for _, address := range addresses {
+		if strings.EqualFold(address, user.Email) {
+			return apperrors.New(apperrors.CodeInvalidInput, "you already receive your own summary")
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM summary_cc_recipients WHERE user_id = $1 AND NOT (email = ANY($2))`
+	if _, err := tx.ExecContext(ctx, query, user.ID, pq.Array(addresses)); err != nil {
+		return fmt.Errorf("failed to prune summary CC list: %w", err)
+	}
+
+	var added []*models.SummaryCCRecipient
+	for _, address := range addresses {
+		code := email.GenerateVerificationCode()
+		query := `
+			INSERT INTO summary_cc_recipients (user_id, email, confirmation_code)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, email) DO NOTHING
+			RETURNING id`
+
+		var id int
+		err := tx.QueryRowContext(ctx, query, user.ID, address, code).Scan(&id)
+		if err == nil {
+			added = append(added, &models.SummaryCCRecipient{ID: id, UserID: user.ID, Email: address, ConfirmationCode: code})
+			continue
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("failed to add summary CC: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save summary CC list: %w", err)
+	}
+
+	for _, recipient := range added {
+		err := s.emailService.SendSummaryCCRequest(ctx, user.ID, recipient.Email, user.Name, user.Email, recipient.ConfirmationCode)
+		if err != nil {
+			return err
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":  user.ID,
+		"cc_count": len(addresses),
+		"added":    len(added),
+	}).Info("Summary CC list updated")
+
+	return nil
+}
+
+// confirmSummaryCC confirms pending CC requests for sender whose code appears
+// in body. It reports whether the reply was a CC confirmation.
+func (s *Service) confirmSummaryCC(ctx context.Context, sender, body string) (bool, error) {
+	query := `
+		SELECT id, confirmation_code FROM summary_cc_recipients
+		WHERE email = LOWER($1) AND confirmed_at IS NULL`
+
+	rows, err := s.db.QueryContext(ctx, query, sender)
+	if err != nil {
+		return false, fmt.Errorf("failed to query pending CC requests: %w", err)
+	}
+
+	var confirmIDs []int
+	for rows.Next() {
+		var id int
+		var code string
+		if err := rows.Scan(&id, &code); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan CC request: %w", err)
+		}
+		if contains(body, code) {
+			confirmIDs = append(confirmIDs, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to read CC requests: %w", err)
+	}
+
+	if len(confirmIDs) == 0 {
+		return false, nil
+	}
+
+	query = `UPDATE summary_cc_recipients SET confirmed_at = NOW() WHERE id = ANY($1)`
+	if _, err := s.db.ExecContext(ctx, query, pq.Array(confirmIDs)); err != nil {
+		return true, fmt.Errorf("failed to confirm summary CC: %w", err)
+	}
+
+	logrus.WithField("confirmed", len(confirmIDs)).Info("Summary CC confirmed")
+	return true, nil
+}
+
+// GetSummaryCC returns the confirmed addresses copied on the user's weekly summary
+func (s *Service) GetSummaryCC(ctx context.Context, userID int) ([]string, error) {
+	query := `
+		SELECT email FROM summary_cc_recipients
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL
+		ORDER BY created_at`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query summary CC: %w", err)
+	}
+	defer rows.Close()
+
+	var addresses []string
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			return nil, fmt.Errorf("failed to scan summary CC: %w", err)
+		}
+		addresses = append(addresses, address)
+	}
+
+	return addresses, rows.Err()
+}
@@ -0,0 +1,223 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/core/commands"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/timezones"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Signup wizard steps, in the order they are asked
+const (
+	wizardStepName     = "name"
+	wizardStepTimezone = "timezone"
+	wizardStepTime     = "time"
+	wizardStepProject  = "project"
+)
+
+var wizardSteps = []string{wizardStepName, wizardStepTimezone, wizardStepTime, wizardStepProject}
+
+// wizardQuestion is what the signup email for a step asks
+type wizardQuestion struct {
+	question string
+	example  string
+}
+
+var wizardQuestions = map[string]wizardQuestion{
+	wizardStepName:     {"What should we call you?", "Alex"},
+	wizardStepTimezone: {"What timezone are you in?", "America/New_York or Europe/Berlin"},
+	wizardStepTime:     {"What time should your daily prompt arrive?", "4pm or 16:00"},
+	wizardStepProject:  {`What project are you focused on? Reply "none" to skip.`, "Billing migration"},
+}
+
+// wizardColumns is the users column each step's answer is saved to
+var wizardColumns = map[string]string{
+	wizardStepName:     "name",
+	wizardStepTimezone: "timezone",
+	wizardStepTime:     "prompt_time",
+	wizardStepProject:  "project_focus",
+}
+
+var (
+	namePrefix = regexp.MustCompile(`(?i)^(?:name\s*:|my name is|i'm|i am|call me|it's)\s*`)
+	hourSuffix = regexp.MustCompile(`^(\d{1,2})h(\d{2})?$`)
+)
+
+// startWizard asks the first signup question, restarting the wizard if the
+// user was part way through it
+func (s *Service) startWizard(ctx context.Context, user *models.User) error {
+	query := `
+		INSERT INTO signup_wizards (user_id, step)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET step = EXCLUDED.step, updated_at = NOW()`
+
+	if _, err := s.db.ExecContext(ctx, query, user.ID, wizardSteps[0]); err != nil {
+		return fmt.Errorf("failed to start signup wizard: %w", err)
+	}
+	return s.sendWizardQuestion(ctx, user, wizardSteps[0], "")
+}
+
+// wizardStep returns the question the user is answering, or "" if they are
+// not in the wizard
+func (s *Service) wizardStep(ctx context.Context, userID int) (string, error) {
+	query := `SELECT step FROM signup_wizards WHERE user_id = $1`
+
+	var step string
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&step)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get signup wizard step: %w", err)
+	}
+	return step, nil
+}
+
+func (s *Service) sendWizardQuestion(ctx context.Context, user *models.User, step, problem string) error {
+	q := wizardQuestions[step]
+	return s.emailService.SendSignupQuestion(ctx, user.ID, user.Email, wizardStepNumber(step), len(wizardSteps),
+		q.question, q.example, problem)
+}
+
+// handleWizardAnswer saves a reply as the answer to the current question and
+// asks the next one. An answer that can't be read re-asks the same question
+// saying why. After the last question the user confirms their preferences
+// as in the one-reply signup.
+func (s *Service) handleWizardAnswer(ctx context.Context, user *models.User, step, body string) error {
+	answer := firstLine(cleanEmailContent(body))
+	value, problem := parseWizardAnswer(step, answer)
+	if problem != "" {
+		return s.sendWizardQuestion(ctx, user, step, problem)
+	}
+
+	next := ""
+	if n := wizardStepNumber(step); n < len(wizardSteps) {
+		next = wizardSteps[n]
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`UPDATE users SET %s = $2, updated_at = NOW() WHERE id = $1`, wizardColumns[step])
+	if _, err := tx.ExecContext(ctx, query, user.ID, value); err != nil {
+		return fmt.Errorf("failed to save signup answer: %w", err)
+	}
+	if next == "" {
+		_, err = tx.ExecContext(ctx, `DELETE FROM signup_wizards WHERE user_id = $1`, user.ID)
+	} else {
+		_, err = tx.ExecContext(ctx, `UPDATE signup_wizards SET step = $2, updated_at = NOW() WHERE user_id = $1`, user.ID, next)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to advance signup wizard: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save signup answer: %w", err)
+	}
+	s.emailService.Users().Invalidate(user.ID)
+
+	logrus.WithFields(logrus.Fields{"user_id": user.ID, "step": step}).Info("Saved signup answer")
+
+	if next != "" {
+		return s.sendWizardQuestion(ctx, user, next, "")
+	}
+
+	updated, err := s.emailService.GetUserByID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	return s.requestPreferenceConfirmation(ctx, updated, userPreferences(updated))
+}
+
+// wizardStepNumber is step's position in the wizard, counting from 1
+func wizardStepNumber(step string) int {
+	for i, s := range wizardSteps {
+		if s == step {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// parseWizardAnswer returns the users column value for an answer to step,
+// or a problem telling the user why the answer wasn't understood
+func parseWizardAnswer(step, answer string) (interface{}, string) {
+	switch step {
+	case wizardStepName:
+		name := strings.TrimRight(strings.TrimSpace(namePrefix.ReplaceAllString(answer, "")), ".!")
+		if name == "" || len(name) > maxNameLength {
+			return nil, fmt.Sprintf("We need a name between 1 and %d characters.", maxNameLength)
+		}
+		return name, ""
+	case wizardStepTimezone:
+		tz, err := timezones.Resolve(answer)
+		var ambiguous *timezones.AmbiguousError
+		if errors.As(err, &ambiguous) {
+			return nil, fmt.Sprintf("%q could mean %s. Which one did you mean?", ambiguous.Input, strings.Join(ambiguous.Candidates, " or "))
+		}
+		if err != nil {
+			return nil, fmt.Sprintf("We couldn't find a timezone called %q.", answer)
+		}
+		return tz, ""
+	case wizardStepTime:
+		t, err := parsePromptTime(answer)
+		if err != nil {
+			return nil, fmt.Sprintf("We couldn't read %q as a time of day.", answer)
+		}
+		return t, ""
+	case wizardStepProject:
+		if isSkipAnswer(answer) {
+			return nil, ""
+		}
+		return answer, ""
+	}
+	return nil, fmt.Sprintf("Unknown signup question %q.", step)
+}
+
+// firstLine returns the first non-blank line of content
+func firstLine(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// isSkipAnswer reports whether an optional answer was left out
+func isSkipAnswer(answer string) bool {
+	switch strings.ToLower(strings.Trim(strings.TrimSpace(answer), "_.")) {
+	case "", "none", "skip", "no", "n/a", "-":
+		return true
+	}
+	return false
+}
+
+// parsePromptTime is commands.ParseTime made forgiving of how people write
+// times: "4 p.m.", "4p", "16.30", "16h30" and "9 o'clock" are all accepted
+func parsePromptTime(answer string) (time.Time, error) {
+	t := strings.ToLower(answer)
+	t = strings.NewReplacer("o'clock", "", "oclock", "", "a.m.", "am", "p.m.", "pm", "a.m", "am", "p.m", "pm", " ", "").Replace(t)
+	t = strings.ReplaceAll(t, ".", ":")
+	if m := hourSuffix.FindStringSubmatch(t); m != nil {
+		t = m[1] + ":" + m[2]
+		if m[2] == "" {
+			t += "00"
+		}
+	}
+	if strings.HasSuffix(t, "a") || strings.HasSuffix(t, "p") {
+		t += "m"
+	}
+	return commands.ParseTime(t)
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePromptTime(t *testing.T) {
+	tests := []struct {
+		answer  string
+		want    string
+		wantErr bool
+	}{
+		{"16:00", "16:00", false},
+		{"4pm", "16:00", false},
+		{"4 p.m.", "16:00", false},
+		{"4p", "16:00", false},
+		{"9:30 AM", "09:30", false},
+		{"16.30", "16:30", false},
+		{"16h30", "16:30", false},
+		{"9h", "09:00", false},
+		{"9 o'clock", "09:00", false},
+		{"teatime", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			got, err := parsePromptTime(tt.answer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePromptTime(%q) error = %v, wantErr %v", tt.answer, err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Format("15:04") != tt.want {
+				t.Errorf("parsePromptTime(%q) = %s, want %s", tt.answer, got.Format("15:04"), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWizardAnswer(t *testing.T) {
+	tests := []struct {
+		step        string
+		answer      string
+		want        interface{}
+		wantProblem bool
+	}{
+		{wizardStepName, "My name is Alex.", "Alex", false},
+		{wizardStepName, "I'm Sam", "Sam", false},
+		{wizardStepName, "", nil, true},
+		{wizardStepTimezone, "Europe/Pari", "Europe/Paris", false},
+		{wizardStepTimezone, "nowhere", nil, true},
+		{wizardStepTimezone, "CST", nil, true},
+		{wizardStepTime, "5pm", time.Date(0, 1, 1, 17, 0, 0, 0, time.UTC), false},
+		{wizardStepProject, "Billing migration", "Billing migration", false},
+		{wizardStepProject, "none", nil, false},
+		{wizardStepProject, "Skip", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.step+"/"+tt.answer, func(t *testing.T) {
+			got, problem := parseWizardAnswer(tt.step, tt.answer)
+			if (problem != "") != tt.wantProblem {
+				t.Fatalf("parseWizardAnswer(%q, %q) problem = %q, wantProblem %v", tt.step, tt.answer, problem, tt.wantProblem)
+			}
+			if got != tt.want {
+				t.Errorf("parseWizardAnswer(%q, %q) = %v, want %v", tt.step, tt.answer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUserPreferences(t *testing.T) {
+	// A reply to the welcome email's form, labels and hints included
+	body := `1. Name: Alex
+2. Timezone (e.g., America/New_York): america/chicago
+3. Preferred daily prompt time (e.g., 16:00): 5pm
+4. Project focus tag (optional): ___________
+5. Week starts on (Monday or Sunday, optional): Sunday
+
+> 1. Name: ___________`
+
+	prefs, err := parseUserPreferences(body)
+	if err != nil {
+		t.Fatalf("parseUserPreferences() error = %v", err)
+	}
+	if prefs.Name != "Alex" || prefs.Timezone != "America/Chicago" || prefs.PromptTime.Format("15:04") != "17:00" ||
+		prefs.ProjectFocus != nil || prefs.WeekStart != "sunday" {
+		t.Errorf("parseUserPreferences() = %+v", prefs)
+	}
+
+	// A correction followed by the current preferences keeps the correction
+	corrected, err := parseUserPreferences("Timzone: Europe/Berlin\n" + preferenceForm(prefs))
+	if err != nil {
+		t.Fatalf("parseUserPreferences() error = %v", err)
+	}
+	if corrected.Timezone != "Europe/Berlin" || corrected.Name != "Alex" || corrected.WeekStart != "sunday" {
+		t.Errorf("parseUserPreferences() correction = %+v", corrected)
+	}
+
+	if _, err := parseUserPreferences("Name: Alex"); err == nil {
+		t.Error("parseUserPreferences() without a timezone succeeded")
+	}
+
+	// Lines filled in inside the form's box, borders and all, with the
+	// optional ones left untouched
+	boxed := `| 1. Name: Alex                                            |
+| 2. Timezone (e.g., America/New_York): America/Denver      |
+3. Preferred daily prompt time (e.g., 16:00): 9am|
+| 4. Project focus tag (optional): ___________             |
+5. Week starts on (Monday or Sunday, optional): ________|`
+	prefs, err = parseUserPreferences(boxed)
+	if err != nil {
+		t.Fatalf("parseUserPreferences() boxed error = %v", err)
+	}
+	if prefs.Name != "Alex" || prefs.Timezone != "America/Denver" || prefs.PromptTime.Format("15:04") != "09:00" ||
+		prefs.ProjectFocus != nil || prefs.WeekStart != "monday" {
+		t.Errorf("parseUserPreferences() boxed = %+v", prefs)
+	}
+}
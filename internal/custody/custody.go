@@ -0,0 +1,299 @@
+// Package custody lets users hold the key to their own entries. A user sets
+// a passphrase and gets a data key: an X25519 key pair whose private half is
+// only ever stored wrapped under the passphrase. Turning on self-custody
+// seals their entries to the public half, so from then on operators can
+// write entries but never read them. It can't be turned off: nobody but the
+// passphrase holder can unseal what was sealed.
+package custody
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+)
+
+// MinPassphraseLength is the shortest passphrase a data key is wrapped with
+const MinPassphraseLength = 12
+
+// ConfirmPhrase must be sent to turn on self-custody, after Warning has been
+// shown
+const ConfirmPhrase = "I understand this cannot be undone"
+
+// Warning is shown before self-custody is turned on
+const Warning = "Self-custody cannot be undone. Your entries will be encrypted with a key that only your " +
+	"passphrase unlocks, and the stored copies of your replies are erased. Nobody, including the operators, " +
+	"can read them or recover them if you lose the passphrase, though replies still travel as ordinary email. Weekly summaries, project rollups, questions about your entries and search stop " +
+	"working for sealed entries. Keep your key file and passphrase safe."
+
+var errWrongPassphrase = apperrors.New(apperrors.CodeUnauthorized, "wrong passphrase")
+
+// Key is a user's data key as it is stored and exported: the private key is
+// only present wrapped under the user's passphrase
+type Key struct {
+	UserID     int       `json:"user_id"`
+	Algorithm  string    `json:"algorithm"`
+	PublicKey  []byte    `json:"public_key"`
+	KDF        string    `json:"kdf"`
+	Salt       []byte    `json:"salt"`
+	Iterations int       `json:"iterations"`
+	WrappedKey []byte    `json:"wrapped_key"`
+	CreatedAt  time.Time `json:"created_at"`
+	// SelfCustodyAt is when the user's entries were sealed, if they were
+	SelfCustodyAt *time.Time `json:"self_custody_at,omitempty"`
+}
+
+// Unwrap returns the private key, given the passphrase it was wrapped with
+func (k *Key) Unwrap(passphrase string) (*ecdh.PrivateKey, error) {
+	return unwrap(k.WrappedKey, k.PublicKey, passphrase, k.Salt, k.Iterations)
+}
+
+// Service manages data keys and self-custody
+type Service struct {
+	db *database.DB
+}
+
+func NewService(db *database.DB) *Service {
+	return &Service{db: db}
+}
+
+// Create makes userID's data key wrapped under passphrase. The private key
+// is only held in memory while it is wrapped.
+func (s *Service) Create(ctx context.Context, userID int, passphrase string) (*Key, error) {
+	if err := checkPassphrase(passphrase); err != nil {
+		return nil, err
+	}
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	key, err := newKey(userID, private, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO user_keys (user_id, public_key, salt, iterations, wrapped_key)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO NOTHING
+		RETURNING created_at`
+
+	err = s.db.QueryRowContext(ctx, query, userID, key.PublicKey, key.Salt, key.Iterations, key.WrappedKey).Scan(&key.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeConflict, "user %d already has a data key", userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save data key: %w", err)
+	}
+	return key, nil
+}
+
+// Get returns userID's data key
+func (s *Service) Get(ctx context.Context, userID int) (*Key, error) {
+	query := `
+		SELECT k.public_key, k.salt, k.iterations, k.wrapped_key, k.created_at, u.self_custody_at
+		FROM user_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.user_id = $1`
+
+	key := &Key{UserID: userID, Algorithm: algorithm, KDF: kdf}
+	var selfCustodyAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&key.PublicKey, &key.Salt, &key.Iterations, &key.WrappedKey,
+		&key.CreatedAt, &selfCustodyAt)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeNotFound, "user %d has no data key", userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data key: %w", err)
+	}
+	if selfCustodyAt.Valid {
+		key.SelfCustodyAt = &selfCustodyAt.Time
+	}
+	return key, nil
+}
+
+// ChangePassphrase rewraps userID's data key under a new passphrase. The
+// key itself is unchanged, so sealed entries stay readable with it.
+func (s *Service) ChangePassphrase(ctx context.Context, userID int, oldPassphrase, newPassphrase string) (*Key, error) {
+	if err := checkPassphrase(newPassphrase); err != nil {
+		return nil, err
+	}
+	current, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	private, err := current.Unwrap(oldPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := newKey(userID, private, newPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	key.CreatedAt, key.SelfCustodyAt = current.CreatedAt, current.SelfCustodyAt
+
+	// Only replace the wrapping that was unwrapped, in case of a race
+	query := `
+		UPDATE user_keys SET salt = $2, iterations = $3, wrapped_key = $4, updated_at = NOW()
+		WHERE user_id = $1 AND wrapped_key = $5`
+
+	result, err := s.db.ExecContext(ctx, query, userID, key.Salt, key.Iterations, key.WrappedKey, current.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save data key: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return nil, apperrors.New(apperrors.CodeConflict, "data key of user %d changed, try again", userID)
+	}
+	return key, nil
+}
+
+// EnableSelfCustody seals all of userID's entries to their data key and
+// seals new ones from then on. confirm must be ConfirmPhrase, and the
+// passphrase must unlock the key, so nobody locks themselves out by
+// mistake. It returns how many entries were sealed.
+func (s *Service) EnableSelfCustody(ctx context.Context, userID int, passphrase, confirm string) (int, error) {
+	if confirm != ConfirmPhrase {
+		return 0, apperrors.New(apperrors.CodeInvalidInput, "%s To continue, confirm with %q.", Warning, ConfirmPhrase)
+	}
+	key, err := s.Get(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if key.SelfCustodyAt != nil {
+		return 0, apperrors.New(apperrors.CodeConflict, "self-custody is already on for user %d", userID)
+	}
+	if _, err := key.Unwrap(passphrase); err != nil {
+		return 0, err
+	}
+	public, err := ecdh.X25519().NewPublicKey(key.PublicKey)
+	if err != nil {
+		return 0, fmt.Errorf("invalid data key of user %d: %w", userID, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE users SET self_custody_at = NOW(), updated_at = NOW() WHERE id = $1 AND self_custody_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to turn on self-custody: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return 0, apperrors.New(apperrors.CodeConflict, "self-custody is already on for user %d", userID)
+	}
+
+	// Deleted entries are sealed too, since they can still be restored
+	rows, err := tx.QueryContext(ctx, `SELECT id, raw_content FROM entries WHERE user_id = $1 AND raw_content NOT LIKE $2 FOR UPDATE`,
+		userID, SealedPrefix+"%")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query entries: %w", err)
+	}
+	type plainEntry struct {
+		id      int
+		content string
+	}
+	var entries []plainEntry
+	for rows.Next() {
+		var entry plainEntry
+		if err := rows.Scan(&entry.id, &entry.content); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query entries: %w", err)
+	}
+
+	for _, entry := range entries {
+		sealed, err := Seal(public, entry.content)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE entries SET raw_content = $2, parsed_content = NULL, version = version + 1, updated_at = NOW() WHERE id = $1`,
+			entry.id, sealed)
+		if err != nil {
+			return 0, fmt.Errorf("failed to seal entry: %w", err)
+		}
+	}
+
+	// Embeddings are derived from the plaintext
+	if _, err := tx.ExecContext(ctx, `DELETE FROM entry_embeddings WHERE user_id = $1`, userID); err != nil {
+		return 0, fmt.Errorf("failed to delete entry embeddings: %w", err)
+	}
+
+	// So are the stored replies the entries were saved from. Replies still
+	// being processed are redacted when they finish, as later ones are.
+	_, err = tx.ExecContext(ctx, `
+		UPDATE inbound_messages SET payload = ''
+		WHERE LOWER(sender) IN (
+			SELECT LOWER(email) FROM users WHERE id = $1
+			UNION SELECT LOWER(email) FROM user_emails WHERE user_id = $1)`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to redact inbound messages: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit self-custody: %w", err)
+	}
+	return len(entries), nil
+}
+
+// SealingKey returns the public key userID's entries are sealed to, or nil
+// when they aren't in self-custody
+func SealingKey(ctx context.Context, db *database.DB, userID int) (*ecdh.PublicKey, error) {
+	query := `
+		SELECT k.public_key FROM user_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.user_id = $1 AND u.self_custody_at IS NOT NULL`
+
+	var public []byte
+	err := db.QueryRowContext(ctx, query, userID).Scan(&public)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sealing key: %w", err)
+	}
+	return ecdh.X25519().NewPublicKey(public)
+}
+
+const (
+	algorithm = "x25519-aes256gcm"
+	kdf       = "pbkdf2-sha256"
+)
+
+func newKey(userID int, private *ecdh.PrivateKey, passphrase string) (*Key, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	wrapped, err := wrap(private, passphrase, salt, kdfIterations)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		UserID:     userID,
+		Algorithm:  algorithm,
+		PublicKey:  private.PublicKey().Bytes(),
+		KDF:        kdf,
+		Salt:       salt,
+		Iterations: kdfIterations,
+		WrappedKey: wrapped,
+	}, nil
+}
+
+func checkPassphrase(passphrase string) error {
+	if len([]rune(passphrase)) < MinPassphraseLength {
+		return apperrors.New(apperrors.CodeInvalidInput, "passphrase must be at least %d characters", MinPassphraseLength)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package custody
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// PBKDF2-HMAC-SHA256 test vectors for "password" and "salt"
+	tests := []struct {
+		iterations int
+		want       string
+	}{
+		{1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2([]byte("password"), []byte("salt"), tt.iterations, 32))
+		if got != tt.want {
+			t.Errorf("pbkdf2(%d iterations) = %s, want %s", tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func testKeyPair(t *testing.T) *ecdh.PrivateKey {
+	t.Helper()
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return private
+}
+
+func TestSealOpen(t *testing.T) {
+	private := testKeyPair(t)
+
+	first, err := Seal(private.PublicKey(), "Shipped the importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	followUp, err := Seal(private.PublicKey(), "[17:05 UTC] Fixed the flaky test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(first) {
+		t.Fatalf("Seal() = %q, want the %s prefix", first, SealedPrefix)
+	}
+
+	got, err := Open(private, first+"\n"+followUp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Shipped the importer\n\n[17:05 UTC] Fixed the flaky test"
+	if got != want {
+		t.Errorf("Open() = %q, want %q", got, want)
+	}
+
+	if _, err := Open(testKeyPair(t), first); err == nil {
+		t.Error("Open() with another key succeeded, want an error")
+	}
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	private := testKeyPair(t)
+	salt := []byte("0123456789abcdef")
+
+	wrapped, err := wrap(private, "correct horse battery", salt, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := private.PublicKey().Bytes()
+
+	got, err := unwrap(wrapped, public, "correct horse battery", salt, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(private) {
+		t.Error("unwrap() returned another key")
+	}
+
+	_, err = unwrap(wrapped, public, "wrong horse battery", salt, 1000)
+	if !apperrors.Is(err, apperrors.CodeUnauthorized) {
+		t.Errorf("unwrap() with a wrong passphrase = %v, want %s", err, apperrors.CodeUnauthorized)
+	}
+}
+
+func TestDecryptTextInJSON(t *testing.T) {
+	private := testKeyPair(t)
+	sealed, err := Seal(private.PublicKey(), "Line one\n\"quoted\" line two")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	export, _ := json.Marshal([]map[string]string{{"entry_date": "2024-05-06", "raw_content": sealed}})
+	got, err := DecryptText(private, string(export), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []map[string]string
+	if err := json.Unmarshal([]byte(got), &entries); err != nil {
+		t.Fatalf("decrypted export is not JSON: %v\n%s", err, got)
+	}
+	if entries[0]["raw_content"] != "Line one\n\"quoted\" line two" {
+		t.Errorf("raw_content = %q", entries[0]["raw_content"])
+	}
+}
+
+func TestUnsealed(t *testing.T) {
+	entries := []*models.Entry{{RawContent: "plain"}, {RawContent: SealedPrefix + "AAAA"}}
+	if got := Unsealed(entries); len(got) != 1 || got[0].RawContent != "plain" {
+		t.Errorf("Unsealed() = %v, want only the plain entry", got)
+	}
+}
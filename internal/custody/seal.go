@@ -0,0 +1,229 @@
+package custody
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// SealedPrefix starts every sealed segment of an entry's content
+const SealedPrefix = "sealed:v1:"
+
+// sealedRegex finds sealed segments in any text, such as a JSON export
+var sealedRegex = regexp.MustCompile(`sealed:v1:[A-Za-z0-9+/]+=*`)
+
+const (
+	// kdfIterations is the PBKDF2-HMAC-SHA256 work factor for new keys
+	kdfIterations = 600000
+	saltSize      = 16
+	keySize       = 32
+)
+
+// IsSealed reports whether entry content is sealed
+func IsSealed(content string) bool {
+	return strings.HasPrefix(content, SealedPrefix)
+}
+
+// Seal encrypts plaintext to public, so only the holder of its private key
+// can read it: an ephemeral X25519 key agrees a one-time AES-256-GCM key.
+// The result is SealedPrefix and the base64 of the ephemeral public key,
+// nonce and ciphertext.
+func Seal(public *ecdh.PublicKey, plaintext string) (string, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+	shared, err := ephemeral.ECDH(public)
+	if err != nil {
+		return "", fmt.Errorf("failed to agree key: %w", err)
+	}
+
+	aead, err := newAEAD(messageKey(shared, ephemeral.PublicKey().Bytes(), public.Bytes()))
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := append(ephemeral.PublicKey().Bytes(), nonce...)
+	sealed = aead.Seal(sealed, nonce, []byte(plaintext), nil)
+	return SealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts entry content made of one or more sealed segments, one per
+// line, joining what they hold with blank lines
+func Open(private *ecdh.PrivateKey, content string) (string, error) {
+	var parts []string
+	for _, segment := range strings.Split(content, "\n") {
+		if segment == "" {
+			continue
+		}
+		plaintext, err := openSegment(private, segment)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, plaintext)
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// DecryptText replaces every sealed segment in text with what it holds, so
+// an export of any shape can be read back. Newlines in the plaintext are
+// escaped when the segment sits inside a JSON string.
+func DecryptText(private *ecdh.PrivateKey, text string, jsonEscape bool) (string, error) {
+	var firstErr error
+	out := sealedRegex.ReplaceAllStringFunc(text, func(segment string) string {
+		plaintext, err := openSegment(private, segment)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return segment
+		}
+		if jsonEscape {
+			plaintext = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(plaintext)
+		}
+		return plaintext
+	})
+	return out, firstErr
+}
+
+func openSegment(private *ecdh.PrivateKey, segment string) (string, error) {
+	if !IsSealed(segment) {
+		return "", errors.New("content is not sealed")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(segment, SealedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("sealed content is not base64: %w", err)
+	}
+
+	const publicSize = 32
+	if len(sealed) < publicSize {
+		return "", errors.New("sealed content is too short")
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(sealed[:publicSize])
+	if err != nil {
+		return "", fmt.Errorf("invalid sealed content: %w", err)
+	}
+	shared, err := private.ECDH(ephemeral)
+	if err != nil {
+		return "", fmt.Errorf("failed to agree key: %w", err)
+	}
+
+	aead, err := newAEAD(messageKey(shared, ephemeral.Bytes(), private.PublicKey().Bytes()))
+	if err != nil {
+		return "", err
+	}
+	rest := sealed[publicSize:]
+	if len(rest) < aead.NonceSize() {
+		return "", errors.New("sealed content is too short")
+	}
+	plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("sealed content was not made for this key, or was changed")
+	}
+	return string(plaintext), nil
+}
+
+// messageKey derives a message's AES key from the agreed secret and both
+// public keys
+func messageKey(shared, ephemeral, recipient []byte) []byte {
+	h := sha256.New()
+	h.Write(shared)
+	h.Write(ephemeral)
+	h.Write(recipient)
+	return h.Sum(nil)
+}
+
+// wrap encrypts a private key under passphrase, bound to its public key
+func wrap(private *ecdh.PrivateKey, passphrase string, salt []byte, iterations int) ([]byte, error) {
+	aead, err := newAEAD(pbkdf2([]byte(passphrase), salt, iterations, keySize))
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, private.Bytes(), private.PublicKey().Bytes()), nil
+}
+
+// unwrap reverses wrap; a wrong passphrase fails authentication
+func unwrap(wrapped []byte, public []byte, passphrase string, salt []byte, iterations int) (*ecdh.PrivateKey, error) {
+	aead, err := newAEAD(pbkdf2([]byte(passphrase), salt, iterations, keySize))
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+	raw, err := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], public)
+	if err != nil {
+		return nil, errWrongPassphrase
+	}
+	return ecdh.X25519().NewPrivateKey(raw)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2 derives a keyLen-byte key from password with PBKDF2-HMAC-SHA256
+// (RFC 8018)
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	key := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	var counter [4]byte
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		key = prf.Sum(key)
+
+		t := key[len(key)-hashLen:]
+		copy(u, t)
+		for i := 2; i <= iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range u {
+				t[j] ^= u[j]
+			}
+		}
+	}
+	return key[:keyLen]
+}
+
+// Unsealed returns the entries that aren't sealed. Sealed content is never
+// given to a model.
+func Unsealed(entries []*models.Entry) []*models.Entry {
+	var unsealed []*models.Entry
+	for _, entry := range entries {
+		if !IsSealed(entry.RawContent) {
+			unsealed = append(unsealed, entry)
+		}
+	}
+	return unsealed
+}
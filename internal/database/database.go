@@ -13,30 +13,83 @@ import (
 
 type DB struct {
 	*sql.DB
+
+	// replica is the read replica Reader returns; nil without
+	// POSTGRES_REPLICA_HOST. isReplica marks the replica's own DB.
+	replica   *DB
+	isReplica bool
+	// queryTimeout and slowQuery are DB_QUERY_TIMEOUT and DB_SLOW_QUERY
+	queryTimeout time.Duration
+	slowQuery    time.Duration
 }
 
+// New opens a pool sized by the DB_* settings. Point POSTGRES_HOST at an RDS
+// Proxy endpoint (with POSTGRES_SSLMODE=require) to share connections
+// between Lambda containers. With POSTGRES_REPLICA_HOST a second pool, of
+// the same size, is opened to the replica.
 func New(cfg *config.Config) (*DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		cfg.PostgresHost, cfg.PostgresPort, cfg.PostgresUser, cfg.PostgresPassword, cfg.PostgresDB)
+	db, err := open(cfg, DSN(cfg))
+	if err != nil {
+		return nil, err
+	}
+	logrus.Info("Database connection established")
 
+	if cfg.PostgresReplicaHost != "" {
+		replica, err := open(cfg, replicaDSN(cfg))
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("read replica: %w", err)
+		}
+		replica.isReplica = true
+		db.replica = replica
+		logrus.WithField("host", cfg.PostgresReplicaHost).Info("Read replica connection established")
+	}
+	return db, nil
+}
+
+func open(cfg *config.Config, dsn string) (*DB, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
 
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	logrus.Info("Database connection established")
-	return &DB{db}, nil
+	return &DB{DB: db, queryTimeout: cfg.DBQueryTimeout, slowQuery: cfg.DBSlowQuery}, nil
 }
 
+// DSN is the connection string for the configured database
+func DSN(cfg *config.Config) string {
+	return dsn(cfg, cfg.PostgresHost)
+}
+
+// replicaDSN is the connection string for the read replica
+func replicaDSN(cfg *config.Config) string {
+	return dsn(cfg, cfg.PostgresReplicaHost)
+}
+
+func dsn(cfg *config.Config, host string) string {
+	sslMode := cfg.PostgresSSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		host, cfg.PostgresPort, cfg.PostgresUser, cfg.PostgresPassword, cfg.PostgresDB, sslMode)
+}
+
+// Close closes the pool and the replica's
 func (db *DB) Close() error {
+	if db.replica != nil {
+		db.replica.Close()
+	}
 	return db.DB.Close()
 }
 
@@ -112,10 +165,557 @@ func (db *DB) RunMigrations() error {
 		CREATE INDEX IF NOT EXISTS idx_email_logs_user ON email_logs(user_id);
 		CREATE INDEX IF NOT EXISTS idx_email_logs_type_date ON email_logs(email_type, created_at);
 		CREATE INDEX IF NOT EXISTS idx_email_logs_retry ON email_logs(status, retry_count, created_at);`,
+
+		`-- User signup status (double opt-in)
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS signup_status VARCHAR(30) NOT NULL DEFAULT 'pending_verification';
+		UPDATE users SET signup_status = 'active' WHERE is_verified = TRUE AND signup_status = 'pending_verification';`,
+
+		`-- User week start preference
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS week_start VARCHAR(10) NOT NULL DEFAULT 'monday';`,
+
+		`-- User delivery channel and chat channel identities
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS delivery_channel VARCHAR(20) NOT NULL DEFAULT 'email';
+		CREATE TABLE IF NOT EXISTS user_channels (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			channel VARCHAR(20) NOT NULL,
+			webhook_url TEXT,
+			external_user_id VARCHAR(255),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_user_channels_user_channel ON user_channels(user_id, channel);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_user_channels_external ON user_channels(channel, external_user_id);`,
+
+		`-- Email suppressions and broadcast audit log
+		CREATE TABLE IF NOT EXISTS email_suppressions (
+			id SERIAL PRIMARY KEY,
+			email VARCHAR(255) UNIQUE NOT NULL,
+			reason VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS broadcasts (
+			id SERIAL PRIMARY KEY,
+			subject VARCHAR(500) NOT NULL,
+			template_name VARCHAR(255) NOT NULL,
+			body_template TEXT NOT NULL,
+			recipient_count INTEGER NOT NULL DEFAULT 0,
+			dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+			sent_by VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_broadcasts_created ON broadcasts(created_at);`,
+
+		`-- Webhook endpoints and deliveries
+		CREATE TABLE IF NOT EXISTS webhook_endpoints (
+			id SERIAL PRIMARY KEY,
+			url TEXT NOT NULL,
+			secret VARCHAR(64) NOT NULL,
+			event_types TEXT[] NOT NULL,
+			is_active BOOLEAN DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id SERIAL PRIMARY KEY,
+			endpoint_id INTEGER NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+			event_type VARCHAR(50) NOT NULL,
+			payload JSON NOT NULL,
+			status VARCHAR(20) DEFAULT 'pending',
+			attempts INTEGER DEFAULT 0,
+			response_status INTEGER,
+			error_message TEXT,
+			next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			delivered_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries(status, next_attempt_at);
+		CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint ON webhook_deliveries(endpoint_id, created_at);`,
+
+		`-- Weekly summary CC recipients
+		CREATE TABLE IF NOT EXISTS summary_cc_recipients (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			email VARCHAR(255) NOT NULL,
+			confirmation_code VARCHAR(10) NOT NULL,
+			confirmed_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_summary_cc_user_email ON summary_cc_recipients(user_id, email);
+		CREATE INDEX IF NOT EXISTS idx_summary_cc_email ON summary_cc_recipients(email);
+		ALTER TABLE email_logs ADD COLUMN IF NOT EXISTS cc_emails TEXT[];`,
+
+		`-- User entry format preference
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS entry_format VARCHAR(20) NOT NULL DEFAULT 'freeform';`,
+
+		`-- User summary voice preference
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS summary_voice VARCHAR(20) NOT NULL DEFAULT 'coach';`,
+
+		`-- Quotes, quote deliveries and per-user opt-out
+		CREATE TABLE IF NOT EXISTS quotes (
+			id SERIAL PRIMARY KEY,
+			text TEXT NOT NULL,
+			author VARCHAR(255),
+			submitted_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			is_active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_quotes_text ON quotes(text);
+		CREATE TABLE IF NOT EXISTS quote_deliveries (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			quote_id INTEGER NOT NULL REFERENCES quotes(id) ON DELETE CASCADE,
+			shown_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_quote_deliveries_user_shown ON quote_deliveries(user_id, shown_at);
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS quotes_enabled BOOLEAN NOT NULL DEFAULT TRUE;
+		INSERT INTO quotes (text, author) VALUES
+			('The way to get started is to quit talking and begin doing.', 'Walt Disney'),
+			('Innovation distinguishes between a leader and a follower.', 'Steve Jobs'),
+			('Your limitation—it''s only your imagination.', NULL),
+			('Push yourself, because no one else is going to do it for you.', NULL),
+			('Great things never come from comfort zones.', NULL),
+			('Dream it. Wish it. Do it.', NULL),
+			('Success doesn''t just find you. You have to go out and get it.', NULL),
+			('The harder you work for something, the greater you''ll feel when you achieve it.', NULL),
+			('Don''t stop when you''re tired. Stop when you''re done.', NULL),
+			('Wake up with determination. Go to bed with satisfaction.', NULL)
+		ON CONFLICT (text) DO NOTHING;`,
+		`
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS reply_token VARCHAR(32) NOT NULL DEFAULT substr(md5(random()::text || clock_timestamp()::text), 1, 16);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_users_reply_token ON users(reply_token);
+		ALTER TABLE email_logs ADD COLUMN IF NOT EXISTS reply_to VARCHAR(255);`,
+		`
+		CREATE TABLE IF NOT EXISTS prompt_sends (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			prompt_date DATE NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, prompt_date)
+		);`,
+		`
+		CREATE TABLE IF NOT EXISTS api_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL DEFAULT '',
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			last_used_at TIMESTAMP,
+			revoked_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id);`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS compare_weeks BOOLEAN NOT NULL DEFAULT TRUE;`,
+		`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'email_logs' AND column_name = 'priority') THEN
+				ALTER TABLE email_logs ADD COLUMN priority SMALLINT NOT NULL DEFAULT 1;
+				UPDATE email_logs SET priority = 2
+				WHERE email_type IN ('verification', 'clarification', 'confirmation', 'data_report', 'schedule_update', 'cc_request');
+				UPDATE email_logs SET priority = 0
+				WHERE email_type IN ('weekly_summary', 'announcement');
+			END IF;
+		END $$;
+		CREATE INDEX IF NOT EXISTS idx_email_logs_priority ON email_logs(status, priority, created_at);`,
+		`
+		ALTER TABLE entries ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+		CREATE INDEX IF NOT EXISTS idx_entries_deleted_at ON entries(deleted_at) WHERE deleted_at IS NOT NULL;`,
+		`
+		CREATE TABLE IF NOT EXISTS clarification_state (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			thread_key VARCHAR(255) NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, thread_key)
+		);`,
+		`
+		CREATE MATERIALIZED VIEW IF NOT EXISTS analytics_daily_active_repliers AS
+		SELECT entry_date AS day, COUNT(DISTINCT user_id) AS repliers
+		FROM entries
+		WHERE deleted_at IS NULL
+		GROUP BY entry_date;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_analytics_daily_active_repliers_day ON analytics_daily_active_repliers(day);
+		CREATE MATERIALIZED VIEW IF NOT EXISTS analytics_reply_latency AS
+		SELECT
+			date_trunc('week', p.created_at)::date AS week_start,
+			COUNT(*) AS replies,
+			COUNT(*) FILTER (WHERE e.created_at - p.created_at < INTERVAL '1 hour') AS under_1h,
+			COUNT(*) FILTER (WHERE e.created_at - p.created_at >= INTERVAL '1 hour' AND e.created_at - p.created_at < INTERVAL '4 hours') AS from_1h_to_4h,
+			COUNT(*) FILTER (WHERE e.created_at - p.created_at >= INTERVAL '4 hours' AND e.created_at - p.created_at < INTERVAL '12 hours') AS from_4h_to_12h,
+			COUNT(*) FILTER (WHERE e.created_at - p.created_at >= INTERVAL '12 hours' AND e.created_at - p.created_at < INTERVAL '24 hours') AS from_12h_to_24h,
+			COUNT(*) FILTER (WHERE e.created_at - p.created_at >= INTERVAL '24 hours') AS over_24h,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM e.created_at - p.created_at) / 60) AS p50_minutes,
+			percentile_cont(0.9) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM e.created_at - p.created_at) / 60) AS p90_minutes
+		FROM entries e
+		JOIN LATERAL (
+			SELECT created_at FROM prompt_sends
+			WHERE user_id = e.user_id AND created_at <= e.created_at
+			ORDER BY created_at DESC
+			LIMIT 1
+		) p ON TRUE
+		WHERE e.deleted_at IS NULL
+		GROUP BY 1;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_analytics_reply_latency_week ON analytics_reply_latency(week_start);
+		CREATE MATERIALIZED VIEW IF NOT EXISTS analytics_cohort_retention AS
+		WITH cohorts AS (
+			SELECT id AS user_id, date_trunc('week', created_at)::date AS cohort_week
+			FROM users
+			WHERE is_verified AND created_at IS NOT NULL
+		),
+		sizes AS (
+			SELECT cohort_week, COUNT(*) AS cohort_size FROM cohorts GROUP BY cohort_week
+		),
+		activity AS (
+			SELECT DISTINCT user_id, date_trunc('week', entry_date)::date AS active_week
+			FROM entries
+			WHERE deleted_at IS NULL
+		)
+		SELECT
+			c.cohort_week,
+			(a.active_week - c.cohort_week) / 7 AS week_number,
+			s.cohort_size,
+			COUNT(*) AS active_users
+		FROM cohorts c
+		JOIN activity a ON a.user_id = c.user_id AND a.active_week >= c.cohort_week
+		JOIN sizes s ON s.cohort_week = c.cohort_week
+		GROUP BY c.cohort_week, a.active_week, s.cohort_size;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_analytics_cohort_retention_week ON analytics_cohort_retention(cohort_week, week_number);`,
+		`
+		CREATE TABLE IF NOT EXISTS job_runs (
+			id SERIAL PRIMARY KEY,
+			job_name VARCHAR(100) NOT NULL,
+			triggered_by VARCHAR(20) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'running',
+			error_message TEXT,
+			duration_ms INTEGER,
+			started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			finished_at TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_job_runs_name_started ON job_runs(job_name, started_at DESC);
+		CREATE TABLE IF NOT EXISTS job_settings (
+			job_name VARCHAR(100) PRIMARY KEY,
+			enabled BOOLEAN NOT NULL,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`-- Mentors receiving a monthly digest
+		CREATE TABLE IF NOT EXISTS mentors (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+			email VARCHAR(255) NOT NULL,
+			confirmation_code VARCHAR(10) NOT NULL,
+			confirmed_at TIMESTAMP,
+			last_digest_month DATE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_mentors_email ON mentors(email);`,
+
+		`-- Full-text search over entries for <ask>
+		CREATE INDEX IF NOT EXISTS idx_entries_search ON entries USING GIN (to_tsvector('english', raw_content)) WHERE deleted_at IS NULL;`,
+
+		`-- Entry embeddings, only where pgvector is installed; semantic search
+		-- stays off without it
+		DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector') THEN
+				CREATE EXTENSION IF NOT EXISTS vector;
+				CREATE TABLE IF NOT EXISTS entry_embeddings (
+					entry_id INTEGER PRIMARY KEY REFERENCES entries(id) ON DELETE CASCADE,
+					user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					model VARCHAR(100) NOT NULL,
+					content_hash VARCHAR(32) NOT NULL,
+					embedding vector(1024) NOT NULL,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE INDEX IF NOT EXISTS idx_entry_embeddings_user_id ON entry_embeddings(user_id);
+				CREATE INDEX IF NOT EXISTS idx_entry_embeddings_embedding ON entry_embeddings USING hnsw (embedding vector_cosine_ops);
+			END IF;
+		END $$;`,
+
+		`-- Date ranges and holiday calendars without prompts
+		CREATE TABLE IF NOT EXISTS user_blackouts (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			start_date DATE,
+			end_date DATE,
+			country VARCHAR(2),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK ((country IS NULL) = (start_date IS NOT NULL AND end_date IS NOT NULL AND end_date >= start_date))
+		);
+		CREATE INDEX IF NOT EXISTS idx_user_blackouts_user_id ON user_blackouts(user_id);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_user_blackouts_country ON user_blackouts(user_id) WHERE country IS NOT NULL;`,
+
+		`-- Dashboard magic links and sessions
+		CREATE TABLE IF NOT EXISTS web_logins (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_web_logins_user_id ON web_logins(user_id);
+		CREATE TABLE IF NOT EXISTS web_sessions (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			expires_at TIMESTAMP NOT NULL,
+			last_seen_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_web_sessions_user_id ON web_sessions(user_id);`,
+
+		`-- Project history and quarterly project rollups
+		CREATE TABLE IF NOT EXISTS user_projects (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			started_on DATE NOT NULL,
+			ended_on DATE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK (ended_on IS NULL OR ended_on > started_on)
+		);
+		CREATE INDEX IF NOT EXISTS idx_user_projects_user_id ON user_projects(user_id);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_user_projects_current ON user_projects(user_id) WHERE ended_on IS NULL;
+		INSERT INTO user_projects (user_id, name, started_on)
+		SELECT id, project_focus, created_at::date FROM users u
+		WHERE project_focus IS NOT NULL AND project_focus <> ''
+		  AND NOT EXISTS (SELECT 1 FROM user_projects p WHERE p.user_id = u.id);
+		CREATE TABLE IF NOT EXISTS project_rollups (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			project VARCHAR(255) NOT NULL,
+			quarter_start DATE NOT NULL,
+			first_entry_date DATE NOT NULL,
+			last_entry_date DATE NOT NULL,
+			summary_paragraph TEXT NOT NULL,
+			bullet_points JSON NOT NULL,
+			llm_model VARCHAR(100) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_project_rollups_user_project_quarter ON project_rollups(user_id, project, quarter_start);`,
+
+		`-- Inbound webhook rate limiting
+		CREATE TABLE IF NOT EXISTS inbound_requests (
+			id BIGSERIAL PRIMARY KEY,
+			sender VARCHAR(255) NOT NULL,
+			received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_inbound_requests_sender_received ON inbound_requests(sender, received_at);`,
+
+		`ALTER TABLE weekly_summaries ADD COLUMN IF NOT EXISTS card_url TEXT;`,
+
+		`-- LLM call log
+		CREATE TABLE IF NOT EXISTS llm_calls (
+			id BIGSERIAL PRIMARY KEY,
+			model VARCHAR(255) NOT NULL,
+			input_tokens INTEGER NOT NULL DEFAULT 0,
+			output_tokens INTEGER NOT NULL DEFAULT 0,
+			duration_ms BIGINT NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_llm_calls_created_at ON llm_calls(created_at);`,
+		`
+		CREATE TABLE IF NOT EXISTS integration_credentials (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			integration VARCHAR(50) NOT NULL,
+			key_id VARCHAR(50) NOT NULL,
+			ciphertext BYTEA NOT NULL,
+			rotated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, integration)
+		);
+		CREATE INDEX IF NOT EXISTS idx_integration_credentials_key_id ON integration_credentials(key_id);`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS weekly_goals BOOLEAN NOT NULL DEFAULT FALSE;`,
+		`
+		CREATE TABLE IF NOT EXISTS weekly_goals (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			week_start_date DATE NOT NULL,
+			goals TEXT[] NOT NULL DEFAULT '{}',
+			prompted_at TIMESTAMP,
+			replied_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, week_start_date)
+		);`,
+		`
+		CREATE TABLE IF NOT EXISTS signup_wizards (
+			user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			step VARCHAR(20) NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS language VARCHAR(10) NOT NULL DEFAULT 'en';`,
+		`
+		CREATE TABLE IF NOT EXISTS job_schedule (
+			job_name VARCHAR(100) PRIMARY KEY,
+			last_fired_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS summary_language VARCHAR(10) NOT NULL DEFAULT 'auto';`,
+		`
+		ALTER TABLE email_logs ADD COLUMN IF NOT EXISTS body_html TEXT;
+		ALTER TABLE email_logs ADD COLUMN IF NOT EXISTS tracking_token VARCHAR(32);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_email_logs_tracking_token ON email_logs(tracking_token) WHERE tracking_token IS NOT NULL;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS email_tracking BOOLEAN NOT NULL DEFAULT TRUE;
+		CREATE TABLE IF NOT EXISTS email_events (
+			id SERIAL PRIMARY KEY,
+			email_log_id INTEGER NOT NULL REFERENCES email_logs(id) ON DELETE CASCADE,
+			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			event_type VARCHAR(20) NOT NULL,
+			url TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_email_events_email ON email_events(email_log_id);
+		CREATE INDEX IF NOT EXISTS idx_email_events_type_date ON email_events(event_type, created_at);`,
+		`
+		CREATE TABLE IF NOT EXISTS admin_actions (
+			id SERIAL PRIMARY KEY,
+			action VARCHAR(50) NOT NULL,
+			actor VARCHAR(255) NOT NULL,
+			user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			details JSONB,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_admin_actions_user ON admin_actions(user_id, created_at);`,
+		`ALTER TABLE entries ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;`,
+		`
+		CREATE TABLE IF NOT EXISTS inbound_messages (
+			id BIGSERIAL PRIMARY KEY,
+			source VARCHAR(20) NOT NULL,
+			message_id VARCHAR(255),
+			s3_bucket VARCHAR(255),
+			s3_key VARCHAR(1024),
+			payload TEXT NOT NULL,
+			sender VARCHAR(255),
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			error_message TEXT,
+			received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			processed_at TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_inbound_messages_status ON inbound_messages(status, received_at);`,
+		`
+		CREATE TABLE IF NOT EXISTS organizations (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL UNIQUE,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL;
+		CREATE INDEX IF NOT EXISTS idx_users_organization ON users(organization_id);
+		ALTER TABLE llm_calls ADD COLUMN IF NOT EXISTS user_id INTEGER;
+		ALTER TABLE llm_calls ADD COLUMN IF NOT EXISTS cost_cents INTEGER NOT NULL DEFAULT 0;
+		CREATE INDEX IF NOT EXISTS idx_llm_calls_user_created ON llm_calls(user_id, created_at);
+		CREATE TABLE IF NOT EXISTS usage_records (
+			id BIGSERIAL PRIMARY KEY,
+			organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+			metric VARCHAR(50) NOT NULL,
+			usage_date DATE NOT NULL,
+			quantity BIGINT NOT NULL,
+			action VARCHAR(20) NOT NULL,
+			idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+			reported_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(organization_id, metric, usage_date)
+		);
+		CREATE INDEX IF NOT EXISTS idx_usage_records_unreported ON usage_records(usage_date) WHERE reported_at IS NULL;`,
+		`
+		ALTER TABLE organizations ADD COLUMN IF NOT EXISTS billing_email VARCHAR(255);
+		ALTER TABLE organizations ADD COLUMN IF NOT EXISTS stripe_customer_id VARCHAR(255) UNIQUE;
+		ALTER TABLE organizations ADD COLUMN IF NOT EXISTS stripe_subscription_id VARCHAR(255);
+		ALTER TABLE organizations ADD COLUMN IF NOT EXISTS subscription_status VARCHAR(50) NOT NULL DEFAULT 'none';
+		ALTER TABLE organizations ADD COLUMN IF NOT EXISTS grace_until TIMESTAMP;
+		ALTER TABLE organizations ADD COLUMN IF NOT EXISTS dunning_stage INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE organizations ADD COLUMN IF NOT EXISTS invoice_url TEXT;
+		ALTER TABLE organizations ADD COLUMN IF NOT EXISTS billing_updated_at TIMESTAMP;
+		CREATE TABLE IF NOT EXISTS stripe_events (
+			id VARCHAR(255) PRIMARY KEY,
+			type VARCHAR(100) NOT NULL,
+			received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`
+		ALTER TABLE organizations ADD COLUMN IF NOT EXISTS summary_period_weeks INTEGER;
+		ALTER TABLE organizations ADD COLUMN IF NOT EXISTS summary_period_end DATE;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS summary_period_weeks INTEGER;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS summary_period_end DATE;`,
+		`
+		CREATE TABLE IF NOT EXISTS user_keys (
+			user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			public_key BYTEA NOT NULL,
+			salt BYTEA NOT NULL,
+			iterations INTEGER NOT NULL,
+			wrapped_key BYTEA NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS self_custody_at TIMESTAMP;`,
+		`
+		ALTER TABLE user_channels ADD COLUMN IF NOT EXISTS import_channel VARCHAR(64);
+		CREATE TABLE IF NOT EXISTS channel_messages (
+			channel VARCHAR(20) NOT NULL,
+			message_id VARCHAR(255) NOT NULL,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (channel, message_id)
+		);`,
+
+		`-- Filtering entries by project
+		CREATE INDEX IF NOT EXISTS idx_entries_user_project ON entries(user_id, LOWER(project_tag), entry_date) WHERE deleted_at IS NULL;`,
+		`
+		CREATE TABLE IF NOT EXISTS email_changes (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			old_email VARCHAR(255) NOT NULL,
+			new_email VARCHAR(255) NOT NULL,
+			confirmation_code VARCHAR(10) NOT NULL,
+			requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			confirmed_at TIMESTAMP,
+			alias_until TIMESTAMP
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_email_changes_pending ON email_changes(user_id) WHERE confirmed_at IS NULL;
+		CREATE INDEX IF NOT EXISTS idx_email_changes_new ON email_changes(LOWER(new_email)) WHERE confirmed_at IS NULL;
+		CREATE INDEX IF NOT EXISTS idx_email_changes_alias ON email_changes(LOWER(old_email), alias_until) WHERE confirmed_at IS NOT NULL;`,
+		`
+		CREATE TABLE IF NOT EXISTS user_emails (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			email VARCHAR(255) NOT NULL,
+			verification_code VARCHAR(10) NOT NULL,
+			verified_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_user_emails_user ON user_emails(user_id, LOWER(email));
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_user_emails_verified ON user_emails(LOWER(email)) WHERE verified_at IS NOT NULL;`,
+		`
+		ALTER TABLE email_logs ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMP;
+		CREATE INDEX IF NOT EXISTS idx_email_logs_claimed ON email_logs(claimed_at) WHERE status = 'sending';`,
+		`
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS prompt_threading VARCHAR(10) NOT NULL DEFAULT 'daily';`,
+		`
+		CREATE TABLE IF NOT EXISTS summary_feeds (
+			user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			last_fetched_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`
+		UPDATE webhook_endpoints SET event_types = array_replace(event_types, 'email.bounced', 'email.rejected');`,
 	}
 
+	// Migrations can outlast DB_QUERY_TIMEOUT, building indexes on large
+	// tables, so they bypass it
 	for i, migration := range migrations {
-		if _, err := db.Exec(migration); err != nil {
+		if _, err := db.DB.Exec(migration); err != nil {
 			return fmt.Errorf("failed to run migration %d: %w", i+1, err)
 		}
 	}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Reader returns the read replica for heavy reads that can stand to be a
+// little behind, such as analytics, exports and search, or db itself when
+// there is no replica. Anything that reads its own writes stays on db.
+func (db *DB) Reader() *DB {
+	if db.replica != nil {
+		return db.replica
+	}
+	return db
+}
+
+// bound gives ctx DB_QUERY_TIMEOUT when it has no deadline of its own, so a
+// query run from a background context can't hang forever. A caller that
+// expects a statement to run longer sets its own deadline.
+func (db *DB) bound(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, db.queryTimeout)
+}
+
+// logSlow logs query if it has taken DB_SLOW_QUERY or longer since start
+func (db *DB) logSlow(query string, start time.Time) {
+	if db.slowQuery <= 0 {
+		return
+	}
+	elapsed := time.Since(start)
+	if elapsed < db.slowQuery {
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"duration": elapsed.String(),
+		"replica":  db.isReplica,
+		"query":    strings.Join(strings.Fields(query), " "),
+	}).Warn("Slow query")
+}
+
+// ExecContext runs a statement under the query timeout and slow-query log
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := db.bound(ctx)
+	defer cancel()
+	defer db.logSlow(query, time.Now())
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs a query under the query timeout, logging it if its
+// first rows are slow to arrive. The rows outlive this call, so the timeout
+// is released by its own timer rather than on return.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, _ = db.bound(ctx)
+	defer db.logSlow(query, time.Now())
+	return db.DB.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext is QueryContext for one row
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, _ = db.bound(ctx)
+	defer db.logSlow(query, time.Now())
+	return db.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBound(t *testing.T) {
+	db := &DB{queryTimeout: time.Minute}
+
+	ctx, cancel := db.bound(context.Background())
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Minute {
+		t.Errorf("bound(Background) deadline = %v, %v, want within a minute", deadline, ok)
+	}
+
+	// A caller's own deadline wins, even a longer one
+	long, cancelLong := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelLong()
+	ctx, cancel = db.bound(long)
+	defer cancel()
+	if ctx != long {
+		t.Error("bound() replaced the caller's deadline")
+	}
+
+	// No DB_QUERY_TIMEOUT leaves ctx alone
+	ctx, cancel = (&DB{}).bound(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("bound() without a timeout set a deadline")
+	}
+}
+
+func TestReader(t *testing.T) {
+	primary := &DB{}
+	if primary.Reader() != primary {
+		t.Error("Reader() without a replica isn't the primary")
+	}
+
+	replica := &DB{isReplica: true}
+	primary.replica = replica
+	if primary.Reader() != replica {
+		t.Error("Reader() with a replica isn't the replica")
+	}
+}
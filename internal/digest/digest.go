@@ -0,0 +1,68 @@
+// Package digest condenses a month of a user's weekly summaries into the
+// digest their mentor receives: one highlight and a couple of bullets per
+// week, short enough to read in a minute.
+package digest
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// maxBullets is how many of a week's bullet points the digest keeps
+const maxBullets = 2
+
+// Week is one week of a digest
+type Week struct {
+	WeekStart time.Time
+	Highlight string
+	Bullets   []string
+}
+
+// Build condenses summaries into one Week each, oldest first. Each keeps the
+// first sentence of the summary paragraph and the first maxBullets bullets.
+func Build(summaries []*models.WeeklySummary) []Week {
+	weeks := make([]Week, 0, len(summaries))
+	for _, summary := range summaries {
+		bullets := []string(summary.BulletPoints)
+		if len(bullets) > maxBullets {
+			bullets = bullets[:maxBullets]
+		}
+		weeks = append(weeks, Week{
+			WeekStart: summary.WeekStartDate,
+			Highlight: firstSentence(summary.SummaryParagraph),
+			Bullets:   bullets,
+		})
+	}
+
+	sort.Slice(weeks, func(i, j int) bool { return weeks[i].WeekStart.Before(weeks[j].WeekStart) })
+	return weeks
+}
+
+// MonthStart returns the first day of t's month, in UTC
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// PreviousMonth returns the first day of the month before now's, the month a
+// digest sent on the 1st covers
+func PreviousMonth(now time.Time) time.Time {
+	return MonthStart(now).AddDate(0, -1, 0)
+}
+
+// firstSentence returns text up to and including its first sentence end
+func firstSentence(text string) string {
+	text = strings.TrimSpace(text)
+	for i, r := range text {
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+		if i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\n' {
+			return text[:i+1]
+		}
+	}
+	return text
+}
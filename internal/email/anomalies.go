@@ -0,0 +1,42 @@
+package email
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/events"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// ReportAnomalies logs a non-empty anomaly report, publishes it as
+// AnomaliesDetected for webhooks, and emails it to ADMIN_ALERT_EMAIL
+func (s *Service) ReportAnomalies(ctx context.Context, report *models.AnomalyReport) error {
+	if report.Count() == 0 {
+		logrus.Info("No anomalies found")
+		return nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"bouncing_users":    len(report.BouncingUsers),
+		"missing_summaries": len(report.MissingSummaries),
+		"unsent_summaries":  len(report.UnsentSummaries),
+		"failure_spikes":    len(report.FailureSpikes),
+	}).Warn("Anomalies found")
+
+	if s.events != nil {
+		if err := s.events.Publish(ctx, events.New(events.AnomaliesDetected, report)); err != nil {
+			logrus.WithError(err).Warn("Failed to publish anomalies event")
+		}
+	}
+
+	if s.config.AdminAlertEmail == "" {
+		return nil
+	}
+	subject, body, err := RenderAnomalyReportEmail(report)
+	if err != nil {
+		return fmt.Errorf("failed to render anomaly report: %w", err)
+	}
+	return s.QueueEmail(ctx, nil, s.config.AdminAlertEmail, models.EmailTypeAdminAlert, subject, body, nil)
+}
@@ -0,0 +1,188 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/s3"
+	pkgConfig "github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// archive keeps a record of sent email for organizations that must retain
+// what they send: a BCC to a compliance mailbox, a copy in an S3 bucket, or
+// both
+type archive struct {
+	bcc     string
+	client  *s3.Client
+	bucket  string
+	prefix  string
+	include map[string]bool
+	exclude map[string]bool
+}
+
+// newArchive returns nil when neither ARCHIVE_BCC nor ARCHIVE_BUCKET is set,
+// which turns archiving off
+func newArchive(ctx context.Context, cfg *pkgConfig.Config) (*archive, error) {
+	if cfg.ArchiveBCC == "" && cfg.ArchiveBucket == "" {
+		return nil, nil
+	}
+
+	include, err := emailTypeSet("ARCHIVE_EMAIL_TYPES", cfg.ArchiveEmailTypes)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := emailTypeSet("ARCHIVE_EXCLUDE_EMAIL_TYPES", cfg.ArchiveExcludeEmailTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &archive{
+		bcc:     cfg.ArchiveBCC,
+		bucket:  cfg.ArchiveBucket,
+		prefix:  cfg.ArchivePrefix,
+		include: include,
+		exclude: exclude,
+	}
+	if a.prefix != "" && !strings.HasSuffix(a.prefix, "/") {
+		a.prefix += "/"
+	}
+	if cfg.ArchiveBucket != "" {
+		if a.client, err = s3.NewClient(ctx, cfg.AWSRegion); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// emailTypeSet checks the types listed in the env var name, so a typo can't
+// quietly leave mail out of the archive
+func emailTypeSet(name string, emailTypes []string) (map[string]bool, error) {
+	set := make(map[string]bool, len(emailTypes))
+	for _, emailType := range emailTypes {
+		if !slices.Contains(models.EmailTypes, emailType) {
+			return nil, fmt.Errorf("%s: unknown email type %q", name, emailType)
+		}
+		set[emailType] = true
+	}
+	return set, nil
+}
+
+// covers reports whether emails of emailType are archived: every type when
+// no include list is set, less the excluded ones
+func (a *archive) covers(emailType string) bool {
+	if a == nil || a.exclude[emailType] {
+		return false
+	}
+	return len(a.include) == 0 || a.include[emailType]
+}
+
+// bccAddresses returns the archive mailbox for an email it covers
+func (a *archive) bccAddresses(emailType string) []string {
+	if !a.covers(emailType) || a.bcc == "" {
+		return nil
+	}
+	return []string{a.bcc}
+}
+
+// store uploads a sent email covered by the archive to
+// <prefix><yyyy>/<mm>/<dd>/<email id>.eml, dated by when it was sent
+func (a *archive) store(ctx context.Context, email *models.EmailLog, from, subject, messageID string, sentAt time.Time) error {
+	if !a.covers(email.EmailType) || a.client == nil {
+		return nil
+	}
+
+	message, err := archiveMessage(email, from, a.bccAddresses(email.EmailType), subject, messageID, sentAt)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(message)
+	err = a.client.PutObject(ctx, a.bucket, a.key(email.ID, sentAt), bytes.NewReader(message), int64(len(message)), hex.EncodeToString(hash[:]), "message/rfc822")
+	if err != nil {
+		return fmt.Errorf("failed to archive email: %w", err)
+	}
+	return nil
+}
+
+func (a *archive) key(emailID int, sentAt time.Time) string {
+	return a.prefix + sentAt.UTC().Format("2006/01/02/") + strconv.Itoa(emailID) + ".eml"
+}
+
+// archiveMessage renders email as an RFC 822 message with the headers it was
+// sent with, its text part and, for tracked emails, its HTML part
+func archiveMessage(email *models.EmailLog, from string, bcc []string, subject, messageID string, sentAt time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+		}
+	}
+
+	header("From", from)
+	header("To", email.RecipientEmail)
+	header("Cc", strings.Join(email.CCEmails, ", "))
+	header("Bcc", strings.Join(bcc, ", "))
+	header("Reply-To", strings.Join(replyToAddresses(email.ReplyTo), ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", sentAt.UTC().Format(time.RFC1123Z))
+	header("X-SES-Message-ID", messageID)
+	header("X-Email-Type", email.EmailType)
+	header("MIME-Version", "1.0")
+
+	if email.BodyHTML == nil {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, email.BodyText); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", email.BodyText},
+		{"text/html; charset=utf-8", *email.BodyHTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to build archived email: %w", err)
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build archived email: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return fmt.Errorf("failed to build archived email: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return fmt.Errorf("failed to build archived email: %w", err)
+	}
+	return nil
+}
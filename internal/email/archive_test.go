@@ -0,0 +1,170 @@
+package email
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+func TestArchiveCovers(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    map[string]bool
+	}{
+		{
+			name: "every type by default",
+			want: map[string]bool{models.EmailTypeDailyPrompt: true, models.EmailTypeMagicLink: true},
+		},
+		{
+			name:    "include list",
+			include: []string{models.EmailTypeWeeklySummary},
+			want:    map[string]bool{models.EmailTypeWeeklySummary: true, models.EmailTypeDailyPrompt: false},
+		},
+		{
+			name:    "exclude list",
+			exclude: []string{models.EmailTypeMagicLink},
+			want:    map[string]bool{models.EmailTypeDailyPrompt: true, models.EmailTypeMagicLink: false},
+		},
+		{
+			name:    "exclude wins over include",
+			include: []string{models.EmailTypeMagicLink, models.EmailTypeBilling},
+			exclude: []string{models.EmailTypeMagicLink},
+			want:    map[string]bool{models.EmailTypeBilling: true, models.EmailTypeMagicLink: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			include, err := emailTypeSet("include", tt.include)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exclude, err := emailTypeSet("exclude", tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := &archive{bcc: "archive@example.com", include: include, exclude: exclude}
+
+			for emailType, want := range tt.want {
+				if got := a.covers(emailType); got != want {
+					t.Errorf("covers(%q) = %v, want %v", emailType, got, want)
+				}
+				if got := len(a.bccAddresses(emailType)) == 1; got != want {
+					t.Errorf("bccAddresses(%q) set = %v, want %v", emailType, got, want)
+				}
+			}
+		})
+	}
+
+	var off *archive
+	if off.covers(models.EmailTypeDailyPrompt) || off.bccAddresses(models.EmailTypeDailyPrompt) != nil {
+		t.Error("a nil archive should cover nothing")
+	}
+}
+
+func TestEmailTypeSetRejectsUnknownTypes(t *testing.T) {
+	if _, err := emailTypeSet("ARCHIVE_EMAIL_TYPES", []string{"weekly_sumary"}); err == nil {
+		t.Error("emailTypeSet() error = nil, want an error for an unknown type")
+	}
+}
+
+func TestArchiveKey(t *testing.T) {
+	a := &archive{prefix: "sent/"}
+	sentAt := time.Date(2024, 5, 6, 23, 30, 0, 0, time.FixedZone("PDT", -7*60*60))
+	if got, want := a.key(42, sentAt), "sent/2024/05/07/42.eml"; got != want {
+		t.Errorf("key() = %q, want %q", got, want)
+	}
+}
+
+func TestArchiveMessage(t *testing.T) {
+	html := "<p>Shipped the importer</p>"
+	replyTo := "reply+abc@example.com"
+	sent := &models.EmailLog{
+		ID:             7,
+		RecipientEmail: "alex@example.com",
+		CCEmails:       []string{"manager@example.com"},
+		ReplyTo:        &replyTo,
+		EmailType:      models.EmailTypeWeeklySummary,
+		BodyText:       "Shipped the importer – finally",
+	}
+	sentAt := time.Date(2024, 5, 6, 17, 0, 0, 0, time.UTC)
+
+	t.Run("text", func(t *testing.T) {
+		raw, err := archiveMessage(sent, "noreply@example.com", []string{"archive@example.com"}, "Your week ✓", "msg-1", sentAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil || subject != "Your week ✓" {
+			t.Errorf("Subject = %q (%v), want %q", subject, err, "Your week ✓")
+		}
+		for name, want := range map[string]string{
+			"From":             "noreply@example.com",
+			"To":               "alex@example.com",
+			"Cc":               "manager@example.com",
+			"Bcc":              "archive@example.com",
+			"Reply-To":         replyTo,
+			"X-SES-Message-ID": "msg-1",
+			"X-Email-Type":     models.EmailTypeWeeklySummary,
+		} {
+			if got := msg.Header.Get(name); got != want {
+				t.Errorf("%s = %q, want %q", name, got, want)
+			}
+		}
+		if date, err := msg.Header.Date(); err != nil || !date.Equal(sentAt) {
+			t.Errorf("Date = %v (%v), want %v", date, err, sentAt)
+		}
+
+		body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+		if string(body) != sent.BodyText {
+			t.Errorf("body = %q, want %q", body, sent.BodyText)
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		tracked := *sent
+		tracked.BodyHTML = &html
+		raw, err := archiveMessage(&tracked, "noreply@example.com", nil, "Your week", "msg-2", sentAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		if msg.Header.Get("Bcc") != "" {
+			t.Errorf("Bcc = %q, want none", msg.Header.Get("Bcc"))
+		}
+
+		mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		if err != nil || mediaType != "multipart/alternative" {
+			t.Fatalf("Content-Type = %q (%v), want multipart/alternative", mediaType, err)
+		}
+		parts := multipart.NewReader(msg.Body, params["boundary"])
+		for _, want := range []string{sent.BodyText, html} {
+			part, err := parts.NextPart()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// NextPart decodes quoted-printable itself
+			body, _ := io.ReadAll(part)
+			if string(body) != want {
+				t.Errorf("part = %q, want %q", body, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,252 @@
+package email
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/digest"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/stats"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// update rewrites the golden files from the current templates:
+// go test ./internal/email -run TestGolden -args -update
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+// goldenCase renders one email. template is the file it renders, so every
+// template can be checked for a case.
+type goldenCase struct {
+	name     string
+	template string
+	render   func() (string, string, error)
+}
+
+func goldenCases() []goldenCase {
+	weekStart := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	weekEnd := weekStart.AddDate(0, 0, 4)
+	promptTime := time.Date(0, 1, 1, 17, 0, 0, 0, time.UTC)
+	focus := "Billing migration"
+	grace := weekStart.AddDate(0, 0, 7)
+	data := canonicalTemplateData()
+
+	trend := &stats.Trend{
+		Week: "▂▄▆▇",
+		Month: []stats.WeekEnergy{
+			{WeekStart: weekStart.AddDate(0, 0, -21), Entries: 3, Energy: 3},
+			{WeekStart: weekStart.AddDate(0, 0, -14)},
+			{WeekStart: weekStart.AddDate(0, 0, -7), Entries: 5, Energy: 4},
+			{WeekStart: weekStart, Entries: 4, Energy: 4.5},
+		},
+	}
+	lookback := &Lookback{Date: weekStart.AddDate(0, 0, -91), Excerpt: "Scoped the billing migration"}
+	bullets := []string{"Migrated invoices", "Fixed the retry bug"}
+	goals := []string{"Finish the billing migration", "Write the launch post"}
+
+	cases := []goldenCase{
+		{"welcome", "welcome.txt", func() (string, string, error) {
+			return RenderWelcomeEmail("123456")
+		}},
+		{"daily_prompt", "daily_prompt.txt", func() (string, string, error) {
+			return renderDailyPromptEmail(weekStart, nil, "", "", "", PromptVariation{})
+		}},
+		{"daily_prompt_guided", "daily_prompt.txt", func() (string, string, error) {
+			return renderDailyPromptEmail(weekStart, &focus, "standup", data.Quote, data.Recap, PromptVariation{UserID: 1})
+		}},
+		{"daily_prompt_weekly_thread", "daily_prompt.txt", func() (string, string, error) {
+			return renderDailyPromptEmail(weekStart.AddDate(0, 0, 2), nil, "", "", "", PromptVariation{UserID: 2, Threading: models.PromptThreadingWeekly})
+		}},
+		{"weekly_summary", "weekly_summary.txt", func() (string, string, error) {
+			return RenderWeeklySummaryEmail(weekStart, weekEnd, data.SummaryParagraph, bullets, nil, nil, nil, nil, "")
+		}},
+		{"weekly_summary_full", "weekly_summary.txt", func() (string, string, error) {
+			return RenderWeeklySummaryEmail(weekStart, weekEnd, data.SummaryParagraph, bullets, goals, nil, trend, lookback, data.CardURL)
+		}},
+		{"weekly_summary_sprint", "weekly_summary.txt", func() (string, string, error) {
+			return RenderWeeklySummaryEmail(weekStart, weekStart.AddDate(0, 0, 11), data.SummaryParagraph, bullets, nil, nil, nil, nil, "")
+		}},
+		{"weekly_summary_light", "weekly_summary_light.txt", func() (string, string, error) {
+			missed := []time.Time{weekStart.AddDate(0, 0, 2), weekStart.AddDate(0, 0, 3), weekStart.AddDate(0, 0, 4)}
+			return RenderWeeklySummaryEmail(weekStart, weekEnd, "Shipped the invoice export.", bullets[:1], nil, missed, nil, nil, "")
+		}},
+		{"weekly_goals", "weekly_goals.txt", func() (string, string, error) {
+			return RenderWeeklyGoalsEmail(weekStart, &focus)
+		}},
+		{"clarification", "clarification.txt", func() (string, string, error) {
+			return RenderClarificationEmail(data.OriginalMessage)
+		}},
+		{"clarification_plain", "clarification_plain.txt", func() (string, string, error) {
+			return RenderPlainTextClarificationEmail()
+		}},
+		{"clarification_alert", "clarification_alert.txt", func() (string, string, error) {
+			return RenderClarificationAlertEmail(data.UserEmail, data.Thread, data.Attempts, data.OriginalMessage)
+		}},
+		{"signup_question", "signup_question.txt", func() (string, string, error) {
+			return RenderSignupQuestionEmail(data.StepNumber, data.StepCount, "What timezone are you in?", data.Example, "")
+		}},
+		{"signup_question_retry", "signup_question.txt", func() (string, string, error) {
+			return RenderSignupQuestionEmail(data.StepNumber, data.StepCount, "What timezone are you in?", data.Example, data.Problem)
+		}},
+		{"timezone_clarification", "timezone_clarification.txt", func() (string, string, error) {
+			return RenderTimezoneClarificationEmail(data.TimezoneInput, data.TimezoneOptions, "America/Chicago")
+		}},
+		{"confirmation", "confirmation.txt", func() (string, string, error) {
+			return RenderConfirmationEmail(data.Name, data.Timezone, promptTime, &focus, "sunday")
+		}},
+		{"schedule_updated", "schedule_updated.txt", func() (string, string, error) {
+			return RenderScheduleUpdatedEmail(data.Timezone, promptTime)
+		}},
+		{"cc_request", "cc_request.txt", func() (string, string, error) {
+			return RenderSummaryCCRequestEmail(data.Name, data.RequesterEmail, "123456")
+		}},
+		{"mentor_request", "mentor_request.txt", func() (string, string, error) {
+			return RenderMentorRequestEmail(data.Name, data.RequesterEmail, "123456")
+		}},
+		{"mentor_digest", "mentor_digest.txt", func() (string, string, error) {
+			weeks := []digest.Week{
+				{WeekStart: weekStart, Highlight: "Shipped the billing migration.", Bullets: bullets},
+				{WeekStart: weekStart.AddDate(0, 0, 7), Highlight: "Wrote the launch post."},
+			}
+			return RenderMentorDigestEmail(data.Name, weekStart, weeks)
+		}},
+		{"project_rollup", "project_rollup.txt", func() (string, string, error) {
+			return RenderProjectRollupEmail(&models.ProjectRollup{
+				Project:          focus,
+				QuarterStart:     time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+				FirstEntryDate:   time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+				LastEntryDate:    time.Date(2024, 6, 28, 0, 0, 0, 0, time.UTC),
+				SummaryParagraph: "Moved every invoice to the new billing system.",
+				BulletPoints:     models.BulletPoints(bullets),
+			})
+		}},
+		{"ask_answer", "ask_answer.txt", func() (string, string, error) {
+			return RenderAskAnswerEmail(data.Question, data.Answer, []time.Time{weekEnd})
+		}},
+		{"data_report", "data_report.txt", func() (string, string, error) {
+			return RenderDataReportEmail(data.Report)
+		}},
+		{"magic_link", "magic_link.txt", func() (string, string, error) {
+			return RenderMagicLinkEmail(data.LoginURL, 15*time.Minute)
+		}},
+		{"email_change", "email_change.txt", func() (string, string, error) {
+			return RenderEmailChangeEmail(data.RequesterEmail, "123456")
+		}},
+		{"email_changed", "email_changed.txt", func() (string, string, error) {
+			return RenderEmailChangedEmail(data.RequesterEmail, data.NewEmail, weekStart.AddDate(0, 0, 14))
+		}},
+		{"email_alias", "email_alias.txt", func() (string, string, error) {
+			return RenderEmailAliasEmail(data.RequesterEmail, "123456")
+		}},
+		{"already_signed_up", "already_signed_up.txt", func() (string, string, error) {
+			return RenderAlreadySignedUpEmail(data.Name, data.Timezone, promptTime, data.LoginURL)
+		}},
+		{"outbox_alert", "outbox_alert.txt", func() (string, string, error) {
+			return RenderOutboxAlertEmail(data.Outbox, 2*time.Hour)
+		}},
+		{"anomaly_report", "anomaly_report.txt", func() (string, string, error) {
+			return RenderAnomalyReportEmail(data.Anomalies)
+		}},
+		{"announcement", "announcements/announce.txt", func() (string, string, error) {
+			text, err := Announcement("announce.txt")
+			if err != nil {
+				return "", "", err
+			}
+			tmpl, err := ParseAnnouncement("announce.txt", text)
+			if err != nil {
+				return "", "", err
+			}
+			var buf bytes.Buffer
+			err = tmpl.Execute(&buf, AnnouncementData{Name: data.Name, Email: data.RequesterEmail})
+			return "", buf.String(), err
+		}},
+	}
+
+	for _, notice := range []string{BillingPaymentFailed, BillingReminder, BillingSuspended, BillingRestored} {
+		notice := notice
+		cases = append(cases, goldenCase{notice, notice + ".txt", func() (string, string, error) {
+			return RenderBillingEmail(notice, data.OrgName, &grace, data.InvoiceURL)
+		}})
+	}
+	return cases
+}
+
+// TestGolden renders every email and compares its subject and body with
+// testdata/golden/<case>.golden
+func TestGolden(t *testing.T) {
+	for _, tc := range goldenCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			subject, body, err := tc.render()
+			if err != nil {
+				t.Fatalf("render error = %v", err)
+			}
+			got := fmt.Sprintf("Subject: %s\n\n%s", subject, body)
+
+			path := filepath.Join("testdata", "golden", tc.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("%s differs from %s (run with -update if intended):\n%s", tc.name, path, firstDiff(string(want), got))
+			}
+		})
+	}
+}
+
+// TestGoldenCoversTemplates fails when a template has no golden case, so a
+// new email gets a snapshot along with its emailFooters entry
+func TestGoldenCoversTemplates(t *testing.T) {
+	covered := map[string]bool{}
+	for _, tc := range goldenCases() {
+		covered[tc.template] = true
+	}
+
+	templates, err := templateFS.ReadDir("templates/announcements")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(emailFooters)+len(templates))
+	for name := range emailFooters {
+		names = append(names, name)
+	}
+	for _, entry := range templates {
+		names = append(names, "announcements/"+entry.Name())
+	}
+
+	for _, name := range names {
+		if !covered[name] {
+			t.Errorf("%s has no golden case", name)
+		}
+	}
+}
+
+// firstDiff describes the first line where got differs from want
+func firstDiff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want %q\n   got %q", i+1, w, g)
+		}
+	}
+	return "trailing newline differs"
+}
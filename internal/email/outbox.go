@@ -0,0 +1,348 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ses"
+	"github.com/aws/smithy-go"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// outboxClaimGrace is how long past OUTBOX_MAX_RUN an email may stay
+// claimed before it is treated as left by a run that crashed, allowing for
+// a send already under way when the run's time ran out
+const outboxClaimGrace = 5 * time.Minute
+
+// OutboxTopic is the job queue topic signaled when an email is queued to
+// send now. The scheduler runs the email-outbox job on it.
+const OutboxTopic = "email_outbox"
+
+// outboxPriorities is the order ProcessOutbox sends in, highest first
+var outboxPriorities = []int{
+	models.EmailPriorityTransactional,
+	models.EmailPriorityNormal,
+	models.EmailPriorityBatch,
+}
+
+// sendBudget paces one ProcessOutbox run to SES's limits: sends are spaced
+// to the max send rate, and the run stops when the 24-hour quota is spent
+// or OUTBOX_MAX_RUN has passed. OUTBOX_TRANSACTIONAL_QUOTA percent of the
+// daily quota is held back for transactional mail, so a backlog of batch
+// mail can't leave verification emails unsendable until the quota resets.
+type sendBudget struct {
+	interval  time.Duration // between recipients; 0 for no pacing
+	remaining int           // recipients left in the 24-hour quota; -1 if unknown
+	reserved  int           // part of remaining only transactional mail may use
+	deadline  time.Time
+	next      time.Time
+}
+
+// newSendBudget reads the account's quota from SES. If that fails the run
+// is paced by SES_MAX_SEND_RATE alone and SES's own throttling is the
+// backstop.
+func (s *Service) newSendBudget(ctx context.Context) *sendBudget {
+	if s.config.EmailDryRun {
+		// Nothing reaches SES, so nothing limits the run but its length
+		return &sendBudget{remaining: -1, deadline: time.Now().Add(s.config.OutboxMaxRun)}
+	}
+
+	rate := s.config.SESMaxSendRate
+	remaining, reserved := -1, 0
+
+	quota, err := s.sesClient.GetSendQuota(ctx, &ses.GetSendQuotaInput{})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to read SES send quota, pacing by SES_MAX_SEND_RATE only")
+	} else {
+		rate = sendRate(rate, quota)
+		// A negative Max24HourSend means the account has no daily quota
+		if quota.Max24HourSend >= 0 {
+			remaining = int(quota.Max24HourSend - quota.SentLast24Hours)
+			if remaining < 0 {
+				remaining = 0
+			}
+			reserved = int(quota.Max24HourSend) * s.config.OutboxTransactionalQuota / 100
+		}
+	}
+
+	budget := &sendBudget{
+		remaining: remaining,
+		reserved:  reserved,
+		deadline:  time.Now().Add(s.config.OutboxMaxRun),
+	}
+	if rate > 0 {
+		budget.interval = time.Duration(float64(time.Second) / rate)
+	}
+	return budget
+}
+
+// wait blocks until an email of priority to recipients addresses may be
+// sent and takes it from the budget. It returns false when the email should
+// wait for a later run instead: the quota can't cover it, the run is out of
+// time, or ctx is done.
+func (b *sendBudget) wait(ctx context.Context, priority, recipients int) bool {
+	if available := b.available(priority); available >= 0 && available < recipients {
+		return false
+	}
+	if !b.next.IsZero() && b.next.After(b.deadline) {
+		return false
+	}
+	if time.Now().After(b.deadline) {
+		return false
+	}
+
+	if delay := time.Until(b.next); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+		}
+	}
+
+	b.next = time.Now().Add(b.interval * time.Duration(recipients))
+	if b.remaining > 0 {
+		b.remaining -= recipients
+	}
+	return true
+}
+
+// available returns how many recipients the quota still covers for mail of
+// priority, or -1 if the quota is unknown
+func (b *sendBudget) available(priority int) int {
+	if b.remaining < 0 {
+		return -1
+	}
+	available := b.remaining
+	if priority != models.EmailPriorityTransactional {
+		available -= b.reserved
+	}
+	if available < 0 {
+		return 0
+	}
+	return available
+}
+
+// ProcessOutbox sends due pending emails, highest priority first, a page of
+// OUTBOX_BATCH_SIZE at a time. With OUTBOX_DRAIN it keeps fetching pages
+// until the outbox is empty, the SES budget is spent, SES throttles or
+// OUTBOX_MAX_RUN passes, so a burst of summaries drains in one run rather
+// than ten emails every five minutes. Each page starts again from the
+// highest priority, so transactional mail queued mid-run isn't stuck
+// behind a backlog of batch mail, and other mail stops short of the quota
+// reserved for it. Without OUTBOX_DRAIN it sends one page of each priority.
+//
+// Each page is claimed before it is sent, so runs in other processes, such
+// as the scheduler and a CLI process-outbox, send different emails. Emails
+// a run claims but doesn't send go back to pending, and claims a crashed run
+// left are released at the start of the next.
+func (s *Service) ProcessOutbox(ctx context.Context) error {
+	s.outboxMu.Lock()
+	defer s.outboxMu.Unlock()
+
+	if err := s.releaseStaleClaims(ctx); err != nil {
+		return err
+	}
+
+	budget := s.newSendBudget(ctx)
+	pageSize := s.config.OutboxBatchSize
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+
+	sent := 0
+	start := time.Now()
+	defer func() {
+		if sent > 0 {
+			logrus.WithFields(logrus.Fields{
+				"processed": sent,
+				"duration":  time.Since(start).String(),
+			}).Info("Processed email outbox")
+		}
+	}()
+
+	if !s.config.OutboxDrain {
+		for _, priority := range outboxPriorities {
+			emails, err := s.claimEmails(ctx, priority, pageSize)
+			if err != nil {
+				return err
+			}
+			n, stop := s.deliverPage(ctx, emails, budget)
+			sent += n
+			if stop {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	for {
+		emails, err := s.nextPage(ctx, pageSize)
+		if err != nil {
+			return err
+		}
+		if len(emails) == 0 {
+			return nil
+		}
+		n, stop := s.deliverPage(ctx, emails, budget)
+		sent += n
+		if stop {
+			return nil
+		}
+	}
+}
+
+// notifyOutbox wakes the outbox sender. The email is already in the
+// outbox, so if the signal fails it only waits for the email-outbox job.
+func (s *Service) notifyOutbox(ctx context.Context) {
+	if s.queue == nil {
+		return
+	}
+	if err := s.queue.Notify(ctx, OutboxTopic); err != nil {
+		logrus.WithError(err).Warn("Failed to wake the outbox sender")
+	}
+}
+
+// nextPage claims up to pageSize due emails of the highest priority that
+// has any
+func (s *Service) nextPage(ctx context.Context, pageSize int) ([]*models.EmailLog, error) {
+	for _, priority := range outboxPriorities {
+		emails, err := s.claimEmails(ctx, priority, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(emails) > 0 {
+			return emails, nil
+		}
+	}
+	return nil, nil
+}
+
+// deliverPage sends emails within budget, returning how many were attempted
+// and whether the run should stop. Emails left unsent go back to pending
+// for the next run. With SES_BULK_TEMPLATES, runs of emails of a bulk type
+// go out up to 50 in one call.
+func (s *Service) deliverPage(ctx context.Context, emails []*models.EmailLog, budget *sendBudget) (int, bool) {
+	for i := 0; i < len(emails); {
+		batch := []*models.EmailLog{emails[i]}
+		if s.sesTemplates != nil && !s.config.EmailDryRun {
+			if bulk := s.sesTemplates.batch(emails[i:], budget.available(emails[i].Priority)); bulk != nil {
+				batch = bulk
+			}
+		}
+
+		recipients := 0
+		for _, email := range batch {
+			recipients += 1 + len(email.CCEmails)
+		}
+		if !budget.wait(ctx, batch[0].Priority, recipients) {
+			logrus.WithField("unsent", len(emails)-i).Info("Outbox send budget reached, leaving the rest for the next run")
+			s.releaseEmails(ctx, emails[i:])
+			return i, true
+		}
+
+		var throttled bool
+		if len(batch) > 1 {
+			throttled = s.deliverBulk(ctx, batch)
+		} else {
+			throttled = s.deliverQueued(ctx, batch[0])
+		}
+		if throttled {
+			s.releaseEmails(ctx, emails[i:])
+			return i, true
+		}
+		i += len(batch)
+	}
+	return len(emails), false
+}
+
+// releaseEmails returns claimed emails to pending. It runs even if ctx is
+// done, since a cancelled run is one reason emails go unsent; if it fails,
+// the next run releases them once the claim is stale.
+func (s *Service) releaseEmails(ctx context.Context, emails []*models.EmailLog) {
+	ids := make([]int64, len(emails))
+	for i, email := range emails {
+		ids[i] = int64(email.ID)
+	}
+
+	query := `
+		UPDATE email_logs SET status = 'pending', claimed_at = NULL, updated_at = NOW()
+		WHERE id = ANY($1) AND status = 'sending'`
+
+	if _, err := s.db.ExecContext(context.WithoutCancel(ctx), query, pq.Array(ids)); err != nil {
+		logrus.WithError(err).WithField("emails", len(ids)).Error("Failed to release unsent emails")
+	}
+}
+
+// releaseStaleClaims returns to pending the emails claimed longer ago than
+// any live run could still be sending them. An email whose run crashed
+// after SES accepted it is sent again, which is preferred to losing it.
+func (s *Service) releaseStaleClaims(ctx context.Context) error {
+	query := `
+		UPDATE email_logs SET status = 'pending', claimed_at = NULL, updated_at = NOW()
+		WHERE status = 'sending' AND claimed_at < $1`
+
+	cutoff := time.Now().Add(-(s.config.OutboxMaxRun + outboxClaimGrace))
+	result, err := s.db.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to release stale outbox claims: %w", err)
+	}
+	if released, _ := result.RowsAffected(); released > 0 {
+		logrus.WithField("released", released).Warn("Released emails claimed by an outbox run that didn't finish")
+	}
+	return nil
+}
+
+// deliverQueued sends one queued email, recording a failure on its row.
+// If SES throttles the send, it reports throttled so the run stops and the
+// email goes back to pending.
+func (s *Service) deliverQueued(ctx context.Context, email *models.EmailLog) (throttled bool) {
+	err := s.sendEmail(ctx, email)
+	if err == nil {
+		return false
+	}
+
+	logger := logrus.WithError(err).WithFields(logrus.Fields{
+		"email_id":   email.ID,
+		"priority":   email.Priority,
+		"error_code": apperrors.CodeOf(err),
+	})
+
+	if isThrottling(err) {
+		logger.Warn("SES throttled the outbox, returning email to pending")
+		return true
+	}
+
+	logger.Error("Failed to send email")
+	s.recordFailure(ctx, email, err)
+	return false
+}
+
+// recordFailure marks email failed with sendErr and publishes the failure
+func (s *Service) recordFailure(ctx context.Context, email *models.EmailLog, sendErr error) {
+	if err := s.markEmailFailed(ctx, email.ID, sendErr.Error()); err != nil {
+		logrus.WithError(err).Error("Failed to mark email as failed")
+	}
+	s.publishFailure(ctx, email, sendErr)
+}
+
+// isThrottling reports whether SES refused a send for exceeding the
+// account's send rate or quota
+func isThrottling(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "Throttling", "ThrottlingException":
+		return true
+	}
+	return false
+}
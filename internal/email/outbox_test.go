@@ -0,0 +1,180 @@
+package email
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	pkgConfig "github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// The outbox tests need a disposable Postgres database, whose email_logs
+// are emptied before each test:
+//
+//	TEST_DATABASE_URL=postgres://localhost/wdygdtw_test?sslmode=disable go test ./internal/email -run TestOutbox
+
+func outboxDB(t *testing.T) *database.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	conn, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db := &database.DB{DB: conn}
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+	if _, err := db.Exec(`TRUNCATE email_logs RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("failed to reset test database: %v", err)
+	}
+	return db
+}
+
+// outboxService is a dry-run email service with its own outboxMu, as
+// another process would have
+func outboxService(t *testing.T, db *database.DB) *Service {
+	t.Helper()
+	service, err := NewService(db, &pkgConfig.Config{AWSSESRegion: "us-east-1", EmailDryRun: true, OutboxDrain: true, OutboxBatchSize: 3, OutboxMaxRun: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func queueOutboxEmails(t *testing.T, service *Service, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := service.QueueEmail(context.Background(), nil, "alex@example.com", models.EmailTypeAnnouncement, "Hello", "Hi", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func emailStatuses(t *testing.T, db *database.DB) map[string]int {
+	t.Helper()
+	rows, err := db.Query(`SELECT status, COUNT(*) FROM email_logs GROUP BY status`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	statuses := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			t.Fatal(err)
+		}
+		statuses[status] = count
+	}
+	return statuses
+}
+
+func TestOutboxClaimsAreDisjoint(t *testing.T) {
+	db := outboxDB(t)
+	a, b := outboxService(t, db), outboxService(t, db)
+	queueOutboxEmails(t, a, 20)
+
+	var mu sync.Mutex
+	claimed := map[int]int{}
+	var wg sync.WaitGroup
+	for _, service := range []*Service{a, b} {
+		wg.Add(1)
+		go func(service *Service) {
+			defer wg.Done()
+			for {
+				emails, err := service.claimEmails(context.Background(), models.EmailPriorityBatch, 3)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if len(emails) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, email := range emails {
+					claimed[email.ID]++
+				}
+				mu.Unlock()
+			}
+		}(service)
+	}
+	wg.Wait()
+
+	if len(claimed) != 20 {
+		t.Errorf("claimed %d emails, want 20", len(claimed))
+	}
+	for id, n := range claimed {
+		if n != 1 {
+			t.Errorf("email %d claimed %d times", id, n)
+		}
+	}
+}
+
+func TestOutboxConcurrentRuns(t *testing.T) {
+	db := outboxDB(t)
+	a, b := outboxService(t, db), outboxService(t, db)
+	queueOutboxEmails(t, a, 20)
+
+	var wg sync.WaitGroup
+	for _, service := range []*Service{a, b} {
+		wg.Add(1)
+		go func(service *Service) {
+			defer wg.Done()
+			if err := service.ProcessOutbox(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}(service)
+	}
+	wg.Wait()
+
+	if got := emailStatuses(t, db); got[models.EmailStatusSent] != 20 || len(got) != 1 {
+		t.Errorf("email statuses = %v, want all 20 sent", got)
+	}
+}
+
+func TestOutboxReleasesClaims(t *testing.T) {
+	db := outboxDB(t)
+	service := outboxService(t, db)
+	queueOutboxEmails(t, service, 4)
+
+	emails, err := service.claimEmails(context.Background(), models.EmailPriorityBatch, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.releaseEmails(context.Background(), emails[2:])
+	if got := emailStatuses(t, db); got[models.EmailStatusSending] != 2 || got[models.EmailStatusPending] != 2 {
+		t.Errorf("after release, email statuses = %v, want 2 sending and 2 pending", got)
+	}
+
+	// Claimed emails are still unsent, so the outbox status counts them
+	status, err := service.OutboxStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Pending != 4 || status.Due != 4 || status.Sending != 2 || status.OldestClaimedAt == nil {
+		t.Errorf("OutboxStatus() = %+v, want 4 pending and due, 2 of them claimed", status)
+	}
+
+	// Claims too old for a live run are released at the start of the next
+	if _, err := db.Exec(`UPDATE email_logs SET claimed_at = NOW() - INTERVAL '1 hour' WHERE status = 'sending'`); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.ProcessOutbox(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := emailStatuses(t, db); got[models.EmailStatusSent] != 4 {
+		t.Errorf("after the next run, email statuses = %v, want all 4 sent", got)
+	}
+}
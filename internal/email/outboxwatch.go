@@ -0,0 +1,186 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/events"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// OutboxTypeStatus is the unsent mail of one email type. Due emails are
+// past their scheduled_at and waiting only on ProcessOutbox. Sending emails
+// are claimed by a run, which includes a crashed run's claims until the
+// next run releases them.
+type OutboxTypeStatus struct {
+	EmailType       string     `json:"email_type"`
+	Pending         int        `json:"pending"`
+	Due             int        `json:"due"`
+	Sending         int        `json:"sending"`
+	OldestDueAt     *time.Time `json:"oldest_due_at,omitempty"`
+	OldestClaimedAt *time.Time `json:"oldest_claimed_at,omitempty"`
+}
+
+// OutboxStatus is the outbox's unsent mail, in total and by email type
+type OutboxStatus struct {
+	Pending         int                `json:"pending"`
+	Due             int                `json:"due"`
+	Sending         int                `json:"sending"`
+	OldestDueAt     *time.Time         `json:"oldest_due_at,omitempty"`
+	OldestClaimedAt *time.Time         `json:"oldest_claimed_at,omitempty"`
+	Types           []OutboxTypeStatus `json:"types"`
+}
+
+// OldestDueAge is how long the oldest due email has waited, or 0 if none is
+// due
+func (o *OutboxStatus) OldestDueAge(now time.Time) time.Duration {
+	if o.OldestDueAt == nil {
+		return 0
+	}
+	return now.Sub(*o.OldestDueAt)
+}
+
+// OutboxStatus counts unsent emails by type. An email is due from its
+// scheduled_at, or from when it was queued if it has none. Claimed emails
+// count as pending and due as well as sending, so a claim that is never
+// sent still ages the oldest due email.
+func (s *Service) OutboxStatus(ctx context.Context) (*OutboxStatus, error) {
+	query := `
+		SELECT email_type, COUNT(*),
+			COUNT(*) FILTER (WHERE scheduled_at IS NULL OR scheduled_at <= NOW()),
+			COUNT(*) FILTER (WHERE status = 'sending'),
+			MIN(COALESCE(scheduled_at, created_at)) FILTER (WHERE scheduled_at IS NULL OR scheduled_at <= NOW()),
+			MIN(claimed_at) FILTER (WHERE status = 'sending')
+		FROM email_logs
+		WHERE status IN ('pending', 'sending')
+		GROUP BY email_type
+		ORDER BY email_type`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox status: %w", err)
+	}
+	defer rows.Close()
+
+	status := &OutboxStatus{Types: []OutboxTypeStatus{}}
+	for rows.Next() {
+		var t OutboxTypeStatus
+		if err := rows.Scan(&t.EmailType, &t.Pending, &t.Due, &t.Sending, &t.OldestDueAt, &t.OldestClaimedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox status: %w", err)
+		}
+		status.Pending += t.Pending
+		status.Due += t.Due
+		status.Sending += t.Sending
+		status.OldestDueAt = earliest(status.OldestDueAt, t.OldestDueAt)
+		status.OldestClaimedAt = earliest(status.OldestClaimedAt, t.OldestClaimedAt)
+		status.Types = append(status.Types, t)
+	}
+
+	return status, rows.Err()
+}
+
+// earliest returns the earlier of two optional times
+func earliest(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.Before(*a)) {
+		return b
+	}
+	return a
+}
+
+// WatchOutbox alerts when the oldest due email has waited longer than
+// OUTBOX_STUCK_AFTER, which means ProcessOutbox isn't running or can't send.
+// The alert is logged, published as OutboxStuck, and emailed to
+// ADMIN_ALERT_EMAIL directly rather than through the outbox it reports on.
+// While the outbox stays stuck the alert repeats once per OUTBOX_STUCK_AFTER.
+func (s *Service) WatchOutbox(ctx context.Context) error {
+	stuckAfter := s.config.OutboxStuckAfter
+	if stuckAfter <= 0 {
+		return nil
+	}
+
+	status, err := s.OutboxStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	age := status.OldestDueAge(now)
+	if age < stuckAfter {
+		s.outboxAlertMu.Lock()
+		s.outboxAlertedAt = time.Time{}
+		s.outboxAlertMu.Unlock()
+		return nil
+	}
+
+	s.outboxAlertMu.Lock()
+	recent := !s.outboxAlertedAt.IsZero() && now.Sub(s.outboxAlertedAt) < stuckAfter
+	if !recent {
+		s.outboxAlertedAt = now
+	}
+	s.outboxAlertMu.Unlock()
+
+	logger := logrus.WithFields(logrus.Fields{
+		"due":        status.Due,
+		"sending":    status.Sending,
+		"oldest_age": age.Round(time.Second).String(),
+	})
+	if recent {
+		logger.Warn("Email outbox is still stuck")
+		return nil
+	}
+	logger.Error("Email outbox is stuck")
+
+	if s.events != nil {
+		err := s.events.Publish(ctx, events.New(events.OutboxStuck, events.OutboxStuckAlert{
+			Due:         status.Due,
+			OldestDueAt: *status.OldestDueAt,
+			Threshold:   stuckAfter.String(),
+		}))
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to publish outbox stuck event")
+		}
+	}
+
+	if s.config.AdminAlertEmail != "" {
+		if err := s.sendOutboxAlert(ctx, s.config.AdminAlertEmail, status, age); err != nil {
+			return fmt.Errorf("failed to send outbox alert: %w", err)
+		}
+	}
+	return nil
+}
+
+// sendOutboxAlert records the alert in email_logs and sends it at once
+func (s *Service) sendOutboxAlert(ctx context.Context, adminEmail string, status *OutboxStatus, age time.Duration) error {
+	subject, body, err := RenderOutboxAlertEmail(status, age)
+	if err != nil {
+		return fmt.Errorf("failed to render outbox alert email: %w", err)
+	}
+
+	query := `
+		INSERT INTO email_logs (recipient_email, email_type, priority, subject, body_text)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+
+	alert := &models.EmailLog{
+		RecipientEmail: adminEmail,
+		EmailType:      models.EmailTypeAdminAlert,
+		Priority:       models.EmailPriorityFor(models.EmailTypeAdminAlert),
+		Subject:        subject,
+		BodyText:       body,
+	}
+	err = s.db.QueryRowContext(ctx, query, alert.RecipientEmail, alert.EmailType, alert.Priority, alert.Subject, alert.BodyText).Scan(&alert.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record outbox alert: %w", err)
+	}
+
+	if err := s.sendEmail(ctx, alert); err != nil {
+		if markErr := s.markEmailFailed(ctx, alert.ID, err.Error()); markErr != nil {
+			logrus.WithError(markErr).Error("Failed to mark email as failed")
+		}
+		return err
+	}
+	return nil
+}
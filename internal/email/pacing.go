@@ -0,0 +1,100 @@
+package email
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ses"
+	"github.com/sirupsen/logrus"
+)
+
+// outboxInterval is how often the email-outbox job runs ProcessOutbox
+const outboxInterval = 5 * time.Minute
+
+// SendPacer spreads a burst of batch mail, such as the weekly summaries,
+// over the time the outbox needs to send it. Each email is scheduled for
+// the slot after the mail already due and the emails paced before it, so
+// the outbox sends summaries as they fall due instead of holding hundreds
+// of them pending at once behind whatever else is queued.
+type SendPacer struct {
+	start    time.Time
+	interval time.Duration // per recipient; 0 sends everything now
+	slot     int           // recipients due ahead of the next email
+}
+
+// NewSendPacer sizes slots to the outbox's send rate and starts them after
+// the emails already due. If the outbox depth can't be read, pacing starts
+// now.
+func (s *Service) NewSendPacer(ctx context.Context) *SendPacer {
+	pacer := &SendPacer{
+		start:    time.Now(),
+		interval: pacingInterval(s.maxSendRate(ctx), s.config.OutboxMaxRun, s.config.OutboxDrain, s.config.OutboxBatchSize),
+	}
+
+	status, err := s.OutboxStatus(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to read outbox depth, pacing from now")
+	} else {
+		pacer.slot = status.Due
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"outbox_due": pacer.slot,
+		"interval":   pacer.interval.String(),
+	}).Info("Pacing batch email to the outbox send rate")
+	return pacer
+}
+
+// Next returns when an email to recipients addresses should be scheduled,
+// or nil to send it as soon as it is queued
+func (p *SendPacer) Next(recipients int) *time.Time {
+	if p == nil || p.interval <= 0 {
+		return nil
+	}
+	at := p.start.Add(p.interval * time.Duration(p.slot))
+	p.slot += recipients
+	return &at
+}
+
+// maxSendRate is SES_MAX_SEND_RATE capped to the account's SES rate, or 0
+// if neither is known
+func (s *Service) maxSendRate(ctx context.Context) float64 {
+	quota, err := s.sesClient.GetSendQuota(ctx, &ses.GetSendQuotaInput{})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to read SES send quota, pacing by SES_MAX_SEND_RATE only")
+		return s.config.SESMaxSendRate
+	}
+	return sendRate(s.config.SESMaxSendRate, quota)
+}
+
+// sendRate caps the configured rate to quota's, using quota's when the
+// configured rate is 0
+func sendRate(configured float64, quota *ses.GetSendQuotaOutput) float64 {
+	if quota.MaxSendRate > 0 && (configured <= 0 || quota.MaxSendRate < configured) {
+		return quota.MaxSendRate
+	}
+	return configured
+}
+
+// pacingInterval is the time the outbox takes per recipient. Each run
+// sends at rate for at most maxRun, or a single page without drain, and
+// runs are outboxInterval apart. It is 0 when nothing limits the outbox.
+func pacingInterval(rate float64, maxRun time.Duration, drain bool, pageSize int) time.Duration {
+	if maxRun <= 0 || maxRun > outboxInterval {
+		maxRun = outboxInterval
+	}
+	perRun := -1.0
+	if rate > 0 {
+		perRun = rate * maxRun.Seconds()
+	}
+	if !drain && pageSize > 0 && (perRun < 0 || float64(pageSize) < perRun) {
+		perRun = float64(pageSize)
+	}
+	if perRun < 0 {
+		return 0
+	}
+	if perRun < 1 {
+		perRun = 1
+	}
+	return time.Duration(float64(outboxInterval) / perRun)
+}
@@ -0,0 +1,51 @@
+package email
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPacingInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float64
+		maxRun   time.Duration
+		drain    bool
+		pageSize int
+		want     time.Duration
+	}{
+		{"unlimited", 0, 4 * time.Minute, true, 50, 0},
+		{"rate for the whole interval", 10, 5 * time.Minute, true, 50, 100 * time.Millisecond},
+		{"rate for part of the interval", 10, 150 * time.Second, true, 50, 200 * time.Millisecond},
+		{"max run past the interval", 10, time.Hour, true, 50, 100 * time.Millisecond},
+		{"one page per run", 10, 4 * time.Minute, false, 50, 6 * time.Second},
+		{"one page per run, no rate", 0, 4 * time.Minute, false, 100, 3 * time.Second},
+		{"page larger than the rate allows", 0.1, 100 * time.Second, false, 50, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pacingInterval(tt.rate, tt.maxRun, tt.drain, tt.pageSize)
+			if got != tt.want {
+				t.Errorf("pacingInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendPacerNext(t *testing.T) {
+	start := time.Date(2024, 3, 1, 16, 30, 0, 0, time.UTC)
+	pacer := &SendPacer{start: start, interval: time.Second, slot: 30}
+
+	want := []time.Time{start.Add(30 * time.Second), start.Add(31 * time.Second), start.Add(34 * time.Second)}
+	for i, recipients := range []int{1, 3, 1} {
+		got := pacer.Next(recipients)
+		if got == nil || !got.Equal(want[i]) {
+			t.Errorf("Next() #%d = %v, want %v", i, got, want[i])
+		}
+	}
+
+	if got := (&SendPacer{start: start}).Next(1); got != nil {
+		t.Errorf("Next() without pacing = %v, want nil", got)
+	}
+}
@@ -0,0 +1,45 @@
+package email
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// replyLocalPart is the mailbox that reply tokens are appended to with plus
+// addressing, e.g. reply+3f9c2a7d1b0e4c55@domain
+const replyLocalPart = "reply"
+
+// ReplyAddress returns the per-user reply address for token, or "" when the
+// user has no token so the default From address is used
+func (s *Service) ReplyAddress(token string) string {
+	if token == "" {
+		return ""
+	}
+	return replyLocalPart + "+" + token + "@" + s.config.Domain
+}
+
+// ReplyToken extracts the token from a reply+<token>@domain address. Display
+// names are allowed; other addresses return false.
+func ReplyToken(address string) (string, bool) {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		address = parsed.Address
+	}
+
+	local, _, found := strings.Cut(strings.TrimSpace(address), "@")
+	if !found {
+		return "", false
+	}
+
+	mailbox, token, found := strings.Cut(local, "+")
+	if !found || !strings.EqualFold(mailbox, replyLocalPart) || token == "" {
+		return "", false
+	}
+	return strings.ToLower(token), true
+}
+
+func replyToAddresses(replyTo *string) []string {
+	if replyTo == nil || *replyTo == "" {
+		return nil
+	}
+	return []string{*replyTo}
+}
@@ -2,47 +2,134 @@ package email
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ses"
 	"github.com/aws/aws-sdk-go-v2/service/ses/types"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 
 	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
-	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/models"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/digest"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/events"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/jobqueue"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/stats"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/users"
 	pkgConfig "github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
 )
 
+// alreadySignedUpInterval is how often a verified user who keeps signing up
+// is reminded that they already are
+const alreadySignedUpInterval = 24 * time.Hour
+
 type Service struct {
 	db        *database.DB
 	sesClient *ses.Client
 	config    *pkgConfig.Config
+	events    events.Publisher
+	users     *users.Repository
+	archive   *archive
+	queue     jobqueue.Queue
+	// sesTemplates is set with SES_BULK_TEMPLATES
+	sesTemplates *sesTemplates
+
+	// outboxMu keeps this process to one ProcessOutbox run at a time, as a
+	// queue signal and the email-outbox schedule may both start one. Runs in
+	// different processes are kept apart by claiming rows, but two in one
+	// process would split its SES budget.
+	outboxMu sync.Mutex
+
+	// outboxAlertedAt is when WatchOutbox last alerted; zero once the
+	// outbox recovers
+	outboxAlertMu   sync.Mutex
+	outboxAlertedAt time.Time
 }
 
+// NewService fails if any email template is invalid, so a broken template
+// stops the process at startup instead of at send time
 func NewService(db *database.DB, cfg *pkgConfig.Config) (*Service, error) {
+	if err := ValidateTemplates(); err != nil {
+		return nil, err
+	}
+
 	awsCfg, err := config.LoadDefaultConfig(context.TODO(),
 		config.WithRegion(cfg.AWSSESRegion))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	return &Service{
+	archive, err := newArchive(context.TODO(), cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &Service{
 		db:        db,
 		sesClient: ses.NewFromConfig(awsCfg),
 		config:    cfg,
-	}, nil
+		users:     users.NewRepository(db, cfg.UserCacheSize, cfg.UserCacheTTL),
+		archive:   archive,
+	}
+	if cfg.SESBulkTemplates {
+		service.sesTemplates, err = newSESTemplates(cfg.SESTemplatePrefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return service, nil
+}
+
+// IsProd reports whether the service sends from production (APP_ENV=prod)
+func (s *Service) IsProd() bool {
+	return s.config.IsProd()
+}
+
+// SetEvents enables publishing EmailFailed events to bus
+func (s *Service) SetEvents(bus events.Publisher) {
+	s.events = bus
+}
+
+// SetQueue has emails queued to send now signal OutboxTopic on q, so a
+// scheduler listening on it sends them without waiting for the next
+// email-outbox run
+func (s *Service) SetQueue(q jobqueue.Queue) {
+	s.queue = q
 }
 
 func (s *Service) QueueEmail(ctx context.Context, userID *int, recipientEmail, emailType, subject, body string, scheduledAt *time.Time) error {
+	return s.queueEmail(ctx, userID, recipientEmail, nil, "", emailType, subject, body, scheduledAt)
+}
+
+func (s *Service) queueEmail(ctx context.Context, userID *int, recipientEmail string, ccEmails []string, replyTo, emailType, subject, body string, scheduledAt *time.Time) error {
+	return s.queueTrackedEmail(ctx, nil, userID, recipientEmail, ccEmails, replyTo, emailType, subject, body, scheduledAt)
+}
+
+// queueTrackedEmail queues an email that, with t, also has an HTML part
+// carrying t's open pixel and click links
+func (s *Service) queueTrackedEmail(ctx context.Context, t *tracking, userID *int, recipientEmail string, ccEmails []string, replyTo, emailType, subject, body string, scheduledAt *time.Time) error {
 	query := `
-		INSERT INTO email_logs (user_id, recipient_email, email_type, subject, body_text, scheduled_at)
-		VALUES ($1, $2, $3, $4, $5, $6)`
+		INSERT INTO email_logs (user_id, recipient_email, cc_emails, reply_to, email_type, priority, subject, body_text, body_html, tracking_token, scheduled_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11)`
+
+	var cc interface{}
+	if len(ccEmails) > 0 {
+		cc = pq.Array(ccEmails)
+	}
+
+	var bodyHTML, token interface{}
+	if t != nil {
+		bodyHTML, token = t.html(body), t.token
+	}
 
-	_, err := s.db.ExecContext(ctx, query, userID, recipientEmail, emailType, subject, body, scheduledAt)
+	priority := models.EmailPriorityFor(emailType)
+	_, err := s.db.ExecContext(ctx, query, userID, recipientEmail, cc, replyTo, emailType, priority, subject, body, bodyHTML, token, scheduledAt)
 	if err != nil {
 		return fmt.Errorf("failed to queue email: %w", err)
 	}
@@ -50,55 +137,86 @@ func (s *Service) QueueEmail(ctx context.Context, userID *int, recipientEmail, e
 	logrus.WithFields(logrus.Fields{
 		"user_id":    userID,
 		"email_type": emailType,
+		"priority":   priority,
 		"recipient":  recipientEmail,
+		"cc_count":   len(ccEmails),
+		"tracked":    t != nil,
 	}).Info("Email queued for delivery")
 
+	if scheduledAt == nil || !scheduledAt.After(time.Now()) {
+		s.notifyOutbox(ctx)
+	}
 	return nil
 }
 
-func (s *Service) ProcessOutbox(ctx context.Context) error {
+// claimEmails moves up to limit due pending emails of priority to 'sending'
+// and returns them, oldest first. Rows another run is claiming are skipped,
+// so concurrent runs, in this process or another, never get the same email.
+func (s *Service) claimEmails(ctx context.Context, priority, limit int) ([]*models.EmailLog, error) {
 	query := `
-		SELECT id, user_id, recipient_email, email_type, subject, body_text, retry_count
-		FROM email_logs 
-		WHERE status = 'pending' AND (scheduled_at IS NULL OR scheduled_at <= NOW())
-		ORDER BY created_at ASC
-		LIMIT 10`
-
-	rows, err := s.db.QueryContext(ctx, query)
+		WITH claimed AS (
+			UPDATE email_logs SET status = 'sending', claimed_at = NOW(), updated_at = NOW()
+			WHERE id IN (
+				SELECT id FROM email_logs
+				WHERE status = 'pending' AND priority = $1 AND (scheduled_at IS NULL OR scheduled_at <= NOW())
+				ORDER BY created_at ASC
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, user_id, recipient_email, cc_emails, reply_to, email_type, priority, subject, body_text, body_html, retry_count, created_at
+		)
+		SELECT id, user_id, recipient_email, cc_emails, reply_to, email_type, priority, subject, body_text, body_html, retry_count
+		FROM claimed
+		ORDER BY created_at, id`
+
+	rows, err := s.db.QueryContext(ctx, query, priority, limit)
 	if err != nil {
-		return fmt.Errorf("failed to query pending emails: %w", err)
+		return nil, fmt.Errorf("failed to claim pending emails: %w", err)
 	}
 	defer rows.Close()
 
+	var emails []*models.EmailLog
 	for rows.Next() {
 		var email models.EmailLog
-		err := rows.Scan(&email.ID, &email.UserID, &email.RecipientEmail, 
-			&email.EmailType, &email.Subject, &email.BodyText, &email.RetryCount)
+		err := rows.Scan(&email.ID, &email.UserID, &email.RecipientEmail, pq.Array(&email.CCEmails), &email.ReplyTo,
+			&email.EmailType, &email.Priority, &email.Subject, &email.BodyText, &email.BodyHTML, &email.RetryCount)
 		if err != nil {
 			logrus.WithError(err).Error("Failed to scan email log")
 			continue
 		}
-
-		if err := s.sendEmail(ctx, &email); err != nil {
-			logrus.WithError(err).WithField("email_id", email.ID).Error("Failed to send email")
-			if err := s.markEmailFailed(ctx, email.ID, err.Error()); err != nil {
-				logrus.WithError(err).Error("Failed to mark email as failed")
-			}
-		}
+		emails = append(emails, &email)
 	}
 
-	return nil
+	return emails, rows.Err()
 }
 
+// sendEmail sends email through SES, or with EMAIL_DRY_RUN logs it and marks
+// it sent. Outside production the subject is tagged with the environment.
 func (s *Service) sendEmail(ctx context.Context, email *models.EmailLog) error {
+	subject := s.config.SubjectTag() + email.Subject
+	if s.config.EmailDryRun {
+		logrus.WithFields(logrus.Fields{
+			"email_id":   email.ID,
+			"email_type": email.EmailType,
+			"recipient":  email.RecipientEmail,
+			"cc":         email.CCEmails,
+			"subject":    subject,
+			"body":       email.BodyText,
+		}).Info("EMAIL_DRY_RUN: email not sent")
+		return s.markEmailSent(ctx, email.ID, fmt.Sprintf("dry-run-%d", email.ID))
+	}
+
 	input := &ses.SendEmailInput{
 		Source: aws.String(s.config.EmailFrom),
 		Destination: &types.Destination{
-			ToAddresses: []string{email.RecipientEmail},
+			ToAddresses:  []string{email.RecipientEmail},
+			CcAddresses:  email.CCEmails,
+			BccAddresses: s.archive.bccAddresses(email.EmailType),
 		},
+		ReplyToAddresses: replyToAddresses(email.ReplyTo),
 		Message: &types.Message{
 			Subject: &types.Content{
-				Data: aws.String(email.Subject),
+				Data: aws.String(subject),
 			},
 			Body: &types.Body{
 				Text: &types.Content{
@@ -107,15 +225,47 @@ func (s *Service) sendEmail(ctx context.Context, email *models.EmailLog) error {
 			},
 		},
 	}
+	if email.BodyHTML != nil {
+		input.Message.Body.Html = &types.Content{Data: email.BodyHTML}
+	}
 
 	result, err := s.sesClient.SendEmail(ctx, input)
 	if err != nil {
+		var rejected *types.MessageRejected
+		if errors.As(err, &rejected) {
+			return apperrors.Wrap(apperrors.CodeSESRejected, err, "SES rejected email")
+		}
 		return fmt.Errorf("failed to send email via SES: %w", err)
 	}
 
+	// The email is out, so a failed copy is logged rather than retried with
+	// the send
+	if err := s.archive.store(ctx, email, s.config.EmailFrom, subject, *result.MessageId, time.Now()); err != nil {
+		logrus.WithError(err).WithField("email_id", email.ID).Error("Failed to archive sent email")
+	}
+
 	return s.markEmailSent(ctx, email.ID, *result.MessageId)
 }
 
+// publishFailure emits EmailFailed for a message that couldn't be sent
+func (s *Service) publishFailure(ctx context.Context, email *models.EmailLog, sendErr error) {
+	if s.events == nil {
+		return
+	}
+
+	err := s.events.Publish(ctx, events.New(events.EmailFailed, events.EmailFailure{
+		EmailID:   email.ID,
+		UserID:    email.UserID,
+		Recipient: email.RecipientEmail,
+		EmailType: email.EmailType,
+		Error:     sendErr.Error(),
+		Rejected:  apperrors.Is(sendErr, apperrors.CodeSESRejected),
+	}))
+	if err != nil {
+		logrus.WithError(err).WithField("email_id", email.ID).Warn("Failed to publish email failure event")
+	}
+}
+
 func (s *Service) markEmailSent(ctx context.Context, emailID int, messageID string) error {
 	query := `
 		UPDATE email_logs 
@@ -158,22 +308,40 @@ func (s *Service) SendWelcomeEmail(ctx context.Context, recipientEmail, verifica
 	return s.QueueEmail(ctx, nil, recipientEmail, models.EmailTypeVerification, subject, body, nil)
 }
 
-func (s *Service) SendDailyPrompt(ctx context.Context, userID int, recipientEmail string, projectFocus *string) error {
-	subject, body, err := RenderDailyPromptEmail(projectFocus)
+// SendDailyPrompt queues the prompt with a Reply-To carrying the user's reply
+// token so the reply is matched even if sent from another address
+func (s *Service) SendDailyPrompt(ctx context.Context, userID int, recipientEmail, replyToken string, projectFocus *string, entryFormat, quote, recap string, variation PromptVariation) error {
+	subject, body, err := RenderDailyPromptEmail(projectFocus, entryFormat, quote, recap, variation)
 	if err != nil {
 		return fmt.Errorf("failed to render daily prompt: %w", err)
 	}
 
-	return s.QueueEmail(ctx, &userID, recipientEmail, models.EmailTypeDailyPrompt, subject, body, nil)
+	return s.queueEmail(ctx, &userID, recipientEmail, nil, s.ReplyAddress(replyToken), models.EmailTypeDailyPrompt, subject, body, nil)
+}
+
+// SendWeeklyGoalsPrompt asks the user for the goals of the week starting at
+// weekStart. Its Reply-To is the user's reply address, like the daily prompt.
+func (s *Service) SendWeeklyGoalsPrompt(ctx context.Context, userID int, recipientEmail, replyToken string, projectFocus *string, weekStart time.Time) error {
+	subject, body, err := RenderWeeklyGoalsEmail(weekStart, projectFocus)
+	if err != nil {
+		return fmt.Errorf("failed to render weekly goals prompt: %w", err)
+	}
+
+	return s.queueEmail(ctx, &userID, recipientEmail, nil, s.ReplyAddress(replyToken), models.EmailTypeWeeklyGoals, subject, body, nil)
 }
 
-func (s *Service) SendWeeklySummary(ctx context.Context, userID int, recipientEmail string, weekStart time.Time, summaryParagraph string, bulletPoints []string) error {
-	subject, body, err := RenderWeeklySummaryEmail(weekStart, summaryParagraph, bulletPoints)
+// SendWeeklySummary queues the summary to the user, copying any confirmed
+// ccEmails, to be sent at scheduledAt or as soon as possible if it is nil.
+// weekStart and weekEnd are the first and last days it covers. With
+// EMAIL_TRACKING it is tracked unless the user opted out.
+func (s *Service) SendWeeklySummary(ctx context.Context, userID int, recipientEmail string, ccEmails []string, weekStart, weekEnd time.Time, summaryParagraph string, bulletPoints []string, goals []string, missedDays []time.Time, trend *stats.Trend, lookback *Lookback, cardURL string, scheduledAt *time.Time) error {
+	subject, body, err := RenderWeeklySummaryEmail(weekStart, weekEnd, summaryParagraph, bulletPoints, goals, missedDays, trend, lookback, cardURL)
 	if err != nil {
 		return fmt.Errorf("failed to render weekly summary: %w", err)
 	}
 
-	return s.QueueEmail(ctx, &userID, recipientEmail, models.EmailTypeWeeklySummary, subject, body, nil)
+	t := s.summaryTracking(ctx, userID, ccEmails)
+	return s.queueTrackedEmail(ctx, t, &userID, recipientEmail, ccEmails, "", models.EmailTypeWeeklySummary, subject, body, scheduledAt)
 }
 
 func (s *Service) SendClarificationRequest(ctx context.Context, userID int, recipientEmail, originalMessage string) error {
@@ -185,39 +353,222 @@ func (s *Service) SendClarificationRequest(ctx context.Context, userID int, reci
 	return s.QueueEmail(ctx, &userID, recipientEmail, models.EmailTypeClarification, subject, body, nil)
 }
 
-// GetUserByEmail retrieves user from database
-func (s *Service) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	query := `
-		SELECT id, email, name, timezone, prompt_time, verification_code, is_verified, 
-			   is_paused, pause_until, project_focus, created_at, updated_at
-		FROM users WHERE email = $1`
+// SendPlainTextClarification asks the user to reply with just a description
+// of their day
+func (s *Service) SendPlainTextClarification(ctx context.Context, userID int, recipientEmail string) error {
+	subject, body, err := RenderPlainTextClarificationEmail()
+	if err != nil {
+		return fmt.Errorf("failed to render plain text clarification email: %w", err)
+	}
 
-	var user models.User
-	var pauseUntil sql.NullTime
-	var verificationCode sql.NullString
-	var projectFocus sql.NullString
+	return s.QueueEmail(ctx, &userID, recipientEmail, models.EmailTypeClarification, subject, body, nil)
+}
+
+// SendClarificationAlert notifies adminEmail that userEmail is stuck in a
+// clarification loop
+func (s *Service) SendClarificationAlert(ctx context.Context, adminEmail, userEmail, thread string, attempts int, originalMessage string) error {
+	subject, body, err := RenderClarificationAlertEmail(userEmail, thread, attempts, originalMessage)
+	if err != nil {
+		return fmt.Errorf("failed to render clarification alert email: %w", err)
+	}
 
-	err := s.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID, &user.Email, &user.Name, &user.Timezone, &user.PromptTime,
-		&verificationCode, &user.IsVerified, &user.IsPaused, &pauseUntil,
-		&projectFocus, &user.CreatedAt, &user.UpdatedAt)
+	return s.QueueEmail(ctx, nil, adminEmail, models.EmailTypeAdminAlert, subject, body, nil)
+}
 
+// SendSignupQuestion asks an unverified user one signup question
+func (s *Service) SendSignupQuestion(ctx context.Context, userID int, recipientEmail string, stepNumber, stepCount int, question, example, problem string) error {
+	subject, body, err := RenderSignupQuestionEmail(stepNumber, stepCount, question, example, problem)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failed to get user by email: %w", err)
+		return fmt.Errorf("failed to render signup question: %w", err)
 	}
 
-	if verificationCode.Valid {
-		user.VerificationCode = &verificationCode.String
+	return s.QueueEmail(ctx, &userID, recipientEmail, models.EmailTypeSignupQuestion, subject, body, nil)
+}
+
+// SendTimezoneClarification asks the user which timezone an ambiguous
+// answer such as "CST" meant
+func (s *Service) SendTimezoneClarification(ctx context.Context, userID int, recipientEmail, input string, options []string, example string) error {
+	subject, body, err := RenderTimezoneClarificationEmail(input, options, example)
+	if err != nil {
+		return fmt.Errorf("failed to render timezone clarification: %w", err)
 	}
-	if pauseUntil.Valid {
-		user.PauseUntil = &pauseUntil.Time
+
+	return s.QueueEmail(ctx, &userID, recipientEmail, models.EmailTypeClarification, subject, body, nil)
+}
+
+func (s *Service) SendConfirmationEmail(ctx context.Context, userID int, recipientEmail, name, timezone string, promptTime time.Time, projectFocus *string, weekStart string) error {
+	subject, body, err := RenderConfirmationEmail(name, timezone, promptTime, projectFocus, weekStart)
+	if err != nil {
+		return fmt.Errorf("failed to render confirmation email: %w", err)
 	}
-	if projectFocus.Valid {
-		user.ProjectFocus = &projectFocus.String
+
+	return s.QueueEmail(ctx, &userID, recipientEmail, models.EmailTypeConfirmation, subject, body, nil)
+}
+
+func (s *Service) SendScheduleUpdated(ctx context.Context, userID int, recipientEmail, timezone string, promptTime time.Time) error {
+	subject, body, err := RenderScheduleUpdatedEmail(timezone, promptTime)
+	if err != nil {
+		return fmt.Errorf("failed to render schedule update email: %w", err)
 	}
 
-	return &user, nil
-}
\ No newline at end of file
+	return s.QueueEmail(ctx, &userID, recipientEmail, models.EmailTypeScheduleUpdate, subject, body, nil)
+}
+
+// SendSummaryCCRequest asks ccEmail to confirm they want copies of the requester's weekly summary
+func (s *Service) SendSummaryCCRequest(ctx context.Context, userID int, ccEmail, requesterName, requesterEmail, code string) error {
+	subject, body, err := RenderSummaryCCRequestEmail(requesterName, requesterEmail, code)
+	if err != nil {
+		return fmt.Errorf("failed to render CC request email: %w", err)
+	}
+
+	return s.QueueEmail(ctx, &userID, ccEmail, models.EmailTypeCCRequest, subject, body, nil)
+}
+
+// SendMentorRequest asks mentorEmail to confirm they want the requester's monthly digest
+func (s *Service) SendMentorRequest(ctx context.Context, userID int, mentorEmail, requesterName, requesterEmail, code string) error {
+	subject, body, err := RenderMentorRequestEmail(requesterName, requesterEmail, code)
+	if err != nil {
+		return fmt.Errorf("failed to render mentor request email: %w", err)
+	}
+
+	return s.QueueEmail(ctx, &userID, mentorEmail, models.EmailTypeMentorRequest, subject, body, nil)
+}
+
+// SendEmailChangeRequest sends newEmail the code that confirms moving the
+// user's account to it from oldEmail
+func (s *Service) SendEmailChangeRequest(ctx context.Context, userID int, oldEmail, newEmail, code string) error {
+	subject, body, err := RenderEmailChangeEmail(oldEmail, code)
+	if err != nil {
+		return fmt.Errorf("failed to render email change email: %w", err)
+	}
+
+	return s.QueueEmail(ctx, &userID, newEmail, models.EmailTypeEmailChange, subject, body, nil)
+}
+
+// SendEmailChanged tells oldEmail that the user's account moved to newEmail
+func (s *Service) SendEmailChanged(ctx context.Context, userID int, oldEmail, newEmail string, aliasUntil time.Time) error {
+	subject, body, err := RenderEmailChangedEmail(oldEmail, newEmail, aliasUntil)
+	if err != nil {
+		return fmt.Errorf("failed to render email changed email: %w", err)
+	}
+
+	return s.QueueEmail(ctx, &userID, oldEmail, models.EmailTypeEmailChange, subject, body, nil)
+}
+
+// SendEmailAliasRequest sends alias the code that confirms the user replies
+// from it
+func (s *Service) SendEmailAliasRequest(ctx context.Context, userID int, userEmail, alias, code string) error {
+	subject, body, err := RenderEmailAliasEmail(userEmail, code)
+	if err != nil {
+		return fmt.Errorf("failed to render email alias email: %w", err)
+	}
+
+	return s.QueueEmail(ctx, &userID, alias, models.EmailTypeEmailAlias, subject, body, nil)
+}
+
+// SendMentorDigest sends mentorEmail the user's digest for month
+func (s *Service) SendMentorDigest(ctx context.Context, userID int, mentorEmail, name string, month time.Time, weeks []digest.Week) error {
+	subject, body, err := RenderMentorDigestEmail(name, month, weeks)
+	if err != nil {
+		return fmt.Errorf("failed to render mentor digest: %w", err)
+	}
+
+	return s.QueueEmail(ctx, &userID, mentorEmail, models.EmailTypeMentorDigest, subject, body, nil)
+}
+
+// SendProjectRollup sends the user a quarter's rollup of one project
+func (s *Service) SendProjectRollup(ctx context.Context, userID int, recipientEmail string, rollup *models.ProjectRollup) error {
+	subject, body, err := RenderProjectRollupEmail(rollup)
+	if err != nil {
+		return fmt.Errorf("failed to render project rollup: %w", err)
+	}
+
+	return s.QueueEmail(ctx, &userID, recipientEmail, models.EmailTypeProjectRollup, subject, body, nil)
+}
+
+// SendAskAnswer emails the answer to a question the user asked about their journal
+func (s *Service) SendAskAnswer(ctx context.Context, userID int, recipientEmail, question, answer string, citations []time.Time) error {
+	subject, body, err := RenderAskAnswerEmail(question, answer, citations)
+	if err != nil {
+		return fmt.Errorf("failed to render ask answer: %w", err)
+	}
+
+	return s.QueueEmail(ctx, &userID, recipientEmail, models.EmailTypeAskAnswer, subject, body, nil)
+}
+
+func (s *Service) SendDataReport(ctx context.Context, userID int, recipientEmail string, report *models.DataReport) error {
+	subject, body, err := RenderDataReportEmail(report)
+	if err != nil {
+		return fmt.Errorf("failed to render data report: %w", err)
+	}
+
+	return s.QueueEmail(ctx, &userID, recipientEmail, models.EmailTypeDataReport, subject, body, nil)
+}
+
+// SendMagicLink emails the user a link that signs them in to the dashboard
+func (s *Service) SendMagicLink(ctx context.Context, userID int, recipientEmail, loginURL string, expires time.Duration) error {
+	subject, body, err := RenderMagicLinkEmail(loginURL, expires)
+	if err != nil {
+		return fmt.Errorf("failed to render magic link email: %w", err)
+	}
+
+	return s.QueueEmail(ctx, &userID, recipientEmail, models.EmailTypeMagicLink, subject, body, nil)
+}
+
+// SendBillingEmail sends billing notice to an organization's billing
+// contact, who may not be a user
+func (s *Service) SendBillingEmail(ctx context.Context, recipientEmail, notice, orgName string, graceUntil *time.Time, invoiceURL string) error {
+	subject, body, err := RenderBillingEmail(notice, orgName, graceUntil, invoiceURL)
+	if err != nil {
+		return fmt.Errorf("failed to render billing email: %w", err)
+	}
+
+	return s.QueueEmail(ctx, nil, recipientEmail, models.EmailTypeBilling, subject, body, nil)
+}
+
+// SendAlreadySignedUp tells a verified user who signed up again that they
+// already are, and how to change their settings. It is sent at most once per
+// alreadySignedUpInterval, so repeated signups can't flood the inbox.
+func (s *Service) SendAlreadySignedUp(ctx context.Context, user *models.User) error {
+	var recent bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM email_logs
+			WHERE user_id = $1 AND email_type = $2 AND created_at >= $3
+		)`
+	err := s.db.QueryRowContext(ctx, query, user.ID, models.EmailTypeAlreadySignedUp, time.Now().Add(-alreadySignedUpInterval)).Scan(&recent)
+	if err != nil {
+		return fmt.Errorf("failed to check recent signup emails: %w", err)
+	}
+	if recent {
+		logrus.WithField("user_id", user.ID).Info("Already sent a signed up notice recently, skipping")
+		return nil
+	}
+
+	loginURL := ""
+	if s.config.DashboardURL != "" {
+		loginURL = s.config.DashboardURL + "/app/login"
+	}
+	subject, body, err := RenderAlreadySignedUpEmail(user.Name, user.Timezone, user.PromptTime, loginURL)
+	if err != nil {
+		return fmt.Errorf("failed to render already signed up email: %w", err)
+	}
+
+	return s.QueueEmail(ctx, &user.ID, user.Email, models.EmailTypeAlreadySignedUp, subject, body, nil)
+}
+
+// GetUserByEmail retrieves user from database
+func (s *Service) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.users.GetByEmail(ctx, email)
+}
+
+// GetUserByID retrieves a user by id, returning nil if there is none
+func (s *Service) GetUserByID(ctx context.Context, userID int) (*models.User, error) {
+	return s.users.GetByID(ctx, userID)
+}
+
+// Users is the repository user lookups go through; invalidate a user there
+// after changing their row
+func (s *Service) Users() *users.Repository {
+	return s.users
+}
@@ -0,0 +1,222 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ses"
+	"github.com/aws/aws-sdk-go-v2/service/ses/types"
+	"github.com/sirupsen/logrus"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// sesMaxBulkDestinations is the most recipients SendBulkTemplatedEmail takes
+// in one call
+const sesMaxBulkDestinations = 50
+
+// sesBulkTypes are the batch email types sent in bulk under
+// SES_BULK_TEMPLATES. Each ends with the account footer, which its SES
+// template carries, so a message only passes SES its subject and what comes
+// before the footer.
+var sesBulkTypes = []string{
+	models.EmailTypeWeeklySummary,
+	models.EmailTypeAnnouncement,
+	models.EmailTypeProjectRollup,
+}
+
+// sesTemplateData is the replacement data of one bulk message. Triple braces
+// in the template keep SES from HTML-escaping it in a text part.
+type sesTemplateData struct {
+	Subject string `json:"subject"`
+	Content string `json:"content"`
+}
+
+// sesTemplates are the SES templates bulk sends use, built from the local
+// layout and footer
+type sesTemplates struct {
+	footer string
+	// names maps each of sesBulkTypes to its template's name
+	names map[string]string
+}
+
+// newSESTemplates builds the templates named with prefix. A template's name
+// ends with a hash of its content, so after the layout or footer changes a
+// process sends through the templates it was built with, or individually
+// if they haven't been synced yet, rather than through stale ones.
+func newSESTemplates(prefix string) (*sesTemplates, error) {
+	tmpl, err := parseInLayout(templateFS, "ses_template", "", footerAccount)
+	if err != nil {
+		return nil, err
+	}
+	var footer bytes.Buffer
+	if err := tmpl.Execute(&footer, nil); err != nil {
+		return nil, fmt.Errorf("failed to render footer: %w", err)
+	}
+
+	t := &sesTemplates{footer: footer.String(), names: map[string]string{}}
+	hash := sha256.Sum256([]byte(t.subjectPart() + t.textPart()))
+	for _, emailType := range sesBulkTypes {
+		t.names[emailType] = fmt.Sprintf("%s-%s-%s", prefix, emailType, hex.EncodeToString(hash[:4]))
+	}
+	return t, nil
+}
+
+func (t *sesTemplates) subjectPart() string {
+	return "{{{subject}}}"
+}
+
+func (t *sesTemplates) textPart() string {
+	return "{{{content}}}" + t.footer
+}
+
+// SESTemplates returns the SES templates for bulk sends under prefix, which
+// `cli infra sync-ses-templates` creates
+func SESTemplates(prefix string) ([]types.Template, error) {
+	t, err := newSESTemplates(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]types.Template, 0, len(sesBulkTypes))
+	for _, emailType := range sesBulkTypes {
+		templates = append(templates, types.Template{
+			TemplateName: aws.String(t.names[emailType]),
+			SubjectPart:  aws.String(t.subjectPart()),
+			TextPart:     aws.String(t.textPart()),
+		})
+	}
+	return templates, nil
+}
+
+// sendsInBulk reports whether email can go out through its type's template:
+// a text-only message with no Reply-To, since SES sets Reply-To per call,
+// whose body ends with the footer the template adds
+func (t *sesTemplates) sendsInBulk(email *models.EmailLog) bool {
+	_, ok := t.names[email.EmailType]
+	return ok && email.BodyHTML == nil && email.ReplyTo == nil && strings.HasSuffix(email.BodyText, t.footer)
+}
+
+// batch returns the leading emails of one type that can go out in one bulk
+// call to at most maxRecipients addresses, -1 for any number, or nil if
+// fewer than two can
+func (t *sesTemplates) batch(emails []*models.EmailLog, maxRecipients int) []*models.EmailLog {
+	n, recipients := 0, 0
+	for n < len(emails) && n < sesMaxBulkDestinations {
+		email := emails[n]
+		if !t.sendsInBulk(email) || email.EmailType != emails[0].EmailType {
+			break
+		}
+		if maxRecipients >= 0 && recipients+1+len(email.CCEmails) > maxRecipients {
+			break
+		}
+		recipients += 1 + len(email.CCEmails)
+		n++
+	}
+	if n < 2 {
+		return nil
+	}
+	return emails[:n]
+}
+
+// data returns the replacement data that renders email, with its subject
+// tagged with subjectTag, through its template
+func (t *sesTemplates) data(email *models.EmailLog, subjectTag string) string {
+	// Encoding two strings can't fail
+	data, _ := json.Marshal(sesTemplateData{
+		Subject: subjectTag + email.Subject,
+		Content: strings.TrimSuffix(email.BodyText, t.footer),
+	})
+	return string(data)
+}
+
+// deliverBulk sends emails, which sesTemplates.batch chose, in one
+// SendBulkTemplatedEmail call and records each one's outcome. If the call
+// fails, say because the templates haven't been synced, they are sent one by
+// one instead. If SES throttles the call or any message, it reports
+// throttled so the run stops and the emails not sent go back to pending.
+func (s *Service) deliverBulk(ctx context.Context, emails []*models.EmailLog) (throttled bool) {
+	template := s.sesTemplates.names[emails[0].EmailType]
+	subjectTag := s.config.SubjectTag()
+	bcc := s.archive.bccAddresses(emails[0].EmailType)
+
+	input := &ses.SendBulkTemplatedEmailInput{
+		Source:              aws.String(s.config.EmailFrom),
+		Template:            aws.String(template),
+		DefaultTemplateData: aws.String("{}"),
+	}
+	for _, email := range emails {
+		input.Destinations = append(input.Destinations, types.BulkEmailDestination{
+			Destination: &types.Destination{
+				ToAddresses:  []string{email.RecipientEmail},
+				CcAddresses:  email.CCEmails,
+				BccAddresses: bcc,
+			},
+			ReplacementTemplateData: aws.String(s.sesTemplates.data(email, subjectTag)),
+		})
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"template": template, "emails": len(emails)})
+	result, err := s.sesClient.SendBulkTemplatedEmail(ctx, input)
+	if isThrottling(err) {
+		logger.WithError(err).Warn("SES throttled the outbox, returning emails to pending")
+		return true
+	}
+	if err != nil {
+		var missing *types.TemplateDoesNotExistException
+		if errors.As(err, &missing) {
+			logger.Warn("SES template missing, sending individually; run `cli infra sync-ses-templates`")
+		} else {
+			logger.WithError(err).Warn("Bulk send failed, sending individually")
+		}
+		for _, email := range emails {
+			if s.deliverQueued(ctx, email) {
+				return true
+			}
+		}
+		return false
+	}
+
+	sentAt := time.Now()
+	for i, status := range result.Status {
+		if i >= len(emails) {
+			break
+		}
+		email := emails[i]
+
+		switch status.Status {
+		case types.BulkEmailStatusSuccess:
+			messageID := aws.ToString(status.MessageId)
+			if err := s.archive.store(ctx, email, s.config.EmailFrom, subjectTag+email.Subject, messageID, sentAt); err != nil {
+				logrus.WithError(err).WithField("email_id", email.ID).Error("Failed to archive sent email")
+			}
+			if err := s.markEmailSent(ctx, email.ID, messageID); err != nil {
+				logrus.WithError(err).WithField("email_id", email.ID).Error("Failed to mark email as sent")
+			}
+		case types.BulkEmailStatusAccountThrottled, types.BulkEmailStatusAccountDailyQuotaExceeded, types.BulkEmailStatusTransientFailure:
+			// Left claimed for deliverPage to return to pending
+			throttled = true
+		default:
+			sendErr := fmt.Errorf("SES bulk send failed with %s: %s", status.Status, aws.ToString(status.Error))
+			if status.Status == types.BulkEmailStatusMessageRejected {
+				sendErr = apperrors.Wrap(apperrors.CodeSESRejected, sendErr, "SES rejected email")
+			}
+			logrus.WithError(sendErr).WithField("email_id", email.ID).Error("Failed to send email")
+			s.recordFailure(ctx, email, sendErr)
+		}
+	}
+
+	if throttled {
+		logger.Warn("SES throttled part of a bulk send, returning those emails to pending")
+	}
+	return throttled
+}
@@ -0,0 +1,114 @@
+package email
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// renderSESTemplate replaces the template's fields the way SES does for
+// triple-brace tags
+func renderSESTemplate(part, data string) string {
+	var fields map[string]string
+	json.Unmarshal([]byte(data), &fields)
+	for name, value := range fields {
+		part = strings.ReplaceAll(part, "{{{"+name+"}}}", value)
+	}
+	return part
+}
+
+func TestSESTemplatesRenderQueuedEmail(t *testing.T) {
+	templates, err := SESTemplates("wdygdtw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != len(sesBulkTypes) {
+		t.Fatalf("got %d templates, want %d", len(templates), len(sesBulkTypes))
+	}
+	name := aws.ToString(templates[0].TemplateName)
+	if !strings.HasPrefix(name, "wdygdtw-weekly_summary-") {
+		t.Errorf("template name = %q", name)
+	}
+
+	weekStart := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	subject, body, err := RenderWeeklySummaryEmail(weekStart, weekStart.AddDate(0, 0, 4), "A good week.", []string{"Shipped {{it}}"}, nil, nil, nil, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ses, err := newSESTemplates("wdygdtw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := &models.EmailLog{EmailType: models.EmailTypeWeeklySummary, Subject: subject, BodyText: body}
+	if !ses.sendsInBulk(email) {
+		t.Fatal("a rendered weekly summary can't be sent in bulk")
+	}
+
+	data := ses.data(email, "[staging] ")
+	if got := renderSESTemplate(aws.ToString(templates[0].TextPart), data); got != body {
+		t.Errorf("template renders\n%s\nwant\n%s", got, body)
+	}
+	if got := renderSESTemplate(aws.ToString(templates[0].SubjectPart), data); got != "[staging] "+subject {
+		t.Errorf("template subject = %q", got)
+	}
+}
+
+func TestSESTemplatesBatch(t *testing.T) {
+	ses, err := newSESTemplates("wdygdtw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := func() *models.EmailLog {
+		return &models.EmailLog{EmailType: models.EmailTypeWeeklySummary, BodyText: "Done." + ses.footer}
+	}
+	html, replyTo := "<p>Done.</p>", "reply@example.com"
+	tracked, withReplyTo, withCC := summary(), summary(), summary()
+	tracked.BodyHTML = &html
+	withReplyTo.ReplyTo = &replyTo
+	withCC.CCEmails = []string{"boss@example.com"}
+	announcement := summary()
+	announcement.EmailType = models.EmailTypeAnnouncement
+	prompt := summary()
+	prompt.EmailType = models.EmailTypeDailyPrompt
+	unfootered := summary()
+	unfootered.BodyText = "Done."
+
+	tests := []struct {
+		name          string
+		emails        []*models.EmailLog
+		maxRecipients int
+		want          int
+	}{
+		{"run of one type", []*models.EmailLog{summary(), summary(), summary(), announcement}, -1, 3},
+		{"single email", []*models.EmailLog{summary(), announcement, summary()}, -1, 0},
+		{"not a bulk type", []*models.EmailLog{prompt, prompt}, -1, 0},
+		{"tracked", []*models.EmailLog{summary(), summary(), tracked, summary()}, -1, 2},
+		{"reply-to", []*models.EmailLog{withReplyTo, summary(), summary()}, -1, 0},
+		{"different footer", []*models.EmailLog{summary(), summary(), unfootered}, -1, 2},
+		{"quota", []*models.EmailLog{summary(), summary(), summary()}, 2, 2},
+		{"quota counts CC", []*models.EmailLog{summary(), withCC, summary()}, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(ses.batch(tt.emails, tt.maxRecipients)); got != tt.want {
+				t.Errorf("batch() has %d emails, want %d", got, tt.want)
+			}
+		})
+	}
+
+	many := make([]*models.EmailLog, 120)
+	for i := range many {
+		many[i] = summary()
+	}
+	if got := len(ses.batch(many, -1)); got != sesMaxBulkDestinations {
+		t.Errorf("batch() of 120 has %d emails, want %d", got, sesMaxBulkDestinations)
+	}
+}
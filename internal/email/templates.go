@@ -4,14 +4,135 @@ import (
 	"bytes"
 	"embed"
 	"fmt"
+	"io/fs"
 	"math/rand"
+	"path"
+	"strings"
 	"text/template"
 	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/digest"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/entryformat"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/stats"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
 )
 
-//go:embed ../../templates/*.txt
+//go:embed templates/*.txt templates/layouts/*.txt templates/partials/*.txt templates/announcements/*.txt
 var templateFS embed.FS
 
+// Footers, defined in templates/partials, that the base layout ends an
+// email with
+const (
+	footerAccount = "footer_account" // to users: how to take a break
+	footerInvite  = "footer_invite"  // to people a user asked us to email
+	footerBrand   = "footer_brand"   // signup and sign-in mail
+	footerNone    = ""               // alerts to the admin
+)
+
+// emailFooters registers every template under templates/ with the footer it
+// gets. ValidateTemplates fails on a template missing from it.
+var emailFooters = map[string]string{
+	"already_signed_up.txt":      footerAccount,
+	"anomaly_report.txt":         footerNone,
+	"ask_answer.txt":             footerAccount,
+	"billing_payment_failed.txt": footerBrand,
+	"billing_reminder.txt":       footerBrand,
+	"billing_restored.txt":       footerBrand,
+	"billing_suspended.txt":      footerBrand,
+	"cc_request.txt":             footerInvite,
+	"clarification.txt":          footerAccount,
+	"clarification_alert.txt":    footerNone,
+	"clarification_plain.txt":    footerAccount,
+	"confirmation.txt":           footerBrand,
+	"daily_prompt.txt":           footerAccount,
+	"data_report.txt":            footerAccount,
+	"email_change.txt":           footerBrand,
+	"email_changed.txt":          footerBrand,
+	"email_alias.txt":            footerBrand,
+	"magic_link.txt":             footerBrand,
+	"mentor_digest.txt":          footerInvite,
+	"mentor_request.txt":         footerInvite,
+	"outbox_alert.txt":           footerNone,
+	"project_rollup.txt":         footerAccount,
+	"schedule_updated.txt":       footerAccount,
+	"signup_question.txt":        footerBrand,
+	"timezone_clarification.txt": footerBrand,
+	"weekly_goals.txt":           footerAccount,
+	"weekly_summary.txt":         footerAccount,
+	"weekly_summary_light.txt":   footerAccount,
+	"welcome.txt":                footerBrand,
+}
+
+// renderEmail renders the template called name, under templates/, in the
+// base layout
+func renderEmail(name string, data interface{}) (string, error) {
+	src, err := templateFS.ReadFile(path.Join("templates", name))
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := parseInLayout(templateFS, name, string(src), emailFooters[name])
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ParseAnnouncement parses a broadcast announcement in the base layout, so
+// it ends with the same footer as every other email to users
+func ParseAnnouncement(name, text string) (*template.Template, error) {
+	if name == "" {
+		name = "announcement"
+	}
+	return parseInLayout(templateFS, name, text, footerAccount)
+}
+
+// parseInLayout parses text as the content of templates/layouts/base.txt,
+// with every partial available to it and footer, a partial's name or
+// footerNone, as its footer
+func parseInLayout(fsys fs.FS, name, text, footer string) (*template.Template, error) {
+	tmpl, err := template.ParseFS(fsys, "templates/layouts/base.txt", "templates/partials/*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse layout: %w", err)
+	}
+
+	// Content decides its own spacing; the layout adds the footer's
+	if _, err := tmpl.New(name).Parse(strings.TrimRight(text, "\n")); err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.New("content").Parse(fmt.Sprintf(`{{template %q .}}`, name)); err != nil {
+		return nil, err
+	}
+	if footer != footerNone {
+		footer = fmt.Sprintf(`{{template %q .}}`, footer)
+	}
+	if _, err := tmpl.New("footer").Parse(footer); err != nil {
+		return nil, err
+	}
+	return tmpl.Lookup("base.txt"), nil
+}
+
+// AnnouncementData is passed to broadcast announcement templates
+type AnnouncementData struct {
+	Name  string
+	Email string
+}
+
+// Announcement returns the embedded announcement template called name, such
+// as "announce.txt"
+func Announcement(name string) (string, error) {
+	body, err := templateFS.ReadFile(path.Join("templates/announcements", path.Base(name)))
+	if err != nil {
+		return "", fmt.Errorf("no announcement template %q", name)
+	}
+	return string(body), nil
+}
+
 type TemplateData struct {
 	// Welcome email
 	VerificationCode string
@@ -19,117 +140,636 @@ type TemplateData struct {
 	// Daily prompt
 	DayOfWeek    string
 	Date         string
+	Opener       string
 	ProjectFocus string
 	Quote        string
+	Recap        string
+	EntryFormat  string
+	Skeleton     string
 
-	// Weekly summary
+	// Weekly summary; PeriodName is "Week", or "Sprint" for a longer period,
+	// and MissedDays lists the days without an entry in a light week
+	PeriodName        string
+	MissedDays        string
 	WeekStart         string
 	WeekEnd           string
 	SummaryParagraph  string
 	BulletPoints      []string
+	EnergyTrend       string
+	MonthlyTrend      string
+	MonthlyWeeks      []TrendWeek
+	Lookback          *Lookback
+	CardURL           string
+	Goals             []string
 
 	// Clarification
 	OriginalMessage string
+	UserEmail       string
+	Thread          string
+	Attempts        int
+
+	// Confirmation
+	Name       string
+	Timezone   string
+	PromptTime string
+	WeekStarts string
+
+	// Summary CC and mentor requests
+	RequesterEmail string
+
+	// Mentor digest
+	Month       string
+	DigestWeeks []digest.Week
+
+	// Project rollup
+	Project      string
+	ProjectSpan  string
+	ProjectDates string
+	Quarter      string
+
+	// Journal question
+	Question  string
+	Answer    string
+	Citations []string
+
+	// Data report
+	Report *models.DataReport
+
+	// Dashboard sign-in
+	LoginURL     string
+	LoginExpires string
+
+	// Email address change
+	NewEmail   string
+	AliasUntil string
+
+	// Outbox alert
+	Outbox    *OutboxStatus
+	OutboxAge string
+
+	// Signup question
+	StepNumber int
+	StepCount  int
+	Example    string
+	Problem    string
+
+	// Timezone clarification
+	TimezoneInput   string
+	TimezoneOptions []string
+
+	// Anomaly report
+	Anomalies *models.AnomalyReport
+
+	// Billing notices
+	OrgName    string
+	GraceUntil string
+	InvoiceURL string
 }
 
-var quotes = []string{
-	"The way to get started is to quit talking and begin doing. - Walt Disney",
-	"Innovation distinguishes between a leader and a follower. - Steve Jobs",
-	"Your limitation—it's only your imagination.",
-	"Push yourself, because no one else is going to do it for you.",
-	"Great things never come from comfort zones.",
-	"Dream it. Wish it. Do it.",
-	"Success doesn't just find you. You have to go out and get it.",
-	"The harder you work for something, the greater you'll feel when you achieve it.",
-	"Don't stop when you're tired. Stop when you're done.",
-	"Wake up with determination. Go to bed with satisfaction.",
+// Lookback is the weekly summary's "this time last quarter" line: an entry
+// from a quarter ago related to this week's work
+type Lookback struct {
+	Date    time.Time
+	Excerpt string
 }
 
-func RenderWelcomeEmail(verificationCode string) (string, string, error) {
-	tmpl, err := template.ParseFS(templateFS, "../../templates/welcome.txt")
-	if err != nil {
-		return "", "", fmt.Errorf("failed to parse welcome template: %w", err)
-	}
+// TrendWeek is one row of the monthly trend section
+type TrendWeek struct {
+	Label   string
+	Bar     string
+	Entries int
+}
 
+func RenderWelcomeEmail(verificationCode string) (string, string, error) {
 	data := TemplateData{
 		VerificationCode: verificationCode,
 	}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", "", fmt.Errorf("failed to execute welcome template: %w", err)
+	body, err := renderEmail("welcome.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render welcome template: %w", err)
 	}
 
 	subject := "Welcome to What Did You Get Done This Week?"
-	return subject, buf.String(), nil
+	return subject, body, nil
 }
 
-func RenderDailyPromptEmail(projectFocus *string) (string, string, error) {
-	tmpl, err := template.ParseFS(templateFS, "../../templates/daily_prompt.txt")
-	if err != nil {
-		return "", "", fmt.Errorf("failed to parse daily prompt template: %w", err)
-	}
+// RenderDailyPromptEmail renders the prompt, appending the section skeleton
+// when entryFormat is a guided format. recap, the line recalling the last
+// entry, and quote are left out when empty. The subject and the line asking
+// for a reply vary by day as variation sets out.
+func RenderDailyPromptEmail(projectFocus *string, entryFormat, quote, recap string, variation PromptVariation) (string, string, error) {
+	return renderDailyPromptEmail(time.Now(), projectFocus, entryFormat, quote, recap, variation)
+}
 
-	now := time.Now()
+// renderDailyPromptEmail renders the prompt for the day of now
+func renderDailyPromptEmail(now time.Time, projectFocus *string, entryFormat, quote, recap string, variation PromptVariation) (string, string, error) {
 	data := TemplateData{
 		DayOfWeek: now.Format("Monday"),
 		Date:      now.Format("January 2, 2006"),
-		Quote:     quotes[rand.Intn(len(quotes))],
+		Opener:    variation.opener(now),
+		Quote:     quote,
+		Recap:     recap,
 	}
 
 	if projectFocus != nil {
 		data.ProjectFocus = *projectFocus
 	}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", "", fmt.Errorf("failed to execute daily prompt template: %w", err)
+	if format, ok := entryformat.Lookup(entryFormat); ok {
+		data.EntryFormat = format.Name
+		data.Skeleton = format.Skeleton()
+	}
+
+	body, err := renderEmail("daily_prompt.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render daily prompt template: %w", err)
 	}
 
-	subject := fmt.Sprintf("What did you get done today? - %s", now.Format("Jan 2"))
-	return subject, buf.String(), nil
+	return variation.subject(now), body, nil
 }
 
-func RenderWeeklySummaryEmail(weekStart time.Time, summaryParagraph string, bulletPoints []string) (string, string, error) {
-	tmpl, err := template.ParseFS(templateFS, "../../templates/weekly_summary.txt")
+// dailyPromptSubject is the first of dailyPromptSubjects, which end with the
+// prompt's date in promptDateLayout
+const (
+	dailyPromptSubject = "What did you get done today?"
+	promptDateLayout   = "Jan 2"
+)
+
+// PromptDate returns the date of the daily prompt subject is a reply to.
+// The subject has no year, so it is the latest such date at or before now.
+func PromptDate(subject string, now time.Time) (time.Time, bool) {
+	m := promptSubjectDate.FindStringSubmatch(subject)
+	if m == nil {
+		return time.Time{}, false
+	}
+	date, err := time.Parse(promptDateLayout, m[1])
 	if err != nil {
-		return "", "", fmt.Errorf("failed to parse weekly summary template: %w", err)
+		return time.Time{}, false
 	}
 
-	weekEnd := weekStart.AddDate(0, 0, 4) // Friday
+	date = time.Date(now.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if date.After(now) {
+		date = date.AddDate(-1, 0, 0)
+	}
+	return date, true
+}
+
+// RenderWeeklySummaryEmail renders a weekly summary. cardURL links its share
+// card, if one was made.
+// RenderWeeklySummaryEmail lists goals, the week's goals from the Monday
+// prompt, after the accomplishments when there are any. A summary of more
+// than a week, from a sprint calendar, is titled as a sprint. A light week,
+// one with stats.LightWeekMissedDays or more missedDays, gets the gentler
+// weekly_summary_light.txt, which names the days without entries.
+func RenderWeeklySummaryEmail(weekStart, weekEnd time.Time, summaryParagraph string, bulletPoints []string, goals []string, missedDays []time.Time, trend *stats.Trend, lookback *Lookback, cardURL string) (string, string, error) {
+	periodName := "Week"
+	if weekEnd.Sub(weekStart) >= 7*24*time.Hour {
+		periodName = "Sprint"
+	}
 	data := TemplateData{
+		PeriodName:       periodName,
 		WeekStart:        weekStart.Format("Jan 2"),
 		WeekEnd:          weekEnd.Format("Jan 2"),
 		SummaryParagraph: summaryParagraph,
 		BulletPoints:     bulletPoints,
+		Lookback:         lookback,
+		CardURL:          cardURL,
+		Goals:            goals,
 	}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", "", fmt.Errorf("failed to execute weekly summary template: %w", err)
+	if trend != nil {
+		data.EnergyTrend = trend.Week
+		data.MonthlyTrend = trend.MonthSparkline()
+		for _, week := range trend.Month {
+			row := TrendWeek{Label: week.WeekStart.Format("Jan 2"), Entries: week.Entries}
+			if week.Entries > 0 {
+				row.Bar = stats.Sparkline([]float64{week.Energy})
+			}
+			data.MonthlyWeeks = append(data.MonthlyWeeks, row)
+		}
+	}
+
+	name := "weekly_summary.txt"
+	if stats.IsLightWeek(missedDays) {
+		name = "weekly_summary_light.txt"
+		data.MissedDays = period.DayList(missedDays)
 	}
 
-	subject := fmt.Sprintf("This is What I Did This Week - %s", weekStart.Format("Jan 2"))
-	return subject, buf.String(), nil
+	body, err := renderEmail(name, data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render weekly summary template: %w", err)
+	}
+
+	subject := fmt.Sprintf("This is What I Did This %s - %s", periodName, weekStart.Format("Jan 2"))
+	return subject, body, nil
 }
 
-func RenderClarificationEmail(originalMessage string) (string, string, error) {
-	tmpl, err := template.ParseFS(templateFS, "../../templates/clarification.txt")
+// weeklyGoalsSubject starts the Monday goals prompt's subject; replies to it
+// are saved as goals rather than entries
+const weeklyGoalsSubject = "What will you get done this week?"
+
+// IsWeeklyGoalsReply reports whether subject is a reply to the Monday goals
+// prompt
+func IsWeeklyGoalsReply(subject string) bool {
+	return strings.Contains(subject, weeklyGoalsSubject)
+}
+
+func RenderWeeklyGoalsEmail(weekStart time.Time, projectFocus *string) (string, string, error) {
+	data := TemplateData{
+		WeekStart: weekStart.Format("Jan 2"),
+		WeekEnd:   period.SummaryEnd(weekStart).Format("Jan 2"),
+	}
+	if projectFocus != nil {
+		data.ProjectFocus = *projectFocus
+	}
+
+	body, err := renderEmail("weekly_goals.txt", data)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to parse clarification template: %w", err)
+		return "", "", fmt.Errorf("failed to render weekly goals template: %w", err)
 	}
 
+	subject := fmt.Sprintf("%s - %s", weeklyGoalsSubject, weekStart.Format("Jan 2"))
+	return subject, body, nil
+}
+
+func RenderClarificationEmail(originalMessage string) (string, string, error) {
 	data := TemplateData{
 		OriginalMessage: originalMessage,
 	}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", "", fmt.Errorf("failed to execute clarification template: %w", err)
+	body, err := renderEmail("clarification.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render clarification template: %w", err)
 	}
 
 	subject := "Clarification needed for your journal entry"
-	return subject, buf.String(), nil
+	return subject, body, nil
+}
+
+// RenderPlainTextClarificationEmail asks for a plain description of the day,
+// sent instead of another clarification once a thread keeps failing to parse
+func RenderPlainTextClarificationEmail() (string, string, error) {
+	body, err := renderEmail("clarification_plain.txt", TemplateData{})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render plain text clarification template: %w", err)
+	}
+
+	subject := "Just tell us about your day in plain text"
+	return subject, body, nil
+}
+
+// RenderClarificationAlertEmail tells an admin that a user's replies keep
+// failing to parse
+func RenderClarificationAlertEmail(userEmail, thread string, attempts int, originalMessage string) (string, string, error) {
+	data := TemplateData{
+		OriginalMessage: originalMessage,
+		UserEmail:       userEmail,
+		Thread:          thread,
+		Attempts:        attempts,
+	}
+
+	body, err := renderEmail("clarification_alert.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render clarification alert template: %w", err)
+	}
+
+	subject := fmt.Sprintf("Clarification loop for %s", userEmail)
+	return subject, body, nil
+}
+
+// RenderSignupQuestionEmail asks one signup question. problem, if set,
+// explains why the previous answer to the same question was not understood.
+func RenderSignupQuestionEmail(stepNumber, stepCount int, question, example, problem string) (string, string, error) {
+	data := TemplateData{
+		StepNumber: stepNumber,
+		StepCount:  stepCount,
+		Question:   question,
+		Example:    example,
+		Problem:    problem,
+	}
+
+	body, err := renderEmail("signup_question.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render signup question template: %w", err)
+	}
+
+	subject := fmt.Sprintf("Quick setup (%d of %d): %s", stepNumber, stepCount, question)
+	return subject, body, nil
+}
+
+// RenderTimezoneClarificationEmail asks which of options input meant.
+// example is how the reply should name the chosen timezone.
+func RenderTimezoneClarificationEmail(input string, options []string, example string) (string, string, error) {
+	data := TemplateData{
+		TimezoneInput:   input,
+		TimezoneOptions: options,
+		Example:         example,
+	}
+
+	body, err := renderEmail("timezone_clarification.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render timezone clarification template: %w", err)
+	}
+
+	subject := fmt.Sprintf("Which timezone did you mean by %q?", input)
+	return subject, body, nil
+}
+
+func RenderConfirmationEmail(name, timezone string, promptTime time.Time, projectFocus *string, weekStart string) (string, string, error) {
+	data := TemplateData{
+		Name:       name,
+		Timezone:   timezone,
+		PromptTime: promptTime.Format("15:04"),
+		WeekStarts: period.FirstWeekday(weekStart).String(),
+	}
+
+	if projectFocus != nil {
+		data.ProjectFocus = *projectFocus
+	}
+
+	body, err := renderEmail("confirmation.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render confirmation template: %w", err)
+	}
+
+	subject := "Please confirm your preferences"
+	return subject, body, nil
+}
+
+func RenderScheduleUpdatedEmail(timezone string, promptTime time.Time) (string, string, error) {
+	data := TemplateData{
+		Timezone:   timezone,
+		PromptTime: promptTime.Format("15:04"),
+	}
+
+	body, err := renderEmail("schedule_updated.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render schedule update template: %w", err)
+	}
+
+	subject := fmt.Sprintf("Your daily prompt is now at %s %s", promptTime.Format("15:04"), timezone)
+	return subject, body, nil
+}
+
+func RenderSummaryCCRequestEmail(requesterName, requesterEmail, code string) (string, string, error) {
+	data := TemplateData{
+		Name:             requesterName,
+		RequesterEmail:   requesterEmail,
+		VerificationCode: code,
+	}
+
+	body, err := renderEmail("cc_request.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render CC request template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s wants to CC you on their weekly summary", requesterName)
+	return subject, body, nil
+}
+
+func RenderMentorRequestEmail(requesterName, requesterEmail, code string) (string, string, error) {
+	data := TemplateData{
+		Name:             requesterName,
+		RequesterEmail:   requesterEmail,
+		VerificationCode: code,
+	}
+
+	body, err := renderEmail("mentor_request.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render mentor request template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s would like you to be their mentor", requesterName)
+	return subject, body, nil
+}
+
+// RenderMentorDigestEmail renders the monthly digest of name's summaries for
+// the month starting at month
+func RenderMentorDigestEmail(name string, month time.Time, weeks []digest.Week) (string, string, error) {
+	data := TemplateData{
+		Name:        name,
+		Month:       month.Format("January 2006"),
+		DigestWeeks: weeks,
+	}
+
+	body, err := renderEmail("mentor_digest.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render mentor digest template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s's month: %s", name, data.Month)
+	return subject, body, nil
+}
+
+// RenderProjectRollupEmail renders a quarter's rollup of one project:
+// "3 months on Project Atlas"
+func RenderProjectRollupEmail(rollup *models.ProjectRollup) (string, string, error) {
+	data := TemplateData{
+		Project:          rollup.Project,
+		ProjectSpan:      period.DurationLabel(rollup.FirstEntryDate, rollup.LastEntryDate),
+		ProjectDates:     rollup.FirstEntryDate.Format("Jan 2") + " - " + rollup.LastEntryDate.Format("Jan 2, 2006"),
+		Quarter:          period.QuarterLabel(rollup.QuarterStart),
+		SummaryParagraph: rollup.SummaryParagraph,
+		BulletPoints:     rollup.BulletPoints,
+	}
+
+	body, err := renderEmail("project_rollup.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render project rollup template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s on %s", data.ProjectSpan, rollup.Project)
+	return subject, body, nil
+}
+
+// RenderAskAnswerEmail renders the answer to a question about the journal,
+// with the cited entry dates
+func RenderAskAnswerEmail(question, answer string, citations []time.Time) (string, string, error) {
+	data := TemplateData{
+		Question: question,
+		Answer:   answer,
+	}
+	for _, date := range citations {
+		data.Citations = append(data.Citations, date.Format("Monday, January 2, 2006"))
+	}
+
+	body, err := renderEmail("ask_answer.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render ask answer template: %w", err)
+	}
+
+	subject := "Re: " + question
+	return subject, body, nil
+}
+
+func RenderDataReportEmail(report *models.DataReport) (string, string, error) {
+	data := TemplateData{
+		Report: report,
+	}
+
+	body, err := renderEmail("data_report.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render data report template: %w", err)
+	}
+
+	subject := "Your data report"
+	return subject, body, nil
+}
+
+// RenderMagicLinkEmail renders the dashboard sign-in link, valid for expires
+func RenderMagicLinkEmail(loginURL string, expires time.Duration) (string, string, error) {
+	data := TemplateData{
+		LoginURL:     loginURL,
+		LoginExpires: fmt.Sprintf("%d minutes", int(expires.Minutes())),
+	}
+
+	body, err := renderEmail("magic_link.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render magic link template: %w", err)
+	}
+
+	subject := "Your sign-in link"
+	return subject, body, nil
+}
+
+// RenderEmailChangeEmail asks the new address of an account moving from
+// oldEmail to confirm with code
+func RenderEmailChangeEmail(oldEmail, code string) (string, string, error) {
+	data := TemplateData{
+		UserEmail:        oldEmail,
+		VerificationCode: code,
+	}
+
+	body, err := renderEmail("email_change.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render email change template: %w", err)
+	}
+
+	subject := "Confirm your new email address"
+	return subject, body, nil
+}
+
+// RenderEmailChangedEmail tells the old address that the account moved to
+// newEmail, and how to move it back while replies from it still count
+func RenderEmailChangedEmail(oldEmail, newEmail string, aliasUntil time.Time) (string, string, error) {
+	data := TemplateData{
+		UserEmail:  oldEmail,
+		NewEmail:   newEmail,
+		AliasUntil: aliasUntil.Format("January 2, 2006"),
+	}
+
+	body, err := renderEmail("email_changed.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render email changed template: %w", err)
+	}
+
+	subject := "Your email address has changed"
+	return subject, body, nil
+}
+
+// RenderEmailAliasEmail asks an address the user with userEmail wants to
+// reply from to confirm with code
+func RenderEmailAliasEmail(userEmail, code string) (string, string, error) {
+	data := TemplateData{
+		UserEmail:        userEmail,
+		VerificationCode: code,
+	}
+
+	body, err := renderEmail("email_alias.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render email alias template: %w", err)
+	}
+
+	subject := "Confirm replies from this address"
+	return subject, body, nil
+}
+
+// RenderAlreadySignedUpEmail answers a signup from an address that is
+// already verified with the account's schedule and how to change it.
+// loginURL is the dashboard sign-in page, or empty without a dashboard.
+func RenderAlreadySignedUpEmail(name, timezone string, promptTime time.Time, loginURL string) (string, string, error) {
+	data := TemplateData{
+		Name:       name,
+		Timezone:   timezone,
+		PromptTime: promptTime.Format("15:04"),
+		LoginURL:   loginURL,
+	}
+
+	body, err := renderEmail("already_signed_up.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render already signed up template: %w", err)
+	}
+
+	subject := "You're already signed up"
+	return subject, body, nil
+}
+
+// RenderOutboxAlertEmail tells an admin that due emails have waited age in
+// the outbox
+func RenderOutboxAlertEmail(status *OutboxStatus, age time.Duration) (string, string, error) {
+	data := TemplateData{
+		Outbox:    status,
+		OutboxAge: age.Round(time.Minute).String(),
+	}
+
+	body, err := renderEmail("outbox_alert.txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render outbox alert template: %w", err)
+	}
+
+	subject := fmt.Sprintf("Email outbox stuck: %d emails due", status.Due)
+	return subject, body, nil
+}
+
+func RenderAnomalyReportEmail(report *models.AnomalyReport) (string, string, error) {
+	body, err := renderEmail("anomaly_report.txt", TemplateData{Anomalies: report})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render anomaly report template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%d anomalies found - %s", report.Count(), report.CheckedAt.Format("Jan 2"))
+	return subject, body, nil
+}
+
+// Billing notices sent to an organization's billing contact, each the name
+// of its template
+const (
+	BillingPaymentFailed = "billing_payment_failed"
+	BillingReminder      = "billing_reminder"
+	BillingSuspended     = "billing_suspended"
+	BillingRestored      = "billing_restored"
+)
+
+var billingSubjects = map[string]string{
+	BillingPaymentFailed: "Payment failed for %s",
+	BillingReminder:      "Payment still due for %s",
+	BillingSuspended:     "Prompts paused for %s",
+	BillingRestored:      "Payment received for %s",
+}
+
+// RenderBillingEmail renders billing notice for organization orgName.
+// graceUntil is when sending stops, if a grace period is running.
+func RenderBillingEmail(notice, orgName string, graceUntil *time.Time, invoiceURL string) (string, string, error) {
+	subject, ok := billingSubjects[notice]
+	if !ok {
+		return "", "", fmt.Errorf("unknown billing notice %q", notice)
+	}
+
+	data := TemplateData{
+		OrgName:    orgName,
+		InvoiceURL: invoiceURL,
+	}
+	if graceUntil != nil {
+		data.GraceUntil = graceUntil.UTC().Format("January 2, 2006")
+	}
+
+	body, err := renderEmail(notice+".txt", data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render billing template: %w", err)
+	}
+
+	return fmt.Sprintf(subject, orgName), body, nil
 }
 
 func GenerateVerificationCode() string {
@@ -0,0 +1,178 @@
+package email
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Event types recorded in email_events
+const (
+	EventOpen  = "open"
+	EventClick = "click"
+)
+
+// Paths the API server serves tracking under; the email's token follows
+const (
+	TrackOpenPath  = "/t/o/"
+	TrackClickPath = "/t/c/"
+)
+
+// TrackingPixel is a transparent 1x1 GIF
+var TrackingPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+var linkRegex = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// tracking is how one email is tracked: its HTML part loads a pixel and
+// sends links through the click endpoint, both naming the email by token
+type tracking struct {
+	token   string
+	baseURL string
+	secret  []byte
+}
+
+// summaryTracking returns tracking for a summary to userID, or nil when
+// EMAIL_TRACKING is off or the user opted out. Summaries copied to anyone
+// aren't tracked, since their recipients never agreed to it, and neither
+// are those BCC'd to the archive, whose opens would count as the user's.
+func (s *Service) summaryTracking(ctx context.Context, userID int, ccEmails []string) *tracking {
+	if !s.config.EmailTracking || s.config.DashboardURL == "" || s.config.AuthSecret == "" || len(ccEmails) > 0 {
+		return nil
+	}
+	if s.archive.bccAddresses(models.EmailTypeWeeklySummary) != nil {
+		return nil
+	}
+
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		logrus.WithError(err).WithField("user_id", userID).Warn("Failed to read tracking preference, sending untracked")
+		return nil
+	}
+	if !user.EmailTracking {
+		return nil
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		logrus.WithError(err).Warn("Failed to generate tracking token, sending untracked")
+		return nil
+	}
+	return &tracking{token: hex.EncodeToString(b), baseURL: s.config.DashboardURL, secret: []byte(s.config.AuthSecret)}
+}
+
+// html renders body as the email's HTML part: the text as is, with links
+// wrapped to go through the click endpoint, then the open pixel. The text
+// part keeps the original links.
+func (t *tracking) html(body string) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"></head>\n")
+	b.WriteString("<body><pre style=\"font-family: inherit; white-space: pre-wrap\">")
+
+	last := 0
+	for _, loc := range linkRegex.FindAllStringIndex(body, -1) {
+		link := body[loc[0]:loc[1]]
+		b.WriteString(html.EscapeString(body[last:loc[0]]))
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>", html.EscapeString(t.clickURL(link)), html.EscapeString(link))
+		last = loc[1]
+	}
+	b.WriteString(html.EscapeString(body[last:]))
+
+	fmt.Fprintf(&b, "</pre><img src=\"%s\" width=\"1\" height=\"1\" alt=\"\"></body></html>\n", html.EscapeString(t.openURL()))
+	return b.String()
+}
+
+func (t *tracking) openURL() string {
+	return t.baseURL + TrackOpenPath + t.token + ".gif"
+}
+
+func (t *tracking) clickURL(target string) string {
+	query := url.Values{"u": {target}, "s": {signClick(t.secret, t.token, target)}}
+	return t.baseURL + TrackClickPath + t.token + "?" + query.Encode()
+}
+
+// signClick signs a click link so the endpoint only redirects to links
+// that were in the email, and can't be used as an open redirect
+func signClick(secret []byte, token, target string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("click:" + token + ":" + target))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RecordOpen records that the email with token was opened
+func (s *Service) RecordOpen(ctx context.Context, token string) error {
+	return s.recordEvent(ctx, token, EventOpen, "")
+}
+
+// RecordClick checks that target was a link in the email with token,
+// records the click and returns the link to redirect to
+func (s *Service) RecordClick(ctx context.Context, token, target, signature string) (string, error) {
+	expected := signClick([]byte(s.config.AuthSecret), token, target)
+	if s.config.AuthSecret == "" || !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", apperrors.New(apperrors.CodeInvalidInput, "invalid tracking link")
+	}
+	if err := s.recordEvent(ctx, token, EventClick, target); err != nil {
+		// The reader still gets where they were going
+		logrus.WithError(err).Warn("Failed to record email click")
+	}
+	return target, nil
+}
+
+// recordEvent adds an email_events row for the email with token, unless
+// its user has since opted out of tracking
+func (s *Service) recordEvent(ctx context.Context, token, eventType, link string) error {
+	query := `
+		INSERT INTO email_events (email_log_id, user_id, event_type, url)
+		SELECT el.id, el.user_id, $2, NULLIF($3, '')
+		FROM email_logs el
+		WHERE el.tracking_token = $1
+		  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = el.user_id AND u.email_tracking = FALSE)`
+
+	if _, err := s.db.ExecContext(ctx, query, token, eventType, link); err != nil {
+		return fmt.Errorf("failed to record email %s: %w", eventType, err)
+	}
+	return nil
+}
+
+// TrackingStats counts tracked weekly summaries and how many were opened
+// or clicked
+type TrackingStats struct {
+	Tracked int `json:"tracked"`
+	Opened  int `json:"opened"`
+	Clicked int `json:"clicked"`
+}
+
+// SummaryTrackingStats counts the tracked weekly summaries sent in the
+// last days days
+func (s *Service) SummaryTrackingStats(ctx context.Context, days int) (*TrackingStats, error) {
+	query := `
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM email_events ev WHERE ev.email_log_id = el.id AND ev.event_type = $2)),
+			COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM email_events ev WHERE ev.email_log_id = el.id AND ev.event_type = $3))
+		FROM email_logs el
+		WHERE el.email_type = $1 AND el.tracking_token IS NOT NULL AND el.status = 'sent'
+		  AND el.sent_at >= NOW() - make_interval(days => $4)`
+
+	var stats TrackingStats
+	err := s.db.QueryRowContext(ctx, query, models.EmailTypeWeeklySummary, EventOpen, EventClick, days).
+		Scan(&stats.Tracked, &stats.Opened, &stats.Clicked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tracked summaries: %w", err)
+	}
+	return &stats, nil
+}
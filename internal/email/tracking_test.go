@@ -0,0 +1,49 @@
+package email
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTrackingHTML(t *testing.T) {
+	tr := &tracking{token: "abc123", baseURL: "https://app.example.com", secret: []byte("secret")}
+	body := "Shipped <billing> & more\nShare your week: https://cards.example.com/c/1.png\n"
+
+	html := tr.html(body)
+	if !strings.Contains(html, "Shipped &lt;billing&gt; &amp; more") {
+		t.Errorf("html = %q, want the text escaped", html)
+	}
+	if !strings.Contains(html, `<img src="https://app.example.com/t/o/abc123.gif"`) {
+		t.Errorf("html = %q, want the open pixel", html)
+	}
+
+	href := regexp.MustCompile(`<a href="([^"]+)">https://cards.example.com/c/1.png</a>`).FindStringSubmatch(html)
+	if href == nil {
+		t.Fatalf("html = %q, want the link wrapped", html)
+	}
+	link, err := url.Parse(strings.ReplaceAll(href[1], "&amp;", "&"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Path != "/t/c/abc123" || link.Query().Get("u") != "https://cards.example.com/c/1.png" {
+		t.Errorf("click link = %s, want it to name the token and target", link)
+	}
+	if link.Query().Get("s") != signClick([]byte("secret"), "abc123", "https://cards.example.com/c/1.png") {
+		t.Errorf("click link = %s, want it signed", link)
+	}
+}
+
+func TestSignClick(t *testing.T) {
+	sig := signClick([]byte("secret"), "abc123", "https://example.com")
+	for name, other := range map[string]string{
+		"other token":  signClick([]byte("secret"), "def456", "https://example.com"),
+		"other target": signClick([]byte("secret"), "abc123", "https://evil.example.com"),
+		"other secret": signClick([]byte("other"), "abc123", "https://example.com"),
+	} {
+		if other == sig {
+			t.Errorf("%s: signature matches", name)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package email
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/digest"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// ValidateTemplates parses every embedded template in the base layout and
+// renders it against canonicalTemplateData, or announcements against a
+// sample AnnouncementData, so a template that no longer parses or refers to
+// a field its data doesn't have fails at startup rather than when the email
+// is sent. Templates missing from emailFooters fail too. The error names
+// every failing template.
+func ValidateTemplates() error {
+	return validateTemplates(templateFS, emailFooters)
+}
+
+func validateTemplates(fsys fs.FS, footers map[string]string) error {
+	names, err := fs.Glob(fsys, "templates/*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to list email templates: %w", err)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no email templates embedded")
+	}
+	announcements, err := fs.Glob(fsys, "templates/announcements/*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to list announcement templates: %w", err)
+	}
+
+	data := canonicalTemplateData()
+	announcement := AnnouncementData{Name: "Alex", Email: "alex@example.com"}
+
+	var failures []string
+	for _, name := range names {
+		footer, ok := footers[path.Base(name)]
+		if !ok {
+			failures = append(failures, path.Base(name)+": not registered in emailFooters")
+			continue
+		}
+		if err := renderTemplate(fsys, name, footer, data); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	for _, name := range announcements {
+		if err := renderTemplate(fsys, name, footerAccount, announcement); err != nil {
+			failures = append(failures, "announcements/"+err.Error())
+		}
+	}
+
+	if total := len(names) + len(announcements); len(failures) > 0 {
+		return fmt.Errorf("%d of %d email templates are invalid:\n%s", len(failures), total, strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// renderTemplate parses the template at name in the base layout with footer
+// and executes it, prefixing any error with its file name
+func renderTemplate(fsys fs.FS, name, footer string, data interface{}) error {
+	src, err := fs.ReadFile(fsys, name)
+	if err == nil {
+		var tmpl *template.Template
+		if tmpl, err = parseInLayout(fsys, path.Base(name), string(src), footer); err == nil {
+			err = tmpl.Execute(io.Discard, data)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", path.Base(name), err)
+	}
+	return nil
+}
+
+// canonicalTemplateData fills every TemplateData field, including optional
+// pointers and slices, so each template's conditional and range blocks are
+// rendered too. A field added to TemplateData for a template belongs here.
+func canonicalTemplateData() TemplateData {
+	weekStart := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	firstEntry := weekStart.AddDate(0, -3, 0)
+	lastEntry := weekStart.AddDate(0, 0, 4)
+
+	return TemplateData{
+		VerificationCode: "123456",
+
+		DayOfWeek:    "Monday",
+		Date:         "May 6, 2024",
+		Opener:       "Reply to this email with what you accomplished today.",
+		ProjectFocus: "Billing migration",
+		Quote:        "\"Ship it.\" - Anonymous",
+		Recap:        "Yesterday you said you'd finish the API migration - how did that go?",
+		EntryFormat:  "standup",
+		Skeleton:     "Yesterday:\nToday:\nBlockers:",
+
+		PeriodName:       "Week",
+		MissedDays:       "Wed May 8, Thu May 9 and Fri May 10",
+		WeekStart:        "May 6",
+		WeekEnd:          "May 10",
+		SummaryParagraph: "Shipped the billing migration.",
+		BulletPoints:     []string{"Migrated invoices", "Fixed the retry bug"},
+		EnergyTrend:      "▂▄▆▇█",
+		MonthlyTrend:     "▃▅▆█",
+		MonthlyWeeks:     []TrendWeek{{Label: "Apr 15", Bar: "▃▃▃", Entries: 3}, {Label: "Apr 22", Entries: 0}},
+		Lookback:         &Lookback{Date: weekStart.AddDate(0, 0, -91), Excerpt: "Scoped the billing migration"},
+		CardURL:          "https://cards.example.com/cards/0123456789abcdef.png",
+		Goals:            []string{"Finish the billing migration", "Write the launch post"},
+
+		OriginalMessage: "did some stuff",
+		UserEmail:       "user@example.com",
+		Thread:          "What did you get done today?",
+		Attempts:        3,
+
+		Name:       "Alex",
+		Timezone:   "America/New_York",
+		PromptTime: "5:00 PM",
+		WeekStarts: "Monday",
+
+		RequesterEmail: "alex@example.com",
+
+		Month: "May 2024",
+		DigestWeeks: []digest.Week{
+			{WeekStart: weekStart, Highlight: "Shipped the billing migration.", Bullets: []string{"Migrated invoices"}},
+		},
+
+		Project:      "Billing migration",
+		ProjectSpan:  "3 months",
+		ProjectDates: "Apr 1 - Jun 28, 2024",
+		Quarter:      "2024Q2",
+
+		Question:  "When did I last work on billing?",
+		Answer:    "You finished the billing migration [2024-05-10].",
+		Citations: []string{"May 10, 2024"},
+
+		Report: &models.DataReport{
+			Email:              "alex@example.com",
+			MemberSince:        firstEntry,
+			EntryCount:         42,
+			FirstEntryDate:     &firstEntry,
+			LastEntryDate:      &lastEntry,
+			WeeklySummaryCount: 12,
+			EmailLogCount:      120,
+			AttachmentCount:    2,
+			IntegrationCount:   1,
+			RetentionPolicy:    "Deleted entries are purged after 30 days.",
+		},
+
+		LoginURL:     "https://app.example.com/app/auth?token=abc123",
+		LoginExpires: "15 minutes",
+
+		NewEmail:   "alex@newco.example.com",
+		AliasUntil: "May 20, 2024",
+
+		Outbox: &OutboxStatus{
+			Pending:     14,
+			Due:         12,
+			OldestDueAt: &weekStart,
+			Types: []OutboxTypeStatus{
+				{EmailType: models.EmailTypeDailyPrompt, Pending: 12, Due: 12, OldestDueAt: &weekStart},
+				{EmailType: models.EmailTypeWeeklySummary, Pending: 2},
+			},
+		},
+		OutboxAge: "2h0m0s",
+
+		StepNumber: 2,
+		StepCount:  4,
+		Example:    "America/New_York",
+		Problem:    `We couldn't find a timezone called "Mars/Olympus".`,
+
+		TimezoneInput:   "CST",
+		TimezoneOptions: []string{"America/Chicago (UTC-06:00)", "Asia/Shanghai (UTC+08:00)"},
+
+		Anomalies: &models.AnomalyReport{
+			CheckedAt:        weekStart,
+			BouncingUsers:    []models.UserAnomaly{{UserID: 7, Email: "alex@example.com", Detail: "3 prompts failed in the last 7 days"}},
+			MissingSummaries: []models.UserAnomaly{{UserID: 8, Email: "sam@example.com", Detail: "4 entries but no summary for the week of May 6"}},
+			UnsentSummaries:  []models.UserAnomaly{{UserID: 9, Email: "kim@example.com", Detail: "summary for the week of May 6 not sent (failed)"}},
+			FailureSpikes:    []models.FailureSpike{{EmailType: models.EmailTypeDailyPrompt, Sent: 40, Failed: 10, Rate: 0.2, BaselineRate: 0.01}},
+		},
+
+		OrgName:    "Acme",
+		GraceUntil: "May 13, 2024",
+		InvoiceURL: "https://invoice.stripe.com/i/acct_123/test_abc",
+	}
+}
@@ -0,0 +1,93 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestValidateTemplates(t *testing.T) {
+	if err := ValidateTemplates(); err != nil {
+		t.Fatalf("ValidateTemplates() error = %v", err)
+	}
+}
+
+func TestValidateTemplatesReportsBrokenTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/good.txt":                 {Data: []byte("Hi {{.Name}}, your code is {{.VerificationCode}}")},
+		"templates/unparsable.txt":           {Data: []byte("Hi {{.Name}")},
+		"templates/unknown_field.txt":        {Data: []byte("Hi {{.Nickname}}")},
+		"templates/announcements/good.txt":   {Data: []byte("Hi {{.Name}} <{{.Email}}>")},
+		"templates/announcements/broken.txt": {Data: []byte("Hi {{.VerificationCode}}")},
+		"templates/layouts/base.txt":         {Data: []byte(`{{template "content" .}}{{template "footer" .}}`)},
+		"templates/partials/footer.txt":      {Data: []byte(`{{define "footer_account"}}\n-- Pause with <pause>1 week</pause>{{end}}`)},
+	}
+	footers := map[string]string{
+		"good.txt":          footerAccount,
+		"unparsable.txt":    footerAccount,
+		"unknown_field.txt": footerNone,
+	}
+
+	err := validateTemplates(fsys, footers)
+	if err == nil {
+		t.Fatal("validateTemplates() succeeded with broken templates")
+	}
+
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "3 of 5 email templates are invalid") {
+		t.Errorf("error = %q, want it to count 3 of 5 invalid", msg)
+	}
+	for _, name := range []string{"unparsable.txt", "unknown_field.txt", "announcements/broken.txt"} {
+		if !strings.Contains(msg, name) {
+			t.Errorf("error = %q, want it to name %s", msg, name)
+		}
+	}
+	if strings.Contains(msg, "good.txt") {
+		t.Errorf("error = %q names a valid template", msg)
+	}
+}
+
+func TestValidateTemplatesRequiresTemplates(t *testing.T) {
+	if err := validateTemplates(fstest.MapFS{}, emailFooters); err == nil {
+		t.Fatal("validateTemplates() succeeded with no templates")
+	}
+}
+
+func TestValidateTemplatesRequiresFooter(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/good.txt":         {Data: []byte("Hi {{.Name}}")},
+		"templates/layouts/base.txt": {Data: []byte(`{{template "content" .}}{{template "footer" .}}`)},
+	}
+
+	err := validateTemplates(fsys, map[string]string{})
+	if err == nil || !strings.Contains(err.Error(), "good.txt: not registered") {
+		t.Fatalf("validateTemplates() error = %v, want good.txt reported as unregistered", err)
+	}
+}
+
+func TestRenderEmailUsesLayout(t *testing.T) {
+	body, err := renderEmail("daily_prompt.txt", canonicalTemplateData())
+	if err != nil {
+		t.Fatalf("renderEmail() error = %v", err)
+	}
+	if !strings.Contains(body, "<pause>") {
+		t.Errorf("daily prompt = %q, want the command cheat sheet", body)
+	}
+	if !strings.Contains(body, "\n-- \n") {
+		t.Errorf("daily prompt = %q, want the account footer", body)
+	}
+}
+
+func TestAnnouncement(t *testing.T) {
+	body, err := Announcement("announce.txt")
+	if err != nil {
+		t.Fatalf("Announcement() error = %v", err)
+	}
+	if !strings.Contains(body, "{{.Name}}") {
+		t.Errorf("announce.txt = %q, want it to greet {{.Name}}", body)
+	}
+
+	if _, err := Announcement("missing.txt"); err == nil {
+		t.Error("Announcement(missing.txt) succeeded")
+	}
+}
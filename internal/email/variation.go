@@ -0,0 +1,87 @@
+package email
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// dailyPromptSubjects rotate from day to day, so mail clients that group
+// messages by subject don't fold a user's prompts into one conversation.
+// {weekday} is replaced with the prompt's day. Each is followed by " - " and
+// the date in promptDateLayout, which PromptDate reads back from replies.
+var dailyPromptSubjects = []string{
+	dailyPromptSubject,
+	"How did {weekday} go?",
+	"What did you ship this {weekday}?",
+	"Quick check-in: what got done today?",
+	"Wrapping up {weekday}: what did you finish?",
+}
+
+// dailyPromptOpeners rotate the line asking for the reply, so a week of
+// prompts in one thread doesn't read as the same message repeated
+var dailyPromptOpeners = []string{
+	"Reply to this email with what you accomplished today.",
+	"Hit reply and jot down what you got done today.",
+	"Reply with today's wins and what moved forward.",
+	"Reply with what you finished, fixed or figured out.",
+}
+
+// weeklyThreadSubject starts the subject every prompt of a week shares for
+// users with weekly threading. It carries no day, so replies to it are
+// dated the day they arrive.
+const weeklyThreadSubject = "Your daily check-ins"
+
+// PromptVariation is what a user's daily prompt varies by
+type PromptVariation struct {
+	// UserID offsets the rotation, so users prompted on the same day don't
+	// all get the same subject
+	UserID int
+	// Threading is models.PromptThreadingDaily or PromptThreadingWeekly
+	Threading string
+	// WeekStart is the user's week start preference, which a weekly thread
+	// begins on
+	WeekStart string
+}
+
+// PromptVariationFor returns the variation of user's prompts
+func PromptVariationFor(user *models.User) PromptVariation {
+	return PromptVariation{UserID: user.ID, Threading: user.PromptThreading, WeekStart: user.WeekStart}
+}
+
+// pick returns the item of n for the day of now
+func (v PromptVariation) pick(now time.Time, n int) int {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+	i := (int(day) + v.UserID) % n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
+
+// subject returns the prompt's subject for the day of now
+func (v PromptVariation) subject(now time.Time) string {
+	if v.Threading == models.PromptThreadingWeekly {
+		weekStart := period.StartOfWeek(now, period.FirstWeekday(v.WeekStart))
+		return weeklyThreadSubject + " - week of " + weekStart.Format(promptDateLayout)
+	}
+	subject := dailyPromptSubjects[v.pick(now, len(dailyPromptSubjects))]
+	return strings.ReplaceAll(subject, "{weekday}", now.Format("Monday")) + " - " + now.Format(promptDateLayout)
+}
+
+// opener returns the prompt's request for a reply for the day of now
+func (v PromptVariation) opener(now time.Time) string {
+	return dailyPromptOpeners[v.pick(now, len(dailyPromptOpeners))]
+}
+
+// promptSubjectDate matches any daily prompt subject, capturing its date
+var promptSubjectDate = func() *regexp.Regexp {
+	alternatives := make([]string, len(dailyPromptSubjects))
+	for i, subject := range dailyPromptSubjects {
+		alternatives[i] = strings.ReplaceAll(regexp.QuoteMeta(subject), `\{weekday\}`, `[A-Z][a-z]+day`)
+	}
+	return regexp.MustCompile(`(?:` + strings.Join(alternatives, "|") + `) - ([A-Z][a-z]{2} \d{1,2})`)
+}()
@@ -0,0 +1,65 @@
+package email
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+func TestPromptVariationSubjects(t *testing.T) {
+	// Monday, May 6 2024
+	monday := time.Date(2024, 5, 6, 17, 0, 0, 0, time.UTC)
+	daily := PromptVariation{UserID: 7}
+
+	seen := map[string]bool{}
+	for i := 0; i < len(dailyPromptSubjects); i++ {
+		day := monday.AddDate(0, 0, i)
+		subject := daily.subject(day)
+		if seen[subject] {
+			t.Errorf("subject %q repeated within %d days", subject, len(dailyPromptSubjects))
+		}
+		seen[subject] = true
+
+		// Every rotated subject is still read back as a prompt for its day
+		got, ok := PromptDate("Re: "+subject, day.AddDate(0, 0, 1))
+		if !ok || got.Format("2006-01-02") != day.Format("2006-01-02") {
+			t.Errorf("PromptDate(%q) = %v, %v, want %s", subject, got, ok, day.Format("2006-01-02"))
+		}
+	}
+
+	if daily.subject(monday) == (PromptVariation{UserID: 8}).subject(monday) {
+		t.Error("users prompted the same day got the same subject")
+	}
+}
+
+func TestPromptVariationWeeklyThread(t *testing.T) {
+	sunday := time.Date(2024, 5, 5, 17, 0, 0, 0, time.UTC)
+	tests := []struct {
+		weekStart string
+		day       time.Time
+		want      string
+	}{
+		{"monday", sunday, "Your daily check-ins - week of Apr 29"},
+		{"monday", sunday.AddDate(0, 0, 1), "Your daily check-ins - week of May 6"},
+		{"monday", sunday.AddDate(0, 0, 5), "Your daily check-ins - week of May 6"},
+		{"sunday", sunday, "Your daily check-ins - week of May 5"},
+	}
+
+	for _, tt := range tests {
+		variation := PromptVariation{UserID: 7, Threading: models.PromptThreadingWeekly, WeekStart: tt.weekStart}
+		got := variation.subject(tt.day)
+		if got != tt.want {
+			t.Errorf("subject(%s) with week start %s = %q, want %q", tt.day.Format("Mon Jan 2"), tt.weekStart, got, tt.want)
+		}
+		if _, ok := PromptDate("Re: "+got, tt.day); ok {
+			t.Errorf("PromptDate(%q) found a date in a weekly thread subject", got)
+		}
+	}
+
+	// The thread shares a subject, but each day's prompt reads differently
+	variation := PromptVariation{UserID: 7, Threading: models.PromptThreadingWeekly}
+	if variation.opener(sunday) == variation.opener(sunday.AddDate(0, 0, 1)) {
+		t.Error("consecutive prompts in a weekly thread have the same opener")
+	}
+}
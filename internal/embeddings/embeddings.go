@@ -0,0 +1,258 @@
+// Package embeddings stores a vector per entry - Bedrock Titan text
+// embeddings in pgvector - so entries can be found by meaning rather than
+// wording. Entries are embedded in the background by the embed-entries job.
+package embeddings
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	pkgConfig "github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Dimensions is the vector size requested from the model; it must match the
+// entry_embeddings.embedding column
+const Dimensions = 1024
+
+// BatchSize is how many entries one embed-entries run embeds
+const BatchSize = 100
+
+// maxInputChars keeps input well under Titan's 8k-token limit
+const maxInputChars = 20000
+
+type Service struct {
+	db     *database.DB
+	client *bedrockruntime.Client
+	model  string
+}
+
+// NewService returns nil when EMBEDDINGS_MODEL is empty, which turns
+// semantic search off everywhere
+func NewService(db *database.DB, cfg *pkgConfig.Config) (*Service, error) {
+	if cfg.EmbeddingsModel == "" {
+		return nil, nil
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(context.TODO(),
+		config.WithRegion(cfg.AWSRegion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &Service{
+		db:     db,
+		client: bedrockruntime.NewFromConfig(awsCfg),
+		model:  cfg.EmbeddingsModel,
+	}, nil
+}
+
+// Match is an entry found by SearchSimilarEntries, with its cosine
+// similarity to the query (1 is identical)
+type Match struct {
+	Entry      *models.Entry
+	Similarity float64
+}
+
+// SearchOptions narrows SearchSimilarEntries. From and To bound entry dates
+// (To exclusive); nil leaves that side open.
+type SearchOptions struct {
+	From  *time.Time
+	To    *time.Time
+	Limit int
+}
+
+type titanRequest struct {
+	InputText  string `json:"inputText"`
+	Dimensions int    `json:"dimensions"`
+	Normalize  bool   `json:"normalize"`
+}
+
+type titanResponse struct {
+	Embedding           []float32 `json:"embedding"`
+	InputTextTokenCount int       `json:"inputTextTokenCount"`
+}
+
+// Embed returns the vector for text
+func (s *Service) Embed(ctx context.Context, text string) ([]float32, error) {
+	if len(text) > maxInputChars {
+		text = text[:maxInputChars]
+	}
+
+	body, err := json.Marshal(titanRequest{InputText: text, Dimensions: Dimensions, Normalize: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embedding request: %w", err)
+	}
+
+	result, err := s.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(s.model),
+		ContentType: aws.String("application/json"),
+		Body:        body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoke embedding model: %w", err)
+	}
+
+	var response titanResponse
+	if err := json.Unmarshal(result.Body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal embedding response: %w", err)
+	}
+	if len(response.Embedding) != Dimensions {
+		return nil, fmt.Errorf("embedding has %d dimensions, want %d", len(response.Embedding), Dimensions)
+	}
+
+	return response.Embedding, nil
+}
+
+// IndexPending embeds up to limit entries that have no embedding from the
+// current model or whose text changed since, most recently updated first.
+// An entry that fails is logged and retried on the next run.
+func (s *Service) IndexPending(ctx context.Context, limit int) (int, error) {
+	query := `
+		SELECT e.id, e.user_id, e.raw_content
+		FROM entries e
+		LEFT JOIN entry_embeddings ee ON ee.entry_id = e.id
+		WHERE e.deleted_at IS NULL AND e.raw_content NOT LIKE '` + custody.SealedPrefix + `%'
+		  AND (ee.entry_id IS NULL OR ee.model <> $1 OR ee.content_hash <> md5(e.raw_content))
+		ORDER BY e.updated_at DESC
+		LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, s.model, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query entries to embed: %w", err)
+	}
+
+	type pending struct {
+		id, userID int
+		content    string
+	}
+	var entries []pending
+	for rows.Next() {
+		var entry pending
+		if err := rows.Scan(&entry.id, &entry.userID, &entry.content); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan entry to embed: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read entries to embed: %w", err)
+	}
+
+	indexed := 0
+	for _, entry := range entries {
+		if err := s.indexEntry(ctx, entry.id, entry.userID, entry.content); err != nil {
+			logrus.WithError(err).WithField("entry_id", entry.id).Warn("Failed to embed entry")
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		indexed++
+	}
+
+	return indexed, nil
+}
+
+func (s *Service) indexEntry(ctx context.Context, entryID, userID int, content string) error {
+	vector, err := s.Embed(ctx, content)
+	if err != nil {
+		return err
+	}
+
+	sum := md5.Sum([]byte(content))
+	query := `
+		INSERT INTO entry_embeddings (entry_id, user_id, model, content_hash, embedding)
+		VALUES ($1, $2, $3, $4, $5::vector)
+		ON CONFLICT (entry_id)
+		DO UPDATE SET model = $3, content_hash = $4, embedding = $5::vector, updated_at = NOW()`
+
+	_, err = s.db.ExecContext(ctx, query, entryID, userID, s.model, hex.EncodeToString(sum[:]), formatVector(vector))
+	if err != nil {
+		return fmt.Errorf("failed to store embedding: %w", err)
+	}
+	return nil
+}
+
+// SearchSimilarEntries returns the user's embedded entries closest in meaning
+// to text, most similar first, read from the read replica if there is one
+func (s *Service) SearchSimilarEntries(ctx context.Context, userID int, text string, opts SearchOptions) ([]*Match, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+
+	vector, err := s.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT e.id, e.user_id, e.entry_date, e.raw_content, e.parsed_content, e.project_tag,
+		       e.created_at, e.updated_at, 1 - (ee.embedding <=> $2::vector)
+		FROM entry_embeddings ee
+		JOIN entries e ON e.id = ee.entry_id
+		WHERE ee.user_id = $1 AND ee.model = $3 AND e.deleted_at IS NULL
+		  AND ($4::date IS NULL OR e.entry_date >= $4::date)
+		  AND ($5::date IS NULL OR e.entry_date < $5::date)
+		ORDER BY ee.embedding <=> $2::vector
+		LIMIT $6`
+
+	rows, err := s.db.Reader().QueryContext(ctx, query, userID, formatVector(vector), s.model, opts.From, opts.To, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search entry embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []*Match
+	for rows.Next() {
+		var entry models.Entry
+		var parsedContent, projectTag sql.NullString
+		match := &Match{Entry: &entry}
+
+		err := rows.Scan(&entry.ID, &entry.UserID, &entry.EntryDate, &entry.RawContent,
+			&parsedContent, &projectTag, &entry.CreatedAt, &entry.UpdatedAt, &match.Similarity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan similar entry: %w", err)
+		}
+
+		if parsedContent.Valid {
+			entry.ParsedContent = &parsedContent.String
+		}
+		if projectTag.Valid {
+			entry.ProjectTag = &projectTag.String
+		}
+
+		matches = append(matches, match)
+	}
+
+	return matches, rows.Err()
+}
+
+// formatVector renders v in pgvector's text form, [1,2,3]
+func formatVector(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
@@ -0,0 +1,194 @@
+// Package entries lists a user's journal entries a page at a time. The
+// API's GET /v1/entries and the CLI's entry list both parse their options
+// with ParseFilter and read through a Repository, so the same options select
+// the same entries in both.
+package entries
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// Page sizes when none is given and the most one page may hold
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// Filter selects entries. Every set field must match.
+type Filter struct {
+	// From and To bound the entry date, both inclusive
+	From *time.Time
+	To   *time.Time
+	// Project matches the project tag, ignoring case
+	Project string
+	// Query matches entries containing every word of it, by full-text search
+	Query string
+}
+
+// ParseFilter reads a Filter from its text form: dates as YYYY-MM-DD, empty
+// values meaning no constraint
+func ParseFilter(from, to, project, query string) (Filter, error) {
+	var filter Filter
+	for _, bound := range []struct {
+		name  string
+		value string
+		dest  **time.Time
+	}{{"from", from, &filter.From}, {"to", to, &filter.To}} {
+		if bound.value == "" {
+			continue
+		}
+		date, err := time.Parse(dateLayout, bound.value)
+		if err != nil {
+			return Filter{}, apperrors.New(apperrors.CodeInvalidInput, "%s must be YYYY-MM-DD: %s", bound.name, bound.value)
+		}
+		*bound.dest = &date
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return Filter{}, apperrors.New(apperrors.CodeInvalidInput, "to is before from")
+	}
+
+	filter.Project = strings.TrimSpace(project)
+	filter.Query = strings.TrimSpace(query)
+	return filter, nil
+}
+
+// Page is one page of entries, oldest first. NextPage is the cursor of the
+// following page, empty on the last one.
+type Page struct {
+	Entries  []*models.Entry `json:"entries"`
+	NextPage string          `json:"next_page,omitempty"`
+}
+
+// Repository reads entries
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// List returns up to limit of the user's live entries matching filter,
+// starting after the page cursor, or from the first entry when page is
+// empty. limit is clamped to MaxPageSize; 0 means DefaultPageSize.
+func (r *Repository) List(ctx context.Context, userID int, filter Filter, page string, limit int) (*Page, error) {
+	switch {
+	case limit <= 0:
+		limit = DefaultPageSize
+	case limit > MaxPageSize:
+		limit = MaxPageSize
+	}
+
+	var after *time.Time
+	if page != "" {
+		date, err := decodeCursor(page)
+		if err != nil {
+			return nil, err
+		}
+		after = &date
+	}
+
+	query, args := listQuery(userID, filter, after, limit+1)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+	defer rows.Close()
+
+	result := &Page{Entries: []*models.Entry{}}
+	for rows.Next() {
+		var entry models.Entry
+		var parsedContent, projectTag sql.NullString
+
+		err := rows.Scan(&entry.ID, &entry.UserID, &entry.EntryDate, &entry.RawContent,
+			&parsedContent, &projectTag, &entry.CreatedAt, &entry.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+
+		if parsedContent.Valid {
+			entry.ParsedContent = &parsedContent.String
+		}
+		if projectTag.Valid {
+			entry.ProjectTag = &projectTag.String
+		}
+
+		result.Entries = append(result.Entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+
+	// The extra row only says there is another page
+	if len(result.Entries) > limit {
+		result.Entries = result.Entries[:limit]
+		result.NextPage = encodeCursor(result.Entries[limit-1].EntryDate)
+	}
+	return result, nil
+}
+
+// listQuery builds the query for List. A user has at most one live entry a
+// day, so the entry date alone orders pages and marks where one ends. The
+// conditions are served by idx_entries_user_date, idx_entries_user_project
+// and the full-text idx_entries_search.
+func listQuery(userID int, filter Filter, after *time.Time, limit int) (string, []interface{}) {
+	conditions := []string{"user_id = $1", "deleted_at IS NULL"}
+	args := []interface{}{userID}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.From != nil {
+		add("entry_date >= $%d", filter.From.Format(dateLayout))
+	}
+	if filter.To != nil {
+		add("entry_date <= $%d", filter.To.Format(dateLayout))
+	}
+	if after != nil {
+		add("entry_date > $%d", after.Format(dateLayout))
+	}
+	if filter.Project != "" {
+		add("LOWER(project_tag) = LOWER($%d)", filter.Project)
+	}
+	if filter.Query != "" {
+		add("to_tsvector('english', raw_content) @@ plainto_tsquery('english', $%d)", filter.Query)
+	}
+
+	args = append(args, limit)
+	query := fmt.Sprintf(`
+		SELECT id, user_id, entry_date, raw_content, parsed_content, project_tag, created_at, updated_at
+		FROM entries
+		WHERE %s
+		ORDER BY entry_date ASC
+		LIMIT $%d`, strings.Join(conditions, " AND "), len(args))
+	return query, args
+}
+
+// encodeCursor makes the page cursor following an entry dated date. Cursors
+// are opaque to clients so the ordering can change without breaking them.
+func encodeCursor(date time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(date.Format(dateLayout)))
+}
+
+func decodeCursor(page string) (time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(page)
+	if err == nil {
+		var date time.Time
+		if date, err = time.Parse(dateLayout, string(raw)); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, apperrors.New(apperrors.CodeInvalidInput, "invalid page cursor: %s", page)
+}
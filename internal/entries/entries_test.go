@@ -0,0 +1,74 @@
+package entries
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+)
+
+func TestParseFilter(t *testing.T) {
+	filter, err := ParseFilter("2024-05-01", "2024-05-31", " Atlas ", "billing export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.From.Format(dateLayout) != "2024-05-01" || filter.To.Format(dateLayout) != "2024-05-31" {
+		t.Errorf("dates = %v to %v", filter.From, filter.To)
+	}
+	if filter.Project != "Atlas" || filter.Query != "billing export" {
+		t.Errorf("filter = %+v", filter)
+	}
+
+	if filter, err := ParseFilter("", "", "", ""); err != nil || filter.From != nil || filter.To != nil {
+		t.Errorf("empty ParseFilter() = %+v, %v, want no constraints", filter, err)
+	}
+
+	for _, bad := range [][2]string{{"May 1", ""}, {"", "2024-13-01"}, {"2024-05-31", "2024-05-01"}} {
+		if _, err := ParseFilter(bad[0], bad[1], "", ""); !apperrors.Is(err, apperrors.CodeInvalidInput) {
+			t.Errorf("ParseFilter(%q, %q) error = %v, want %s", bad[0], bad[1], err, apperrors.CodeInvalidInput)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	date := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	got, err := decodeCursor(encodeCursor(date))
+	if err != nil || !got.Equal(date) {
+		t.Errorf("decodeCursor(encodeCursor(%s)) = %s, %v", date, got, err)
+	}
+
+	for _, bad := range []string{"2024-05-06", "!!", encodeCursor(date)[:4]} {
+		if _, err := decodeCursor(bad); !apperrors.Is(err, apperrors.CodeInvalidInput) {
+			t.Errorf("decodeCursor(%q) error = %v, want %s", bad, err, apperrors.CodeInvalidInput)
+		}
+	}
+}
+
+func TestListQuery(t *testing.T) {
+	filter, _ := ParseFilter("2024-05-01", "", "atlas", "billing")
+	after := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+
+	query, args := listQuery(7, filter, &after, 51)
+	for _, want := range []string{
+		"user_id = $1 AND deleted_at IS NULL",
+		"entry_date >= $2",
+		"entry_date > $3",
+		"LOWER(project_tag) = LOWER($4)",
+		"plainto_tsquery('english', $5)",
+		"LIMIT $6",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query = %s\nwant it to contain %q", query, want)
+		}
+	}
+	wantArgs := []interface{}{7, "2024-05-01", "2024-05-06", "atlas", "billing", 51}
+	if len(args) != len(wantArgs) {
+		t.Fatalf("args = %v, want %v", args, wantArgs)
+	}
+	for i := range args {
+		if args[i] != wantArgs[i] {
+			t.Errorf("args[%d] = %v, want %v", i, args[i], wantArgs[i])
+		}
+	}
+}
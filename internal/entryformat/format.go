@@ -0,0 +1,194 @@
+// Package entryformat defines the guided reflection formats a user can choose
+// for daily entries, and splits replies into the chosen format's sections.
+package entryformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Format names stored in users.entry_format
+const (
+	Freeform   = "freeform"
+	Standup    = "standup"
+	Reflection = "reflection"
+)
+
+// Section is one prompt within a format
+type Section struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+// Format is a named set of sections a reply is split into
+type Format struct {
+	Name     string
+	Sections []Section
+}
+
+var formats = map[string]Format{
+	Standup: {
+		Name: Standup,
+		Sections: []Section{
+			{Key: "accomplished", Label: "Accomplished"},
+			{Key: "blocked", Label: "Blocked"},
+			{Key: "learned", Label: "Learned"},
+			{Key: "tomorrow", Label: "Tomorrow"},
+		},
+	},
+	Reflection: {
+		Name: Reflection,
+		Sections: []Section{
+			{Key: "went_well", Label: "Went well"},
+			{Key: "could_improve", Label: "Could improve"},
+			{Key: "grateful_for", Label: "Grateful for"},
+		},
+	},
+}
+
+// Names returns the selectable format names, freeform first
+func Names() []string {
+	names := []string{Freeform}
+	var structured []string
+	for name := range formats {
+		structured = append(structured, name)
+	}
+	sort.Strings(structured)
+	return append(names, structured...)
+}
+
+// Parse validates a format name and normalizes it
+func Parse(value string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(value))
+	if name == "" || name == Freeform {
+		return Freeform, nil
+	}
+	if _, ok := formats[name]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown entry format: %s (expected one of %s)", value, strings.Join(Names(), ", "))
+}
+
+// Lookup returns the structured format with the given name. Freeform and
+// unknown names report false.
+func Lookup(name string) (Format, bool) {
+	format, ok := formats[strings.ToLower(name)]
+	return format, ok
+}
+
+// Skeleton is the blank outline included in the daily prompt
+func (f Format) Skeleton() string {
+	var b strings.Builder
+	for _, section := range f.Sections {
+		fmt.Fprintf(&b, "%s:\n\n", section.Label)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// SectionText is a section's answer in a structured entry
+type SectionText struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Text  string `json:"text"`
+}
+
+// Structured is the JSON stored in entries.parsed_content for a structured entry
+type Structured struct {
+	Format   string        `json:"format"`
+	Sections []SectionText `json:"sections"`
+}
+
+// Split divides content into the format's sections by looking for lines that
+// start with a section label followed by a colon. Text before the first label
+// is kept with the first section. It reports false when no label is found.
+func (f Format) Split(content string) (*Structured, bool) {
+	texts := make(map[string][]string)
+	current := ""
+	var preamble []string
+
+	for _, line := range strings.Split(content, "\n") {
+		if key, rest, ok := f.matchLabel(line); ok {
+			current = key
+			if rest != "" {
+				texts[key] = append(texts[key], rest)
+			}
+			continue
+		}
+
+		if current == "" {
+			preamble = append(preamble, line)
+			continue
+		}
+		texts[current] = append(texts[current], line)
+	}
+
+	if current == "" {
+		return nil, false
+	}
+
+	structured := &Structured{Format: f.Name}
+	for i, section := range f.Sections {
+		lines := texts[section.Key]
+		if i == 0 {
+			lines = append(preamble, lines...)
+		}
+		structured.Sections = append(structured.Sections, SectionText{
+			Key:   section.Key,
+			Label: section.Label,
+			Text:  strings.TrimSpace(strings.Join(lines, "\n")),
+		})
+	}
+
+	return structured, true
+}
+
+func (f Format) matchLabel(line string) (string, string, bool) {
+	trimmed := strings.TrimLeft(strings.TrimSpace(line), "-*# ")
+	for _, section := range f.Sections {
+		for _, label := range []string{section.Label, section.Key} {
+			if len(trimmed) <= len(label) || !strings.EqualFold(trimmed[:len(label)], label) {
+				continue
+			}
+			if rest := strings.TrimSpace(trimmed[len(label):]); strings.HasPrefix(rest, ":") {
+				return section.Key, strings.TrimSpace(rest[1:]), true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// Encode marshals the structured entry for storage
+func (s *Structured) Encode() (string, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode structured entry: %w", err)
+	}
+	return string(data), nil
+}
+
+// Decode reads a structured entry from parsed_content. Plain text entries
+// report false.
+func Decode(parsedContent string) (*Structured, bool) {
+	if !strings.HasPrefix(strings.TrimSpace(parsedContent), "{") {
+		return nil, false
+	}
+
+	var structured Structured
+	if err := json.Unmarshal([]byte(parsedContent), &structured); err != nil || len(structured.Sections) == 0 {
+		return nil, false
+	}
+	return &structured, true
+}
+
+// String renders the entry as labelled lines, omitting empty sections
+func (s *Structured) String() string {
+	var lines []string
+	for _, section := range s.Sections {
+		if section.Text != "" {
+			lines = append(lines, fmt.Sprintf("%s: %s", section.Label, strings.ReplaceAll(section.Text, "\n", " ")))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,109 @@
+// Package errors defines the typed error taxonomy used across core, email and
+// llm so that classes of failure can be alerted on and surfaced consistently in
+// logs and API responses. Import it as apperrors to avoid shadowing the
+// standard library package.
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+)
+
+// Code identifies a class of failure
+type Code string
+
+const (
+	CodeUserNotFound Code = "user_not_found"
+	CodeNotVerified  Code = "not_verified"
+	CodeNotFound     Code = "not_found"
+	CodeParseFailure Code = "parse_failure"
+	CodeLLMThrottled Code = "llm_throttled"
+	CodeSESRejected  Code = "ses_rejected"
+	CodeInvalidInput Code = "invalid_input"
+	CodeConflict     Code = "conflict"
+	CodeUnauthorized Code = "unauthorized"
+	CodeInternal     Code = "internal"
+
+	// CodeSummaryRejected is a generated summary that failed the safety and
+	// grounding checks
+	CodeSummaryRejected Code = "summary_rejected"
+
+	// CodeForbidden is an authenticated request from somewhere not allowed
+	CodeForbidden Code = "forbidden"
+	// CodeRateLimited is a caller that sent too many requests
+	CodeRateLimited Code = "rate_limited"
+	// CodeTooLarge is a request or message over a size limit
+	CodeTooLarge Code = "too_large"
+	// CodeUnsupportedType is content of a type that isn't handled
+	CodeUnsupportedType Code = "unsupported_type"
+)
+
+// Error is an error tagged with a Code
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates a coded error
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap tags an underlying error with a code and context message
+func Wrap(code Code, err error, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// CodeOf returns the code of the first coded error in err's chain, or
+// CodeInternal when there is none
+func CodeOf(err error) Code {
+	var coded *Error
+	if stderrors.As(err, &coded) {
+		return coded.Code
+	}
+	return CodeInternal
+}
+
+// Is reports whether err carries the given code
+func Is(err error, code Code) bool {
+	return err != nil && CodeOf(err) == code
+}
+
+// HTTPStatus maps a code to the status used in API responses
+func HTTPStatus(code Code) int {
+	switch code {
+	case CodeUserNotFound, CodeNotFound:
+		return http.StatusNotFound
+	case CodeNotVerified, CodeForbidden:
+		return http.StatusForbidden
+	case CodeParseFailure, CodeInvalidInput:
+		return http.StatusBadRequest
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeLLMThrottled, CodeRateLimited:
+		return http.StatusTooManyRequests
+	case CodeTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case CodeUnsupportedType:
+		return http.StatusUnsupportedMediaType
+	case CodeSESRejected:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
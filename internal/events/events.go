@@ -0,0 +1,154 @@
+// Package events carries domain events from core and email to whatever
+// consumes them - webhooks in-process, and optionally an SNS topic or SQS
+// queue for services outside it - so consumers react to events instead of
+// reading each other's tables.
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Domain event types
+const (
+	UserVerified      = "UserVerified"
+	EntrySaved        = "EntrySaved"
+	SummaryGenerated  = "SummaryGenerated"
+	EmailFailed       = "EmailFailed"
+	OutboxStuck       = "OutboxStuck"
+	AnomaliesDetected = "AnomaliesDetected"
+)
+
+// Bus kinds for EVENT_BUS
+const (
+	BusLocal = "local"
+	BusSNS   = "sns"
+	BusSQS   = "sqs"
+)
+
+// Event is one domain event. Data is JSON-encoded when it leaves the process.
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// New returns an event of eventType stamped with a random ID and the current time
+func New(eventType string, data interface{}) Event {
+	now := time.Now().UTC()
+
+	// IDs are for consumers' deduplication, so a clock-based one will do if
+	// the random source fails
+	id := fmt.Sprintf("evt_%x", now.UnixNano())
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err == nil {
+		id = "evt_" + hex.EncodeToString(b)
+	}
+
+	return Event{ID: id, Type: eventType, OccurredAt: now, Data: data}
+}
+
+// EmailFailure is the data of an EmailFailed event
+type EmailFailure struct {
+	EmailID   int    `json:"email_id"`
+	UserID    *int   `json:"user_id"`
+	Recipient string `json:"recipient"`
+	EmailType string `json:"email_type"`
+	Error     string `json:"error"`
+	Rejected  bool   `json:"rejected"` // SES rejected the message, rather than a transient failure
+}
+
+// OutboxStuckAlert is the data of an OutboxStuck event: the oldest due email
+// has waited longer than Threshold
+type OutboxStuckAlert struct {
+	Due         int       `json:"due"`
+	OldestDueAt time.Time `json:"oldest_due_at"`
+	Threshold   string    `json:"threshold"`
+}
+
+// Publisher is what core and email publish to
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Handler consumes an event
+type Handler func(ctx context.Context, event Event) error
+
+type subscription struct {
+	name    string
+	handler Handler
+	types   map[string]bool // nil for every type
+}
+
+// Dispatcher delivers each event to its subscribers in-process, in the order
+// they subscribed. It is the local bus; the SNS and SQS buses are a
+// Dispatcher with a Forwarder subscribed to every event.
+type Dispatcher struct {
+	subscriptions []subscription
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+// NewBus returns the bus EVENT_BUS selects. For sns and sqs, target is the
+// topic ARN or queue URL events are forwarded to.
+func NewBus(ctx context.Context, kind, region, target string) (*Dispatcher, error) {
+	d := NewDispatcher()
+
+	var forwarder *Forwarder
+	var err error
+	switch kind {
+	case BusLocal, "":
+		return d, nil
+	case BusSNS:
+		forwarder, err = NewSNSForwarder(ctx, region, target)
+	case BusSQS:
+		forwarder, err = NewSQSForwarder(ctx, region, target)
+	default:
+		return nil, fmt.Errorf("unknown event bus %q (want local, sns or sqs)", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d.Subscribe(kind, forwarder.Handle)
+	return d, nil
+}
+
+// Subscribe registers handler for eventTypes, or for every event when none
+// are given. name identifies the subscriber in logs.
+func (d *Dispatcher) Subscribe(name string, handler Handler, eventTypes ...string) {
+	sub := subscription{name: name, handler: handler}
+	if len(eventTypes) > 0 {
+		sub.types = map[string]bool{}
+		for _, eventType := range eventTypes {
+			sub.types[eventType] = true
+		}
+	}
+	d.subscriptions = append(d.subscriptions, sub)
+}
+
+// Publish hands event to every matching subscriber. A failing subscriber
+// doesn't stop the others; their errors are returned together.
+func (d *Dispatcher) Publish(ctx context.Context, event Event) error {
+	if d == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, sub := range d.subscriptions {
+		if sub.types != nil && !sub.types[event.Type] {
+			continue
+		}
+		if err := sub.handler(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s subscriber: %w", sub.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// Forwarder sends events to an SNS topic or SQS queue through the services'
+// query APIs with SigV4-signed requests, using the same credential chain as
+// the SES and Bedrock clients. The event type is also set as the event_type
+// message attribute, so subscriptions can filter on it.
+type Forwarder struct {
+	httpClient  *http.Client
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	region      string
+	service     string // "sns" or "sqs"
+	endpoint    string
+	target      string
+}
+
+// NewSNSForwarder publishes events to topicARN
+func NewSNSForwarder(ctx context.Context, region, topicARN string) (*Forwarder, error) {
+	if !strings.HasPrefix(topicARN, "arn:") {
+		return nil, fmt.Errorf("EVENT_BUS_TARGET must be an SNS topic ARN, got %q", topicARN)
+	}
+	return newForwarder(ctx, region, "sns", fmt.Sprintf("https://sns.%s.amazonaws.com/", region), topicARN)
+}
+
+// NewSQSForwarder sends events to the queue at queueURL
+func NewSQSForwarder(ctx context.Context, region, queueURL string) (*Forwarder, error) {
+	if parsed, err := url.Parse(queueURL); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, fmt.Errorf("EVENT_BUS_TARGET must be an SQS queue URL, got %q", queueURL)
+	}
+	return newForwarder(ctx, region, "sqs", queueURL, queueURL)
+}
+
+func newForwarder(ctx context.Context, region, service, endpoint, target string) (*Forwarder, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &Forwarder{
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		credentials: awsCfg.Credentials,
+		signer:      v4.NewSigner(),
+		region:      awsCfg.Region,
+		service:     service,
+		endpoint:    endpoint,
+		target:      target,
+	}, nil
+}
+
+// Handle forwards event. It is subscribed to every event by NewBus.
+func (f *Forwarder) Handle(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	form := url.Values{}
+	switch f.service {
+	case "sns":
+		form.Set("Action", "Publish")
+		form.Set("Version", "2010-03-31")
+		form.Set("TopicArn", f.target)
+		form.Set("Message", string(body))
+		form.Set("MessageAttributes.entry.1.Name", "event_type")
+		form.Set("MessageAttributes.entry.1.Value.DataType", "String")
+		form.Set("MessageAttributes.entry.1.Value.StringValue", event.Type)
+	case "sqs":
+		form.Set("Action", "SendMessage")
+		form.Set("Version", "2012-11-05")
+		form.Set("MessageBody", string(body))
+		form.Set("MessageAttribute.1.Name", "event_type")
+		form.Set("MessageAttribute.1.Value.DataType", "String")
+		form.Set("MessageAttribute.1.Value.StringValue", event.Type)
+	}
+
+	return f.post(ctx, form.Encode())
+}
+
+func (f *Forwarder) post(ctx context.Context, payload string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.endpoint, strings.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build %s request: %w", f.service, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	creds, err := f.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+
+	hash := sha256.Sum256([]byte(payload))
+	if err := f.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), f.service, f.region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign %s request: %w", f.service, err)
+	}
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", f.service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s returned %d: %s", f.service, resp.StatusCode, body)
+	}
+	return nil
+}
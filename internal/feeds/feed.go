@@ -0,0 +1,235 @@
+// Package feeds renders a user's weekly summaries as Atom and RSS feeds, for
+// feed readers, Notion and internal portals to pull summaries from
+package feeds
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Path is where the API server serves feeds: Path, the feed token, then
+// .atom or .rss
+const Path = "/feeds/"
+
+// Formats a feed is served in, the extension of its URL
+const (
+	FormatAtom = "atom"
+	FormatRSS  = "rss"
+)
+
+// ContentTypes maps each format to the Content-Type it is served with
+var ContentTypes = map[string]string{
+	FormatAtom: "application/atom+xml; charset=utf-8",
+	FormatRSS:  "application/rss+xml; charset=utf-8",
+}
+
+// Feed is a user's summaries, newest first
+type Feed struct {
+	User      *models.User
+	Summaries []*models.WeeklySummary
+	// Self is the feed's own URL
+	Self string
+	// DashboardURL links each entry to its week in the web dashboard; entries
+	// have no link when it is empty
+	DashboardURL string
+}
+
+// id identifies the feed by its user rather than its URL, so readers keep
+// their read state when the URL is rotated
+func (f *Feed) id() string {
+	return fmt.Sprintf("urn:wdygdtw:summaries:%d", f.User.ID)
+}
+
+func (f *Feed) title() string {
+	return f.User.Name + "'s weekly summaries"
+}
+
+// updated is when the newest summary was written, or when the user signed
+// up if there are none yet
+func (f *Feed) updated() time.Time {
+	updated := f.User.CreatedAt
+	for _, summary := range f.Summaries {
+		if summary.CreatedAt.After(updated) {
+			updated = summary.CreatedAt
+		}
+	}
+	return updated.UTC()
+}
+
+func (f *Feed) link(summary *models.WeeklySummary) string {
+	if f.DashboardURL == "" {
+		return ""
+	}
+	return f.DashboardURL + "/app/summaries?week=" + summary.WeekStartDate.Format("2006-01-02")
+}
+
+func entryID(summary *models.WeeklySummary) string {
+	return fmt.Sprintf("urn:wdygdtw:summary:%d", summary.ID)
+}
+
+func entryTitle(summary *models.WeeklySummary) string {
+	return "Week of " + summary.WeekStartDate.Format("January 2, 2006")
+}
+
+// entryHTML is the summary as the HTML body of an entry: the paragraph, the
+// bullets and the share card if there is one
+func entryHTML(summary *models.WeeklySummary) string {
+	var b strings.Builder
+	end := period.SummaryEnd(summary.WeekStartDate)
+	fmt.Fprintf(&b, "<p><em>%s - %s</em></p>\n", summary.WeekStartDate.Format("Jan 2"), end.Format("Jan 2, 2006"))
+	if summary.SummaryParagraph != "" {
+		fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(summary.SummaryParagraph))
+	}
+	if len(summary.BulletPoints) > 0 {
+		b.WriteString("<ul>\n")
+		for _, bullet := range summary.BulletPoints {
+			fmt.Fprintf(&b, "<li>%s</li>\n", html.EscapeString(bullet))
+		}
+		b.WriteString("</ul>\n")
+	}
+	if summary.CardURL != nil {
+		fmt.Fprintf(&b, "<p><img src=\"%s\" alt=\"Share card\"></p>\n", html.EscapeString(*summary.CardURL))
+	}
+	return b.String()
+}
+
+// Write renders the feed in format to w
+func (f *Feed) Write(w io.Writer, format string) error {
+	var doc interface{}
+	switch format {
+	case FormatAtom:
+		doc = f.atom()
+	case FormatRSS:
+		doc = f.rss()
+	default:
+		return fmt.Errorf("unknown feed format: %s", format)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode %s feed: %w", format, err)
+	}
+	return nil
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func (f *Feed) atom() *atomFeed {
+	feed := &atomFeed{
+		ID:      f.id(),
+		Title:   f.title(),
+		Updated: f.updated().Format(time.RFC3339),
+		Author:  atomAuthor{Name: f.User.Name},
+		Links:   []atomLink{{Rel: "self", Href: f.Self}},
+	}
+	for _, summary := range f.Summaries {
+		entry := atomEntry{
+			ID:        entryID(summary),
+			Title:     entryTitle(summary),
+			Published: summary.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   summary.CreatedAt.UTC().Format(time.RFC3339),
+			Content:   atomContent{Type: "html", Body: entryHTML(summary)},
+		}
+		if link := f.link(summary); link != "" {
+			entry.Links = []atomLink{{Rel: "alternate", Href: link}}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func (f *Feed) rss() *rssFeed {
+	link := f.Self
+	if f.DashboardURL != "" {
+		link = f.DashboardURL + "/app/summaries"
+	}
+
+	feed := &rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.title(),
+			Link:          link,
+			Description:   "What " + f.User.Name + " got done, week by week",
+			LastBuildDate: f.updated().Format(time.RFC1123Z),
+		},
+	}
+	for _, summary := range f.Summaries {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       entryTitle(summary),
+			Link:        f.link(summary),
+			GUID:        rssGUID{Value: entryID(summary)},
+			PubDate:     summary.CreatedAt.UTC().Format(time.RFC1123Z),
+			Description: entryHTML(summary),
+		})
+	}
+	return feed
+}
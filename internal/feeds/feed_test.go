@@ -0,0 +1,101 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+func testFeed() *Feed {
+	card := "https://cards.example.com/cards/abc.png"
+	return &Feed{
+		User: &models.User{ID: 7, Name: "Alex", CreatedAt: time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)},
+		Summaries: []*models.WeeklySummary{
+			{
+				ID:               12,
+				WeekStartDate:    time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC),
+				SummaryParagraph: "Shipped the <billing> migration & fixed alerts.",
+				BulletPoints:     models.BulletPoints{"Migrated billing", "Fixed paging alerts"},
+				CardURL:          &card,
+				CreatedAt:        time.Date(2024, 5, 10, 17, 30, 0, 0, time.UTC),
+			},
+			{
+				ID:               9,
+				WeekStartDate:    time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC),
+				SummaryParagraph: "Planned the quarter.",
+				CreatedAt:        time.Date(2024, 5, 3, 17, 30, 0, 0, time.UTC),
+			},
+		},
+		Self:         "https://wdygdtw.example.com/feeds/wdygf_abc.atom",
+		DashboardURL: "https://wdygdtw.example.com",
+	}
+}
+
+func TestAtom(t *testing.T) {
+	var b bytes.Buffer
+	if err := testFeed().Write(&b, FormatAtom); err != nil {
+		t.Fatal(err)
+	}
+
+	var got atomFeed
+	if err := xml.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("feed is not valid XML: %v\n%s", err, b.String())
+	}
+	if got.ID != "urn:wdygdtw:summaries:7" || got.Updated != "2024-05-10T17:30:00Z" {
+		t.Errorf("feed id, updated = %q, %q", got.ID, got.Updated)
+	}
+	if len(got.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got.Entries))
+	}
+
+	entry := got.Entries[0]
+	if entry.Title != "Week of May 6, 2024" || entry.ID != "urn:wdygdtw:summary:12" {
+		t.Errorf("entry title, id = %q, %q", entry.Title, entry.ID)
+	}
+	if len(entry.Links) != 1 || entry.Links[0].Href != "https://wdygdtw.example.com/app/summaries?week=2024-05-06" {
+		t.Errorf("entry links = %+v", entry.Links)
+	}
+	for _, want := range []string{"Shipped the &lt;billing&gt; migration &amp; fixed alerts.", "<li>Fixed paging alerts</li>", `<img src="https://cards.example.com/cards/abc.png"`} {
+		if !strings.Contains(entry.Content.Body, want) {
+			t.Errorf("entry content missing %q:\n%s", want, entry.Content.Body)
+		}
+	}
+}
+
+func TestRSS(t *testing.T) {
+	feed := testFeed()
+	feed.DashboardURL = ""
+
+	var b bytes.Buffer
+	if err := feed.Write(&b, FormatRSS); err != nil {
+		t.Fatal(err)
+	}
+
+	var got rssFeed
+	if err := xml.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("feed is not valid XML: %v\n%s", err, b.String())
+	}
+	if got.Channel.Link != feed.Self {
+		t.Errorf("channel link = %q, want the feed itself without a dashboard", got.Channel.Link)
+	}
+	if len(got.Channel.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(got.Channel.Items))
+	}
+	item := got.Channel.Items[1]
+	if item.Link != "" || item.GUID.Value != "urn:wdygdtw:summary:9" || item.GUID.IsPermaLink {
+		t.Errorf("item = %+v", item)
+	}
+	if item.PubDate != "Fri, 03 May 2024 17:30:00 +0000" {
+		t.Errorf("pubDate = %q", item.PubDate)
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	if err := testFeed().Write(&bytes.Buffer{}, "json"); err == nil {
+		t.Error("Write() with an unknown format succeeded")
+	}
+}
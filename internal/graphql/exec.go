@@ -0,0 +1,521 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+)
+
+// ResolveFunc returns the value of a field. source is the parent object's
+// value and args holds the field arguments with variables substituted.
+type ResolveFunc func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+
+// Schema holds the root types. Mutation may be nil.
+type Schema struct {
+	Query    *Object
+	Mutation *Object
+}
+
+// Object is an object type
+type Object struct {
+	Name   string
+	Fields map[string]*FieldDef
+}
+
+// FieldDef defines a field. Type is nil for scalars, which are encoded as
+// JSON; otherwise the resolved value (or each element of a resolved slice) is
+// an instance of Type. A nil Resolve reads the source struct field whose JSON
+// name matches.
+type FieldDef struct {
+	Type    *Object
+	Args    []string
+	Resolve ResolveFunc
+}
+
+// NewObject creates an object type exposing every JSON-tagged field of
+// sample's struct type as a scalar. Fields can be added or replaced after.
+func NewObject(name string, sample interface{}) *Object {
+	object := &Object{Name: name, Fields: make(map[string]*FieldDef)}
+	if sample == nil {
+		return object
+	}
+
+	t := reflect.TypeOf(sample)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonName(t.Field(i)); name != "" {
+			object.Fields[name] = &FieldDef{}
+		}
+	}
+	return object
+}
+
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// Request is the standard GraphQL-over-HTTP request body
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Response is the standard GraphQL response body
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is a request or field error. Path is set for field errors, and
+// Unwrap returns the error a resolver failed with.
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+	err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the resolver error, if any
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Execute parses and runs req against schema. Field errors are reported in
+// the response alongside the data that did resolve; a request that can't be
+// parsed or validated has no data.
+func Execute(ctx context.Context, schema *Schema, req Request) *Response {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	root := schema.Query
+	if op.Type == "mutation" {
+		root = schema.Mutation
+	}
+	if root == nil {
+		return &Response{Errors: []*Error{{Message: op.Type + "s are not supported"}}}
+	}
+
+	variables, err := coerceVariables(op, req.Variables)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	e := &executor{doc: doc, variables: variables}
+	data := e.executeSelections(ctx, root, nil, op.Selections, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func selectOperation(doc *Document, name string) (*Operation, error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document has several operations")
+		}
+		return doc.Operations[0], nil
+	}
+
+	for _, op := range doc.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+func coerceVariables(op *Operation, values map[string]interface{}) (map[string]interface{}, error) {
+	variables := make(map[string]interface{}, len(op.Variables))
+	for _, def := range op.Variables {
+		value, ok := values[def.Name]
+		if !ok && def.HasDefault {
+			value, ok = def.Default, true
+		}
+		if (!ok || value == nil) && def.NonNull {
+			return nil, fmt.Errorf("variable $%s of type %s is required", def.Name, def.Type)
+		}
+		if ok {
+			variables[def.Name] = value
+		}
+	}
+	return variables, nil
+}
+
+type executor struct {
+	doc       *Document
+	variables map[string]interface{}
+	errors    []*Error
+}
+
+func (e *executor) fail(path []interface{}, err error) {
+	e.errors = append(e.errors, &Error{Message: err.Error(), Path: append([]interface{}{}, path...)})
+}
+
+func (e *executor) resolverFailed(path []interface{}, err error) {
+	e.errors = append(e.errors, &Error{Message: err.Error(), Path: append([]interface{}{}, path...), err: err})
+}
+
+func (e *executor) executeSelections(ctx context.Context, object *Object, source interface{}, selections []Selection, path []interface{}) *orderedMap {
+	groups := &fieldGroups{fields: make(map[string][]*Field)}
+	e.collect(object, selections, path, groups, make(map[string]bool))
+
+	result := &orderedMap{}
+	for _, key := range groups.keys {
+		field, err := mergeFields(key, groups.fields[key])
+		if err != nil {
+			e.fail(append(path, key), err)
+			result.set(key, nil)
+			continue
+		}
+		result.set(key, e.executeField(ctx, object, source, field, append(path, key)))
+	}
+	return result
+}
+
+// fieldGroups holds the fields selected under each response key, keys in
+// the order they are first selected
+type fieldGroups struct {
+	keys   []string
+	fields map[string][]*Field
+}
+
+func (g *fieldGroups) add(field *Field) {
+	key := field.ResponseKey()
+	if _, ok := g.fields[key]; !ok {
+		g.keys = append(g.keys, key)
+	}
+	g.fields[key] = append(g.fields[key], field)
+}
+
+// collect gathers the included fields of selections, expanding fragments
+func (e *executor) collect(object *Object, selections []Selection, path []interface{}, groups *fieldGroups, visited map[string]bool) {
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *Field:
+			if !e.included(sel.Directives, path) {
+				continue
+			}
+			groups.add(sel)
+		case *FragmentSpread:
+			if !e.included(sel.Directives, path) || visited[sel.Name] {
+				continue
+			}
+			fragment, ok := e.doc.Fragments[sel.Name]
+			if !ok {
+				e.fail(path, fmt.Errorf("unknown fragment %q", sel.Name))
+				continue
+			}
+			if fragment.TypeCondition != object.Name {
+				continue
+			}
+			visited[sel.Name] = true
+			e.collect(object, fragment.Selections, path, groups, visited)
+		case *InlineFragment:
+			if !e.included(sel.Directives, path) {
+				continue
+			}
+			if sel.TypeCondition != "" && sel.TypeCondition != object.Name {
+				continue
+			}
+			e.collect(object, sel.Selections, path, groups, visited)
+		}
+	}
+}
+
+// mergeFields combines fields selected under the same response key into one
+// field with all their subselections. They must select the same field with
+// the same arguments.
+func mergeFields(key string, fields []*Field) (*Field, error) {
+	first := fields[0]
+	if len(fields) == 1 {
+		return first, nil
+	}
+
+	merged := *first
+	merged.Selections = append([]Selection{}, first.Selections...)
+	for _, field := range fields[1:] {
+		if field.Name != first.Name {
+			return nil, fmt.Errorf("fields %q conflict because %s and %s are different fields", key, first.Name, field.Name)
+		}
+		if !reflect.DeepEqual(field.Arguments, first.Arguments) {
+			return nil, fmt.Errorf("fields %q conflict because they have differing arguments", key)
+		}
+		merged.Selections = append(merged.Selections, field.Selections...)
+	}
+	return &merged, nil
+}
+
+// included evaluates @skip and @include
+func (e *executor) included(directives []*Directive, path []interface{}) bool {
+	for _, directive := range directives {
+		switch directive.Name {
+		case "skip", "include":
+			value, err := e.resolveValue(directive.Arguments["if"])
+			flag, ok := value.(bool)
+			if err != nil || !ok {
+				e.fail(path, fmt.Errorf("@%s requires a Boolean if argument", directive.Name))
+				return false
+			}
+			if flag == (directive.Name == "skip") {
+				return false
+			}
+		default:
+			e.fail(path, fmt.Errorf("unknown directive @%s", directive.Name))
+			return false
+		}
+	}
+	return true
+}
+
+func (e *executor) executeField(ctx context.Context, object *Object, source interface{}, field *Field, path []interface{}) interface{} {
+	if field.Name == "__typename" {
+		return object.Name
+	}
+
+	def, ok := object.Fields[field.Name]
+	if !ok {
+		e.fail(path, fmt.Errorf("cannot query field %q on type %s", field.Name, object.Name))
+		return nil
+	}
+
+	args, err := e.arguments(def, field)
+	if err != nil {
+		e.fail(path, err)
+		return nil
+	}
+
+	if def.Type == nil && len(field.Selections) > 0 {
+		e.fail(path, fmt.Errorf("field %q is a scalar and has no subfields", field.Name))
+		return nil
+	}
+	if def.Type != nil && len(field.Selections) == 0 {
+		e.fail(path, fmt.Errorf("field %q of type %s must have a selection of subfields", field.Name, def.Type.Name))
+		return nil
+	}
+
+	var value interface{}
+	if def.Resolve != nil {
+		value, err = def.Resolve(ctx, source, args)
+	} else {
+		value, err = structField(source, field.Name)
+	}
+	if err != nil {
+		e.resolverFailed(path, err)
+		return nil
+	}
+
+	return e.complete(ctx, def.Type, value, field, path)
+}
+
+func (e *executor) arguments(def *FieldDef, field *Field) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(field.Arguments))
+	for name, raw := range field.Arguments {
+		known := false
+		for _, allowed := range def.Args {
+			known = known || allowed == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown argument %q on field %q", name, field.Name)
+		}
+
+		value, err := e.resolveValue(raw)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			args[name] = value
+		}
+	}
+	return args, nil
+}
+
+// resolveValue substitutes variables in a parsed literal
+func (e *executor) resolveValue(raw interface{}) (interface{}, error) {
+	switch v := raw.(type) {
+	case Variable:
+		return e.variables[v.Name], nil
+	case EnumValue:
+		return string(v), nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			value, err := e.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return list, nil
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			value, err := e.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = value
+		}
+		return object, nil
+	}
+	return raw, nil
+}
+
+// complete shapes a resolved value: scalars as is, objects by their
+// selection set, slices element by element
+func (e *executor) complete(ctx context.Context, object *Object, value interface{}, field *Field, path []interface{}) interface{} {
+	if isNil(value) {
+		return nil
+	}
+	if object == nil {
+		return value
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Slice {
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = e.complete(ctx, object, v.Index(i).Interface(), field, append(path, i))
+		}
+		return list
+	}
+
+	return e.executeSelections(ctx, object, value, field.Selections, path)
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// structField reads the field of source whose JSON name is name
+func structField(source interface{}, name string) (interface{}, error) {
+	v := reflect.ValueOf(source)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("field %q has no resolver", name)
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if jsonName(t.Field(i)) == name {
+			return v.Field(i).Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf("field %q has no resolver", name)
+}
+
+// orderedMap keeps response keys in selection order
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if m.values == nil {
+		m.values = make(map[string]interface{})
+	}
+	m.keys = append(m.keys, key)
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		encodedValue, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(encodedValue)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// StringArg returns a String argument, reporting whether it was given
+func StringArg(args map[string]interface{}, name string) (string, bool, error) {
+	value, ok := args[name]
+	if !ok {
+		return "", false, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", false, apperrors.New(apperrors.CodeInvalidInput, "argument %q must be a String", name)
+	}
+	return s, true, nil
+}
+
+// IntArg returns an Int argument, reporting whether it was given. Numbers
+// from JSON variables arrive as float64 and must be whole.
+func IntArg(args map[string]interface{}, name string) (int, bool, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return 0, false, nil
+	case int64:
+		return int(v), true, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), true, nil
+		}
+	}
+	return 0, false, apperrors.New(apperrors.CodeInvalidInput, "argument %q must be an Int", name)
+}
+
+// BoolArg returns a Boolean argument, reporting whether it was given
+func BoolArg(args map[string]interface{}, name string) (bool, bool, error) {
+	value, ok := args[name]
+	if !ok {
+		return false, false, nil
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, false, apperrors.New(apperrors.CodeInvalidInput, "argument %q must be a Boolean", name)
+	}
+	return b, true, nil
+}
@@ -0,0 +1,377 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testEntry struct {
+	ID      int    `json:"id"`
+	Content string `json:"content"`
+	Mood    string `json:"mood"`
+}
+
+type testUser struct {
+	Name    string `json:"name"`
+	Private string `json:"-"`
+}
+
+var errResolver = errors.New("resolver failed")
+
+// testSchema is Query { user: User, entries(limit): [Entry], broken: Entry }
+// and Mutation { rename(name): User }
+func testSchema() *Schema {
+	entry := NewObject("Entry", testEntry{})
+	user := NewObject("User", testUser{})
+	user.Fields["entries"] = &FieldDef{
+		Type: entry,
+		Args: []string{"limit"},
+		Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			entries := []*testEntry{
+				{ID: 1, Content: "Shipped the release", Mood: "good"},
+				{ID: 2, Content: "Fixed the build", Mood: "ok"},
+				{ID: 3, Content: "Planned Q3", Mood: "good"},
+			}
+			limit, ok, err := IntArg(args, "limit")
+			if err != nil {
+				return nil, err
+			}
+			if ok && limit < len(entries) {
+				entries = entries[:limit]
+			}
+			return entries, nil
+		},
+	}
+
+	query := NewObject("Query", nil)
+	query.Fields["user"] = &FieldDef{
+		Type: user,
+		Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			return &testUser{Name: "Ada", Private: "secret"}, nil
+		},
+	}
+	query.Fields["broken"] = &FieldDef{
+		Type: entry,
+		Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			return nil, errResolver
+		},
+	}
+
+	mutation := NewObject("Mutation", nil)
+	mutation.Fields["rename"] = &FieldDef{
+		Type: user,
+		Args: []string{"name"},
+		Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			name, _, err := StringArg(args, "name")
+			if err != nil {
+				return nil, err
+			}
+			return &testUser{Name: name}, nil
+		},
+	}
+
+	return &Schema{Query: query, Mutation: mutation}
+}
+
+// execute runs req and returns the response as JSON
+func execute(t *testing.T, req Request) (string, *Response) {
+	t.Helper()
+	resp := Execute(context.Background(), testSchema(), req)
+	body, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("failed to encode response: %v", err)
+	}
+	return string(body), resp
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string
+	}{
+		{
+			name:  "scalar fields in selection order",
+			query: `{ user { name entries(limit: 1) { content id } } }`,
+			want:  `{"data":{"user":{"name":"Ada","entries":[{"content":"Shipped the release","id":1}]}}}`,
+		},
+		{
+			name:  "aliases",
+			query: `{ me: user { who: name first: entries(limit: 1) { id } all: entries { id } } }`,
+			want:  `{"data":{"me":{"who":"Ada","first":[{"id":1}],"all":[{"id":1},{"id":2},{"id":3}]}}}`,
+		},
+		{
+			name:  "typename",
+			query: `{ __typename user { __typename } }`,
+			want:  `{"data":{"__typename":"Query","user":{"__typename":"User"}}}`,
+		},
+		{
+			name:  "named fragment",
+			query: `{ user { entries(limit: 2) { ...Fields } } } fragment Fields on Entry { id mood }`,
+			want:  `{"data":{"user":{"entries":[{"id":1,"mood":"good"},{"id":2,"mood":"ok"}]}}}`,
+		},
+		{
+			name:  "fragment on another type is skipped",
+			query: `{ user { name ...Fields } } fragment Fields on Entry { id }`,
+			want:  `{"data":{"user":{"name":"Ada"}}}`,
+		},
+		{
+			name:  "inline fragments",
+			query: `{ user { ... on User { name } ... on Entry { id } ... { entries(limit: 1) { id } } } }`,
+			want:  `{"data":{"user":{"name":"Ada","entries":[{"id":1}]}}}`,
+		},
+		{
+			name:  "fragment spread twice is expanded once",
+			query: `{ user { ...U ...U } } fragment U on User { name }`,
+			want:  `{"data":{"user":{"name":"Ada"}}}`,
+		},
+		{
+			name:  "same key selected twice merges subselections",
+			query: `{ user { entries(limit: 1) { id } entries(limit: 1) { mood } } }`,
+			want:  `{"data":{"user":{"entries":[{"id":1,"mood":"good"}]}}}`,
+		},
+		{
+			name:  "fragment and field on the same key merge",
+			query: `{ user { entries { id } ...E } } fragment E on User { entries { content } }`,
+			want:  `{"data":{"user":{"entries":[{"id":1,"content":"Shipped the release"},{"id":2,"content":"Fixed the build"},{"id":3,"content":"Planned Q3"}]}}}`,
+		},
+		{
+			name:  "skip and include literals",
+			query: `{ user { name @skip(if: true) a: name @skip(if: false) b: name @include(if: false) c: name @include(if: true) } }`,
+			want:  `{"data":{"user":{"a":"Ada","c":"Ada"}}}`,
+		},
+		{
+			name:      "skip and include variables",
+			query:     `query ($hide: Boolean!, $show: Boolean!) { user { name @skip(if: $hide) entries(limit: 1) @include(if: $show) { id } } }`,
+			variables: map[string]interface{}{"hide": true, "show": true},
+			want:      `{"data":{"user":{"entries":[{"id":1}]}}}`,
+		},
+		{
+			name:      "directives on fragments",
+			query:     `query ($show: Boolean!) { user { ...U @include(if: $show) ... on User @skip(if: $show) { entries(limit: 1) { id } } } } fragment U on User { name }`,
+			variables: map[string]interface{}{"show": true},
+			want:      `{"data":{"user":{"name":"Ada"}}}`,
+		},
+		{
+			name:      "variables from JSON",
+			query:     `query ($limit: Int) { user { entries(limit: $limit) { id } } }`,
+			variables: map[string]interface{}{"limit": float64(2)},
+			want:      `{"data":{"user":{"entries":[{"id":1},{"id":2}]}}}`,
+		},
+		{
+			name:  "variable default",
+			query: `query ($limit: Int = 1) { user { entries(limit: $limit) { id } } }`,
+			want:  `{"data":{"user":{"entries":[{"id":1}]}}}`,
+		},
+		{
+			name:      "given variable overrides default",
+			query:     `query ($limit: Int = 1) { user { entries(limit: $limit) { id } } }`,
+			variables: map[string]interface{}{"limit": float64(3)},
+			want:      `{"data":{"user":{"entries":[{"id":1},{"id":2},{"id":3}]}}}`,
+		},
+		{
+			name:  "unset optional variable omits the argument",
+			query: `query ($limit: Int) { user { entries(limit: $limit) { id } } }`,
+			want:  `{"data":{"user":{"entries":[{"id":1},{"id":2},{"id":3}]}}}`,
+		},
+		{
+			name:      "mutation",
+			query:     `mutation Rename($name: String!) { rename(name: $name) { name } }`,
+			variables: map[string]interface{}{"name": "Grace"},
+			want:      `{"data":{"rename":{"name":"Grace"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := execute(t, Request{Query: tt.query, Variables: tt.variables})
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteFieldErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantData string
+		wantErr  string
+		wantPath string
+	}{
+		{
+			name:     "unknown field",
+			query:    `{ user { name age } }`,
+			wantData: `{"user":{"name":"Ada","age":null}}`,
+			wantErr:  `cannot query field "age" on type User`,
+			wantPath: `["user","age"]`,
+		},
+		{
+			name:     "json hidden field",
+			query:    `{ user { Private } }`,
+			wantData: `{"user":{"Private":null}}`,
+			wantErr:  `cannot query field "Private" on type User`,
+			wantPath: `["user","Private"]`,
+		},
+		{
+			name:     "unknown argument",
+			query:    `{ user { entries(first: 1) { id } } }`,
+			wantData: `{"user":{"entries":null}}`,
+			wantErr:  `unknown argument "first" on field "entries"`,
+			wantPath: `["user","entries"]`,
+		},
+		{
+			name:     "invalid argument type",
+			query:    `{ user { entries(limit: "two") { id } } }`,
+			wantData: `{"user":{"entries":null}}`,
+			wantErr:  `argument "limit" must be an Int`,
+			wantPath: `["user","entries"]`,
+		},
+		{
+			name:     "subfields on a scalar",
+			query:    `{ user { name { first } } }`,
+			wantData: `{"user":{"name":null}}`,
+			wantErr:  `field "name" is a scalar and has no subfields`,
+			wantPath: `["user","name"]`,
+		},
+		{
+			name:     "object without subfields",
+			query:    `{ user }`,
+			wantData: `{"user":null}`,
+			wantErr:  `field "user" of type User must have a selection of subfields`,
+			wantPath: `["user"]`,
+		},
+		{
+			name:     "resolver error",
+			query:    `{ broken { id } user { name } }`,
+			wantData: `{"broken":null,"user":{"name":"Ada"}}`,
+			wantErr:  errResolver.Error(),
+			wantPath: `["broken"]`,
+		},
+		{
+			name:     "error inside a list",
+			query:    `{ user { entries(limit: 1) { id nope } } }`,
+			wantData: `{"user":{"entries":[{"id":1,"nope":null}]}}`,
+			wantErr:  `cannot query field "nope" on type Entry`,
+			wantPath: `["user","entries",0,"nope"]`,
+		},
+		{
+			name:     "unknown fragment",
+			query:    `{ user { name ...Missing } }`,
+			wantData: `{"user":{"name":"Ada"}}`,
+			wantErr:  `unknown fragment "Missing"`,
+			wantPath: `["user"]`,
+		},
+		{
+			name:     "unknown directive",
+			query:    `{ user { name @deprecated } }`,
+			wantData: `{"user":{}}`,
+			wantErr:  "unknown directive @deprecated",
+			wantPath: `["user"]`,
+		},
+		{
+			name:     "directive without a Boolean",
+			query:    `{ user { name @skip(if: "yes") } }`,
+			wantData: `{"user":{}}`,
+			wantErr:  "@skip requires a Boolean if argument",
+			wantPath: `["user"]`,
+		},
+		{
+			name:     "same key on different fields",
+			query:    `{ user { x: name x: entries { id } } }`,
+			wantData: `{"user":{"x":null}}`,
+			wantErr:  `fields "x" conflict because name and entries are different fields`,
+			wantPath: `["user","x"]`,
+		},
+		{
+			name:     "same key with different arguments",
+			query:    `{ user { entries(limit: 1) { id } entries(limit: 2) { id } } }`,
+			wantData: `{"user":{"entries":null}}`,
+			wantErr:  `fields "entries" conflict because they have differing arguments`,
+			wantPath: `["user","entries"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp := execute(t, Request{Query: tt.query})
+
+			data, err := json.Marshal(resp.Data)
+			if err != nil {
+				t.Fatalf("failed to encode data: %v", err)
+			}
+			if string(data) != tt.wantData {
+				t.Errorf("data = %s, want %s", data, tt.wantData)
+			}
+
+			if len(resp.Errors) != 1 {
+				t.Fatalf("got %d errors (%v), want 1", len(resp.Errors), resp.Errors)
+			}
+			if resp.Errors[0].Message != tt.wantErr {
+				t.Errorf("error = %q, want %q", resp.Errors[0].Message, tt.wantErr)
+			}
+			path, _ := json.Marshal(resp.Errors[0].Path)
+			if string(path) != tt.wantPath {
+				t.Errorf("error path = %s, want %s", path, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestExecuteResolverErrorUnwraps(t *testing.T) {
+	_, resp := execute(t, Request{Query: `{ broken { id } }`})
+	if len(resp.Errors) != 1 || !errors.Is(resp.Errors[0], errResolver) {
+		t.Fatalf("errors = %v, want one wrapping the resolver error", resp.Errors)
+	}
+}
+
+func TestExecuteRequestErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       Request
+		wantError string
+	}{
+		{"malformed query", Request{Query: `{ user { name }`}, "unexpected end of document"},
+		{"fragment cycle", Request{Query: `{ user { ...A } } fragment A on User { ...A }`}, "spreads itself"},
+		{"several operations without a name", Request{Query: `query A { user { name } } query B { user { name } }`}, "operationName is required"},
+		{"unknown operation", Request{Query: `query A { user { name } }`, OperationName: "B"}, `unknown operation "B"`},
+		{"missing required variable", Request{Query: `query ($name: String!) { rename(name: $name) { name } }`}, "variable $name of type String! is required"},
+		{"null required variable", Request{Query: `query ($name: String!) { rename(name: $name) { name } }`, Variables: map[string]interface{}{"name": nil}}, "is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, resp := execute(t, tt.req)
+			if resp.Data != nil {
+				t.Errorf("response has data: %s", body)
+			}
+			if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, tt.wantError) {
+				t.Errorf("response = %s, want one error containing %q", body, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestExecuteSelectsNamedOperation(t *testing.T) {
+	got, _ := execute(t, Request{
+		Query:         `query A { user { name } } query B { __typename }`,
+		OperationName: "B",
+	})
+	if want := `{"data":{"__typename":"Query"}}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestExecuteMutationWithoutSchema(t *testing.T) {
+	schema := testSchema()
+	schema.Mutation = nil
+	resp := Execute(context.Background(), schema, Request{Query: `mutation { rename(name: "x") { name } }`})
+	if resp.Data != nil || len(resp.Errors) != 1 || resp.Errors[0].Message != "mutations are not supported" {
+		t.Errorf("response = %+v, want only a mutations are not supported error", resp)
+	}
+}
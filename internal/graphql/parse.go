@@ -0,0 +1,701 @@
+// Package graphql is a small GraphQL executor for the dashboard API. It
+// supports queries and mutations with arguments, variables, aliases,
+// fragments and the @include/@skip directives. Subscriptions and
+// introspection are not supported; the schema is documented in the README.
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Document is a parsed request
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query or mutation
+type Operation struct {
+	Type       string
+	Name       string
+	Variables  []*VariableDefinition
+	Selections []Selection
+}
+
+// VariableDefinition declares an operation variable and its default
+type VariableDefinition struct {
+	Name       string
+	Type       string
+	NonNull    bool
+	Default    interface{}
+	HasDefault bool
+}
+
+// Fragment is a named fragment definition
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []Selection
+}
+
+// Selection is a *Field, *FragmentSpread or *InlineFragment
+type Selection interface{}
+
+// Field selects a field, optionally aliased
+type Field struct {
+	Alias      string
+	Name       string
+	Arguments  map[string]interface{}
+	Directives []*Directive
+	Selections []Selection
+}
+
+// ResponseKey is the alias if set, else the field name
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// FragmentSpread includes a named fragment
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+}
+
+// InlineFragment includes selections inline, optionally for a type
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []*Directive
+	Selections    []Selection
+}
+
+// Directive is a directive such as @include(if: $flag)
+type Directive struct {
+	Name      string
+	Arguments map[string]interface{}
+}
+
+// Variable is a reference to an operation variable inside a value
+type Variable struct {
+	Name string
+}
+
+// EnumValue is an unquoted name used as a value
+type EnumValue string
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// Parse parses a GraphQL request document
+func Parse(source string) (*Document, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	doc := &Document{Fragments: make(map[string]*Fragment)}
+
+	for p.peek().kind != tokenEOF {
+		switch {
+		case p.peekPunct("{"):
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Type: "query", Selections: selections})
+		case p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case p.peekName("fragment"):
+			fragment, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := doc.Fragments[fragment.Name]; exists {
+				return nil, fmt.Errorf("fragment %q is defined more than once", fragment.Name)
+			}
+			doc.Fragments[fragment.Name] = fragment
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("document contains no operations")
+	}
+	if err := checkFragmentCycles(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// checkFragmentCycles rejects fragments that spread themselves, directly or
+// through other fragments, which would otherwise expand forever in nested
+// selections
+func checkFragmentCycles(doc *Document) error {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(doc.Fragments))
+
+	var visit func(name string) error
+	var visitSelections func(selections []Selection) error
+	visit = func(name string) error {
+		fragment, ok := doc.Fragments[name]
+		if !ok || state[name] == done {
+			return nil
+		}
+		if state[name] == visiting {
+			return fmt.Errorf("fragment %q spreads itself", name)
+		}
+		state[name] = visiting
+		if err := visitSelections(fragment.Selections); err != nil {
+			return err
+		}
+		state[name] = done
+		return nil
+	}
+	visitSelections = func(selections []Selection) error {
+		for _, selection := range selections {
+			var err error
+			switch sel := selection.(type) {
+			case *Field:
+				err = visitSelections(sel.Selections)
+			case *FragmentSpread:
+				err = visit(sel.Name)
+			case *InlineFragment:
+				err = visitSelections(sel.Selections)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for name := range doc.Fragments {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(source[i:], "..."):
+			tokens = append(tokens, token{kind: tokenPunct, value: "...", pos: i})
+			i += 3
+		case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+			tokens = append(tokens, token{kind: tokenPunct, value: string(c), pos: i})
+			i++
+		case c == '_' || isLetter(c):
+			start := i
+			for i < len(source) && (source[i] == '_' || isLetter(source[i]) || isDigit(source[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenName, value: source[start:i], pos: start})
+		case c == '-' || isDigit(c):
+			start := i
+			kind := tokenInt
+			i++
+			for i < len(source) && (isDigit(source[i]) || strings.IndexByte(".eE+-", source[i]) >= 0) {
+				if strings.IndexByte(".eE", source[i]) >= 0 {
+					kind = tokenFloat
+				}
+				i++
+			}
+			tokens = append(tokens, token{kind: kind, value: source[start:i], pos: start})
+		case c == '"':
+			start := i
+			if strings.HasPrefix(source[i:], `"""`) {
+				end := strings.Index(source[i+3:], `"""`)
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated block string at offset %d", start)
+				}
+				tokens = append(tokens, token{kind: tokenString, value: source[i+3 : i+3+end], pos: start})
+				i += end + 6
+				continue
+			}
+			i++
+			for i < len(source) && source[i] != '"' && source[i] != '\n' {
+				if source[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(source) || source[i] != '"' {
+				return nil, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			i++
+			value, err := strconv.Unquote(source[start:i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d", start)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value, pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// maxDepth bounds how deeply selection sets and values nest, so a hostile
+// document can't exhaust the parser's stack
+const maxDepth = 32
+
+type parser struct {
+	tokens []token
+	pos    int
+	// depth is how many selection sets and values enclose the current one
+	depth int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) peekPunct(value string) bool {
+	t := p.peek()
+	return t.kind == tokenPunct && t.value == value
+}
+
+func (p *parser) peekName(value string) bool {
+	t := p.peek()
+	return t.kind == tokenName && t.value == value
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of document")
+	}
+	return fmt.Errorf("unexpected %q at offset %d", t.value, t.pos)
+}
+
+func (p *parser) expectPunct(value string) error {
+	if !p.peekPunct(value) {
+		return p.unexpected()
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) expectName() (string, error) {
+	if p.peek().kind != tokenName {
+		return "", p.unexpected()
+	}
+	return p.next().value, nil
+}
+
+func (p *parser) parseOperation() (*Operation, error) {
+	op := &Operation{Type: p.next().value}
+	if op.Type == "subscription" {
+		return nil, fmt.Errorf("subscriptions are not supported")
+	}
+
+	if p.peek().kind == tokenName {
+		op.Name = p.next().value
+	}
+
+	if p.peekPunct("(") {
+		p.next()
+		for !p.peekPunct(")") {
+			def, err := p.parseVariableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			op.Variables = append(op.Variables, def)
+		}
+		p.next()
+	}
+
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.Selections = selections
+	return op, nil
+}
+
+func (p *parser) parseVariableDefinition() (*VariableDefinition, error) {
+	if err := p.expectPunct("$"); err != nil {
+		return nil, err
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectPunct(":"); err != nil {
+		return nil, err
+	}
+
+	def := &VariableDefinition{Name: name}
+	typeName, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	def.Type = typeName
+	def.NonNull = strings.HasSuffix(typeName, "!")
+
+	if p.peekPunct("=") {
+		p.next()
+		value, err := p.parseValue(true)
+		if err != nil {
+			return nil, err
+		}
+		def.Default, def.HasDefault = value, true
+	}
+	return def, nil
+}
+
+func (p *parser) parseType() (string, error) {
+	var typeName string
+	if p.peekPunct("[") {
+		p.next()
+		inner, err := p.parseType()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return "", err
+		}
+		typeName = "[" + inner + "]"
+	} else {
+		name, err := p.expectName()
+		if err != nil {
+			return "", err
+		}
+		typeName = name
+	}
+
+	if p.peekPunct("!") {
+		p.next()
+		typeName += "!"
+	}
+	return typeName, nil
+}
+
+func (p *parser) parseFragment() (*Fragment, error) {
+	p.next()
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekName("on") {
+		return nil, p.unexpected()
+	}
+	p.next()
+	typeCondition, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &Fragment{Name: name, TypeCondition: typeCondition, Selections: selections}, nil
+}
+
+// descend enters a nested selection set or value; the caller leaves it
+// with p.depth--
+func (p *parser) descend() error {
+	if p.depth >= maxDepth {
+		return fmt.Errorf("document nests more than %d levels deep at offset %d", maxDepth, p.peek().pos)
+	}
+	p.depth++
+	return nil
+}
+
+func (p *parser) parseSelectionSet() ([]Selection, error) {
+	if err := p.descend(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+
+	var selections []Selection
+	for !p.peekPunct("}") {
+		if p.peek().kind == tokenEOF {
+			return nil, p.unexpected()
+		}
+
+		if p.peekPunct("...") {
+			p.next()
+			selection, err := p.parseFragmentSelection()
+			if err != nil {
+				return nil, err
+			}
+			selections = append(selections, selection)
+			continue
+		}
+
+		field, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, field)
+	}
+	p.next()
+
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("selection set is empty")
+	}
+	return selections, nil
+}
+
+func (p *parser) parseFragmentSelection() (Selection, error) {
+	if p.peek().kind == tokenName && !p.peekName("on") {
+		name := p.next().value
+		directives, err := p.parseDirectives()
+		if err != nil {
+			return nil, err
+		}
+		return &FragmentSpread{Name: name, Directives: directives}, nil
+	}
+
+	inline := &InlineFragment{}
+	if p.peekName("on") {
+		p.next()
+		typeCondition, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		inline.TypeCondition = typeCondition
+	}
+
+	directives, err := p.parseDirectives()
+	if err != nil {
+		return nil, err
+	}
+	inline.Directives = directives
+
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	inline.Selections = selections
+	return inline, nil
+}
+
+func (p *parser) parseField() (*Field, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+
+	field := &Field{Name: name}
+	if p.peekPunct(":") {
+		p.next()
+		if field.Name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+		field.Alias = name
+	}
+
+	if field.Arguments, err = p.parseArguments(); err != nil {
+		return nil, err
+	}
+	if field.Directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+
+	if p.peekPunct("{") {
+		if field.Selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return field, nil
+}
+
+func (p *parser) parseArguments() (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	if !p.peekPunct("(") {
+		return args, nil
+	}
+	p.next()
+
+	for !p.peekPunct(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue(false)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := args[name]; exists {
+			return nil, fmt.Errorf("argument %q is given more than once", name)
+		}
+		args[name] = value
+	}
+	p.next()
+	return args, nil
+}
+
+func (p *parser) parseDirectives() ([]*Directive, error) {
+	var directives []*Directive
+	for p.peekPunct("@") {
+		p.next()
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, &Directive{Name: name, Arguments: args})
+	}
+	return directives, nil
+}
+
+// parseValue parses a literal. Variables are not allowed in constant
+// positions such as variable defaults.
+func (p *parser) parseValue(constant bool) (interface{}, error) {
+	if err := p.descend(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	t := p.peek()
+	switch t.kind {
+	case tokenPunct:
+		switch t.value {
+		case "$":
+			if constant {
+				return nil, p.unexpected()
+			}
+			p.next()
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			return Variable{Name: name}, nil
+		case "[":
+			p.next()
+			list := []interface{}{}
+			for !p.peekPunct("]") {
+				value, err := p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			p.next()
+			return list, nil
+		case "{":
+			p.next()
+			object := make(map[string]interface{})
+			for !p.peekPunct("}") {
+				name, err := p.expectName()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expectPunct(":"); err != nil {
+					return nil, err
+				}
+				value, err := p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+				object[name] = value
+			}
+			p.next()
+			return object, nil
+		}
+	case tokenInt:
+		p.next()
+		value, err := strconv.ParseInt(t.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q at offset %d", t.value, t.pos)
+		}
+		return value, nil
+	case tokenFloat:
+		p.next()
+		value, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q at offset %d", t.value, t.pos)
+		}
+		return value, nil
+	case tokenString:
+		p.next()
+		return t.value, nil
+	case tokenName:
+		p.next()
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return EnumValue(t.value), nil
+	}
+	return nil, p.unexpected()
+}
@@ -0,0 +1,144 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(`
+		# entries for the week
+		query Week($from: String!, $limit: Int = 10, $tags: [String]) {
+			recent: entries(from: $from, limit: $limit) @include(if: true) {
+				...EntryFields
+				... on Entry { content }
+			}
+		}
+		fragment EntryFields on Entry { id, entry_date }
+	`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(doc.Operations) != 1 {
+		t.Fatalf("got %d operations, want 1", len(doc.Operations))
+	}
+	op := doc.Operations[0]
+	if op.Type != "query" || op.Name != "Week" {
+		t.Errorf("operation = %s %s, want query Week", op.Type, op.Name)
+	}
+
+	if len(op.Variables) != 3 {
+		t.Fatalf("got %d variables, want 3", len(op.Variables))
+	}
+	from, limit, tags := op.Variables[0], op.Variables[1], op.Variables[2]
+	if from.Name != "from" || from.Type != "String!" || !from.NonNull || from.HasDefault {
+		t.Errorf("$from = %+v", from)
+	}
+	if limit.Type != "Int" || limit.NonNull || !limit.HasDefault || limit.Default != int64(10) {
+		t.Errorf("$limit = %+v", limit)
+	}
+	if tags.Type != "[String]" {
+		t.Errorf("$tags type = %q, want [String]", tags.Type)
+	}
+
+	field, ok := op.Selections[0].(*Field)
+	if !ok {
+		t.Fatalf("selection is %T, want *Field", op.Selections[0])
+	}
+	if field.Alias != "recent" || field.Name != "entries" || field.ResponseKey() != "recent" {
+		t.Errorf("field = %s: %s", field.Alias, field.Name)
+	}
+	if field.Arguments["from"] != (Variable{Name: "from"}) {
+		t.Errorf("from argument = %#v", field.Arguments["from"])
+	}
+	if len(field.Directives) != 1 || field.Directives[0].Name != "include" || field.Directives[0].Arguments["if"] != true {
+		t.Errorf("directives = %+v", field.Directives)
+	}
+	if spread, ok := field.Selections[0].(*FragmentSpread); !ok || spread.Name != "EntryFields" {
+		t.Errorf("first subselection = %#v, want spread of EntryFields", field.Selections[0])
+	}
+	if inline, ok := field.Selections[1].(*InlineFragment); !ok || inline.TypeCondition != "Entry" {
+		t.Errorf("second subselection = %#v, want inline fragment on Entry", field.Selections[1])
+	}
+
+	fragment := doc.Fragments["EntryFields"]
+	if fragment == nil || fragment.TypeCondition != "Entry" || len(fragment.Selections) != 2 {
+		t.Errorf("fragment = %+v", fragment)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	doc, err := Parse(`{ f(a: 1, b: -2.5e1, c: "x\"y", d: """block "quoted" text""", e: true, g: null, h: ENUM, i: [1 2], j: {k: "v"}) }`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	args := doc.Operations[0].Selections[0].(*Field).Arguments
+	checks := map[string]interface{}{
+		"a": int64(1),
+		"b": -25.0,
+		"c": `x"y`,
+		"d": `block "quoted" text`,
+		"e": true,
+		"g": nil,
+		"h": EnumValue("ENUM"),
+	}
+	for name, want := range checks {
+		if got := args[name]; got != want {
+			t.Errorf("argument %s = %#v, want %#v", name, got, want)
+		}
+	}
+	if list, ok := args["i"].([]interface{}); !ok || len(list) != 2 || list[1] != int64(2) {
+		t.Errorf("argument i = %#v", args["i"])
+	}
+	if object, ok := args["j"].(map[string]interface{}); !ok || object["k"] != "v" {
+		t.Errorf("argument j = %#v", args["j"])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{"empty document", ``, "no operations"},
+		{"only a fragment", `fragment F on Entry { id }`, "no operations"},
+		{"unclosed selection set", `{ entries { id }`, "unexpected end of document"},
+		{"empty selection set", `{ }`, "selection set is empty"},
+		{"unterminated string", `{ f(a: "abc) }`, "unterminated string"},
+		{"unterminated block string", `{ f(a: """abc) }`, "unterminated block string"},
+		{"unexpected character", `{ f ? }`, "unexpected character"},
+		{"missing argument value", `{ f(a: ) }`, `unexpected ")"`},
+		{"duplicate argument", `{ f(a: 1, a: 2) }`, `argument "a" is given more than once`},
+		{"duplicate fragment", `{ ...F } fragment F on Q { a } fragment F on Q { b }`, `fragment "F" is defined more than once`},
+		{"subscription", `subscription { events }`, "subscriptions are not supported"},
+		{"variable in default", `query ($a: Int = $b) { f }`, `unexpected "$"`},
+		{"fragment without type condition", `{ ...F } fragment F { a }`, `unexpected "{"`},
+		{"fragment spreads itself", `{ ...F } fragment F on Q { a ...F }`, "spreads itself"},
+		{"fragment cycle", `{ ...A } fragment A on Q { ...B } fragment B on Q { c { ...A } }`, "spreads itself"},
+		{"cycle through inline fragment", `{ ...A } fragment A on Q { ... on Q { ...A } }`, "spreads itself"},
+		{"deeply nested selections", strings.Repeat("{a", 100000) + strings.Repeat("}", 100000), "more than 32 levels deep"},
+		{"deeply nested list", `{ f(a: ` + strings.Repeat("[", 100000) + `) }`, "more than 32 levels deep"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.source)
+			if err == nil {
+				t.Fatalf("Parse(%q) succeeded, want error containing %q", tt.source, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse(%q) error = %q, want it to contain %q", tt.source, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSharedFragmentIsNotACycle(t *testing.T) {
+	_, err := Parse(`{ a { ...F } b { ...F } } fragment F on Q { id }`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+}
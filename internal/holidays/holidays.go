@@ -0,0 +1,320 @@
+// Package holidays computes national public holidays for the calendars users
+// can pick with <holiday>, from rules rather than a data feed. Regional
+// holidays (US states, German Länder, Scottish bank holidays) are left out.
+package holidays
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Holiday is a public holiday. One that moves to a weekday when it falls on
+// a weekend appears twice: on its date and, marked, on the day it is observed.
+type Holiday struct {
+	Date time.Time
+	Name string
+}
+
+// calendars maps an ISO 3166 country code to the holidays of a year
+var calendars = map[string]func(year int) []Holiday{
+	"US": unitedStates,
+	"GB": unitedKingdom,
+	"CA": canada,
+	"AU": australia,
+	"DE": germany,
+	"FR": france,
+}
+
+// aliases are other codes users write for a supported country
+var aliases = map[string]string{
+	"UK": "GB",
+}
+
+// Normalize returns the supported country code for code, accepting any case
+// and aliases such as UK, or "" if there is no calendar for it
+func Normalize(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if alias, ok := aliases[code]; ok {
+		code = alias
+	}
+	if _, ok := calendars[code]; !ok {
+		return ""
+	}
+	return code
+}
+
+// Supported returns the country codes with a calendar, sorted
+func Supported() []string {
+	codes := make([]string, 0, len(calendars))
+	for code := range calendars {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// In returns country's holidays in year, by date. An unsupported country has
+// none.
+func In(country string, year int) []Holiday {
+	calendar, ok := calendars[Normalize(country)]
+	if !ok {
+		return nil
+	}
+	holidays := calendar(year)
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date.Before(holidays[j].Date) })
+	return holidays
+}
+
+// On returns the name of country's holiday on day's calendar date. The next
+// year is checked too, since a New Year's Day on a Saturday can be observed
+// on December 31.
+func On(country string, day time.Time) (string, bool) {
+	for _, holiday := range append(In(country, day.Year()), In(country, day.Year()+1)...) {
+		if holiday.Date.Year() != day.Year() {
+			continue
+		}
+		if holiday.Date.Month() == day.Month() && holiday.Date.Day() == day.Day() {
+			return holiday.Name, true
+		}
+	}
+	return "", false
+}
+
+// Next returns the next occurrence, on or after from's calendar date, of
+// the holiday called name in country's calendar or, when country is "",
+// whichever calendar has it soonest. Names match loosely, so "christmas"
+// finds Christmas Day and "new years" New Year's Day. end is the last day
+// off for it: the observed or substitute day when the holiday is moved.
+func Next(country, name string, from time.Time) (start, end time.Time, ok bool) {
+	countries := []string{Normalize(country)}
+	if country == "" {
+		countries = Supported()
+	}
+	from = date(from.Year(), from.Month(), from.Day())
+	key := nameKey(name)
+
+	for _, code := range countries {
+		for year := from.Year(); year <= from.Year()+1; year++ {
+			s, e, found := next(code, key, year, from)
+			if found && (!ok || s.Before(start)) {
+				start, end, ok = s, e, true
+			}
+			if found {
+				break
+			}
+		}
+	}
+	return start, end, ok
+}
+
+// next finds the first holiday keyed key in country's year that isn't
+// before from, along with the day it is observed on
+func next(country, key string, year int, from time.Time) (start, end time.Time, ok bool) {
+	for _, holiday := range In(country, year) {
+		name := strings.TrimSuffix(strings.TrimSuffix(holiday.Name, " (observed)"), " (substitute day)")
+		if nameKey(name) != key || holiday.Date.Before(from) {
+			continue
+		}
+		if ok && holiday.Date.Sub(end) > 7*24*time.Hour {
+			break
+		}
+		if !ok {
+			start, ok = holiday.Date, true
+		}
+		end = holiday.Date
+	}
+	return start, end, ok
+}
+
+// nameKey is how holiday names are compared: lower case, spelled the US
+// way, without apostrophes, a leading "the" or a trailing "day"
+func nameKey(name string) string {
+	name = strings.NewReplacer("'", "", "’", "", ".", "", "labour", "labor").Replace(strings.ToLower(name))
+	name = strings.Join(strings.Fields(name), " ")
+	name = strings.TrimPrefix(name, "the ")
+	return strings.TrimSuffix(name, " day")
+}
+
+func unitedStates(year int) []Holiday {
+	holidays := []Holiday{
+		{nthWeekday(year, time.January, time.Monday, 3), "Martin Luther King Jr. Day"},
+		{nthWeekday(year, time.February, time.Monday, 3), "Presidents' Day"},
+		{lastWeekday(year, time.May, time.Monday), "Memorial Day"},
+		{nthWeekday(year, time.September, time.Monday, 1), "Labor Day"},
+		{nthWeekday(year, time.October, time.Monday, 2), "Columbus Day"},
+		{nthWeekday(year, time.November, time.Thursday, 4), "Thanksgiving"},
+	}
+
+	// Fixed-date federal holidays on a Saturday are observed the Friday
+	// before, on a Sunday the Monday after
+	for _, fixed := range []Holiday{
+		{date(year, time.January, 1), "New Year's Day"},
+		{date(year, time.June, 19), "Juneteenth"},
+		{date(year, time.July, 4), "Independence Day"},
+		{date(year, time.November, 11), "Veterans Day"},
+		{date(year, time.December, 25), "Christmas Day"},
+	} {
+		holidays = append(holidays, fixed)
+		switch fixed.Date.Weekday() {
+		case time.Saturday:
+			holidays = append(holidays, Holiday{fixed.Date.AddDate(0, 0, -1), fixed.Name + " (observed)"})
+		case time.Sunday:
+			holidays = append(holidays, Holiday{fixed.Date.AddDate(0, 0, 1), fixed.Name + " (observed)"})
+		}
+	}
+	return holidays
+}
+
+// unitedKingdom is the England and Wales bank holidays
+func unitedKingdom(year int) []Holiday {
+	easter := easterSunday(year)
+	return substituteWeekends([]Holiday{
+		{date(year, time.January, 1), "New Year's Day"},
+		{easter.AddDate(0, 0, -2), "Good Friday"},
+		{easter.AddDate(0, 0, 1), "Easter Monday"},
+		{nthWeekday(year, time.May, time.Monday, 1), "Early May Bank Holiday"},
+		{lastWeekday(year, time.May, time.Monday), "Spring Bank Holiday"},
+		{lastWeekday(year, time.August, time.Monday), "Summer Bank Holiday"},
+		{date(year, time.December, 25), "Christmas Day"},
+		{date(year, time.December, 26), "Boxing Day"},
+	})
+}
+
+// canada is the federal statutory holidays observed nationwide
+func canada(year int) []Holiday {
+	// Victoria Day is the last Monday before May 25
+	victoria := date(year, time.May, 24)
+	for victoria.Weekday() != time.Monday {
+		victoria = victoria.AddDate(0, 0, -1)
+	}
+
+	return substituteWeekends([]Holiday{
+		{date(year, time.January, 1), "New Year's Day"},
+		{easterSunday(year).AddDate(0, 0, -2), "Good Friday"},
+		{victoria, "Victoria Day"},
+		{date(year, time.July, 1), "Canada Day"},
+		{nthWeekday(year, time.September, time.Monday, 1), "Labour Day"},
+		{nthWeekday(year, time.October, time.Monday, 2), "Thanksgiving"},
+		{date(year, time.December, 25), "Christmas Day"},
+		{date(year, time.December, 26), "Boxing Day"},
+	})
+}
+
+// australia is the national public holidays; the King's Birthday is the
+// June date most states use
+func australia(year int) []Holiday {
+	easter := easterSunday(year)
+	holidays := substituteWeekends([]Holiday{
+		{date(year, time.January, 1), "New Year's Day"},
+		{date(year, time.January, 26), "Australia Day"},
+		{easter.AddDate(0, 0, -2), "Good Friday"},
+		{easter.AddDate(0, 0, 1), "Easter Monday"},
+		{nthWeekday(year, time.June, time.Monday, 2), "King's Birthday"},
+		{date(year, time.December, 25), "Christmas Day"},
+		{date(year, time.December, 26), "Boxing Day"},
+	})
+	// Anzac Day is kept on the day, whatever the weekday
+	return append(holidays, Holiday{date(year, time.April, 25), "Anzac Day"})
+}
+
+func germany(year int) []Holiday {
+	easter := easterSunday(year)
+	return []Holiday{
+		{date(year, time.January, 1), "Neujahr"},
+		{easter.AddDate(0, 0, -2), "Karfreitag"},
+		{easter.AddDate(0, 0, 1), "Ostermontag"},
+		{date(year, time.May, 1), "Tag der Arbeit"},
+		{easter.AddDate(0, 0, 39), "Christi Himmelfahrt"},
+		{easter.AddDate(0, 0, 50), "Pfingstmontag"},
+		{date(year, time.October, 3), "Tag der Deutschen Einheit"},
+		{date(year, time.December, 25), "Erster Weihnachtstag"},
+		{date(year, time.December, 26), "Zweiter Weihnachtstag"},
+	}
+}
+
+func france(year int) []Holiday {
+	easter := easterSunday(year)
+	return []Holiday{
+		{date(year, time.January, 1), "Jour de l'an"},
+		{easter.AddDate(0, 0, 1), "Lundi de Pâques"},
+		{date(year, time.May, 1), "Fête du Travail"},
+		{date(year, time.May, 8), "Victoire 1945"},
+		{easter.AddDate(0, 0, 39), "Ascension"},
+		{easter.AddDate(0, 0, 50), "Lundi de Pentecôte"},
+		{date(year, time.July, 14), "Fête nationale"},
+		{date(year, time.August, 15), "Assomption"},
+		{date(year, time.November, 1), "Toussaint"},
+		{date(year, time.November, 11), "Armistice 1918"},
+		{date(year, time.December, 25), "Noël"},
+	}
+}
+
+// substituteWeekends adds a substitute day for each holiday on a weekend:
+// the next weekday that isn't already a holiday, so Christmas on a Saturday
+// and Boxing Day on a Sunday are also observed Monday and Tuesday. holidays
+// must be in date order.
+func substituteWeekends(holidays []Holiday) []Holiday {
+	taken := map[time.Time]bool{}
+	for _, holiday := range holidays {
+		taken[holiday.Date] = true
+	}
+
+	result := make([]Holiday, 0, len(holidays))
+	for _, holiday := range holidays {
+		result = append(result, holiday)
+		if !isWeekend(holiday.Date) {
+			continue
+		}
+
+		day := holiday.Date
+		for isWeekend(day) || taken[day] {
+			day = day.AddDate(0, 0, 1)
+		}
+		taken[day] = true
+		result = append(result, Holiday{day, holiday.Name + " (substitute day)"})
+	}
+	return result
+}
+
+func isWeekend(day time.Time) bool {
+	return day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// nthWeekday returns the nth (1-based) weekday of month
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	first := date(year, month, 1)
+	offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+7*(n-1))
+}
+
+// lastWeekday returns the last weekday of month
+func lastWeekday(year int, month time.Month, weekday time.Weekday) time.Time {
+	last := date(year, month+1, 0)
+	offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+	return last.AddDate(0, 0, -offset)
+}
+
+// easterSunday is the Gregorian Easter date (the anonymous Gregorian
+// algorithm)
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return date(year, time.Month(month), day)
+}
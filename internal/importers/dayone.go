@@ -0,0 +1,108 @@
+package importers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dayOneExport is the part of a Day One JSON export we read. Exports are a
+// zip with one JSON file per journal; the file or the zip can be imported.
+type dayOneExport struct {
+	Entries []struct {
+		CreationDate time.Time `json:"creationDate"`
+		TimeZone     string    `json:"timeZone"`
+		Text         string    `json:"text"`
+	} `json:"entries"`
+}
+
+var (
+	// dayOneMoment is an embedded photo, audio or video reference, which has
+	// no meaning outside Day One
+	dayOneMoment = regexp.MustCompile(`!\[[^\]]*\]\(dayone-moment:[^)]*\)`)
+	// dayOneEscape is Day One's backslash-escaping of markdown punctuation
+	dayOneEscape = regexp.MustCompile(`\\([\\.!\-*_#()\[\]+>` + "`" + `])`)
+)
+
+// DayOne reads a Day One JSON export: a journal's .json file, or the export
+// .zip, whose journals are all read. Each entry is dated in the timezone it
+// was written in.
+func DayOne(path string) (*Result, error) {
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		return dayOneZip(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := parseDayOne(f, filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Entries: merge(entries)}, nil
+}
+
+func dayOneZip(path string) (*Result, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Day One export: %w", err)
+	}
+	defer archive.Close()
+
+	var entries []Entry
+	journals := 0
+	for _, file := range archive.File {
+		if !strings.EqualFold(filepath.Ext(file.Name), ".json") {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		parsed, err := parseDayOne(r, file.Name)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, parsed...)
+		journals++
+	}
+	if journals == 0 {
+		return nil, fmt.Errorf("no journal JSON files in %s", path)
+	}
+	return &Result{Entries: merge(entries)}, nil
+}
+
+func parseDayOne(r io.Reader, source string) ([]Entry, error) {
+	var export dayOneExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("failed to parse Day One export %s: %w", source, err)
+	}
+
+	sort.SliceStable(export.Entries, func(i, j int) bool {
+		return export.Entries[i].CreationDate.Before(export.Entries[j].CreationDate)
+	})
+
+	entries := make([]Entry, 0, len(export.Entries))
+	for _, e := range export.Entries {
+		created := e.CreationDate
+		if loc, err := time.LoadLocation(e.TimeZone); err == nil && e.TimeZone != "" {
+			created = created.In(loc)
+		}
+
+		text := dayOneMoment.ReplaceAllString(e.Text, "")
+		text = dayOneEscape.ReplaceAllString(text, "$1")
+		entries = append(entries, Entry{Date: day(created), Content: text, Source: source})
+	}
+	return entries, nil
+}
@@ -0,0 +1,86 @@
+// Package importers reads journal entries written elsewhere: Day One JSON
+// exports, Obsidian daily-note vaults and folders of dated markdown files.
+// Each importer returns at most one entry per day, in date order; notes from
+// the same day are joined with a blank line.
+package importers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is one day's imported journal text. Date is midnight UTC of the
+// calendar day, like entries.entry_date.
+type Entry struct {
+	Date    time.Time
+	Content string
+	Source  string // file the entry came from, for reporting
+}
+
+// Result is what an importer read. Skipped lists files that looked like
+// notes but had no date to file them under.
+type Result struct {
+	Entries []Entry
+	Skipped []string
+}
+
+// Importer reads entries from path, a file or folder depending on the format
+type Importer func(path string) (*Result, error)
+
+var formats = map[string]Importer{
+	"dayone":   DayOne,
+	"obsidian": Obsidian,
+	"markdown": Markdown,
+}
+
+// Formats returns the supported format names, sorted
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Import reads path with the named format's importer
+func Import(format, path string) (*Result, error) {
+	importer, ok := formats[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unknown import format %q (expected one of %s)", format, strings.Join(Formats(), ", "))
+	}
+	return importer(path)
+}
+
+// merge joins entries from the same day, skips empty ones and sorts by date
+func merge(entries []Entry) []Entry {
+	byDay := make(map[time.Time]*Entry)
+	var days []time.Time
+	for _, entry := range entries {
+		entry.Content = strings.TrimSpace(entry.Content)
+		if entry.Content == "" {
+			continue
+		}
+		if existing, ok := byDay[entry.Date]; ok {
+			existing.Content += "\n\n" + entry.Content
+			continue
+		}
+		e := entry
+		byDay[entry.Date] = &e
+		days = append(days, entry.Date)
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	merged := make([]Entry, 0, len(days))
+	for _, day := range days {
+		merged = append(merged, *byDay[day])
+	}
+	return merged
+}
+
+// day returns t's calendar date as midnight UTC
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
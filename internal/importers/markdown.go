@@ -0,0 +1,143 @@
+package importers
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// filenameDate finds a date in a file name: 2024-05-02, 2024_05_02,
+// 2024.05.02 or 20240502
+var filenameDate = regexp.MustCompile(`(\d{4})[-_.]?(\d{2})[-_.]?(\d{2})`)
+
+// Markdown reads a folder of markdown files, or a single file, dating each
+// by a date: field in its front matter or else the date in its file name.
+// Files with neither are skipped.
+func Markdown(path string) (*Result, error) {
+	result := &Result{}
+	var entries []Entry
+
+	err := walkNotes(path, func(file, rel string, content []byte) error {
+		frontMatter, body := splitFrontMatter(string(content))
+
+		date, ok := frontMatterDate(frontMatter)
+		if !ok {
+			date, ok = dateInName(filepath.Base(file))
+		}
+		if !ok {
+			result.Skipped = append(result.Skipped, rel)
+			return nil
+		}
+
+		entries = append(entries, Entry{Date: date, Content: body, Source: rel})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Entries = merge(entries)
+	return result, nil
+}
+
+// walkNotes calls fn with every markdown file under root in lexical order,
+// skipping hidden files and folders such as .obsidian and .trash. root may be
+// a single file. rel is the file's path relative to root.
+func walkNotes(root string, fn func(file, rel string, content []byte) error) error {
+	return filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isMarkdown(file) {
+			return nil
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil || rel == "." {
+			rel = filepath.Base(file)
+		}
+		return fn(file, filepath.ToSlash(rel), content)
+	})
+}
+
+func isMarkdown(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// splitFrontMatter separates a leading YAML front matter block from the
+// note's body
+func splitFrontMatter(content string) (string, string) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	if !strings.HasPrefix(content, "---\n") && !strings.HasPrefix(content, "---\r\n") {
+		return "", content
+	}
+
+	rest := content[strings.Index(content, "\n")+1:]
+	for offset := 0; offset < len(rest); {
+		end := strings.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if end >= 0 {
+			line = rest[offset : offset+end]
+		}
+		if strings.TrimSpace(line) == "---" {
+			body := ""
+			if end >= 0 {
+				body = rest[offset+end+1:]
+			}
+			return rest[:offset], body
+		}
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+
+	// Unterminated: treat it all as body
+	return "", content
+}
+
+// frontMatterDate reads a date: field, ignoring any time after the date
+func frontMatterDate(frontMatter string) (time.Time, bool) {
+	for _, line := range strings.Split(frontMatter, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "date") {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if len(value) >= len("2006-01-02") {
+			if date, err := time.Parse("2006-01-02", value[:len("2006-01-02")]); err == nil {
+				return date, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func dateInName(name string) (time.Time, bool) {
+	match := filenameDate.FindStringSubmatch(name)
+	if match == nil {
+		return time.Time{}, false
+	}
+	date, err := time.Parse("20060102", match[1]+match[2]+match[3])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
@@ -0,0 +1,141 @@
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultDailyNoteFormat is Obsidian's daily note name when the vault
+// doesn't configure one
+const defaultDailyNoteFormat = "YYYY-MM-DD"
+
+// obsidianDailyNotes is the vault's Daily notes plugin settings
+type obsidianDailyNotes struct {
+	Folder string `json:"folder"`
+	Format string `json:"format"`
+}
+
+var (
+	obsidianEmbed    = regexp.MustCompile(`!\[\[[^\]]*\]\]`)
+	obsidianWikiLink = regexp.MustCompile(`\[\[([^\]|]*)(?:\|([^\]]*))?\]\]`)
+	obsidianComment  = regexp.MustCompile(`(?s)%%.*?%%`)
+)
+
+// Obsidian reads the daily notes of a vault, using the folder and date
+// format from its Daily notes settings (.obsidian/daily-notes.json). Other
+// notes are ignored. Links are turned into plain text and embeds and
+// comments are dropped.
+func Obsidian(vault string) (*Result, error) {
+	settings := obsidianDailyNotes{}
+	data, err := os.ReadFile(filepath.Join(vault, ".obsidian", "daily-notes.json"))
+	if err == nil {
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return nil, fmt.Errorf("failed to parse daily notes settings: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if settings.Format == "" {
+		settings.Format = defaultDailyNoteFormat
+	}
+
+	layout, err := momentLayout(settings.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	folder := filepath.Join(vault, filepath.FromSlash(strings.Trim(settings.Folder, "/")))
+	var entries []Entry
+	err = walkNotes(folder, func(file, rel string, content []byte) error {
+		// A format like YYYY/MM/YYYY-MM-DD names notes by their path
+		name := strings.TrimSuffix(rel, filepath.Ext(rel))
+		if !strings.Contains(layout, "/") {
+			name = filepath.Base(name)
+		}
+		date, err := time.Parse(layout, name)
+		if err != nil {
+			return nil
+		}
+
+		_, body := splitFrontMatter(string(content))
+		entries = append(entries, Entry{Date: day(date), Content: cleanObsidian(body), Source: rel})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Entries: merge(entries)}, nil
+}
+
+// cleanObsidian replaces [[Note|alias]] links with their text and removes
+// ![[embeds]] and %% comments %%
+func cleanObsidian(body string) string {
+	body = obsidianComment.ReplaceAllString(body, "")
+	body = obsidianEmbed.ReplaceAllString(body, "")
+	return obsidianWikiLink.ReplaceAllStringFunc(body, func(link string) string {
+		match := obsidianWikiLink.FindStringSubmatch(link)
+		if match[2] != "" {
+			return match[2]
+		}
+		return match[1]
+	})
+}
+
+// momentTokens maps Moment.js date tokens, which Obsidian uses for note
+// names, to Go layout elements. Longer tokens come first.
+var momentTokens = []struct{ moment, layout string }{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MMMM", "January"},
+	{"MMM", "Jan"},
+	{"MM", "01"},
+	{"M", "1"},
+	{"DD", "02"},
+	{"D", "2"},
+	{"dddd", "Monday"},
+	{"ddd", "Mon"},
+}
+
+// momentLayout converts a Moment.js format into a Go time layout. Text in
+// [brackets] is literal. Tokens without a Go equivalent, such as Do (1st),
+// are an error.
+func momentLayout(format string) (string, error) {
+	var layout strings.Builder
+	for i := 0; i < len(format); {
+		if format[i] == '[' {
+			end := strings.IndexByte(format[i:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated [ in daily note format %q", format)
+			}
+			layout.WriteString(format[i+1 : i+end])
+			i += end + 1
+			continue
+		}
+
+		matched := false
+		for _, token := range momentTokens {
+			if strings.HasPrefix(format[i:], token.moment) {
+				layout.WriteString(token.layout)
+				i += len(token.moment)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		if c := format[i]; (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			return "", fmt.Errorf("unsupported token %q in daily note format %q", string(c), format)
+		}
+		layout.WriteByte(format[i])
+		i++
+	}
+	return layout.String(), nil
+}
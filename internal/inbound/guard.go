@@ -0,0 +1,163 @@
+// Package inbound guards the webhook path that delivers email replies over
+// HTTP instead of through SES. A request must be signed with the shared
+// secret, may be restricted to known source addresses, and each sender is
+// limited to a number of replies per hour. Replies from either path are
+// kept in inbound_messages, so one that failed can be replayed.
+package inbound
+
+import (
+	"context"
+	"crypto/hmac"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/webhooks"
+)
+
+// Signature headers. X-Inbound-Signature is "sha256=" and the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed by INBOUND_WEBHOOK_SECRET, the
+// same scheme outbound webhooks use.
+const (
+	HeaderTimestamp = "X-Inbound-Timestamp"
+	HeaderSignature = "X-Inbound-Signature"
+)
+
+const (
+	// maxClockSkew is how old or far in the future a signed timestamp may
+	// be, which bounds how long a captured request can be replayed
+	maxClockSkew = 5 * time.Minute
+	// RateWindow is the period INBOUND_RATE_LIMIT counts replies over
+	RateWindow = time.Hour
+)
+
+// Guard authenticates and rate limits inbound webhook requests
+type Guard struct {
+	db      *database.DB
+	secret  string
+	allowed []*net.IPNet
+	limit   int
+}
+
+// NewGuard returns a guard for requests signed with secret. allowedIPs are
+// addresses or CIDR ranges; when empty any source is accepted. limit is the
+// number of replies a sender may make per RateWindow, 0 for no limit.
+func NewGuard(db *database.DB, secret string, allowedIPs []string, limit int) (*Guard, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("INBOUND_WEBHOOK_SECRET is required for the inbound webhook")
+	}
+
+	guard := &Guard{db: db, secret: secret, limit: limit}
+	for _, entry := range allowedIPs {
+		value := entry
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INBOUND_ALLOWED_IPS entry %q: %w", entry, err)
+		}
+		guard.allowed = append(guard.allowed, network)
+	}
+	return guard, nil
+}
+
+// Verify checks the request's signature headers against body. Header names
+// are matched case-insensitively.
+func (g *Guard) Verify(headers map[string]string, body []byte) error {
+	timestamp := header(headers, HeaderTimestamp)
+	signature := strings.TrimPrefix(header(headers, HeaderSignature), "sha256=")
+	if timestamp == "" || signature == "" {
+		return apperrors.New(apperrors.CodeUnauthorized, "missing %s or %s header", HeaderTimestamp, HeaderSignature)
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return apperrors.New(apperrors.CodeUnauthorized, "invalid %s header", HeaderTimestamp)
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return apperrors.New(apperrors.CodeUnauthorized, "request timestamp is outside the allowed window")
+	}
+
+	expected := webhooks.Sign(g.secret, timestamp, body)
+	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+		return apperrors.New(apperrors.CodeUnauthorized, "invalid request signature")
+	}
+	return nil
+}
+
+// CheckSource rejects requests from addresses outside the allowlist
+func (g *Guard) CheckSource(sourceIP string) error {
+	if len(g.allowed) == 0 {
+		return nil
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(sourceIP))
+	if ip != nil {
+		for _, network := range g.allowed {
+			if network.Contains(ip) {
+				return nil
+			}
+		}
+	}
+	return apperrors.New(apperrors.CodeForbidden, "source address %s is not allowed", sourceIP)
+}
+
+// Allow records a reply from sender and returns CodeRateLimited if it is
+// over the limit for the last RateWindow. Rejected replies count too, so a
+// sender that keeps retrying stays limited.
+func (g *Guard) Allow(ctx context.Context, sender string) error {
+	if g.limit <= 0 {
+		return nil
+	}
+	sender = strings.ToLower(strings.TrimSpace(sender))
+	windowStart := time.Now().UTC().Add(-RateWindow)
+
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM inbound_requests WHERE sender = $1 AND received_at < $2`, sender, windowStart); err != nil {
+		return fmt.Errorf("failed to prune inbound requests: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO inbound_requests (sender, received_at) VALUES ($1, $2)`, sender, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to record inbound request: %w", err)
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM inbound_requests WHERE sender = $1`, sender).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count inbound requests: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to record inbound request: %w", err)
+	}
+
+	if count > g.limit {
+		return apperrors.New(apperrors.CodeRateLimited, "%s sent more than %d replies in the last hour", sender, g.limit)
+	}
+	return nil
+}
+
+// header looks up name in headers regardless of case, as API Gateway passes
+// them through as the client sent them
+func header(headers map[string]string, name string) string {
+	if value, ok := headers[name]; ok {
+		return value
+	}
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
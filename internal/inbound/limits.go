@@ -0,0 +1,148 @@
+package inbound
+
+import (
+	stderrors "errors"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+)
+
+// What happens to a reply body over Limits.MaxBodyBytes
+const (
+	OversizeTrim   = "trim"
+	OversizeReject = "reject"
+)
+
+// Limits bound the inbound mail that is stored and processed, so one huge
+// message can't exhaust the parser's memory or the database's row size
+type Limits struct {
+	// MaxBytes is the largest raw request or message accepted. Larger ones
+	// are refused before they are parsed or stored; 0 for no limit.
+	MaxBytes int
+	// MaxBodyBytes is the longest reply body processed. A longer body is
+	// cut to it, or the reply rejected, by OversizePolicy; 0 for no limit.
+	MaxBodyBytes   int
+	OversizePolicy string
+}
+
+// Ignored is the error for mail to the reply address that isn't a reply,
+// such as a calendar invite or a delivery receipt. It is recorded as
+// ignored, and nothing is saved or sent back.
+type Ignored struct {
+	Reason string
+}
+
+func (e *Ignored) Error() string {
+	return "ignored " + e.Reason
+}
+
+// IsIgnored reports whether err is an Ignored
+func IsIgnored(err error) bool {
+	var ignored *Ignored
+	return stderrors.As(err, &ignored)
+}
+
+// CheckSize refuses a raw request or message of size bytes over MaxBytes
+func (l Limits) CheckSize(size int) error {
+	if l.MaxBytes > 0 && size > l.MaxBytes {
+		return apperrors.New(apperrors.CodeTooLarge, "message is %d bytes, over the %d byte limit", size, l.MaxBytes)
+	}
+	return nil
+}
+
+// replyTypes are the content types a reply may have. An empty type is
+// taken as plain text.
+var replyTypes = map[string]bool{
+	"":                      true,
+	"text/plain":            true,
+	"text/html":             true,
+	"multipart/alternative": true,
+	"multipart/mixed":       true,
+	"multipart/related":     true,
+}
+
+// receiptSubjects start the subjects of read receipts and bounces from
+// clients that don't send them as multipart/report
+var receiptSubjects = []string{
+	"read:", "not read:", "delivered:", "undeliverable:", "undelivered mail",
+	"delivery status notification", "returned mail:", "mail delivery failed",
+}
+
+// Screen checks reply before it is handled. Calendar invites and delivery
+// receipts are Ignored, other content types than text, HTML and their
+// multipart wrappers are refused, and a body over MaxBodyBytes is trimmed in
+// place or refused.
+func (l Limits) Screen(reply *Reply) error {
+	mediaType := ""
+	var params map[string]string
+	if reply.ContentType != "" {
+		var err error
+		if mediaType, params, err = mime.ParseMediaType(reply.ContentType); err != nil {
+			return apperrors.Wrap(apperrors.CodeUnsupportedType, err, "invalid content type %q", reply.ContentType)
+		}
+	}
+
+	switch {
+	case isCalendar(mediaType, reply.Body):
+		return &Ignored{Reason: "calendar invite"}
+	case isReceipt(mediaType, params, reply):
+		return &Ignored{Reason: "delivery receipt"}
+	case !replyTypes[mediaType]:
+		return apperrors.New(apperrors.CodeUnsupportedType, "unsupported content type %s", mediaType)
+	}
+
+	if l.MaxBodyBytes > 0 && len(reply.Body) > l.MaxBodyBytes {
+		if l.OversizePolicy == OversizeReject {
+			return apperrors.New(apperrors.CodeTooLarge, "reply body is %d bytes, over the %d byte limit", len(reply.Body), l.MaxBodyBytes)
+		}
+		reply.Body = truncate(reply.Body, l.MaxBodyBytes)
+	}
+	return nil
+}
+
+// isCalendar reports whether a message is an invitation or an RSVP to one.
+// Calendar clients send these as text/calendar, or with an iCalendar part
+// whose contents reach the body.
+func isCalendar(mediaType, body string) bool {
+	return mediaType == "text/calendar" || mediaType == "application/ics" ||
+		strings.Contains(body, "BEGIN:VCALENDAR")
+}
+
+// isReceipt reports whether a message is a bounce or read receipt: a
+// multipart/report, mail from the mailer daemon, or a receipt subject
+func isReceipt(mediaType string, params map[string]string, reply *Reply) bool {
+	switch mediaType {
+	case "multipart/report", "message/delivery-status", "message/disposition-notification":
+		return true
+	}
+	if params["report-type"] != "" {
+		return true
+	}
+
+	from := strings.ToLower(reply.From)
+	if i := strings.LastIndex(from, "<"); i >= 0 {
+		from = from[i+1:]
+	}
+	local, _, _ := strings.Cut(from, "@")
+	if local == "mailer-daemon" || local == "postmaster" {
+		return true
+	}
+
+	subject := strings.ToLower(strings.TrimSpace(reply.Subject))
+	for _, prefix := range receiptSubjects {
+		if strings.HasPrefix(subject, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate cuts s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,75 @@
+package inbound
+
+import (
+	"strings"
+	"testing"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+)
+
+func TestScreen(t *testing.T) {
+	limits := Limits{MaxBodyBytes: 64, OversizePolicy: OversizeTrim}
+
+	tests := []struct {
+		name    string
+		reply   Reply
+		ignored bool
+		code    apperrors.Code
+	}{
+		{"plain reply", Reply{From: "a@example.com", Body: "Shipped the importer"}, false, ""},
+		{"html reply", Reply{From: "a@example.com", ContentType: "text/html; charset=utf-8", Body: "<p>Shipped</p>"}, false, ""},
+		{"calendar invite", Reply{From: "a@example.com", ContentType: "text/calendar; method=REQUEST"}, true, ""},
+		{"invite in the body", Reply{From: "a@example.com", Body: "BEGIN:VCALENDAR\nMETHOD:REPLY"}, true, ""},
+		{"bounce", Reply{From: "MAILER-DAEMON@example.com", Subject: "Failure notice"}, true, ""},
+		{"delivery report", Reply{From: "a@example.com", ContentType: "multipart/report; report-type=delivery-status"}, true, ""},
+		{"read receipt", Reply{From: "Ann <a@example.com>", Subject: "Read: What did you get done today?"}, true, ""},
+		{"attachment only", Reply{From: "a@example.com", ContentType: "application/pdf"}, false, apperrors.CodeUnsupportedType},
+		{"bad content type", Reply{From: "a@example.com", ContentType: "text/"}, false, apperrors.CodeUnsupportedType},
+	}
+	for _, tt := range tests {
+		reply := tt.reply
+		err := limits.Screen(&reply)
+		if IsIgnored(err) != tt.ignored {
+			t.Errorf("%s: Screen() = %v, ignored want %v", tt.name, err, tt.ignored)
+			continue
+		}
+		if !tt.ignored && tt.code == "" && err != nil {
+			t.Errorf("%s: Screen() = %v, want nil", tt.name, err)
+		}
+		if tt.code != "" && !apperrors.Is(err, tt.code) {
+			t.Errorf("%s: Screen() = %v, want code %s", tt.name, err, tt.code)
+		}
+	}
+}
+
+func TestScreenOversizedBody(t *testing.T) {
+	// "é" is two bytes, so the limit falls inside a character
+	body := strings.Repeat("é", 10)
+
+	reply := Reply{From: "a@example.com", Body: body}
+	if err := (Limits{MaxBodyBytes: 5, OversizePolicy: OversizeTrim}).Screen(&reply); err != nil {
+		t.Fatalf("trim: Screen() = %v", err)
+	}
+	if reply.Body != "éé" {
+		t.Errorf("trim: body = %q, want %q", reply.Body, "éé")
+	}
+
+	reply = Reply{From: "a@example.com", Body: body}
+	err := (Limits{MaxBodyBytes: 5, OversizePolicy: OversizeReject}).Screen(&reply)
+	if !apperrors.Is(err, apperrors.CodeTooLarge) {
+		t.Errorf("reject: Screen() = %v, want %s", err, apperrors.CodeTooLarge)
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	limits := Limits{MaxBytes: 100}
+	if err := limits.CheckSize(100); err != nil {
+		t.Errorf("CheckSize(100) = %v, want nil", err)
+	}
+	if err := limits.CheckSize(101); !apperrors.Is(err, apperrors.CodeTooLarge) {
+		t.Errorf("CheckSize(101) = %v, want %s", err, apperrors.CodeTooLarge)
+	}
+	if err := (Limits{}).CheckSize(1 << 30); err != nil {
+		t.Errorf("no limit: CheckSize() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,225 @@
+package inbound
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Reply is an inbound reply as the parser hands it on. The webhook posts it
+// as JSON; for SES it is built from the receipt event. ContentType is the
+// message's Content-Type header, if known.
+type Reply struct {
+	From        string   `json:"from"`
+	To          []string `json:"to"`
+	Subject     string   `json:"subject"`
+	Body        string   `json:"body"`
+	ContentType string   `json:"content_type,omitempty"`
+}
+
+// Handler processes replies; core.Service is one
+type Handler interface {
+	ResolveReplySender(ctx context.Context, recipients []string, sender string) (string, bool, error)
+	HandleEmailReply(ctx context.Context, senderEmail string, forwarded bool, subject, body string) error
+}
+
+// Handle screens reply against limits, resolves who sent it and has h
+// process it, returning the resolved sender. With a guard the sender is rate
+// limited first. Mail that isn't a reply returns an Ignored error.
+func Handle(ctx context.Context, h Handler, guard *Guard, limits Limits, reply *Reply) (string, error) {
+	if reply.From == "" {
+		return "", apperrors.New(apperrors.CodeInvalidInput, "no sender email found")
+	}
+
+	size := len(reply.Body)
+	if err := limits.Screen(reply); err != nil {
+		return "", err
+	}
+	if len(reply.Body) < size {
+		logrus.WithFields(logrus.Fields{"bytes": size, "limit": limits.MaxBodyBytes}).Warn("Trimmed oversized reply body")
+	}
+
+	sender, forwarded, err := h.ResolveReplySender(ctx, reply.To, reply.From)
+	if err != nil {
+		return "", err
+	}
+	if guard != nil {
+		if err := guard.Allow(ctx, sender); err != nil {
+			return sender, err
+		}
+	}
+	return sender, h.HandleEmailReply(ctx, sender, forwarded, reply.Subject, reply.Body)
+}
+
+// Store keeps each inbound message with whether it was processed, so ones
+// that failed can be replayed without the user sending them again
+type Store struct {
+	db *database.DB
+}
+
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// Record saves msg as pending and sets its ID
+func (s *Store) Record(ctx context.Context, msg *models.InboundMessage) error {
+	query := `
+		INSERT INTO inbound_messages (source, message_id, s3_bucket, s3_key, payload)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, received_at`
+
+	err := s.db.QueryRowContext(ctx, query, msg.Source, msg.MessageID, msg.S3Bucket, msg.S3Key, msg.Payload).
+		Scan(&msg.ID, &msg.Status, &msg.ReceivedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record inbound message: %w", err)
+	}
+	return nil
+}
+
+// Finish records the outcome of processing message id: processed when
+// procErr is nil, ignored when it is an Ignored, else failed with its
+// message. sender is who it was resolved to, if that far was reached. The
+// payload of a reply from a user in self-custody is redacted, so their
+// entries aren't kept in plaintext here; it can't be replayed.
+func (s *Store) Finish(ctx context.Context, id int64, sender string, procErr error) error {
+	status := models.InboundStatusProcessed
+	var errorMessage *string
+	if procErr != nil {
+		status = models.InboundStatusFailed
+		if IsIgnored(procErr) {
+			status = models.InboundStatusIgnored
+		}
+		message := procErr.Error()
+		errorMessage = &message
+	}
+
+	query := `
+		UPDATE inbound_messages
+		SET status = $2, error_message = $3, sender = COALESCE(NULLIF($4, ''), sender),
+		    attempts = attempts + 1,
+		    processed_at = CASE WHEN $2 = 'processed' THEN NOW() ELSE processed_at END,
+		    payload = CASE WHEN EXISTS (
+		        SELECT 1 FROM users u
+		        WHERE u.self_custody_at IS NOT NULL
+		          AND (LOWER(u.email) = LOWER($4)
+		               OR u.id IN (SELECT user_id FROM user_emails WHERE LOWER(email) = LOWER($4)))
+		    ) THEN '' ELSE payload END
+		WHERE id = $1`
+
+	if _, err := s.db.ExecContext(ctx, query, id, status, errorMessage, sender); err != nil {
+		return fmt.Errorf("failed to update inbound message: %w", err)
+	}
+	return nil
+}
+
+const messageColumns = `id, source, message_id, s3_bucket, s3_key, payload, sender, status, attempts,
+	error_message, received_at, processed_at`
+
+// Get returns message id
+func (s *Store) Get(ctx context.Context, id int64) (*models.InboundMessage, error) {
+	query := `SELECT ` + messageColumns + ` FROM inbound_messages WHERE id = $1`
+
+	msg, err := scanMessage(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeNotFound, "inbound message not found: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbound message: %w", err)
+	}
+	return msg, nil
+}
+
+// List returns the latest limit messages, only those with status unless it
+// is empty
+func (s *Store) List(ctx context.Context, status string, limit int) ([]*models.InboundMessage, error) {
+	query := `SELECT ` + messageColumns + `
+		FROM inbound_messages
+		WHERE $1 = '' OR status = $1
+		ORDER BY received_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inbound messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*models.InboundMessage
+	for rows.Next() {
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan inbound message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// Replay processes message id again with h under limits and records the
+// outcome. The sender's rate limit doesn't apply. A message that was
+// already processed isn't replayed, since its entry was saved.
+func (s *Store) Replay(ctx context.Context, id int64, h Handler, limits Limits) error {
+	msg, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if msg.Status == models.InboundStatusProcessed {
+		return apperrors.New(apperrors.CodeConflict, "inbound message %d was already processed", id)
+	}
+	if msg.Payload == "" {
+		return apperrors.New(apperrors.CodeConflict, "inbound message %d was redacted for self-custody", id)
+	}
+
+	var reply Reply
+	var sender string
+	if err = json.Unmarshal([]byte(msg.Payload), &reply); err != nil {
+		err = apperrors.Wrap(apperrors.CodeInvalidInput, err, "invalid inbound payload")
+	} else {
+		sender, err = Handle(ctx, h, nil, limits, &reply)
+	}
+
+	if finishErr := s.Finish(ctx, id, sender, err); finishErr != nil {
+		return finishErr
+	}
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMessage(row scanner) (*models.InboundMessage, error) {
+	var msg models.InboundMessage
+	var messageID, s3Bucket, s3Key, sender, errorMessage sql.NullString
+	var processedAt sql.NullTime
+
+	err := row.Scan(&msg.ID, &msg.Source, &messageID, &s3Bucket, &s3Key, &msg.Payload, &sender,
+		&msg.Status, &msg.Attempts, &errorMessage, &msg.ReceivedAt, &processedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.MessageID = nullString(messageID)
+	msg.S3Bucket = nullString(s3Bucket)
+	msg.S3Key = nullString(s3Key)
+	msg.Sender = nullString(sender)
+	msg.ErrorMessage = nullString(errorMessage)
+	if processedAt.Valid {
+		msg.ProcessedAt = &processedAt.Time
+	}
+	return &msg, nil
+}
+
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
@@ -0,0 +1,180 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ses"
+	"github.com/aws/aws-sdk-go-v2/service/ses/types"
+)
+
+// DeliverabilityReport is how well the sending domain authenticates outbound
+// mail. DMARC passes when either DKIM or SPF aligns with the From domain;
+// SES signs with the domain's DKIM keys, but SPF only aligns when a custom
+// MAIL FROM domain is set. Problems is empty when everything checks out.
+type DeliverabilityReport struct {
+	Domain string
+
+	DKIMEnabled bool
+	DKIMStatus  string
+	DKIMRecords []DNSRecord
+
+	MailFromDomain string
+	SPFRecord      string
+	SPFAligned     bool
+
+	DMARCRecord string
+	DMARCPolicy string
+
+	Problems []string
+}
+
+// DNSRecord is a record SES expects to be published, and whether it is
+type DNSRecord struct {
+	Type      string
+	Name      string
+	Value     string
+	Published bool
+}
+
+// Aligned reports whether no problems were found
+func (r *DeliverabilityReport) Aligned() bool {
+	return len(r.Problems) == 0
+}
+
+// SendingDomain returns the domain of from, an address with or without a
+// display name
+func SendingDomain(from string) (string, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return "", fmt.Errorf("invalid sender address %q: %w", from, err)
+	}
+	at := strings.LastIndexByte(addr.Address, '@')
+	return strings.ToLower(addr.Address[at+1:]), nil
+}
+
+// CheckDeliverability compares domain's SES DKIM and MAIL FROM settings with
+// what is published in DNS. Failed DNS lookups are reported as problems, not
+// errors; only SES API failures are returned.
+func (s *SESSetup) CheckDeliverability(ctx context.Context, domain string) (*DeliverabilityReport, error) {
+	report := &DeliverabilityReport{Domain: domain}
+
+	dkim, err := s.client.GetIdentityDkimAttributes(ctx, &ses.GetIdentityDkimAttributesInput{
+		Identities: []string{domain},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DKIM attributes: %w", err)
+	}
+
+	if attr, ok := dkim.DkimAttributes[domain]; ok {
+		report.DKIMEnabled = attr.DkimEnabled
+		report.DKIMStatus = string(attr.DkimVerificationStatus)
+		for _, token := range attr.DkimTokens {
+			report.DKIMRecords = append(report.DKIMRecords, DNSRecord{
+				Type:  "CNAME",
+				Name:  token + "._domainkey." + domain,
+				Value: token + ".dkim.amazonses.com",
+			})
+		}
+	}
+
+	mailFrom, err := s.client.GetIdentityMailFromDomainAttributes(ctx, &ses.GetIdentityMailFromDomainAttributesInput{
+		Identities: []string{domain},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MAIL FROM attributes: %w", err)
+	}
+
+	if attr, ok := mailFrom.MailFromDomainAttributes[domain]; ok && attr.MailFromDomainStatus == types.CustomMailFromStatusSuccess {
+		report.MailFromDomain = strings.ToLower(aws.ToString(attr.MailFromDomain))
+	}
+
+	checkDKIM(ctx, report)
+	checkSPF(ctx, report)
+	checkDMARC(ctx, report)
+	return report, nil
+}
+
+func checkDKIM(ctx context.Context, report *DeliverabilityReport) {
+	if !report.DKIMEnabled {
+		report.Problems = append(report.Problems, "DKIM signing is not enabled in SES (run infra setup-ses)")
+	}
+	if report.DKIMStatus != string(types.VerificationStatusSuccess) {
+		status := report.DKIMStatus
+		if status == "" {
+			status = "not started"
+		}
+		report.Problems = append(report.Problems, "DKIM verification is "+status)
+	}
+
+	for i, record := range report.DKIMRecords {
+		target, err := net.DefaultResolver.LookupCNAME(ctx, record.Name)
+		if err == nil && strings.EqualFold(strings.TrimSuffix(target, "."), record.Value) {
+			report.DKIMRecords[i].Published = true
+			continue
+		}
+		report.Problems = append(report.Problems, fmt.Sprintf("DKIM record %s is not published as CNAME %s", record.Name, record.Value))
+	}
+}
+
+// checkSPF looks for an SPF record authorizing SES on the custom MAIL FROM
+// domain. Without one, mail is sent from amazonses.com and SPF can't align.
+func checkSPF(ctx context.Context, report *DeliverabilityReport) {
+	if report.MailFromDomain == "" {
+		report.Problems = append(report.Problems, "no custom MAIL FROM domain, so SPF does not align and DMARC relies on DKIM alone")
+		return
+	}
+
+	report.SPFRecord = lookupTXTPrefix(ctx, report.MailFromDomain, "v=spf1")
+	if report.SPFRecord == "" {
+		report.Problems = append(report.Problems, "no SPF record on MAIL FROM domain "+report.MailFromDomain)
+		return
+	}
+	if !strings.Contains(report.SPFRecord, "include:amazonses.com") {
+		report.Problems = append(report.Problems, "SPF record on "+report.MailFromDomain+" does not include amazonses.com")
+		return
+	}
+
+	// Relaxed alignment: the MAIL FROM domain must be the From domain or a subdomain of it
+	report.SPFAligned = report.MailFromDomain == report.Domain || strings.HasSuffix(report.MailFromDomain, "."+report.Domain)
+	if !report.SPFAligned {
+		report.Problems = append(report.Problems, "MAIL FROM domain "+report.MailFromDomain+" is not within "+report.Domain)
+	}
+}
+
+func checkDMARC(ctx context.Context, report *DeliverabilityReport) {
+	report.DMARCRecord = lookupTXTPrefix(ctx, "_dmarc."+report.Domain, "v=DMARC1")
+	if report.DMARCRecord == "" {
+		report.Problems = append(report.Problems, "no DMARC record at _dmarc."+report.Domain)
+		return
+	}
+
+	for _, tag := range strings.Split(report.DMARCRecord, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(tag), "=")
+		if ok && strings.TrimSpace(key) == "p" {
+			report.DMARCPolicy = strings.TrimSpace(value)
+		}
+	}
+	if report.DMARCPolicy == "" {
+		report.Problems = append(report.Problems, "DMARC record has no policy (p=)")
+	}
+}
+
+// lookupTXTPrefix returns name's TXT record starting with prefix, or "" when
+// there is none or the lookup fails
+func lookupTXTPrefix(ctx context.Context, name, prefix string) string {
+	records, err := net.DefaultResolver.LookupTXT(ctx, name)
+	if err != nil {
+		return ""
+	}
+	for _, record := range records {
+		if strings.HasPrefix(strings.ToLower(record), strings.ToLower(prefix)) {
+			return record
+		}
+	}
+	return ""
+}
@@ -0,0 +1,288 @@
+// Package infra automates the AWS setup that inbound email depends on, so new
+// deployments don't need manual console configuration.
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ses"
+	"github.com/aws/aws-sdk-go-v2/service/ses/types"
+	"github.com/sirupsen/logrus"
+)
+
+// SESSetupOptions describes the desired inbound email configuration. Defaults
+// match the names used in terraform/main.tf.
+type SESSetupOptions struct {
+	Domain       string
+	RuleSetName  string
+	RuleName     string
+	Recipients   []string
+	Bucket       string
+	ObjectPrefix string
+	LambdaARN    string
+	Activate     bool
+	DryRun       bool
+}
+
+// SESSetupResult reports what was changed and the DNS records still needed
+type SESSetupResult struct {
+	Actions            []string
+	VerificationStatus string
+	VerificationToken  string
+	DKIMTokens         []string
+}
+
+// SESSetup applies SESSetupOptions idempotently: existing resources are
+// updated in place and re-running with the same options changes nothing
+type SESSetup struct {
+	client *ses.Client
+}
+
+func NewSESSetup(ctx context.Context, region string) (*SESSetup, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &SESSetup{client: ses.NewFromConfig(awsCfg)}, nil
+}
+
+func (o *SESSetupOptions) applyDefaults() error {
+	if o.Domain == "" {
+		return fmt.Errorf("domain is required")
+	}
+	if o.Bucket == "" {
+		return fmt.Errorf("S3 bucket is required for the receipt rule")
+	}
+	if !strings.HasPrefix(o.LambdaARN, "arn:") {
+		return fmt.Errorf("lambda function ARN is required, got %q", o.LambdaARN)
+	}
+
+	if o.RuleSetName == "" {
+		o.RuleSetName = o.Domain + "-ruleset"
+	}
+	if o.RuleName == "" {
+		o.RuleName = "inbound-email-rule"
+	}
+	if len(o.Recipients) == 0 {
+		o.Recipients = []string{o.Domain}
+	}
+	return nil
+}
+
+// Run verifies the domain identity and creates or updates the receipt rule
+// set and rule. With DryRun only read calls are made and Actions lists what
+// would change.
+func (s *SESSetup) Run(ctx context.Context, opts SESSetupOptions) (*SESSetupResult, error) {
+	if err := opts.applyDefaults(); err != nil {
+		return nil, err
+	}
+
+	result := &SESSetupResult{}
+	logger := logrus.WithFields(logrus.Fields{
+		"domain":   opts.Domain,
+		"rule_set": opts.RuleSetName,
+		"rule":     opts.RuleName,
+		"dry_run":  opts.DryRun,
+	})
+
+	if err := s.verifyDomain(ctx, opts, result); err != nil {
+		return nil, err
+	}
+	if err := s.ensureRuleSet(ctx, opts, result); err != nil {
+		return nil, err
+	}
+	if err := s.ensureRule(ctx, opts, result); err != nil {
+		return nil, err
+	}
+	if opts.Activate {
+		if err := s.ensureActive(ctx, opts, result); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.WithField("actions", len(result.Actions)).Info("SES setup completed")
+	return result, nil
+}
+
+func (s *SESSetup) record(result *SESSetupResult, dryRun bool, action string) {
+	if dryRun {
+		action = "would " + action
+	}
+	result.Actions = append(result.Actions, action)
+}
+
+func (s *SESSetup) verifyDomain(ctx context.Context, opts SESSetupOptions, result *SESSetupResult) error {
+	attrs, err := s.client.GetIdentityVerificationAttributes(ctx, &ses.GetIdentityVerificationAttributesInput{
+		Identities: []string{opts.Domain},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get domain verification status: %w", err)
+	}
+
+	if attr, ok := attrs.VerificationAttributes[opts.Domain]; ok {
+		result.VerificationStatus = string(attr.VerificationStatus)
+		result.VerificationToken = aws.ToString(attr.VerificationToken)
+	}
+
+	if result.VerificationStatus == string(types.VerificationStatusSuccess) {
+		return nil
+	}
+	if opts.DryRun {
+		s.record(result, true, "request domain verification and DKIM for "+opts.Domain)
+		return nil
+	}
+
+	// Re-requesting verification is idempotent and returns the DNS tokens to publish
+	identity, err := s.client.VerifyDomainIdentity(ctx, &ses.VerifyDomainIdentityInput{Domain: aws.String(opts.Domain)})
+	if err != nil {
+		return fmt.Errorf("failed to verify domain identity: %w", err)
+	}
+	result.VerificationToken = aws.ToString(identity.VerificationToken)
+
+	dkim, err := s.client.VerifyDomainDkim(ctx, &ses.VerifyDomainDkimInput{Domain: aws.String(opts.Domain)})
+	if err != nil {
+		return fmt.Errorf("failed to enable DKIM: %w", err)
+	}
+	result.DKIMTokens = dkim.DkimTokens
+
+	if result.VerificationStatus == "" {
+		result.VerificationStatus = string(types.VerificationStatusPending)
+	}
+	s.record(result, false, "requested domain verification and DKIM for "+opts.Domain)
+	return nil
+}
+
+func (s *SESSetup) ensureRuleSet(ctx context.Context, opts SESSetupOptions, result *SESSetupResult) error {
+	_, err := s.client.DescribeReceiptRuleSet(ctx, &ses.DescribeReceiptRuleSetInput{RuleSetName: aws.String(opts.RuleSetName)})
+	if err == nil {
+		return nil
+	}
+
+	var missing *types.RuleSetDoesNotExistException
+	if !errors.As(err, &missing) {
+		return fmt.Errorf("failed to describe receipt rule set: %w", err)
+	}
+
+	if !opts.DryRun {
+		_, err := s.client.CreateReceiptRuleSet(ctx, &ses.CreateReceiptRuleSetInput{RuleSetName: aws.String(opts.RuleSetName)})
+		if err != nil {
+			return fmt.Errorf("failed to create receipt rule set: %w", err)
+		}
+	}
+	s.record(result, opts.DryRun, "create receipt rule set "+opts.RuleSetName)
+	return nil
+}
+
+// desiredRule stores the raw message in S3 before invoking the parser Lambda,
+// matching the action order in terraform/main.tf
+func desiredRule(opts SESSetupOptions) *types.ReceiptRule {
+	return &types.ReceiptRule{
+		Name:        aws.String(opts.RuleName),
+		Enabled:     true,
+		ScanEnabled: true,
+		Recipients:  opts.Recipients,
+		Actions: []types.ReceiptAction{
+			{S3Action: &types.S3Action{
+				BucketName:      aws.String(opts.Bucket),
+				ObjectKeyPrefix: aws.String(opts.ObjectPrefix),
+			}},
+			{LambdaAction: &types.LambdaAction{
+				FunctionArn:    aws.String(opts.LambdaARN),
+				InvocationType: types.InvocationTypeEvent,
+			}},
+		},
+	}
+}
+
+func (s *SESSetup) ensureRule(ctx context.Context, opts SESSetupOptions, result *SESSetupResult) error {
+	rule := desiredRule(opts)
+
+	existing, err := s.client.DescribeReceiptRule(ctx, &ses.DescribeReceiptRuleInput{
+		RuleSetName: aws.String(opts.RuleSetName),
+		RuleName:    aws.String(opts.RuleName),
+	})
+
+	var missingRule *types.RuleDoesNotExistException
+	var missingSet *types.RuleSetDoesNotExistException
+	switch {
+	case err == nil:
+		if ruleMatches(existing.Rule, rule) {
+			return nil
+		}
+		if !opts.DryRun {
+			_, err := s.client.UpdateReceiptRule(ctx, &ses.UpdateReceiptRuleInput{RuleSetName: aws.String(opts.RuleSetName), Rule: rule})
+			if err != nil {
+				return fmt.Errorf("failed to update receipt rule: %w", err)
+			}
+		}
+		s.record(result, opts.DryRun, "update receipt rule "+opts.RuleName)
+	case errors.As(err, &missingRule), errors.As(err, &missingSet):
+		if !opts.DryRun {
+			_, err := s.client.CreateReceiptRule(ctx, &ses.CreateReceiptRuleInput{RuleSetName: aws.String(opts.RuleSetName), Rule: rule})
+			if err != nil {
+				return fmt.Errorf("failed to create receipt rule: %w", err)
+			}
+		}
+		s.record(result, opts.DryRun, "create receipt rule "+opts.RuleName)
+	default:
+		return fmt.Errorf("failed to describe receipt rule: %w", err)
+	}
+
+	return nil
+}
+
+func ruleMatches(current, desired *types.ReceiptRule) bool {
+	if current == nil || current.Enabled != desired.Enabled || current.ScanEnabled != desired.ScanEnabled {
+		return false
+	}
+	if strings.Join(current.Recipients, ",") != strings.Join(desired.Recipients, ",") {
+		return false
+	}
+	if len(current.Actions) != len(desired.Actions) {
+		return false
+	}
+
+	for i, action := range current.Actions {
+		want := desired.Actions[i]
+		switch {
+		case want.S3Action != nil:
+			if action.S3Action == nil ||
+				aws.ToString(action.S3Action.BucketName) != aws.ToString(want.S3Action.BucketName) ||
+				aws.ToString(action.S3Action.ObjectKeyPrefix) != aws.ToString(want.S3Action.ObjectKeyPrefix) {
+				return false
+			}
+		case want.LambdaAction != nil:
+			if action.LambdaAction == nil ||
+				aws.ToString(action.LambdaAction.FunctionArn) != aws.ToString(want.LambdaAction.FunctionArn) ||
+				action.LambdaAction.InvocationType != want.LambdaAction.InvocationType {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *SESSetup) ensureActive(ctx context.Context, opts SESSetupOptions, result *SESSetupResult) error {
+	active, err := s.client.DescribeActiveReceiptRuleSet(ctx, &ses.DescribeActiveReceiptRuleSetInput{})
+	if err != nil {
+		return fmt.Errorf("failed to describe active receipt rule set: %w", err)
+	}
+	if active.Metadata != nil && aws.ToString(active.Metadata.Name) == opts.RuleSetName {
+		return nil
+	}
+
+	if !opts.DryRun {
+		_, err := s.client.SetActiveReceiptRuleSet(ctx, &ses.SetActiveReceiptRuleSetInput{RuleSetName: aws.String(opts.RuleSetName)})
+		if err != nil {
+			return fmt.Errorf("failed to activate receipt rule set: %w", err)
+		}
+	}
+	s.record(result, opts.DryRun, "activate receipt rule set "+opts.RuleSetName)
+	return nil
+}
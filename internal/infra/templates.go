@@ -0,0 +1,109 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ses"
+	"github.com/aws/aws-sdk-go-v2/service/ses/types"
+	"github.com/sirupsen/logrus"
+)
+
+// SyncTemplates creates or updates templates in SES and, with prune,
+// deletes the other templates whose names start with prefix and a hyphen,
+// such as those a changed footer replaced. With dryRun only read calls are
+// made. It returns what was, or would be, changed.
+func (s *SESSetup) SyncTemplates(ctx context.Context, prefix string, templates []types.Template, prune, dryRun bool) ([]string, error) {
+	result := &SESSetupResult{}
+
+	wanted := map[string]bool{}
+	for _, template := range templates {
+		name := aws.ToString(template.TemplateName)
+		wanted[name] = true
+		if err := s.ensureTemplate(ctx, template, dryRun, result); err != nil {
+			return nil, err
+		}
+	}
+
+	if prune {
+		existing, err := s.listTemplates(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range existing {
+			if wanted[name] || !strings.HasPrefix(name, prefix+"-") {
+				continue
+			}
+			if !dryRun {
+				if _, err := s.client.DeleteTemplate(ctx, &ses.DeleteTemplateInput{TemplateName: aws.String(name)}); err != nil {
+					return nil, fmt.Errorf("failed to delete SES template %s: %w", name, err)
+				}
+			}
+			s.record(result, dryRun, "delete template "+name)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"templates": len(templates),
+		"actions":   len(result.Actions),
+		"dry_run":   dryRun,
+	}).Info("SES templates synced")
+	return result.Actions, nil
+}
+
+func (s *SESSetup) ensureTemplate(ctx context.Context, template types.Template, dryRun bool, result *SESSetupResult) error {
+	name := aws.ToString(template.TemplateName)
+
+	existing, err := s.client.GetTemplate(ctx, &ses.GetTemplateInput{TemplateName: template.TemplateName})
+	var missing *types.TemplateDoesNotExistException
+	switch {
+	case err == nil:
+		if templateMatches(existing.Template, &template) {
+			return nil
+		}
+		if !dryRun {
+			if _, err := s.client.UpdateTemplate(ctx, &ses.UpdateTemplateInput{Template: &template}); err != nil {
+				return fmt.Errorf("failed to update SES template %s: %w", name, err)
+			}
+		}
+		s.record(result, dryRun, "update template "+name)
+	case errors.As(err, &missing):
+		if !dryRun {
+			if _, err := s.client.CreateTemplate(ctx, &ses.CreateTemplateInput{Template: &template}); err != nil {
+				return fmt.Errorf("failed to create SES template %s: %w", name, err)
+			}
+		}
+		s.record(result, dryRun, "create template "+name)
+	default:
+		return fmt.Errorf("failed to get SES template %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *SESSetup) listTemplates(ctx context.Context) ([]string, error) {
+	var names []string
+	input := &ses.ListTemplatesInput{}
+	for {
+		page, err := s.client.ListTemplates(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list SES templates: %w", err)
+		}
+		for _, metadata := range page.TemplatesMetadata {
+			names = append(names, aws.ToString(metadata.Name))
+		}
+		if page.NextToken == nil {
+			return names, nil
+		}
+		input.NextToken = page.NextToken
+	}
+}
+
+func templateMatches(current, desired *types.Template) bool {
+	return current != nil &&
+		aws.ToString(current.SubjectPart) == aws.ToString(desired.SubjectPart) &&
+		aws.ToString(current.TextPart) == aws.ToString(desired.TextPart) &&
+		aws.ToString(current.HtmlPart) == aws.ToString(desired.HtmlPart)
+}
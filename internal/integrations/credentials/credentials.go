@@ -0,0 +1,152 @@
+// Package credentials stores the secrets integrations need - tokens, webhook
+// URLs - encrypted at rest in integration_credentials. Integrations keep
+// their secrets here rather than in their own tables.
+package credentials
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+)
+
+// Store reads and writes encrypted integration credentials
+type Store struct {
+	db      *database.DB
+	keyring *Keyring
+}
+
+// NewStore returns nil when INTEGRATION_ENCRYPTION_KEYS is empty, in which
+// case integrations keep their secrets in plaintext as before
+func NewStore(db *database.DB, keys string) (*Store, error) {
+	if strings.TrimSpace(keys) == "" {
+		return nil, nil
+	}
+
+	keyring, err := ParseKeyring(keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid INTEGRATION_ENCRYPTION_KEYS: %w", err)
+	}
+	return &Store{db: db, keyring: keyring}, nil
+}
+
+// aad ties a credential's ciphertext to the user and integration it
+// belongs to
+func aad(userID int, integration string) []byte {
+	return []byte(fmt.Sprintf("%d/%s", userID, integration))
+}
+
+// Put stores secret as userID's credential for integration, replacing any
+// earlier one. Replacing a credential is how a token is rotated.
+func (s *Store) Put(ctx context.Context, userID int, integration, secret string) error {
+	keyID, sealed, err := s.keyring.Seal([]byte(secret), aad(userID, integration))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO integration_credentials (user_id, integration, key_id, ciphertext)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, integration) DO UPDATE
+		SET key_id = EXCLUDED.key_id, ciphertext = EXCLUDED.ciphertext, rotated_at = NOW(), updated_at = NOW()`
+
+	if _, err := s.db.ExecContext(ctx, query, userID, integration, keyID, sealed); err != nil {
+		return fmt.Errorf("failed to store %s credential: %w", integration, err)
+	}
+	return nil
+}
+
+// Get returns userID's credential for integration, or "" if there is none
+func (s *Store) Get(ctx context.Context, userID int, integration string) (string, error) {
+	query := `SELECT key_id, ciphertext FROM integration_credentials WHERE user_id = $1 AND integration = $2`
+
+	var keyID string
+	var sealed []byte
+	err := s.db.QueryRowContext(ctx, query, userID, integration).Scan(&keyID, &sealed)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s credential: %w", integration, err)
+	}
+
+	secret, err := s.keyring.Open(keyID, sealed, aad(userID, integration))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s credential for user %d: %w", integration, userID, err)
+	}
+	return string(secret), nil
+}
+
+// Delete removes userID's credential for integration
+func (s *Store) Delete(ctx context.Context, userID int, integration string) error {
+	query := `DELETE FROM integration_credentials WHERE user_id = $1 AND integration = $2`
+	if _, err := s.db.ExecContext(ctx, query, userID, integration); err != nil {
+		return fmt.Errorf("failed to delete %s credential: %w", integration, err)
+	}
+	return nil
+}
+
+// RotateKeys re-encrypts every credential not under the primary key and
+// returns how many it changed. Afterwards the old keys can be removed from
+// INTEGRATION_ENCRYPTION_KEYS.
+func (s *Store) RotateKeys(ctx context.Context) (int, error) {
+	query := `
+		SELECT id, user_id, integration, key_id, ciphertext
+		FROM integration_credentials WHERE key_id <> $1`
+
+	rows, err := s.db.QueryContext(ctx, query, s.keyring.Primary())
+	if err != nil {
+		return 0, fmt.Errorf("failed to query credentials: %w", err)
+	}
+
+	type credential struct {
+		id          int
+		userID      int
+		integration string
+		keyID       string
+		sealed      []byte
+	}
+	var stale []credential
+	for rows.Next() {
+		var c credential
+		if err := rows.Scan(&c.id, &c.userID, &c.integration, &c.keyID, &c.sealed); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan credential: %w", err)
+		}
+		stale = append(stale, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query credentials: %w", err)
+	}
+
+	rotated := 0
+	for _, c := range stale {
+		secret, err := s.keyring.Open(c.keyID, c.sealed, aad(c.userID, c.integration))
+		if err != nil {
+			return rotated, fmt.Errorf("credential %d: %w", c.id, err)
+		}
+		keyID, sealed, err := s.keyring.Seal(secret, aad(c.userID, c.integration))
+		if err != nil {
+			return rotated, err
+		}
+
+		// Matching the old key ID skips a row a concurrent Put has replaced
+		update := `
+			UPDATE integration_credentials SET key_id = $2, ciphertext = $3, updated_at = NOW()
+			WHERE id = $1 AND key_id = $4`
+		if _, err := s.db.ExecContext(ctx, update, c.id, keyID, sealed, c.keyID); err != nil {
+			return rotated, fmt.Errorf("failed to re-encrypt credential %d: %w", c.id, err)
+		}
+		rotated++
+	}
+
+	if rotated > 0 {
+		logrus.WithFields(logrus.Fields{"count": rotated, "key_id": s.keyring.Primary()}).Info("Re-encrypted integration credentials")
+	}
+	return rotated, nil
+}
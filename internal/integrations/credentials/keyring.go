@@ -0,0 +1,104 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// keySize is the AES-256 key length
+const keySize = 32
+
+// Keyring holds the keys credentials are encrypted with. The first key
+// encrypts; every key can decrypt, so a key can be rotated out by adding a
+// new one in front and re-encrypting with RotateKeys before removing it.
+type Keyring struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// ParseKeyring parses INTEGRATION_ENCRYPTION_KEYS: comma-separated
+// "id:base64-key" pairs of 32-byte keys, primary first
+func ParseKeyring(value string) (*Keyring, error) {
+	k := &Keyring{aeads: map[string]cipher.AEAD{}}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid encryption key %q, expected id:base64-key", pair)
+		}
+		if _, exists := k.aeads[id]; exists {
+			return nil, fmt.Errorf("encryption key %q is listed twice", id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q is not base64: %w", id, err)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("encryption key %q is %d bytes, expected %d", id, len(key), keySize)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+
+		if k.primary == "" {
+			k.primary = id
+		}
+		k.aeads[id] = aead
+	}
+
+	if k.primary == "" {
+		return nil, errors.New("no encryption keys configured")
+	}
+	return k, nil
+}
+
+// Primary is the ID of the key new credentials are encrypted with
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// Seal encrypts plaintext with the primary key. aad binds the ciphertext to
+// its row, so it can't be copied to another user's credential. The result
+// is the nonce followed by the ciphertext.
+func (k *Keyring) Seal(plaintext, aad []byte) (keyID string, sealed []byte, err error) {
+	aead := k.aeads[k.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return k.primary, aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// Open decrypts what Seal returned under keyID
+func (k *Keyring) Open(keyID string, sealed, aad []byte) ([]byte, error) {
+	aead, ok := k.aeads[keyID]
+	if !ok {
+		return nil, fmt.Errorf("encryption key %q is not configured", keyID)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with key %q: %w", keyID, err)
+	}
+	return plaintext, nil
+}
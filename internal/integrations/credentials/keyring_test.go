@@ -0,0 +1,80 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, keySize))
+}
+
+func TestParseKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		primary string
+		wantErr bool
+	}{
+		{"single key", "k1:" + testKey(1), "k1", false},
+		{"primary first", "k2:" + testKey(2) + ", k1:" + testKey(1), "k2", false},
+		{"empty", " , ", "", true},
+		{"missing id", ":" + testKey(1), "", true},
+		{"not base64", "k1:not-base64!", "", true},
+		{"short key", "k1:" + base64.StdEncoding.EncodeToString([]byte("short")), "", true},
+		{"duplicate id", "k1:" + testKey(1) + ",k1:" + testKey(2), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := ParseKeyring(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && keyring.Primary() != tt.primary {
+				t.Errorf("Primary() = %q, want %q", keyring.Primary(), tt.primary)
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old, err := ParseKeyring("k1:" + testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID, sealed, err := old.Seal([]byte("https://example.webhook.office.com/x"), aad(7, "msteams"))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	rotated, err := ParseKeyring("k2:" + testKey(2) + ",k1:" + testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := rotated.Open(keyID, sealed, aad(7, "msteams"))
+	if err != nil {
+		t.Fatalf("Open() with the old key still configured: %v", err)
+	}
+	if string(plaintext) != "https://example.webhook.office.com/x" {
+		t.Errorf("Open() = %q", plaintext)
+	}
+
+	if _, err := rotated.Open(keyID, sealed, aad(8, "msteams")); err == nil {
+		t.Error("Open() accepted another user's credential")
+	}
+
+	newID, _, err := rotated.Seal(plaintext, aad(7, "msteams"))
+	if err != nil || newID != "k2" {
+		t.Errorf("Seal() after rotation = %q, %v, want the new primary key", newID, err)
+	}
+
+	retired, err := ParseKeyring("k2:" + testKey(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := retired.Open(keyID, sealed, aad(7, "msteams")); err == nil {
+		t.Error("Open() succeeded with the key removed")
+	}
+}
@@ -0,0 +1,147 @@
+package msteams
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+)
+
+// maxActivityBytes caps the size of an inbound activity
+const maxActivityBytes = 1 << 20
+
+var (
+	mentionRegex = regexp.MustCompile(`(?s)<at>.*?</at>`)
+	htmlTagRegex = regexp.MustCompile(`<[^>]+>`)
+)
+
+// ReplyFunc handles a reply sent by the Teams user with the given id
+type ReplyFunc func(ctx context.Context, externalUserID, text string) error
+
+// activity is the subset of a Bot Framework activity sent by outgoing webhooks
+type activity struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	From struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		AADObjectID string `json:"aadObjectId"`
+	} `json:"from"`
+}
+
+// Handler receives replies from a Teams outgoing webhook. Requests are
+// authenticated with the HMAC security token Teams issues for the webhook.
+type Handler struct {
+	secret  []byte
+	onReply ReplyFunc
+}
+
+func NewHandler(securityToken string, onReply ReplyFunc) (*Handler, error) {
+	secret, err := base64.StdEncoding.DecodeString(securityToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Teams security token: %w", err)
+	}
+
+	return &Handler{secret: secret, onReply: onReply}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxActivityBytes))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.validSignature(r.Header.Get("Authorization"), body) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var act activity
+	if err := json.Unmarshal(body, &act); err != nil {
+		http.Error(w, "invalid activity", http.StatusBadRequest)
+		return
+	}
+
+	if act.Type != "message" {
+		writeMessage(w, "")
+		return
+	}
+
+	externalUserID := act.From.AADObjectID
+	if externalUserID == "" {
+		externalUserID = act.From.ID
+	}
+
+	err = h.onReply(r.Context(), externalUserID, cleanText(act.Text))
+	if err != nil {
+		code := apperrors.CodeOf(err)
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"external_user_id": externalUserID,
+			"error_code":       code,
+		}).Error("Failed to process Teams reply")
+
+		switch code {
+		case apperrors.CodeUserNotFound:
+			writeMessage(w, "This Teams account isn't linked to a What Did You Get Done account yet.")
+		case apperrors.CodeNotVerified:
+			writeMessage(w, "Finish signing up over email first, then reply here.")
+		default:
+			writeMessage(w, "Sorry, something went wrong saving that. Please try again later.")
+		}
+		return
+	}
+
+	writeMessage(w, "Got it. Logged for today.")
+}
+
+// validSignature checks the "HMAC <base64>" authorization header against the body
+func (h *Handler) validSignature(header string, body []byte) bool {
+	provided, ok := strings.CutPrefix(header, "HMAC ")
+	if !ok {
+		return false
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(provided)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// cleanText strips the bot @mention and Teams HTML markup from a message.
+// Teams escapes what the user typed, so command tags such as <pause> survive
+// the tag strip and are restored by the unescape.
+func cleanText(text string) string {
+	text = mentionRegex.ReplaceAllString(text, "")
+	text = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "</p>", "\n").Replace(text)
+	text = htmlTagRegex.ReplaceAllString(text, "")
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
+// writeMessage responds with a message activity Teams shows in the conversation
+func writeMessage(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"type": "message", "text": text}); err != nil {
+		logrus.WithError(err).Error("Failed to encode Teams response")
+	}
+}
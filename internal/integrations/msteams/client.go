@@ -0,0 +1,83 @@
+// Package msteams delivers daily prompts to Microsoft Teams through incoming
+// webhooks and captures replies through an outgoing webhook (bot) endpoint.
+package msteams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Client posts messages to Teams incoming webhooks
+type Client struct {
+	httpClient *http.Client
+}
+
+func NewClient() *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// messageCard is the legacy connector card format accepted by incoming webhooks
+type messageCard struct {
+	Type    string `json:"@type"`
+	Context string `json:"@context"`
+	Summary string `json:"summary"`
+	Title   string `json:"title"`
+	Text    string `json:"text"`
+}
+
+// SendDailyPrompt posts the daily prompt to the user's chat or channel webhook
+func (c *Client) SendDailyPrompt(ctx context.Context, user *models.User, target *models.UserChannel, quote, recap string) error {
+	if target.WebhookURL == nil {
+		return fmt.Errorf("no Teams webhook configured for user %d", user.ID)
+	}
+
+	subject, body, err := email.RenderDailyPromptEmail(user.ProjectFocus, user.EntryFormat, quote, recap, email.PromptVariationFor(user))
+	if err != nil {
+		return fmt.Errorf("failed to render daily prompt: %w", err)
+	}
+
+	return c.post(ctx, *target.WebhookURL, messageCard{
+		Type:    "MessageCard",
+		Context: "https://schema.org/extensions",
+		Summary: subject,
+		Title:   subject,
+		// Templates are laid out for a fixed-width font
+		Text: "```\n" + body + "\n```",
+	})
+}
+
+func (c *Client) post(ctx context.Context, webhookURL string, card messageCard) error {
+	payload, err := json.Marshal(card)
+	if err != nil {
+		return fmt.Errorf("failed to marshal card: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to Teams webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Teams webhook returned %d: %s", resp.StatusCode, respBody)
+	}
+
+	return nil
+}
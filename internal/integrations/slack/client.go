@@ -0,0 +1,185 @@
+// Package slack logs Slack messages as entries: a "Log to journal" message
+// action and the /standup slash command log on demand, and a daily import
+// reads what each linked user posted in their standup channel.
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultAPIURL = "https://slack.com/api"
+
+// historyPageSize is how many messages each conversations.history call asks for
+const historyPageSize = 200
+
+var (
+	// labelRegex matches links and mentions with a label, such as
+	// <https://example.com|the doc> or <#C123|standup>
+	labelRegex = regexp.MustCompile(`<([^<>|]+)\|([^<>]+)>`)
+	// refRegex matches the rest: <https://example.com>, <@U123>, <!here>
+	refRegex = regexp.MustCompile(`<([^<>]+)>`)
+)
+
+// Message is a message a user posted in a channel
+type Message struct {
+	Channel string
+	User    string
+	// TS is Slack's id for the message within its channel
+	TS     string
+	Text   string
+	SentAt time.Time
+}
+
+// ID identifies the message across channels
+func (m Message) ID() string {
+	return m.Channel + "/" + m.TS
+}
+
+// Client reads channel history with a bot token
+type Client struct {
+	token      string
+	apiURL     string
+	httpClient *http.Client
+}
+
+// NewClient returns nil when SLACK_BOT_TOKEN is empty, in which case
+// standup channels aren't imported
+func NewClient(botToken string) *Client {
+	if botToken == "" {
+		return nil
+	}
+	return &Client{
+		token:      botToken,
+		apiURL:     defaultAPIURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// historyResponse is the subset of a conversations.history response used
+type historyResponse struct {
+	OK       bool   `json:"ok"`
+	Error    string `json:"error"`
+	Messages []struct {
+		Type    string `json:"type"`
+		Subtype string `json:"subtype"`
+		User    string `json:"user"`
+		BotID   string `json:"bot_id"`
+		Text    string `json:"text"`
+		TS      string `json:"ts"`
+	} `json:"messages"`
+	ResponseMetadata struct {
+		NextCursor string `json:"next_cursor"`
+	} `json:"response_metadata"`
+}
+
+// History returns the messages people posted in channel since oldest,
+// oldest first. Bot messages, joins and other events are left out.
+func (c *Client) History(ctx context.Context, channel string, oldest time.Time) ([]Message, error) {
+	var messages []Message
+	cursor := ""
+	for {
+		params := url.Values{
+			"channel": {channel},
+			"oldest":  {strconv.FormatInt(oldest.Unix(), 10)},
+			"limit":   {strconv.Itoa(historyPageSize)},
+		}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+
+		var page historyResponse
+		if err := c.get(ctx, "conversations.history", params, &page); err != nil {
+			return nil, err
+		}
+
+		for _, m := range page.Messages {
+			if m.Type != "message" || m.Subtype != "" || m.BotID != "" || m.User == "" {
+				continue
+			}
+			sentAt, err := parseTS(m.TS)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, Message{
+				Channel: channel,
+				User:    m.User,
+				TS:      m.TS,
+				Text:    cleanText(m.Text),
+				SentAt:  sentAt,
+			})
+		}
+
+		cursor = page.ResponseMetadata.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+
+	// Slack lists the newest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+func (c *Client) get(ctx context.Context, method string, params url.Values, out *historyResponse) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL+"/"+method+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build Slack request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Slack %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Slack %s returned %d: %s", method, resp.StatusCode, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Slack %s response: %w", method, err)
+	}
+	if !out.OK {
+		return fmt.Errorf("Slack %s failed: %s", method, out.Error)
+	}
+	return nil
+}
+
+// parseTS reads a message timestamp such as "1715000000.000200"
+func parseTS(ts string) (time.Time, error) {
+	secs, micros, _ := strings.Cut(ts, ".")
+	s, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid Slack timestamp %q", ts)
+	}
+	us, _ := strconv.ParseInt(micros, 10, 64)
+	return time.Unix(s, us*int64(time.Microsecond)).UTC(), nil
+}
+
+// cleanText turns Slack's message markup into plain text. Slack escapes
+// what the user typed, so command tags such as <pause> survive the markup
+// strip and are restored by the unescape.
+func cleanText(text string) string {
+	text = labelRegex.ReplaceAllString(text, "$2")
+	text = refRegex.ReplaceAllStringFunc(text, func(ref string) string {
+		ref = strings.Trim(ref, "<>")
+		if strings.HasPrefix(ref, "@") || strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "!") {
+			return ""
+		}
+		return ref
+	})
+	return strings.TrimSpace(html.UnescapeString(text))
+}
@@ -0,0 +1,256 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+)
+
+// maxRequestBytes caps the size of an interaction or slash command request
+const maxRequestBytes = 1 << 20
+
+// maxRequestAge is how old a signed request may be, against replays
+const maxRequestAge = 5 * time.Minute
+
+// LogCallbackID is the callback ID of the "Log to journal" message action
+const LogCallbackID = "log_to_journal"
+
+// StandupCommand is the slash command that logs its text
+const StandupCommand = "/standup"
+
+// MessageFunc logs msg for the Slack user with the given id
+type MessageFunc func(ctx context.Context, externalUserID string, msg Message) error
+
+// ReplyFunc handles text sent by the Slack user with the given id
+type ReplyFunc func(ctx context.Context, externalUserID, text string) error
+
+// interaction is the subset of a message action payload used
+type interaction struct {
+	Type       string `json:"type"`
+	CallbackID string `json:"callback_id"`
+	User       struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Message struct {
+		Text string `json:"text"`
+		TS   string `json:"ts"`
+	} `json:"message"`
+	ResponseURL string `json:"response_url"`
+}
+
+// Handler receives message actions and slash commands. Requests are
+// authenticated with the app's signing secret.
+type Handler struct {
+	secret     []byte
+	onMessage  MessageFunc
+	onCommand  ReplyFunc
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+func NewHandler(signingSecret string, onMessage MessageFunc, onCommand ReplyFunc) *Handler {
+	return &Handler{
+		secret:     []byte(signingSecret),
+		onMessage:  onMessage,
+		onCommand:  onCommand,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBytes))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.validSignature(r.Header.Get("X-Slack-Request-Timestamp"), r.Header.Get("X-Slack-Signature"), body) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case form.Get("payload") != "":
+		h.handleAction(w, r, form.Get("payload"))
+	case form.Get("command") != "":
+		h.handleCommand(w, r, form)
+	default:
+		http.Error(w, "unsupported request", http.StatusBadRequest)
+	}
+}
+
+// handleAction logs the message a "Log to journal" action was used on and
+// tells the user how it went through the action's response URL
+func (h *Handler) handleAction(w http.ResponseWriter, r *http.Request, payload string) {
+	var act interaction
+	if err := json.Unmarshal([]byte(payload), &act); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if act.Type != "message_action" || act.CallbackID != LogCallbackID {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sentAt, err := parseTS(act.Message.TS)
+	if err != nil {
+		http.Error(w, "invalid message", http.StatusBadRequest)
+		return
+	}
+	msg := Message{
+		Channel: act.Channel.ID,
+		User:    act.User.ID,
+		TS:      act.Message.TS,
+		Text:    cleanText(act.Message.Text),
+		SentAt:  sentAt,
+	}
+
+	text := "Got it. Logged to your journal."
+	if msg.Text == "" {
+		text = "That message has no text to log."
+	} else if err := h.onMessage(r.Context(), act.User.ID, msg); err != nil {
+		text = h.errorMessage(err, act.User.ID)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	h.respond(r.Context(), act.ResponseURL, text)
+}
+
+// handleCommand logs the text of /standup
+func (h *Handler) handleCommand(w http.ResponseWriter, r *http.Request, form url.Values) {
+	if form.Get("command") != StandupCommand {
+		writeMessage(w, "Unknown command "+form.Get("command"))
+		return
+	}
+
+	text := cleanText(form.Get("text"))
+	if text == "" {
+		writeMessage(w, "Usage: "+StandupCommand+" what you got done")
+		return
+	}
+
+	externalUserID := form.Get("user_id")
+	if err := h.onCommand(r.Context(), externalUserID, text); err != nil {
+		writeMessage(w, h.errorMessage(err, externalUserID))
+		return
+	}
+
+	writeMessage(w, "Got it. Logged for today.")
+}
+
+// errorMessage logs err and returns what to tell the user about it
+func (h *Handler) errorMessage(err error, externalUserID string) string {
+	code := apperrors.CodeOf(err)
+	if code == apperrors.CodeConflict {
+		return "That message is already in your journal."
+	}
+
+	logrus.WithError(err).WithFields(logrus.Fields{
+		"external_user_id": externalUserID,
+		"error_code":       code,
+	}).Error("Failed to process Slack message")
+
+	switch code {
+	case apperrors.CodeUserNotFound:
+		return "This Slack account isn't linked to a What Did You Get Done account yet."
+	case apperrors.CodeNotVerified:
+		return "Finish signing up over email first, then log from Slack."
+	default:
+		return "Sorry, something went wrong saving that. Please try again later."
+	}
+}
+
+// validSignature checks Slack's v0 request signature: an HMAC-SHA256 of the
+// timestamp and body under the signing secret
+func (h *Handler) validSignature(timestamp, signature string, body []byte) bool {
+	secs, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := h.now().Sub(time.Unix(secs, 0))
+	if age > maxRequestAge || age < -maxRequestAge {
+		return false
+	}
+
+	provided, ok := strings.CutPrefix(signature, "v0=")
+	if !ok {
+		return false
+	}
+	sig, err := hex.DecodeString(provided)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// ephemeralMessage is a reply only the user who acted sees
+type ephemeralMessage struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// respond posts text to an action's response URL
+func (h *Handler) respond(ctx context.Context, responseURL, text string) {
+	if responseURL == "" {
+		return
+	}
+	payload, err := json.Marshal(ephemeralMessage{ResponseType: "ephemeral", Text: text})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode Slack response")
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(payload))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to build Slack response")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to send Slack response")
+		return
+	}
+	resp.Body.Close()
+}
+
+// writeMessage answers a slash command with a message only the user sees
+func writeMessage(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ephemeralMessage{ResponseType: "ephemeral", Text: text}); err != nil {
+		logrus.WithError(err).Error("Failed to encode Slack response")
+	}
+}
@@ -0,0 +1,104 @@
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Shipped the importer", "Shipped the importer"},
+		{"Reviewed <https://example.com/pr/1|the PR> with <@U123>", "Reviewed the PR with"},
+		{"See <https://example.com>", "See https://example.com"},
+		{"<!here> paired in <#C123|standup>", "paired in standup"},
+		{"&lt;pause&gt;1 week&lt;/pause&gt; &amp; done", "<pause>1 week</pause> & done"},
+	}
+	for _, tt := range tests {
+		if got := cleanText(tt.in); got != tt.want {
+			t.Errorf("cleanText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func signedRequest(t *testing.T, secret string, at time.Time, body string) *http.Request {
+	t.Helper()
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/integrations/slack", strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestStandupCommand(t *testing.T) {
+	now := time.Unix(1715000000, 0)
+	var gotUser, gotText string
+	h := NewHandler("secret", nil, func(ctx context.Context, externalUserID, text string) error {
+		gotUser, gotText = externalUserID, text
+		return nil
+	})
+	h.now = func() time.Time { return now }
+
+	body := url.Values{"command": {"/standup"}, "user_id": {"U123"}, "text": {"Fixed the flaky test"}}.Encode()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, signedRequest(t, "secret", now, body))
+	if rec.Code != http.StatusOK || gotUser != "U123" || gotText != "Fixed the flaky test" {
+		t.Fatalf("signed command: status %d, user %q, text %q", rec.Code, gotUser, gotText)
+	}
+
+	for name, req := range map[string]*http.Request{
+		"wrong secret": signedRequest(t, "other", now, body),
+		"replayed":     signedRequest(t, "secret", now.Add(-10*time.Minute), body),
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want %d", name, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" || r.URL.Query().Get("channel") != "C1" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"ok": true, "messages": [
+			{"type": "message", "user": "U2", "text": "second", "ts": "1715000100.000200"},
+			{"type": "message", "subtype": "channel_join", "user": "U3", "text": "joined", "ts": "1715000050.000000"},
+			{"type": "message", "bot_id": "B1", "text": "reminder", "ts": "1715000040.000000"},
+			{"type": "message", "user": "U1", "text": "first", "ts": "1715000000.000100"}
+		]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("xoxb-test")
+	c.apiURL = server.URL
+
+	messages, err := c.History(context.Background(), "C1", time.Unix(1714990000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].Text != "first" || messages[1].Text != "second" {
+		t.Fatalf("History() = %+v, want first and second, oldest first", messages)
+	}
+	if messages[0].ID() != "C1/1715000000.000100" || !messages[0].SentAt.Equal(time.Unix(1715000000, 100000)) {
+		t.Errorf("first message = %+v", messages[0])
+	}
+}
@@ -0,0 +1,103 @@
+// Package jobqueue wakes workers as soon as there is work for them, instead
+// of waiting for their next scheduled run. The database stays the source of
+// truth - a job is a row, such as a pending email_logs row - and the queue
+// only carries the signal that one is ready, so a lost signal delays work
+// until the next scheduled run rather than losing it.
+package jobqueue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+)
+
+// Queue kinds for JOB_QUEUE
+const (
+	KindMemory   = "memory"
+	KindPostgres = "postgres"
+	KindRedis    = "redis"
+)
+
+// Queue signals workers listening on a topic
+type Queue interface {
+	// Notify wakes every listener on topic
+	Notify(ctx context.Context, topic string) error
+	// Listen returns a channel that receives after each Notify on topic.
+	// Signals the listener hasn't taken yet are merged into one, and one is
+	// sent after a dropped connection is restored, since signals may have
+	// been missed. The channel is closed when ctx is done.
+	Listen(ctx context.Context, topic string) (<-chan struct{}, error)
+	Close() error
+}
+
+// New returns the queue JOB_QUEUE selects. memory only reaches listeners in
+// the same process; postgres (LISTEN/NOTIFY on the app database) and redis
+// (pub/sub on JOB_QUEUE_URL) reach them in any process.
+func New(cfg *config.Config, db *database.DB) (Queue, error) {
+	switch cfg.JobQueue {
+	case KindMemory, "":
+		return NewMemory(), nil
+	case KindPostgres:
+		return newPostgres(db, database.DSN(cfg)), nil
+	case KindRedis:
+		return newRedis(cfg.JobQueueURL)
+	}
+	return nil, fmt.Errorf("unknown job queue %q (want memory, postgres or redis)", cfg.JobQueue)
+}
+
+// Memory is the in-process queue
+type Memory struct {
+	mu        sync.Mutex
+	listeners map[string][]chan struct{}
+}
+
+func NewMemory() *Memory {
+	return &Memory{listeners: map[string][]chan struct{}{}}
+}
+
+func (m *Memory) Notify(ctx context.Context, topic string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, ch := range m.listeners[topic] {
+		wake(ch)
+	}
+	return nil
+}
+
+func (m *Memory) Listen(ctx context.Context, topic string) (<-chan struct{}, error) {
+	ch := make(chan struct{}, 1)
+
+	m.mu.Lock()
+	m.listeners[topic] = append(m.listeners[topic], ch)
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		listeners := m.listeners[topic]
+		for i, listener := range listeners {
+			if listener == ch {
+				m.listeners[topic] = append(listeners[:i:i], listeners[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// wake signals ch without blocking; a signal already waiting covers this one
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
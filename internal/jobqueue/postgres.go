@@ -0,0 +1,71 @@
+package jobqueue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+)
+
+// postgresPingInterval is how often an idle listener checks its connection
+const postgresPingInterval = 90 * time.Second
+
+// postgresQueue signals with NOTIFY on the app database. Each Listen holds
+// its own connection, outside the pool, for LISTEN.
+type postgresQueue struct {
+	db  *database.DB
+	dsn string
+}
+
+func newPostgres(db *database.DB, dsn string) *postgresQueue {
+	return &postgresQueue{db: db, dsn: dsn}
+}
+
+func (q *postgresQueue) Notify(ctx context.Context, topic string) error {
+	if _, err := q.db.ExecContext(ctx, `SELECT pg_notify($1, '')`, topic); err != nil {
+		return fmt.Errorf("failed to notify %s: %w", topic, err)
+	}
+	return nil
+}
+
+func (q *postgresQueue) Listen(ctx context.Context, topic string) (<-chan struct{}, error) {
+	logger := logrus.WithField("topic", topic)
+	listener := pq.NewListener(q.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.WithError(err).Warn("Job queue listener connection failed, reconnecting")
+		}
+	})
+	if err := listener.Listen(topic); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to listen on %s: %w", topic, err)
+	}
+
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer close(ch)
+		defer listener.Close()
+
+		ping := time.NewTicker(postgresPingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			// A nil notification means the connection was restored
+			case <-listener.Notify:
+				wake(ch)
+			case <-ping.C:
+				go listener.Ping()
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (q *postgresQueue) Close() error {
+	return nil
+}
@@ -0,0 +1,361 @@
+package jobqueue
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Timeouts for talking to Redis, and the longest wait between attempts to
+// resubscribe after losing the connection
+const (
+	redisTimeout    = 5 * time.Second
+	redisMaxBackoff = 30 * time.Second
+)
+
+// redisQueue signals over Redis pub/sub. It speaks just enough of the Redis
+// protocol for AUTH, PUBLISH and SUBSCRIBE, so the queue doesn't pull in a
+// Redis client for three commands.
+type redisQueue struct {
+	addr     string
+	username string
+	password string
+	tls      bool
+
+	// conn publishes; it is dialed on first use and again after an error
+	mu   sync.Mutex
+	conn *redisConn
+
+	// pending holds the topics Notify has queued for the publisher, and
+	// lastErr why the publisher is backing off, if it is
+	pendingMu sync.Mutex
+	pending   map[string]bool
+	lastErr   error
+	kick      chan struct{}
+	start     sync.Once
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newRedis parses JOB_QUEUE_URL, redis://[[user]:password@]host[:port] or
+// rediss:// for TLS. The connection is made on first use, so the app starts
+// while Redis is down and falls back to its scheduled runs.
+func newRedis(rawURL string) (*redisQueue, error) {
+	if rawURL == "" {
+		return nil, fmt.Errorf("JOB_QUEUE_URL is required with JOB_QUEUE=redis")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Hostname() == "" {
+		return nil, fmt.Errorf("JOB_QUEUE_URL must be a redis:// or rediss:// URL, got %q", rawURL)
+	}
+
+	q := &redisQueue{
+		addr:    u.Host,
+		tls:     u.Scheme == "rediss",
+		pending: make(map[string]bool),
+		kick:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if u.Port() == "" {
+		q.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		q.username = u.User.Username()
+		q.password, _ = u.User.Password()
+	}
+	return q, nil
+}
+
+// Notify queues a signal for a background publisher and never waits on
+// Redis, so a batch of emails isn't held up while Redis is unreachable.
+// Signals to the same topic coalesce until they're sent. It returns the
+// error the publisher is backing off after, if any; the signal stays queued.
+func (q *redisQueue) Notify(ctx context.Context, topic string) error {
+	q.start.Do(func() { go q.publish() })
+
+	q.pendingMu.Lock()
+	q.pending[topic] = true
+	err := q.lastErr
+	q.pendingMu.Unlock()
+
+	wake(q.kick)
+	return err
+}
+
+// publish sends queued signals until Close, retrying failed ones with
+// backoff
+func (q *redisQueue) publish() {
+	backoff := time.Second
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-q.kick:
+		}
+
+		q.pendingMu.Lock()
+		topics := q.pending
+		q.pending = make(map[string]bool)
+		q.pendingMu.Unlock()
+
+		var failed error
+		for topic := range topics {
+			if failed = q.publishOne(topic); failed != nil {
+				break
+			}
+			delete(topics, topic)
+		}
+
+		q.pendingMu.Lock()
+		for topic := range topics {
+			q.pending[topic] = true
+		}
+		q.lastErr = failed
+		q.pendingMu.Unlock()
+
+		if failed == nil {
+			backoff = time.Second
+			continue
+		}
+		logrus.WithError(failed).Warn("Failed to signal job queue, retrying")
+		select {
+		case <-q.done:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, redisMaxBackoff)
+		wake(q.kick)
+	}
+}
+
+// publishOne sends one signal, dialing first if there's no connection
+func (q *redisQueue) publishOne(topic string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.conn == nil {
+		conn, err := q.dial(context.Background())
+		if err != nil {
+			return err
+		}
+		q.conn = conn
+	}
+
+	q.conn.conn.SetDeadline(time.Now().Add(redisTimeout))
+	if _, err := q.conn.do("PUBLISH", topic, ""); err != nil {
+		q.conn.conn.Close()
+		q.conn = nil
+		return fmt.Errorf("failed to notify %s: %w", topic, err)
+	}
+	return nil
+}
+
+func (q *redisQueue) Listen(ctx context.Context, topic string) (<-chan struct{}, error) {
+	conn, err := q.subscribe(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer close(ch)
+		logger := logrus.WithField("topic", topic)
+
+		for {
+			err := receive(ctx, conn, ch)
+			if ctx.Err() != nil {
+				return
+			}
+			logger.WithError(err).Warn("Job queue connection lost, reconnecting")
+
+			for backoff := time.Second; ; backoff = min(2*backoff, redisMaxBackoff) {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				if conn, err = q.subscribe(ctx, topic); err == nil {
+					break
+				}
+				logger.WithError(err).Warn("Failed to resubscribe to job queue")
+			}
+			wake(ch)
+		}
+	}()
+	return ch, nil
+}
+
+func (q *redisQueue) Close() error {
+	q.closeOnce.Do(func() { close(q.done) })
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.conn == nil {
+		return nil
+	}
+	err := q.conn.conn.Close()
+	q.conn = nil
+	return err
+}
+
+// subscribe returns a connection subscribed to topic
+func (q *redisQueue) subscribe(ctx context.Context, topic string) (*redisConn, error) {
+	conn, err := q.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.conn.SetDeadline(time.Now().Add(redisTimeout))
+	if _, err := conn.do("SUBSCRIBE", topic); err != nil {
+		conn.conn.Close()
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+	}
+	// Messages may be hours apart
+	conn.conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// receive wakes ch for each message on a subscribed connection until the
+// connection fails or ctx is done, then closes it
+func receive(ctx context.Context, conn *redisConn, ch chan struct{}) error {
+	stop := context.AfterFunc(ctx, func() { conn.conn.Close() })
+	defer stop()
+	defer conn.conn.Close()
+
+	for {
+		reply, err := conn.read()
+		if err != nil {
+			return err
+		}
+		if message, ok := reply.([]interface{}); ok && len(message) == 3 && message[0] == "message" {
+			wake(ch)
+		}
+	}
+}
+
+func (q *redisQueue) dial(ctx context.Context) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+
+	var conn net.Conn
+	var err error
+	if q.tls {
+		conn, err = (&tls.Dialer{NetDialer: dialer}).DialContext(ctx, "tcp", q.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", q.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+	if q.password != "" {
+		// Without a username AUTH checks the default user's password
+		args := []string{"AUTH", q.password}
+		if q.username != "" {
+			args = []string{"AUTH", q.username, q.password}
+		}
+		conn.SetDeadline(time.Now().Add(redisTimeout))
+		if _, err := c.do(args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to authenticate to redis: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// redisConn is one connection to Redis
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// do sends a command and reads its reply
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	if _, err := c.conn.Write(encodeCommand(args)); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+func (c *redisConn) read() (interface{}, error) {
+	return readReply(c.r)
+}
+
+// encodeCommand encodes a command as an array of bulk strings
+func encodeCommand(args []string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return b.Bytes()
+}
+
+// redisError is an error reply
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// readReply reads one reply: a string for simple and bulk strings, an int64,
+// a []interface{} for arrays, nil for null, or a redisError
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("invalid redis reply %q", line)
+	}
+
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("invalid redis reply %q", line)
+}
@@ -0,0 +1,710 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/analytics"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/anomalies"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/billing"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/core"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/custody"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/digest"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/embeddings"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/integrations/slack"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/llm"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/orgs"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/retention"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/stats"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/webhooks"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// RunHistoryRetention is how long job_runs rows are kept
+const RunHistoryRetention = 30 * 24 * time.Hour
+
+// slackImportLookback is how far back each Slack import reads. It overlaps
+// the previous run, so a missed run loses nothing; messages already
+// imported are skipped.
+const slackImportLookback = 48 * time.Hour
+
+// Services are what the built-in jobs run against
+type Services struct {
+	Core       *core.Service
+	Email      *email.Service
+	LLM        *llm.Service
+	Webhooks   *webhooks.Service
+	Analytics  *analytics.Service
+	Embeddings *embeddings.Service // nil when EMBEDDINGS_MODEL is unset
+	Retention  *retention.Service
+	Anomalies  *anomalies.Service
+	Orgs       *orgs.Service
+	Billing    *billing.Service // nil when STRIPE_SECRET_KEY is unset
+	Slack      *slack.Client    // nil when SLACK_BOT_TOKEN is unset
+}
+
+// RegisterBuiltin adds the scheduler's jobs to r
+func RegisterBuiltin(r *Registry, svc Services) {
+	r.Register(Job{
+		Name:        "daily-prompts",
+		Description: "Send daily prompts to users whose local prompt hour it is",
+		Schedule:    "0 * * * *",
+		Run: func(ctx context.Context) error {
+			return sendDailyPrompts(ctx, svc.Core, svc.Billing)
+		},
+	})
+
+	r.Register(Job{
+		Name:        "weekly-goals",
+		Description: fmt.Sprintf("Ask users who opted in for the week's goals at %02d:00 on their Monday", core.WeeklyGoalsHour),
+		Schedule:    "0 * * * *",
+		Run: func(ctx context.Context) error {
+			return sendWeeklyGoalsPrompts(ctx, svc.Core, svc.Billing)
+		},
+	})
+
+	r.Register(Job{
+		Name:        "weekly-summaries",
+		Description: "Generate and send weekly summaries",
+		Schedule:    "30 16 * * 5",
+		Run: func(ctx context.Context) error {
+			return sendWeeklySummaries(ctx, svc.Core, svc.Email, svc.LLM, svc.Billing)
+		},
+	})
+
+	r.Register(Job{
+		Name:        "mentor-digests",
+		Description: "Send mentors last month's digest of their mentee's summaries",
+		Schedule:    "0 9 1 * *",
+		Run: func(ctx context.Context) error {
+			return sendMentorDigests(ctx, svc.Core, svc.Email, digest.PreviousMonth(time.Now()))
+		},
+	})
+
+	r.Register(Job{
+		Name:        "project-rollups",
+		Description: "Send users a rollup of each project they worked on last quarter",
+		Schedule:    "0 9 1 1,4,7,10 *",
+		Run: func(ctx context.Context) error {
+			return sendProjectRollups(ctx, svc.Core, svc.Email, period.PreviousQuarter(time.Now()))
+		},
+	})
+
+	r.Register(Job{
+		Name:        "email-outbox",
+		Description: "Send due emails from the outbox",
+		Schedule:    "*/5 * * * *",
+		Run:         svc.Email.ProcessOutbox,
+	})
+
+	r.Register(Job{
+		Name:        "outbox-watchdog",
+		Description: "Alert when the oldest due email has waited longer than OUTBOX_STUCK_AFTER",
+		Schedule:    "*/15 * * * *",
+		Run:         svc.Email.WatchOutbox,
+	})
+
+	r.Register(Job{
+		Name:        "webhook-deliveries",
+		Description: "Send due webhook deliveries",
+		Schedule:    "* * * * *",
+		Run:         svc.Webhooks.ProcessDeliveries,
+	})
+
+	r.Register(Job{
+		Name:        "refresh-analytics",
+		Description: "Refresh the dashboard analytics views",
+		Schedule:    "0 2 * * *",
+		Run:         svc.Analytics.Refresh,
+	})
+
+	if svc.Embeddings != nil {
+		r.Register(Job{
+			Name:        "embed-entries",
+			Description: "Embed new and edited entries for semantic search",
+			Schedule:    "*/10 * * * *",
+			Run: func(ctx context.Context) error {
+				indexed, err := svc.Embeddings.IndexPending(ctx, embeddings.BatchSize)
+				if err != nil {
+					return err
+				}
+				if indexed > 0 {
+					logrus.WithField("count", indexed).Info("Embedded entries")
+				}
+				return nil
+			},
+		})
+	}
+
+	r.Register(Job{
+		Name:        "purge-deleted-entries",
+		Description: "Remove entries deleted longer ago than the restore window",
+		Schedule:    "0 3 * * *",
+		Run: func(ctx context.Context) error {
+			purged, err := svc.Core.PurgeDeletedEntries(ctx)
+			if err != nil {
+				return err
+			}
+			if purged > 0 {
+				logrus.WithField("count", purged).Info("Purged deleted entries")
+			}
+			return nil
+		},
+	})
+
+	r.Register(Job{
+		Name:        "enforce-retention",
+		Description: "Remove or clear data older than its RETENTION_POLICIES period",
+		Schedule:    "45 3 * * *",
+		Run: func(ctx context.Context) error {
+			results, err := svc.Retention.Enforce(ctx, false)
+			for _, result := range results {
+				if result.Affected > 0 {
+					logrus.WithFields(logrus.Fields{
+						"policy": result.Name,
+						"count":  result.Affected,
+					}).Info("Enforced retention")
+				}
+			}
+			return err
+		},
+	})
+
+	r.Register(Job{
+		Name:        "detect-anomalies",
+		Description: "Report bouncing prompts, missing or unsent summaries and email failure spikes to the admin",
+		Schedule:    "0 4 * * *",
+		Run: func(ctx context.Context) error {
+			now := time.Now().UTC()
+			report, err := svc.Anomalies.Detect(ctx, now, lastWeeklySummaryRun(now))
+			if err != nil {
+				return err
+			}
+			return svc.Email.ReportAnomalies(ctx, report)
+		},
+	})
+
+	r.Register(Job{
+		Name:        "meter-usage",
+		Description: fmt.Sprintf("Record each organization's usage for the last %d days for metered billing", orgs.MeterDays),
+		Schedule:    "15 0 * * *",
+		Run: func(ctx context.Context) error {
+			written, err := svc.Orgs.MeterRecent(ctx, time.Now())
+			if err != nil {
+				return err
+			}
+			if written > 0 {
+				logrus.WithField("count", written).Info("Metered organization usage")
+			}
+			return nil
+		},
+	})
+
+	if svc.Billing != nil {
+		r.Register(Job{
+			Name:        "billing-dunning",
+			Description: "Remind billing contacts of unpaid subscriptions and tell them when prompts stop",
+			Schedule:    "0 14 * * *",
+			Run: func(ctx context.Context) error {
+				sent, err := svc.Billing.RunDunning(ctx, time.Now())
+				if sent > 0 {
+					logrus.WithField("count", sent).Info("Sent billing notices")
+				}
+				return err
+			},
+		})
+	}
+
+	if svc.Slack != nil {
+		r.Register(Job{
+			Name:        "slack-standups",
+			Description: "Import what linked users posted in their Slack standup channel as entries",
+			Schedule:    "0 6 * * *",
+			Run: func(ctx context.Context) error {
+				return importSlackStandups(ctx, svc.Core, svc.Slack, time.Now())
+			},
+		})
+	}
+
+	r.Register(Job{
+		Name:        "prune-job-runs",
+		Description: "Remove job run history older than 30 days",
+		Schedule:    "30 3 * * *",
+		Run: func(ctx context.Context) error {
+			pruned, err := r.PruneHistory(ctx, RunHistoryRetention)
+			if err != nil {
+				return err
+			}
+			if pruned > 0 {
+				logrus.WithField("count", pruned).Info("Pruned job run history")
+			}
+			return nil
+		},
+	})
+}
+
+func sendDailyPrompts(ctx context.Context, coreService *core.Service, billingService *billing.Service) error {
+	decisions, err := promptDecisions(ctx, coreService, billingService, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, d := range decisions {
+		user := d.User
+		if !d.Send {
+			logrus.WithFields(logrus.Fields{"user_id": user.ID, "reason": d.Reason}).Info("Skipping daily prompt")
+			continue
+		}
+
+		err = coreService.SendDailyPrompt(ctx, user)
+		if apperrors.Is(err, apperrors.CodeConflict) {
+			logrus.WithField("user_id", user.ID).Info("Daily prompt already sent today, skipping")
+			continue
+		}
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to send daily prompt")
+			continue
+		}
+
+		logrus.WithField("user_id", user.ID).Info("Daily prompt queued")
+	}
+
+	return nil
+}
+
+// sendWeeklyGoalsPrompts sends the goals prompt to opted-in users for whom
+// it is WeeklyGoalsHour on Monday, skipping days off and suspended
+// subscriptions
+func sendWeeklyGoalsPrompts(ctx context.Context, coreService *core.Service, billingService *billing.Service) error {
+	now := time.Now()
+	users, err := coreService.GetUsersForWeeklyGoals(ctx, now)
+	if err != nil {
+		return err
+	}
+	suspended, err := billingService.SuspendedUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		local, ok := userTime(user, now)
+		if !ok || local.Weekday() != time.Monday || local.Hour() != core.WeeklyGoalsHour || suspended[user.ID] {
+			continue
+		}
+
+		if off, err := coreService.IsDayOff(ctx, user, now); err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to check days off, sending weekly goals prompt anyway")
+		} else if off {
+			logrus.WithField("user_id", user.ID).Info("Skipping weekly goals prompt on a day off")
+			continue
+		}
+
+		err := coreService.SendWeeklyGoalsPrompt(ctx, user, now)
+		if apperrors.Is(err, apperrors.CodeConflict) {
+			continue
+		}
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to send weekly goals prompt")
+			continue
+		}
+
+		logrus.WithField("user_id", user.ID).Info("Weekly goals prompt queued")
+	}
+
+	return nil
+}
+
+// promptDecisions decides which of the users due a prompt in at's UTC hour
+// get one at at. Whether a prompt was already sent that day is left to
+// SendDailyPrompt.
+func promptDecisions(ctx context.Context, coreService *core.Service, billingService *billing.Service, at time.Time) ([]Decision, error) {
+	users, err := coreService.GetUsersForDailyPrompt(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	suspended, err := billingService.SuspendedUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	decisions := make([]Decision, 0, len(users))
+	for _, user := range users {
+		// Check if user's local time matches their preferred prompt time
+		local, ok := userTime(user, at)
+		if !ok {
+			decisions = append(decisions, Decision{User: user, Reason: "invalid timezone " + user.Timezone})
+			continue
+		}
+		if local.Hour() != user.PromptTime.Hour() {
+			decisions = append(decisions, Decision{User: user,
+				Reason: fmt.Sprintf("local time is %s, prompt hour is %02d:00", local.Format("15:04"), user.PromptTime.Hour())})
+			continue
+		}
+
+		if suspended[user.ID] {
+			decisions = append(decisions, Decision{User: user, Reason: "subscription suspended"})
+			continue
+		}
+
+		off, err := coreService.IsDayOff(ctx, user, at)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to check days off, sending prompt")
+		}
+		if off {
+			decisions = append(decisions, Decision{User: user, Reason: "day off"})
+			continue
+		}
+
+		decisions = append(decisions, Decision{User: user, Send: true})
+	}
+
+	return decisions, nil
+}
+
+// userTime returns at in the user's timezone
+func userTime(user *models.User, at time.Time) (time.Time, bool) {
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		logrus.WithError(err).WithField("timezone", user.Timezone).Error("Invalid timezone")
+		return time.Time{}, false
+	}
+	return at.In(loc), true
+}
+
+func sendWeeklySummaries(ctx context.Context, coreService *core.Service, emailService *email.Service, llmService *llm.Service, billingService *billing.Service) error {
+	now := time.Now().UTC()
+
+	jobs, _, err := weeklySummaryJobs(ctx, coreService, billingService, now)
+	if err != nil {
+		return err
+	}
+
+	// Schedule summaries no faster than the outbox can send them, after the
+	// mail already waiting, so a fast LLM run doesn't flood the outbox
+	pacer := emailService.NewSendPacer(ctx)
+
+	// Generate summaries concurrently; results are handled one at a time
+	llmService.GenerateWeeklySummaries(ctx, jobs, func(result llm.SummaryResult) {
+		user := result.Job.User
+		weekStart, weekEnd := result.Job.Start, result.Job.End
+		if result.Err != nil {
+			logrus.WithError(result.Err).WithFields(logrus.Fields{
+				"user_id":    user.ID,
+				"error_code": apperrors.CodeOf(result.Err),
+			}).Error("Failed to generate weekly summary")
+			return
+		}
+
+		// Energy trend is derived from stored entries, no extra LLM call
+		trend, err := coreService.BuildEnergyTrend(ctx, user.ID, weekStart)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to build energy trend")
+		}
+
+		lookback, err := coreService.LastQuarterLookback(ctx, user.ID, weekStart, result.Job.Entries)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to find last quarter lookback")
+		}
+
+		ccEmails, err := coreService.GetSummaryCC(ctx, user.ID)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to load summary CC list")
+		}
+
+		goals, err := coreService.GetWeeklyGoals(ctx, user.ID, weekStart)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to load weekly goals")
+		}
+
+		cardURL, err := coreService.CreateShareCard(ctx, user, weekStart, result.Summary.BulletPoints)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to create share card, sending summary without it")
+		}
+
+		// Send summary email
+		scheduledAt := pacer.Next(1 + len(ccEmails))
+		err = emailService.SendWeeklySummary(ctx, user.ID, user.Email, ccEmails, weekStart, weekEnd,
+			result.Summary.Paragraph, result.Summary.BulletPoints, goals, result.Job.MissedDays, trend, lookback, cardURL, scheduledAt)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to send weekly summary")
+			return
+		}
+
+		// Save summary to database
+		err = saveWeeklySummary(ctx, coreService, user.ID, weekStart, result.Summary)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to save weekly summary")
+		} else if cardURL != "" {
+			if err := coreService.SetSummaryCard(ctx, user.ID, weekStart, cardURL); err != nil {
+				logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to save share card link")
+			}
+		}
+
+		fields := logrus.Fields{
+			"user_id":    user.ID,
+			"model":      result.Summary.Model,
+			"latency_ms": result.Duration.Milliseconds(),
+		}
+		if scheduledAt != nil {
+			fields["scheduled_at"] = scheduledAt.UTC().Format(time.RFC3339)
+		}
+		logrus.WithFields(fields).Info("Weekly summary sent")
+	})
+
+	return nil
+}
+
+// lastWeeklySummaryRun is the most recent Friday 16:30 UTC, matching the
+// weekly-summaries schedule, at or before now
+func lastWeeklySummaryRun(now time.Time) time.Time {
+	now = now.UTC()
+	run := time.Date(now.Year(), now.Month(), now.Day(), 16, 30, 0, 0, time.UTC)
+	run = run.AddDate(0, 0, -((int(run.Weekday()) - int(time.Friday) + 7) % 7))
+	if run.After(now) {
+		run = run.AddDate(0, 0, -7)
+	}
+	return run
+}
+
+// weeklySummaryJobs returns a summary job for each user with entries in the
+// week containing now, and a decision for every user saying why. A user on a
+// fiscal or sprint calendar is summarized on the first run after each of its
+// periods ends, over the whole period.
+func weeklySummaryJobs(ctx context.Context, coreService *core.Service, billingService *billing.Service, now time.Time) ([]llm.SummaryJob, []Decision, error) {
+	users, err := coreService.GetUsersForWeeklySummary(ctx, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	suspended, err := billingService.SuspendedUsers(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var jobs []llm.SummaryJob
+	var decisions []Decision
+	for _, user := range users {
+		if suspended[user.ID] {
+			decisions = append(decisions, Decision{User: user, Reason: "subscription suspended"})
+			continue
+		}
+
+		calendar, err := coreService.SummaryCalendar(ctx, user.ID)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to get summary calendar")
+			decisions = append(decisions, Decision{User: user, Reason: "failed to load calendar"})
+			continue
+		}
+
+		// Get entries for this week, honoring the user's week start
+		// preference, or for the calendar period just ended
+		weekStart := period.StartOfWeek(now, period.FirstWeekday(user.WeekStart))
+		weekEnd := period.SummaryEnd(weekStart)
+		to := weekStart.AddDate(0, 0, 7)
+		if !calendar.IsZero() {
+			var closing bool
+			weekStart, weekEnd, closing = calendar.Closing(now, 7*24*time.Hour)
+			if !closing {
+				_, end := calendar.Bounds(now)
+				decisions = append(decisions, Decision{User: user, Reason: "period ends " + end.Format("2006-01-02")})
+				continue
+			}
+			to = weekEnd.AddDate(0, 0, 1)
+		}
+		entries, err := coreService.GetEntriesBetween(ctx, user.ID, weekStart, to)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to get week entries")
+			decisions = append(decisions, Decision{User: user, Reason: "failed to load entries"})
+			continue
+		}
+		if unsealed := custody.Unsealed(entries); len(unsealed) < len(entries) {
+			if len(unsealed) == 0 {
+				decisions = append(decisions, Decision{User: user, Reason: "entries are sealed (self-custody)"})
+				continue
+			}
+			entries = unsealed
+		}
+
+		if len(entries) == 0 {
+			logrus.WithField("user_id", user.ID).Info("No entries for this week, skipping summary")
+			decisions = append(decisions, Decision{User: user, Reason: "no entries since " + weekStart.Format("2006-01-02")})
+			continue
+		}
+
+		previous, err := coreService.PriorSummaries(ctx, user, weekStart)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to load prior summaries, summarizing without comparison")
+		}
+
+		missed, err := coreService.MissedDays(ctx, user.ID, weekStart, weekEnd, now)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to find missed days, summarizing as a full week")
+		}
+
+		reason := fmt.Sprintf("%d entries since %s", len(entries), weekStart.Format("2006-01-02"))
+		if stats.IsLightWeek(missed) {
+			reason += fmt.Sprintf(", light week (%d days missed)", len(missed))
+		}
+		jobs = append(jobs, llm.SummaryJob{User: user, Entries: entries, Previous: previous, Start: weekStart, End: weekEnd, MissedDays: missed})
+		decisions = append(decisions, Decision{User: user, Send: true, Reason: reason})
+	}
+
+	return jobs, decisions, nil
+}
+
+// sendMentorDigests sends each confirmed mentor their mentee's digest for
+// month. Mentors already sent it are skipped, so the job can be re-run.
+func sendMentorDigests(ctx context.Context, coreService *core.Service, emailService *email.Service, month time.Time) error {
+	mentors, err := coreService.MentorsDueDigest(ctx, month)
+	if err != nil {
+		return err
+	}
+
+	monthEnd := month.AddDate(0, 1, -1)
+	for _, mentor := range mentors {
+		logger := logrus.WithFields(logrus.Fields{"user_id": mentor.UserID, "mentor_id": mentor.ID})
+
+		user, err := emailService.GetUserByID(ctx, mentor.UserID)
+		if err != nil {
+			logger.WithError(err).Error("Failed to load mentee")
+			continue
+		}
+		if user == nil || !user.IsVerified {
+			continue
+		}
+
+		summaries, err := coreService.GetWeeklySummariesBetween(ctx, user.ID, month, monthEnd)
+		if err != nil {
+			logger.WithError(err).Error("Failed to load summaries for mentor digest")
+			continue
+		}
+		if len(summaries) == 0 {
+			logger.Info("No summaries last month, skipping mentor digest")
+			continue
+		}
+
+		err = emailService.SendMentorDigest(ctx, user.ID, mentor.Email, user.Name, month, digest.Build(summaries))
+		if err != nil {
+			logger.WithError(err).Error("Failed to send mentor digest")
+			continue
+		}
+
+		if err := coreService.MarkMentorDigestSent(ctx, mentor.ID, month); err != nil {
+			logger.WithError(err).Error("Failed to record mentor digest")
+			continue
+		}
+
+		logger.Info("Mentor digest sent")
+	}
+
+	return nil
+}
+
+// sendProjectRollups sends each user a rollup of every project they tagged
+// enough entries with in the quarter starting at quarter. Rollups are saved
+// once sent, so the job can be re-run.
+func sendProjectRollups(ctx context.Context, coreService *core.Service, emailService *email.Service, quarter time.Time) error {
+	due, err := coreService.ProjectRollupsDue(ctx, quarter)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range due {
+		logger := logrus.WithFields(logrus.Fields{"user_id": d.UserID, "project": d.Project})
+
+		user, err := emailService.GetUserByID(ctx, d.UserID)
+		if err != nil {
+			logger.WithError(err).Error("Failed to load user")
+			continue
+		}
+		if user == nil {
+			continue
+		}
+
+		rollup, err := coreService.GenerateProjectRollup(ctx, user, d.Project, quarter)
+		if err != nil {
+			logger.WithError(err).WithField("error_code", apperrors.CodeOf(err)).Error("Failed to generate project rollup")
+			continue
+		}
+
+		if err := emailService.SendProjectRollup(ctx, user.ID, user.Email, rollup); err != nil {
+			logger.WithError(err).Error("Failed to send project rollup")
+			continue
+		}
+
+		if err := coreService.SaveProjectRollup(ctx, rollup); err != nil {
+			logger.WithError(err).Error("Failed to save project rollup")
+			continue
+		}
+
+		logger.WithField("model", rollup.LLMModel).Info("Project rollup sent")
+	}
+
+	return nil
+}
+
+// importSlackStandups logs the messages each linked user posted in their
+// standup channel since slackImportLookback before now. Each channel is read
+// once, however many of its members are linked.
+func importSlackStandups(ctx context.Context, coreService *core.Service, client *slack.Client, now time.Time) error {
+	links, err := coreService.ChannelImports(ctx, models.ChannelSlack)
+	if err != nil {
+		return err
+	}
+
+	linked := make(map[string]map[string]bool)
+	for _, link := range links {
+		if linked[*link.ImportChannel] == nil {
+			linked[*link.ImportChannel] = make(map[string]bool)
+		}
+		linked[*link.ImportChannel][*link.ExternalUserID] = true
+	}
+
+	imported, failed := 0, 0
+	for channel, users := range linked {
+		messages, err := client.History(ctx, channel, now.Add(-slackImportLookback))
+		if err != nil {
+			logrus.WithError(err).WithField("slack_channel", channel).Error("Failed to read Slack standup channel")
+			failed++
+			continue
+		}
+
+		for _, msg := range messages {
+			if !users[msg.User] || msg.Text == "" {
+				continue
+			}
+			err := coreService.ImportChannelMessage(ctx, models.ChannelSlack, msg.User, msg.ID(), msg.Text, msg.SentAt)
+			if apperrors.Is(err, apperrors.CodeConflict) {
+				continue
+			}
+			if err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{
+					"slack_user": msg.User,
+					"message_id": msg.ID(),
+					"error_code": apperrors.CodeOf(err),
+				}).Error("Failed to import Slack message")
+				failed++
+				continue
+			}
+			imported++
+		}
+	}
+
+	if imported > 0 {
+		logrus.WithField("count", imported).Info("Imported Slack standup messages")
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to import %d Slack channels or messages", failed)
+	}
+	return nil
+}
+
+func saveWeeklySummary(ctx context.Context, coreService *core.Service, userID int, weekStart time.Time, summary *llm.WeeklySummary) error {
+	return coreService.SaveWeeklySummary(ctx, userID, weekStart, summary.Paragraph,
+		summary.BulletPoints, summary.Model, summary.CostCents)
+}
@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/core"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/email"
+	pkgConfig "github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+)
+
+// TestWeeklySummaryJobs needs a disposable Postgres database, whose users
+// are emptied first:
+//
+//	TEST_DATABASE_URL=postgres://localhost/wdygdtw_test?sslmode=disable go test ./internal/jobs -run TestWeeklySummaryJobs
+func TestWeeklySummaryJobs(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	conn, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	db := &database.DB{DB: conn}
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+	if _, err := db.Exec(`TRUNCATE users RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wednesday; the entry is from Monday of the same week
+	now := time.Date(2024, 5, 8, 16, 30, 0, 0, time.UTC)
+	_, err = db.Exec(`
+		INSERT INTO users (email, name, timezone, verification_code, is_verified, signup_status, summary_voice, is_paused, pause_until)
+		VALUES ('alex@example.com', 'Alex', 'UTC', '123456', TRUE, 'active', 'coach', FALSE, NULL),
+		       ('sam@example.com', 'Sam', 'UTC', '123456', TRUE, 'active', 'first_person', TRUE, '2024-05-20'),
+		       ('new@example.com', 'New', 'UTC', '123456', FALSE, 'pending_confirmation', 'first_person', FALSE, NULL)`)
+	if err != nil {
+		t.Fatalf("failed to seed users: %v", err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO entries (user_id, entry_date, raw_content)
+		SELECT id, '2024-05-06', 'Shipped the importer' FROM users`)
+	if err != nil {
+		t.Fatalf("failed to seed entries: %v", err)
+	}
+
+	emailService, err := email.NewService(db, &pkgConfig.Config{AWSSESRegion: "us-east-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, decisions, err := weeklySummaryJobs(context.Background(), core.NewService(db, emailService), nil, now)
+	if err != nil {
+		t.Fatalf("weeklySummaryJobs() error = %v", err)
+	}
+
+	if len(decisions) != 1 {
+		t.Errorf("got %d decisions, want only the unpaused verified user's", len(decisions))
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(jobs))
+	}
+	job := jobs[0]
+	if job.User.Email != "alex@example.com" || job.User.SummaryVoice != "coach" || job.User.WeekStart == "" {
+		t.Errorf("job user = %+v", job.User)
+	}
+	if len(job.Entries) != 1 || !job.Start.Equal(time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("job covers %d entries from %s", len(job.Entries), job.Start)
+	}
+}
@@ -0,0 +1,326 @@
+// Package jobs names the scheduler's background jobs so they can be listed,
+// run by hand and disabled, and records every run in job_runs.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/go-co-op/gocron"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/jobqueue"
+)
+
+// What started a run
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+	TriggerQueue    = "queue"
+)
+
+// Run statuses
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Job is a named background task. Schedule is a standard five-field cron
+// expression in UTC.
+type Job struct {
+	Name        string
+	Description string
+	Schedule    string
+	Run         func(ctx context.Context) error
+}
+
+// JobRun is one recorded run of a job
+type JobRun struct {
+	ID           int        `json:"id"`
+	JobName      string     `json:"job_name"`
+	TriggeredBy  string     `json:"triggered_by"`
+	Status       string     `json:"status"`
+	ErrorMessage *string    `json:"error_message,omitempty"`
+	DurationMs   *int       `json:"duration_ms,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// JobStatus is a job with whether it is enabled and its latest run
+type JobStatus struct {
+	Job
+	Enabled bool
+	Reason  string // why the job is disabled, if it is
+	LastRun *JobRun
+}
+
+// Registry holds the registered jobs
+type Registry struct {
+	db       *database.DB
+	jobs     []*Job
+	byName   map[string]*Job
+	disabled map[string]bool
+}
+
+// NewRegistry returns an empty registry. Jobs named in disabled (JOBS_DISABLED)
+// never run on schedule, whatever `jobs enable` says.
+func NewRegistry(db *database.DB, disabled []string) *Registry {
+	r := &Registry{
+		db:       db,
+		byName:   map[string]*Job{},
+		disabled: map[string]bool{},
+	}
+	for _, name := range disabled {
+		r.disabled[name] = true
+	}
+	return r
+}
+
+// Register adds job. Names must be unique.
+func (r *Registry) Register(job Job) {
+	if _, exists := r.byName[job.Name]; exists {
+		panic(fmt.Sprintf("jobs: %s registered twice", job.Name))
+	}
+	r.jobs = append(r.jobs, &job)
+	r.byName[job.Name] = &job
+}
+
+// SetSchedules replaces the schedule of each job schedules names
+// (JOB_SCHEDULES), so staging can run weekly jobs hourly. Unknown jobs and
+// unparsable schedules are an error.
+func (r *Registry) SetSchedules(schedules map[string]string) error {
+	for name, schedule := range schedules {
+		job, err := r.Get(name)
+		if err != nil {
+			return err
+		}
+		if _, err := cron.ParseStandard(schedule); err != nil {
+			return fmt.Errorf("invalid schedule for job %s: %w", name, err)
+		}
+		job.Schedule = schedule
+	}
+	return nil
+}
+
+// Get returns the job called name
+func (r *Registry) Get(name string) (*Job, error) {
+	job, ok := r.byName[name]
+	if !ok {
+		return nil, apperrors.New(apperrors.CodeNotFound, "unknown job: %s", name)
+	}
+	return job, nil
+}
+
+// Schedule adds every job to s. Each tick checks whether the job is enabled,
+// so `jobs disable` takes effect without restarting the scheduler.
+func (r *Registry) Schedule(s *gocron.Scheduler) error {
+	for _, job := range r.jobs {
+		name := job.Name
+		_, err := s.Cron(job.Schedule).Do(func() {
+			if err := r.Run(context.Background(), name, TriggerSchedule); err != nil {
+				logrus.WithError(err).WithField("job", name).Error("Job failed")
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to schedule job %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Listen runs the job called name each time q signals topic, until ctx is
+// done. Signals that arrive during a run merge into one run after it.
+func (r *Registry) Listen(ctx context.Context, q jobqueue.Queue, topic, name string) error {
+	if _, err := r.Get(name); err != nil {
+		return err
+	}
+
+	wakeups, err := q.Listen(ctx, topic)
+	if err != nil {
+		return err
+	}
+	for range wakeups {
+		if err := r.Run(ctx, name, TriggerQueue); err != nil && ctx.Err() == nil {
+			logrus.WithError(err).WithField("job", name).Error("Job failed")
+		}
+	}
+	return nil
+}
+
+// Run runs the job called name and records the run. Scheduled and queued
+// runs of a disabled job are skipped; manual runs always go ahead. A failure to record
+// history is logged but doesn't stop the job.
+func (r *Registry) Run(ctx context.Context, name, trigger string) error {
+	job, err := r.Get(name)
+	if err != nil {
+		return err
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"job": name, "trigger": trigger})
+
+	if trigger != TriggerManual {
+		enabled, _, err := r.enabled(ctx, name)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to check whether job is enabled, running it")
+		} else if !enabled {
+			logger.Debug("Job disabled, skipping")
+			return nil
+		}
+	}
+
+	runID, err := r.startRun(ctx, name, trigger)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to record job start")
+	}
+
+	start := time.Now()
+	runErr := job.Run(ctx)
+	duration := time.Since(start)
+
+	if runID != 0 {
+		if err := r.finishRun(ctx, runID, duration, runErr); err != nil {
+			logger.WithError(err).Warn("Failed to record job result")
+		}
+	}
+
+	logger.WithField("duration_ms", duration.Milliseconds()).Debug("Job finished")
+	return runErr
+}
+
+func (r *Registry) startRun(ctx context.Context, name, trigger string) (int, error) {
+	query := `INSERT INTO job_runs (job_name, triggered_by) VALUES ($1, $2) RETURNING id`
+
+	var id int
+	if err := r.db.QueryRowContext(ctx, query, name, trigger).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert job run: %w", err)
+	}
+	return id, nil
+}
+
+func (r *Registry) finishRun(ctx context.Context, runID int, duration time.Duration, runErr error) error {
+	query := `
+		UPDATE job_runs
+		SET status = $2, error_message = $3, duration_ms = $4, finished_at = NOW()
+		WHERE id = $1`
+
+	status := StatusSucceeded
+	var errorMessage *string
+	if runErr != nil {
+		status = StatusFailed
+		message := runErr.Error()
+		errorMessage = &message
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, runID, status, errorMessage, duration.Milliseconds()); err != nil {
+		return fmt.Errorf("failed to update job run: %w", err)
+	}
+	return nil
+}
+
+// enabled reports whether name runs on schedule, and if not, why
+func (r *Registry) enabled(ctx context.Context, name string) (bool, string, error) {
+	if r.disabled[name] {
+		return false, "JOBS_DISABLED", nil
+	}
+
+	var enabled bool
+	err := r.db.QueryRowContext(ctx, `SELECT enabled FROM job_settings WHERE job_name = $1`, name).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return true, "", nil
+	}
+	if err != nil {
+		return true, "", fmt.Errorf("failed to read job settings: %w", err)
+	}
+	if !enabled {
+		return false, "disabled from the CLI", nil
+	}
+	return true, "", nil
+}
+
+// SetEnabled enables or disables scheduled runs of name. It can't override
+// JOBS_DISABLED.
+func (r *Registry) SetEnabled(ctx context.Context, name string, enabled bool) error {
+	if _, err := r.Get(name); err != nil {
+		return err
+	}
+	if enabled && r.disabled[name] {
+		return apperrors.New(apperrors.CodeConflict, "%s is disabled by JOBS_DISABLED; remove it there instead", name)
+	}
+
+	query := `
+		INSERT INTO job_settings (job_name, enabled, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (job_name) DO UPDATE SET enabled = $2, updated_at = NOW()`
+
+	if _, err := r.db.ExecContext(ctx, query, name, enabled); err != nil {
+		return fmt.Errorf("failed to update job settings: %w", err)
+	}
+	return nil
+}
+
+// List returns every job, in registration order, with its status
+func (r *Registry) List(ctx context.Context) ([]JobStatus, error) {
+	statuses := make([]JobStatus, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		enabled, reason, err := r.enabled(ctx, job.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		runs, err := r.History(ctx, job.Name, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		status := JobStatus{Job: *job, Enabled: enabled, Reason: reason}
+		if len(runs) > 0 {
+			status.LastRun = runs[0]
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// History returns up to limit of name's most recent runs, newest first
+func (r *Registry) History(ctx context.Context, name string, limit int) ([]*JobRun, error) {
+	query := `
+		SELECT id, job_name, triggered_by, status, error_message, duration_ms, started_at, finished_at
+		FROM job_runs
+		WHERE job_name = $1
+		ORDER BY started_at DESC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*JobRun
+	for rows.Next() {
+		var run JobRun
+		err := rows.Scan(&run.ID, &run.JobName, &run.TriggeredBy, &run.Status, &run.ErrorMessage,
+			&run.DurationMs, &run.StartedAt, &run.FinishedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job run: %w", err)
+		}
+		runs = append(runs, &run)
+	}
+	return runs, rows.Err()
+}
+
+// PruneHistory deletes runs that started more than retention ago
+func (r *Registry) PruneHistory(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoff := time.Now().UTC().Add(-retention)
+	result, err := r.db.ExecContext(ctx, `DELETE FROM job_runs WHERE started_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune job runs: %w", err)
+	}
+	return result.RowsAffected()
+}
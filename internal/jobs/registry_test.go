@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSetSchedules(t *testing.T) {
+	newRegistry := func() *Registry {
+		r := NewRegistry(nil, nil)
+		r.Register(Job{Name: "weekly-summaries", Schedule: "30 16 * * 5", Run: func(context.Context) error { return nil }})
+		return r
+	}
+
+	r := newRegistry()
+	if err := r.SetSchedules(map[string]string{"weekly-summaries": "30 * * * *"}); err != nil {
+		t.Fatalf("SetSchedules() error = %v", err)
+	}
+	if job, _ := r.Get("weekly-summaries"); job.Schedule != "30 * * * *" {
+		t.Errorf("schedule = %q, want 30 * * * *", job.Schedule)
+	}
+
+	for name, schedules := range map[string]map[string]string{
+		"unknown job":      {"nightly": "0 2 * * *"},
+		"invalid schedule": {"weekly-summaries": "every friday"},
+	} {
+		if err := newRegistry().SetSchedules(schedules); err == nil {
+			t.Errorf("%s: SetSchedules() succeeded", name)
+		}
+	}
+}
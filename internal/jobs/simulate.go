@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Decision is whether a user would be sent something, and why
+type Decision struct {
+	User   *models.User
+	Send   bool
+	Reason string
+}
+
+// Simulation is what the scheduler would do at an instant
+type Simulation struct {
+	At time.Time
+	// Jobs are the jobs whose schedule fires in At's minute
+	Jobs []JobStatus
+	// Prompts and Summaries are only set when their job is among Jobs
+	Prompts   []Decision
+	Summaries []Decision
+}
+
+// Simulate reports which jobs would run at at and who they would send daily
+// prompts and weekly summaries to, without running anything. It reads the
+// current database, so entries, pauses and days off are as they are now.
+func Simulate(ctx context.Context, r *Registry, svc Services, at time.Time) (*Simulation, error) {
+	at = at.UTC().Truncate(time.Minute)
+	sim := &Simulation{At: at}
+
+	for _, job := range r.jobs {
+		// Schedules are in UTC, as gocron runs them
+		schedule, err := cron.ParseStandard("CRON_TZ=UTC " + job.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse schedule of job %s: %w", job.Name, err)
+		}
+		if !schedule.Next(at.Add(-time.Second)).Equal(at) {
+			continue
+		}
+
+		enabled, reason, err := r.enabled(ctx, job.Name)
+		if err != nil {
+			return nil, err
+		}
+		sim.Jobs = append(sim.Jobs, JobStatus{Job: *job, Enabled: enabled, Reason: reason})
+	}
+
+	for _, job := range sim.Jobs {
+		var err error
+		switch job.Name {
+		case "daily-prompts":
+			sim.Prompts, err = simulatePrompts(ctx, svc, at)
+		case "weekly-summaries":
+			_, sim.Summaries, err = weeklySummaryJobs(ctx, svc.Core, svc.Billing, at)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sim, nil
+}
+
+// simulatePrompts is promptDecisions plus the already-sent check that
+// SendDailyPrompt would make
+func simulatePrompts(ctx context.Context, svc Services, at time.Time) ([]Decision, error) {
+	decisions, err := promptDecisions(ctx, svc.Core, svc.Billing, at)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, d := range decisions {
+		if !d.Send {
+			continue
+		}
+		sent, err := svc.Core.PromptSent(ctx, d.User, at)
+		if err != nil {
+			return nil, err
+		}
+		if sent {
+			decisions[i].Send = false
+			decisions[i].Reason = "already sent today"
+		}
+	}
+	return decisions, nil
+}
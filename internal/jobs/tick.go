@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// Tick runs, once, every job whose schedule fired since the last tick, for
+// deployments where the scheduler is a short-lived invocation (an
+// EventBridge rule or a cron'd task) rather than a long-running process.
+// When each job last fired is kept in job_schedule, so ticks can come every
+// minute or every few minutes, overlapping ticks don't run a job twice, and
+// several missed firings run the job once. A firing more than maxLateness
+// old is skipped rather than run late. Jobs run one after another; a failed
+// job doesn't stop the rest.
+func (r *Registry) Tick(ctx context.Context, now time.Time, maxLateness time.Duration) error {
+	now = now.UTC()
+	var errs []error
+
+	for _, job := range r.jobs {
+		due, err := r.claimFiring(ctx, job, now, maxLateness)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		if err := r.Run(ctx, job.Name, TriggerSchedule); err != nil {
+			logrus.WithError(err).WithField("job", job.Name).Error("Job failed")
+			errs = append(errs, fmt.Errorf("job %s: %w", job.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// claimFiring reports whether job is due at now and, if so, records the
+// firing so no other tick runs it
+func (r *Registry) claimFiring(ctx context.Context, job *Job, now time.Time, maxLateness time.Duration) (bool, error) {
+	// Schedules are in UTC, as gocron runs them
+	schedule, err := cron.ParseStandard("CRON_TZ=UTC " + job.Schedule)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse schedule of job %s: %w", job.Name, err)
+	}
+
+	var last time.Time
+	err = r.db.QueryRowContext(ctx, `SELECT last_fired_at FROM job_schedule WHERE job_name = $1`, job.Name).Scan(&last)
+	if err == sql.ErrNoRows {
+		// A job's first tick only runs it if it fires this minute
+		last = now.Truncate(time.Minute).Add(-time.Nanosecond)
+	} else if err != nil {
+		return false, fmt.Errorf("failed to read schedule of job %s: %w", job.Name, err)
+	}
+
+	fired, missed, ok := latestFiring(schedule, last, now, maxLateness)
+	if missed {
+		logrus.WithFields(logrus.Fields{
+			"job":        job.Name,
+			"last_fired": last.Format(time.RFC3339),
+		}).Warn("Skipping firings more than SCHEDULER_MAX_LATENESS late")
+	}
+	if !ok {
+		return false, nil
+	}
+
+	query := `
+		INSERT INTO job_schedule (job_name, last_fired_at) VALUES ($1, $2)
+		ON CONFLICT (job_name) DO UPDATE SET last_fired_at = EXCLUDED.last_fired_at, updated_at = NOW()
+		WHERE job_schedule.last_fired_at < EXCLUDED.last_fired_at`
+
+	result, err := r.db.ExecContext(ctx, query, job.Name, fired)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim firing of job %s: %w", job.Name, err)
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim firing of job %s: %w", job.Name, err)
+	}
+	return claimed == 1, nil
+}
+
+// latestFiring returns schedule's latest firing after last and at or before
+// now. Firings more than maxLateness before now don't count; missed reports
+// whether any were skipped for that.
+func latestFiring(schedule cron.Schedule, last, now time.Time, maxLateness time.Duration) (fired time.Time, missed, ok bool) {
+	if earliest := now.Add(-maxLateness).Add(-time.Nanosecond); maxLateness > 0 && last.Before(earliest) {
+		missed = !schedule.Next(last).After(earliest)
+		last = earliest
+	}
+
+	for next := schedule.Next(last); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		fired, ok = next, true
+	}
+	return fired, missed, ok
+}
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestLatestFiring(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name       string
+		schedule   string
+		last       string
+		now        string
+		wantFired  string
+		wantMissed bool
+	}{
+		{"due this minute", "0 * * * *", "2024-05-03T15:00:00Z", "2024-05-03T16:00:20Z", "2024-05-03T16:00:00Z", false},
+		{"not due yet", "0 * * * *", "2024-05-03T16:00:00Z", "2024-05-03T16:59:59Z", "", false},
+		{"already fired", "*/5 * * * *", "2024-05-03T16:05:00Z", "2024-05-03T16:05:40Z", "", false},
+		{"late tick", "30 16 * * 5", "2024-04-26T16:30:00Z", "2024-05-03T16:42:00Z", "2024-05-03T16:30:00Z", false},
+		{"several missed run once", "*/5 * * * *", "2024-05-03T16:00:00Z", "2024-05-03T16:17:00Z", "2024-05-03T16:15:00Z", false},
+		{"too late", "30 16 * * 5", "2024-04-26T16:30:00Z", "2024-05-03T18:00:00Z", "", true},
+		{"recent kept after an outage", "0 * * * *", "2024-05-01T00:00:00Z", "2024-05-03T16:10:00Z", "2024-05-03T16:00:00Z", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := cron.ParseStandard("CRON_TZ=UTC " + tt.schedule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fired, missed, ok := latestFiring(schedule, at(tt.last), at(tt.now), time.Hour)
+			if ok != (tt.wantFired != "") {
+				t.Fatalf("ok = %v, want fired %q", ok, tt.wantFired)
+			}
+			if ok && !fired.Equal(at(tt.wantFired)) {
+				t.Errorf("fired = %s, want %s", fired, tt.wantFired)
+			}
+			if missed != tt.wantMissed {
+				t.Errorf("missed = %v, want %v", missed, tt.wantMissed)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+// Package language lists the languages a user can reply in and the words
+// each uses for the reply keywords, so "pausa 2 semanas" pauses prompts for
+// a Spanish speaker just as <pause>2 weeks</pause> does. It also tells which
+// of them a piece of text is written in, so summaries can match entries.
+package language
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Language codes stored in users.language
+const (
+	English    = "en"
+	Spanish    = "es"
+	French     = "fr"
+	German     = "de"
+	Portuguese = "pt"
+)
+
+// Language is one supported reply language
+type Language struct {
+	Code string
+	// Name is the language's name in itself, as shown to users
+	Name string
+	// EnglishName is how prompts to the model name it
+	EnglishName string
+	// Pause and Project are the keywords that start a pause or project line
+	Pause   []string
+	Project []string
+	// Units maps day, week and month words to "day", "week" and "month"
+	Units map[string]string
+	// Numbers maps the number words a duration may use to their value
+	Numbers map[string]int
+	// stopwords are common words that mark text as written in the language
+	stopwords []string
+	// names are what Parse accepts besides the code
+	names []string
+}
+
+var languages = map[string]Language{
+	English: {
+		Code:        English,
+		Name:        "English",
+		EnglishName: "English",
+		stopwords: []string{
+			"the", "and", "with", "for", "was", "were", "that", "this", "to", "of",
+			"is", "on", "we", "my", "our", "from", "it", "had", "have",
+		},
+		names: []string{"english"},
+	},
+	Spanish: {
+		Code:        Spanish,
+		Name:        "Español",
+		EnglishName: "Spanish",
+		Pause:       []string{"pausa", "pausar"},
+		Project:     []string{"proyecto"},
+		Units: map[string]string{
+			"día": "day", "dia": "day", "días": "day", "dias": "day",
+			"semana": "week", "semanas": "week",
+			"mes": "month", "meses": "month",
+		},
+		Numbers: map[string]int{
+			"un": 1, "una": 1, "uno": 1, "dos": 2, "tres": 3, "cuatro": 4, "cinco": 5,
+			"seis": 6, "siete": 7, "ocho": 8, "nueve": 9, "diez": 10,
+		},
+		stopwords: []string{
+			"el", "la", "los", "las", "y", "con", "para", "por", "que", "del",
+			"una", "en", "se", "al", "mi", "fue", "hice", "está", "pero", "muy",
+		},
+		names: []string{"spanish", "español", "espanol", "castellano"},
+	},
+	French: {
+		Code:        French,
+		Name:        "Français",
+		EnglishName: "French",
+		Pause:       []string{"pause"},
+		Project:     []string{"projet"},
+		Units: map[string]string{
+			"jour": "day", "jours": "day",
+			"semaine": "week", "semaines": "week",
+			"mois": "month",
+		},
+		Numbers: map[string]int{
+			"un": 1, "une": 1, "deux": 2, "trois": 3, "quatre": 4, "cinq": 5,
+			"six": 6, "sept": 7, "huit": 8, "neuf": 9, "dix": 10,
+		},
+		stopwords: []string{
+			"le", "la", "les", "et", "avec", "pour", "des", "du", "une", "est",
+			"sur", "dans", "au", "aux", "pas", "nous", "je", "mon", "été", "fait",
+		},
+		names: []string{"french", "français", "francais"},
+	},
+	German: {
+		Code:        German,
+		Name:        "Deutsch",
+		EnglishName: "German",
+		Pause:       []string{"pause", "pausieren"},
+		Project:     []string{"projekt"},
+		Units: map[string]string{
+			"tag": "day", "tage": "day", "tagen": "day",
+			"woche": "week", "wochen": "week",
+			"monat": "month", "monate": "month", "monaten": "month",
+		},
+		Numbers: map[string]int{
+			"ein": 1, "eine": 1, "einen": 1, "einer": 1, "zwei": 2, "drei": 3, "vier": 4,
+			"fünf": 5, "fuenf": 5, "sechs": 6, "sieben": 7, "acht": 8, "neun": 9, "zehn": 10,
+		},
+		stopwords: []string{
+			"der", "die", "das", "und", "mit", "für", "ist", "ein", "eine", "den",
+			"dem", "auf", "zu", "ich", "wir", "nicht", "von", "habe", "wurde", "im",
+		},
+		names: []string{"german", "deutsch"},
+	},
+	Portuguese: {
+		Code:        Portuguese,
+		Name:        "Português",
+		EnglishName: "Portuguese",
+		Pause:       []string{"pausa", "pausar"},
+		Project:     []string{"projeto", "projecto"},
+		Units: map[string]string{
+			"dia": "day", "dias": "day",
+			"semana": "week", "semanas": "week",
+			"mês": "month", "mes": "month", "meses": "month",
+		},
+		Numbers: map[string]int{
+			"um": 1, "uma": 1, "dois": 2, "duas": 2, "três": 3, "tres": 3, "quatro": 4,
+			"cinco": 5, "seis": 6, "sete": 7, "oito": 8, "nove": 9, "dez": 10,
+		},
+		stopwords: []string{
+			"o", "os", "as", "e", "com", "para", "do", "da", "dos", "das",
+			"no", "na", "em", "um", "uma", "foi", "não", "meu", "fiz", "mas",
+		},
+		names: []string{"portuguese", "português", "portugues"},
+	},
+}
+
+// All returns the supported languages, English first and the rest by code
+func All() []Language {
+	codes := make([]string, 0, len(languages))
+	for code := range languages {
+		if code != English {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+
+	all := []Language{languages[English]}
+	for _, code := range codes {
+		all = append(all, languages[code])
+	}
+	return all
+}
+
+// Parse accepts a language code or name, in English or in the language
+// itself, and returns the code
+func Parse(value string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(value))
+	for code, lang := range languages {
+		if name == code || strings.EqualFold(name, lang.Name) {
+			return code, nil
+		}
+		for _, alias := range lang.names {
+			if name == alias {
+				return code, nil
+			}
+		}
+	}
+
+	codes := make([]string, 0, len(languages))
+	for _, lang := range All() {
+		codes = append(codes, lang.Code)
+	}
+	return "", fmt.Errorf("unsupported language: %s (expected one of %s)", value, strings.Join(codes, ", "))
+}
+
+// Lookup returns the language with code. Unknown codes report false.
+func Lookup(code string) (Language, bool) {
+	lang, ok := languages[strings.ToLower(code)]
+	return lang, ok
+}
+
+// minDetectHits is how many stopwords text needs before Detect names its
+// language, so a few words or a list of names aren't guessed at
+const minDetectHits = 3
+
+var wordRegex = regexp.MustCompile(`\pL+`)
+
+// Detect returns the language text is most likely written in, by counting
+// each language's stopwords. Text that is too short, or that doesn't lean
+// clearly to one language, reports false.
+func Detect(text string) (string, bool) {
+	counts := make(map[string]int)
+	for _, word := range wordRegex.FindAllString(strings.ToLower(text), -1) {
+		counts[word]++
+	}
+
+	best, bestHits, runnerUp := "", 0, 0
+	for _, lang := range All() {
+		hits := 0
+		for _, word := range lang.stopwords {
+			hits += counts[word]
+		}
+		if hits > bestHits {
+			best, bestHits, runnerUp = lang.Code, hits, bestHits
+		} else if hits > runnerUp {
+			runnerUp = hits
+		}
+	}
+
+	if bestHits < minDetectHits || bestHits == runnerUp {
+		return "", false
+	}
+	return best, true
+}
@@ -0,0 +1,29 @@
+package language
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{"english", "Shipped the billing migration and fixed the flaky tests with the QA team", English, true},
+		{"spanish", "Terminé la migración de facturación y arreglé las pruebas con el equipo de QA", Spanish, true},
+		{"french", "J'ai terminé la migration de la facturation et corrigé les tests avec l'équipe", French, true},
+		{"german", "Ich habe die Migration der Abrechnung abgeschlossen und die Tests mit dem Team repariert", German, true},
+		{"portuguese", "Terminei a migração do faturamento e corrigi os testes com a equipe de QA", Portuguese, true},
+		{"too short", "Apollo, Gemini", "", false},
+		{"empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Detect(tt.text)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Detect(%q) = %q, %v, want %q, %v", tt.text, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
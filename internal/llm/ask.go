@@ -0,0 +1,157 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// askDayLayout labels entries in the prompt; answers cite dates in the same form
+const askDayLayout = "2006-01-02"
+
+// citationRegex matches a date cited as [YYYY-MM-DD]
+var citationRegex = regexp.MustCompile(`\[(\d{4}-\d{2}-\d{2})\]`)
+
+// Answer is a reply to a question about the user's journal. Citations are
+// the dates of the entries the answer cites, oldest first, limited to entries
+// the model was actually given.
+type Answer struct {
+	Text      string
+	Citations []time.Time
+	Model     string
+	CostCents int
+}
+
+// AnswerQuestion answers question from entries alone, the most relevant
+// first, trimmed to LLM_MAX_INPUT_TOKENS. It walks the same model chain as
+// summaries; the template fallback lists the matching entries instead.
+func (s *Service) AnswerQuestion(ctx context.Context, question string, entries []*models.Entry) (*Answer, error) {
+	ctx = withEntriesUser(ctx, entries)
+	entries = entriesWithinBudget(entries, s.config.LLMMaxInputTokens)
+	prompt := buildAskPrompt(question, entries)
+	chain := s.modelChain()
+
+	var lastErr error
+	for attempt, modelID := range chain {
+		logger := logrus.WithFields(logrus.Fields{
+			"entries_count": len(entries),
+			"model":         modelID,
+			"attempt":       attempt + 1,
+			"chain_length":  len(chain),
+		})
+
+		if modelID == TemplateModel {
+			logger.Warn("Falling back to template-only answer")
+			return templateAnswer(entries), nil
+		}
+
+		response, err := s.callClaude(ctx, modelID, prompt)
+		if err == nil && len(response.Content) > 0 {
+			text := strings.TrimSpace(response.Content[0].Text)
+			return &Answer{
+				Text:      text,
+				Citations: citedDates(text, entries),
+				Model:     modelID,
+				CostCents: s.estimateCost(modelID, response.Usage),
+			}, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("empty response from model")
+		}
+
+		lastErr = err
+		logger.WithError(err).WithField("error_code", apperrors.CodeOf(err)).Warn("Answer attempt failed")
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, fmt.Errorf("all %d answer models failed: %w", len(chain), lastErr)
+}
+
+// entriesWithinBudget keeps entries, in order, while their formatted text
+// fits budget tokens. A budget of 0 keeps them all; the first entry is
+// always kept.
+func entriesWithinBudget(entries []*models.Entry, budget int) []*models.Entry {
+	if budget <= 0 {
+		return entries
+	}
+
+	used := 0
+	for i, entry := range entries {
+		used += estimateTokens(formatEntry(entry, askDayLayout))
+		if used > budget && i > 0 {
+			return entries[:i]
+		}
+	}
+	return entries
+}
+
+func buildAskPrompt(question string, entries []*models.Entry) string {
+	sorted := make([]*models.Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].EntryDate.Before(sorted[j].EntryDate)
+	})
+
+	return fmt.Sprintf(`You are answering a question about a user's work journal. Below are the journal entries that best match the question, one per day.
+
+Answer the question using only these entries. The answer should:
+- Be a few sentences, addressed to the user ("You last worked on...")
+- Cite the date of every entry it relies on in square brackets, like [2024-05-02]
+- Say plainly if the entries don't answer the question, rather than guessing
+
+Journal entries:
+%s
+Question: %s`, formatEntries(sorted, askDayLayout), question)
+}
+
+// citedDates returns the dates cited in text that belong to one of entries
+func citedDates(text string, entries []*models.Entry) []time.Time {
+	known := map[string]time.Time{}
+	for _, entry := range entries {
+		known[entry.EntryDate.Format(askDayLayout)] = entry.EntryDate
+	}
+
+	seen := map[string]bool{}
+	var dates []time.Time
+	for _, match := range citationRegex.FindAllStringSubmatch(text, -1) {
+		date, ok := known[match[1]]
+		if !ok || seen[match[1]] {
+			continue
+		}
+		seen[match[1]] = true
+		dates = append(dates, date)
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// templateAnswer lists the matching entries when no model is available
+func templateAnswer(entries []*models.Entry) *Answer {
+	var b strings.Builder
+	b.WriteString("I couldn't write an answer right now, but these entries match your question:\n")
+
+	var dates []time.Time
+	for _, entry := range entries {
+		excerpt := strings.Join(strings.Fields(entry.RawContent), " ")
+		if len(excerpt) > 120 {
+			excerpt = excerpt[:117] + "..."
+		}
+		b.WriteString(fmt.Sprintf("\n[%s] %s", entry.EntryDate.Format(askDayLayout), excerpt))
+		dates = append(dates, entry.EntryDate)
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return &Answer{Text: b.String(), Citations: dates, Model: TemplateModel}
+}
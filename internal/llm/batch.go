@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/stats"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// SummaryJob is a single user's weekly summary request. Previous holds the
+// user's earlier summaries for comparison, newest first. Start and End are
+// the first and last days the summary covers; a period longer than a week,
+// such as a sprint, is summarized as a range.
+type SummaryJob struct {
+	User     *models.User
+	Entries  []*models.Entry
+	Previous []*models.WeeklySummary
+	Start    time.Time
+	End      time.Time
+	// MissedDays are the days in the period without an entry. A light week
+	// gets a gentler summary that notes the gap.
+	MissedDays []time.Time
+}
+
+// GenerateSummary runs a single job through the model chain
+func (s *Service) GenerateSummary(ctx context.Context, job SummaryJob) (*WeeklySummary, error) {
+	firstDay := period.FirstWeekday(job.User.WeekStart)
+	scope, priorText := weeklyScope, priorWeeksText(job.Previous)
+	fields := logrus.Fields{"prior_weeks": len(job.Previous)}
+	if job.End.Sub(job.Start) >= 7*24*time.Hour {
+		scope, priorText = rangeScope(job.Start, job.End), ""
+		fields = logrus.Fields{"from": job.Start.Format("2006-01-02"), "to": job.End.Format("2006-01-02")}
+	}
+	if stats.IsLightWeek(job.MissedDays) {
+		scope.missedDays = job.MissedDays
+		fields["missed_days"] = len(job.MissedDays)
+	}
+	return s.summarizeEntries(ctx, job.Entries, job.User.SummaryVoice, job.User.SummaryLanguage, firstDay, scope, priorText, fields)
+}
+
+// SummaryResult is the outcome of a SummaryJob
+type SummaryResult struct {
+	Job      SummaryJob
+	Summary  *WeeklySummary
+	Err      error
+	Duration time.Duration
+}
+
+// BatchStats aggregates timing metrics for a batch run
+type BatchStats struct {
+	Total        int
+	Succeeded    int
+	Failed       int
+	Elapsed      time.Duration
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+}
+
+// AverageLatency returns the mean per-job LLM latency
+func (b BatchStats) AverageLatency() time.Duration {
+	if b.Total == 0 {
+		return 0
+	}
+	return b.TotalLatency / time.Duration(b.Total)
+}
+
+// GenerateWeeklySummaries runs the jobs through a bounded worker pool sized by
+// LLM_CONCURRENCY. handle is called sequentially for each result as it completes.
+func (s *Service) GenerateWeeklySummaries(ctx context.Context, jobs []SummaryJob, handle func(SummaryResult)) BatchStats {
+	concurrency := s.config.LLMConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	started := time.Now()
+	jobsCh := make(chan SummaryJob)
+	resultsCh := make(chan SummaryResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobsCh {
+				jobStarted := time.Now()
+				summary, err := s.GenerateSummary(ctx, job)
+				resultsCh <- SummaryResult{
+					Job:      job,
+					Summary:  summary,
+					Err:      err,
+					Duration: time.Since(jobStarted),
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobsCh)
+		for _, job := range jobs {
+			select {
+			case <-ctx.Done():
+				return
+			case jobsCh <- job:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(resultsCh)
+	}()
+
+	var stats BatchStats
+	for result := range resultsCh {
+		stats.Total++
+		stats.TotalLatency += result.Duration
+		if result.Duration > stats.MaxLatency {
+			stats.MaxLatency = result.Duration
+		}
+		if result.Err != nil {
+			stats.Failed++
+		} else {
+			stats.Succeeded++
+		}
+
+		handle(result)
+	}
+	stats.Elapsed = time.Since(started)
+
+	logrus.WithFields(logrus.Fields{
+		"provider":       s.config.LLMProvider,
+		"concurrency":    concurrency,
+		"jobs":           len(jobs),
+		"succeeded":      stats.Succeeded,
+		"failed":         stats.Failed,
+		"skipped":        len(jobs) - stats.Total,
+		"elapsed_ms":     stats.Elapsed.Milliseconds(),
+		"avg_latency_ms": stats.AverageLatency().Milliseconds(),
+		"max_latency_ms": stats.MaxLatency.Milliseconds(),
+	}).Info("Weekly summary batch completed")
+
+	return stats
+}
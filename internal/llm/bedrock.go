@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	pkgConfig "github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+)
+
+// bedrockProvider calls Claude models on AWS Bedrock, streaming the
+// response when LLM_STREAMING is set
+type bedrockProvider struct {
+	client    *bedrockruntime.Client
+	streaming bool
+}
+
+func newBedrockProvider(ctx context.Context, cfg *pkgConfig.Config) (*bedrockProvider, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.AWSRegion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &bedrockProvider{
+		client:    bedrockruntime.NewFromConfig(awsCfg),
+		streaming: cfg.LLMStreaming,
+	}, nil
+}
+
+func (p *bedrockProvider) Invoke(ctx context.Context, modelID, prompt string, maxTokens int) (*ClaudeResponse, error) {
+	request := ClaudeRequest{
+		AnthropicVersion: "bedrock-2023-05-31",
+		MaxTokens:        maxTokens,
+		Messages: []Message{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+	}
+
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	if p.streaming {
+		return p.callClaudeStream(ctx, modelID, requestBody)
+	}
+
+	input := &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(modelID),
+		ContentType: aws.String("application/json"),
+		Body:        requestBody,
+	}
+
+	result, err := p.client.InvokeModel(ctx, input)
+	if err != nil {
+		return nil, classifyInvokeError(err, "failed to invoke model")
+	}
+
+	var response ClaudeResponse
+	if err := json.Unmarshal(result.Body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &response, nil
+}
+
+// EstimateCost uses Claude Haiku's price, the cheapest model:
+// ~$0.25 per 1M input tokens and ~$1.25 per 1M output tokens
+func (p *bedrockProvider) EstimateCost(modelID string, usage Usage) int {
+	return centsFor(usage, 25, 125)
+}
+
+// classifyInvokeError tags throttling responses so callers can back off
+func classifyInvokeError(err error, message string) error {
+	var throttled *types.ThrottlingException
+	var quotaExceeded *types.ServiceQuotaExceededException
+	if errors.As(err, &throttled) || errors.As(err, &quotaExceeded) {
+		return apperrors.Wrap(apperrors.CodeLLMThrottled, err, message)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+type userKey struct{}
+
+// WithUser attributes the model calls made with ctx to userID, so their
+// cost is metered to the user's organization
+func WithUser(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// withEntriesUser attributes calls to the user entries belong to, unless
+// ctx already names one
+func withEntriesUser(ctx context.Context, entries []*models.Entry) context.Context {
+	if _, ok := ctx.Value(userKey{}).(int); ok || len(entries) == 0 {
+		return ctx
+	}
+	return WithUser(ctx, entries[0].UserID)
+}
+
+// SetCallLog records every model call in llm_calls, which the llm_calls
+// retention policy prunes. Without it calls are only logged.
+func (s *Service) SetCallLog(db *database.DB) {
+	s.callLog = db
+}
+
+// logCall records one model call. A failure to record is logged and never
+// fails the call itself.
+func (s *Service) logCall(ctx context.Context, modelID string, duration time.Duration, response *ClaudeResponse, callErr error) {
+	if s.callLog == nil {
+		return
+	}
+
+	var usage Usage
+	costCents := 0
+	if response != nil {
+		usage = response.Usage
+		costCents = s.estimateCost(modelID, usage)
+	}
+	var userID *int
+	if id, ok := ctx.Value(userKey{}).(int); ok {
+		userID = &id
+	}
+	errorMessage := ""
+	if callErr != nil {
+		errorMessage = callErr.Error()
+	}
+
+	query := `
+		INSERT INTO llm_calls (model, input_tokens, output_tokens, duration_ms, error, cost_cents, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := s.callLog.ExecContext(ctx, query, modelID, usage.InputTokens, usage.OutputTokens, duration.Milliseconds(),
+		errorMessage, costCents, userID)
+	if err != nil {
+		logrus.WithError(err).WithField("model", modelID).Warn("Failed to record LLM call")
+	}
+}
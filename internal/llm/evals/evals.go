@@ -0,0 +1,347 @@
+// Package evals scores weekly summaries against fixtures of realistic
+// weeks, so a prompt or model change can be checked before rollout. Each
+// fixture keeps a response a real model gave; Replay serves it back for
+// deterministic runs in CI, while a live provider scores the current prompt
+// and model.
+package evals
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/llm"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/stats"
+	pkgConfig "github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+//go:embed fixtures/*.json
+var builtinFixtures embed.FS
+
+const dateLayout = "2006-01-02"
+
+// Fixture is one week of entries and what a good summary of it looks like
+type Fixture struct {
+	Name string `json:"name"`
+	// Voice and Language are the user's summary preferences
+	Voice     string         `json:"voice"`
+	Language  string         `json:"language,omitempty"`
+	WeekStart string         `json:"week_start"`
+	Entries   []FixtureEntry `json:"entries"`
+	Expect    Expect         `json:"expect"`
+	// Recorded is a model's response to the fixture's prompt, which Replay
+	// answers with
+	Recorded string `json:"recorded"`
+
+	// path is the file the fixture was read from, empty for built-in ones
+	path string
+}
+
+// FixtureEntry is one day's entry
+type FixtureEntry struct {
+	Date    string `json:"date"`
+	Content string `json:"content"`
+	Project string `json:"project,omitempty"`
+}
+
+// Expect describes a good summary beyond passing llm.CheckSummary
+type Expect struct {
+	MinBullets int `json:"min_bullets"`
+	MaxBullets int `json:"max_bullets"`
+	// MustMention are terms the summary has to include, case-insensitively;
+	// "a|b" accepts either
+	MustMention []string `json:"must_mention,omitempty"`
+	// MustNotMention are terms that would be made up or wrongly claimed,
+	// such as work the entries only plan
+	MustNotMention []string `json:"must_not_mention,omitempty"`
+}
+
+// Builtin returns the fixtures embedded in the binary
+func Builtin() ([]*Fixture, error) {
+	paths, err := fs.Glob(builtinFixtures, "fixtures/*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	fixtures := make([]*Fixture, 0, len(paths))
+	for _, path := range paths {
+		data, err := builtinFixtures.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fixture, err := parseFixture(path, data)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
+
+// LoadDir reads the *.json fixtures in dir, which Save writes back to
+func LoadDir(dir string) ([]*Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no fixtures in %s", dir)
+	}
+	sort.Strings(paths)
+
+	fixtures := make([]*Fixture, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture: %w", err)
+		}
+		fixture, err := parseFixture(path, data)
+		if err != nil {
+			return nil, err
+		}
+		fixture.path = path
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
+
+func parseFixture(path string, data []byte) (*Fixture, error) {
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	if fixture.Name == "" {
+		fixture.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	if _, err := time.Parse(dateLayout, fixture.WeekStart); err != nil {
+		return nil, fmt.Errorf("invalid week_start in fixture %s: %w", fixture.Name, err)
+	}
+	if len(fixture.Entries) == 0 {
+		return nil, fmt.Errorf("fixture %s has no entries", fixture.Name)
+	}
+	for _, entry := range fixture.Entries {
+		if _, err := time.Parse(dateLayout, entry.Date); err != nil {
+			return nil, fmt.Errorf("invalid entry date in fixture %s: %w", fixture.Name, err)
+		}
+	}
+	return &fixture, nil
+}
+
+// Save writes the fixture back to the file LoadDir read it from
+func Save(fixture *Fixture) error {
+	if fixture.path == "" {
+		return fmt.Errorf("fixture %s is built in; load it from a directory to record it", fixture.Name)
+	}
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	return os.WriteFile(fixture.path, append(data, '\n'), 0o644)
+}
+
+// job is the summary request the fixture stands for: a user with its
+// preferences and the week's entries, weekends off
+func (f *Fixture) job() llm.SummaryJob {
+	start, _ := time.Parse(dateLayout, f.WeekStart)
+	end := start.AddDate(0, 0, 6)
+
+	user := &models.User{ID: 1, Name: "Alex", SummaryVoice: f.Voice, SummaryLanguage: f.Language, WeekStart: period.WeekStartMonday}
+	entries := make([]*models.Entry, 0, len(f.Entries))
+	dates := make([]time.Time, 0, len(f.Entries))
+	for i, e := range f.Entries {
+		date, _ := time.Parse(dateLayout, e.Date)
+		entry := &models.Entry{ID: i + 1, UserID: user.ID, EntryDate: date, RawContent: e.Content}
+		if e.Project != "" {
+			project := e.Project
+			entry.ProjectTag = &project
+		}
+		entries = append(entries, entry)
+		dates = append(dates, date)
+	}
+
+	weekend := func(day time.Time) bool {
+		return day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+	}
+	return llm.SummaryJob{
+		User:       user,
+		Entries:    entries,
+		Start:      start,
+		End:        end,
+		MissedDays: stats.MissedDays(dates, start, end, weekend),
+	}
+}
+
+// Result is how one fixture's summary scored
+type Result struct {
+	Fixture *Fixture
+	Summary *llm.WeeklySummary
+	Err     error
+	// Calls counts model calls; more than one means a draft was rejected
+	Calls int
+	// Response is the model's first response, which recording keeps
+	Response string
+	Checks   []Check
+}
+
+// Check is one property of a summary
+type Check struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// Score is the share of checks that passed
+func (r *Result) Score() float64 {
+	if len(r.Checks) == 0 {
+		return 0
+	}
+	passed := 0
+	for _, check := range r.Checks {
+		if check.Passed {
+			passed++
+		}
+	}
+	return float64(passed) / float64(len(r.Checks))
+}
+
+// Failed returns the checks that didn't pass
+func (r *Result) Failed() []Check {
+	var failed []Check
+	for _, check := range r.Checks {
+		if !check.Passed {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+// Report is the outcome of a run
+type Report struct {
+	Results []*Result
+}
+
+// Score is the share of all checks that passed
+func (r *Report) Score() float64 {
+	total, passed := 0, 0
+	for _, result := range r.Results {
+		for _, check := range result.Checks {
+			total++
+			if check.Passed {
+				passed++
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(passed) / float64(total)
+}
+
+// ProviderFor returns the provider a fixture's summary is generated with
+type ProviderFor func(fixture *Fixture) llm.Provider
+
+// Run summarizes each fixture through the same model chain and validation
+// as weekly summaries, using cfg's models, and scores the result
+func Run(ctx context.Context, cfg *pkgConfig.Config, fixtures []*Fixture, providerFor ProviderFor) *Report {
+	report := &Report{}
+	for _, fixture := range fixtures {
+		recorder := &recordingProvider{Provider: providerFor(fixture)}
+		service := llm.NewServiceWithProvider(cfg, recorder)
+
+		job := fixture.job()
+		summary, err := service.GenerateSummary(ctx, job)
+		result := &Result{Fixture: fixture, Summary: summary, Err: err, Calls: recorder.calls, Response: recorder.first}
+		if err != nil {
+			result.Checks = []Check{{Name: "generated", Detail: err.Error()}}
+		} else {
+			result.Checks = score(fixture, summary, job.Entries, recorder.calls)
+		}
+		report.Results = append(report.Results, result)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return report
+}
+
+// score checks summary against the fixture's expectations
+func score(fixture *Fixture, summary *llm.WeeklySummary, entries []*models.Entry, calls int) []Check {
+	text := strings.ToLower(summary.Paragraph + "\n" + strings.Join(summary.BulletPoints, "\n"))
+	var checks []Check
+
+	checks = append(checks, Check{
+		Name:   "model",
+		Passed: summary.Model != llm.TemplateModel,
+		Detail: "fell back to the template summary",
+	})
+	checks = append(checks, Check{
+		Name:   "first draft",
+		Passed: calls == 1,
+		Detail: fmt.Sprintf("took %d model calls", calls),
+	})
+
+	problems := llm.CheckSummary(summary, entries)
+	checks = append(checks, Check{
+		Name:   "grounded",
+		Passed: len(problems) == 0,
+		Detail: strings.Join(problems, "; "),
+	})
+
+	expect := fixture.Expect
+	n := len(summary.BulletPoints)
+	checks = append(checks, Check{
+		Name:   "bullets",
+		Passed: (expect.MinBullets == 0 || n >= expect.MinBullets) && (expect.MaxBullets == 0 || n <= expect.MaxBullets),
+		Detail: fmt.Sprintf("%d bullets, want %d-%d", n, expect.MinBullets, expect.MaxBullets),
+	})
+
+	for _, term := range expect.MustMention {
+		found := false
+		for _, alternative := range strings.Split(term, "|") {
+			if strings.Contains(text, strings.ToLower(strings.TrimSpace(alternative))) {
+				found = true
+				break
+			}
+		}
+		checks = append(checks, Check{Name: "mentions " + term, Passed: found, Detail: "not mentioned"})
+	}
+	for _, term := range expect.MustNotMention {
+		checks = append(checks, Check{
+			Name:   "doesn't mention " + term,
+			Passed: !strings.Contains(text, strings.ToLower(term)),
+			Detail: "mentioned, but the entries don't support it",
+		})
+	}
+
+	passed, detail := voiceMatches(text, fixture.Voice)
+	checks = append(checks, Check{Name: "voice", Passed: passed, Detail: detail})
+	return checks
+}
+
+// voiceMatches reports whether text is written in voice: as the user for
+// first person, to the user for the coach
+func voiceMatches(text, voice string) (bool, string) {
+	words := map[string]int{}
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r == '\'')
+	}) {
+		words[word]++
+	}
+	first := words["i"] + words["my"] + words["i'm"] + words["i've"] + words["me"]
+	second := words["you"] + words["your"] + words["you're"] + words["you've"]
+
+	if voice == models.SummaryVoiceFirstPerson {
+		return first > 0 && second == 0, fmt.Sprintf("%d first-person and %d second-person words, want first person only", first, second)
+	}
+	return second > 0 && first == 0, fmt.Sprintf("%d second-person and %d first-person words, want second person only", second, first)
+}
@@ -0,0 +1,137 @@
+package evals
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/llm"
+	pkgConfig "github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+)
+
+// replayConfig is a model chain that replays once and then falls back to
+// the template summary
+var replayConfig = &pkgConfig.Config{LLMProvider: "replay", LLMModel: "recorded", LLMFallbackModels: []string{llm.TemplateModel}}
+
+// TestBuiltinFixtures replays every recorded response; a fixture failing
+// here needs a better recording, or its expectations are wrong
+func TestBuiltinFixtures(t *testing.T) {
+	fixtures, err := Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no built-in fixtures")
+	}
+
+	report := Run(context.Background(), replayConfig, fixtures, Replay)
+	for _, result := range report.Results {
+		for _, check := range result.Failed() {
+			t.Errorf("%s: %s failed: %s", result.Fixture.Name, check.Name, check.Detail)
+		}
+	}
+	if score := report.Score(); score != 1 {
+		t.Errorf("Score() = %.2f, want 1", score)
+	}
+}
+
+func TestRunCatchesBadSummaries(t *testing.T) {
+	fixtures, err := Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var steady *Fixture
+	for _, fixture := range fixtures {
+		if fixture.Name == "steady_week_coach" {
+			steady = fixture
+		}
+	}
+	if steady == nil {
+		t.Fatal("steady_week_coach fixture is missing")
+	}
+
+	tests := []struct {
+		name     string
+		response string
+		failed   []string
+	}{
+		{
+			name: "made-up launch",
+			response: "SUMMARY: You finished the billing export migration and the production cutover completed without issues.\nBULLETS:\n" +
+				"• Fixed the currency bug in the billing export\n• Wrote the runbook\n• Migrated production with zero downtime",
+			failed: []string{"doesn't mention production cutover completed", "doesn't mention migrated production"},
+		},
+		{
+			name: "wrong voice",
+			response: "SUMMARY: I moved the billing export to the new schema and wrote my runbook.\nBULLETS:\n" +
+				"• Fixed the currency bug\n• Ran the billing migration on staging\n• Wrote the runbook for finance",
+			failed: []string{"voice"},
+		},
+		{
+			name:     "vague",
+			response: "Great week!",
+			failed:   []string{"bullets", "mentions billing"},
+		},
+		{
+			name:     "rejected by validation",
+			response: "SUMMARY: You got through the damn billing export migration.\nBULLETS:\n• Fixed the currency bug\n• Wrote the runbook\n• Ran the migration on staging",
+			failed:   []string{"model", "first draft"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := *steady
+			fixture.Recorded = tt.response
+			result := Run(context.Background(), replayConfig, []*Fixture{&fixture}, Replay).Results[0]
+
+			failed := map[string]bool{}
+			for _, check := range result.Failed() {
+				failed[check.Name] = true
+			}
+			for _, name := range tt.failed {
+				if !failed[name] {
+					t.Errorf("check %q passed, want it to fail (failed: %v)", name, result.Failed())
+				}
+			}
+			if result.Score() == 1 {
+				t.Error("Score() = 1 for a bad summary")
+			}
+		})
+	}
+}
+
+func TestSaveRecordsFixture(t *testing.T) {
+	dir := t.TempDir()
+	data, err := builtinFixtures.ReadFile("fixtures/light_week.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "light_week.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures[0].Recorded = "SUMMARY: new recording\nBULLETS:\n• one"
+	if err := Save(fixtures[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded[0].Recorded != fixtures[0].Recorded {
+		t.Errorf("Recorded = %q after saving, want %q", reloaded[0].Recorded, fixtures[0].Recorded)
+	}
+
+	builtin, _ := Builtin()
+	if err := Save(builtin[0]); err == nil || !strings.Contains(err.Error(), "built in") {
+		t.Errorf("Save(built-in fixture) error = %v, want a built-in error", err)
+	}
+}
@@ -0,0 +1,56 @@
+package evals
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/llm"
+)
+
+// Replay is the fake provider for deterministic runs: it answers every
+// prompt with the fixture's recorded response, free of charge
+func Replay(fixture *Fixture) llm.Provider {
+	return replayProvider{name: fixture.Name, response: fixture.Recorded}
+}
+
+type replayProvider struct {
+	name     string
+	response string
+}
+
+func (p replayProvider) Invoke(ctx context.Context, modelID, prompt string, maxTokens int) (*llm.ClaudeResponse, error) {
+	if p.response == "" {
+		return nil, fmt.Errorf("fixture %s has no recorded response", p.name)
+	}
+	return &llm.ClaudeResponse{
+		Content: []llm.ContentBlock{{Type: "text", Text: p.response}},
+		Usage:   llm.Usage{InputTokens: len(prompt) / 4, OutputTokens: len(p.response) / 4},
+	}, nil
+}
+
+func (p replayProvider) EstimateCost(modelID string, usage llm.Usage) int {
+	return 0
+}
+
+// recordingProvider counts the calls made through it and keeps the first
+// response
+type recordingProvider struct {
+	llm.Provider
+
+	mu    sync.Mutex
+	calls int
+	first string
+}
+
+func (p *recordingProvider) Invoke(ctx context.Context, modelID, prompt string, maxTokens int) (*llm.ClaudeResponse, error) {
+	response, err := p.Provider.Invoke(ctx, modelID, prompt, maxTokens)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if err == nil && p.first == "" && len(response.Content) > 0 {
+		p.first = response.Content[0].Text
+	}
+	return response, err
+}
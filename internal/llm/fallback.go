@@ -0,0 +1,80 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/entryformat"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// TemplateModel is the fallback chain entry that builds a summary from the
+// entries themselves without calling a model
+const TemplateModel = "template"
+
+const (
+	templateMaxBullets     = 5
+	templateMaxBulletRunes = 120
+)
+
+// templateSummary lists the entries verbatim so users still get a summary
+// when every model in the chain fails
+func templateSummary(entries []*models.Entry, voice string, scope summaryScope) *WeeklySummary {
+	days := make(map[string]bool)
+	var bullets []string
+
+	for _, entry := range entries {
+		days[entry.EntryDate.Format("2006-01-02")] = true
+
+		if len(bullets) == templateMaxBullets {
+			continue
+		}
+
+		text := entry.RawContent
+		if structured, ok := structuredEntry(entry); ok {
+			// The first section of every guided format is what got done
+			text = structured.Sections[0].Text
+		}
+
+		line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(text), "\n", 2)[0])
+		if line == "" {
+			continue
+		}
+		if runes := []rune(line); len(runes) > templateMaxBulletRunes {
+			line = string(runes[:templateMaxBulletRunes-1]) + "…"
+		}
+		bullets = append(bullets, fmt.Sprintf("%s: %s", entry.EntryDate.Format(scope.dayLayout), line))
+	}
+
+	paragraph := fmt.Sprintf("You logged %d %s across %d %s %s. Here's what you wrote down.",
+		len(entries), plural(len(entries), "entry", "entries"), len(days), plural(len(days), "day", "days"), scope.span)
+	if voice == models.SummaryVoiceFirstPerson {
+		paragraph = fmt.Sprintf("I logged %d %s across %d %s %s.",
+			len(entries), plural(len(entries), "entry", "entries"), len(days), plural(len(days), "day", "days"), scope.span)
+	}
+	if len(scope.missedDays) > 0 {
+		paragraph += fmt.Sprintf(" There are no entries for %s.", period.DayList(scope.missedDays))
+	}
+
+	return &WeeklySummary{
+		Paragraph:    paragraph,
+		BulletPoints: bullets,
+		Model:        TemplateModel,
+	}
+}
+
+// structuredEntry decodes a guided-format entry from parsed_content
+func structuredEntry(entry *models.Entry) (*entryformat.Structured, bool) {
+	if entry.ParsedContent == nil {
+		return nil, false
+	}
+	return entryformat.Decode(*entry.ParsedContent)
+}
+
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
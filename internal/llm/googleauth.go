@@ -0,0 +1,238 @@
+package llm
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	googleCloudScope   = "https://www.googleapis.com/auth/cloud-platform"
+	googleTokenURL     = "https://oauth2.googleapis.com/token"
+	googleMetadataURL  = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	googleTokenRefresh = time.Minute // before expiry
+)
+
+// googleCredentials is a credentials file: a service account key or the
+// user credentials written by `gcloud auth application-default login`
+type googleCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// googleTokenSource issues OAuth2 access tokens from Application Default
+// Credentials, caching each until shortly before it expires
+type googleTokenSource struct {
+	httpClient *http.Client
+	creds      *googleCredentials // nil to use the metadata server
+	key        *rsa.PrivateKey
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// newGoogleTokenSource finds credentials the way Google's client libraries
+// do: the file in GOOGLE_APPLICATION_CREDENTIALS, then gcloud's
+// application default credentials file, then the metadata server of the
+// GCE, GKE or Cloud Run instance it runs on
+func newGoogleTokenSource(httpClient *http.Client) (*googleTokenSource, error) {
+	ts := &googleTokenSource{httpClient: httpClient}
+
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		if wellKnown := gcloudCredentialsPath(); wellKnown != "" {
+			if _, err := os.Stat(wellKnown); err == nil {
+				path = wellKnown
+			}
+		}
+	}
+	if path == "" {
+		return ts, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Google credentials: %w", err)
+	}
+	var creds googleCredentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse Google credentials %s: %w", path, err)
+	}
+
+	switch creds.Type {
+	case "service_account":
+		ts.key, err = parseGooglePrivateKey(creds.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service account key in %s: %w", path, err)
+		}
+		if creds.TokenURI == "" {
+			creds.TokenURI = googleTokenURL
+		}
+	case "authorized_user":
+		if creds.RefreshToken == "" {
+			return nil, fmt.Errorf("Google credentials %s have no refresh token", path)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Google credentials type %q in %s", creds.Type, path)
+	}
+
+	ts.creds = &creds
+	return ts, nil
+}
+
+// gcloudCredentialsPath is where gcloud writes application default
+// credentials
+func gcloudCredentialsPath() string {
+	if runtime.GOOS == "windows" {
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return filepath.Join(appData, "gcloud", "application_default_credentials.json")
+		}
+		return ""
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+func parseGooglePrivateKey(value string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("key is not RSA")
+		}
+		return rsaKey, nil
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// Token returns a current access token, fetching a new one when the cached
+// token is about to expire
+func (ts *googleTokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != "" && time.Until(ts.expiry) > googleTokenRefresh {
+		return ts.token, nil
+	}
+
+	var req *http.Request
+	var err error
+	switch {
+	case ts.creds == nil:
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, googleMetadataURL, nil)
+		if err == nil {
+			req.Header.Set("Metadata-Flavor", "Google")
+		}
+	case ts.creds.Type == "service_account":
+		var assertion string
+		assertion, err = ts.signAssertion(time.Now())
+		if err == nil {
+			req, err = tokenRequest(ctx, ts.creds.TokenURI, url.Values{
+				"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+				"assertion":  {assertion},
+			})
+		}
+	default:
+		req, err = tokenRequest(ctx, googleTokenURL, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {ts.creds.ClientID},
+			"client_secret": {ts.creds.ClientSecret},
+			"refresh_token": {ts.creds.RefreshToken},
+		})
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to build Google token request: %w", err)
+	}
+
+	resp, err := ts.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch Google access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read Google token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Google token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("invalid Google token response: %s", strings.TrimSpace(string(body)))
+	}
+
+	ts.token = token.AccessToken
+	ts.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return ts.token, nil
+}
+
+// signAssertion is the RS256 JWT a service account exchanges for an access
+// token
+func (ts *googleTokenSource) signAssertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": ts.creds.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   ts.creds.ClientEmail,
+		"scope": googleCloudScope,
+		"aud":   ts.creds.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, ts.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token assertion: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func tokenRequest(ctx context.Context, tokenURL string, form url.Values) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// partialSummary is the summary of one chunk of entries, or of several
+// earlier partial summaries, covering from through to
+type partialSummary struct {
+	from, to time.Time
+	summary  *WeeklySummary
+}
+
+// summarizeEntries sends entries in a single prompt when they fit
+// LLM_MAX_INPUT_TOKENS. Otherwise it summarizes each week (or part of a week)
+// on its own and then combines those summaries, so long ranges stay within
+// the model's context. Weeks start on firstDay. priorText is only used for
+// the final prompt. lang is the user's summary language preference.
+func (s *Service) summarizeEntries(ctx context.Context, entries []*models.Entry, voice, lang string, firstDay time.Weekday, scope summaryScope, priorText string, fields logrus.Fields) (*WeeklySummary, error) {
+	ctx = withEntriesUser(ctx, entries)
+	scope.language = summaryLanguage(lang, entries)
+	logged := logrus.Fields{"language": scope.language}
+	for key, value := range fields {
+		logged[key] = value
+	}
+	fields = logged
+
+	budget := s.config.LLMMaxInputTokens
+	if budget <= 0 || estimateTokens(formatEntries(entries, scope.dayLayout)) <= budget {
+		return s.summarize(ctx, buildSummaryPrompt(entries, voice, scope, priorText), entries, voice, scope, fields)
+	}
+
+	chunks := chunkEntries(entries, scope.dayLayout, firstDay, budget)
+	logrus.WithFields(fields).WithFields(logrus.Fields{
+		"entries_count": len(entries),
+		"chunks":        len(chunks),
+		"token_budget":  budget,
+	}).Info("Entries exceed input budget, summarizing in chunks")
+
+	cost := 0
+	parts := make([]partialSummary, 0, len(chunks))
+	for _, chunk := range chunks {
+		from, to := chunk[0].EntryDate, chunk[len(chunk)-1].EntryDate
+		chunkScope := rangeScope(from, to)
+		chunkScope.language = scope.language
+
+		summary, err := s.summarize(ctx, buildSummaryPrompt(chunk, voice, chunkScope, ""), chunk, voice, chunkScope, fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize entries from %s to %s: %w", from.Format("2006-01-02"), to.Format("2006-01-02"), err)
+		}
+		cost += summary.CostCents
+		parts = append(parts, partialSummary{from: from, to: to, summary: summary})
+	}
+
+	summary, err := s.combineSummaries(ctx, parts, entries, voice, scope, priorText, fields)
+	if err != nil {
+		return nil, err
+	}
+	summary.CostCents += cost
+	return summary, nil
+}
+
+// combineSummaries merges partial summaries into one summary for scope. When
+// the partials themselves exceed the input budget, neighbouring ones are
+// merged first, until a single prompt fits. entries are only used for the
+// template fallback.
+func (s *Service) combineSummaries(ctx context.Context, parts []partialSummary, entries []*models.Entry, voice string, scope summaryScope, priorText string, fields logrus.Fields) (*WeeklySummary, error) {
+	budget := s.config.LLMMaxInputTokens
+	cost := 0
+
+	for len(parts) > 1 && estimateTokens(formatPartialSummaries(parts)) > budget {
+		groups := groupPartialSummaries(parts, budget)
+		if len(groups) == len(parts) {
+			// Every partial is over budget on its own; merging can't shrink
+			// anything, so send them as they are
+			break
+		}
+
+		merged := make([]partialSummary, 0, len(groups))
+		for _, group := range groups {
+			if len(group) == 1 {
+				merged = append(merged, group[0])
+				continue
+			}
+
+			from, to := group[0].from, group[len(group)-1].to
+			groupScope := rangeScope(from, to)
+			groupScope.language = scope.language
+			summary, err := s.summarize(ctx, buildCombinePrompt(group, voice, groupScope, ""), entries, voice, groupScope, fields)
+			if err != nil {
+				return nil, fmt.Errorf("failed to combine summaries from %s to %s: %w", from.Format("2006-01-02"), to.Format("2006-01-02"), err)
+			}
+			cost += summary.CostCents
+			merged = append(merged, partialSummary{from: from, to: to, summary: summary})
+		}
+		parts = merged
+	}
+
+	summary, err := s.summarize(ctx, buildCombinePrompt(parts, voice, scope, priorText), entries, voice, scope, fields)
+	if err != nil {
+		return nil, err
+	}
+	summary.CostCents += cost
+	return summary, nil
+}
+
+// groupPartialSummaries splits parts into consecutive groups whose formatted
+// text fits budget tokens
+func groupPartialSummaries(parts []partialSummary, budget int) [][]partialSummary {
+	var groups [][]partialSummary
+	var current []partialSummary
+	used := 0
+
+	for _, part := range parts {
+		tokens := estimateTokens(formatPartialSummary(part))
+		if len(current) > 0 && used+tokens > budget {
+			groups = append(groups, current)
+			current, used = nil, 0
+		}
+		current = append(current, part)
+		used += tokens
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+
+	return groups
+}
+
+func formatPartialSummaries(parts []partialSummary) string {
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteString(formatPartialSummary(part))
+	}
+	return b.String()
+}
+
+func formatPartialSummary(part partialSummary) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s to %s: %s\n", part.from.Format("Mon Jan 2"), part.to.Format("Mon Jan 2"), part.summary.Paragraph))
+	for _, bullet := range part.summary.BulletPoints {
+		b.WriteString("  • " + bullet + "\n")
+	}
+	return b.String()
+}
+
+// buildCombinePrompt asks for one summary of scope from the summaries of
+// its parts
+func buildCombinePrompt(parts []partialSummary, voice string, scope summaryScope, priorText string) string {
+	instructions := `
+- Treat the period summaries as one body of work: merge related items across periods rather than summarizing each period in turn
+- Favour work that recurs or builds across periods over one-off items` + comparisonInstructions(priorText)
+
+	heading := "summaries of " + scope.entries + ", period by period"
+	return summaryPrompt(voice, scope, heading, formatPartialSummaries(parts), instructions, priorText)
+}
@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// priorSummaryTokenBudget caps how much of the prompt earlier summaries may
+// use, so comparison doesn't crowd out the current week's entries
+const priorSummaryTokenBudget = 600
+
+// priorSummariesSection renders earlier summaries, newest first, for the
+// prompt. Summaries are dropped oldest first to stay within the token budget;
+// if even the newest doesn't fit, its bullets are left out.
+func priorSummariesSection(previous []*models.WeeklySummary) string {
+	var b strings.Builder
+	budget := priorSummaryTokenBudget
+
+	for i, summary := range previous {
+		block := formatPriorSummary(summary, true)
+		if estimateTokens(block) > budget && i == 0 {
+			block = formatPriorSummary(summary, false)
+		}
+		if estimateTokens(block) > budget {
+			break
+		}
+		b.WriteString(block)
+		budget -= estimateTokens(block)
+	}
+
+	return b.String()
+}
+
+func formatPriorSummary(summary *models.WeeklySummary, withBullets bool) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Week of %s: %s\n", summary.WeekStartDate.Format("2006-01-02"), summary.SummaryParagraph))
+	if withBullets {
+		for _, bullet := range summary.BulletPoints {
+			b.WriteString("  • " + bullet + "\n")
+		}
+	}
+	return b.String()
+}
+
+// comparisonInstructions asks the model to relate this week to the earlier ones
+func comparisonInstructions(priorText string) string {
+	if priorText == "" {
+		return ""
+	}
+	return `
+- Compare with the previous weeks' summaries: note momentum, and call out blockers that keep recurring (e.g. "third week in a row waiting on security review")
+- Don't present work from previous weeks as new this week`
+}
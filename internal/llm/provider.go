@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	pkgConfig "github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+)
+
+// LLM_PROVIDER values
+const (
+	ProviderBedrock = "amazon_bedrock"
+	ProviderVertex  = "google_vertex"
+)
+
+// Provider is the backend LLM_PROVIDER selects. Model IDs in LLM_MODEL and
+// LLM_FALLBACK_MODELS are the provider's own.
+type Provider interface {
+	// Invoke sends prompt as a single user message and returns the reply
+	// and its token usage. Throttling is reported as CodeLLMThrottled.
+	Invoke(ctx context.Context, modelID, prompt string, maxTokens int) (*ClaudeResponse, error)
+	// EstimateCost is the approximate price of usage on modelID, in cents
+	EstimateCost(modelID string, usage Usage) int
+}
+
+// NewProvider returns the provider LLM_PROVIDER selects
+func NewProvider(ctx context.Context, cfg *pkgConfig.Config) (Provider, error) {
+	switch cfg.LLMProvider {
+	case ProviderBedrock:
+		return newBedrockProvider(ctx, cfg)
+	case ProviderVertex:
+		return newVertexProvider(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unknown LLM_PROVIDER %q (expected %s or %s)", cfg.LLMProvider, ProviderBedrock, ProviderVertex)
+	}
+}
+
+// centsFor prices usage at per-million-token rates given in cents, charging
+// at least a cent per call
+func centsFor(usage Usage, inputPerMillion, outputPerMillion float64) int {
+	cents := int((float64(usage.InputTokens)*inputPerMillion + float64(usage.OutputTokens)*outputPerMillion) / 1000000)
+	if cents < 1 {
+		return 1
+	}
+	return cents
+}
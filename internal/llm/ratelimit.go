@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces out requests to a provider at a fixed interval
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+var (
+	providerLimitersMu sync.Mutex
+	providerLimiters   = map[string]*rateLimiter{}
+)
+
+// limiterForProvider returns the shared limiter for a provider so every service
+// instance in the process draws from the same request budget
+func limiterForProvider(provider string, requestsPerMinute int) *rateLimiter {
+	if requestsPerMinute <= 0 {
+		return nil
+	}
+
+	providerLimitersMu.Lock()
+	defer providerLimitersMu.Unlock()
+
+	if limiter, ok := providerLimiters[provider]; ok {
+		return limiter
+	}
+
+	limiter := &rateLimiter{
+		ticker: time.NewTicker(time.Minute / time.Duration(requestsPerMinute)),
+	}
+	providerLimiters[provider] = limiter
+	return limiter
+}
+
+// Wait blocks until the next request slot is available or the context is cancelled
+func (r *rateLimiter) Wait(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.ticker.C:
+		return nil
+	}
+}
@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// maxRecapLength caps the recap line; longer answers are cut at a word
+const maxRecapLength = 160
+
+// PromptRecap writes the one-line follow-up the daily prompt opens with,
+// such as "Yesterday you said you'd finish the API migration - how did that
+// go?". day says when entry was written, like "Yesterday" or "On Friday".
+// Only the first model of the chain is tried; a recap isn't worth retries,
+// and the caller quotes the entry instead when this fails.
+func (s *Service) PromptRecap(ctx context.Context, day string, entry *models.Entry) (string, error) {
+	modelID := s.modelChain()[0]
+	if modelID == TemplateModel {
+		return "", fmt.Errorf("no model configured for prompt recaps")
+	}
+
+	ctx = withEntriesUser(ctx, []*models.Entry{entry})
+	response, err := s.callClaude(ctx, modelID, buildRecapPrompt(day, entry.RawContent))
+	if err != nil {
+		return "", err
+	}
+	if len(response.Content) == 0 {
+		return "", fmt.Errorf("empty response from model")
+	}
+
+	line := cleanRecap(response.Content[0].Text)
+	if line == "" {
+		return "", fmt.Errorf("empty recap from model")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":    entry.UserID,
+		"model":      modelID,
+		"cost_cents": s.estimateCost(modelID, response.Usage),
+	}).Info("Generated prompt recap")
+	return line, nil
+}
+
+func buildRecapPrompt(day, content string) string {
+	return fmt.Sprintf(`Below is a user's most recent work journal entry. Write one short line that opens today's "What did you get done today?" email by following up on it.
+
+The line should:
+- Start with "%s"
+- Pick the one plan or open thread from the entry most worth following up on
+- End with a friendly question, like "Yesterday you said you'd finish the API migration - how did that go?"
+- Be a single sentence under 25 words, with no quotation marks or preamble
+
+Journal entry:
+%s`, day, content)
+}
+
+// cleanRecap keeps the first line of a model's answer, unquoted and capped
+// at maxRecapLength
+func cleanRecap(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	line = strings.Trim(strings.TrimSpace(line), `"'`)
+	if len(line) <= maxRecapLength {
+		return line
+	}
+	if i := strings.LastIndex(line[:maxRecapLength], " "); i > 0 {
+		line = line[:i]
+	}
+	return line + "..."
+}
@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// summaryScope words the prompt and template summary for the span of time
+// the entries cover
+type summaryScope struct {
+	accomplishments string // what the model is asked to summarize
+	entries         string // heading for the entries in the prompt
+	span            string // "this week", used in the template paragraph
+	dayLayout       string // how each entry's date is labelled
+	language        string // code of the language the summary is written in
+	// missedDays are the days without an entry in a light week, which the
+	// summary notes; nil otherwise
+	missedDays []time.Time
+}
+
+var weeklyScope = summaryScope{
+	accomplishments: "weekly accomplishments",
+	entries:         "weekly entries",
+	span:            "this week",
+	dayLayout:       "Monday",
+}
+
+// rangeScope labels entries with full dates, since a custom range can span
+// several weeks
+func rangeScope(from, to time.Time) summaryScope {
+	span := fmt.Sprintf("from %s to %s", from.Format("Jan 2, 2006"), to.Format("Jan 2, 2006"))
+	return summaryScope{
+		accomplishments: "accomplishments " + span,
+		entries:         "entries " + span,
+		span:            span,
+		dayLayout:       "Mon Jan 2",
+	}
+}
+
+// GenerateRangeSummary summarizes entries dated from through to, such as a
+// sprint or a review window, through the same model chain as weekly summaries.
+// Long ranges are split into weeks starting on firstDay.
+func (s *Service) GenerateRangeSummary(ctx context.Context, entries []*models.Entry, voice, lang string, firstDay time.Weekday, from, to time.Time) (*WeeklySummary, error) {
+	return s.summarizeEntries(ctx, entries, voice, lang, firstDay, rangeScope(from, to), "", logrus.Fields{
+		"from": from.Format("2006-01-02"),
+		"to":   to.Format("2006-01-02"),
+	})
+}
+
+// projectScope words a rollup of one project's entries, first through last
+// dated from and to: "3 months on Project Atlas"
+func projectScope(project string, from, to time.Time) summaryScope {
+	span := fmt.Sprintf("%s on %s", period.DurationLabel(from, to), project)
+	return summaryScope{
+		accomplishments: "accomplishments over " + span,
+		entries:         fmt.Sprintf("entries tagged %s from %s to %s", project, from.Format("Jan 2, 2006"), to.Format("Jan 2, 2006")),
+		span:            "over " + span,
+		dayLayout:       "Mon Jan 2",
+	}
+}
+
+// GenerateProjectRollup summarizes a project's entries dated from through to
+// through the same model chain as weekly summaries, split into weeks starting
+// on firstDay when they are long
+func (s *Service) GenerateProjectRollup(ctx context.Context, entries []*models.Entry, voice, lang string, firstDay time.Weekday, project string, from, to time.Time) (*WeeklySummary, error) {
+	return s.summarizeEntries(ctx, entries, voice, lang, firstDay, projectScope(project, from, to), "", logrus.Fields{
+		"project": project,
+		"from":    from.Format("2006-01-02"),
+		"to":      to.Format("2006-01-02"),
+	})
+}
@@ -2,22 +2,25 @@ package llm
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/sirupsen/logrus"
 
-	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/models"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/language"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
 	pkgConfig "github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
 )
 
 type Service struct {
-	client *bedrockruntime.Client
-	config *pkgConfig.Config
+	provider Provider
+	config   *pkgConfig.Config
+	limiter  *rateLimiter
+	callLog  *database.DB
 }
 
 type WeeklySummary struct {
@@ -53,28 +56,123 @@ type Usage struct {
 	OutputTokens int `json:"output_tokens"`
 }
 
+// NewService fails on an unknown LLM_PROVIDER or if the provider's
+// credentials can't be loaded
 func NewService(cfg *pkgConfig.Config) (*Service, error) {
-	awsCfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(cfg.AWSRegion))
+	provider, err := NewProvider(context.TODO(), cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		return nil, err
 	}
+	return NewServiceWithProvider(cfg, provider), nil
+}
 
+// NewServiceWithProvider is NewService with provider in place of the one
+// LLM_PROVIDER selects, such as a fake one that replays recorded responses
+func NewServiceWithProvider(cfg *pkgConfig.Config, provider Provider) *Service {
 	return &Service{
-		client: bedrockruntime.NewFromConfig(awsCfg),
-		config: cfg,
-	}, nil
+		provider: provider,
+		config:   cfg,
+		limiter:  limiterForProvider(cfg.LLMProvider, cfg.LLMRequestsPerMinute),
+	}
 }
 
-func (s *Service) GenerateWeeklySummary(ctx context.Context, entries []*models.Entry) (*WeeklySummary, error) {
-	prompt := s.buildWeeklySummaryPrompt(entries)
-	
-	logrus.WithFields(logrus.Fields{
-		"entries_count": len(entries),
-		"model":         s.config.LLMModel,
-	}).Info("Generating weekly summary")
+// GenerateWeeklySummary tries the primary model and then each LLM_FALLBACK_MODELS
+// entry in order, returning the first summary produced. Summary.Model records
+// the model that was actually used. previous holds earlier summaries, newest
+// first, for the model to compare against; it may be empty. firstDay is the
+// user's first day of the week, and lang their summary language preference.
+func (s *Service) GenerateWeeklySummary(ctx context.Context, entries []*models.Entry, voice, lang string, firstDay time.Weekday, previous []*models.WeeklySummary) (*WeeklySummary, error) {
+	return s.summarizeEntries(ctx, entries, voice, lang, firstDay, weeklyScope, priorWeeksText(previous), logrus.Fields{"prior_weeks": len(previous)})
+}
+
+// summarize runs prompt through the model chain, falling back to a template
+// summary of entries worded for scope. Each summary must pass CheckSummary;
+// a rejected one is regenerated once by the same model before moving down
+// the chain. If every model answered but none passed, the template summary
+// is used even when the chain doesn't end with it.
+func (s *Service) summarize(ctx context.Context, prompt string, entries []*models.Entry, voice string, scope summaryScope, fields logrus.Fields) (*WeeklySummary, error) {
+	chain := s.modelChain()
+
+	var lastErr error
+	rejected := false
+	for attempt, modelID := range chain {
+		logger := logrus.WithFields(fields).WithFields(logrus.Fields{
+			"entries_count": len(entries),
+			"model":         modelID,
+			"attempt":       attempt + 1,
+			"chain_length":  len(chain),
+		})
+
+		if modelID == TemplateModel {
+			logger.Warn("Falling back to template-only summary")
+			return templateSummary(entries, voice, scope), nil
+		}
+
+		logger.Info("Generating summary")
+
+		summary, err := s.generateChecked(ctx, modelID, prompt, entries, logger)
+		if err == nil {
+			return summary, nil
+		}
+
+		lastErr = err
+		if apperrors.Is(err, apperrors.CodeSummaryRejected) {
+			rejected = true
+		}
+		logger.WithError(err).WithField("error_code", apperrors.CodeOf(err)).Warn("Summary attempt failed")
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if rejected && ctx.Err() == nil {
+		logrus.WithFields(fields).WithField("entries_count", len(entries)).Warn("No summary passed validation, falling back to template-only summary")
+		return templateSummary(entries, voice, scope), nil
+	}
+
+	return nil, fmt.Errorf("all %d summary models failed: %w", len(chain), lastErr)
+}
+
+// generateChecked generates a summary with modelID and checks it, retrying
+// once with the reasons it was rejected. Both attempts are charged.
+func (s *Service) generateChecked(ctx context.Context, modelID, prompt string, entries []*models.Entry, logger *logrus.Entry) (*WeeklySummary, error) {
+	summary, err := s.generateWithModel(ctx, modelID, prompt)
+	if err != nil {
+		return nil, err
+	}
+	problems := CheckSummary(summary, entries)
+	if len(problems) == 0 {
+		return summary, nil
+	}
+
+	logger.WithField("problems", problems).Warn("Summary failed validation, regenerating")
+
+	retry, err := s.generateWithModel(ctx, modelID, prompt+regenerationNote(problems))
+	if err != nil {
+		return nil, err
+	}
+	retry.CostCents += summary.CostCents
+
+	if problems := CheckSummary(retry, entries); len(problems) > 0 {
+		return nil, apperrors.New(apperrors.CodeSummaryRejected, "summary failed validation: %s", strings.Join(problems, "; "))
+	}
+	return retry, nil
+}
+
+// modelChain is the primary model followed by the configured fallbacks
+func (s *Service) modelChain() []string {
+	chain := []string{s.config.LLMModel}
+	for _, modelID := range s.config.LLMFallbackModels {
+		if modelID != s.config.LLMModel {
+			chain = append(chain, modelID)
+		}
+	}
+	return chain
+}
 
-	response, err := s.callClaude(ctx, prompt)
+func (s *Service) generateWithModel(ctx context.Context, modelID, prompt string) (*WeeklySummary, error) {
+	response, err := s.callClaude(ctx, modelID, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call Claude: %w", err)
 	}
@@ -84,30 +182,78 @@ func (s *Service) GenerateWeeklySummary(ctx context.Context, entries []*models.E
 		return nil, fmt.Errorf("failed to parse summary response: %w", err)
 	}
 
-	summary.Model = s.config.LLMModel
-	summary.CostCents = s.estimateCost(response.Usage)
+	summary.Model = modelID
+	summary.CostCents = s.estimateCost(modelID, response.Usage)
 
 	logrus.WithFields(logrus.Fields{
+		"model":         modelID,
 		"input_tokens":  response.Usage.InputTokens,
 		"output_tokens": response.Usage.OutputTokens,
 		"cost_cents":    summary.CostCents,
-	}).Info("Weekly summary generated")
+	}).Info("Summary generated")
 
 	return summary, nil
 }
 
-func (s *Service) buildWeeklySummaryPrompt(entries []*models.Entry) string {
-	var entriesText strings.Builder
-	
-	days := []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}
-	
-	for i, entry := range entries {
-		if i < len(days) {
-			entriesText.WriteString(fmt.Sprintf("%s: %s\n", days[i], entry.RawContent))
-		}
+// voiceInstructions tells the model who the summary is written as
+func voiceInstructions(voice string) string {
+	if voice == models.SummaryVoiceFirstPerson {
+		return `- Be written in the first person as the user ("This week I shipped..."), ready to paste directly into a status report
+- Not address the user or give them feedback`
+	}
+	return `- Address the user directly in the second person ("You shipped..."), as feedback from a coach`
+}
+
+// summaryLanguage is the language a summary of entries is written in: lang
+// when the user chose one, otherwise the language the entries are written
+// in, and English when that can't be told
+func summaryLanguage(lang string, entries []*models.Entry) string {
+	if l, ok := language.Lookup(lang); ok {
+		return l.Code
 	}
 
-	return fmt.Sprintf(`System: You are tasked with summarizing a user's weekly accomplishments in the tone and style of Elon Musk - direct, output-driven, and focused on execution. Create a concise summary paragraph followed by 3-5 key bullet points of the most important achievements.
+	var text strings.Builder
+	for _, entry := range entries {
+		text.WriteString(entry.RawContent + "\n")
+	}
+	if code, ok := language.Detect(text.String()); ok {
+		return code
+	}
+	return language.English
+}
+
+// languageInstructions asks for a summary in code's language. English needs
+// no instruction.
+func languageInstructions(code string) string {
+	l, ok := language.Lookup(code)
+	if !ok || l.Code == language.English {
+		return ""
+	}
+	return fmt.Sprintf(`
+- Be written entirely in %s, the language of the entries, but keep the SUMMARY: and BULLETS: labels in English`, l.EnglishName)
+}
+
+// priorWeeksText introduces earlier summaries for comparison, or is empty when
+// there are none
+func priorWeeksText(previous []*models.WeeklySummary) string {
+	text := priorSummariesSection(previous)
+	if text == "" {
+		return ""
+	}
+	return "\nPrevious weeks' summaries (most recent first):\n" + text
+}
+
+// buildSummaryPrompt asks for a summary of entries worded for scope.
+// priorText is appended after the entries for comparison and may be empty.
+func buildSummaryPrompt(entries []*models.Entry, voice string, scope summaryScope, priorText string) string {
+	return summaryPrompt(voice, scope, scope.entries, formatEntries(entries, scope.dayLayout), comparisonInstructions(priorText), priorText)
+}
+
+// summaryPrompt is the summary request shared by entries and combined
+// summaries: body is listed under heading, and instructions extend the
+// style rules
+func summaryPrompt(voice string, scope summaryScope, heading, body, instructions, priorText string) string {
+	return fmt.Sprintf(`System: You are tasked with summarizing a user's %s in the tone and style of Elon Musk - direct, output-driven, and focused on execution. Create a concise summary paragraph followed by 3-5 key bullet points of the most important achievements.
 
 The summary should:
 - Be written in Elon's assertive, no-nonsense tone
@@ -115,9 +261,11 @@ The summary should:
 - Highlight the most impactful work
 - Be motivational but realistic
 - Avoid fluff or unnecessary praise
+- For entries split into labelled sections, draw accomplishments from what was done, not from blockers or plans
+%s%s%s%s
 
-User's weekly entries:
-%s
+User's %s:
+%s%s
 
 Please respond with:
 1. A single paragraph summary (2-3 sentences)
@@ -129,43 +277,32 @@ BULLETS:
 • [bullet 1]
 • [bullet 2]
 • [bullet 3]
-etc.`, entriesText.String())
+etc.`, scope.accomplishments, voiceInstructions(voice), languageInstructions(scope.language), lightWeekInstructions(scope.missedDays), instructions, heading, body, priorText)
 }
 
-func (s *Service) callClaude(ctx context.Context, prompt string) (*ClaudeResponse, error) {
-	request := ClaudeRequest{
-		AnthropicVersion: "bedrock-2023-05-31",
-		MaxTokens:        1000,
-		Messages: []Message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-	}
-
-	requestBody, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	input := &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(s.config.LLMModel),
-		ContentType: aws.String("application/json"),
-		Body:        requestBody,
-	}
-
-	result, err := s.client.InvokeModel(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to invoke model: %w", err)
+// lightWeekInstructions note the days without entries and soften the tone
+// for a light week, or are empty otherwise
+func lightWeekInstructions(missedDays []time.Time) string {
+	if len(missedDays) == 0 {
+		return ""
 	}
+	return fmt.Sprintf(`
+- This was a light week: there are no entries for %s. Say so plainly and kindly in the summary paragraph
+- Describe only the work in the entries. Never invent, guess or imply what happened on the days without entries
+- Keep the tone gentle and encouraging rather than motivational; don't push for more output`, period.DayList(missedDays))
+}
 
-	var response ClaudeResponse
-	if err := json.Unmarshal(result.Body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+// callClaude sends prompt to modelID through the configured provider and
+// records the call
+func (s *Service) callClaude(ctx context.Context, modelID, prompt string) (*ClaudeResponse, error) {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limiter wait cancelled: %w", err)
 	}
 
-	return &response, nil
+	start := time.Now()
+	response, err := s.provider.Invoke(ctx, modelID, prompt, s.config.LLMMaxTokens)
+	s.logCall(ctx, modelID, time.Since(start), response, err)
+	return response, err
 }
 
 func (s *Service) parseWeeklySummaryResponse(response *ClaudeResponse) (*WeeklySummary, error) {
@@ -253,16 +390,7 @@ func (s *Service) fallbackParse(text string) (*WeeklySummary, error) {
 	}, nil
 }
 
-func (s *Service) estimateCost(usage Usage) int {
-	// Rough cost estimation for Claude Haiku (cheapest model)
-	// Input: ~$0.25 per 1M tokens, Output: ~$1.25 per 1M tokens
-	inputCostCents := (usage.InputTokens * 25) / 1000000  // $0.25 per 1M tokens
-	outputCostCents := (usage.OutputTokens * 125) / 1000000 // $1.25 per 1M tokens
-	
-	totalCents := inputCostCents + outputCostCents
-	if totalCents < 1 {
-		return 1 // Minimum 1 cent
-	}
-	
-	return totalCents
-}
\ No newline at end of file
+// estimateCost is the provider's estimate for usage on modelID, in cents
+func (s *Service) estimateCost(modelID string, usage Usage) int {
+	return s.provider.EstimateCost(modelID, usage)
+}
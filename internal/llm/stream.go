@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/sirupsen/logrus"
+)
+
+// streamEvent is the subset of the Claude messages streaming payload we consume
+type streamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage Usage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Usage Usage `json:"usage"`
+}
+
+// callClaudeStream invokes the model with a response stream and assembles the
+// text deltas into a single ClaudeResponse. Cancelling ctx closes the stream.
+func (p *bedrockProvider) callClaudeStream(ctx context.Context, modelID string, requestBody []byte) (*ClaudeResponse, error) {
+	input := &bedrockruntime.InvokeModelWithResponseStreamInput{
+		ModelId:     aws.String(modelID),
+		ContentType: aws.String("application/json"),
+		Body:        requestBody,
+	}
+
+	output, err := p.client.InvokeModelWithResponseStream(ctx, input)
+	if err != nil {
+		return nil, classifyInvokeError(err, "failed to invoke model with response stream")
+	}
+
+	stream := output.GetStream()
+	defer stream.Close()
+
+	var text strings.Builder
+	var usage Usage
+	chunks := 0
+
+	events := stream.Events()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("response stream cancelled after %d chunks: %w", chunks, ctx.Err())
+		case event, ok := <-events:
+			if !ok {
+				if err := stream.Err(); err != nil {
+					return nil, fmt.Errorf("response stream failed: %w", err)
+				}
+
+				logrus.WithFields(logrus.Fields{
+					"chunks":        chunks,
+					"output_tokens": usage.OutputTokens,
+				}).Debug("Response stream completed")
+
+				return &ClaudeResponse{
+					Content: []ContentBlock{{Type: "text", Text: text.String()}},
+					Usage:   usage,
+				}, nil
+			}
+
+			chunk, isChunk := event.(*types.ResponseStreamMemberChunk)
+			if !isChunk {
+				continue
+			}
+			chunks++
+
+			if err := applyStreamChunk(chunk.Value.Bytes, &text, &usage); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func applyStreamChunk(payload []byte, text *strings.Builder, usage *Usage) error {
+	var event streamEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+	}
+
+	switch event.Type {
+	case "message_start":
+		usage.InputTokens = event.Message.Usage.InputTokens
+	case "content_block_delta":
+		if event.Delta.Type == "text_delta" {
+			text.WriteString(event.Delta.Text)
+		}
+	case "message_delta":
+		usage.OutputTokens = event.Usage.OutputTokens
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// estimateTokens approximates Claude's tokenizer at about four characters per token
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// formatEntries renders entries for a prompt, one per day labelled with
+// dayLayout. Structured entries are indented under their day.
+func formatEntries(entries []*models.Entry, dayLayout string) string {
+	var b strings.Builder
+	for _, entry := range entries {
+		b.WriteString(formatEntry(entry, dayLayout))
+	}
+	return b.String()
+}
+
+func formatEntry(entry *models.Entry, dayLayout string) string {
+	day := entry.EntryDate.Format(dayLayout)
+	structured, ok := structuredEntry(entry)
+	if !ok {
+		return fmt.Sprintf("%s: %s\n", day, entry.RawContent)
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s (%s format):\n", day, structured.Format))
+	for _, line := range strings.Split(structured.String(), "\n") {
+		b.WriteString("  " + line + "\n")
+	}
+	return b.String()
+}
+
+// chunkEntries splits entries into date-ordered chunks that never cross a
+// boundary of a week starting on firstDay and whose formatted text fits
+// budget tokens. A week over budget is split further; a single entry over
+// budget is a chunk of its own.
+func chunkEntries(entries []*models.Entry, dayLayout string, firstDay time.Weekday, budget int) [][]*models.Entry {
+	sorted := make([]*models.Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].EntryDate.Before(sorted[j].EntryDate)
+	})
+
+	var chunks [][]*models.Entry
+	var current []*models.Entry
+	used := 0
+
+	for _, entry := range sorted {
+		tokens := estimateTokens(formatEntry(entry, dayLayout))
+		newWeek := len(current) > 0 && !period.StartOfWeek(entry.EntryDate, firstDay).Equal(period.StartOfWeek(current[0].EntryDate, firstDay))
+		if len(current) > 0 && (newWeek || used+tokens > budget) {
+			chunks = append(chunks, current)
+			current, used = nil, 0
+		}
+		current = append(current, entry)
+		used += tokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
@@ -0,0 +1,263 @@
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Length limits for a generated summary. The prompt asks for a short
+// paragraph and 3-5 bullets; these leave room without letting a rambling
+// response through.
+const (
+	maxParagraphRunes = 1200
+	maxBullets        = 8
+	maxBulletRunes    = 300
+)
+
+// Grounding: a bullet with at least minGroundingKeywords keywords needs
+// minGroundedShare of them to appear in the entries
+const (
+	minGroundingKeywords = 3
+	minGroundedShare     = 0.3
+	keywordStemRunes     = 5
+)
+
+// profanity is screened out of summaries unless the user wrote the word
+// themselves. Matched on whole words, case-insensitively.
+var profanity = []string{
+	"shit", "shitty", "fuck", "fucking", "fucked", "bullshit", "crap", "crappy",
+	"damn", "asshole", "bastard", "bitch", "dick", "piss", "pissed", "wtf",
+}
+
+var (
+	emailRegex  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phoneRegex  = regexp.MustCompile(`\+?\(?\d{3}\)?[-. ]\d{3}[-. ]\d{4}\b`)
+	ssnRegex    = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+	cardRegex   = regexp.MustCompile(`\b(?:\d[ -]?){13,19}\b`)
+	numberRegex = regexp.MustCompile(`\d[\d,.]*%?`)
+	wordRegex   = regexp.MustCompile(`[\p{L}\p{N}']+`)
+)
+
+// stopwords are left out of grounding keywords: function words, and the
+// verbs summaries use to describe any kind of work
+var stopwords = toSet(strings.Fields(`
+	about after again also been before being both came could does doing done during each
+	from have having here into just like made make many more most much other over same
+	some such than that their them then there these they this those through under until
+	very were what when where which while will with would your yours week weeks weekly
+	today yesterday tomorrow monday tuesday wednesday thursday friday saturday sunday
+	worked working work continued continue progress focused focus completed complete
+	finished finish started start made making helped help team across several various
+	multiple including included shipped ship delivered deliver handled handle spent time
+	additionally overall successfully significant solid great good strong productive`))
+
+// CheckSummary returns why summary shouldn't be sent, or nothing if it may
+// be. entries are what the summary must be grounded in.
+func CheckSummary(summary *WeeklySummary, entries []*models.Entry) []string {
+	var problems []string
+
+	if strings.TrimSpace(summary.Paragraph) == "" {
+		problems = append(problems, "the summary paragraph is empty")
+	}
+	if n := len([]rune(summary.Paragraph)); n > maxParagraphRunes {
+		problems = append(problems, fmt.Sprintf("the summary paragraph is %d characters (limit %d)", n, maxParagraphRunes))
+	}
+	if len(summary.BulletPoints) > maxBullets {
+		problems = append(problems, fmt.Sprintf("there are %d bullets (limit %d)", len(summary.BulletPoints), maxBullets))
+	}
+	for _, bullet := range summary.BulletPoints {
+		if n := len([]rune(bullet)); n > maxBulletRunes {
+			problems = append(problems, fmt.Sprintf("a bullet is %d characters (limit %d)", n, maxBulletRunes))
+		}
+	}
+
+	source := entriesText(entries)
+	text := summary.Paragraph + "\n" + strings.Join(summary.BulletPoints, "\n")
+	problems = append(problems, screenText(text, source)...)
+
+	vocabulary := stems(source)
+	for _, bullet := range summary.BulletPoints {
+		if reason := ungrounded(bullet, source, vocabulary); reason != "" {
+			problems = append(problems, fmt.Sprintf("bullet %q %s", bullet, reason))
+		}
+	}
+
+	return problems
+}
+
+// screenText finds profanity and personal data in text. Profanity, email
+// addresses and phone numbers the user wrote in source are allowed; social
+// security and card numbers never are.
+func screenText(text, source string) []string {
+	var problems []string
+
+	lowerSource := strings.ToLower(source)
+	for _, word := range wordRegex.FindAllString(strings.ToLower(text), -1) {
+		for _, bad := range profanity {
+			if word == bad && !containsWord(lowerSource, bad) {
+				problems = append(problems, fmt.Sprintf("it contains profanity (%q)", word))
+			}
+		}
+	}
+
+	for _, match := range emailRegex.FindAllString(text, -1) {
+		if !strings.Contains(lowerSource, strings.ToLower(match)) {
+			problems = append(problems, "it contains an email address that isn't in the entries")
+		}
+	}
+	for _, match := range phoneRegex.FindAllString(text, -1) {
+		if !strings.Contains(source, match) {
+			problems = append(problems, "it contains a phone number that isn't in the entries")
+		}
+	}
+	if ssnRegex.MatchString(text) {
+		problems = append(problems, "it contains what looks like a social security number")
+	}
+	for _, match := range cardRegex.FindAllString(text, -1) {
+		if luhnValid(match) {
+			problems = append(problems, "it contains what looks like a card number")
+			break
+		}
+	}
+
+	return problems
+}
+
+// ungrounded explains why bullet looks like an accomplishment the entries
+// don't mention, or returns "". Numbers must appear in the entries as
+// written; otherwise enough of the bullet's keywords must share a stem with
+// a word in the entries.
+func ungrounded(bullet, source string, vocabulary map[string]bool) string {
+	for _, number := range numberRegex.FindAllString(bullet, -1) {
+		number = strings.TrimRight(number, ".,")
+		if len(strings.Trim(number, "%")) >= 2 && !strings.Contains(source, number) {
+			return fmt.Sprintf("mentions %s, which isn't in the entries", number)
+		}
+	}
+
+	keywords := keywords(bullet)
+	if len(keywords) < minGroundingKeywords {
+		return ""
+	}
+
+	grounded := 0
+	for _, keyword := range keywords {
+		if vocabulary[stem(keyword)] {
+			grounded++
+		}
+	}
+	if float64(grounded)/float64(len(keywords)) < minGroundedShare {
+		return "isn't supported by the entries"
+	}
+	return ""
+}
+
+// regenerationNote is appended to the prompt when a summary is rejected, so
+// the retry knows what to avoid
+func regenerationNote(problems []string) string {
+	return "\n\nA previous draft was rejected because:\n- " + strings.Join(problems, "\n- ") +
+		"\nWrite a new summary that avoids these problems. Only mention work the entries describe."
+}
+
+// entriesText is the text the summary is checked against: each entry as the
+// user wrote it, with its date so summaries may mention days
+func entriesText(entries []*models.Entry) string {
+	var b strings.Builder
+	for _, entry := range entries {
+		b.WriteString(entry.EntryDate.Format("Monday, January 2, 2006 (2006-01-02)\n"))
+		b.WriteString(entry.RawContent)
+		b.WriteString("\n")
+		if entry.ProjectTag != nil {
+			b.WriteString(*entry.ProjectTag)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// keywords are the lowercased words of text that say what was done
+func keywords(text string) []string {
+	var result []string
+	for _, word := range wordRegex.FindAllString(strings.ToLower(text), -1) {
+		word = strings.Trim(word, "'")
+		if len([]rune(word)) < 4 || stopwords[word] || !hasLetter(word) {
+			continue
+		}
+		result = append(result, word)
+	}
+	return result
+}
+
+// stems returns the stem of every word in text
+func stems(text string) map[string]bool {
+	result := make(map[string]bool)
+	for _, word := range wordRegex.FindAllString(strings.ToLower(text), -1) {
+		result[stem(strings.Trim(word, "'"))] = true
+	}
+	return result
+}
+
+// stem is a crude prefix stem, so "migrated" and "migration" match
+func stem(word string) string {
+	if runes := []rune(word); len(runes) > keywordStemRunes {
+		return string(runes[:keywordStemRunes])
+	}
+	return word
+}
+
+func containsWord(text, word string) bool {
+	for _, w := range wordRegex.FindAllString(text, -1) {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLetter(word string) bool {
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// luhnValid reports whether the digits in s pass the Luhn check card
+// numbers use, so order numbers and the like aren't flagged
+func luhnValid(s string) bool {
+	var digits []int
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	if len(digits) < 13 {
+		return false
+	}
+
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if (len(digits)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+func toSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	pkgConfig "github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+)
+
+// vertexRequestTimeout bounds one generateContent call
+const vertexRequestTimeout = 2 * time.Minute
+
+// vertexProvider calls Gemini models through Vertex AI's generateContent
+// REST endpoint, authenticated with Application Default Credentials
+type vertexProvider struct {
+	httpClient *http.Client
+	token      func(ctx context.Context) (string, error)
+	baseURL    string // up to and including .../locations/{location}
+}
+
+func newVertexProvider(ctx context.Context, cfg *pkgConfig.Config) (*vertexProvider, error) {
+	if cfg.VertexProject == "" {
+		return nil, fmt.Errorf("VERTEX_PROJECT is required when LLM_PROVIDER is %s", ProviderVertex)
+	}
+
+	httpClient := &http.Client{Timeout: vertexRequestTimeout}
+	tokens, err := newGoogleTokenSource(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vertexProvider{
+		httpClient: httpClient,
+		token:      tokens.Token,
+		baseURL:    vertexBaseURL(cfg.VertexProject, cfg.VertexLocation),
+	}, nil
+}
+
+// vertexBaseURL is the regional endpoint for location, or the global one
+func vertexBaseURL(project, location string) string {
+	host := location + "-aiplatform.googleapis.com"
+	if location == "global" {
+		host = "aiplatform.googleapis.com"
+	}
+	return fmt.Sprintf("https://%s/v1/projects/%s/locations/%s", host, project, location)
+}
+
+type vertexRequest struct {
+	Contents         []vertexContent        `json:"contents"`
+	GenerationConfig vertexGenerationConfig `json:"generationConfig"`
+}
+
+type vertexContent struct {
+	Role  string       `json:"role"`
+	Parts []vertexPart `json:"parts"`
+}
+
+type vertexPart struct {
+	Text string `json:"text"`
+}
+
+type vertexGenerationConfig struct {
+	MaxOutputTokens int `json:"maxOutputTokens,omitempty"`
+}
+
+type vertexResponse struct {
+	Candidates []struct {
+		Content      vertexContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+type vertexError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// Invoke returns the first candidate's text. LLM_STREAMING doesn't apply:
+// the reply is read in one response.
+func (p *vertexProvider) Invoke(ctx context.Context, modelID, prompt string, maxTokens int) (*ClaudeResponse, error) {
+	requestBody, err := json.Marshal(vertexRequest{
+		Contents:         []vertexContent{{Role: "user", Parts: []vertexPart{{Text: prompt}}}},
+		GenerationConfig: vertexGenerationConfig{MaxOutputTokens: maxTokens},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	token, err := p.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/publishers/google/models/%s:generateContent", p.baseURL, modelID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoke model: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, vertexStatusError(resp.StatusCode, body)
+	}
+
+	var response vertexResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(response.Candidates) == 0 {
+		if reason := response.PromptFeedback.BlockReason; reason != "" {
+			return nil, fmt.Errorf("prompt blocked by Vertex AI: %s", reason)
+		}
+		return nil, fmt.Errorf("empty response from model")
+	}
+
+	var text strings.Builder
+	for _, part := range response.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+
+	return &ClaudeResponse{
+		Content: []ContentBlock{{Type: "text", Text: text.String()}},
+		Usage: Usage{
+			InputTokens:  response.UsageMetadata.PromptTokenCount,
+			OutputTokens: response.UsageMetadata.CandidatesTokenCount,
+		},
+	}, nil
+}
+
+// vertexStatusError tags quota errors so callers can back off
+func vertexStatusError(status int, body []byte) error {
+	var parsed vertexError
+	message := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &parsed); err == nil && parsed.Error.Message != "" {
+		message = parsed.Error.Message
+	}
+
+	if status == http.StatusTooManyRequests || parsed.Error.Status == "RESOURCE_EXHAUSTED" {
+		return apperrors.New(apperrors.CodeLLMThrottled, "Vertex AI throttled the request: %s", message)
+	}
+	return fmt.Errorf("Vertex AI returned %d: %s", status, message)
+}
+
+// vertexPrices are cents per million input and output tokens, matched by
+// model ID prefix, most specific first
+var vertexPrices = []struct {
+	prefix        string
+	input, output float64
+}{
+	{"gemini-2.5-pro", 125, 1000},
+	{"gemini-2.5-flash", 30, 250},
+	{"gemini-2.0-flash-lite", 7.5, 30},
+	{"gemini-2.0-flash", 15, 60},
+	{"gemini-1.5-pro", 125, 500},
+	{"gemini-1.5-flash", 7.5, 30},
+}
+
+// EstimateCost prices unknown models like gemini-2.5-pro so new models are
+// over- rather than under-estimated
+func (p *vertexProvider) EstimateCost(modelID string, usage Usage) int {
+	for _, price := range vertexPrices {
+		if strings.HasPrefix(modelID, price.prefix) {
+			return centsFor(usage, price.input, price.output)
+		}
+	}
+	return centsFor(usage, vertexPrices[0].input, vertexPrices[0].output)
+}
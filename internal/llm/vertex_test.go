@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+)
+
+func testVertexProvider(handler http.HandlerFunc) (*vertexProvider, func()) {
+	server := httptest.NewServer(handler)
+	return &vertexProvider{
+		httpClient: server.Client(),
+		token:      func(ctx context.Context) (string, error) { return "test-token", nil },
+		baseURL:    server.URL + "/v1/projects/p/locations/us-central1",
+	}, server.Close
+}
+
+func TestVertexInvoke(t *testing.T) {
+	provider, done := testVertexProvider(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/projects/p/locations/us-central1/publishers/google/models/gemini-2.0-flash:generateContent" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+
+		var request vertexRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if request.GenerationConfig.MaxOutputTokens != 500 || request.Contents[0].Parts[0].Text != "Summarize" {
+			t.Errorf("request = %+v", request)
+		}
+
+		w.Write([]byte(`{
+			"candidates": [{"content": {"role": "model", "parts": [{"text": "SUMMARY: "}, {"text": "Shipped it."}]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 1200, "candidatesTokenCount": 80, "totalTokenCount": 1280}
+		}`))
+	})
+	defer done()
+
+	response, err := provider.Invoke(context.Background(), "gemini-2.0-flash", "Summarize", 500)
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if response.Content[0].Text != "SUMMARY: Shipped it." {
+		t.Errorf("text = %q", response.Content[0].Text)
+	}
+	if response.Usage.InputTokens != 1200 || response.Usage.OutputTokens != 80 {
+		t.Errorf("usage = %+v", response.Usage)
+	}
+}
+
+func TestVertexInvokeErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		wantThrottled bool
+	}{
+		{"rate limited", http.StatusTooManyRequests, `{"error": {"code": 429, "message": "Quota exceeded", "status": "RESOURCE_EXHAUSTED"}}`, true},
+		{"bad request", http.StatusBadRequest, `{"error": {"code": 400, "message": "Invalid model", "status": "INVALID_ARGUMENT"}}`, false},
+		{"blocked prompt", http.StatusOK, `{"promptFeedback": {"blockReason": "SAFETY"}}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, done := testVertexProvider(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			defer done()
+
+			_, err := provider.Invoke(context.Background(), "gemini-2.0-flash", "Summarize", 500)
+			if err == nil {
+				t.Fatal("Invoke() succeeded")
+			}
+			if throttled := apperrors.Is(err, apperrors.CodeLLMThrottled); throttled != tt.wantThrottled {
+				t.Errorf("throttled = %v, want %v (error %v)", throttled, tt.wantThrottled, err)
+			}
+		})
+	}
+}
+
+func TestVertexEstimateCost(t *testing.T) {
+	provider := &vertexProvider{}
+	usage := Usage{InputTokens: 2000000, OutputTokens: 1000000}
+
+	tests := map[string]int{
+		"gemini-2.0-flash-001":      90,   // 2 x 15 + 1 x 60
+		"gemini-2.0-flash-lite-001": 45,   // 2 x 7.5 + 1 x 30
+		"gemini-1.5-pro-002":        750,  // 2 x 125 + 1 x 500
+		"gemini-9-ultra":            1250, // priced like gemini-2.5-pro
+	}
+	for modelID, want := range tests {
+		if got := provider.EstimateCost(modelID, usage); got != want {
+			t.Errorf("EstimateCost(%s) = %d, want %d", modelID, got, want)
+		}
+	}
+
+	if got := provider.EstimateCost("gemini-2.0-flash", Usage{InputTokens: 10}); got != 1 {
+		t.Errorf("EstimateCost of a tiny call = %d, want the 1 cent minimum", got)
+	}
+}
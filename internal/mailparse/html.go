@@ -0,0 +1,64 @@
+package mailparse
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlDetectRegex = regexp.MustCompile(`(?is)^\s*(<!doctype\s+html|<html|<head|<body|<div|<p[\s>]|<meta|<table)|</(div|p|br|html|body)>`)
+
+	// Elements whose content is never part of the message
+	htmlInvisibleRegex = regexp.MustCompile(`(?is)<(head|style|script|title)\b.*?</(head|style|script|title)>|<!--.*?-->`)
+
+	// Where each client's quoted reply chain begins: Gmail's gmail_quote
+	// container, Apple Mail's <blockquote type="cite">, Outlook's reply
+	// header and divider, Thunderbird's cite prefix and Yahoo's quote wrapper
+	htmlQuoteStartRegex = regexp.MustCompile(`(?is)<div[^>]*class="[^"]*gmail_quote[^"]*"|` +
+		`<blockquote[^>]*type="cite"|` +
+		`<div[^>]*id="(divRplyFwdMsg|appendonsend)"|` +
+		`<div[^>]*class="[^"]*(moz-cite-prefix|yahoo_quoted)[^"]*"|` +
+		`<hr[^>]*id="stopSpelling"`)
+
+	htmlLineBreakRegex = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlBlockRegex     = regexp.MustCompile(`(?i)</?(p|div|h[1-6]|tr|table|blockquote|pre|ul|ol)\b[^>]*>|<hr[^>]*>`)
+	htmlListItemRegex  = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlCellRegex      = regexp.MustCompile(`(?i)</t[dh]>`)
+	htmlTagRegex       = regexp.MustCompile(`<[^>]*>`)
+	htmlSpaceRunsRegex = regexp.MustCompile(`[ \t\x{00a0}]+`)
+	htmlBlockRunsRegex = regexp.MustCompile(`\x00[ \x00]*`)
+)
+
+// IsHTML reports whether body looks like an HTML document or fragment rather
+// than plain text that happens to contain an angle bracket
+func IsHTML(body string) bool {
+	return htmlDetectRegex.MatchString(body)
+}
+
+// HTMLToText renders an HTML email body as plain text, dropping the quoted
+// reply chain that follows the first quote marker. Each <br> is a line break,
+// adjacent block elements together start one new line, and list items
+// become "- " lines.
+func HTMLToText(body string) string {
+	body = htmlInvisibleRegex.ReplaceAllString(body, "")
+	if loc := htmlQuoteStartRegex.FindStringIndex(body); loc != nil {
+		body = body[:loc[0]]
+	}
+
+	// Source line breaks are just formatting in HTML
+	body = strings.NewReplacer("\r", " ", "\n", " ").Replace(body)
+
+	// Block boundaries are marked with NUL so nested and adjacent blocks
+	// collapse into a single line break once the tags are gone
+	body = htmlLineBreakRegex.ReplaceAllString(body, "\n")
+	body = htmlBlockRegex.ReplaceAllString(body, "\x00")
+	body = htmlListItemRegex.ReplaceAllString(body, "\x00- ")
+	body = htmlCellRegex.ReplaceAllString(body, " ")
+	body = htmlTagRegex.ReplaceAllString(body, "")
+	body = html.UnescapeString(body)
+	body = htmlSpaceRunsRegex.ReplaceAllString(body, " ")
+	body = htmlBlockRunsRegex.ReplaceAllString(body, "\n")
+
+	return normalizeLines(body)
+}
@@ -0,0 +1,42 @@
+// Package mailparse extracts what a user actually wrote from an inbound
+// email body: HTML is converted to text, and the quoted reply chain and
+// signature that mail clients append are removed.
+//
+// testdata holds sample replies from Gmail, Outlook and Apple Mail, in plain
+// text (.txt) and HTML (.html), each with the expected extraction (.want).
+package mailparse
+
+import (
+	"strings"
+)
+
+// ExtractReply returns the new text of a reply, in either plain text or HTML.
+// Lines are trimmed and runs of blank lines collapsed so paragraphs survive.
+func ExtractReply(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	if IsHTML(body) {
+		body = HTMLToText(body)
+	}
+
+	text := StripSignature(StripQuoted(body))
+	return normalizeLines(text)
+}
+
+// normalizeLines trims every line and keeps at most one blank line in a row
+func normalizeLines(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
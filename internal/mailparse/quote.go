@@ -0,0 +1,67 @@
+package mailparse
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Attribution lines that introduce a quoted message, e.g. Gmail and Apple
+	// Mail's "On Thu, May 2, 2024 at 9:00 AM Jane <jane@example.com> wrote:"
+	// and their French, German and Spanish equivalents. Gmail wraps long
+	// attributions, so they are matched against two joined lines.
+	attributionRegex = regexp.MustCompile(`(?i)^(on|le|am|el)\s.{0,300}(wrote|a écrit|schrieb|escribió)(\s.{0,200})?\s*:$`)
+
+	// Outlook and other clients separate the original message with a divider
+	// or a header block
+	originalMessageRegex = regexp.MustCompile(`(?i)^-{2,}\s*(original message|forwarded message|ursprüngliche nachricht|message d'origine)\s*-{2,}$`)
+	underscoreRuleRegex  = regexp.MustCompile(`^_{10,}$`)
+	headerFromRegex      = regexp.MustCompile(`(?i)^\*?(from|von|de)\s*:\*?\s`)
+	headerFieldRegex     = regexp.MustCompile(`(?i)^\*?(sent|date|to|subject|cc|gesendet|datum|an|betreff|envoyé|objet|à)\s*:`)
+)
+
+// StripQuoted removes the quoted reply chain from a plain text reply: lines
+// from the first attribution, divider or Outlook header block onwards, and
+// any other lines quoted with ">"
+func StripQuoted(text string) string {
+	lines := strings.Split(text, "\n")
+	var kept []string
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+
+		if isQuoteStart(lines, i) {
+			break
+		}
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+		kept = append(kept, lines[i])
+	}
+
+	return strings.Join(kept, "\n")
+}
+
+// isQuoteStart reports whether the reply chain begins at lines[i]
+func isQuoteStart(lines []string, i int) bool {
+	line := strings.TrimSpace(lines[i])
+	next := ""
+	if i+1 < len(lines) {
+		next = strings.TrimSpace(lines[i+1])
+	}
+
+	switch {
+	case attributionRegex.MatchString(line):
+		return true
+	case next != "" && attributionRegex.MatchString(line+" "+next):
+		return true
+	case originalMessageRegex.MatchString(line):
+		return true
+	case underscoreRuleRegex.MatchString(line) && headerFromRegex.MatchString(next):
+		return true
+	case headerFromRegex.MatchString(line) && headerFieldRegex.MatchString(next):
+		// Outlook's plain text header block: From: followed by Sent:/To:
+		return true
+	}
+	return false
+}
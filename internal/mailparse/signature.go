@@ -0,0 +1,28 @@
+package mailparse
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// The RFC 3676 delimiter "-- ", which some clients trim to "--"
+	signatureDelimiterRegex = regexp.MustCompile(`^--\s*$`)
+
+	// Footers mobile and desktop clients add on their own
+	clientFooterRegex = regexp.MustCompile(`(?i)^(sent from (my )?(iphone|ipad|android|mobile|samsung|galaxy|blackberry|mail for windows|outlook|yahoo mail|proton ?mail)|` +
+		`sent via |get outlook for (ios|android)|sent with proton ?mail|envoyé de mon|von meinem .* gesendet)`)
+)
+
+// StripSignature removes everything after a signature delimiter or a client
+// footer such as "Sent from my iPhone"
+func StripSignature(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if signatureDelimiterRegex.MatchString(line) || clientFooterRegex.MatchString(line) {
+			return strings.Join(lines[:i], "\n")
+		}
+	}
+	return text
+}
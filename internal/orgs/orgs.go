@@ -0,0 +1,132 @@
+// Package orgs groups users into organizations for team billing and meters
+// each organization's usage: members, prompts, summaries and LLM spend. The
+// meter-usage job records every closed day in usage_records, which metered
+// billing reads from.
+package orgs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+type Service struct {
+	db *database.DB
+}
+
+func NewService(db *database.DB) *Service {
+	return &Service{db: db}
+}
+
+// Create adds an organization. Names are unique.
+func (s *Service) Create(ctx context.Context, name string) (*models.Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "organization name is required")
+	}
+
+	query := `
+		INSERT INTO organizations (name) VALUES ($1)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, name, created_at`
+
+	var org models.Organization
+	err := s.db.QueryRowContext(ctx, query, name).Scan(&org.ID, &org.Name, &org.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeConflict, "organization already exists: %s", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	return &org, nil
+}
+
+// Get returns organization id with its member count
+func (s *Service) Get(ctx context.Context, id int) (*models.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.created_at, (SELECT COUNT(*) FROM users u WHERE u.organization_id = o.id)
+		FROM organizations o
+		WHERE o.id = $1`
+
+	var org models.Organization
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&org.ID, &org.Name, &org.CreatedAt, &org.Members)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeNotFound, "organization not found: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return &org, nil
+}
+
+// List returns every organization with its member count
+func (s *Service) List(ctx context.Context) ([]*models.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.created_at, COUNT(u.id)
+		FROM organizations o
+		LEFT JOIN users u ON u.organization_id = o.id
+		GROUP BY o.id
+		ORDER BY o.id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []*models.Organization
+	for rows.Next() {
+		var org models.Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.CreatedAt, &org.Members); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, &org)
+	}
+	return orgs, rows.Err()
+}
+
+// SetUserOrganization moves userID into organization orgID, or out of any
+// organization when orgID is nil
+func (s *Service) SetUserOrganization(ctx context.Context, userID int, orgID *int) error {
+	if orgID != nil {
+		if _, err := s.Get(ctx, *orgID); err != nil {
+			return err
+		}
+	}
+
+	result, err := s.db.ExecContext(ctx, `UPDATE users SET organization_id = $2, updated_at = NOW() WHERE id = $1`, userID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to set user organization: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return apperrors.New(apperrors.CodeUserNotFound, "user not found: %d", userID)
+	}
+	return nil
+}
+
+// SetCalendar sets the summary calendar of organization orgID's members who
+// have none of their own; the zero Calendar clears it
+func (s *Service) SetCalendar(ctx context.Context, orgID int, calendar period.Calendar) error {
+	var weeks *int
+	var end *time.Time
+	if !calendar.IsZero() {
+		weeks, end = &calendar.Weeks, &calendar.End
+	}
+
+	query := `UPDATE organizations SET summary_period_weeks = $2, summary_period_end = $3 WHERE id = $1`
+	result, err := s.db.ExecContext(ctx, query, orgID, weeks, end)
+	if err != nil {
+		return fmt.Errorf("failed to set organization calendar: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return apperrors.New(apperrors.CodeNotFound, "organization not found: %d", orgID)
+	}
+	return nil
+}
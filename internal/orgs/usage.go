@@ -0,0 +1,142 @@
+package orgs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Metered metrics. Users is the organization's verified members that day;
+// the rest are what happened that day, LLM spend in estimated cents.
+const (
+	MetricUsers        = "users"
+	MetricPrompts      = "prompts"
+	MetricSummaries    = "summaries"
+	MetricLLMCostCents = "llm_cost_cents"
+)
+
+// How a record combines with the billing period's usage, as in Stripe's
+// usage records: increments add to it, set replaces it
+const (
+	ActionIncrement = "increment"
+	ActionSet       = "set"
+)
+
+// MeterDays is how many closed days each metering run records again, so a
+// missed run is caught up by the next
+const MeterDays = 3
+
+const dayLayout = "2006-01-02"
+
+// MeterRecent meters the MeterDays days before now's UTC day
+func (s *Service) MeterRecent(ctx context.Context, now time.Time) (int64, error) {
+	today := now.UTC().Truncate(24 * time.Hour)
+
+	var total int64
+	for i := MeterDays; i >= 1; i-- {
+		n, err := s.Meter(ctx, today.AddDate(0, 0, -i))
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Meter records every organization's usage on the UTC day of day, one
+// record per metric, and returns how many were written. Records already
+// reported to billing are left as they are. LLM calls are pruned by the
+// llm_calls retention policy, so days must be metered within it.
+func (s *Service) Meter(ctx context.Context, day time.Time) (int64, error) {
+	start := day.UTC().Truncate(24 * time.Hour)
+	end := start.AddDate(0, 0, 1)
+
+	query := `
+		INSERT INTO usage_records (organization_id, metric, usage_date, quantity, action, idempotency_key)
+		SELECT o.id, m.metric, $3::date, m.quantity, m.action,
+			'usage-' || o.id || '-' || m.metric || '-' || $3::text
+		FROM organizations o
+		CROSS JOIN LATERAL (VALUES
+			($4::text, (SELECT COUNT(*) FROM users u
+				WHERE u.organization_id = o.id AND u.is_verified = TRUE AND u.created_at < $2), $8::text),
+			($5::text, (SELECT COUNT(*) FROM prompt_sends ps JOIN users u ON u.id = ps.user_id
+				WHERE u.organization_id = o.id AND ps.created_at >= $1 AND ps.created_at < $2), $9::text),
+			($6::text, (SELECT COUNT(*) FROM weekly_summaries ws JOIN users u ON u.id = ws.user_id
+				WHERE u.organization_id = o.id AND ws.created_at >= $1 AND ws.created_at < $2), $9::text),
+			($7::text, (SELECT COALESCE(SUM(lc.cost_cents), 0) FROM llm_calls lc JOIN users u ON u.id = lc.user_id
+				WHERE u.organization_id = o.id AND lc.created_at >= $1 AND lc.created_at < $2), $9::text)
+		) AS m(metric, quantity, action)
+		WHERE o.created_at < $2
+		ON CONFLICT (organization_id, metric, usage_date) DO UPDATE
+		SET quantity = EXCLUDED.quantity, updated_at = NOW()
+		WHERE usage_records.reported_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, start, end, start.Format(dayLayout),
+		MetricUsers, MetricPrompts, MetricSummaries, MetricLLMCostCents, ActionSet, ActionIncrement)
+	if err != nil {
+		return 0, fmt.Errorf("failed to meter usage for %s: %w", start.Format(dayLayout), err)
+	}
+	return result.RowsAffected()
+}
+
+// Usage returns organization orgID's metered usage in the month of month,
+// with the daily records it is made of
+func (s *Service) Usage(ctx context.Context, orgID int, month time.Time) (*models.OrgUsage, error) {
+	if _, err := s.Get(ctx, orgID); err != nil {
+		return nil, err
+	}
+
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	usage := &models.OrgUsage{OrganizationID: orgID, Month: from.Format("2006-01"), Records: []models.UsageRecord{}}
+
+	query := `
+		SELECT id, organization_id, metric, usage_date, quantity, action, idempotency_key, reported_at
+		FROM usage_records
+		WHERE organization_id = $1 AND usage_date >= $2 AND usage_date < $3
+		ORDER BY usage_date, metric`
+
+	rows, err := s.db.QueryContext(ctx, query, orgID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage records: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record models.UsageRecord
+		err := rows.Scan(&record.ID, &record.OrganizationID, &record.Metric, &record.UsageDate,
+			&record.Quantity, &record.Action, &record.IdempotencyKey, &record.ReportedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan usage record: %w", err)
+		}
+		addRecord(usage, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query usage records: %w", err)
+	}
+	return usage, nil
+}
+
+// addRecord adds a day's record to usage: the peak for users, the total for
+// everything else
+func addRecord(usage *models.OrgUsage, record models.UsageRecord) {
+	switch record.Metric {
+	case MetricUsers:
+		if record.Quantity > usage.Users {
+			usage.Users = record.Quantity
+		}
+	case MetricPrompts:
+		usage.Prompts += record.Quantity
+	case MetricSummaries:
+		usage.Summaries += record.Quantity
+	case MetricLLMCostCents:
+		usage.LLMCostCents += record.Quantity
+	}
+	if usage.MeteredThrough == nil || record.UsageDate.After(*usage.MeteredThrough) {
+		day := record.UsageDate
+		usage.MeteredThrough = &day
+	}
+	usage.Records = append(usage.Records, record)
+}
@@ -0,0 +1,40 @@
+package orgs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+func TestAddRecord(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+	records := []models.UsageRecord{
+		{Metric: MetricUsers, UsageDate: day(1), Quantity: 4},
+		{Metric: MetricPrompts, UsageDate: day(1), Quantity: 4},
+		{Metric: MetricUsers, UsageDate: day(2), Quantity: 6},
+		{Metric: MetricPrompts, UsageDate: day(2), Quantity: 5},
+		{Metric: MetricSummaries, UsageDate: day(3), Quantity: 5},
+		{Metric: MetricLLMCostCents, UsageDate: day(3), Quantity: 12},
+		{Metric: MetricUsers, UsageDate: day(3), Quantity: 5},
+		{Metric: MetricLLMCostCents, UsageDate: day(2), Quantity: 3},
+	}
+
+	usage := &models.OrgUsage{}
+	for _, record := range records {
+		addRecord(usage, record)
+	}
+
+	if usage.Users != 6 {
+		t.Errorf("Users = %d, want the peak 6", usage.Users)
+	}
+	if usage.Prompts != 9 || usage.Summaries != 5 || usage.LLMCostCents != 15 {
+		t.Errorf("totals = %d prompts, %d summaries, %d cents, want 9, 5, 15", usage.Prompts, usage.Summaries, usage.LLMCostCents)
+	}
+	if usage.MeteredThrough == nil || !usage.MeteredThrough.Equal(day(3)) {
+		t.Errorf("MeteredThrough = %v, want %v", usage.MeteredThrough, day(3))
+	}
+	if len(usage.Records) != len(records) {
+		t.Errorf("got %d records, want %d", len(usage.Records), len(records))
+	}
+}
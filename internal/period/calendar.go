@@ -0,0 +1,74 @@
+package period
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxCalendarWeeks is the longest summary period a calendar may have
+const MaxCalendarWeeks = 8
+
+// Calendar lays summary periods out as back-to-back runs of Weeks weeks,
+// one of which ends on End, for teams that report by fiscal week or sprint.
+// The zero Calendar is unset: summaries follow ordinary weeks.
+type Calendar struct {
+	Weeks int
+	End   time.Time // the last day of any one period, at midnight UTC
+}
+
+// NewCalendar returns a calendar of weeks-week periods, one of which ends
+// on the day of end
+func NewCalendar(weeks int, end time.Time) (Calendar, error) {
+	if weeks < 1 || weeks > MaxCalendarWeeks {
+		return Calendar{}, fmt.Errorf("invalid period length: %d weeks (expected 1 to %d)", weeks, MaxCalendarWeeks)
+	}
+	return Calendar{Weeks: weeks, End: midnight(end)}, nil
+}
+
+// IsZero reports whether the calendar is unset
+func (c Calendar) IsZero() bool {
+	return c.Weeks == 0
+}
+
+// Bounds returns the first and last days of the period containing t, at
+// midnight UTC
+func (c Calendar) Bounds(t time.Time) (start, end time.Time) {
+	days := 7 * c.Weeks
+	first := c.End.AddDate(0, 0, 1) // a period starts the day after End
+	offset := int(midnight(t).Sub(first).Hours() / 24)
+
+	periods := offset / days
+	if offset < 0 && offset%days != 0 {
+		periods--
+	}
+	start = first.AddDate(0, 0, periods*days)
+	return start, start.AddDate(0, 0, days-1)
+}
+
+// Closing returns the period a summary run at run covers when runs come
+// every interval: the first run on or after a period's last day covers it.
+// ok is false when no period ended since the run before.
+func (c Calendar) Closing(run time.Time, interval time.Duration) (start, end time.Time, ok bool) {
+	runDay := midnight(run)
+	previousRunDay := midnight(run.Add(-interval))
+
+	start, end = c.Bounds(run)
+	if end.Equal(runDay) {
+		return start, end, true
+	}
+	start, end = c.Bounds(start.AddDate(0, 0, -1))
+	return start, end, end.After(previousRunDay)
+}
+
+// String describes the calendar, as "2-week periods, one ending Fri May 10, 2024"
+func (c Calendar) String() string {
+	if c.IsZero() {
+		return "calendar weeks"
+	}
+	return fmt.Sprintf("%d-week periods, one ending %s", c.Weeks, c.End.Format("Mon Jan 2, 2006"))
+}
+
+func midnight(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,69 @@
+package period
+
+import (
+	"testing"
+	"time"
+)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCalendarBounds(t *testing.T) {
+	// Two-week sprints, one of which ends Friday May 10
+	sprints, err := NewCalendar(2, day(time.May, 10).Add(15*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		at         time.Time
+		start, end time.Time
+	}{
+		{day(time.May, 10).Add(23 * time.Hour), day(time.April, 27), day(time.May, 10)},
+		{day(time.May, 11), day(time.May, 11), day(time.May, 24)},
+		{day(time.May, 24), day(time.May, 11), day(time.May, 24)},
+		{day(time.April, 26), day(time.April, 13), day(time.April, 26)},
+		{day(time.March, 1), day(time.February, 17), day(time.March, 1)},
+	}
+	for _, tt := range tests {
+		start, end := sprints.Bounds(tt.at)
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Errorf("Bounds(%s) = %s - %s, want %s - %s", tt.at.Format(time.RFC3339),
+				start.Format("Jan 2"), end.Format("Jan 2"), tt.start.Format("Jan 2"), tt.end.Format("Jan 2"))
+		}
+	}
+}
+
+func TestCalendarClosing(t *testing.T) {
+	week := 7 * 24 * time.Hour
+	friday := func(d int) time.Time { return day(time.May, d).Add(16*time.Hour + 30*time.Minute) }
+
+	tests := []struct {
+		name     string
+		calendar Calendar
+		run      time.Time
+		ok       bool
+		end      time.Time
+	}{
+		{"sprint ends on the run's day", Calendar{Weeks: 2, End: day(time.May, 10)}, friday(10), true, day(time.May, 10)},
+		{"a week into the next sprint", Calendar{Weeks: 2, End: day(time.May, 10)}, friday(17), false, time.Time{}},
+		{"sprint ended Tuesday", Calendar{Weeks: 2, End: day(time.May, 14)}, friday(17), true, day(time.May, 14)},
+		{"Tuesday sprint, next run", Calendar{Weeks: 2, End: day(time.May, 14)}, friday(24), false, time.Time{}},
+		{"fiscal weeks ending Thursday", Calendar{Weeks: 1, End: day(time.May, 9)}, friday(17), true, day(time.May, 16)},
+	}
+	for _, tt := range tests {
+		_, end, ok := tt.calendar.Closing(tt.run, week)
+		if ok != tt.ok || (ok && !end.Equal(tt.end)) {
+			t.Errorf("%s: Closing() = %s, %v, want %s, %v", tt.name, end.Format("Jan 2"), ok, tt.end.Format("Jan 2"), tt.ok)
+		}
+	}
+}
+
+func TestNewCalendarRejectsLength(t *testing.T) {
+	for _, weeks := range []int{0, -1, MaxCalendarWeeks + 1} {
+		if _, err := NewCalendar(weeks, day(time.May, 10)); err == nil {
+			t.Errorf("NewCalendar(%d) succeeded, want an error", weeks)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package period centralizes the week and quarter boundary calculations
+// shared by the scheduler, CLI and summary rendering.
+package period
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Week start preference values
+const (
+	WeekStartMonday = "monday"
+	WeekStartSunday = "sunday"
+)
+
+// ParseWeekStart validates a week start preference and normalizes it
+func ParseWeekStart(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "mon", WeekStartMonday:
+		return WeekStartMonday, nil
+	case "sun", WeekStartSunday:
+		return WeekStartSunday, nil
+	}
+	return "", fmt.Errorf("invalid week start: %s (expected Monday or Sunday)", value)
+}
+
+// FirstWeekday returns the weekday a week begins on for a stored preference,
+// defaulting to Monday
+func FirstWeekday(weekStart string) time.Weekday {
+	if strings.EqualFold(weekStart, WeekStartSunday) {
+		return time.Sunday
+	}
+	return time.Monday
+}
+
+// StartOfWeek returns midnight UTC on the first day of the week containing t
+func StartOfWeek(t time.Time, firstDay time.Weekday) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) - int(firstDay) + 7) % 7
+	start := t.AddDate(0, 0, -offset)
+	return time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// SummaryEnd returns the Friday that closes the reporting week beginning at start
+func SummaryEnd(start time.Time) time.Time {
+	offset := (int(time.Friday) - int(start.Weekday()) + 7) % 7
+	return start.AddDate(0, 0, offset)
+}
+
+// StartOfQuarter returns midnight UTC on the first day of t's calendar quarter
+func StartOfQuarter(t time.Time) time.Time {
+	t = t.UTC()
+	month := time.Month((int(t.Month())-1)/3*3 + 1)
+	return time.Date(t.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// PreviousQuarter returns the first day of the quarter before now's, the
+// quarter a rollup sent at the start of a quarter covers
+func PreviousQuarter(now time.Time) time.Time {
+	return StartOfQuarter(now).AddDate(0, -3, 0)
+}
+
+// ParseQuarter parses a quarter written as 2024Q2 (or 2024-Q2) into its
+// first day
+func ParseQuarter(value string) (time.Time, error) {
+	var year, quarter int
+	normalized := strings.ToUpper(strings.Replace(strings.TrimSpace(value), "-", "", 1))
+	if _, err := fmt.Sscanf(normalized, "%4dQ%1d", &year, &quarter); err != nil || quarter < 1 || quarter > 4 || len(normalized) != 6 {
+		return time.Time{}, fmt.Errorf("invalid quarter: %s (expected e.g. 2024Q2)", value)
+	}
+	return time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+// QuarterLabel formats the quarter starting at start as 2024Q2
+func QuarterLabel(start time.Time) string {
+	return fmt.Sprintf("%dQ%d", start.Year(), (int(start.Month())-1)/3+1)
+}
+
+// DurationLabel describes the days from through to, inclusive, roughly:
+// "5 days", "3 weeks" or "3 months"
+func DurationLabel(from, to time.Time) string {
+	days := int(to.Sub(from).Hours()/24) + 1
+	switch {
+	case days == 1:
+		return "1 day"
+	case days < 14:
+		return fmt.Sprintf("%d days", days)
+	case days < 60:
+		return fmt.Sprintf("%d weeks", (days+3)/7)
+	}
+	return fmt.Sprintf("%d months", (days+15)/30)
+}
+
+// DayList names days in order: "Wed May 8, Thu May 9 and Fri May 10"
+func DayList(days []time.Time) string {
+	labels := make([]string, len(days))
+	for i, day := range days {
+		labels[i] = day.Format("Mon Jan 2")
+	}
+	if len(labels) <= 1 {
+		return strings.Join(labels, "")
+	}
+	return strings.Join(labels[:len(labels)-1], ", ") + " and " + labels[len(labels)-1]
+}
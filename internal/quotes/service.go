@@ -0,0 +1,195 @@
+// Package quotes stores the motivational quotes shown in daily prompts and
+// picks one per prompt without repeating a quote for a user within a month.
+package quotes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// MaxTextLength caps quote text so a submission fits the prompt layout
+const MaxTextLength = 300
+
+type Service struct {
+	db *database.DB
+}
+
+func NewService(db *database.DB) *Service {
+	return &Service{db: db}
+}
+
+// Update holds the fields to change on a quote; nil fields are left as is
+type Update struct {
+	Text     *string `json:"text,omitempty"`
+	Author   *string `json:"author,omitempty"`
+	IsActive *bool   `json:"is_active,omitempty"`
+}
+
+const quoteColumns = `id, text, author, submitted_by, is_active, created_at, updated_at`
+
+func scanQuote(row interface{ Scan(...interface{}) error }) (*models.Quote, error) {
+	var quote models.Quote
+	var author sql.NullString
+	var submittedBy sql.NullInt64
+
+	err := row.Scan(&quote.ID, &quote.Text, &author, &submittedBy, &quote.IsActive, &quote.CreatedAt, &quote.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if author.Valid {
+		quote.Author = &author.String
+	}
+	if submittedBy.Valid {
+		id := int(submittedBy.Int64)
+		quote.SubmittedBy = &id
+	}
+	return &quote, nil
+}
+
+// ParseSubmission splits "text - author" into its parts. The author is
+// optional.
+func ParseSubmission(value string) (string, *string, error) {
+	value = strings.TrimSpace(value)
+	text, author := value, ""
+	if i := strings.LastIndex(value, " - "); i > 0 {
+		text, author = strings.TrimSpace(value[:i]), strings.TrimSpace(value[i+3:])
+	}
+
+	text = strings.Trim(text, `"“”`)
+	if text == "" {
+		return "", nil, fmt.Errorf("quote text is empty")
+	}
+	if len([]rune(text)) > MaxTextLength {
+		return "", nil, fmt.Errorf("quote is longer than %d characters", MaxTextLength)
+	}
+
+	if author == "" {
+		return text, nil, nil
+	}
+	return text, &author, nil
+}
+
+// Add stores a quote. Admin quotes are active immediately; submissions from a
+// user (submittedBy set) wait for approval.
+func (s *Service) Add(ctx context.Context, text string, author *string, submittedBy *int) (*models.Quote, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "quote text is required")
+	}
+
+	query := `
+		INSERT INTO quotes (text, author, submitted_by, is_active)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (text) DO NOTHING
+		RETURNING ` + quoteColumns
+
+	quote, err := scanQuote(s.db.QueryRowContext(ctx, query, text, author, submittedBy, submittedBy == nil))
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "quote already exists")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to add quote: %w", err)
+	}
+
+	return quote, nil
+}
+
+// List returns quotes ordered by id. Inactive quotes, including submissions
+// awaiting approval, are included when includeInactive is set.
+func (s *Service) List(ctx context.Context, includeInactive bool) ([]*models.Quote, error) {
+	query := `SELECT ` + quoteColumns + ` FROM quotes WHERE is_active OR $1 ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quotes: %w", err)
+	}
+	defer rows.Close()
+
+	var quotes []*models.Quote
+	for rows.Next() {
+		quote, err := scanQuote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan quote: %w", err)
+		}
+		quotes = append(quotes, quote)
+	}
+
+	return quotes, rows.Err()
+}
+
+// Update changes the given fields of a quote
+func (s *Service) Update(ctx context.Context, id int, update Update) (*models.Quote, error) {
+	if update.Text != nil && strings.TrimSpace(*update.Text) == "" {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "quote text cannot be empty")
+	}
+
+	query := `
+		UPDATE quotes
+		SET text = COALESCE($2, text),
+		    author = COALESCE($3, author),
+		    is_active = COALESCE($4, is_active),
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + quoteColumns
+
+	quote, err := scanQuote(s.db.QueryRowContext(ctx, query, id, update.Text, update.Author, update.IsActive))
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "quote %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update quote: %w", err)
+	}
+
+	return quote, nil
+}
+
+// Remove deletes a quote and its delivery history
+func (s *Service) Remove(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM quotes WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to remove quote: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.New(apperrors.CodeInvalidInput, "quote %d not found", id)
+	}
+
+	return nil
+}
+
+// Pick chooses a random active quote the user hasn't been shown this calendar
+// month and records the delivery. Once every quote has been shown it picks
+// from the full set again. It returns nil when there are no active quotes.
+func (s *Service) Pick(ctx context.Context, userID int) (*models.Quote, error) {
+	query := `
+		SELECT ` + quoteColumns + ` FROM quotes q
+		WHERE is_active
+		ORDER BY EXISTS (
+			SELECT 1 FROM quote_deliveries d
+			WHERE d.quote_id = q.id AND d.user_id = $1
+			  AND d.shown_at >= date_trunc('month', NOW())
+		), random()
+		LIMIT 1`
+
+	quote, err := scanQuote(s.db.QueryRowContext(ctx, query, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pick quote: %w", err)
+	}
+
+	query = `INSERT INTO quote_deliveries (user_id, quote_id) VALUES ($1, $2)`
+	if _, err := s.db.ExecContext(ctx, query, userID, quote.ID); err != nil {
+		return nil, fmt.Errorf("failed to record quote delivery: %w", err)
+	}
+
+	return quote, nil
+}
@@ -0,0 +1,149 @@
+// Package retention enforces how long each type of data is kept. Periods
+// come from RETENTION_POLICIES and are applied nightly by the
+// enforce-retention job; a dry run reports what would be removed.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+)
+
+// rule is how one data type expires: count and apply take the cutoff as $1,
+// and apply's statements delete the expired rows or clear their contents
+type rule struct {
+	description string
+	count       string
+	apply       []string
+}
+
+var rules = map[string]rule{
+	"entries": {
+		description: "Journal entries, by entry date",
+		count:       `SELECT COUNT(*) FROM entries WHERE entry_date < $1`,
+		apply:       []string{`DELETE FROM entries WHERE entry_date < $1`},
+	},
+	"email_bodies": {
+		description: "Bodies of sent and failed emails; the log row is kept",
+		count:       `SELECT COUNT(*) FROM email_logs WHERE created_at < $1 AND status IN ('sent', 'failed') AND (body_text <> '' OR body_html IS NOT NULL)`,
+		apply:       []string{`UPDATE email_logs SET body_text = '', body_html = NULL, updated_at = NOW() WHERE created_at < $1 AND status IN ('sent', 'failed') AND (body_text <> '' OR body_html IS NOT NULL)`},
+	},
+	"email_events": {
+		description: "Email open and click events",
+		count:       `SELECT COUNT(*) FROM email_events WHERE created_at < $1`,
+		apply:       []string{`DELETE FROM email_events WHERE created_at < $1`},
+	},
+	"inbound_messages": {
+		description: "Received replies kept for replay",
+		count:       `SELECT COUNT(*) FROM inbound_messages WHERE received_at < $1`,
+		apply:       []string{`DELETE FROM inbound_messages WHERE received_at < $1`},
+	},
+	"llm_calls": {
+		description: "LLM call log records",
+		count:       `SELECT COUNT(*) FROM llm_calls WHERE created_at < $1`,
+		apply:       []string{`DELETE FROM llm_calls WHERE created_at < $1`},
+	},
+	"audit_logs": {
+		description: "Broadcast and admin action audit records",
+		count: `SELECT (SELECT COUNT(*) FROM broadcasts WHERE created_at < $1) +
+			(SELECT COUNT(*) FROM admin_actions WHERE created_at < $1)`,
+		apply: []string{
+			`DELETE FROM broadcasts WHERE created_at < $1`,
+			`DELETE FROM admin_actions WHERE created_at < $1`,
+		},
+	},
+}
+
+// Names returns the data types that have a retention rule, sorted
+func Names() []string {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Result is what enforcing one policy did, or with DryRun would do. Cutoff
+// is nil for data kept forever.
+type Result struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Keep        time.Duration `json:"keep"`
+	Cutoff      *time.Time    `json:"cutoff,omitempty"`
+	Affected    int64         `json:"affected"`
+	DryRun      bool          `json:"dry_run"`
+}
+
+type Service struct {
+	db       *database.DB
+	policies map[string]time.Duration
+}
+
+// NewService fails on a policy for an unknown data type, so a typo in
+// RETENTION_POLICIES can't silently keep data forever
+func NewService(db *database.DB, policies map[string]time.Duration) (*Service, error) {
+	for name := range policies {
+		if _, ok := rules[name]; !ok {
+			return nil, fmt.Errorf("unknown retention policy %q (known: %v)", name, Names())
+		}
+	}
+	return &Service{db: db, policies: policies}, nil
+}
+
+// Enforce applies every policy in name order, or with dryRun only counts
+// the rows each would affect. It stops at the first failure and returns the
+// results so far.
+func (s *Service) Enforce(ctx context.Context, dryRun bool) ([]Result, error) {
+	now := time.Now().UTC()
+
+	var results []Result
+	for _, name := range Names() {
+		rule := rules[name]
+		result := Result{Name: name, Description: rule.description, Keep: s.policies[name], DryRun: dryRun}
+
+		if result.Keep > 0 {
+			cutoff := now.Add(-result.Keep)
+			result.Cutoff = &cutoff
+
+			var err error
+			if dryRun {
+				err = s.db.QueryRowContext(ctx, rule.count, cutoff).Scan(&result.Affected)
+			} else {
+				result.Affected, err = s.apply(ctx, rule, cutoff)
+			}
+			if err != nil {
+				return results, fmt.Errorf("failed to enforce %s retention: %w", name, err)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// statementTimeout bounds one apply statement in place of DB_QUERY_TIMEOUT,
+// since the first run against a large table can remove years of rows
+const statementTimeout = 15 * time.Minute
+
+func (s *Service) apply(ctx context.Context, rule rule, cutoff time.Time) (int64, error) {
+	var affected int64
+	for _, statement := range rule.apply {
+		statementCtx, cancel := context.WithTimeout(ctx, statementTimeout)
+		res, err := s.db.ExecContext(statementCtx, statement, cutoff)
+		cancel()
+		if err != nil {
+			return affected, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return affected, err
+		}
+		affected += n
+	}
+	return affected, nil
+}
@@ -0,0 +1,114 @@
+// Package s3 reads and writes S3 objects with SigV4-signed HTTP requests, so
+// the few object operations the app needs don't pull in the S3 SDK.
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty body, used when signing GETs
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Client does object operations using the same credential chain as the SES
+// and Bedrock clients
+type Client struct {
+	httpClient  *http.Client
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	region      string
+}
+
+// NewClient returns a client for buckets in region, or the configured
+// default region when region is empty
+func NewClient(ctx context.Context, region string) (*Client, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &Client{
+		httpClient:  &http.Client{Timeout: 10 * time.Minute},
+		credentials: awsCfg.Credentials,
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			// S3 expects the object key escaped exactly once
+			o.DisableURIPathEscaping = true
+		}),
+		region: awsCfg.Region,
+	}, nil
+}
+
+// ObjectURL is the virtual-hosted URL of key in bucket
+func (c *Client) ObjectURL(bucket, key string) string {
+	u := url.URL{
+		Scheme: "https",
+		Host:   fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, c.region),
+		Path:   "/" + key,
+	}
+	return u.String()
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request, payloadHash string) (*http.Response, error) {
+	creds, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := c.signer.SignHTTP(ctx, creds, req, payloadHash, "s3", c.region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign S3 request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 request failed: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("S3 %s %s returned %d: %s", req.Method, req.URL.Path, resp.StatusCode, body)
+	}
+
+	return resp, nil
+}
+
+// PutObject uploads size bytes from body to key. payloadHash is the hex
+// SHA-256 of the body.
+func (c *Client) PutObject(ctx context.Context, bucket, key string, body io.Reader, size int64, payloadHash, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.ObjectURL(bucket, key), body)
+	if err != nil {
+		return fmt.Errorf("failed to build S3 request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.do(ctx, req, payloadHash)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetObject opens key for reading; the caller closes it
+func (c *Client) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.ObjectURL(bucket, key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build S3 request: %w", err)
+	}
+
+	resp, err := c.do(ctx, req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
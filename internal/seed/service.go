@@ -0,0 +1,184 @@
+// Package seed generates deterministic demo users, entries and summaries for
+// local development and demos. It never calls the LLM.
+package seed
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/period"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// SeedModel is recorded as the llm_model of generated summaries so they are easy to identify
+const SeedModel = "seed"
+
+// Options controls how much data is generated
+type Options struct {
+	Users int
+	Weeks int
+	Seed  int64
+}
+
+// Result reports how many rows were written
+type Result struct {
+	Users     int
+	Entries   int
+	Summaries int
+}
+
+type Service struct {
+	db *database.DB
+}
+
+func NewService(db *database.DB) *Service {
+	return &Service{db: db}
+}
+
+var (
+	firstNames = []string{"Ada", "Grace", "Linus", "Margaret", "Dennis", "Barbara", "Ken", "Radia", "Alan", "Frances"}
+	lastNames  = []string{"Lovelace", "Hopper", "Torvalds", "Hamilton", "Ritchie", "Liskov", "Thompson", "Perlman", "Turing", "Allen"}
+	timezones  = []string{"America/New_York", "America/Chicago", "America/Los_Angeles", "Europe/London", "Europe/Berlin", "Asia/Tokyo", "Australia/Sydney"}
+	projects   = []string{"Project Atlas", "Billing Migration", "Mobile App", "Search Revamp", "Onboarding", "Data Platform"}
+	verbs      = []string{"Shipped", "Fixed", "Reviewed", "Designed", "Refactored", "Deployed", "Prototyped", "Documented"}
+	objects    = []string{
+		"the login flow", "the payment webhook handler", "three flaky integration tests", "the on-call runbook",
+		"the new pricing page", "API pagination", "the caching layer", "the quarterly roadmap",
+		"the CSV export", "the signup funnel metrics", "the database index for search", "the release checklist",
+	}
+	outcomes = []string{
+		"and unblocked the team", "ahead of schedule", "after pairing with design", "with full test coverage",
+		"which cut latency in half", "and closed out the epic", "", "",
+	}
+)
+
+// Run generates the requested users with entries and summaries for the last
+// opts.Weeks weeks. The same seed always produces the same data.
+func (s *Service) Run(ctx context.Context, opts Options) (*Result, error) {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	result := &Result{}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin seed transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	thisWeek := period.StartOfWeek(time.Now(), time.Monday)
+
+	for i := 0; i < opts.Users; i++ {
+		name := fmt.Sprintf("%s %s", firstNames[rng.Intn(len(firstNames))], lastNames[rng.Intn(len(lastNames))])
+		emailAddr := fmt.Sprintf("demo-user-%02d@example.com", i+1)
+		timezone := timezones[rng.Intn(len(timezones))]
+		promptTime := time.Date(0, 1, 1, 15+rng.Intn(4), 0, 0, 0, time.UTC)
+		project := projects[rng.Intn(len(projects))]
+
+		var userID int
+		query := `
+			INSERT INTO users (email, name, timezone, prompt_time, project_focus, is_verified, signup_status, created_at)
+			VALUES ($1, $2, $3, $4, $5, TRUE, $6, $7)
+			ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name, updated_at = NOW()
+			RETURNING id`
+
+		createdAt := thisWeek.AddDate(0, 0, -7*opts.Weeks-rng.Intn(14))
+		err := tx.QueryRowContext(ctx, query, emailAddr, name, timezone, promptTime, project,
+			models.SignupStatusActive, createdAt).Scan(&userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seed user %s: %w", emailAddr, err)
+		}
+		result.Users++
+
+		for week := opts.Weeks; week >= 1; week-- {
+			weekStart := thisWeek.AddDate(0, 0, -7*week)
+
+			var weekEntries []string
+			for day := 0; day < 5; day++ {
+				// Leave realistic gaps in the journal
+				if rng.Float64() < 0.2 {
+					continue
+				}
+
+				content := generateEntry(rng)
+				query := `
+					INSERT INTO entries (user_id, entry_date, raw_content, parsed_content, project_tag)
+					VALUES ($1, $2, $3, $3, $4)
+					ON CONFLICT (user_id, entry_date) DO NOTHING`
+
+				res, err := tx.ExecContext(ctx, query, userID, weekStart.AddDate(0, 0, day), content, project)
+				if err != nil {
+					return nil, fmt.Errorf("failed to seed entry: %w", err)
+				}
+				if rows, _ := res.RowsAffected(); rows > 0 {
+					result.Entries++
+				}
+				weekEntries = append(weekEntries, content)
+			}
+
+			if len(weekEntries) == 0 {
+				continue
+			}
+
+			paragraph, bullets := generateSummary(weekEntries, project)
+			query := `
+				INSERT INTO weekly_summaries (user_id, week_start_date, summary_paragraph, bullet_points, llm_model, llm_cost_cents)
+				VALUES ($1, $2, $3, $4, $5, 0)
+				ON CONFLICT (user_id, week_start_date) DO NOTHING`
+
+			res, err := tx.ExecContext(ctx, query, userID, weekStart, paragraph, bullets, SeedModel)
+			if err != nil {
+				return nil, fmt.Errorf("failed to seed weekly summary: %w", err)
+			}
+			if rows, _ := res.RowsAffected(); rows > 0 {
+				result.Summaries++
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit seed data: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"users":     result.Users,
+		"entries":   result.Entries,
+		"summaries": result.Summaries,
+		"seed":      opts.Seed,
+	}).Info("Demo data seeded")
+
+	return result, nil
+}
+
+func generateEntry(rng *rand.Rand) string {
+	count := 1 + rng.Intn(3)
+	sentences := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		sentence := fmt.Sprintf("%s %s", verbs[rng.Intn(len(verbs))], objects[rng.Intn(len(objects))])
+		if outcome := outcomes[rng.Intn(len(outcomes))]; outcome != "" {
+			sentence += " " + outcome
+		}
+		sentences = append(sentences, sentence+".")
+	}
+	return strings.Join(sentences, " ")
+}
+
+func generateSummary(entries []string, project string) (string, models.BulletPoints) {
+	paragraph := fmt.Sprintf("Solid week on %s: %d days logged with steady execution. Keep the momentum going.",
+		project, len(entries))
+
+	var bullets models.BulletPoints
+	for _, entry := range entries {
+		if len(bullets) == 3 {
+			break
+		}
+		first := strings.SplitN(entry, ".", 2)[0]
+		bullets = append(bullets, first)
+	}
+
+	return paragraph, bullets
+}
@@ -0,0 +1,92 @@
+// Package sharecard renders a weekly summary as a PNG card for sharing, with
+// the week, the top bullets and the user's streak, and stores it in S3 under
+// an unguessable key.
+package sharecard
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"time"
+)
+
+// Card size matches the Open Graph image size social sites preview
+const (
+	Width  = 1200
+	Height = 630
+)
+
+// maxBullets is how many summary bullets fit on a card
+const maxBullets = 3
+
+var (
+	background = color.RGBA{0x1b, 0x1f, 0x2a, 0xff}
+	accent     = color.RGBA{0xf5, 0xa6, 0x23, 0xff}
+	foreground = color.RGBA{0xf2, 0xf2, 0xf2, 0xff}
+	muted      = color.RGBA{0x8a, 0x93, 0xa6, 0xff}
+)
+
+// Card is what a share card shows
+type Card struct {
+	WeekStart time.Time
+	WeekEnd   time.Time
+	Bullets   []string
+	Streak    int // current streak in days; 0 leaves it off
+}
+
+// Render draws card as a PNG
+func Render(card Card) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+	fillRect(img, image.Rect(0, 0, 16, Height), accent)
+
+	const margin = 72
+	y := 64
+	drawText(img, margin, y, 4, "WHAT I GOT DONE THIS WEEK", muted)
+	y += 7*4 + 24
+	drawText(img, margin, y, 8, weekRange(card.WeekStart, card.WeekEnd), foreground)
+	y += 7*8 + 48
+
+	// Bullets at scale 4 are 24px a character
+	const bulletScale = 4
+	width := (Width - 2*margin - 48) / ((glyphWidth + glyphSpacing) * bulletScale)
+	bullets := card.Bullets
+	if len(bullets) > maxBullets {
+		bullets = bullets[:maxBullets]
+	}
+	for _, bullet := range bullets {
+		fillRect(img, image.Rect(margin, y+8, margin+16, y+24), accent)
+		for _, line := range wrap(bullet, width, 2) {
+			drawText(img, margin+48, y, bulletScale, line, foreground)
+			y += 7*bulletScale + 16
+		}
+		y += 20
+	}
+
+	if card.Streak > 0 {
+		streak := fmt.Sprintf("%d-DAY STREAK", card.Streak)
+		drawText(img, Width-margin-textWidth(streak, 5), Height-56-7*5, 5, streak, accent)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode share card: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// weekRange formats the week as "May 6 - 10", or "Apr 29 - May 3" across
+// months
+func weekRange(start, end time.Time) string {
+	if start.Month() == end.Month() {
+		return fmt.Sprintf("%s - %d", start.Format("Jan 2"), end.Day())
+	}
+	return fmt.Sprintf("%s - %s", start.Format("Jan 2"), end.Format("Jan 2"))
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, &image.Uniform{c}, image.Point{}, draw.Src)
+}
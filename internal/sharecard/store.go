@@ -0,0 +1,69 @@
+package sharecard
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/s3"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/config"
+)
+
+// keyPrefix is where cards are stored in the bucket
+const keyPrefix = "cards/"
+
+// Store uploads cards to an S3 bucket readers can fetch them from
+type Store struct {
+	client  *s3.Client
+	bucket  string
+	baseURL string
+}
+
+// NewStore returns nil when SHARE_CARD_BUCKET is empty, which turns share
+// cards off. Links are SHARE_CARD_BASE_URL followed by the object key, such
+// as a CloudFront domain in front of the bucket; without it they point at the
+// bucket, which must then allow public reads of cards/.
+func NewStore(ctx context.Context, cfg *config.Config) (*Store, error) {
+	if cfg.ShareCardBucket == "" {
+		return nil, nil
+	}
+
+	client, err := s3.NewClient(ctx, cfg.AWSRegion)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		client:  client,
+		bucket:  cfg.ShareCardBucket,
+		baseURL: strings.TrimRight(cfg.ShareCardBaseURL, "/"),
+	}, nil
+}
+
+// Upload renders card, stores it under a random key and returns its link
+func (s *Store) Upload(ctx context.Context, card Card) (string, error) {
+	image, err := Render(card)
+	if err != nil {
+		return "", err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate card key: %w", err)
+	}
+	key := keyPrefix + hex.EncodeToString(token) + ".png"
+
+	hash := sha256.Sum256(image)
+	err = s.client.PutObject(ctx, s.bucket, key, bytes.NewReader(image), int64(len(image)), hex.EncodeToString(hash[:]), "image/png")
+	if err != nil {
+		return "", fmt.Errorf("failed to upload share card: %w", err)
+	}
+
+	if s.baseURL == "" {
+		return s.client.ObjectURL(s.bucket, key), nil
+	}
+	return s.baseURL + "/" + key, nil
+}
@@ -0,0 +1,50 @@
+// Package stats derives lightweight metrics from stored entries without
+// calling the LLM.
+package stats
+
+import (
+	"strings"
+	"unicode"
+)
+
+// neutralEnergy is the score of an entry with no energy signal either way
+const neutralEnergy = 0.5
+
+var positiveWords = map[string]bool{
+	"shipped": true, "launched": true, "released": true, "delivered": true,
+	"finished": true, "completed": true, "done": true, "closed": true,
+	"merged": true, "fixed": true, "solved": true, "won": true,
+	"progress": true, "productive": true, "great": true, "excited": true,
+	"energized": true, "happy": true, "crushed": true, "nailed": true,
+}
+
+var negativeWords = map[string]bool{
+	"blocked": true, "stuck": true, "waiting": true, "delayed": true,
+	"slow": true, "failed": true, "broke": true, "broken": true,
+	"tired": true, "exhausted": true, "sick": true, "burned": true,
+	"frustrated": true, "stressed": true, "overwhelmed": true, "struggled": true,
+}
+
+// EntryEnergy scores an entry from 0 (drained) to 1 (energized) by counting
+// positive and negative keywords
+func EntryEnergy(text string) float64 {
+	var positive, negative int
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	for _, word := range words {
+		switch {
+		case positiveWords[word]:
+			positive++
+		case negativeWords[word]:
+			negative++
+		}
+	}
+
+	if positive+negative == 0 {
+		return neutralEnergy
+	}
+
+	return neutralEnergy + neutralEnergy*float64(positive-negative)/float64(positive+negative)
+}
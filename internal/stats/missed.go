@@ -0,0 +1,32 @@
+package stats
+
+import "time"
+
+// LightWeekMissedDays is how many days without an entry make a summary
+// period a light week, whose summary notes the gap and is worded gently
+const LightWeekMissedDays = 3
+
+// MissedDays returns the days from through to, inclusive, that have no entry
+// in dates. Days for which off returns true (holidays, time off) aren't
+// missed; off may be nil.
+func MissedDays(dates []time.Time, from, to time.Time, off func(day time.Time) bool) []time.Time {
+	logged := make(map[time.Time]bool, len(dates))
+	for _, date := range dates {
+		logged[calendarDay(date)] = true
+	}
+
+	var missed []time.Time
+	for day := calendarDay(from); !day.After(calendarDay(to)); day = day.AddDate(0, 0, 1) {
+		if logged[day] || (off != nil && off(day)) {
+			continue
+		}
+		missed = append(missed, day)
+	}
+	return missed
+}
+
+// IsLightWeek reports whether missed, a period's MissedDays, make it a
+// light week
+func IsLightWeek(missed []time.Time) bool {
+	return len(missed) >= LightWeekMissedDays
+}
@@ -0,0 +1,28 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMissedDays(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+	// Monday May 6 through Friday May 10, with Thursday off
+	dates := []time.Time{day(6), day(6).Add(15 * time.Hour)}
+	off := func(d time.Time) bool { return d.Equal(day(9)) }
+
+	missed := MissedDays(dates, day(6), day(10), off)
+	if len(missed) != 3 || !missed[0].Equal(day(7)) || !missed[1].Equal(day(8)) || !missed[2].Equal(day(10)) {
+		t.Fatalf("MissedDays() = %v, want May 7, 8 and 10", missed)
+	}
+	if !IsLightWeek(missed) {
+		t.Error("IsLightWeek() = false for 3 missed days")
+	}
+
+	if missed := MissedDays(append(dates, day(7)), day(6), day(10), off); IsLightWeek(missed) {
+		t.Errorf("IsLightWeek(%v) = true for 2 missed days", missed)
+	}
+	if missed := MissedDays(nil, day(10), day(6), nil); len(missed) != 0 {
+		t.Errorf("MissedDays() over an empty range = %v", missed)
+	}
+}
@@ -0,0 +1,76 @@
+package stats
+
+import (
+	"sort"
+	"time"
+)
+
+// Streak is a user's run of consecutive days with an entry
+type Streak struct {
+	// Current counts back from today, or from yesterday while today's
+	// entry is still to come
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+	// LoggedToday is whether today already has an entry
+	LoggedToday bool `json:"logged_today"`
+}
+
+// BuildStreak computes the streak from the dates that have an entry, as of
+// today. Dates are compared by calendar day and may repeat or be unordered.
+// Days for which off returns true (holidays, time off) neither count nor
+// break a streak; off may be nil.
+func BuildStreak(dates []time.Time, today time.Time, off func(day time.Time) bool) Streak {
+	days := make([]time.Time, 0, len(dates))
+	seen := map[string]bool{}
+	for _, date := range dates {
+		day := calendarDay(date)
+		key := day.Format("2006-01-02")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	var streak Streak
+	run := 0
+	for i, day := range days {
+		if i > 0 && bridged(days[i-1], day, off) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > streak.Longest {
+			streak.Longest = run
+		}
+	}
+
+	today = calendarDay(today)
+	streak.LoggedToday = seen[today.Format("2006-01-02")]
+
+	// run is the streak ending at the latest entry; it only counts if that
+	// entry is today's, or only today and days off have passed since
+	if len(days) > 0 {
+		last := days[len(days)-1]
+		if last.Equal(today) || (last.Before(today) && bridged(last, today, off)) {
+			streak.Current = run
+		}
+	}
+	return streak
+}
+
+// bridged reports whether every day strictly between from and to is off, so
+// entries on from and to belong to one streak
+func bridged(from, to time.Time, off func(day time.Time) bool) bool {
+	for day := from.AddDate(0, 0, 1); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if off == nil || !off(day) {
+			return false
+		}
+	}
+	return true
+}
+
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
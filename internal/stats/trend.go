@@ -0,0 +1,104 @@
+package stats
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// TrendWeeks is how many weeks the monthly trend covers
+const TrendWeeks = 4
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// noDataBlock marks a week without entries in a sparkline
+const noDataBlock = '·'
+
+// WeekEnergy is the average entry energy for one week
+type WeekEnergy struct {
+	WeekStart time.Time
+	Entries   int
+	Energy    float64
+}
+
+// Trend is the energy trend shown in the weekly summary
+type Trend struct {
+	// Week has one block per entry in the summarized week
+	Week string
+	// Month covers the summarized week and the ones before it, oldest first
+	Month []WeekEnergy
+}
+
+// MonthSparkline renders one block per week of the monthly trend
+func (t *Trend) MonthSparkline() string {
+	var b strings.Builder
+	for _, week := range t.Month {
+		if week.Entries == 0 {
+			b.WriteRune(noDataBlock)
+			continue
+		}
+		b.WriteRune(sparkBlock(week.Energy))
+	}
+	return b.String()
+}
+
+// BuildTrend computes the trend for the week starting at weekStart from entries
+// covering that week and the TrendWeeks-1 weeks before it
+func BuildTrend(entries []*models.Entry, weekStart time.Time) *Trend {
+	trend := &Trend{Month: make([]WeekEnergy, TrendWeeks)}
+	sums := make([]float64, TrendWeeks)
+	monthStart := weekStart.AddDate(0, 0, -7*(TrendWeeks-1))
+
+	for i := range trend.Month {
+		trend.Month[i].WeekStart = monthStart.AddDate(0, 0, 7*i)
+	}
+
+	var week []float64
+	for _, entry := range entries {
+		if entry.EntryDate.Before(monthStart) {
+			continue
+		}
+
+		index := int(entry.EntryDate.Sub(monthStart).Hours() / 24 / 7)
+		if index >= TrendWeeks {
+			continue
+		}
+
+		energy := EntryEnergy(entry.RawContent)
+		trend.Month[index].Entries++
+		sums[index] += energy
+
+		if index == TrendWeeks-1 {
+			week = append(week, energy)
+		}
+	}
+
+	for i := range trend.Month {
+		if trend.Month[i].Entries > 0 {
+			trend.Month[i].Energy = sums[i] / float64(trend.Month[i].Entries)
+		}
+	}
+
+	trend.Week = Sparkline(week)
+	return trend
+}
+
+// Sparkline renders values in [0, 1] as block characters
+func Sparkline(values []float64) string {
+	var b strings.Builder
+	for _, value := range values {
+		b.WriteRune(sparkBlock(value))
+	}
+	return b.String()
+}
+
+func sparkBlock(value float64) rune {
+	if value < 0 {
+		value = 0
+	}
+	if value > 1 {
+		value = 1
+	}
+	return sparkBlocks[int(value*float64(len(sparkBlocks)-1)+0.5)]
+}
@@ -0,0 +1,151 @@
+package timezones
+
+// aliases maps abbreviations, timezone names and cities, as normalize
+// writes them, to the IANA zones they can mean. Where one name is used in
+// several places every zone is listed, and Resolve asks which is meant.
+// Cities already named by an IANA zone ("Berlin", "Tokyo") don't need an
+// entry; they are matched against common.
+var aliases = map[string][]string{
+	// North America
+	"est":      {"America/New_York"},
+	"edt":      {"America/New_York"},
+	"et":       {"America/New_York"},
+	"eastern":  {"America/New_York"},
+	"cst":      {"America/Chicago", "Asia/Shanghai"},
+	"cdt":      {"America/Chicago"},
+	"ct":       {"America/Chicago"},
+	"central":  {"America/Chicago"},
+	"mst":      {"America/Denver", "America/Phoenix"},
+	"mdt":      {"America/Denver"},
+	"mt":       {"America/Denver"},
+	"mountain": {"America/Denver"},
+	"pst":      {"America/Los_Angeles"},
+	"pdt":      {"America/Los_Angeles"},
+	"pt":       {"America/Los_Angeles"},
+	"pacific":  {"America/Los_Angeles"},
+	"akst":     {"America/Anchorage"},
+	"akdt":     {"America/Anchorage"},
+	"alaska":   {"America/Anchorage"},
+	"hst":      {"Pacific/Honolulu"},
+	"hawaii":   {"Pacific/Honolulu"},
+	"ast":      {"America/Halifax", "Asia/Riyadh"},
+	"atlantic": {"America/Halifax"},
+	"nst":      {"America/St_Johns"},
+
+	"nyc":            {"America/New_York"},
+	"boston":         {"America/New_York"},
+	"washington":     {"America/New_York"},
+	"dc":             {"America/New_York"},
+	"philadelphia":   {"America/New_York"},
+	"atlanta":        {"America/New_York"},
+	"miami":          {"America/New_York"},
+	"pittsburgh":     {"America/New_York"},
+	"austin":         {"America/Chicago"},
+	"dallas":         {"America/Chicago"},
+	"houston":        {"America/Chicago"},
+	"minneapolis":    {"America/Chicago"},
+	"nashville":      {"America/Chicago"},
+	"salt lake city": {"America/Denver"},
+	"boulder":        {"America/Denver"},
+	"la":             {"America/Los_Angeles"},
+	"san francisco":  {"America/Los_Angeles"},
+	"sf":             {"America/Los_Angeles"},
+	"seattle":        {"America/Los_Angeles"},
+	"san diego":      {"America/Los_Angeles"},
+	"san jose":       {"America/Los_Angeles"},
+	"portland":       {"America/Los_Angeles", "America/New_York"},
+	"ottawa":         {"America/Toronto"},
+	"calgary":        {"America/Edmonton"},
+
+	// Europe and Africa
+	"wet":              {"Europe/Lisbon"},
+	"west":             {"Europe/Lisbon"},
+	"bst":              {"Europe/London", "Asia/Dhaka"},
+	"ist":              {"Asia/Kolkata", "Europe/Dublin", "Asia/Jerusalem"},
+	"cet":              {"Europe/Paris"},
+	"cest":             {"Europe/Paris"},
+	"central european": {"Europe/Paris"},
+	"eet":              {"Europe/Athens"},
+	"eest":             {"Europe/Athens"},
+	"msk":              {"Europe/Moscow"},
+	"sast":             {"Africa/Johannesburg"},
+	"cat":              {"Africa/Maputo"},
+	"eat":              {"Africa/Nairobi"},
+	"wat":              {"Africa/Lagos"},
+	"edinburgh":        {"Europe/London"},
+	"manchester":       {"Europe/London"},
+	"birmingham":       {"Europe/London", "America/Chicago"},
+	"cambridge":        {"Europe/London", "America/New_York"},
+	"munich":           {"Europe/Berlin"},
+	"frankfurt":        {"Europe/Berlin"},
+	"hamburg":          {"Europe/Berlin"},
+	"barcelona":        {"Europe/Madrid"},
+	"milan":            {"Europe/Rome"},
+	"geneva":           {"Europe/Zurich"},
+	"tel aviv":         {"Asia/Jerusalem"},
+
+	// Asia and Oceania
+	"pkt":        {"Asia/Karachi"},
+	"jst":        {"Asia/Tokyo"},
+	"kst":        {"Asia/Seoul"},
+	"hkt":        {"Asia/Hong_Kong"},
+	"sgt":        {"Asia/Singapore"},
+	"pht":        {"Asia/Manila"},
+	"wib":        {"Asia/Jakarta"},
+	"awst":       {"Australia/Perth"},
+	"acst":       {"Australia/Adelaide"},
+	"aest":       {"Australia/Sydney"},
+	"aedt":       {"Australia/Sydney"},
+	"nzst":       {"Pacific/Auckland"},
+	"nzdt":       {"Pacific/Auckland"},
+	"india":      {"Asia/Kolkata"},
+	"mumbai":     {"Asia/Kolkata"},
+	"delhi":      {"Asia/Kolkata"},
+	"new delhi":  {"Asia/Kolkata"},
+	"bangalore":  {"Asia/Kolkata"},
+	"bengaluru":  {"Asia/Kolkata"},
+	"hyderabad":  {"Asia/Kolkata"},
+	"chennai":    {"Asia/Kolkata"},
+	"beijing":    {"Asia/Shanghai"},
+	"shenzhen":   {"Asia/Shanghai"},
+	"osaka":      {"Asia/Tokyo"},
+	"abu dhabi":  {"Asia/Dubai"},
+	"canberra":   {"Australia/Sydney"},
+	"wellington": {"Pacific/Auckland"},
+}
+
+// fractionalOffsets maps the UTC offsets that aren't whole hours to a zone
+// that keeps them year-round, or mostly does
+var fractionalOffsets = map[string]string{
+	"-03:30": "America/St_Johns",
+	"+03:30": "Asia/Tehran",
+	"+04:30": "Asia/Kabul",
+	"+05:30": "Asia/Kolkata",
+	"+05:45": "Asia/Kathmandu",
+	"+06:30": "Asia/Yangon",
+	"+09:30": "Australia/Darwin",
+	"+10:30": "Australia/Adelaide",
+	"+12:45": "Pacific/Chatham",
+}
+
+// common are IANA zones whose city part is matched against city answers
+// and misspellings
+var common = []string{
+	"UTC", "GMT",
+	"America/New_York", "America/Chicago", "America/Denver", "America/Los_Angeles",
+	"America/Phoenix", "America/Anchorage", "America/Toronto", "America/Vancouver",
+	"America/Montreal", "America/Edmonton", "America/Halifax", "America/Mexico_City",
+	"America/Bogota", "America/Lima", "America/Santiago", "America/Sao_Paulo",
+	"America/Buenos_Aires",
+	"Europe/London", "Europe/Dublin", "Europe/Lisbon", "Europe/Paris", "Europe/Berlin",
+	"Europe/Amsterdam", "Europe/Brussels", "Europe/Zurich", "Europe/Rome", "Europe/Madrid",
+	"Europe/Stockholm", "Europe/Oslo", "Europe/Copenhagen", "Europe/Warsaw", "Europe/Prague",
+	"Europe/Vienna", "Europe/Athens", "Europe/Helsinki", "Europe/Istanbul", "Europe/Kyiv",
+	"Europe/Moscow",
+	"Africa/Cairo", "Africa/Johannesburg", "Africa/Lagos", "Africa/Nairobi",
+	"Asia/Jerusalem", "Asia/Riyadh", "Asia/Dubai", "Asia/Karachi", "Asia/Kolkata",
+	"Asia/Dhaka", "Asia/Bangkok", "Asia/Singapore", "Asia/Hong_Kong", "Asia/Shanghai",
+	"Asia/Taipei", "Asia/Seoul", "Asia/Tokyo", "Asia/Jakarta", "Asia/Manila",
+	"Australia/Perth", "Australia/Adelaide", "Australia/Brisbane", "Australia/Sydney",
+	"Australia/Melbourne", "Pacific/Auckland", "Pacific/Honolulu",
+}
@@ -0,0 +1,222 @@
+// Package timezones turns the ways people write a timezone - IANA names in
+// any case, abbreviations like "EST", offsets like "GMT+2", city names and
+// misspellings of them - into the IANA name time.LoadLocation expects.
+package timezones
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTypoDistance is the most edits an answer may be from a known timezone
+// or city and still be taken as a misspelling of it
+const maxTypoDistance = 2
+
+// AmbiguousError is returned for input that fits more than one timezone,
+// such as "CST", so the user can be asked which they meant
+type AmbiguousError struct {
+	Input      string
+	Candidates []string
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%q could mean %s", e.Input, strings.Join(e.Candidates, " or "))
+}
+
+var offsetPattern = regexp.MustCompile(`^(?:utc|gmt)?\s*([+-])\s*(\d{1,2})(?::?(\d{2}))?$`)
+
+// Resolve returns the IANA timezone input names. It accepts, in order:
+// abbreviations and names ("PST", "Eastern Time"), UTC offsets ("GMT+2",
+// "UTC-05:30"), IANA names in any case with spaces for underscores, city
+// names ("Bangalore", "new york") and misspellings of any of these
+// ("Europe/Berln"). Input that fits several timezones returns an
+// *AmbiguousError.
+func Resolve(input string) (string, error) {
+	key := normalize(input)
+	if key == "" {
+		return "", fmt.Errorf("invalid timezone: %q", input)
+	}
+
+	if zones, ok := aliases[key]; ok {
+		return pick(input, zones)
+	}
+
+	if tz, ok, err := parseOffset(key); ok {
+		return tz, err
+	}
+
+	if tz, err := canonical(strings.ReplaceAll(strings.TrimSpace(input), " ", "_")); err == nil {
+		return tz, nil
+	}
+
+	if zones := closest(key); zones != nil {
+		return pick(input, zones)
+	}
+	return "", fmt.Errorf("invalid timezone: %s", input)
+}
+
+// Label describes tz with its offset from UTC at at, as "America/Chicago
+// (UTC-05:00)"
+func Label(tz string, at time.Time) string {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return tz
+	}
+	return fmt.Sprintf("%s (UTC%s)", tz, at.In(loc).Format("-07:00"))
+}
+
+func pick(input string, zones []string) (string, error) {
+	if len(zones) > 1 {
+		return "", &AmbiguousError{Input: strings.TrimSpace(input), Candidates: zones}
+	}
+	return zones[0], nil
+}
+
+// normalize lowercases input, drops dots and collapses spaces, and drops a
+// trailing "time", so "Eastern Standard Time" and "eastern" are both
+// "eastern"
+func normalize(input string) string {
+	key := strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(input, ".", ""))), " ")
+	for _, suffix := range []string{" standard time", " daylight time", " time"} {
+		if strings.HasSuffix(key, suffix) && key != suffix[1:] {
+			return strings.TrimSuffix(key, suffix)
+		}
+	}
+	return key
+}
+
+// parseOffset resolves "+2", "GMT+2" and "UTC-05:30". Whole hours map to
+// the fixed Etc/GMT zones, whose signs are inverted, and the common
+// fractional offsets to a zone that uses them. ok is false when key isn't
+// an offset at all.
+func parseOffset(key string) (tz string, ok bool, err error) {
+	m := offsetPattern.FindStringSubmatch(strings.ReplaceAll(key, " ", ""))
+	if m == nil {
+		return "", false, nil
+	}
+
+	hours, _ := strconv.Atoi(m[2])
+	minutes := 0
+	if m[3] != "" {
+		minutes, _ = strconv.Atoi(m[3])
+	}
+	if (m[1] == "+" && hours > 14) || (m[1] == "-" && hours > 12) || minutes >= 60 {
+		return "", true, fmt.Errorf("invalid UTC offset: %s%s", m[1], m[2])
+	}
+
+	if minutes != 0 {
+		offset := fmt.Sprintf("%s%02d:%02d", m[1], hours, minutes)
+		if zone, found := fractionalOffsets[offset]; found {
+			return zone, true, nil
+		}
+		return "", true, fmt.Errorf("no timezone uses UTC%s", offset)
+	}
+	if hours == 0 {
+		return "UTC", true, nil
+	}
+
+	// Etc/GMT-2 is two hours ahead of UTC
+	sign := "-"
+	if m[1] == "-" {
+		sign = "+"
+	}
+	return fmt.Sprintf("Etc/GMT%s%d", sign, hours), true, nil
+}
+
+// canonical validates an IANA name and returns it in the form
+// time.LoadLocation expects, so "europe/berlin" is stored as
+// "Europe/Berlin"
+func canonical(tz string) (string, error) {
+	for _, known := range []string{"UTC", "GMT"} {
+		if strings.EqualFold(tz, known) {
+			return known, nil
+		}
+	}
+	if tz == "" || strings.EqualFold(tz, "local") {
+		return "", fmt.Errorf("invalid timezone: %s", tz)
+	}
+
+	if _, err := time.LoadLocation(tz); err == nil {
+		return tz, nil
+	}
+
+	// Title-case each path segment: "america/new_york" -> "America/New_York"
+	segments := strings.Split(strings.ToLower(tz), "/")
+	for i, segment := range segments {
+		words := strings.Split(segment, "_")
+		for j, word := range words {
+			if word != "" {
+				words[j] = strings.ToUpper(word[:1]) + word[1:]
+			}
+		}
+		segments[i] = strings.Join(words, "_")
+	}
+
+	candidate := strings.Join(segments, "/")
+	if _, err := time.LoadLocation(candidate); err != nil {
+		return "", fmt.Errorf("invalid timezone: %s", tz)
+	}
+	return candidate, nil
+}
+
+// closest returns the zones of the common timezone, or of the city or
+// name in aliases, nearest to key, or nil if none is within
+// maxTypoDistance. A key without "/" is compared with city names.
+func closest(key string) []string {
+	key = strings.ReplaceAll(key, " ", "_")
+
+	var best []string
+	bestName, bestDistance := "", maxTypoDistance+1
+	consider := func(name string, zones []string) {
+		d := editDistance(key, name)
+		// Short names need a closer match so unrelated cities aren't confused
+		if d*3 > len(name) {
+			return
+		}
+		// Ties go to the alphabetically first name, as aliases is a map
+		if d < bestDistance || (d == bestDistance && name < bestName) {
+			best, bestName, bestDistance = zones, name, d
+		}
+	}
+
+	for _, tz := range common {
+		name := strings.ToLower(tz)
+		if !strings.Contains(key, "/") {
+			name = name[strings.LastIndex(name, "/")+1:]
+		}
+		consider(name, []string{tz})
+	}
+	if !strings.Contains(key, "/") {
+		for name, zones := range aliases {
+			// Abbreviations are too short to guess at
+			if len(name) > 4 {
+				consider(strings.ReplaceAll(name, " ", "_"), zones)
+			}
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,113 @@
+package timezones
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"America/New_York", "America/New_York", false},
+		{"europe/berlin", "Europe/Berlin", false},
+		{"America/New York", "America/New_York", false},
+		{"utc", "UTC", false},
+		{"EST", "America/New_York", false},
+		{"pst", "America/Los_Angeles", false},
+		{"CET", "Europe/Paris", false},
+		{"Eastern Standard Time", "America/New_York", false},
+		{"Pacific Time", "America/Los_Angeles", false},
+		{"a.e.s.t.", "Australia/Sydney", false},
+		{"GMT+2", "Etc/GMT-2", false},
+		{"UTC-5", "Etc/GMT+5", false},
+		{"+09:00", "Etc/GMT-9", false},
+		{"UTC+5:30", "Asia/Kolkata", false},
+		{"GMT+0", "UTC", false},
+		{"Etc/GMT+5", "Etc/GMT+5", false},
+		{"new york", "America/New_York", false},
+		{"Tokyo", "Asia/Tokyo", false},
+		{"San Francisco", "America/Los_Angeles", false},
+		{"Bangalore", "Asia/Kolkata", false},
+		{"Bangalor", "Asia/Kolkata", false},
+		{"Europe/Berln", "Europe/Berlin", false},
+		{"America/Los_Angelse", "America/Los_Angeles", false},
+		{"GMT+15", "", true},
+		{"UTC+5:15", "", true},
+		{"Mars/Olympus", "", true},
+		{"Local", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Resolve(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if got != "" {
+				if _, err := time.LoadLocation(got); err != nil {
+					t.Errorf("Resolve(%q) = %q, which doesn't load: %v", tt.input, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveAmbiguous(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"CST", []string{"America/Chicago", "Asia/Shanghai"}},
+		{"IST", []string{"Asia/Kolkata", "Europe/Dublin", "Asia/Jerusalem"}},
+		{"Portland", []string{"America/Los_Angeles", "America/New_York"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Resolve(tt.input)
+			var ambiguous *AmbiguousError
+			if !errors.As(err, &ambiguous) {
+				t.Fatalf("Resolve(%q) error = %v, want *AmbiguousError", tt.input, err)
+			}
+			if ambiguous.Input != tt.input || !reflect.DeepEqual(ambiguous.Candidates, tt.want) {
+				t.Errorf("Resolve(%q) = %+v, want candidates %q", tt.input, ambiguous, tt.want)
+			}
+		})
+	}
+}
+
+func TestAliasesLoad(t *testing.T) {
+	for name, zones := range aliases {
+		for _, zone := range zones {
+			if _, err := time.LoadLocation(zone); err != nil {
+				t.Errorf("alias %q: %v", name, err)
+			}
+		}
+	}
+	for offset, zone := range fractionalOffsets {
+		if _, err := time.LoadLocation(zone); err != nil {
+			t.Errorf("offset %s: %v", offset, err)
+		}
+	}
+	for _, zone := range common {
+		if _, err := time.LoadLocation(zone); err != nil {
+			t.Errorf("common zone: %v", err)
+		}
+	}
+}
+
+func TestLabel(t *testing.T) {
+	at := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	if got, want := Label("America/Chicago", at), "America/Chicago (UTC-06:00)"; got != want {
+		t.Errorf("Label() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,110 @@
+package users
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// cache is a least recently used cache of users by ID and email. A nil
+// cache caches nothing.
+type cache struct {
+	size int
+	ttl  time.Duration
+
+	mu     sync.Mutex
+	order  *list.List // most recently used first
+	ids    map[int]*list.Element
+	emails map[string]*list.Element
+}
+
+type cacheEntry struct {
+	user    *models.User
+	expires time.Time
+}
+
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:   size,
+		ttl:    ttl,
+		order:  list.New(),
+		ids:    map[int]*list.Element{},
+		emails: map[string]*list.Element{},
+	}
+}
+
+func (c *cache) byID(userID int, now time.Time) *models.User {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(c.ids[userID], now)
+}
+
+func (c *cache) byEmail(email string, now time.Time) *models.User {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(c.emails[email], now)
+}
+
+// get returns a copy of the element's user, dropping it once expired
+func (c *cache) get(e *list.Element, now time.Time) *models.User {
+	if e == nil {
+		return nil
+	}
+	entry := e.Value.(*cacheEntry)
+	if !now.Before(entry.expires) {
+		c.removeElement(e)
+		return nil
+	}
+	c.order.MoveToFront(e)
+	return copyUser(entry.user)
+}
+
+func (c *cache) add(user *models.User, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.ids[user.ID]; ok {
+		c.removeElement(e)
+	}
+	if e, ok := c.emails[user.Email]; ok {
+		c.removeElement(e)
+	}
+
+	e := c.order.PushFront(&cacheEntry{user: copyUser(user), expires: now.Add(c.ttl)})
+	c.ids[user.ID] = e
+	c.emails[user.Email] = e
+
+	for c.order.Len() > c.size {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *cache) remove(userID int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.ids[userID]; ok {
+		c.removeElement(e)
+	}
+}
+
+func (c *cache) removeElement(e *list.Element) {
+	user := e.Value.(*cacheEntry).user
+	c.order.Remove(e)
+	delete(c.ids, user.ID)
+	delete(c.emails, user.Email)
+}
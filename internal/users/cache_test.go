@@ -0,0 +1,79 @@
+package users
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Date(2024, 12, 27, 10, 0, 0, 0, time.UTC)
+	c := newCache(2, time.Minute)
+
+	c.add(&models.User{ID: 1, Email: "a@example.com"}, now)
+	c.add(&models.User{ID: 2, Email: "b@example.com"}, now)
+
+	if user := c.byEmail("a@example.com", now); user == nil || user.ID != 1 {
+		t.Fatalf("byEmail(a) = %+v", user)
+	}
+
+	// 1 was used more recently, so adding a third evicts 2
+	c.add(&models.User{ID: 3, Email: "c@example.com"}, now)
+	if c.byID(2, now) != nil || c.byEmail("b@example.com", now) != nil {
+		t.Error("least recently used user was not evicted")
+	}
+	if c.byID(1, now) == nil || c.byID(3, now) == nil {
+		t.Error("recently used users were evicted")
+	}
+
+	if c.byID(1, now.Add(time.Minute)) != nil {
+		t.Error("expired user was returned")
+	}
+
+	c.remove(3)
+	if c.byID(3, now) != nil || c.byEmail("c@example.com", now) != nil {
+		t.Error("invalidated user was returned")
+	}
+}
+
+func TestCacheEmailChange(t *testing.T) {
+	now := time.Date(2024, 12, 27, 10, 0, 0, 0, time.UTC)
+	c := newCache(10, time.Minute)
+
+	c.add(&models.User{ID: 1, Email: "old@example.com"}, now)
+	c.add(&models.User{ID: 1, Email: "new@example.com"}, now)
+
+	if c.byEmail("old@example.com", now) != nil {
+		t.Error("old email still finds the user")
+	}
+	if user := c.byID(1, now); user == nil || user.Email != "new@example.com" {
+		t.Errorf("byID(1) = %+v", user)
+	}
+}
+
+func TestCacheReturnsCopies(t *testing.T) {
+	now := time.Date(2024, 12, 27, 10, 0, 0, 0, time.UTC)
+	c := newCache(10, time.Minute)
+
+	focus := "Launch"
+	c.add(&models.User{ID: 1, Email: "a@example.com", ProjectFocus: &focus}, now)
+
+	user := c.byID(1, now)
+	user.Name = "Changed"
+	*user.ProjectFocus = "Changed"
+
+	again := c.byID(1, now)
+	if again.Name != "" || *again.ProjectFocus != "Launch" {
+		t.Errorf("changing a returned user changed the cache: %+v", again)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *cache
+	c.add(&models.User{ID: 1, Email: "a@example.com"}, time.Now())
+	if c.byID(1, time.Now()) != nil {
+		t.Error("nil cache returned a user")
+	}
+	c.remove(1)
+}
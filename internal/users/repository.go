@@ -0,0 +1,141 @@
+// Package users loads user rows. Services look users up through a
+// Repository rather than querying the users table themselves, so the
+// optional cache sees every read and is invalidated on every update.
+package users
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// Repository reads users, optionally through a cache
+type Repository struct {
+	db    *database.DB
+	cache *cache
+}
+
+// NewRepository caches up to cacheSize users for ttl each; a cacheSize of 0
+// turns the cache off
+func NewRepository(db *database.DB, cacheSize int, ttl time.Duration) *Repository {
+	r := &Repository{db: db}
+	if cacheSize > 0 && ttl > 0 {
+		r.cache = newCache(cacheSize, ttl)
+	}
+	return r
+}
+
+// GetByEmail returns the user with email, or nil if there is none. A
+// verified alias in user_emails finds its user too, as does an address a
+// user changed away from until its alias window in email_changes ends.
+func (r *Repository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if user := r.cache.byEmail(email, time.Now()); user != nil {
+		return user, nil
+	}
+	user, err := r.load(ctx, "email", email)
+	if err != nil || user != nil {
+		return user, err
+	}
+	return r.loadByAlias(ctx, email)
+}
+
+// GetByID returns the user with userID, or nil if there is none
+func (r *Repository) GetByID(ctx context.Context, userID int) (*models.User, error) {
+	if user := r.cache.byID(userID, time.Now()); user != nil {
+		return user, nil
+	}
+	return r.load(ctx, "id", userID)
+}
+
+// Invalidate drops userID from the cache. Call it after every change to the
+// user's row; other processes see the change once their copy's TTL runs out.
+func (r *Repository) Invalidate(userID int) {
+	r.cache.remove(userID)
+}
+
+// loadByAlias returns the user with email as a verified alias or, failing
+// that, the user who most recently changed away from it while it is still
+// their alias
+func (r *Repository) loadByAlias(ctx context.Context, email string) (*models.User, error) {
+	query := `
+		SELECT user_id FROM (
+			SELECT user_id, 1 AS rank, created_at AS at FROM user_emails
+			WHERE LOWER(email) = LOWER($1) AND verified_at IS NOT NULL
+			UNION ALL
+			SELECT user_id, 2, confirmed_at FROM email_changes
+			WHERE LOWER(old_email) = LOWER($1) AND alias_until > NOW()
+		) aliases
+		ORDER BY rank, at DESC
+		LIMIT 1`
+
+	var userID int
+	err := r.db.QueryRowContext(ctx, query, email).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by alias: %w", err)
+	}
+	return r.GetByID(ctx, userID)
+}
+
+// load reads the user whose column equals value; column is never user input
+func (r *Repository) load(ctx context.Context, column string, value interface{}) (*models.User, error) {
+	query := `
+		SELECT id, email, name, timezone, prompt_time, verification_code, is_verified,
+			   is_paused, pause_until, project_focus, signup_status, week_start, delivery_channel, entry_format, summary_voice, quotes_enabled, compare_weeks, weekly_goals, language, summary_language, email_tracking, prompt_threading, reply_token, created_at, updated_at
+		FROM users WHERE ` + column + ` = $1`
+
+	var user models.User
+	var pauseUntil sql.NullTime
+	var verificationCode sql.NullString
+	var projectFocus sql.NullString
+
+	err := r.db.QueryRowContext(ctx, query, value).Scan(
+		&user.ID, &user.Email, &user.Name, &user.Timezone, &user.PromptTime,
+		&verificationCode, &user.IsVerified, &user.IsPaused, &pauseUntil,
+		&projectFocus, &user.SignupStatus, &user.WeekStart, &user.DeliveryChannel, &user.EntryFormat, &user.SummaryVoice, &user.QuotesEnabled, &user.CompareWeeks, &user.WeeklyGoals, &user.Language, &user.SummaryLanguage, &user.EmailTracking, &user.PromptThreading, &user.ReplyToken, &user.CreatedAt, &user.UpdatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user by %s: %w", column, err)
+	}
+
+	if verificationCode.Valid {
+		user.VerificationCode = &verificationCode.String
+	}
+	if pauseUntil.Valid {
+		user.PauseUntil = &pauseUntil.Time
+	}
+	if projectFocus.Valid {
+		user.ProjectFocus = &projectFocus.String
+	}
+
+	r.cache.add(&user, time.Now())
+	return copyUser(&user), nil
+}
+
+// copyUser lets callers modify the user they get without changing the
+// cached one
+func copyUser(user *models.User) *models.User {
+	c := *user
+	if user.VerificationCode != nil {
+		code := *user.VerificationCode
+		c.VerificationCode = &code
+	}
+	if user.PauseUntil != nil {
+		until := *user.PauseUntil
+		c.PauseUntil = &until
+	}
+	if user.ProjectFocus != nil {
+		focus := *user.ProjectFocus
+		c.ProjectFocus = &focus
+	}
+	return &c
+}
@@ -0,0 +1,173 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+const (
+	// maxAttempts is how many times a delivery is tried before it is marked failed
+	maxAttempts = 6
+	// deliveryBatchSize caps the deliveries sent per ProcessDeliveries run
+	deliveryBatchSize = 20
+)
+
+// Signature headers sent with every delivery. Receivers verify
+// X-Webhook-Signature as hex HMAC-SHA256 of "<timestamp>.<body>" keyed by the
+// endpoint secret.
+const (
+	headerEvent     = "X-Webhook-Event"
+	headerDelivery  = "X-Webhook-Delivery"
+	headerTimestamp = "X-Webhook-Timestamp"
+	headerSignature = "X-Webhook-Signature"
+)
+
+type sender struct {
+	httpClient *http.Client
+}
+
+func newSender() *sender {
+	return &sender{httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// pendingDelivery is a queued delivery joined with its endpoint
+type pendingDelivery struct {
+	models.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// ProcessDeliveries sends due deliveries, retrying failures with exponential
+// backoff until maxAttempts is reached
+func (s *Service) ProcessDeliveries(ctx context.Context) error {
+	query := `
+		SELECT d.id, d.endpoint_id, d.event_type, d.payload, d.attempts, e.url, e.secret
+		FROM webhook_deliveries d
+		JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND e.is_active = TRUE
+		ORDER BY d.next_attempt_at ASC
+		LIMIT $1`
+
+	rows, err := s.db.QueryContext(ctx, query, deliveryBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to query pending webhook deliveries: %w", err)
+	}
+
+	var pending []*pendingDelivery
+	for rows.Next() {
+		var d pendingDelivery
+		if err := rows.Scan(&d.ID, &d.EndpointID, &d.EventType, &d.Payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		pending = append(pending, &d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read webhook deliveries: %w", err)
+	}
+
+	for _, d := range pending {
+		status, err := s.sender.send(ctx, d)
+		if err := s.recordAttempt(ctx, d, status, err); err != nil {
+			logrus.WithError(err).WithField("delivery_id", d.ID).Error("Failed to record webhook attempt")
+		}
+	}
+
+	return nil
+}
+
+func (s *sender) send(ctx context.Context, d *pendingDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader([]byte(d.Payload)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerEvent, d.EventType)
+	req.Header.Set(headerDelivery, strconv.Itoa(d.ID))
+	req.Header.Set(headerTimestamp, timestamp)
+	req.Header.Set(headerSignature, "sha256="+Sign(d.Secret, timestamp, []byte(d.Payload)))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (s *Service) recordAttempt(ctx context.Context, d *pendingDelivery, responseStatus int, sendErr error) error {
+	var status *int
+	if responseStatus != 0 {
+		status = &responseStatus
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"delivery_id": d.ID,
+		"endpoint_id": d.EndpointID,
+		"event_type":  d.EventType,
+		"attempt":     d.Attempts + 1,
+	})
+
+	if sendErr == nil {
+		query := `
+			UPDATE webhook_deliveries
+			SET status = 'delivered', attempts = attempts + 1, response_status = $2,
+			    error_message = NULL, delivered_at = NOW(), updated_at = NOW()
+			WHERE id = $1`
+		_, err := s.db.ExecContext(ctx, query, d.ID, status)
+		logger.Info("Webhook delivered")
+		return err
+	}
+
+	attempts := d.Attempts + 1
+	newStatus := models.WebhookStatusPending
+	if attempts >= maxAttempts {
+		newStatus = models.WebhookStatusFailed
+	}
+	nextAttempt := time.Now().UTC().Add(backoff(attempts))
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, response_status = $4, error_message = $5,
+		    next_attempt_at = $6, updated_at = NOW()
+		WHERE id = $1`
+	_, err := s.db.ExecContext(ctx, query, d.ID, newStatus, attempts, status, sendErr.Error(), nextAttempt)
+
+	logger.WithError(sendErr).WithField("status", newStatus).Warn("Webhook delivery failed")
+	return err
+}
+
+// backoff doubles the wait after each failed attempt: 1m, 2m, 4m, ...
+func backoff(attempts int) time.Duration {
+	return time.Minute << (attempts - 1)
+}
+
+// Sign returns the hex HMAC-SHA256 signature of "<timestamp>.<body>"
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
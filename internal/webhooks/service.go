@@ -0,0 +1,269 @@
+// Package webhooks delivers signed JSON events to operator-registered URLs so
+// external automation can react to user activity without polling the database.
+// Events are written to webhook_deliveries and sent by ProcessDeliveries, the
+// same outbox approach used for email.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/database"
+	apperrors "github.com/jamesonstone/what-did-you-get-done-this-week/internal/errors"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/internal/events"
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+// EventTypes lists every event an endpoint can subscribe to
+var EventTypes = []string{
+	models.WebhookEventUserVerified,
+	models.WebhookEventEntryCreated,
+	models.WebhookEventSummaryGenerated,
+	models.WebhookEventEmailRejected,
+	models.WebhookEventOutboxStuck,
+	models.WebhookEventAnomaliesDetected,
+}
+
+// Event is the JSON body POSTed to endpoints
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type Service struct {
+	db     *database.DB
+	sender *sender
+}
+
+func NewService(db *database.DB) *Service {
+	return &Service{
+		db:     db,
+		sender: newSender(),
+	}
+}
+
+// Register adds an endpoint subscribed to eventTypes and returns it with its
+// generated signing secret
+func (s *Service) Register(ctx context.Context, endpointURL string, eventTypes []string) (*models.WebhookEndpoint, error) {
+	parsed, err := url.Parse(endpointURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "invalid webhook URL: %s", endpointURL)
+	}
+
+	if len(eventTypes) == 0 {
+		return nil, apperrors.New(apperrors.CodeInvalidInput, "at least one event type is required")
+	}
+	for _, eventType := range eventTypes {
+		if !isEventType(eventType) {
+			return nil, apperrors.New(apperrors.CodeInvalidInput, "unknown event type: %s", eventType)
+		}
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		URL:        endpointURL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		IsActive:   true,
+	}
+
+	query := `
+		INSERT INTO webhook_endpoints (url, secret, event_types)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at`
+
+	err = s.db.QueryRowContext(ctx, query, endpointURL, secret, pq.Array(eventTypes)).Scan(
+		&endpoint.ID, &endpoint.CreatedAt, &endpoint.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register webhook: %w", err)
+	}
+
+	return endpoint, nil
+}
+
+// Remove deletes an endpoint and its delivery log
+func (s *Service) Remove(ctx context.Context, endpointID int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, endpointID)
+	if err != nil {
+		return fmt.Errorf("failed to remove webhook: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.New(apperrors.CodeInvalidInput, "webhook %d not found", endpointID)
+	}
+
+	return nil
+}
+
+// ListEndpoints returns every registered endpoint
+func (s *Service) ListEndpoints(ctx context.Context) ([]*models.WebhookEndpoint, error) {
+	query := `
+		SELECT id, url, secret, event_types, is_active, created_at, updated_at
+		FROM webhook_endpoints ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []*models.WebhookEndpoint
+	for rows.Next() {
+		var endpoint models.WebhookEndpoint
+		err := rows.Scan(&endpoint.ID, &endpoint.URL, &endpoint.Secret, pq.Array(&endpoint.EventTypes),
+			&endpoint.IsActive, &endpoint.CreatedAt, &endpoint.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		endpoints = append(endpoints, &endpoint)
+	}
+
+	return endpoints, rows.Err()
+}
+
+// ListDeliveries returns the most recent deliveries, newest first. An
+// endpointID of 0 lists deliveries for every endpoint.
+func (s *Service) ListDeliveries(ctx context.Context, endpointID, limit int) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT id, endpoint_id, event_type, payload, status, attempts, response_status,
+		       error_message, next_attempt_at, delivered_at, created_at
+		FROM webhook_deliveries
+		WHERE $1 = 0 OR endpoint_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, endpointID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var responseStatus sql.NullInt64
+		var errorMessage sql.NullString
+		var deliveredAt sql.NullTime
+
+		err := rows.Scan(&delivery.ID, &delivery.EndpointID, &delivery.EventType, &delivery.Payload,
+			&delivery.Status, &delivery.Attempts, &responseStatus, &errorMessage,
+			&delivery.NextAttemptAt, &deliveredAt, &delivery.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+
+		if responseStatus.Valid {
+			status := int(responseStatus.Int64)
+			delivery.ResponseStatus = &status
+		}
+		if errorMessage.Valid {
+			delivery.ErrorMessage = &errorMessage.String
+		}
+		if deliveredAt.Valid {
+			delivery.DeliveredAt = &deliveredAt.Time
+		}
+
+		deliveries = append(deliveries, &delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Publish queues an event for every active endpoint subscribed to eventType.
+// A nil Service publishes nothing, so callers without webhooks configured
+// need no special casing.
+func (s *Service) Publish(ctx context.Context, eventType string, data interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	id, err := generateSecret()
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(Event{
+		ID:        "evt_" + id[:24],
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (endpoint_id, event_type, payload)
+		SELECT id, $1, $2 FROM webhook_endpoints
+		WHERE is_active = TRUE AND $1 = ANY(event_types)`
+
+	result, err := s.db.ExecContext(ctx, query, eventType, string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to queue webhook event: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n > 0 {
+		logrus.WithFields(logrus.Fields{
+			"event_type": eventType,
+			"endpoints":  n,
+		}).Info("Webhook event queued")
+	}
+
+	return nil
+}
+
+// domainEventTypes maps the domain events exposed to webhooks to their public
+// event names, which predate the event bus
+var domainEventTypes = map[string]string{
+	events.UserVerified:      models.WebhookEventUserVerified,
+	events.EntrySaved:        models.WebhookEventEntryCreated,
+	events.SummaryGenerated:  models.WebhookEventSummaryGenerated,
+	events.OutboxStuck:       models.WebhookEventOutboxStuck,
+	events.AnomaliesDetected: models.WebhookEventAnomaliesDetected,
+}
+
+// HandleEvent queues deliveries for a domain event; subscribe it to the event
+// bus. Of EmailFailed events only sends SES rejected are delivered, as
+// email.rejected; bounces SES reports later never reach the app.
+func (s *Service) HandleEvent(ctx context.Context, event events.Event) error {
+	eventType, ok := domainEventTypes[event.Type]
+	if failure, isFailure := event.Data.(events.EmailFailure); isFailure && failure.Rejected {
+		eventType, ok = models.WebhookEventEmailRejected, true
+	}
+	if !ok {
+		return nil
+	}
+	return s.Publish(ctx, eventType, event.Data)
+}
+
+func isEventType(eventType string) bool {
+	for _, known := range EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
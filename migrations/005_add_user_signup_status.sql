@@ -0,0 +1,5 @@
+-- Signup status: tracks the double opt-in flow (pending_verification -> pending_confirmation -> active)
+ALTER TABLE users ADD COLUMN signup_status VARCHAR(30) NOT NULL DEFAULT 'pending_verification';
+
+-- Users verified before the double opt-in flow are already active
+UPDATE users SET signup_status = 'active' WHERE is_verified = TRUE;
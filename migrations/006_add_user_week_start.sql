@@ -0,0 +1,2 @@
+-- Week start preference: which day a user's reporting week begins on ('monday' or 'sunday')
+ALTER TABLE users ADD COLUMN week_start VARCHAR(10) NOT NULL DEFAULT 'monday';
@@ -0,0 +1,16 @@
+-- Delivery channel: where a user's daily prompts are sent ('email' or a chat integration such as 'msteams')
+ALTER TABLE users ADD COLUMN delivery_channel VARCHAR(20) NOT NULL DEFAULT 'email';
+
+-- Chat channel identities: the webhook prompts are delivered to and the external user id replies arrive from
+CREATE TABLE user_channels (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL,
+    webhook_url TEXT,
+    external_user_id VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_user_channels_user_channel ON user_channels(user_id, channel);
+CREATE UNIQUE INDEX idx_user_channels_external ON user_channels(channel, external_user_id);
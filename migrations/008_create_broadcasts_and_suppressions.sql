@@ -0,0 +1,21 @@
+-- Email suppressions: addresses that must not receive bulk email (bounces, complaints, manual opt-outs)
+CREATE TABLE email_suppressions (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Broadcasts: audit record of every admin announcement, including dry runs
+CREATE TABLE broadcasts (
+    id SERIAL PRIMARY KEY,
+    subject VARCHAR(500) NOT NULL,
+    template_name VARCHAR(255) NOT NULL,
+    body_template TEXT NOT NULL,
+    recipient_count INTEGER NOT NULL DEFAULT 0,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    sent_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_broadcasts_created ON broadcasts(created_at);
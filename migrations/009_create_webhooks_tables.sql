@@ -0,0 +1,32 @@
+-- Webhook endpoints: operator-registered URLs that receive signed JSON events
+CREATE TABLE webhook_endpoints (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL, -- HMAC-SHA256 signing key, shared with the receiver
+    event_types TEXT[] NOT NULL, -- e.g. {user.verified,entry.created}
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Webhook deliveries: one row per event per endpoint, processed like the email outbox
+CREATE TABLE webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    endpoint_id INTEGER NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSON NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending', -- 'pending', 'delivered', 'failed'
+    attempts INTEGER DEFAULT 0,
+    response_status INTEGER,
+    error_message TEXT,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index for delivery processing
+CREATE INDEX idx_webhook_deliveries_status ON webhook_deliveries(status, next_attempt_at);
+
+-- Index for per-endpoint delivery logs
+CREATE INDEX idx_webhook_deliveries_endpoint ON webhook_deliveries(endpoint_id, created_at);
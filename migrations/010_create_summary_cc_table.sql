@@ -0,0 +1,15 @@
+-- Summary CC recipients: extra addresses (max 3 per user) copied on the weekly summary once they confirm
+CREATE TABLE summary_cc_recipients (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    confirmation_code VARCHAR(10) NOT NULL,
+    confirmed_at TIMESTAMP, -- NULL until the CC'd party replies with the code
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_summary_cc_user_email ON summary_cc_recipients(user_id, email);
+CREATE INDEX idx_summary_cc_email ON summary_cc_recipients(email);
+
+-- CC addresses for an outbox email
+ALTER TABLE email_logs ADD COLUMN cc_emails TEXT[];
@@ -0,0 +1,2 @@
+-- Entry format preference: 'freeform' or a guided reflection format such as 'standup'
+ALTER TABLE users ADD COLUMN entry_format VARCHAR(20) NOT NULL DEFAULT 'freeform';
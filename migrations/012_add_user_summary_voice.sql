@@ -0,0 +1,2 @@
+-- Summary voice preference: 'coach' ("You shipped...") or 'first_person' ("This week I shipped...")
+ALTER TABLE users ADD COLUMN summary_voice VARCHAR(20) NOT NULL DEFAULT 'coach';
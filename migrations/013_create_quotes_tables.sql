@@ -0,0 +1,39 @@
+-- Quotes shown in the daily prompt. User submissions start inactive until an admin approves them.
+CREATE TABLE quotes (
+    id SERIAL PRIMARY KEY,
+    text TEXT NOT NULL,
+    author VARCHAR(255),
+    submitted_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_quotes_text ON quotes(text);
+
+-- Which quote each user was shown, so a quote isn't repeated within a month
+CREATE TABLE quote_deliveries (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    quote_id INTEGER NOT NULL REFERENCES quotes(id) ON DELETE CASCADE,
+    shown_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_quote_deliveries_user_shown ON quote_deliveries(user_id, shown_at);
+
+-- Per-user opt-out
+ALTER TABLE users ADD COLUMN quotes_enabled BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Starter quotes (previously hard-coded in internal/email/templates.go)
+INSERT INTO quotes (text, author) VALUES
+    ('The way to get started is to quit talking and begin doing.', 'Walt Disney'),
+    ('Innovation distinguishes between a leader and a follower.', 'Steve Jobs'),
+    ('Your limitation—it''s only your imagination.', NULL),
+    ('Push yourself, because no one else is going to do it for you.', NULL),
+    ('Great things never come from comfort zones.', NULL),
+    ('Dream it. Wish it. Do it.', NULL),
+    ('Success doesn''t just find you. You have to go out and get it.', NULL),
+    ('The harder you work for something, the greater you''ll feel when you achieve it.', NULL),
+    ('Don''t stop when you''re tired. Stop when you''re done.', NULL),
+    ('Wake up with determination. Go to bed with satisfaction.', NULL)
+ON CONFLICT (text) DO NOTHING;
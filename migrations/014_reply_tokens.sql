@@ -0,0 +1,6 @@
+-- Per-user reply tokens. Prompts are sent with Reply-To reply+<token>@<domain>
+-- so replies are matched to the user even when sent from another address.
+ALTER TABLE users ADD COLUMN reply_token VARCHAR(32) NOT NULL DEFAULT substr(md5(random()::text || clock_timestamp()::text), 1, 16);
+CREATE UNIQUE INDEX idx_users_reply_token ON users(reply_token);
+
+ALTER TABLE email_logs ADD COLUMN reply_to VARCHAR(255);
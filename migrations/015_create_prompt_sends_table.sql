@@ -0,0 +1,9 @@
+-- Ledger of daily prompts sent, keyed by the user's local date, so the
+-- scheduler and manual CLI triggers can never send two prompts on one day
+CREATE TABLE prompt_sends (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    prompt_date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, prompt_date)
+);
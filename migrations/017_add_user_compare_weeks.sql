@@ -0,0 +1,2 @@
+-- Whether weekly summaries are compared against the user's previous summaries
+ALTER TABLE users ADD COLUMN compare_weeks BOOLEAN NOT NULL DEFAULT TRUE;
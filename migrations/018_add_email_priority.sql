@@ -0,0 +1,11 @@
+-- Outbox priority, set from the email type when queued. ProcessOutbox drains
+-- higher priorities first, each with its own per-run budget, so verification
+-- and other transactional mail never waits behind a batch of summaries.
+ALTER TABLE email_logs ADD COLUMN priority SMALLINT NOT NULL DEFAULT 1;
+
+UPDATE email_logs SET priority = 2
+WHERE email_type IN ('verification', 'clarification', 'confirmation', 'data_report', 'schedule_update', 'cc_request');
+UPDATE email_logs SET priority = 0
+WHERE email_type IN ('weekly_summary', 'announcement');
+
+CREATE INDEX idx_email_logs_priority ON email_logs(status, priority, created_at);
@@ -0,0 +1,5 @@
+-- Soft-deleted entries are hidden from summaries, the API and data reports,
+-- can be restored for 30 days, and are then purged by the scheduler.
+ALTER TABLE entries ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_entries_deleted_at ON entries(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Clarification attempts per reply thread, so a user whose replies keep
+-- failing to parse is escalated instead of getting a clarification each time
+CREATE TABLE clarification_state (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    thread_key VARCHAR(255) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, thread_key)
+);
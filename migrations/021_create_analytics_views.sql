@@ -0,0 +1,65 @@
+-- Dashboard views, refreshed nightly by the scheduler so analytics queries
+-- read precomputed rows instead of scanning entries. Each has a unique index
+-- so it can be refreshed CONCURRENTLY without blocking readers.
+
+-- Users who replied, per entry date
+CREATE MATERIALIZED VIEW analytics_daily_active_repliers AS
+SELECT entry_date AS day, COUNT(DISTINCT user_id) AS repliers
+FROM entries
+WHERE deleted_at IS NULL
+GROUP BY entry_date;
+
+CREATE UNIQUE INDEX idx_analytics_daily_active_repliers_day ON analytics_daily_active_repliers(day);
+
+-- Time from each daily prompt to the reply it prompted, bucketed per week.
+-- A reply is matched to the latest prompt sent to the user before it.
+CREATE MATERIALIZED VIEW analytics_reply_latency AS
+SELECT
+    date_trunc('week', p.created_at)::date AS week_start,
+    COUNT(*) AS replies,
+    COUNT(*) FILTER (WHERE e.created_at - p.created_at < INTERVAL '1 hour') AS under_1h,
+    COUNT(*) FILTER (WHERE e.created_at - p.created_at >= INTERVAL '1 hour' AND e.created_at - p.created_at < INTERVAL '4 hours') AS from_1h_to_4h,
+    COUNT(*) FILTER (WHERE e.created_at - p.created_at >= INTERVAL '4 hours' AND e.created_at - p.created_at < INTERVAL '12 hours') AS from_4h_to_12h,
+    COUNT(*) FILTER (WHERE e.created_at - p.created_at >= INTERVAL '12 hours' AND e.created_at - p.created_at < INTERVAL '24 hours') AS from_12h_to_24h,
+    COUNT(*) FILTER (WHERE e.created_at - p.created_at >= INTERVAL '24 hours') AS over_24h,
+    percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM e.created_at - p.created_at) / 60) AS p50_minutes,
+    percentile_cont(0.9) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM e.created_at - p.created_at) / 60) AS p90_minutes
+FROM entries e
+JOIN LATERAL (
+    SELECT created_at FROM prompt_sends
+    WHERE user_id = e.user_id AND created_at <= e.created_at
+    ORDER BY created_at DESC
+    LIMIT 1
+) p ON TRUE
+WHERE e.deleted_at IS NULL
+GROUP BY 1;
+
+CREATE UNIQUE INDEX idx_analytics_reply_latency_week ON analytics_reply_latency(week_start);
+
+-- Verified users grouped by signup week, with how many of each cohort
+-- replied in each week since signing up (week 0 is the signup week)
+CREATE MATERIALIZED VIEW analytics_cohort_retention AS
+WITH cohorts AS (
+    SELECT id AS user_id, date_trunc('week', created_at)::date AS cohort_week
+    FROM users
+    WHERE is_verified AND created_at IS NOT NULL
+),
+sizes AS (
+    SELECT cohort_week, COUNT(*) AS cohort_size FROM cohorts GROUP BY cohort_week
+),
+activity AS (
+    SELECT DISTINCT user_id, date_trunc('week', entry_date)::date AS active_week
+    FROM entries
+    WHERE deleted_at IS NULL
+)
+SELECT
+    c.cohort_week,
+    (a.active_week - c.cohort_week) / 7 AS week_number,
+    s.cohort_size,
+    COUNT(*) AS active_users
+FROM cohorts c
+JOIN activity a ON a.user_id = c.user_id AND a.active_week >= c.cohort_week
+JOIN sizes s ON s.cohort_week = c.cohort_week
+GROUP BY c.cohort_week, a.active_week, s.cohort_size;
+
+CREATE UNIQUE INDEX idx_analytics_cohort_retention_week ON analytics_cohort_retention(cohort_week, week_number);
@@ -0,0 +1,20 @@
+-- History of scheduler job runs, scheduled or started from the CLI
+CREATE TABLE job_runs (
+    id SERIAL PRIMARY KEY,
+    job_name VARCHAR(100) NOT NULL,
+    triggered_by VARCHAR(20) NOT NULL, -- 'schedule' or 'manual'
+    status VARCHAR(20) NOT NULL DEFAULT 'running', -- running, succeeded, failed
+    error_message TEXT,
+    duration_ms INTEGER,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_job_runs_name_started ON job_runs(job_name, started_at DESC);
+
+-- Jobs disabled from the CLI. JOBS_DISABLED in the environment disables jobs too.
+CREATE TABLE job_settings (
+    job_name VARCHAR(100) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,12 @@
+-- Mentors: one address per user that receives a monthly digest of their weekly summaries once it confirms
+CREATE TABLE mentors (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    confirmation_code VARCHAR(10) NOT NULL,
+    confirmed_at TIMESTAMP, -- NULL until the mentor replies with the code
+    last_digest_month DATE, -- first day of the last month a digest was sent for
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_mentors_email ON mentors(email);
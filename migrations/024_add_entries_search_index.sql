@@ -0,0 +1,2 @@
+-- Full-text search over entries, for <ask> questions about the journal
+CREATE INDEX idx_entries_search ON entries USING GIN (to_tsvector('english', raw_content)) WHERE deleted_at IS NULL;
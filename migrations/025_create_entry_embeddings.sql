@@ -0,0 +1,16 @@
+-- Entry embeddings for semantic search. Requires the pgvector extension
+-- (the pgvector/pgvector image locally; supported on RDS PostgreSQL 15.2+).
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE entry_embeddings (
+    entry_id INTEGER PRIMARY KEY REFERENCES entries(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    model VARCHAR(100) NOT NULL,
+    content_hash VARCHAR(32) NOT NULL, -- md5 of entries.raw_content when embedded
+    embedding vector(1024) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_entry_embeddings_user_id ON entry_embeddings(user_id);
+CREATE INDEX idx_entry_embeddings_embedding ON entry_embeddings USING hnsw (embedding vector_cosine_ops);
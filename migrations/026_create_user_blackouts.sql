@@ -0,0 +1,14 @@
+-- User blackouts: days without prompts that don't break streaks. A row is
+-- either a date range (<off>) or the user's holiday calendar (<holiday>).
+CREATE TABLE user_blackouts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date DATE, -- first day off, inclusive
+    end_date DATE, -- last day off, inclusive
+    country VARCHAR(2), -- ISO 3166 code of a supported holiday calendar
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((country IS NULL) = (start_date IS NOT NULL AND end_date IS NOT NULL AND end_date >= start_date))
+);
+
+CREATE INDEX idx_user_blackouts_user_id ON user_blackouts(user_id);
+CREATE UNIQUE INDEX idx_user_blackouts_country ON user_blackouts(user_id) WHERE country IS NOT NULL;
@@ -0,0 +1,24 @@
+-- Dashboard sign-in. A magic link is a single-use token emailed to the user;
+-- redeeming it starts a session kept in a cookie. As with api_tokens, only
+-- SHA-256 hashes of the tokens are stored.
+CREATE TABLE web_logins (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_web_logins_user_id ON web_logins(user_id);
+
+CREATE TABLE web_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_web_sessions_user_id ON web_sessions(user_id);
@@ -0,0 +1,37 @@
+-- Project history: one row per stretch of time a user spent on a project.
+-- started_on is inclusive and ended_on exclusive; the open row is the
+-- current project_focus.
+CREATE TABLE user_projects (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    started_on DATE NOT NULL,
+    ended_on DATE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ended_on IS NULL OR ended_on > started_on)
+);
+
+CREATE INDEX idx_user_projects_user_id ON user_projects(user_id);
+CREATE UNIQUE INDEX idx_user_projects_current ON user_projects(user_id) WHERE ended_on IS NULL;
+
+-- Earlier focus changes weren't recorded, so the current project is assumed
+-- to date from signup
+INSERT INTO user_projects (user_id, name, started_on)
+SELECT id, project_focus, created_at::date FROM users
+WHERE project_focus IS NOT NULL AND project_focus <> '';
+
+-- Quarterly per-project rollups, one per user, project and quarter
+CREATE TABLE project_rollups (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project VARCHAR(255) NOT NULL,
+    quarter_start DATE NOT NULL, -- first day of the quarter
+    first_entry_date DATE NOT NULL, -- first and last entry summarized
+    last_entry_date DATE NOT NULL,
+    summary_paragraph TEXT NOT NULL,
+    bullet_points JSON NOT NULL,
+    llm_model VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_project_rollups_user_project_quarter ON project_rollups(user_id, project, quarter_start);
@@ -0,0 +1,9 @@
+-- Replies received through the inbound webhook, for per-sender rate limiting.
+-- Rows older than the rate window are deleted as new requests arrive.
+CREATE TABLE inbound_requests (
+    id BIGSERIAL PRIMARY KEY,
+    sender VARCHAR(255) NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_inbound_requests_sender_received ON inbound_requests(sender, received_at);
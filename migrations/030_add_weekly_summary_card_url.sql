@@ -0,0 +1,2 @@
+-- Link to the summary's share card image, when share cards are enabled
+ALTER TABLE weekly_summaries ADD COLUMN card_url TEXT;
@@ -0,0 +1,13 @@
+-- One row per model call, for cost review. Pruned by the llm_calls
+-- retention policy (30 days by default).
+CREATE TABLE llm_calls (
+    id BIGSERIAL PRIMARY KEY,
+    model VARCHAR(255) NOT NULL,
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_llm_calls_created_at ON llm_calls(created_at);
@@ -0,0 +1,15 @@
+-- Secrets integrations need, such as Teams webhook URLs and API tokens,
+-- encrypted with AES-256-GCM under the key named by key_id
+CREATE TABLE integration_credentials (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    integration VARCHAR(50) NOT NULL,
+    key_id VARCHAR(50) NOT NULL,
+    ciphertext BYTEA NOT NULL,
+    rotated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, integration)
+);
+
+CREATE INDEX idx_integration_credentials_key_id ON integration_credentials(key_id);
@@ -0,0 +1,15 @@
+-- Monday goal prompts (opt-in) and the goals users reply with, echoed back
+-- in that week's summary
+ALTER TABLE users ADD COLUMN weekly_goals BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE weekly_goals (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    week_start_date DATE NOT NULL,
+    goals TEXT[] NOT NULL DEFAULT '{}',
+    prompted_at TIMESTAMP,
+    replied_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, week_start_date)
+);
@@ -0,0 +1,8 @@
+-- Signup wizard state: the preference question an unverified user is
+-- answering. The row is removed once every question is answered.
+CREATE TABLE signup_wizards (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    step VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- Reply language: keywords such as "pausa 2 semanas" are read in it
+ALTER TABLE users ADD COLUMN language VARCHAR(10) NOT NULL DEFAULT 'en';
@@ -0,0 +1,7 @@
+-- When each job last fired in tick mode (SCHEDULER_MODE=tick or lambda), so
+-- short-lived scheduler invocations don't run a firing twice or miss one
+CREATE TABLE job_schedule (
+    job_name VARCHAR(100) PRIMARY KEY,
+    last_fired_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
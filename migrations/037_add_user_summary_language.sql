@@ -0,0 +1,2 @@
+-- Weekly summary language: a language code, or 'auto' to match the entries
+ALTER TABLE users ADD COLUMN summary_language VARCHAR(10) NOT NULL DEFAULT 'auto';
@@ -0,0 +1,19 @@
+-- Open and click tracking for summary emails (EMAIL_TRACKING). Tracked
+-- emails carry an HTML part and a token their tracking links refer to.
+ALTER TABLE email_logs ADD COLUMN body_html TEXT;
+ALTER TABLE email_logs ADD COLUMN tracking_token VARCHAR(32);
+CREATE UNIQUE INDEX idx_email_logs_tracking_token ON email_logs(tracking_token) WHERE tracking_token IS NOT NULL;
+
+-- Users can opt out of tracking
+ALTER TABLE users ADD COLUMN email_tracking BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE TABLE email_events (
+    id SERIAL PRIMARY KEY,
+    email_log_id INTEGER NOT NULL REFERENCES email_logs(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    event_type VARCHAR(20) NOT NULL,
+    url TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_email_events_email ON email_events(email_log_id);
+CREATE INDEX idx_email_events_type_date ON email_events(event_type, created_at);
@@ -0,0 +1,10 @@
+-- Audit log of operator changes to accounts, such as `user verify`
+CREATE TABLE admin_actions (
+    id SERIAL PRIMARY KEY,
+    action VARCHAR(50) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    details JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_admin_actions_user ON admin_actions(user_id, created_at);
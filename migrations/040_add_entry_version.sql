@@ -0,0 +1,3 @@
+-- Entry version for optimistic concurrency: every write bumps it, and a
+-- reply is only saved if the version it read is still current
+ALTER TABLE entries ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
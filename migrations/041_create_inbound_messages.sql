@@ -0,0 +1,19 @@
+-- Raw inbound replies as received, so a reply that failed to process can be
+-- replayed once the bug is fixed. SES messages keep the S3 object they were
+-- stored in; webhook messages keep the request body.
+CREATE TABLE inbound_messages (
+    id BIGSERIAL PRIMARY KEY,
+    source VARCHAR(20) NOT NULL,
+    message_id VARCHAR(255),
+    s3_bucket VARCHAR(255),
+    s3_key VARCHAR(1024),
+    payload TEXT NOT NULL,
+    sender VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP
+);
+
+CREATE INDEX idx_inbound_messages_status ON inbound_messages(status, received_at);
@@ -0,0 +1,34 @@
+-- Organizations group users of a team for billing. Usage is metered per
+-- organization per day into usage_records, one row per metric, in the shape
+-- Stripe metered billing takes: "increment" records add to the period's
+-- usage, "set" records (seats) replace it.
+CREATE TABLE organizations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ADD COLUMN organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL;
+CREATE INDEX idx_users_organization ON users(organization_id);
+
+-- Model calls are attributed to the user they were made for, with their
+-- estimated cost, so LLM spend can be metered
+ALTER TABLE llm_calls ADD COLUMN user_id INTEGER;
+ALTER TABLE llm_calls ADD COLUMN cost_cents INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX idx_llm_calls_user_created ON llm_calls(user_id, created_at);
+
+CREATE TABLE usage_records (
+    id BIGSERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    metric VARCHAR(50) NOT NULL,
+    usage_date DATE NOT NULL,
+    quantity BIGINT NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+    reported_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(organization_id, metric, usage_date)
+);
+
+CREATE INDEX idx_usage_records_unreported ON usage_records(usage_date) WHERE reported_at IS NULL;
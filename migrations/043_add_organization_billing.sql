@@ -0,0 +1,20 @@
+-- Organizations subscribe through Stripe. subscription_status mirrors the
+-- Stripe subscription ('none' before checkout); after a failed payment the
+-- organization keeps sending until grace_until, and dunning_stage records
+-- which dunning email it was last sent.
+ALTER TABLE organizations ADD COLUMN billing_email VARCHAR(255);
+ALTER TABLE organizations ADD COLUMN stripe_customer_id VARCHAR(255) UNIQUE;
+ALTER TABLE organizations ADD COLUMN stripe_subscription_id VARCHAR(255);
+ALTER TABLE organizations ADD COLUMN subscription_status VARCHAR(50) NOT NULL DEFAULT 'none';
+ALTER TABLE organizations ADD COLUMN grace_until TIMESTAMP;
+ALTER TABLE organizations ADD COLUMN dunning_stage INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE organizations ADD COLUMN invoice_url TEXT;
+ALTER TABLE organizations ADD COLUMN billing_updated_at TIMESTAMP;
+
+-- Stripe webhook events already handled, since Stripe may deliver one more
+-- than once
+CREATE TABLE stripe_events (
+    id VARCHAR(255) PRIMARY KEY,
+    type VARCHAR(100) NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
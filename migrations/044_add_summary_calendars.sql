@@ -0,0 +1,7 @@
+-- Summary periods for fiscal weeks and sprints: summary_period_weeks-long
+-- periods, one of which ends on summary_period_end. A user's calendar wins
+-- over their organization's; with neither, summaries follow calendar weeks.
+ALTER TABLE organizations ADD COLUMN summary_period_weeks INTEGER;
+ALTER TABLE organizations ADD COLUMN summary_period_end DATE;
+ALTER TABLE users ADD COLUMN summary_period_weeks INTEGER;
+ALTER TABLE users ADD COLUMN summary_period_end DATE;
@@ -0,0 +1,15 @@
+-- Data keys for self-custody. The private half of each X25519 key pair is
+-- only stored wrapped under the user's passphrase (PBKDF2-HMAC-SHA256 and
+-- AES-256-GCM). Once users.self_custody_at is set, the user's entries are
+-- sealed to public_key and the server can no longer read them.
+CREATE TABLE user_keys (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    public_key BYTEA NOT NULL,
+    salt BYTEA NOT NULL,
+    iterations INTEGER NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ADD COLUMN self_custody_at TIMESTAMP;
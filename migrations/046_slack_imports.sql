@@ -0,0 +1,12 @@
+-- Slack: the channel a user's standup messages are imported from
+ALTER TABLE user_channels ADD COLUMN import_channel VARCHAR(64);
+
+-- Chat messages already logged as entries, so importing again or logging a
+-- message twice doesn't repeat it
+CREATE TABLE channel_messages (
+    channel VARCHAR(20) NOT NULL,
+    message_id VARCHAR(255) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel, message_id)
+);
@@ -0,0 +1,2 @@
+-- Filtering a user's entries by project for GET /v1/entries and entry list
+CREATE INDEX idx_entries_user_project ON entries(user_id, LOWER(project_tag), entry_date) WHERE deleted_at IS NULL;
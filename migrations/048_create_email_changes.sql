@@ -0,0 +1,16 @@
+-- Email address changes. A pending change waits for the code sent to
+-- new_email; once confirmed, old_email still finds the user for inbound mail
+-- until alias_until.
+CREATE TABLE email_changes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_email VARCHAR(255) NOT NULL,
+    new_email VARCHAR(255) NOT NULL,
+    confirmation_code VARCHAR(10) NOT NULL,
+    requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    confirmed_at TIMESTAMP,
+    alias_until TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_email_changes_pending ON email_changes(user_id) WHERE confirmed_at IS NULL;
+CREATE INDEX idx_email_changes_new ON email_changes(LOWER(new_email)) WHERE confirmed_at IS NULL;
+CREATE INDEX idx_email_changes_alias ON email_changes(LOWER(old_email), alias_until) WHERE confirmed_at IS NOT NULL;
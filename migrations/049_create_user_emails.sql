@@ -0,0 +1,12 @@
+-- Other addresses a user replies from. A verified alias finds the user for
+-- inbound mail; outbound mail still goes to users.email.
+CREATE TABLE user_emails (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    verification_code VARCHAR(10) NOT NULL,
+    verified_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_user_emails_user ON user_emails(user_id, LOWER(email));
+CREATE UNIQUE INDEX idx_user_emails_verified ON user_emails(LOWER(email)) WHERE verified_at IS NOT NULL;
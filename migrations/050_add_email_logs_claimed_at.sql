@@ -0,0 +1,5 @@
+-- ProcessOutbox claims due emails by moving them from 'pending' to 'sending'
+-- before sending, so concurrent runs in different processes never send the
+-- same email. claimed_at lets a claim left by a crashed run be released.
+ALTER TABLE email_logs ADD COLUMN claimed_at TIMESTAMP;
+CREATE INDEX idx_email_logs_claimed ON email_logs(claimed_at) WHERE status = 'sending';
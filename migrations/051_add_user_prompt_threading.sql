@@ -0,0 +1,3 @@
+-- Prompt threading: 'daily' starts a new thread each day, 'weekly' keeps a
+-- week's daily prompts in one thread
+ALTER TABLE users ADD COLUMN prompt_threading VARCHAR(10) NOT NULL DEFAULT 'daily';
@@ -0,0 +1,9 @@
+-- Private feeds of a user's weekly summaries, which users opt into. Only a
+-- SHA-256 hash of the feed token is stored; the feed URL is shown once and
+-- rotating it replaces the row.
+CREATE TABLE summary_feeds (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    last_fetched_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
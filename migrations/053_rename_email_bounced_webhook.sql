@@ -0,0 +1,3 @@
+-- email.bounced only ever fired when SES rejected a send, not for bounces
+-- SES reports later, so it is now email.rejected
+UPDATE webhook_endpoints SET event_types = array_replace(event_types, 'email.bounced', 'email.rejected');
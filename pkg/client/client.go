@@ -0,0 +1,188 @@
+// Package client is a Go SDK for the admin API served by cmd/api. Responses
+// are decoded into the same types the server uses, from pkg/models.
+//
+//	c := client.New("https://api.example.com", os.Getenv("ADMIN_API_KEY"))
+//	prefs, err := c.GetPreferences(ctx, "user@example.com")
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jamesonstone/what-did-you-get-done-this-week/pkg/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// Client calls the admin API with a bearer API key
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient replaces the default HTTP client, e.g. to set a transport or timeout
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func New(baseURL, apiKey string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error is a non-2xx response. Code is the server's error code, such as
+// "user_not_found" or "invalid_input".
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api error %d (%s): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Signup starts a signup; the user receives the welcome email with a
+// verification code
+func (c *Client) Signup(ctx context.Context, email string) error {
+	return c.do(ctx, http.MethodPost, "/v1/signup", nil, map[string]string{"email": email}, nil)
+}
+
+// ListEntries returns the user's entries dated from through to, inclusive.
+// Zero times default to the last 7 days.
+func (c *Client) ListEntries(ctx context.Context, email string, from, to time.Time) ([]*models.Entry, error) {
+	query := url.Values{"email": {email}}
+	if !from.IsZero() {
+		query.Set("from", from.Format(dateLayout))
+	}
+	if !to.IsZero() {
+		query.Set("to", to.Format(dateLayout))
+	}
+
+	var entries []*models.Entry
+	if err := c.do(ctx, http.MethodGet, "/v1/entries", query, nil, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetWeeklySummary returns the summary for the week containing week, or nil
+// if none was generated. A zero week means the current week.
+func (c *Client) GetWeeklySummary(ctx context.Context, email string, week time.Time) (*models.WeeklySummary, error) {
+	query := url.Values{"email": {email}}
+	if !week.IsZero() {
+		query.Set("week", week.Format(dateLayout))
+	}
+
+	var summary models.WeeklySummary
+	err := c.do(ctx, http.MethodGet, "/v1/summaries", query, nil, &summary)
+	if apiErr, ok := err.(*Error); ok && apiErr.Code == "not_found" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// GetPreferences returns the user's current preferences
+func (c *Client) GetPreferences(ctx context.Context, email string) (*models.Preferences, error) {
+	var prefs models.Preferences
+	if err := c.do(ctx, http.MethodGet, "/v1/preferences", url.Values{"email": {email}}, nil, &prefs); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// UpdatePreferences applies the non-nil fields of update and returns the
+// resulting preferences
+func (c *Client) UpdatePreferences(ctx context.Context, email string, update models.PreferencesUpdate) (*models.Preferences, error) {
+	var prefs models.Preferences
+	if err := c.do(ctx, http.MethodPatch, "/v1/preferences", url.Values{"email": {email}}, update, &prefs); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// DataReport returns counts of everything stored about the user
+func (c *Client) DataReport(ctx context.Context, email string) (*models.DataReport, error) {
+	var report models.DataReport
+	if err := c.do(ctx, http.MethodGet, "/v1/data-report", url.Values{"email": {email}}, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		var payload struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&payload); err == nil {
+			apiErr.Code, apiErr.Message = payload.Code, payload.Error
+		} else {
+			apiErr.Message = resp.Status
+		}
+		return apiErr
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", path, err)
+	}
+	return nil
+}
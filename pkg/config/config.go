@@ -1,18 +1,55 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 )
 
+// Environments APP_ENV selects; each is a profile of defaults
+const (
+	EnvDev     = "dev"
+	EnvStaging = "staging"
+	EnvProd    = "prod"
+)
+
+// profiles are the defaults each APP_ENV changes. Setting a variable
+// overrides its profile default.
+var profiles = map[string]map[string]string{
+	// Log email instead of sending it
+	EnvDev: {
+		"EMAIL_DRY_RUN": "true",
+	},
+	// Send for real, but run the weekly, monthly and quarterly jobs often
+	// enough to try them out
+	EnvStaging: {
+		"EMAIL_DRY_RUN": "false",
+		"JOB_SCHEDULES": "weekly-summaries=30 * * * *;mentor-digests=0 9 * * *;project-rollups=0 9 * * 1",
+	},
+	EnvProd: {
+		"EMAIL_DRY_RUN": "false",
+	},
+}
+
 type Config struct {
+	// AppEnv is dev, staging or prod; see profiles
+	AppEnv string
+
 	// Domain and Email
 	Domain      string
 	EmailFrom   string
 	SignupEmail string
+	// EmailDryRun logs outbox emails and marks them sent instead of sending
+	// them through SES
+	EmailDryRun bool
+	// EmailTracking adds an open pixel and wrapped links to weekly summaries
+	// of users who haven't opted out; it needs DashboardURL and AuthSecret
+	EmailTracking bool
 
 	// AWS
 	AWSRegion       string
@@ -26,33 +63,381 @@ type Config struct {
 	PostgresUser     string
 	PostgresPassword string
 	PostgresDB       string
+	// PostgresSSLMode is lib/pq's sslmode; RDS Proxy needs "require"
+	PostgresSSLMode string
+	// PostgresReplicaHost is a read replica of the database, reached with
+	// the same port and credentials, for heavy reads; empty reads from the
+	// primary
+	PostgresReplicaHost string
+
+	// Connection pool
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+
+	// Queries
+	DBQueryTimeout time.Duration // deadline for queries whose context has none; 0 for none
+	DBSlowQuery    time.Duration // queries taking this long are logged; 0 turns it off
+
+	// User lookup cache, per process; a size of 0 turns it off
+	UserCacheSize int
+	UserCacheTTL  time.Duration
 
 	// Scheduler
 	DefaultPromptTime   string
 	WeeklySummaryTime   string
+	JobsDisabled        []string
+	// JobSchedules replaces the cron schedule of the jobs it names
+	JobSchedules map[string]string
+	// SchedulerMode is "daemon", a long-running process, or "tick" or
+	// "lambda", one pass over the jobs due per invocation
+	SchedulerMode        string
+	SchedulerMaxLateness time.Duration
 
 	// Admin
 	AdminAPIKey string
 
+	// API server
+	APIAddr string
+	// DashboardURL is the public base URL of the API server, used in
+	// dashboard sign-in links. The dashboard is off when it is empty.
+	DashboardURL string
+	// AuthSecret signs dashboard magic links and CSRF tokens
+	AuthSecret string
+
+	// Integrations
+	MSTeamsSecurityToken string
+	// SlackSigningSecret verifies Slack actions and slash commands, and
+	// SlackBotToken reads standup channels for the daily import
+	SlackSigningSecret string
+	SlackBotToken      string
+	// IntegrationEncryptionKeys encrypt integration credentials: "id:base64"
+	// pairs, the first of which encrypts
+	IntegrationEncryptionKeys string
+
+	// Inbound webhook: replies posted over HTTP must be signed with the
+	// secret, may be limited to source addresses, and are rate limited per
+	// sender per hour (0 for no limit)
+	InboundWebhookSecret string
+	InboundAllowedIPs    []string
+	InboundRateLimit     int
+	// Inbound mail over InboundMaxBytes is refused unparsed; a reply body
+	// over InboundMaxBodyBytes is trimmed or rejected by the policy
+	InboundMaxBytes       int
+	InboundMaxBodyBytes   int
+	InboundOversizePolicy string
+
+	// Email outbox
+	OutboxBatchSize          int
+	OutboxDrain              bool
+	OutboxMaxRun             time.Duration
+	OutboxTransactionalQuota int           // percent of the SES daily quota held for transactional mail
+	OutboxStuckAfter         time.Duration // 0 turns off the outbox watchdog
+	SESMaxSendRate           float64
+	// SESBulkTemplates sends batch mail with SendBulkTemplatedEmail, up to
+	// 50 recipients a call, through templates `cli infra sync-ses-templates`
+	// creates with SESTemplatePrefix
+	SESBulkTemplates  bool
+	SESTemplatePrefix string
+
+	// AdminAlertEmail gets operational alerts such as a stuck outbox
+	AdminAlertEmail string
+
+	// Events
+	EventBus       string
+	EventBusTarget string
+
+	// Job queue that wakes the outbox sender when an email is queued;
+	// JobQueueURL is the Redis server for JOB_QUEUE=redis
+	JobQueue    string
+	JobQueueURL string
+
+	// Entries
+	EntryMergeWindow time.Duration
+
+	// How long the old address still finds a user after they change it
+	EmailChangeAliasWindow time.Duration
+
+	// Daily prompt recap of the last entry; PromptRecapLLM has the LLM write
+	// it, one call per prompt
+	PromptRecap    bool
+	PromptRecapLLM bool
+
+	// Clarifications
+	ClarificationMaxAttempts int
+	ClarificationResetAfter  time.Duration
+	ClarificationAdminEmail  string
+
 	// LLM
-	LLMProvider string
-	LLMModel    string
+	LLMProvider          string
+	LLMModel             string
+	LLMFallbackModels    []string
+	LLMConcurrency       int
+	LLMRequestsPerMinute int
+	LLMMaxTokens         int
+	LLMMaxInputTokens    int
+	LLMStreaming         bool
+
+	// Vertex AI, for LLM_PROVIDER=google_vertex
+	VertexProject  string
+	VertexLocation string
+
+	// Embeddings
+	EmbeddingsModel string
+
+	// Share cards; an empty bucket turns them off
+	ShareCardBucket  string
+	ShareCardBaseURL string
+
+	// Compliance archive of sent email: a mailbox BCC'd on each message
+	// and/or a bucket that gets a copy of it. ArchiveEmailTypes limits the
+	// archive to those types, all when empty; ArchiveExcludeEmailTypes
+	// leaves types out.
+	ArchiveBCC               string
+	ArchiveBucket            string
+	ArchivePrefix            string
+	ArchiveEmailTypes        []string
+	ArchiveExcludeEmailTypes []string
+
+	// Retention, by data type; 0 keeps forever
+	RetentionPolicies map[string]time.Duration
+
+	// Billing through Stripe, off when the secret key is empty. After a
+	// failed payment an organization keeps sending for the grace period.
+	StripeSecretKey     string
+	StripeWebhookSecret string
+	StripePriceID       string
+	BillingGracePeriod  time.Duration
 }
 
+// defaultRetention applies to data types RETENTION_POLICIES doesn't mention
+const defaultRetention = "entries=forever,email_bodies=90d,email_events=180d,inbound_messages=30d,llm_calls=30d,audit_logs=365d"
+
 func Load() (*Config, error) {
 	if err := godotenv.Load(); err != nil {
 		logrus.WithError(err).Debug("No .env file found, using environment variables")
 	}
 
+	appEnv := getEnv("APP_ENV", EnvProd)
+	profile, ok := profiles[appEnv]
+	if !ok {
+		return nil, fmt.Errorf("APP_ENV must be dev, staging or prod, got %q", appEnv)
+	}
+	// getProfileEnv reads key, defaulting to the profile's value if it has one
+	getProfileEnv := func(key, defaultValue string) string {
+		if value, ok := profile[key]; ok {
+			defaultValue = value
+		}
+		return getEnv(key, defaultValue)
+	}
+
+	emailDryRun, err := strconv.ParseBool(getProfileEnv("EMAIL_DRY_RUN", "false"))
+	if err != nil {
+		return nil, err
+	}
+
+	emailTracking, err := strconv.ParseBool(getEnv("EMAIL_TRACKING", "false"))
+	if err != nil {
+		return nil, err
+	}
+
+	jobSchedules, err := parseJobSchedules(getProfileEnv("JOB_SCHEDULES", ""))
+	if err != nil {
+		return nil, err
+	}
+
 	port, err := strconv.Atoi(getEnv("POSTGRES_PORT", "5432"))
 	if err != nil {
 		return nil, err
 	}
 
+	dbMaxOpenConns, err := strconv.Atoi(getEnv("DB_MAX_OPEN_CONNS", "25"))
+	if err != nil {
+		return nil, err
+	}
+
+	dbMaxIdleConns, err := strconv.Atoi(getEnv("DB_MAX_IDLE_CONNS", "25"))
+	if err != nil {
+		return nil, err
+	}
+
+	dbConnMaxLifetime, err := time.ParseDuration(getEnv("DB_CONN_MAX_LIFETIME", "5m"))
+	if err != nil {
+		return nil, err
+	}
+
+	dbConnMaxIdleTime, err := time.ParseDuration(getEnv("DB_CONN_MAX_IDLE_TIME", "0"))
+	if err != nil {
+		return nil, err
+	}
+
+	dbQueryTimeout, err := time.ParseDuration(getEnv("DB_QUERY_TIMEOUT", "30s"))
+	if err != nil {
+		return nil, err
+	}
+
+	dbSlowQuery, err := time.ParseDuration(getEnv("DB_SLOW_QUERY", "1s"))
+	if err != nil {
+		return nil, err
+	}
+
+	userCacheSize, err := strconv.Atoi(getEnv("USER_CACHE_SIZE", "0"))
+	if err != nil {
+		return nil, err
+	}
+
+	userCacheTTL, err := time.ParseDuration(getEnv("USER_CACHE_TTL", "30s"))
+	if err != nil {
+		return nil, err
+	}
+
+	llmConcurrency, err := strconv.Atoi(getEnv("LLM_CONCURRENCY", "4"))
+	if err != nil {
+		return nil, err
+	}
+
+	llmRequestsPerMinute, err := strconv.Atoi(getEnv("LLM_REQUESTS_PER_MINUTE", "60"))
+	if err != nil {
+		return nil, err
+	}
+
+	llmMaxTokens, err := strconv.Atoi(getEnv("LLM_MAX_TOKENS", "1000"))
+	if err != nil {
+		return nil, err
+	}
+
+	llmMaxInputTokens, err := strconv.Atoi(getEnv("LLM_MAX_INPUT_TOKENS", "8000"))
+	if err != nil {
+		return nil, err
+	}
+
+	llmStreaming, err := strconv.ParseBool(getEnv("LLM_STREAMING", "false"))
+	if err != nil {
+		return nil, err
+	}
+
+	entryMergeWindow, err := time.ParseDuration(getEnv("ENTRY_MERGE_WINDOW", "30m"))
+	if err != nil {
+		return nil, err
+	}
+
+	emailChangeAliasWindow, err := time.ParseDuration(getEnv("EMAIL_CHANGE_ALIAS_WINDOW", "336h"))
+	if err != nil {
+		return nil, err
+	}
+
+	promptRecap, err := strconv.ParseBool(getEnv("PROMPT_RECAP", "false"))
+	if err != nil {
+		return nil, err
+	}
+
+	promptRecapLLM, err := strconv.ParseBool(getEnv("PROMPT_RECAP_LLM", "false"))
+	if err != nil {
+		return nil, err
+	}
+
+	clarificationMaxAttempts, err := strconv.Atoi(getEnv("CLARIFICATION_MAX_ATTEMPTS", "2"))
+	if err != nil {
+		return nil, err
+	}
+
+	clarificationResetAfter, err := time.ParseDuration(getEnv("CLARIFICATION_RESET_AFTER", "24h"))
+	if err != nil {
+		return nil, err
+	}
+
+	outboxBatchSize, err := strconv.Atoi(getEnv("OUTBOX_BATCH_SIZE", "50"))
+	if err != nil {
+		return nil, err
+	}
+
+	outboxDrain, err := strconv.ParseBool(getEnv("OUTBOX_DRAIN", "true"))
+	if err != nil {
+		return nil, err
+	}
+
+	sesBulkTemplates, err := strconv.ParseBool(getEnv("SES_BULK_TEMPLATES", "false"))
+	if err != nil {
+		return nil, err
+	}
+
+	outboxMaxRun, err := time.ParseDuration(getEnv("OUTBOX_MAX_RUN", "4m"))
+	if err != nil {
+		return nil, err
+	}
+
+	outboxTransactionalQuota, err := strconv.Atoi(getEnv("OUTBOX_TRANSACTIONAL_QUOTA", "10"))
+	if err != nil {
+		return nil, err
+	}
+	if outboxTransactionalQuota < 0 || outboxTransactionalQuota > 100 {
+		return nil, fmt.Errorf("OUTBOX_TRANSACTIONAL_QUOTA must be a percentage from 0 to 100")
+	}
+
+	outboxStuckAfter, err := time.ParseDuration(getEnv("OUTBOX_STUCK_AFTER", "1h"))
+	if err != nil {
+		return nil, err
+	}
+
+	sesMaxSendRate, err := strconv.ParseFloat(getEnv("SES_MAX_SEND_RATE", "0"), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	inboundRateLimit, err := strconv.Atoi(getEnv("INBOUND_RATE_LIMIT", "30"))
+	if err != nil {
+		return nil, err
+	}
+
+	inboundMaxBytes, err := strconv.Atoi(getEnv("INBOUND_MAX_BYTES", "1048576"))
+	if err != nil {
+		return nil, err
+	}
+
+	inboundMaxBodyBytes, err := strconv.Atoi(getEnv("INBOUND_MAX_BODY_BYTES", "262144"))
+	if err != nil {
+		return nil, err
+	}
+
+	inboundOversizePolicy := getEnv("INBOUND_OVERSIZE_POLICY", "trim")
+	switch inboundOversizePolicy {
+	case "trim", "reject":
+	default:
+		return nil, fmt.Errorf("INBOUND_OVERSIZE_POLICY must be trim or reject, got %q", inboundOversizePolicy)
+	}
+
+	schedulerMode := getEnv("SCHEDULER_MODE", "daemon")
+	switch schedulerMode {
+	case "daemon", "tick", "lambda":
+	default:
+		return nil, fmt.Errorf("SCHEDULER_MODE must be daemon, tick or lambda, got %q", schedulerMode)
+	}
+
+	schedulerMaxLateness, err := time.ParseDuration(getEnv("SCHEDULER_MAX_LATENESS", "1h"))
+	if err != nil {
+		return nil, err
+	}
+
+	billingGracePeriod, err := time.ParseDuration(getEnv("BILLING_GRACE_PERIOD", "168h"))
+	if err != nil {
+		return nil, err
+	}
+
+	retentionPolicies, err := parseRetention(defaultRetention + "," + getEnv("RETENTION_POLICIES", ""))
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
+		AppEnv: appEnv,
+
 		Domain:      getEnv("DOMAIN", "whatdidyougetdone.dev"),
 		EmailFrom:   getEnv("EMAIL_FROM", "no-reply@whatdidyougetdone.com"),
 		SignupEmail: getEnv("SIGNUP_EMAIL", "start@whatdidyougetdone.com"),
+		EmailDryRun: emailDryRun,
+
+		EmailTracking: emailTracking,
 
 		AWSRegion:     getEnv("AWS_REGION", "us-east-1"),
 		AWSSESRegion:  getEnv("AWS_SES_REGION", "us-east-1"),
@@ -64,17 +449,198 @@ func Load() (*Config, error) {
 		PostgresUser:     getEnv("POSTGRES_USER", "postgres"),
 		PostgresPassword: getEnv("POSTGRES_PASSWORD", ""),
 		PostgresDB:       getEnv("POSTGRES_DB", "whatdidyougetdone"),
+		PostgresSSLMode:  getEnv("POSTGRES_SSLMODE", "disable"),
+
+		PostgresReplicaHost: getEnv("POSTGRES_REPLICA_HOST", ""),
+
+		DBMaxOpenConns:    dbMaxOpenConns,
+		DBMaxIdleConns:    dbMaxIdleConns,
+		DBConnMaxLifetime: dbConnMaxLifetime,
+		DBConnMaxIdleTime: dbConnMaxIdleTime,
+
+		DBQueryTimeout: dbQueryTimeout,
+		DBSlowQuery:    dbSlowQuery,
+
+		UserCacheSize: userCacheSize,
+		UserCacheTTL:  userCacheTTL,
 
 		DefaultPromptTime: getEnv("DEFAULT_PROMPT_TIME", "16:00"),
 		WeeklySummaryTime: getEnv("WEEKLY_SUMMARY_TIME", "16:30"),
+		JobsDisabled:      splitList(getEnv("JOBS_DISABLED", "")),
+		JobSchedules:      jobSchedules,
+
+		SchedulerMode:        schedulerMode,
+		SchedulerMaxLateness: schedulerMaxLateness,
 
 		AdminAPIKey: getEnv("ADMIN_API_KEY", ""),
 
+		APIAddr:      getEnv("API_ADDR", ":8080"),
+		DashboardURL: strings.TrimSuffix(getEnv("DASHBOARD_URL", ""), "/"),
+		AuthSecret:   getEnv("AUTH_SECRET", ""),
+
+		MSTeamsSecurityToken:      getEnv("MSTEAMS_SECURITY_TOKEN", ""),
+		SlackSigningSecret:        getEnv("SLACK_SIGNING_SECRET", ""),
+		SlackBotToken:             getEnv("SLACK_BOT_TOKEN", ""),
+		IntegrationEncryptionKeys: getEnv("INTEGRATION_ENCRYPTION_KEYS", ""),
+
+		InboundWebhookSecret: getEnv("INBOUND_WEBHOOK_SECRET", ""),
+		InboundAllowedIPs:    splitList(getEnv("INBOUND_ALLOWED_IPS", "")),
+		InboundRateLimit:     inboundRateLimit,
+
+		InboundMaxBytes:       inboundMaxBytes,
+		InboundMaxBodyBytes:   inboundMaxBodyBytes,
+		InboundOversizePolicy: inboundOversizePolicy,
+
+		OutboxBatchSize:          outboxBatchSize,
+		OutboxDrain:              outboxDrain,
+		OutboxMaxRun:             outboxMaxRun,
+		OutboxTransactionalQuota: outboxTransactionalQuota,
+		OutboxStuckAfter:         outboxStuckAfter,
+		SESMaxSendRate:           sesMaxSendRate,
+		SESBulkTemplates:         sesBulkTemplates,
+		SESTemplatePrefix:        getEnv("SES_TEMPLATE_PREFIX", "wdygdtw"),
+
+		AdminAlertEmail: getEnv("ADMIN_ALERT_EMAIL", ""),
+
+		EventBus:       getEnv("EVENT_BUS", "local"),
+		EventBusTarget: getEnv("EVENT_BUS_TARGET", ""),
+
+		JobQueue:    getEnv("JOB_QUEUE", "memory"),
+		JobQueueURL: getEnv("JOB_QUEUE_URL", ""),
+
+		EntryMergeWindow: entryMergeWindow,
+
+		EmailChangeAliasWindow: emailChangeAliasWindow,
+
+		PromptRecap:    promptRecap,
+		PromptRecapLLM: promptRecapLLM,
+
+		ClarificationMaxAttempts: clarificationMaxAttempts,
+		ClarificationResetAfter:  clarificationResetAfter,
+		ClarificationAdminEmail:  getEnv("CLARIFICATION_ADMIN_EMAIL", ""),
+
 		LLMProvider: getEnv("LLM_PROVIDER", "amazon_bedrock"),
 		LLMModel:    getEnv("LLM_MODEL", "anthropic.claude-3-haiku-20240307-v1:0"),
+
+		LLMFallbackModels: splitList(getEnv("LLM_FALLBACK_MODELS", "anthropic.claude-instant-v1,template")),
+
+		LLMConcurrency:       llmConcurrency,
+		LLMRequestsPerMinute: llmRequestsPerMinute,
+		LLMMaxTokens:         llmMaxTokens,
+		LLMMaxInputTokens:    llmMaxInputTokens,
+		LLMStreaming:         llmStreaming,
+
+		VertexProject:  getEnv("VERTEX_PROJECT", getEnv("GOOGLE_CLOUD_PROJECT", "")),
+		VertexLocation: getEnv("VERTEX_LOCATION", "us-central1"),
+
+		EmbeddingsModel: getEnv("EMBEDDINGS_MODEL", ""),
+
+		ShareCardBucket:  getEnv("SHARE_CARD_BUCKET", ""),
+		ShareCardBaseURL: getEnv("SHARE_CARD_BASE_URL", ""),
+
+		ArchiveBCC:               getEnv("ARCHIVE_BCC", ""),
+		ArchiveBucket:            getEnv("ARCHIVE_BUCKET", ""),
+		ArchivePrefix:            getEnv("ARCHIVE_PREFIX", "sent/"),
+		ArchiveEmailTypes:        splitList(getEnv("ARCHIVE_EMAIL_TYPES", "")),
+		ArchiveExcludeEmailTypes: splitList(getEnv("ARCHIVE_EXCLUDE_EMAIL_TYPES", "")),
+
+		RetentionPolicies: retentionPolicies,
+
+		StripeSecretKey:     getEnv("STRIPE_SECRET_KEY", ""),
+		StripeWebhookSecret: getEnv("STRIPE_WEBHOOK_SECRET", ""),
+		StripePriceID:       getEnv("STRIPE_PRICE_ID", ""),
+		BillingGracePeriod:  billingGracePeriod,
 	}, nil
 }
 
+// IsProd reports whether this is the production environment
+func (c *Config) IsProd() bool {
+	return c.AppEnv == EnvProd
+}
+
+// SubjectTag is prefixed to the subject of every email sent outside
+// production, such as "[staging] ", so test mail is easy to tell apart
+func (c *Config) SubjectTag() string {
+	if c.IsProd() {
+		return ""
+	}
+	return "[" + c.AppEnv + "] "
+}
+
+// parseJobSchedules parses "job=cron" pairs separated by semicolons, since
+// cron expressions may contain commas
+func parseJobSchedules(value string) (map[string]string, error) {
+	schedules := map[string]string{}
+	for _, pair := range strings.Split(value, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, schedule, ok := strings.Cut(pair, "=")
+		name, schedule = strings.TrimSpace(name), strings.TrimSpace(schedule)
+		if !ok || name == "" || schedule == "" {
+			return nil, fmt.Errorf("invalid job schedule %q, expected job=cron", pair)
+		}
+		schedules[name] = schedule
+	}
+	return schedules, nil
+}
+
+// parseRetention parses "name=period" pairs such as "email_bodies=90d,
+// audit_logs=1y"; later pairs override earlier ones. A period is "forever",
+// a number of days (d), weeks (w) or years (y), or a Go duration.
+func parseRetention(value string) (map[string]time.Duration, error) {
+	policies := map[string]time.Duration{}
+	for _, pair := range splitList(value) {
+		name, period, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid retention policy %q, expected name=period", pair)
+		}
+
+		keep, err := parseRetentionPeriod(strings.TrimSpace(period))
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention period for %s: %w", name, err)
+		}
+		policies[strings.TrimSpace(name)] = keep
+	}
+	return policies, nil
+}
+
+func parseRetentionPeriod(period string) (time.Duration, error) {
+	if period == "forever" || period == "0" {
+		return 0, nil
+	}
+	if period == "" {
+		return 0, fmt.Errorf("missing period")
+	}
+
+	day := 24 * time.Hour
+	units := map[string]time.Duration{"d": day, "w": 7 * day, "y": 365 * day}
+	if unit, ok := units[period[len(period)-1:]]; ok && len(period) > 1 {
+		n, err := strconv.Atoi(period[:len(period)-1])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("expected a positive count: %s", period)
+		}
+		return time.Duration(n) * unit, nil
+	}
+
+	keep, err := time.ParseDuration(period)
+	if err != nil || keep <= 0 {
+		return 0, fmt.Errorf("expected forever, Nd, Nw, Ny or a positive duration: %s", period)
+	}
+	return keep, nil
+}
+
+// splitList parses a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
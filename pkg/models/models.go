@@ -0,0 +1,573 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type User struct {
+	ID               int        `json:"id" db:"id"`
+	Email            string     `json:"email" db:"email"`
+	Name             string     `json:"name" db:"name"`
+	Timezone         string     `json:"timezone" db:"timezone"`
+	PromptTime       time.Time  `json:"prompt_time" db:"prompt_time"`
+	VerificationCode *string    `json:"verification_code,omitempty" db:"verification_code"`
+	IsVerified       bool       `json:"is_verified" db:"is_verified"`
+	IsPaused         bool       `json:"is_paused" db:"is_paused"`
+	PauseUntil       *time.Time `json:"pause_until,omitempty" db:"pause_until"`
+	ProjectFocus     *string    `json:"project_focus,omitempty" db:"project_focus"`
+	SignupStatus     string     `json:"signup_status" db:"signup_status"`
+	WeekStart        string     `json:"week_start" db:"week_start"`
+	DeliveryChannel  string     `json:"delivery_channel" db:"delivery_channel"`
+	EntryFormat      string     `json:"entry_format" db:"entry_format"`
+	SummaryVoice     string     `json:"summary_voice" db:"summary_voice"`
+	QuotesEnabled    bool       `json:"quotes_enabled" db:"quotes_enabled"`
+	CompareWeeks     bool       `json:"compare_weeks" db:"compare_weeks"`
+	WeeklyGoals      bool       `json:"weekly_goals" db:"weekly_goals"`
+	Language         string     `json:"language" db:"language"`
+	SummaryLanguage  string     `json:"summary_language" db:"summary_language"`
+	EmailTracking    bool       `json:"email_tracking" db:"email_tracking"`
+	PromptThreading  string     `json:"prompt_threading" db:"prompt_threading"`
+	ReplyToken       string     `json:"-" db:"reply_token"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Preferences is the user-editable part of a User as returned by the API
+type Preferences struct {
+	Email           string     `json:"email"`
+	Name            string     `json:"name"`
+	Timezone        string     `json:"timezone"`
+	PromptTime      string     `json:"prompt_time"`
+	ProjectFocus    *string    `json:"project_focus,omitempty"`
+	WeekStart       string     `json:"week_start"`
+	EntryFormat     string     `json:"entry_format"`
+	SummaryVoice    string     `json:"summary_voice"`
+	QuotesEnabled   bool       `json:"quotes_enabled"`
+	CompareWeeks    bool       `json:"compare_weeks"`
+	WeeklyGoals     bool       `json:"weekly_goals"`
+	Language        string     `json:"language"`
+	SummaryLanguage string     `json:"summary_language"`
+	EmailTracking   bool       `json:"email_tracking"`
+	PromptThreading string     `json:"prompt_threading"`
+	DeliveryChannel string     `json:"delivery_channel"`
+	IsPaused        bool       `json:"is_paused"`
+	PauseUntil      *time.Time `json:"pause_until,omitempty"`
+}
+
+// PreferencesFor returns the user's preferences with the prompt time as HH:MM
+func PreferencesFor(user *User) *Preferences {
+	return &Preferences{
+		Email:           user.Email,
+		Name:            user.Name,
+		Timezone:        user.Timezone,
+		PromptTime:      user.PromptTime.Format("15:04"),
+		ProjectFocus:    user.ProjectFocus,
+		WeekStart:       user.WeekStart,
+		EntryFormat:     user.EntryFormat,
+		SummaryVoice:    user.SummaryVoice,
+		QuotesEnabled:   user.QuotesEnabled,
+		CompareWeeks:    user.CompareWeeks,
+		WeeklyGoals:     user.WeeklyGoals,
+		Language:        user.Language,
+		SummaryLanguage: user.SummaryLanguage,
+		EmailTracking:   user.EmailTracking,
+		PromptThreading: user.PromptThreading,
+		DeliveryChannel: user.DeliveryChannel,
+		IsPaused:        user.IsPaused,
+		PauseUntil:      user.PauseUntil,
+	}
+}
+
+// PreferencesUpdate holds preference changes; nil fields are left unchanged.
+// Values accept the same forms as the reply commands, e.g. "8am" for
+// PromptTime and "first person" for SummaryVoice. An empty ProjectFocus
+// clears it.
+type PreferencesUpdate struct {
+	Name          *string `json:"name,omitempty"`
+	Timezone      *string `json:"timezone,omitempty"`
+	PromptTime    *string `json:"prompt_time,omitempty"`
+	ProjectFocus  *string `json:"project_focus,omitempty"`
+	WeekStart     *string `json:"week_start,omitempty"`
+	EntryFormat   *string `json:"entry_format,omitempty"`
+	SummaryVoice  *string `json:"summary_voice,omitempty"`
+	QuotesEnabled *bool   `json:"quotes_enabled,omitempty"`
+	CompareWeeks  *bool   `json:"compare_weeks,omitempty"`
+	WeeklyGoals   *bool   `json:"weekly_goals,omitempty"`
+	Language      *string `json:"language,omitempty"`
+	// SummaryLanguage is a language code, or "auto" to match the entries
+	SummaryLanguage *string `json:"summary_language,omitempty"`
+	// EmailTracking false opts out of open and click tracking
+	EmailTracking *bool `json:"email_tracking,omitempty"`
+	// PromptThreading is "daily" for a new thread each day or "weekly" to
+	// keep a week's prompts in one thread
+	PromptThreading *string `json:"prompt_threading,omitempty"`
+}
+
+// UserChannel links a user to a chat integration
+type UserChannel struct {
+	ID             int       `json:"id" db:"id"`
+	UserID         int       `json:"user_id" db:"user_id"`
+	Channel        string    `json:"channel" db:"channel"`
+	WebhookURL     *string   `json:"webhook_url,omitempty" db:"webhook_url"`
+	ExternalUserID *string   `json:"external_user_id,omitempty" db:"external_user_id"`
+	ImportChannel  *string   `json:"import_channel,omitempty" db:"import_channel"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type Entry struct {
+	ID             int       `json:"id" db:"id"`
+	UserID         int       `json:"user_id" db:"user_id"`
+	EntryDate      time.Time `json:"entry_date" db:"entry_date"`
+	RawContent     string    `json:"raw_content" db:"raw_content"`
+	ParsedContent  *string   `json:"parsed_content,omitempty" db:"parsed_content"`
+	ProjectTag     *string   `json:"project_tag,omitempty" db:"project_tag"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type WeeklySummary struct {
+	ID               int           `json:"id" db:"id"`
+	UserID           int           `json:"user_id" db:"user_id"`
+	WeekStartDate    time.Time     `json:"week_start_date" db:"week_start_date"`
+	SummaryParagraph string        `json:"summary_paragraph" db:"summary_paragraph"`
+	BulletPoints     BulletPoints  `json:"bullet_points" db:"bullet_points"`
+	LLMModel         string        `json:"llm_model" db:"llm_model"`
+	LLMCostCents     int           `json:"llm_cost_cents" db:"llm_cost_cents"`
+	CardURL          *string       `json:"card_url,omitempty" db:"card_url"`
+	CreatedAt        time.Time     `json:"created_at" db:"created_at"`
+}
+
+type EmailLog struct {
+	ID             int        `json:"id" db:"id"`
+	UserID         *int       `json:"user_id,omitempty" db:"user_id"`
+	RecipientEmail string     `json:"recipient_email" db:"recipient_email"`
+	CCEmails       []string   `json:"cc_emails,omitempty" db:"cc_emails"`
+	ReplyTo        *string    `json:"reply_to,omitempty" db:"reply_to"`
+	EmailType      string     `json:"email_type" db:"email_type"`
+	Priority       int        `json:"priority" db:"priority"`
+	Subject        string     `json:"subject" db:"subject"`
+	BodyText       string     `json:"body_text" db:"body_text"`
+	BodyHTML       *string    `json:"body_html,omitempty" db:"body_html"`
+	Status         string     `json:"status" db:"status"`
+	SESMessageID   *string    `json:"ses_message_id,omitempty" db:"ses_message_id"`
+	ErrorMessage   *string    `json:"error_message,omitempty" db:"error_message"`
+	RetryCount     int        `json:"retry_count" db:"retry_count"`
+	ScheduledAt    *time.Time `json:"scheduled_at,omitempty" db:"scheduled_at"`
+	SentAt         *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// DataReport summarizes everything stored about a user
+type DataReport struct {
+	Email              string     `json:"email"`
+	MemberSince        time.Time  `json:"member_since"`
+	EntryCount         int        `json:"entry_count"`
+	FirstEntryDate     *time.Time `json:"first_entry_date,omitempty"`
+	LastEntryDate      *time.Time `json:"last_entry_date,omitempty"`
+	WeeklySummaryCount int        `json:"weekly_summary_count"`
+	EmailLogCount      int        `json:"email_log_count"`
+	AttachmentCount    int        `json:"attachment_count"`
+	IntegrationCount   int        `json:"integration_count"`
+	RetentionPolicy    string     `json:"retention_policy"`
+}
+
+// AnomalyReport is what the nightly anomaly check found; each list is empty
+// when there was nothing to flag
+type AnomalyReport struct {
+	CheckedAt        time.Time      `json:"checked_at"`
+	BouncingUsers    []UserAnomaly  `json:"bouncing_users"`
+	MissingSummaries []UserAnomaly  `json:"missing_summaries"`
+	UnsentSummaries  []UserAnomaly  `json:"unsent_summaries"`
+	FailureSpikes    []FailureSpike `json:"failure_spikes"`
+}
+
+// Count is the number of anomalies in the report
+func (r *AnomalyReport) Count() int {
+	return len(r.BouncingUsers) + len(r.MissingSummaries) + len(r.UnsentSummaries) + len(r.FailureSpikes)
+}
+
+// UserAnomaly is one user an anomaly check flagged, with what it found
+type UserAnomaly struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+	Detail string `json:"detail"`
+}
+
+// FailureSpike is an email type whose failure rate over the last day is
+// well above its rate over the week before
+type FailureSpike struct {
+	EmailType    string  `json:"email_type"`
+	Sent         int     `json:"sent"`
+	Failed       int     `json:"failed"`
+	Rate         float64 `json:"rate"`
+	BaselineRate float64 `json:"baseline_rate"`
+}
+
+// RatePercent is Rate as a percentage
+func (f FailureSpike) RatePercent() float64 { return f.Rate * 100 }
+
+// BaselinePercent is BaselineRate as a percentage
+func (f FailureSpike) BaselinePercent() float64 { return f.BaselineRate * 100 }
+
+// Broadcast is the audit record of an admin announcement
+type Broadcast struct {
+	ID             int       `json:"id" db:"id"`
+	Subject        string    `json:"subject" db:"subject"`
+	TemplateName   string    `json:"template_name" db:"template_name"`
+	BodyTemplate   string    `json:"body_template" db:"body_template"`
+	RecipientCount int       `json:"recipient_count" db:"recipient_count"`
+	DryRun         bool      `json:"dry_run" db:"dry_run"`
+	SentBy         string    `json:"sent_by" db:"sent_by"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// WebhookEndpoint is an operator-registered URL that receives events
+type WebhookEndpoint struct {
+	ID         int       `json:"id" db:"id"`
+	URL        string    `json:"url" db:"url"`
+	Secret     string    `json:"-" db:"secret"`
+	EventTypes []string  `json:"event_types" db:"event_types"`
+	IsActive   bool      `json:"is_active" db:"is_active"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDelivery is one attempt log row for an event sent to an endpoint
+type WebhookDelivery struct {
+	ID             int        `json:"id" db:"id"`
+	EndpointID     int        `json:"endpoint_id" db:"endpoint_id"`
+	EventType      string     `json:"event_type" db:"event_type"`
+	Payload        string     `json:"payload" db:"payload"`
+	Status         string     `json:"status" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	ResponseStatus *int       `json:"response_status,omitempty" db:"response_status"`
+	ErrorMessage   *string    `json:"error_message,omitempty" db:"error_message"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Organization groups the users of a team for billing
+type Organization struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Members   int       `json:"members" db:"-"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// UsageRecord is one metric of an organization's usage on one day, as
+// reported to metered billing
+type UsageRecord struct {
+	ID             int64      `json:"id" db:"id"`
+	OrganizationID int        `json:"organization_id" db:"organization_id"`
+	Metric         string     `json:"metric" db:"metric"`
+	UsageDate      time.Time  `json:"usage_date" db:"usage_date"`
+	Quantity       int64      `json:"quantity" db:"quantity"`
+	Action         string     `json:"action" db:"action"`
+	IdempotencyKey string     `json:"idempotency_key" db:"idempotency_key"`
+	ReportedAt     *time.Time `json:"reported_at,omitempty" db:"reported_at"`
+}
+
+// OrgUsage is an organization's metered usage for a month. Users is the
+// most verified members on any day; the rest are totals. MeteredThrough is
+// the last day metered, nil before the first.
+type OrgUsage struct {
+	OrganizationID int           `json:"organization_id"`
+	Month          string        `json:"month"`
+	Users          int64         `json:"users"`
+	Prompts        int64         `json:"prompts"`
+	Summaries      int64         `json:"summaries"`
+	LLMCostCents   int64         `json:"llm_cost_cents"`
+	MeteredThrough *time.Time    `json:"metered_through,omitempty"`
+	Records        []UsageRecord `json:"records"`
+}
+
+// OrgBilling is an organization's Stripe subscription. Suspended is set
+// when the subscription has lapsed and any grace period has ended, which
+// stops its members' prompts and summaries.
+type OrgBilling struct {
+	OrganizationID       int        `json:"organization_id" db:"id"`
+	Name                 string     `json:"name" db:"name"`
+	BillingEmail         *string    `json:"billing_email,omitempty" db:"billing_email"`
+	StripeCustomerID     *string    `json:"stripe_customer_id,omitempty" db:"stripe_customer_id"`
+	StripeSubscriptionID *string    `json:"stripe_subscription_id,omitempty" db:"stripe_subscription_id"`
+	SubscriptionStatus   string     `json:"subscription_status" db:"subscription_status"`
+	GraceUntil           *time.Time `json:"grace_until,omitempty" db:"grace_until"`
+	DunningStage         int        `json:"dunning_stage" db:"dunning_stage"`
+	InvoiceURL           *string    `json:"invoice_url,omitempty" db:"invoice_url"`
+	Suspended            bool       `json:"suspended" db:"-"`
+	UpdatedAt            *time.Time `json:"updated_at,omitempty" db:"billing_updated_at"`
+}
+
+// InboundMessage is a reply as it was received, kept so it can be replayed
+type InboundMessage struct {
+	ID           int64      `json:"id" db:"id"`
+	Source       string     `json:"source" db:"source"`
+	MessageID    *string    `json:"message_id,omitempty" db:"message_id"`
+	S3Bucket     *string    `json:"s3_bucket,omitempty" db:"s3_bucket"`
+	S3Key        *string    `json:"s3_key,omitempty" db:"s3_key"`
+	Payload      string     `json:"payload" db:"payload"`
+	Sender       *string    `json:"sender,omitempty" db:"sender"`
+	Status       string     `json:"status" db:"status"`
+	Attempts     int        `json:"attempts" db:"attempts"`
+	ErrorMessage *string    `json:"error_message,omitempty" db:"error_message"`
+	ReceivedAt   time.Time  `json:"received_at" db:"received_at"`
+	ProcessedAt  *time.Time `json:"processed_at,omitempty" db:"processed_at"`
+}
+
+// BulletPoints is a custom type for JSON array handling
+type BulletPoints []string
+
+func (bp BulletPoints) Value() (driver.Value, error) {
+	return json.Marshal(bp)
+}
+
+func (bp *BulletPoints) Scan(value interface{}) error {
+	if value == nil {
+		*bp = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New("cannot scan BulletPoints from non-string type")
+	}
+
+	return json.Unmarshal(bytes, bp)
+}
+
+// Signup status constants (double opt-in flow)
+const (
+	SignupStatusPendingVerification = "pending_verification"
+	SignupStatusPendingConfirmation = "pending_confirmation"
+	SignupStatusActive              = "active"
+)
+
+// SummaryCCRecipient is an extra address copied on a user's weekly summary
+type SummaryCCRecipient struct {
+	ID               int        `json:"id" db:"id"`
+	UserID           int        `json:"user_id" db:"user_id"`
+	Email            string     `json:"email" db:"email"`
+	ConfirmationCode string     `json:"-" db:"confirmation_code"`
+	ConfirmedAt      *time.Time `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Mentor receives a monthly digest of a user's weekly summaries once they
+// confirm
+type Mentor struct {
+	ID               int        `json:"id" db:"id"`
+	UserID           int        `json:"user_id" db:"user_id"`
+	Email            string     `json:"email" db:"email"`
+	ConfirmationCode string     `json:"-" db:"confirmation_code"`
+	ConfirmedAt      *time.Time `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	LastDigestMonth  *time.Time `json:"last_digest_month,omitempty" db:"last_digest_month"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
+
+// UserEmail is another address a user replies from. Once verified, mail
+// from it finds the user; mail to the user still goes to their primary
+// address.
+type UserEmail struct {
+	ID               int        `json:"id" db:"id"`
+	UserID           int        `json:"user_id" db:"user_id"`
+	Email            string     `json:"email" db:"email"`
+	VerificationCode string     `json:"-" db:"verification_code"`
+	VerifiedAt       *time.Time `json:"verified_at,omitempty" db:"verified_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
+
+// UserProject is a stretch of time a user spent on a project. EndedOn is
+// exclusive and nil for the current project.
+type UserProject struct {
+	ID        int        `json:"id" db:"id"`
+	UserID    int        `json:"user_id" db:"user_id"`
+	Name      string     `json:"name" db:"name"`
+	StartedOn time.Time  `json:"started_on" db:"started_on"`
+	EndedOn   *time.Time `json:"ended_on,omitempty" db:"ended_on"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ProjectRollup summarizes a quarter's entries tagged with one project
+type ProjectRollup struct {
+	ID               int          `json:"id" db:"id"`
+	UserID           int          `json:"user_id" db:"user_id"`
+	Project          string       `json:"project" db:"project"`
+	QuarterStart     time.Time    `json:"quarter_start" db:"quarter_start"`
+	FirstEntryDate   time.Time    `json:"first_entry_date" db:"first_entry_date"`
+	LastEntryDate    time.Time    `json:"last_entry_date" db:"last_entry_date"`
+	SummaryParagraph string       `json:"summary_paragraph" db:"summary_paragraph"`
+	BulletPoints     BulletPoints `json:"bullet_points" db:"bullet_points"`
+	LLMModel         string       `json:"llm_model" db:"llm_model"`
+	CreatedAt        time.Time    `json:"created_at" db:"created_at"`
+}
+
+// UserBlackout is a stretch of days off for a user: a date range, or every
+// public holiday of Country when it is set
+type UserBlackout struct {
+	ID        int        `json:"id" db:"id"`
+	UserID    int        `json:"user_id" db:"user_id"`
+	StartDate *time.Time `json:"start_date,omitempty" db:"start_date"`
+	EndDate   *time.Time `json:"end_date,omitempty" db:"end_date"`
+	Country   *string    `json:"country,omitempty" db:"country"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Quote is shown in the daily prompt. SubmittedBy is set for user
+// submissions, which stay inactive until approved.
+type Quote struct {
+	ID          int       `json:"id" db:"id"`
+	Text        string    `json:"text" db:"text"`
+	Author      *string   `json:"author,omitempty" db:"author"`
+	SubmittedBy *int      `json:"submitted_by,omitempty" db:"submitted_by"`
+	IsActive    bool      `json:"is_active" db:"is_active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String formats the quote for display, with the author when known
+func (q *Quote) String() string {
+	if q.Author == nil || *q.Author == "" {
+		return q.Text
+	}
+	return q.Text + " - " + *q.Author
+}
+
+// Delivery channel constants
+const (
+	DeliveryChannelEmail   = "email"
+	DeliveryChannelMSTeams = "msteams"
+)
+
+// ChannelSlack links a Slack account, whose messages are logged as entries.
+// Prompts aren't delivered to Slack.
+const ChannelSlack = "slack"
+
+// Summary voice constants
+const (
+	SummaryVoiceCoach       = "coach"
+	SummaryVoiceFirstPerson = "first_person"
+)
+
+// Prompt threading constants. Daily prompts get a new subject each day, so
+// mail clients show each as its own thread; weekly prompts share a subject
+// for the week, so they collect in one.
+const (
+	PromptThreadingDaily  = "daily"
+	PromptThreadingWeekly = "weekly"
+)
+
+// SummaryLanguageAuto writes each summary in the language of its entries
+const SummaryLanguageAuto = "auto"
+
+// Email types constants
+const (
+	EmailTypeVerification    = "verification"
+	EmailTypeDailyPrompt     = "daily_prompt"
+	EmailTypeWeeklySummary   = "weekly_summary"
+	EmailTypeClarification   = "clarification"
+	EmailTypeConfirmation    = "confirmation"
+	EmailTypeDataReport      = "data_report"
+	EmailTypeAnnouncement    = "announcement"
+	EmailTypeScheduleUpdate  = "schedule_update"
+	EmailTypeCCRequest       = "cc_request"
+	EmailTypeAdminAlert      = "admin_alert"
+	EmailTypeMentorRequest   = "mentor_request"
+	EmailTypeMentorDigest    = "mentor_digest"
+	EmailTypeAskAnswer       = "ask_answer"
+	EmailTypeMagicLink       = "magic_link"
+	EmailTypeProjectRollup   = "project_rollup"
+	EmailTypeWeeklyGoals     = "weekly_goals"
+	EmailTypeSignupQuestion  = "signup_question"
+	EmailTypeAlreadySignedUp = "already_signed_up"
+	EmailTypeBilling         = "billing"
+	EmailTypeEmailChange     = "email_change"
+	EmailTypeEmailAlias      = "email_alias"
+)
+
+// EmailTypes lists every email type
+var EmailTypes = []string{
+	EmailTypeVerification, EmailTypeDailyPrompt, EmailTypeWeeklySummary,
+	EmailTypeClarification, EmailTypeConfirmation, EmailTypeDataReport,
+	EmailTypeAnnouncement, EmailTypeScheduleUpdate, EmailTypeCCRequest,
+	EmailTypeAdminAlert, EmailTypeMentorRequest, EmailTypeMentorDigest,
+	EmailTypeAskAnswer, EmailTypeMagicLink, EmailTypeProjectRollup,
+	EmailTypeWeeklyGoals, EmailTypeSignupQuestion, EmailTypeAlreadySignedUp,
+	EmailTypeBilling, EmailTypeEmailChange, EmailTypeEmailAlias,
+}
+
+// Email priorities. The outbox sends higher priorities first.
+const (
+	EmailPriorityBatch         = 0 // weekly summaries and announcements
+	EmailPriorityNormal        = 1 // daily prompts
+	EmailPriorityTransactional = 2 // replies to something the user just did
+)
+
+// EmailPriorityFor returns the outbox priority for an email type
+func EmailPriorityFor(emailType string) int {
+	switch emailType {
+	case EmailTypeVerification, EmailTypeClarification, EmailTypeConfirmation,
+		EmailTypeDataReport, EmailTypeScheduleUpdate, EmailTypeCCRequest,
+		EmailTypeAdminAlert, EmailTypeMentorRequest, EmailTypeAskAnswer,
+		EmailTypeMagicLink, EmailTypeSignupQuestion, EmailTypeAlreadySignedUp,
+		EmailTypeBilling, EmailTypeEmailChange, EmailTypeEmailAlias:
+		return EmailPriorityTransactional
+	case EmailTypeWeeklySummary, EmailTypeAnnouncement, EmailTypeMentorDigest,
+		EmailTypeProjectRollup:
+		return EmailPriorityBatch
+	}
+	return EmailPriorityNormal
+}
+
+// Webhook event types
+const (
+	WebhookEventUserVerified      = "user.verified"
+	WebhookEventEntryCreated      = "entry.created"
+	WebhookEventSummaryGenerated  = "summary.generated"
+	WebhookEventEmailRejected     = "email.rejected"
+	WebhookEventOutboxStuck       = "outbox.stuck"
+	WebhookEventAnomaliesDetected = "anomalies.detected"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookStatusPending   = "pending"
+	WebhookStatusDelivered = "delivered"
+	WebhookStatusFailed    = "failed"
+)
+
+// Inbound message sources
+const (
+	InboundSourceSES     = "ses"
+	InboundSourceWebhook = "webhook"
+)
+
+// Inbound message statuses
+const (
+	InboundStatusPending   = "pending"
+	InboundStatusProcessed = "processed"
+	InboundStatusFailed    = "failed"
+	// InboundStatusIgnored is mail to the reply address that isn't a reply,
+	// such as a calendar invite or a delivery receipt
+	InboundStatusIgnored = "ignored"
+)
+
+// Email statuses constants
+const (
+	EmailStatusPending  = "pending"
+	// EmailStatusSending marks an email claimed by a ProcessOutbox run
+	EmailStatusSending  = "sending"
+	EmailStatusSent     = "sent"
+	EmailStatusFailed   = "failed"
+	EmailStatusRetrying = "retrying"
+)
\ No newline at end of file